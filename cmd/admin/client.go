@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// target is where CLI commands read and write configuration: the database
+// directly, or a running admin service's API.
+type target struct {
+	dsn    string
+	apiURL string
+	token  string
+}
+
+// targetFlags registers --env and --api on fs and returns a function that
+// resolves the target after parsing. With --env staging the settings come
+// from ADMIN_STAGING_DB_DSN, ADMIN_STAGING_API_URL and
+// ADMIN_STAGING_API_TOKEN instead of ADMIN_DB_DSN, ADMIN_API_URL and
+// ADMIN_API_TOKEN. An API URL, if any, takes precedence over the DSN.
+func targetFlags(fs *flag.FlagSet) func() target {
+	env := fs.String("env", "", "named environment whose ADMIN_<ENV>_* variables to use")
+	api := fs.String("api", "", "admin API base URL (default: database access)")
+
+	return func() target {
+		prefix := "ADMIN_"
+		if *env != "" {
+			prefix += strings.ToUpper(strings.ReplaceAll(*env, "-", "_")) + "_"
+		}
+		t := target{
+			dsn:    os.Getenv(prefix + "DB_DSN"),
+			apiURL: os.Getenv(prefix + "API_URL"),
+			token:  os.Getenv(prefix + "API_TOKEN"),
+		}
+		if *api != "" {
+			t.apiURL = *api
+		}
+		if t.apiURL == "" && t.dsn == "" {
+			fmt.Fprintf(os.Stderr, "set %sDB_DSN or %sAPI_URL (or pass --api)\n", prefix, prefix)
+			os.Exit(2)
+		}
+		return t
+	}
+}
+
+// apiClient is a minimal client for the admin API.
+type apiClient struct {
+	baseURL  string
+	token    string
+	operator string
+	http     *http.Client
+}
+
+func newAPIClient(t target, operator string) *apiClient {
+	return &apiClient{
+		baseURL:  strings.TrimRight(t.apiURL, "/"),
+		token:    t.token,
+		operator: operator,
+		http:     &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// do sends a request and decodes a JSON response into out, if non-nil.
+func (c *apiClient) do(method, path string, body []byte, header http.Header, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.operator != "" {
+		req.Header.Set("X-Operator", c.operator)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/secret"
+)
+
+const usage = `Usage: admin [command] [flags]
+
+Commands:
+  serve        Run the admin HTTP service (default)
+  migrate      Apply, roll back or inspect schema migrations
+  export       Dump the configuration to a YAML or JSON file
+  import       Apply a configuration file
+  validate     Check configuration files offline
+  seed         Load sample backends and routes into the database
+  user         Create a local user or set its password
+  healthcheck  Probe the local service's readiness (for container probes)
+
+Run "admin <command> -h" for command flags.
+`
+
+func main() {
+	cmd, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "serve":
+		serve()
+	case "migrate":
+		migrateCommand(args)
+	case "export":
+		exportCommand(args)
+	case "import":
+		importCommand(args)
+	case "validate":
+		validateCommand(args)
+	case "healthcheck":
+		healthcheckCommand(args)
+	case "seed":
+		seedCommand(args)
+	case "user":
+		userCommand(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+}
+
+// newLogger creates the production logger shared by all commands.
+func newLogger() *zap.Logger {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatalf("failed to initialize logger: %v", err)
+	}
+	return logger
+}
+
+// openStore connects to the store and, unless ADMIN_AUTO_MIGRATE is false,
+// applies pending migrations.
+func openStore(logger *zap.Logger, dsn string) config.Store {
+	store := connectStore(logger, dsn)
+
+	migrator, ok := store.(config.Migrator)
+	if !ok {
+		return store
+	}
+
+	if getEnv("ADMIN_AUTO_MIGRATE", "true") == "true" {
+		if err := migrator.Migrate(); err != nil {
+			logger.Fatal("failed to migrate database", zap.Error(err))
+		}
+		return store
+	}
+
+	// Schema changes are managed with "admin migrate"; warn if behind
+	states, err := migrator.MigrationStatus()
+	if err != nil {
+		logger.Fatal("failed to read migration status", zap.Error(err))
+	}
+	for _, st := range states {
+		if !st.Applied {
+			logger.Warn("pending schema migration", zap.Int("version", st.Version), zap.String("name", st.Name))
+		}
+	}
+	return store
+}
+
+// connectStore opens the store selected by ADMIN_DB_DRIVER (default mysql)
+// and configures field encryption.
+func connectStore(logger *zap.Logger, dsn string) config.Store {
+	if dsn == "" {
+		logger.Fatal("ADMIN_DB_DSN environment variable is required")
+	}
+
+	driver := getEnv("ADMIN_DB_DRIVER", "mysql")
+	store, err := config.Open(driver, dsn)
+	if err != nil {
+		logger.Fatal("failed to open store", zap.String("driver", driver), zap.Error(err))
+	}
+
+	// Encryption keys for sensitive columns stored at rest
+	keyring, err := secret.LoadKeyring()
+	if err != nil {
+		logger.Fatal("invalid encryption keys", zap.Error(err))
+	}
+	if keyring != nil {
+		encrypter, ok := store.(config.Encrypter)
+		if !ok {
+			logger.Fatal("store driver does not support field encryption", zap.String("driver", driver))
+		}
+		encrypter.SetCipher(keyring)
+		logger.Info("field encryption enabled", zap.String("primary_key", keyring.PrimaryKeyID()))
+	}
+
+	// Retries of statements and transactions failing with transient errors
+	if retrier, ok := store.(config.Retrier); ok {
+		policy := config.DefaultRetryPolicy
+		if policy.Attempts, err = strconv.Atoi(getEnv("ADMIN_DB_RETRY_ATTEMPTS", "3")); err != nil || policy.Attempts < 1 {
+			logger.Fatal("invalid ADMIN_DB_RETRY_ATTEMPTS: must be a positive integer", zap.Error(err))
+		}
+		if policy.Backoff, err = time.ParseDuration(getEnv("ADMIN_DB_RETRY_BACKOFF", "50ms")); err != nil || policy.Backoff < 0 {
+			logger.Fatal("invalid ADMIN_DB_RETRY_BACKOFF", zap.Error(err))
+		}
+		if policy.MaxBackoff, err = time.ParseDuration(getEnv("ADMIN_DB_RETRY_MAX_BACKOFF", "1s")); err != nil || policy.MaxBackoff < policy.Backoff {
+			logger.Fatal("invalid ADMIN_DB_RETRY_MAX_BACKOFF: must not be shorter than ADMIN_DB_RETRY_BACKOFF", zap.Error(err))
+		}
+		retrier.SetRetryPolicy(policy)
+	}
+
+	return store
+}
+
+// closeStore releases the store's resources, if it holds any.
+func closeStore(store config.Store) {
+	if c, ok := store.(io.Closer); ok {
+		c.Close()
+	}
+}
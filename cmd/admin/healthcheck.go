@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// healthcheckCommand probes the local service's readiness endpoint and
+// exits 0 if it is ready and 1 otherwise, for Docker HEALTHCHECK and
+// Kubernetes exec probes in images without curl.
+func healthcheckCommand(args []string) {
+	fs := flag.NewFlagSet("healthcheck", flag.ExitOnError)
+	url := fs.String("url", "", "endpoint to probe (default: /health/ready on ADMIN_MGMT_LISTEN, or else ADMIN_HTTP_LISTEN)")
+	timeout := fs.Duration("timeout", 3*time.Second, "request timeout")
+	fs.Parse(args)
+
+	if *url == "" {
+		listen := getEnv("ADMIN_MGMT_LISTEN", getEnv("ADMIN_HTTP_LISTEN", ":8081"))
+		*url = "http://" + localAddr(listen) + "/health/ready"
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	resp, err := client.Get(*url)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unhealthy: %v\n", err)
+		os.Exit(1)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "unhealthy: %s\n", resp.Status)
+		os.Exit(1)
+	}
+}
+
+// localAddr turns a listen address into one reachable from the same host.
+func localAddr(listen string) string {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return listen
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -2,84 +2,1050 @@ package main
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/anomaly"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/backup"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/cache"
 	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/configsvc"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/gitops"
 	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/handler"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/healthcheck"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/incident"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/jobs"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/ldap"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/maintenance"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/metrics"
 	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/middleware"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/notify"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/outbox"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/publish"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/registry"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/siem"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/signing"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/stats"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/systemd"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/pkg/api/configv1"
 )
 
-func main() {
-	// Get database DSN from environment
-	dsn := os.Getenv("ADMIN_DB_DSN")
-	if dsn == "" {
-		log.Fatal("ADMIN_DB_DSN environment variable is required")
-	}
-
+// serve runs the admin HTTP service.
+func serve() {
 	// Get HTTP listen address
 	listenAddr := getEnv("ADMIN_HTTP_LISTEN", ":8081")
 
-	// Initialize logger
-	logger, err := zap.NewProduction()
-	if err != nil {
-		log.Fatalf("failed to initialize logger: %v", err)
-	}
+	logger := newLogger()
 	defer logger.Sync()
 
-	// Create MySQL store
-	store, err := config.NewMySQLStore(dsn)
+	store := openStore(logger, os.Getenv("ADMIN_DB_DSN"))
+	defer closeStore(store)
+
+	// Metrics of the service itself, served on the management listener, or
+	// on the API listener without one
+	metricsRegistry := metrics.NewRegistry()
+
+	// The database circuit breaker fails requests fast while the database
+	// is unreachable, probing it until it answers again
+	breaker, _ := store.(config.Breaker)
+	var breakerCooldown time.Duration
+	if breaker != nil {
+		threshold, err := strconv.Atoi(getEnv("ADMIN_DB_BREAKER_THRESHOLD", "5"))
+		if err != nil || threshold < 0 {
+			logger.Fatal("invalid ADMIN_DB_BREAKER_THRESHOLD: must be 0 or a positive integer", zap.Error(err))
+		}
+		if breakerCooldown, err = time.ParseDuration(getEnv("ADMIN_DB_BREAKER_COOLDOWN", "5s")); err != nil || breakerCooldown < time.Second {
+			logger.Fatal("invalid ADMIN_DB_BREAKER_COOLDOWN (at least 1s)", zap.Error(err))
+		}
+		if threshold == 0 {
+			breaker = nil
+		} else {
+			opened := metricsRegistry.Counter("admin_db_circuit_breaker_opened_total", "Times the database circuit breaker opened.")
+			previous := config.BreakerClosed
+			breaker.SetBreakerPolicy(config.BreakerPolicy{
+				Threshold: threshold,
+				Cooldown:  breakerCooldown,
+				OnChange: func(state config.BreakerState) {
+					switch {
+					case state == config.BreakerOpen && previous == config.BreakerClosed:
+						opened.Inc()
+						logger.Warn("database unreachable, circuit breaker open", zap.Int("failures", threshold))
+					case state == config.BreakerClosed:
+						logger.Info("database reachable again, circuit breaker closed")
+					}
+					previous = state
+				},
+			})
+			metricsRegistry.GaugeFunc("admin_db_circuit_breaker_state", "State of the database circuit breaker: 0 closed, 1 open, 2 half-open.",
+				func() float64 { return float64(breaker.BreakerState()) })
+		}
+	}
+
+	// Timeouts and concurrency bounds by path group, replacing the
+	// server's timeouts for long exports and streaming endpoints
+	endpointLimits, err := middleware.ParseEndpointLimits(getEnv("ADMIN_ENDPOINT_LIMITS",
+		"/api/v1/export/=5m,/api/v1/backups/=5m,/api/v1/apply=2m,/api/v1/history/tail=0,/ws=0"))
 	if err != nil {
-		logger.Fatal("failed to create mysql store", zap.Error(err))
+		logger.Fatal("invalid ADMIN_ENDPOINT_LIMITS", zap.Error(err))
+	}
+
+	// Configuration changes are published to live UI clients
+	broker := events.NewBroker()
+	configStore := events.NewNotifyingStore(store, broker)
+	// Stores with an outbox also record the events in the transaction of
+	// each change, for delivery to sinks that must not miss any
+	outboxStore, _ := store.(config.OutboxStore)
+	if outboxStore != nil {
+		configStore.RecordOutbox()
+	}
+
+	// Background workers are stopped when the service shuts down
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Optionally pick up edits made outside the service, e.g. to the
+	// files of the file store
+	if watcher, ok := store.(config.Watcher); ok && getEnv("ADMIN_STORE_WATCH", "false") == "true" {
+		go func() {
+			err := watcher.Watch(ctx, func(h *config.ConfigHistory) {
+				logger.Info("configuration changed outside the service", zap.String("type", h.ConfigType), zap.String("operation", h.Operation))
+				broker.Publish(events.FromHistory(h))
+			}, func(err error) {
+				logger.Warn("failed to reload store", zap.Error(err))
+			})
+			if err != nil {
+				logger.Error("failed to watch store", zap.Error(err))
+			}
+		}()
+	}
+
+	// Optional Ed25519 key for signing compiled config payloads
+	var signer *signing.Signer
+	if key := os.Getenv("ADMIN_SIGNING_KEY"); key != "" {
+		var err error
+		signer, err = signing.NewSigner(key)
+		if err != nil {
+			logger.Fatal("invalid ADMIN_SIGNING_KEY", zap.Error(err))
+		}
+	}
+
+	// Read-only mode refuses all mutations, e.g. during database maintenance
+	readOnly := maintenance.NewMode(
+		getEnv("ADMIN_READ_ONLY", "false") == "true",
+		os.Getenv("ADMIN_READ_ONLY_MESSAGE"),
+	)
+
+	// Admission controllers run before every configuration change
+	admissionChain := admission.Chain{admission.NewFreezeController(configStore)}
+	pluginSchemas, _ := store.(config.PluginSchemaStore)
+	if pluginSchemas != nil {
+		admissionChain = append(admissionChain, admission.NewPluginController(pluginSchemas))
+	}
+	teamStore, _ := store.(config.TeamStore)
+	if teamStore != nil {
+		enforce := getEnv("ADMIN_ENFORCE_TEAM_OWNERSHIP", "false") == "true"
+		admissionChain = append(admissionChain, admission.NewOwnershipController(teamStore, enforce))
+	}
+	if quotaSpec := os.Getenv("ADMIN_CHANGE_QUOTAS"); quotaSpec != "" {
+		quotas, err := admission.ParseQuotas(quotaSpec)
+		if err != nil {
+			logger.Fatal("invalid ADMIN_CHANGE_QUOTAS", zap.Error(err))
+		}
+		admissionChain = append(admissionChain, admission.NewQuotaController(configStore, quotas))
+	}
+	if getEnv("ADMIN_REQUIRE_CHANGE_REASON", "false") == "true" {
+		admissionChain = append(admissionChain, admission.RequireReason{})
+	}
+	if policyDir := os.Getenv("ADMIN_POLICY_DIR"); policyDir != "" {
+		policy, err := admission.NewPolicyController(ctx, policyDir)
+		if err != nil {
+			logger.Fatal("failed to load policies", zap.String("dir", policyDir), zap.Error(err))
+		}
+		admissionChain = append(admissionChain, policy)
+	}
+	if webhookURL := os.Getenv("ADMIN_VALIDATION_WEBHOOK_URL"); webhookURL != "" {
+		timeout, err := time.ParseDuration(getEnv("ADMIN_VALIDATION_WEBHOOK_TIMEOUT", "5s"))
+		if err != nil {
+			logger.Fatal("invalid ADMIN_VALIDATION_WEBHOOK_TIMEOUT", zap.Error(err))
+		}
+		failOpen := getEnv("ADMIN_VALIDATION_WEBHOOK_FAIL_OPEN", "false") == "true"
+		admissionChain = append(admissionChain, admission.NewWebhookController(webhookURL, timeout, failOpen))
+	}
+
+	// Optional PagerDuty/Opsgenie incidents for critical conditions
+	var senders []incident.Sender
+	if key := os.Getenv("ADMIN_PAGERDUTY_ROUTING_KEY"); key != "" {
+		senders = append(senders, incident.NewPagerDuty(incident.PagerDutyEventsURL, key))
+	}
+	if key := os.Getenv("ADMIN_OPSGENIE_API_KEY"); key != "" {
+		senders = append(senders, incident.NewOpsgenie(getEnv("ADMIN_OPSGENIE_API_URL", incident.OpsgenieAPIURL), key))
+	}
+	var incidents *incident.Manager
+	if len(senders) > 0 {
+		incidents = incident.NewManager(os.Getenv("ADMIN_ENVIRONMENT"), senders, logger)
+
+		backendDownAfter, err := time.ParseDuration(getEnv("ADMIN_INCIDENT_BACKEND_DOWN_AFTER", "5m"))
+		if err != nil || backendDownAfter <= 0 {
+			logger.Fatal("invalid ADMIN_INCIDENT_BACKEND_DOWN_AFTER", zap.Error(err))
+		}
+		dbDownAfter, err := time.ParseDuration(getEnv("ADMIN_INCIDENT_DB_DOWN_AFTER", "1m"))
+		if err != nil {
+			logger.Fatal("invalid ADMIN_INCIDENT_DB_DOWN_AFTER", zap.Error(err))
+		}
+
+		go incidents.WatchBackends(ctx, broker, backendDownAfter)
+		if pinger, ok := store.(config.Pinger); ok {
+			go incidents.WatchDatabase(ctx, pinger, 15*time.Second, dbDownAfter)
+		}
+		if os.Getenv("ADMIN_HEALTH_CHECK_INTERVAL") == "" {
+			logger.Warn("incident alerting is enabled but health checking is disabled; backend outages will not page")
+		}
+	}
+
+	// Optional GitOps sync from a Git repository of YAML config
+	var syncer *gitops.Syncer
+	if repoURL := os.Getenv("ADMIN_GITOPS_REPO"); repoURL != "" {
+		interval, err := time.ParseDuration(getEnv("ADMIN_GITOPS_INTERVAL", "1m"))
+		if err != nil {
+			logger.Fatal("invalid ADMIN_GITOPS_INTERVAL", zap.Error(err))
+		}
+		var onSync func(gitops.Status, error)
+		if incidents != nil {
+			onSync = incidents.GitOpsSynced
+		}
+		syncer = gitops.NewSyncer(gitops.Options{
+			RepoURL:       repoURL,
+			Branch:        getEnv("ADMIN_GITOPS_BRANCH", "main"),
+			Path:          getEnv("ADMIN_GITOPS_PATH", "."),
+			WorkDir:       os.Getenv("ADMIN_GITOPS_WORKDIR"),
+			Interval:      interval,
+			WebhookSecret: os.Getenv("ADMIN_GITOPS_WEBHOOK_SECRET"),
+			Paused:        readOnly.ReadOnly,
+			OnSync:        onSync,
+		}, configStore, admissionChain, logger)
+		go syncer.Run(ctx)
+	}
+
+	// Optional scheduled backups to an S3-compatible bucket
+	var backups *backup.Scheduler
+	if bucket := os.Getenv("ADMIN_BACKUP_S3_BUCKET"); bucket != "" {
+		client, err := backup.NewS3Client(backup.S3Config{
+			Endpoint:        getEnv("ADMIN_BACKUP_S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Bucket:          bucket,
+			Region:          getEnv("ADMIN_BACKUP_S3_REGION", "us-east-1"),
+			AccessKeyID:     getEnv("ADMIN_BACKUP_S3_ACCESS_KEY_ID", os.Getenv("AWS_ACCESS_KEY_ID")),
+			SecretAccessKey: getEnv("ADMIN_BACKUP_S3_SECRET_ACCESS_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY")),
+			SessionToken:    getEnv("ADMIN_BACKUP_S3_SESSION_TOKEN", os.Getenv("AWS_SESSION_TOKEN")),
+		})
+		if err != nil {
+			logger.Fatal("invalid backup storage configuration", zap.Error(err))
+		}
+		interval, err := time.ParseDuration(getEnv("ADMIN_BACKUP_INTERVAL", "24h"))
+		if err != nil || interval <= 0 {
+			logger.Fatal("invalid ADMIN_BACKUP_INTERVAL", zap.Error(err))
+		}
+		retention, err := time.ParseDuration(getEnv("ADMIN_BACKUP_RETENTION", "720h"))
+		if err != nil || retention < 0 {
+			logger.Fatal("invalid ADMIN_BACKUP_RETENTION", zap.Error(err))
+		}
+		backups = backup.NewScheduler(store, client, backup.Options{
+			Prefix:    getEnv("ADMIN_BACKUP_S3_PREFIX", "gateway-admin/"),
+			Interval:  interval,
+			Retention: retention,
+		}, logger)
+		go backups.Run(ctx)
+	}
+
+	// Optional Redis cache for the gateway config pull path
+	var configCache handler.ConfigCache
+	if redisURL := os.Getenv("ADMIN_REDIS_URL"); redisURL != "" {
+		ttl, err := time.ParseDuration(getEnv("ADMIN_CONFIG_CACHE_TTL", "5m"))
+		if err != nil {
+			logger.Fatal("invalid ADMIN_CONFIG_CACHE_TTL", zap.Error(err))
+		}
+		c, err := cache.NewConfigCache(redisURL, ttl, logger)
+		if err != nil {
+			logger.Fatal("invalid ADMIN_REDIS_URL", zap.Error(err))
+		}
+		defer c.Close()
+		go c.Watch(ctx, broker)
+		configCache = c
+	}
+
+	// Optional webhooks receiving every configuration change event
+	var sinks []outbox.Sink
+	if webhooks := os.Getenv("ADMIN_EVENT_WEBHOOKS"); webhooks != "" {
+		if outboxStore == nil {
+			logger.Fatal("ADMIN_EVENT_WEBHOOKS requires a store with an outbox")
+		}
+		for _, u := range strings.Split(webhooks, ",") {
+			sink, err := outbox.NewWebhookSink(strings.TrimSpace(u))
+			if err != nil {
+				logger.Fatal("invalid ADMIN_EVENT_WEBHOOKS", zap.Error(err))
+			}
+			sinks = append(sinks, sink)
+		}
+	}
+
+	// Optional Slack/Teams notifications on configuration changes, sent
+	// from the outbox if the store keeps one
+	if webhooks := os.Getenv("ADMIN_NOTIFY_WEBHOOKS"); webhooks != "" {
+		targets, err := notify.ParseTargets(webhooks)
+		if err != nil {
+			logger.Fatal("invalid ADMIN_NOTIFY_WEBHOOKS", zap.Error(err))
+		}
+		notifier := notify.NewNotifier(os.Getenv("ADMIN_ENVIRONMENT"), targets, logger)
+		switch {
+		case !notifier.Enabled():
+		case outboxStore != nil:
+			sinks = append(sinks, notifier.Sinks()...)
+		default:
+			go notifier.Run(ctx, broker)
+		}
+	}
+
+	// Optional export of audit events to a SIEM over syslog, with the
+	// configuration changes sent from the outbox if the store keeps one
+	var siemExporter *siem.Exporter
+	if addr := os.Getenv("ADMIN_SIEM_ADDR"); addr != "" {
+		var err error
+		siemExporter, err = siem.NewExporter(siem.Config{
+			Addr:        addr,
+			Format:      getEnv("ADMIN_SIEM_FORMAT", siem.FormatCEF),
+			Fields:      os.Getenv("ADMIN_SIEM_FIELDS"),
+			Environment: os.Getenv("ADMIN_ENVIRONMENT"),
+		}, logger)
+		if err != nil {
+			logger.Fatal("invalid SIEM configuration", zap.Error(err))
+		}
+		if outboxStore != nil {
+			sinks = append(sinks, siemExporter)
+			go siemExporter.Run(ctx, nil)
+		} else {
+			go siemExporter.Run(ctx, broker)
+		}
+	}
+
+	// Optional detection of anomalous configuration activity, raising
+	// anomaly events and SIEM audit events; fed from the outbox if the store
+	// keeps one, so that a single replica sees the changes of all
+	rules := os.Getenv("ADMIN_ANOMALY_RULES")
+	rateFactor := os.Getenv("ADMIN_ANOMALY_RATE_FACTOR")
+	businessHours := os.Getenv("ADMIN_ANOMALY_BUSINESS_HOURS")
+	if rules != "" || rateFactor != "" || businessHours != "" {
+		cfg := anomaly.Config{Environment: os.Getenv("ADMIN_ENVIRONMENT")}
+		var err error
+		if cfg.Rules, err = anomaly.ParseRules(rules); err != nil {
+			logger.Fatal("invalid ADMIN_ANOMALY_RULES", zap.Error(err))
+		}
+		if rateFactor != "" {
+			if cfg.RateFactor, err = strconv.ParseFloat(rateFactor, 64); err != nil || cfg.RateFactor <= 1 {
+				logger.Fatal("invalid ADMIN_ANOMALY_RATE_FACTOR: must be a number above 1", zap.Error(err))
+			}
+		}
+		if cfg.RateWindow, err = time.ParseDuration(getEnv("ADMIN_ANOMALY_RATE_WINDOW", "10m")); err != nil || cfg.RateWindow <= 0 {
+			logger.Fatal("invalid ADMIN_ANOMALY_RATE_WINDOW", zap.Error(err))
+		}
+		if cfg.RateMin, err = strconv.Atoi(getEnv("ADMIN_ANOMALY_RATE_MIN", "20")); err != nil || cfg.RateMin < 1 {
+			logger.Fatal("invalid ADMIN_ANOMALY_RATE_MIN", zap.Error(err))
+		}
+		if cfg.Baseline, err = time.ParseDuration(getEnv("ADMIN_ANOMALY_BASELINE", "336h")); err != nil || cfg.Baseline <= cfg.RateWindow {
+			logger.Fatal("invalid ADMIN_ANOMALY_BASELINE: must be longer than ADMIN_ANOMALY_RATE_WINDOW", zap.Error(err))
+		}
+		if businessHours != "" {
+			loc := time.Local
+			if tz := os.Getenv("ADMIN_ANOMALY_TIMEZONE"); tz != "" {
+				if loc, err = time.LoadLocation(tz); err != nil {
+					logger.Fatal("invalid ADMIN_ANOMALY_TIMEZONE", zap.Error(err))
+				}
+			}
+			if cfg.BusinessHours, err = anomaly.ParseHours(businessHours, loc); err != nil {
+				logger.Fatal("invalid ADMIN_ANOMALY_BUSINESS_HOURS", zap.Error(err))
+			}
+		}
+
+		detector := anomaly.New(cfg, func(a anomaly.Alert) {
+			e := a.Event()
+			broker.Publish(e)
+			if siemExporter != nil {
+				siemExporter.AuditAnomaly(e)
+			}
+		}, logger)
+		if err := detector.Seed(store); err != nil {
+			logger.Warn("failed to learn recent activity for anomaly detection", zap.Error(err))
+		}
+		if outboxStore != nil {
+			sinks = append(sinks, detector)
+		} else {
+			go detector.Run(ctx, broker)
+		}
+	}
+
+	// The outbox is delivered to the sinks, and pruned after the retention
+	// whether delivered or not
+	if outboxStore != nil {
+		retention, err := time.ParseDuration(getEnv("ADMIN_OUTBOX_RETENTION", "168h"))
+		if err != nil || retention <= 0 {
+			logger.Fatal("invalid ADMIN_OUTBOX_RETENTION", zap.Error(err))
+		}
+		go outbox.NewDispatcher(outboxStore, sinks, retention, logger).Run(ctx, broker)
+	}
+
+	// Validation warnings are returned with responses, or reject changes
+	// in strict mode
+	warnings := handler.Warnings{Strict: getEnv("ADMIN_STRICT_VALIDATION", "false") == "true"}
+
+	// Optional active health checking of backends
+	if interval := os.Getenv("ADMIN_HEALTH_CHECK_INTERVAL"); interval != "" {
+		opts := healthcheck.Options{}
+		var err error
+		if opts.Interval, err = time.ParseDuration(interval); err != nil {
+			logger.Fatal("invalid ADMIN_HEALTH_CHECK_INTERVAL", zap.Error(err))
+		}
+		if opts.Timeout, err = time.ParseDuration(getEnv("ADMIN_HEALTH_CHECK_TIMEOUT", "5s")); err != nil {
+			logger.Fatal("invalid ADMIN_HEALTH_CHECK_TIMEOUT", zap.Error(err))
+		}
+		if opts.FailureThreshold, err = strconv.Atoi(getEnv("ADMIN_HEALTH_CHECK_FAILURES", "3")); err != nil {
+			logger.Fatal("invalid ADMIN_HEALTH_CHECK_FAILURES", zap.Error(err))
+		}
+		if opts.Retention, err = time.ParseDuration(getEnv("ADMIN_LATENCY_RETENTION", "168h")); err != nil {
+			logger.Fatal("invalid ADMIN_LATENCY_RETENTION", zap.Error(err))
+		}
+		checker := healthcheck.NewChecker(store, broker, opts, logger)
+		go checker.Run(ctx)
+		warnings.Health = checker
+	}
+
+	// Gateway traffic stats are kept for a limited time
+	if statsStore, ok := store.(config.StatsStore); ok {
+		retention, err := time.ParseDuration(getEnv("ADMIN_STATS_RETENTION", "720h"))
+		if err != nil || retention <= 0 {
+			logger.Fatal("invalid ADMIN_STATS_RETENTION", zap.Error(err))
+		}
+		go stats.Prune(ctx, statsStore, retention, logger)
+	}
+
+	// Running gateway instances are registered with heartbeats, and
+	// forgotten once gone for longer than the retention
+	gatewayStore, _ := store.(config.GatewayStore)
+	var heartbeatInterval time.Duration
+	if gatewayStore != nil {
+		var err error
+		heartbeatInterval, err = time.ParseDuration(getEnv("ADMIN_GATEWAY_HEARTBEAT_INTERVAL", "30s"))
+		if err != nil || heartbeatInterval < time.Second {
+			logger.Fatal("invalid ADMIN_GATEWAY_HEARTBEAT_INTERVAL (at least 1s)", zap.Error(err))
+		}
+		retention, err := time.ParseDuration(getEnv("ADMIN_GATEWAY_RETENTION", "168h"))
+		if err != nil || retention <= 0 {
+			logger.Fatal("invalid ADMIN_GATEWAY_RETENTION", zap.Error(err))
+		}
+		go registry.Prune(ctx, gatewayStore, retention, logger)
+	}
+
+	// Long operations can run as background jobs, for stores that keep them
+	jobStore, _ := store.(config.JobStore)
+	var runner *jobs.Runner
+	if jobStore != nil {
+		retention, err := time.ParseDuration(getEnv("ADMIN_JOB_RETENTION", "168h"))
+		if err != nil || retention < 0 {
+			logger.Fatal("invalid ADMIN_JOB_RETENTION", zap.Error(err))
+		}
+		runner = jobs.NewRunner(jobStore, jobs.Options{Retention: retention, Paused: readOnly.ReadOnly}, logger)
+	}
+
+	// Optional email alerts on backend health transitions
+	if recipients := os.Getenv("ADMIN_ALERT_EMAILS"); recipients != "" {
+		if os.Getenv("ADMIN_HEALTH_CHECK_INTERVAL") == "" {
+			logger.Warn("ADMIN_ALERT_EMAILS is set but health checking is disabled; set ADMIN_HEALTH_CHECK_INTERVAL")
+		}
+		smtpCfg := notify.SMTPConfig{
+			Addr:     os.Getenv("ADMIN_SMTP_ADDR"),
+			Username: os.Getenv("ADMIN_SMTP_USERNAME"),
+			Password: os.Getenv("ADMIN_SMTP_PASSWORD"),
+			From:     os.Getenv("ADMIN_SMTP_FROM"),
+			To:       strings.Split(recipients, ","),
+		}
+		if smtpCfg.Addr == "" || smtpCfg.From == "" {
+			logger.Fatal("ADMIN_SMTP_ADDR and ADMIN_SMTP_FROM are required for ADMIN_ALERT_EMAILS")
+		}
+		alerter := notify.NewEmailAlerter(smtpCfg, os.Getenv("ADMIN_ENVIRONMENT"), store, logger)
+		go alerter.Run(ctx, broker)
+	}
+
+	// How callers are identified: by the identity headers of an
+	// authenticating proxy, or as local users signing in with a password,
+	// checked locally or against LDAP
+	authMode := os.Getenv("ADMIN_AUTH_MODE")
+	if authMode == "" {
+		authMode = "header"
+		if getEnv("ADMIN_LOCAL_AUTH", "false") == "true" {
+			authMode = "local"
+		}
+	}
+	if authMode != "header" && authMode != "local" && authMode != "ldap" {
+		logger.Fatal("invalid ADMIN_AUTH_MODE (must be header, local or ldap)", zap.String("mode", authMode))
+	}
+	var tokens *auth.TokenIssuer
+	var userStore config.UserStore
+	var loginOptions handler.LoginOptions
+	if authMode != "header" {
+		userStore, _ = store.(config.UserStore)
+		if userStore == nil {
+			logger.Fatal("ADMIN_AUTH_MODE requires a store that keeps users", zap.String("mode", authMode), zap.String("driver", getEnv("ADMIN_DB_DRIVER", "mysql")))
+		}
+		ttl, err := time.ParseDuration(getEnv("ADMIN_AUTH_TOKEN_TTL", "15m"))
+		if err != nil {
+			logger.Fatal("invalid ADMIN_AUTH_TOKEN_TTL", zap.Error(err))
+		}
+		loginOptions.SessionTTL, err = time.ParseDuration(getEnv("ADMIN_AUTH_SESSION_TTL", "720h"))
+		if err != nil || loginOptions.SessionTTL <= 0 {
+			logger.Fatal("invalid ADMIN_AUTH_SESSION_TTL", zap.Error(err))
+		}
+		loginOptions.SecureCookies = getEnv("ADMIN_AUTH_COOKIE_SECURE", "true") == "true"
+		loginOptions.Lockout.Threshold, err = strconv.Atoi(getEnv("ADMIN_LOGIN_LOCKOUT_THRESHOLD", "5"))
+		if err != nil {
+			logger.Fatal("invalid ADMIN_LOGIN_LOCKOUT_THRESHOLD", zap.Error(err))
+		}
+		if loginOptions.Lockout.Base, err = time.ParseDuration(getEnv("ADMIN_LOGIN_LOCKOUT_BASE", "1m")); err != nil {
+			logger.Fatal("invalid ADMIN_LOGIN_LOCKOUT_BASE", zap.Error(err))
+		}
+		if loginOptions.Lockout.Max, err = time.ParseDuration(getEnv("ADMIN_LOGIN_LOCKOUT_MAX", "1h")); err != nil {
+			logger.Fatal("invalid ADMIN_LOGIN_LOCKOUT_MAX", zap.Error(err))
+		}
+		// Behind a proxy every client would share its address, so addresses
+		// are only throttled by default once the proxies are known
+		if loginOptions.TrustedProxies, err = auth.ParseTrustedProxies(os.Getenv("ADMIN_TRUSTED_PROXIES")); err != nil {
+			logger.Fatal("invalid ADMIN_TRUSTED_PROXIES", zap.Error(err))
+		}
+		defaultIPThreshold := "0"
+		if len(loginOptions.TrustedProxies) > 0 {
+			defaultIPThreshold = "20"
+		}
+		ipThreshold, err := strconv.Atoi(getEnv("ADMIN_LOGIN_IP_THRESHOLD", defaultIPThreshold))
+		if err != nil {
+			logger.Fatal("invalid ADMIN_LOGIN_IP_THRESHOLD", zap.Error(err))
+		}
+		if ipThreshold > 0 {
+			ipLockout := loginOptions.Lockout
+			ipLockout.Threshold = ipThreshold
+			loginOptions.Throttle = auth.NewThrottle(ipLockout)
+		}
+		if siemExporter != nil {
+			loginOptions.Audit = siemExporter
+		}
+		loginOptions.TOTPIssuer = getEnv("ADMIN_TOTP_ISSUER", "Gateway Admin")
+		tokens, err = auth.NewTokenIssuer(os.Getenv("ADMIN_AUTH_TOKEN_KEY"), ttl)
+		if err != nil {
+			logger.Fatal("invalid ADMIN_AUTH_TOKEN_KEY", zap.Error(err))
+		}
+	}
+	if authMode == "ldap" {
+		ldapCfg := ldap.Config{
+			URL:            os.Getenv("ADMIN_LDAP_URL"),
+			StartTLS:       getEnv("ADMIN_LDAP_START_TLS", "false") == "true",
+			BindDN:         os.Getenv("ADMIN_LDAP_BIND_DN"),
+			BindPassword:   os.Getenv("ADMIN_LDAP_BIND_PASSWORD"),
+			BaseDN:         os.Getenv("ADMIN_LDAP_BASE_DN"),
+			UserFilter:     os.Getenv("ADMIN_LDAP_USER_FILTER"),
+			GroupAttribute: os.Getenv("ADMIN_LDAP_GROUP_ATTRIBUTE"),
+			DefaultRole:    getEnv("ADMIN_LDAP_DEFAULT_ROLE", auth.RoleViewer),
+		}
+		if ldapCfg.DefaultRole == "none" {
+			ldapCfg.DefaultRole = ""
+		} else if !auth.ValidRole(ldapCfg.DefaultRole) {
+			logger.Fatal("invalid ADMIN_LDAP_DEFAULT_ROLE (must be viewer, editor, admin or none)", zap.String("role", ldapCfg.DefaultRole))
+		}
+		groupRoles, err := auth.ParseGroupRoles(os.Getenv("ADMIN_LDAP_GROUP_ROLES"))
+		if err != nil {
+			logger.Fatal("invalid ADMIN_LDAP_GROUP_ROLES", zap.Error(err))
+		}
+		ldapCfg.GroupRoles = groupRoles
+		if caFile := os.Getenv("ADMIN_LDAP_CA_FILE"); caFile != "" {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				logger.Fatal("failed to read ADMIN_LDAP_CA_FILE", zap.Error(err))
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				logger.Fatal("no certificate found in ADMIN_LDAP_CA_FILE", zap.String("file", caFile))
+			}
+			ldapCfg.TLS = &tls.Config{RootCAs: pool}
+		}
+		directory, err := ldap.New(ldapCfg)
+		if err != nil {
+			logger.Fatal("invalid LDAP configuration", zap.Error(err))
+		}
+		loginOptions.Directory = directory
+		logger.Info("signing in with LDAP", zap.String("url", ldapCfg.URL), zap.String("base_dn", ldapCfg.BaseDN))
 	}
-	defer store.Close()
 
 	// Build router
+	// /api/v1 is deprecated in favour of /api/v2 and served until the
+	// sunset date
+	v1Sunset, err := time.Parse(time.DateOnly, getEnv("ADMIN_API_V1_SUNSET", "2027-04-15"))
+	if err != nil {
+		logger.Fatal("invalid ADMIN_API_V1_SUNSET", zap.Error(err))
+	}
+
 	r := chi.NewRouter()
 
 	// Register middlewares (CORS must be first)
 	r.Use(middleware.CORSMiddleware)
 	r.Use(middleware.RequestLogger(logger))
+	r.Use(middleware.Metrics(metricsRegistry))
+	r.Use(middleware.EncodeYAML)
+	// Gateway endpoints and documents are the same in both API versions
+	r.Use(middleware.APIVersions(store.GetRevision, v1Sunset, "/api/v1/gateway/", "/api/v1/changes/wait", "/api/v1/gateways/register",
+		"/api/v1/gateways/heartbeat", "/api/v1/stats", "/api/v1/graphql", "/api/v1/export/"))
+	r.Use(middleware.Localize)
+	r.Use(middleware.EndpointLimits(endpointLimits, metricsRegistry, logger))
+	r.Use(middleware.DecodeYAML)
+	if tokens != nil {
+		r.Use(middleware.LocalAuth(tokens, userStore, logger))
+		r.Use(middleware.CSRF(tokens))
+	} else {
+		r.Use(middleware.HeaderIdentity)
+	}
+	if teamStore != nil {
+		r.Use(middleware.MemberTeams(teamStore.GetMemberTeams, logger))
+	}
+	if siemExporter != nil {
+		r.Use(middleware.AuditRefusals(siemExporter.AuditRequest))
+	}
 
 	// Create handlers
-	backendHandler := handler.NewBackendHandler(store, logger)
-	routeHandler := handler.NewRouteHandler(store, logger)
+	backendHandler := handler.NewBackendHandler(configStore, admissionChain, logger)
+	descriptors, _ := store.(config.DescriptorStore)
+	rolloutStore, _ := store.(config.RolloutStore)
+	routeHandler := handler.NewRouteHandler(configStore, descriptors, admissionChain, warnings, logger)
+	exportHandler := handler.NewExportHandler(configStore, logger)
 	historyHandler := handler.NewHistoryHandler(store, logger)
+	applyHandler := handler.NewApplyHandler(configStore, admissionChain, runner, logger)
+	restoreHandler := handler.NewRestoreHandler(configStore, admissionChain, logger)
+	gatewayHandler := handler.NewGatewayHandler(store, signer, configCache, logger)
+	maintenanceHandler := handler.NewMaintenanceHandler(readOnly, logger)
+
+	// Optional publishing of the compiled config to etcd and Consul KV, for
+	// gateways that watch them
+	var publishers []*publish.Publisher
+	if endpoints := os.Getenv("ADMIN_ETCD_ENDPOINTS"); endpoints != "" {
+		etcd, err := publish.NewEtcdClient(publish.EtcdConfig{
+			Endpoints: strings.Split(endpoints, ","),
+			Prefix:    getEnv("ADMIN_ETCD_PREFIX", "/gateway-admin/"),
+			Username:  os.Getenv("ADMIN_ETCD_USERNAME"),
+			Password:  os.Getenv("ADMIN_ETCD_PASSWORD"),
+		})
+		if err != nil {
+			logger.Fatal("invalid etcd configuration", zap.Error(err))
+		}
+		publishers = append(publishers, newPublisher(etcd, gatewayHandler, logger))
+	}
+	if addr := os.Getenv("ADMIN_CONSUL_ADDR"); addr != "" {
+		consul, err := publish.NewConsulClient(publish.ConsulConfig{
+			Addr:       addr,
+			Prefix:     getEnv("ADMIN_CONSUL_PREFIX", "gateway-admin/"),
+			Token:      os.Getenv("ADMIN_CONSUL_TOKEN"),
+			Datacenter: os.Getenv("ADMIN_CONSUL_DATACENTER"),
+		})
+		if err != nil {
+			logger.Fatal("invalid Consul configuration", zap.Error(err))
+		}
+		publishers = append(publishers, newPublisher(consul, gatewayHandler, logger))
+	}
+	for _, p := range publishers {
+		go p.Run(ctx, broker)
+	}
+	publishHandler := handler.NewPublishHandler(publishers, logger)
+	freezeHandler := handler.NewFreezeHandler(store, logger)
+	latencyHandler := handler.NewLatencyHandler(store, logger)
+	statsHandler := handler.NewStatsHandler(store, logger)
+	reportHandler := handler.NewReportHandler(store, logger)
+	graphqlHandler, err := handler.NewGraphQLHandler(store, logger)
+	if err != nil {
+		logger.Fatal("failed to build graphql schema", zap.Error(err))
+	}
+	liveHandler := handler.NewLiveHandler(configStore, broker, strings.Split(getEnv("CORS_ALLOWED_ORIGINS", "*"), ","), logger)
+	historyTailHandler := handler.NewHistoryTailHandler(store, broker, strings.Split(getEnv("CORS_ALLOWED_ORIGINS", "*"), ","), logger)
+
+	// Destructive operations optionally require a second factor from local
+	// users
+	var loginHandler *handler.LoginHandler
+	stepUp := func(next http.Handler) http.Handler { return next }
+	if tokens != nil {
+		loginHandler = handler.NewLoginHandler(userStore, tokens, loginOptions, logger)
+		if getEnv("ADMIN_TOTP_STEP_UP", "false") == "true" {
+			stepUp = middleware.RequireSecondFactor(loginHandler.VerifySecondFactor, logger)
+		}
+	}
+
+	// The GitOps webhook comes from the Git host, which proves itself with
+	// its signature rather than by signing in
+	var webhooks []string
+	if syncer != nil && syncer.WebhookSecret() != "" {
+		webhooks = append(webhooks, "/api/v1/gitops/sync")
+	}
 
 	// Register API routes
 	r.Route("/api/v1", func(r chi.Router) {
+		if breaker != nil {
+			r.Use(middleware.Breaker(breaker, breakerCooldown))
+		}
+		r.Use(middleware.ReadOnly(readOnly, "/api/v1/maintenance/read-only", "/api/v1/graphql", "/api/v1/stats", "/api/v1/gateway/config-status",
+			"/api/v1/gateways/register", "/api/v1/gateways/heartbeat", "/api/v1/auth/login", "/api/v1/auth/refresh"))
+		if tokens != nil {
+			// Gateway endpoints authenticate with the gateway token instead
+			r.Use(middleware.RequireSignIn(append([]string{"/api/v1/auth/login", "/api/v1/auth/refresh", "/api/v1/gateway/", "/api/v1/changes/wait",
+				"/api/v1/gateways/register", "/api/v1/gateways/heartbeat", "/api/v1/stats"}, webhooks...)...))
+			r.Use(middleware.RequirePasswordChange("/api/v1/auth/password", "/api/v1/auth/me"))
+		}
+		// Viewers may only read, besides signing in and managing their own
+		// sessions. GraphQL queries are read-only, and gateway endpoints
+		// authenticate with the gateway token instead
+		r.Use(middleware.RequireRole(auth.RoleEditor, append([]string{"/api/v1/auth/", "/api/v1/sessions", "/api/v1/sessions/", "/api/v1/graphql",
+			"/api/v1/gateway/", "/api/v1/changes/wait", "/api/v1/gateways/register", "/api/v1/gateways/heartbeat", "/api/v1/stats"}, webhooks...)...))
+
+		// Local users and signing in
+		if tokens != nil {
+			r.Post("/auth/login", loginHandler.Login)
+			r.Post("/auth/refresh", loginHandler.Refresh)
+			r.Post("/auth/password", loginHandler.ChangePassword)
+			r.Get("/auth/me", loginHandler.Me)
+			r.Get("/auth/totp", loginHandler.GetTOTP)
+			r.Post("/auth/totp", loginHandler.EnrollTOTP)
+			r.Post("/auth/totp/confirm", loginHandler.ConfirmTOTP)
+			r.Post("/auth/totp/recovery-codes", loginHandler.RegenerateRecoveryCodes)
+			r.Post("/auth/totp/disable", loginHandler.DisableTOTP)
+			r.Get("/sessions", loginHandler.ListSessions)
+			r.Delete("/sessions", loginHandler.RevokeSessions)
+			r.Delete("/sessions/{id}", loginHandler.RevokeSession)
+
+			userHandler := handler.NewUserHandler(userStore, loginOptions.Audit, logger)
+			r.With(middleware.RequireAdmin).Get("/users", userHandler.ListUsers)
+			r.With(middleware.RequireAdmin).Post("/users", userHandler.CreateUser)
+			r.With(middleware.RequireAdmin).Get("/users/{name}", userHandler.GetUser)
+			r.With(middleware.RequireAdmin).Put("/users/{name}", userHandler.UpdateUser)
+			r.With(middleware.RequireAdmin).Put("/users/{name}/password", userHandler.SetPassword)
+			r.With(middleware.RequireAdmin).Post("/users/{name}/unlock", userHandler.UnlockUser)
+			r.With(middleware.RequireAdmin).Delete("/users/{name}/totp", userHandler.ResetTOTP)
+			r.With(middleware.RequireAdmin, stepUp).Delete("/users/{name}", userHandler.DeleteUser)
+		}
+
+		// Maintenance
+		r.Get("/maintenance/read-only", maintenanceHandler.GetReadOnly)
+		r.With(middleware.RequireAdmin).Put("/maintenance/read-only", maintenanceHandler.SetReadOnly)
+
+		// Change freeze windows
+		r.Get("/freeze-windows", freezeHandler.ListFreezeWindows)
+		r.With(middleware.RequireAdmin).Post("/freeze-windows", freezeHandler.CreateFreezeWindow)
+		r.With(middleware.RequireAdmin).Put("/freeze-windows/{id}", freezeHandler.UpdateFreezeWindow)
+		r.With(middleware.RequireAdmin).Delete("/freeze-windows/{id}", freezeHandler.DeleteFreezeWindow)
+
 		// Backend management
 		r.Get("/backends", backendHandler.ListBackends)
 		r.Get("/backends/{name}", backendHandler.GetBackend)
 		r.Post("/backends", backendHandler.CreateBackend)
 		r.Put("/backends/{name}", backendHandler.UpdateBackend)
-		r.Delete("/backends/{name}", backendHandler.DeleteBackend)
+		r.With(stepUp).Delete("/backends/{name}", backendHandler.DeleteBackend)
+		r.Post("/backends/{name}/undo", backendHandler.UndoBackend)
+		r.Post("/backends/{name}/drain", backendHandler.DrainBackend)
+		r.Delete("/backends/{name}/drain", backendHandler.UndrainBackend)
+		r.Get("/backends/{name}/latency", latencyHandler.GetLatency)
+		r.Get("/backends/{name}/history", historyHandler.GetBackendHistory)
+		r.Post("/backends/{name}/routes:propose", routeHandler.ProposeRoutes)
 
 		// Route management
 		r.Get("/routes", routeHandler.ListRoutes)
 		r.Get("/routes/{id}", routeHandler.GetRoute)
 		r.Post("/routes", routeHandler.CreateRoute)
+		r.Post("/routes:bulk", routeHandler.CreateRoutes)
+		r.Post("/routes:reassign", routeHandler.ReassignRoutes)
 		r.Put("/routes/{id}", routeHandler.UpdateRoute)
-		r.Delete("/routes/{id}", routeHandler.DeleteRoute)
+		r.With(stepUp).Delete("/routes/{id}", routeHandler.DeleteRoute)
+		r.Post("/routes/{id}/undo", routeHandler.UndoRoute)
+		r.Patch("/routes/{id}/rollout", routeHandler.SetRouteRollout)
+		r.Get("/routes/{id}/stats", statsHandler.GetRouteStats)
+		r.Get("/routes/{id}/history", historyHandler.GetRouteHistory)
+		r.Get("/middlewares", routeHandler.ListMiddlewares)
+
+		// Traffic reports
+		r.Get("/reports/top-routes", reportHandler.TopRoutes)
+		r.Get("/reports/deprecated-routes", reportHandler.DeprecatedRoutes)
 
 		// Configuration history
 		r.Get("/history", historyHandler.ListHistory)
-	})
+		r.Get("/history/tail", historyTailHandler.Tail)
+		r.Get("/history/verify", restoreHandler.VerifyHistory)
+		r.With(middleware.RequireAdmin, stepUp).Post("/history/rebuild", restoreHandler.RebuildFromHistory)
+
+		// Read-only GraphQL queries
+		r.Get("/graphql", graphqlHandler.Query)
+		r.Post("/graphql", graphqlHandler.Query)
+
+		// Declarative configuration
+		r.Post("/apply", applyHandler.Apply)
+		r.With(stepUp).Post("/restore/point-in-time", restoreHandler.RestorePointInTime)
+
+		// API documentation for gateway consumers
+		r.Get("/export/openapi", exportHandler.ExportOpenAPI)
+
+		// Config file for file-based gateway deployments
+		r.Get("/export/gateway-file", gatewayHandler.ExportFile)
+
+		// Gateways whose loaded config is not the current one
+		r.Get("/gateways/drift", gatewayHandler.ListDrift)
+		r.Get("/versions/{rev}/propagation", gatewayHandler.GetPropagation)
+
+		// Publishing of the config to etcd and Consul KV
+		r.Get("/publishers", publishHandler.ListPublishers)
+
+		// Delivery of change events from the outbox, for stores that keep one
+		if outboxStore != nil {
+			outboxHandler := handler.NewOutboxHandler(store, outboxStore, logger)
+			r.Get("/outbox/sinks", outboxHandler.ListSinks)
+		}
+
+		// Registry of running gateway instances, for stores that keep it
+		var registryHandler *handler.RegistryHandler
+		if gatewayStore != nil {
+			registryHandler = handler.NewRegistryHandler(gatewayStore, rolloutStore, heartbeatInterval, logger)
+			r.Get("/gateways", registryHandler.ListGateways)
+		}
+
+		// Config rollouts to a subset of gateways, for stores that keep them
+		if rolloutStore != nil {
+			rolloutHandler := handler.NewRolloutHandler(configStore, rolloutStore, gatewayStore, admissionChain, logger)
+			r.Get("/rollouts", rolloutHandler.ListRollouts)
+			r.Post("/rollouts", rolloutHandler.CreateRollout)
+			r.Get("/rollouts/{id}", rolloutHandler.GetRollout)
+			r.Post("/rollouts/{id}/promote", rolloutHandler.PromoteRollout)
+			r.Post("/rollouts/{id}/abort", rolloutHandler.AbortRollout)
+		}
+
+		// Named configuration snapshots, for stores that keep them
+		if snapshots, ok := store.(config.SnapshotStore); ok {
+			snapshotHandler := handler.NewSnapshotHandler(configStore, snapshots, admissionChain, runner, logger)
+			r.Get("/snapshots", snapshotHandler.ListSnapshots)
+			r.Post("/snapshots", snapshotHandler.CreateSnapshot)
+			r.Get("/snapshots/{id}", snapshotHandler.GetSnapshot)
+			r.With(stepUp).Post("/snapshots/{id}/restore", snapshotHandler.RestoreSnapshot)
+			r.With(middleware.RequireAdmin, stepUp).Delete("/snapshots/{id}", snapshotHandler.DeleteSnapshot)
+		}
+
+		// Protobuf descriptor registry, for stores that keep descriptor sets
+		if descriptors != nil {
+			descriptorHandler := handler.NewDescriptorHandler(configStore, descriptors, logger)
+			r.Get("/backends/{name}/descriptors", descriptorHandler.ListDescriptors)
+			r.Post("/backends/{name}/descriptors", descriptorHandler.UploadDescriptors)
+			r.Get("/backends/{name}/descriptors/{version}", descriptorHandler.GetDescriptors)
+			r.Get("/backends/{name}/descriptors/{version}/download", descriptorHandler.DownloadDescriptors)
+		}
+
+		// Route request/response schemas, for stores that keep them
+		if schemas, ok := store.(config.SchemaStore); ok {
+			schemaHandler := handler.NewSchemaHandler(configStore, schemas, descriptors, logger)
+			r.Get("/routes/{id}/schemas", schemaHandler.ListRouteSchemas)
+			r.Post("/routes/{id}/schemas", schemaHandler.SetRouteSchemas)
+			r.Get("/routes/{id}/schemas/{version}", schemaHandler.GetRouteSchema)
+		}
+
+		// Plugin config schemas, for stores that keep them
+		if pluginSchemas != nil {
+			pluginHandler := handler.NewPluginHandler(pluginSchemas, logger)
+			r.Get("/plugins", pluginHandler.ListPluginSchemas)
+			r.Get("/plugins/{name}/schema", pluginHandler.GetPluginSchema)
+			r.With(middleware.RequireAdmin).Put("/plugins/{name}/schema", pluginHandler.SetPluginSchema)
+			r.With(middleware.RequireAdmin).Delete("/plugins/{name}/schema", pluginHandler.DeletePluginSchema)
+		}
+
+		// Teams owning backends and routes, for stores that keep them
+		if teamStore != nil {
+			teamHandler := handler.NewTeamHandler(store, teamStore, logger)
+			r.Get("/teams", teamHandler.ListTeams)
+			r.With(middleware.RequireAdmin).Post("/teams", teamHandler.CreateTeam)
+			r.Get("/teams/{name}", teamHandler.GetTeam)
+			r.With(middleware.RequireAdmin).Put("/teams/{name}", teamHandler.UpdateTeam)
+			r.With(middleware.RequireAdmin).Delete("/teams/{name}", teamHandler.DeleteTeam)
+			r.Get("/teams/{name}/members", teamHandler.ListTeamMembers)
+			r.With(middleware.RequireAdmin).Post("/teams/{name}/members", teamHandler.AddTeamMember)
+			r.With(middleware.RequireAdmin).Delete("/teams/{name}/members/{member}", teamHandler.RemoveTeamMember)
+		}
+
+		// Encryption key rotation, for stores that encrypt secrets
+		if encrypter, ok := store.(config.Encrypter); ok {
+			encryptionHandler := handler.NewEncryptionHandler(encrypter, runner, logger)
+			r.Post("/encryption/rotate", encryptionHandler.RotateKeys)
+		}
+
+		// Background jobs, for stores that keep them
+		if runner != nil {
+			jobHandler := handler.NewJobHandler(jobStore, runner, logger)
+			r.Get("/jobs", jobHandler.ListJobs)
+			r.Get("/jobs/{id}", jobHandler.GetJob)
+			r.Post("/jobs/{id}/retry", jobHandler.RetryJob)
+		}
+
+		// Gateway-facing endpoints, authenticated with a shared token
+		r.Get("/gateway/signing-key", gatewayHandler.GetSigningKey)
+		if gatewayToken := os.Getenv("ADMIN_GATEWAY_TOKEN"); gatewayToken != "" {
+			waitTimeout, err := time.ParseDuration(getEnv("ADMIN_CHANGES_WAIT_TIMEOUT", "30s"))
+			if err != nil || waitTimeout <= 0 {
+				logger.Fatal("invalid ADMIN_CHANGES_WAIT_TIMEOUT", zap.Error(err))
+			}
+			changesHandler := handler.NewChangesHandler(gatewayHandler, broker, waitTimeout, logger)
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.GatewayAuth(gatewayToken))
+				r.Get("/gateway/config", gatewayHandler.GetConfig)
+				r.Get("/gateway/config/compiled", gatewayHandler.GetCompiledConfig)
+				r.Get("/gateway/credentials", gatewayHandler.ListCredentials)
+				r.Get("/gateway/descriptors/{name}", gatewayHandler.GetDescriptors)
+				r.Post("/gateway/config-status", gatewayHandler.ReportConfig)
+				r.Get("/changes/wait", changesHandler.Wait)
+				if registryHandler != nil {
+					r.Post("/gateways/register", registryHandler.Register)
+					r.Post("/gateways/heartbeat", registryHandler.Heartbeat)
+				}
+				r.Post("/stats", statsHandler.Push)
+			})
+		}
+
+		// Development helpers, never enabled in production
+		if getEnv("ADMIN_DEV_ENDPOINTS", "false") == "true" {
+			devHandler := handler.NewDevHandler(configStore, admissionChain, logger)
+			r.With(middleware.RequireAdmin).Post("/dev/seed", devHandler.Seed)
+			logger.Warn("development endpoints enabled")
+		}
 
-	// Health check endpoint
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok"}`))
+		if backups != nil {
+			backupHandler := handler.NewBackupHandler(backups, logger)
+			r.Get("/backups", backupHandler.ListBackups)
+			r.Get("/backups/{name}", backupHandler.GetBackup)
+		}
+
+		if syncer != nil {
+			gitopsHandler := handler.NewGitOpsHandler(syncer, logger)
+			r.Get("/gitops/status", gitopsHandler.GetStatus)
+			r.Post("/gitops/sync", gitopsHandler.TriggerSync)
+			if tokens == nil && syncer.WebhookSecret() == "" {
+				logger.Warn("GitOps webhook accepts unsigned requests: set ADMIN_GITOPS_WEBHOOK_SECRET unless the authenticating proxy guards it")
+			}
+		}
 	})
 
+	// SCIM provisioning of local users by an identity provider,
+	// authenticated with a shared token
+	if scimToken := os.Getenv("ADMIN_SCIM_TOKEN"); scimToken != "" {
+		groupStore, _ := store.(config.GroupStore)
+		if tokens == nil || groupStore == nil {
+			logger.Fatal("ADMIN_SCIM_TOKEN requires ADMIN_AUTH_MODE local or ldap and a store that keeps users")
+		}
+		scimOptions := handler.SCIMOptions{DefaultRole: getEnv("ADMIN_SCIM_DEFAULT_ROLE", auth.RoleViewer)}
+		if !auth.ValidRole(scimOptions.DefaultRole) {
+			logger.Fatal("invalid ADMIN_SCIM_DEFAULT_ROLE", zap.String("role", scimOptions.DefaultRole))
+		}
+		if scimOptions.GroupRoles, err = auth.ParseGroupRoles(os.Getenv("ADMIN_SCIM_GROUP_ROLES")); err != nil {
+			logger.Fatal("invalid ADMIN_SCIM_GROUP_ROLES", zap.Error(err))
+		}
+		if scimOptions.GroupTeams, err = auth.ParseGroupTeams(os.Getenv("ADMIN_SCIM_GROUP_TEAMS")); err != nil {
+			logger.Fatal("invalid ADMIN_SCIM_GROUP_TEAMS", zap.Error(err))
+		}
+		for _, team := range scimOptions.GroupTeams.Managed() {
+			if err := config.ValidateTeamName(team); err != nil {
+				logger.Fatal("invalid ADMIN_SCIM_GROUP_TEAMS", zap.Error(err))
+			}
+		}
+		if len(scimOptions.GroupTeams) > 0 && teamStore == nil {
+			logger.Fatal("ADMIN_SCIM_GROUP_TEAMS requires a store that keeps teams")
+		}
+		scimHandler := handler.NewSCIMHandler(userStore, groupStore, teamStore, scimOptions, logger)
+		r.Route("/scim/v2", func(r chi.Router) {
+			r.Use(middleware.BearerAuth(scimToken))
+			r.Get("/ServiceProviderConfig", scimHandler.GetServiceProviderConfig)
+			r.Get("/ResourceTypes", scimHandler.ListResourceTypes)
+			r.Get("/Users", scimHandler.ListUsers)
+			r.Post("/Users", scimHandler.CreateUser)
+			r.Get("/Users/{id}", scimHandler.GetUser)
+			r.Put("/Users/{id}", scimHandler.ReplaceUser)
+			r.Patch("/Users/{id}", scimHandler.PatchUser)
+			r.Delete("/Users/{id}", scimHandler.DeleteUser)
+			r.Get("/Groups", scimHandler.ListGroups)
+			r.Post("/Groups", scimHandler.CreateGroup)
+			r.Get("/Groups/{id}", scimHandler.GetGroup)
+			r.Put("/Groups/{id}", scimHandler.ReplaceGroup)
+			r.Patch("/Groups/{id}", scimHandler.PatchGroup)
+			r.Delete("/Groups/{id}", scimHandler.DeleteGroup)
+		})
+	}
+
+	// Jobs are run once every handler has registered its kinds
+	if runner != nil {
+		go runner.Run(ctx)
+	}
+
+	// Live updates for the admin UI
+	r.Get("/ws", liveHandler.Serve)
+
+	// Health check and metrics endpoints, on the management listener if
+	// there is one
+	var pinger handler.Pinger
+	if p, ok := store.(config.Pinger); ok {
+		pinger = p
+	}
+	healthHandler := handler.NewHealthHandler(pinger, logger)
+	if breaker != nil {
+		healthHandler.SetBreaker(breaker)
+	}
+	mgmtAddr := os.Getenv("ADMIN_MGMT_LISTEN")
+	if mgmtAddr == "" {
+		r.Get("/health", healthHandler.Live)
+		r.Get("/health/live", healthHandler.Live)
+		r.Get("/health/ready", healthHandler.Ready)
+		r.Method(http.MethodGet, "/metrics", metricsRegistry.Handler())
+	}
+
+	// Optional gRPC ConfigService, over which gateways subscribe to their
+	// config instead of polling for it: on its own listener, and on the
+	// admin listener over h2c
+	h2c := getEnv("ADMIN_H2C", "true") == "true"
+	grpcAddr := os.Getenv("ADMIN_GRPC_ADDR")
+	gatewayToken := os.Getenv("ADMIN_GATEWAY_TOKEN")
+	var grpcServer *grpc.Server
+	if grpcAddr != "" || h2c && gatewayToken != "" {
+		if gatewayToken == "" {
+			logger.Fatal("ADMIN_GATEWAY_TOKEN is required for ADMIN_GRPC_ADDR")
+		}
+		resync, err := time.ParseDuration(getEnv("ADMIN_GRPC_RESYNC_INTERVAL", "30s"))
+		if err != nil || resync < time.Second {
+			logger.Fatal("invalid ADMIN_GRPC_RESYNC_INTERVAL (at least 1s)", zap.Error(err))
+		}
+		grpcServer = grpc.NewServer()
+		configv1.RegisterConfigServiceServer(grpcServer, configsvc.NewServer(gatewayHandler.ServedPayload, gatewayStore, signer, broker, gatewayToken, resync, logger))
+	}
+
+	shutdownDelay, err := time.ParseDuration(getEnv("ADMIN_SHUTDOWN_DELAY", "0s"))
+	if err != nil || shutdownDelay < 0 {
+		logger.Fatal("invalid ADMIN_SHUTDOWN_DELAY", zap.Error(err))
+	}
+	shutdownTimeout, err := time.ParseDuration(getEnv("ADMIN_SHUTDOWN_TIMEOUT", "10s"))
+	if err != nil || shutdownTimeout <= 0 {
+		logger.Fatal("invalid ADMIN_SHUTDOWN_TIMEOUT", zap.Error(err))
+	}
+
 	// Create HTTP server
 	srv := &http.Server{
 		Addr:         listenAddr,
@@ -88,27 +1054,161 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	if h2c {
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetUnencryptedHTTP2(true)
+		if grpcServer != nil {
+			srv.Handler = withGRPC(grpcServer, r, logger)
+		}
+	}
+
+	// Listen before serving so that readiness is only reported once the
+	// port is bound
+	ln, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		logger.Fatal("failed to listen", zap.String("addr", listenAddr), zap.Error(err))
+	}
 
 	// Graceful shutdown
 	go func() {
-		logger.Info("admin service listening", zap.String("addr", listenAddr))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Info("admin service listening", zap.String("addr", listenAddr), zap.Bool("h2c", h2c))
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("HTTP server error", zap.Error(err))
 		}
 	}()
 
+	// Optional management listener for probes, metrics and profiling,
+	// outside the API's authentication and access logs
+	var mgmtSrv *http.Server
+	if mgmtAddr != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /health", healthHandler.Live)
+		mux.HandleFunc("GET /health/live", healthHandler.Live)
+		mux.HandleFunc("GET /health/ready", healthHandler.Ready)
+		mux.Handle("GET /metrics", metricsRegistry.Handler())
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		// Profiles and traces run for as long as ?seconds= asks
+		mgmtSrv = &http.Server{Addr: mgmtAddr, Handler: mux, ReadTimeout: 15 * time.Second, IdleTimeout: 60 * time.Second}
+		mgmtLn, err := net.Listen("tcp", mgmtAddr)
+		if err != nil {
+			logger.Fatal("failed to listen", zap.String("addr", mgmtAddr), zap.Error(err))
+		}
+		go func() {
+			logger.Info("management listening", zap.String("addr", mgmtAddr))
+			if err := mgmtSrv.Serve(mgmtLn); err != nil && err != http.ErrServerClosed {
+				logger.Fatal("management server error", zap.Error(err))
+			}
+		}()
+	}
+
+	if grpcAddr != "" {
+		grpcLn, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			logger.Fatal("failed to listen", zap.String("addr", grpcAddr), zap.Error(err))
+		}
+		go func() {
+			logger.Info("config service listening", zap.String("addr", grpcAddr))
+			if err := grpcServer.Serve(grpcLn); err != nil {
+				logger.Fatal("gRPC server error", zap.Error(err))
+			}
+		}()
+	}
+
+	// systemd Type=notify support; a no-op outside systemd
+	if ok, err := systemd.Notify(systemd.Ready); err != nil {
+		logger.Warn("failed to notify systemd", zap.Error(err))
+	} else if ok {
+		logger.Info("notified systemd of readiness", zap.Duration("watchdog", systemd.WatchdogInterval()))
+	}
+	go systemd.RunWatchdog(ctx, func(err error) {
+		logger.Warn("systemd watchdog ping failed", zap.Error(err))
+	})
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
 
 	logger.Info("shutting down admin service...")
+	healthHandler.SetDraining()
+	systemd.Notify(systemd.Stopping)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// Keep serving while load balancers see the instance is not ready,
+	// closing idle keep-alive connections so that clients reconnect
+	// elsewhere
+	if shutdownDelay > 0 {
+		srv.SetKeepAlivesEnabled(false)
+		logger.Info("draining connections", zap.Duration("delay", shutdownDelay))
+		time.Sleep(shutdownDelay)
+	}
+
+	cancel()
+
+	// Subscriptions never end on their own; gateways reconnect elsewhere
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("requests still in flight after ADMIN_SHUTDOWN_TIMEOUT, closing connections", zap.Error(err))
+		if err := srv.Close(); err != nil {
+			logger.Error("HTTP server close error", zap.Error(err))
+		}
+	}
+	// Probes are answered until the API has drained
+	if mgmtSrv != nil {
+		if err := mgmtSrv.Close(); err != nil {
+			logger.Error("management server close error", zap.Error(err))
+		}
+	}
+}
+
+// withGRPC serves the gRPC requests of HTTP/2 connections with grpcServer,
+// and others with next. gRPC streams outlive the server's timeouts, which
+// are lifted for them.
+func withGRPC(grpcServer *grpc.Server, next http.Handler, logger *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rc := http.NewResponseController(w)
+		if err := rc.SetReadDeadline(time.Time{}); err != nil {
+			logger.Warn("failed to lift read deadline", zap.Error(err))
+		}
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			logger.Warn("failed to lift write deadline", zap.Error(err))
+		}
+		grpcServer.ServeHTTP(w, r)
+	})
+}
+
+// newPublisher creates a publisher of the config served by gatewayHandler
+// to target, configured from the environment.
+func newPublisher(target publish.Target, gatewayHandler *handler.GatewayHandler, logger *zap.Logger) *publish.Publisher {
+	interval, err := time.ParseDuration(getEnv("ADMIN_PUBLISH_INTERVAL", "30s"))
+	if err != nil || interval < time.Second {
+		logger.Fatal("invalid ADMIN_PUBLISH_INTERVAL (at least 1s)", zap.Error(err))
+	}
+	var clusters []string
+	if list := os.Getenv("ADMIN_PUBLISH_CLUSTERS"); list != "" {
+		clusters = strings.Split(list, ",")
+		if err := config.ValidateClusters(clusters); err != nil {
+			logger.Fatal("invalid ADMIN_PUBLISH_CLUSTERS", zap.Error(err))
+		}
+	}
 
-	if err := srv.Shutdown(ctx); err != nil {
-		logger.Error("HTTP server shutdown error", zap.Error(err))
+	payload := func(ctx context.Context, cluster string) ([]byte, error) {
+		return gatewayHandler.ServedPayload(ctx, "", cluster)
 	}
+	return publish.NewPublisher(target, payload, clusters, interval, logger)
 }
 
 func getEnv(key, defaultValue string) string {
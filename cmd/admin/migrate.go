@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+const migrateUsage = `Usage: admin migrate <up|down|status|to N>
+
+  up        Apply all pending migrations
+  down      Roll back the most recently applied migration
+  status    List migrations and whether they are applied
+  to N      Migrate up or down to version N (0 rolls back everything)
+`
+
+// migrateCommand manages schema migrations independently of the service.
+func migrateCommand(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	fs.Parse(args)
+	args = fs.Args()
+
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	logger := newLogger()
+	defer logger.Sync()
+
+	store := connectStore(logger, os.Getenv("ADMIN_DB_DSN"))
+	defer closeStore(store)
+
+	migrator, ok := store.(config.Migrator)
+	if !ok {
+		logger.Fatal("store driver does not support migrations", zap.String("driver", getEnv("ADMIN_DB_DRIVER", "mysql")))
+	}
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Migrate(); err != nil {
+			logger.Fatal("migration failed", zap.Error(err))
+		}
+	case "down":
+		version, err := migrator.MigrateDown()
+		if err != nil {
+			logger.Fatal("rollback failed", zap.Error(err))
+		}
+		if version == 0 {
+			fmt.Println("no applied migrations to roll back")
+			return
+		}
+		fmt.Printf("rolled back migration %d\n", version)
+	case "to":
+		if len(args) != 2 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n", args[1])
+			os.Exit(2)
+		}
+		if err := migrator.MigrateTo(version); err != nil {
+			logger.Fatal("migration failed", zap.Error(err))
+		}
+	case "status":
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	printMigrationStatus(migrator.MigrationStatus())
+}
+
+func printMigrationStatus(states []config.MigrationState, err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read migration status: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, st := range states {
+		status, at := "pending", ""
+		if st.Applied {
+			status, at = "applied", st.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, status, at)
+	}
+	w.Flush()
+}
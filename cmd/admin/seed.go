@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/apply"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/seed"
+)
+
+// seedCommand loads the sample configuration. Existing resources are left
+// untouched, so it is safe to run more than once.
+func seedCommand(args []string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "print what would be created without writing")
+	operator := fs.String("operator", "seed", "operator recorded in history")
+	fs.Parse(args)
+
+	logger := newLogger()
+	defer logger.Sync()
+
+	store := openStore(logger, os.Getenv("ADMIN_DB_DSN"))
+	defer closeStore(store)
+
+	plan, err := seed.Plan(store)
+	if err != nil {
+		logger.Fatal("failed to plan seed", zap.Error(err))
+	}
+
+	printPlan(plan)
+	if *dryRun || !plan.HasChanges() {
+		return
+	}
+
+	if err := apply.Execute(store, plan, apply.Actor{Operator: *operator, Reason: seed.Reason}); err != nil {
+		logger.Error("failed to seed", zap.Error(err))
+		os.Exit(1)
+	}
+	fmt.Println("applied")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// userCommand creates a local user, or sets the password of an existing
+// one, ending its sessions and lifting any lockout, e.g. to create the
+// first admin or recover its access, also turning off its two-factor
+// authentication with -reset-totp. The password is read from the
+// ADMIN_USER_PASSWORD environment variable or else the first line of
+// standard input.
+func userCommand(args []string) {
+	fs := flag.NewFlagSet("user", flag.ExitOnError)
+	name := fs.String("name", "", "user name (required)")
+	role := fs.String("role", auth.RoleAdmin, "role of a new user: viewer, editor or admin")
+	displayName := fs.String("display-name", "", "display name of a new user")
+	mustChange := fs.Bool("must-change-password", false, "require the user to change the password when signing in")
+	resetTOTP := fs.Bool("reset-totp", false, "turn off two-factor authentication of an existing user")
+	fs.Parse(args)
+
+	if err := config.ValidateUserName(*name); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if !auth.ValidRole(*role) {
+		fmt.Fprintln(os.Stderr, "invalid role (must be viewer, editor or admin)")
+		os.Exit(2)
+	}
+	password, ok := os.LookupEnv("ADMIN_USER_PASSWORD")
+	if !ok {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(os.Stderr, "no password given on standard input")
+			os.Exit(2)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if err := auth.ValidatePassword(password); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger := newLogger()
+	defer logger.Sync()
+
+	store := openStore(logger, os.Getenv("ADMIN_DB_DSN"))
+	defer closeStore(store)
+
+	users, ok := store.(config.UserStore)
+	if !ok {
+		logger.Fatal("store driver does not keep users", zap.String("driver", getEnv("ADMIN_DB_DRIVER", "mysql")))
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		logger.Fatal("failed to hash password", zap.Error(err))
+	}
+
+	existing, err := users.GetUser(*name)
+	if err != nil {
+		logger.Fatal("failed to get user", zap.Error(err))
+	}
+	if existing != nil {
+		now := time.Now().Truncate(time.Millisecond)
+		if err := users.SetUserPassword(*name, hash, *mustChange, now); err != nil {
+			logger.Fatal("failed to set password", zap.Error(err))
+		}
+		if err := users.RevokeUserSessions(*name, now); err != nil {
+			logger.Fatal("failed to revoke sessions", zap.Error(err))
+		}
+		if err := users.UnlockUser(*name); err != nil {
+			logger.Fatal("failed to unlock user", zap.Error(err))
+		}
+		if *resetTOTP {
+			if err := users.DisableUserTOTP(*name); err != nil {
+				logger.Fatal("failed to reset two-factor authentication", zap.Error(err))
+			}
+		}
+		fmt.Printf("password of %s set\n", *name)
+		return
+	}
+
+	user := config.User{
+		Name:               *name,
+		DisplayName:        *displayName,
+		Role:               *role,
+		PasswordHash:       hash,
+		MustChangePassword: *mustChange,
+		CreatedBy:          "cli",
+	}
+	if err := users.CreateUser(&user); err != nil {
+		logger.Fatal("failed to create user", zap.Error(err))
+	}
+	fmt.Printf("created %s user %s\n", user.Role, user.Name)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/apply"
+)
+
+// validationProblem is one finding of the validate command.
+type validationProblem struct {
+	File    string `json:"file,omitempty"`
+	Message string `json:"message"`
+}
+
+// validationReport is printed by the validate command.
+type validationReport struct {
+	Valid    bool                `json:"valid"`
+	Files    int                 `json:"files"`
+	Backends int                 `json:"backends"`
+	Routes   int                 `json:"routes"`
+	Problems []validationProblem `json:"problems"`
+}
+
+// validateCommand checks config files offline, without a database or API.
+// All arguments form one bundle, so routes may reference backends defined
+// in another file. It exits 1 if any problem is found.
+func validateCommand(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	format := fs.String("format", "text", "report format: text or json")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: admin validate [flags] <file|dir|->...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 || (*format != "text" && *format != "json") {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	report := validationReport{Problems: []validationProblem{}}
+	bundle := &apply.Document{}
+
+	for _, arg := range fs.Args() {
+		files := []string{arg}
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			if files, err = apply.DocumentFiles(arg); err != nil {
+				report.Problems = append(report.Problems, validationProblem{File: arg, Message: err.Error()})
+				continue
+			}
+		}
+
+		for _, file := range files {
+			report.Files++
+			if doc := validateFile(file, &report); doc != nil {
+				bundle.Merge(doc)
+			}
+		}
+	}
+
+	report.Backends = len(bundle.Backends)
+	report.Routes = len(bundle.Routes)
+	if err := bundle.Validate(); err != nil {
+		var verr *apply.ValidationError
+		if !errors.As(err, &verr) {
+			fatalf("validate: %v", err)
+		}
+		for _, p := range verr.Problems {
+			report.Problems = append(report.Problems, validationProblem{Message: p})
+		}
+	}
+	report.Valid = len(report.Problems) == 0
+
+	printValidationReport(report, *format)
+	if !report.Valid {
+		os.Exit(1)
+	}
+}
+
+// validateFile parses and lints a single file, recording problems.
+func validateFile(file string, report *validationReport) *apply.Document {
+	data, err := readInput(file)
+	if err != nil {
+		report.Problems = append(report.Problems, validationProblem{File: file, Message: err.Error()})
+		return nil
+	}
+
+	doc, err := apply.ParseDocument(data)
+	if err != nil {
+		report.Problems = append(report.Problems, validationProblem{File: file, Message: err.Error()})
+		return nil
+	}
+
+	problems, err := apply.Lint(data)
+	if err != nil {
+		report.Problems = append(report.Problems, validationProblem{File: file, Message: err.Error()})
+	}
+	for _, p := range problems {
+		report.Problems = append(report.Problems, validationProblem{File: file, Message: p})
+	}
+	return doc
+}
+
+func printValidationReport(report validationReport, format string) {
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+
+	for _, p := range report.Problems {
+		if p.File != "" {
+			fmt.Printf("%s: %s\n", p.File, p.Message)
+		} else {
+			fmt.Println(p.Message)
+		}
+	}
+	if report.Valid {
+		fmt.Printf("OK: %d files, %d backends, %d routes\n", report.Files, report.Backends, report.Routes)
+	} else {
+		fmt.Printf("%d problems found in %d files\n", len(report.Problems), report.Files)
+	}
+}
@@ -0,0 +1,79 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Request describes a proposed configuration change.
+type Request struct {
+	Operation  string      `json:"operation"`   // "CREATE", "UPDATE", "DELETE"
+	ConfigType string      `json:"config_type"` // "backend" or "route"
+	Operator   string      `json:"operator,omitempty"`
+	Role       string      `json:"operator_role,omitempty"`
+	Teams      []string    `json:"operator_teams,omitempty"`
+	Reason     string      `json:"change_reason,omitempty"`
+	Old        interface{} `json:"old,omitempty"`
+	New        interface{} `json:"new,omitempty"`
+
+	// FreezeOverride is set when the caller asks to bypass an active
+	// change freeze.
+	FreezeOverride bool `json:"freeze_override,omitempty"`
+
+	// Batch is the number of changes submitted together with this one,
+	// e.g. by a declarative apply; zero means a single change.
+	Batch int `json:"batch,omitempty"`
+}
+
+// Controller decides whether a proposed change may be persisted. It
+// returns a *DeniedError to reject the change; any other error means the
+// decision could not be made.
+type Controller interface {
+	Admit(ctx context.Context, req *Request) error
+}
+
+// Chain runs controllers in order and stops at the first rejection or
+// error. A nil Chain admits everything.
+type Chain []Controller
+
+// Admit implements Controller.
+func (c Chain) Admit(ctx context.Context, req *Request) error {
+	for _, ctrl := range c {
+		if err := ctrl.Admit(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeniedError is returned when a controller rejects a change.
+type DeniedError struct {
+	Source  string
+	Reasons []string
+}
+
+func (e *DeniedError) Error() string {
+	return e.Source + " denied change: " + strings.Join(e.Reasons, "; ")
+}
+
+// IsDenied reports whether err is a rejection rather than a failure.
+func IsDenied(err error) bool {
+	var denied *DeniedError
+	return errors.As(err, &denied) || IsQuotaExceeded(err)
+}
+
+// toInput converts a request to plain JSON values so that policies and
+// external checks see the same field names as the API.
+func toInput(req *Request) (interface{}, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var input interface{}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
@@ -0,0 +1,61 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// FreezeWindowSource lists the configured freeze windows.
+type FreezeWindowSource interface {
+	GetFreezeWindows() ([]config.FreezeWindow, error)
+}
+
+// FreezeController rejects changes while a freeze window is active. Admins
+// may push a change through by setting FreezeOverride on the request.
+type FreezeController struct {
+	windows FreezeWindowSource
+	now     func() time.Time
+}
+
+// NewFreezeController creates a FreezeController reading windows from src.
+func NewFreezeController(src FreezeWindowSource) *FreezeController {
+	return &FreezeController{windows: src, now: time.Now}
+}
+
+// Admit implements Controller.
+func (c *FreezeController) Admit(ctx context.Context, req *Request) error {
+	windows, err := c.windows.GetFreezeWindows()
+	if err != nil {
+		return fmt.Errorf("load freeze windows: %w", err)
+	}
+
+	now := c.now()
+	var reasons []string
+	for i := range windows {
+		w := &windows[i]
+		active, until := w.ActiveAt(now)
+		if !active {
+			continue
+		}
+		msg := fmt.Sprintf("change freeze %q is in effect until %s", w.Name, until.Format(time.RFC3339))
+		if w.Reason != "" {
+			msg += " (" + w.Reason + ")"
+		}
+		reasons = append(reasons, msg)
+	}
+
+	if len(reasons) == 0 {
+		return nil
+	}
+	if req.FreezeOverride {
+		if req.Role == auth.RoleAdmin {
+			return nil
+		}
+		return &DeniedError{Source: "change freeze", Reasons: append(reasons, "freeze_override requires the admin role")}
+	}
+	return &DeniedError{Source: "change freeze", Reasons: append(reasons, "admins may override with freeze_override=true")}
+}
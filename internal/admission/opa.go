@@ -0,0 +1,87 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+// PolicyQuery is evaluated against every change. Policies declare messages
+// in a deny set, for example:
+//
+//	package gateway.admin
+//
+//	deny contains msg if {
+//	    input.config_type == "route"
+//	    input.new.timeout_ms > 30000
+//	    msg := "route timeout must not exceed 30s"
+//	}
+const PolicyQuery = "data.gateway.admin.deny"
+
+// PolicyController evaluates changes against embedded OPA/Rego policies.
+type PolicyController struct {
+	query rego.PreparedEvalQuery
+}
+
+// NewPolicyController compiles every .rego file in dir.
+func NewPolicyController(ctx context.Context, dir string) (*PolicyController, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.rego"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .rego files found in %s", dir)
+	}
+
+	opts := []func(*rego.Rego){rego.Query(PolicyQuery)}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, rego.Module(file, string(data)))
+	}
+
+	query, err := rego.New(opts...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("compile policies: %w", err)
+	}
+
+	return &PolicyController{query: query}, nil
+}
+
+// Admit implements Controller.
+func (p *PolicyController) Admit(ctx context.Context, req *Request) error {
+	input, err := toInput(req)
+	if err != nil {
+		return err
+	}
+
+	results, err := p.query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return fmt.Errorf("evaluate policies: %w", err)
+	}
+
+	var reasons []string
+	for _, result := range results {
+		for _, expr := range result.Expressions {
+			msgs, ok := expr.Value.([]interface{})
+			if !ok {
+				continue
+			}
+			for _, msg := range msgs {
+				reasons = append(reasons, fmt.Sprint(msg))
+			}
+		}
+	}
+
+	if len(reasons) > 0 {
+		sort.Strings(reasons)
+		return &DeniedError{Source: "policy", Reasons: reasons}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// TeamSource looks up teams by name.
+type TeamSource interface {
+	GetTeam(name string) (*config.Team, error)
+}
+
+// OwnershipController rejects backends and routes assigned to teams that
+// do not exist. With enforcement on, it also restricts callers other than
+// admins to resources their teams own: both the current and the proposed
+// owner team must be among the caller's teams. Resources without an owner
+// team stay open to every editor, and requests without a role, such as
+// those of the command line tools, are not restricted.
+type OwnershipController struct {
+	teams   TeamSource
+	enforce bool
+}
+
+// NewOwnershipController creates an OwnershipController reading teams from
+// src, restricting editors to their teams' resources if enforce is set.
+func NewOwnershipController(src TeamSource, enforce bool) *OwnershipController {
+	return &OwnershipController{teams: src, enforce: enforce}
+}
+
+// Admit implements Controller.
+func (c *OwnershipController) Admit(ctx context.Context, req *Request) error {
+	oldTeam, newTeam := ownerTeam(req.Old), ownerTeam(req.New)
+
+	if req.New != nil && newTeam != "" && newTeam != oldTeam {
+		team, err := c.teams.GetTeam(newTeam)
+		if err != nil {
+			return fmt.Errorf("load team %s: %w", newTeam, err)
+		}
+		if team == nil {
+			return &DeniedError{Source: "team ownership", Reasons: []string{fmt.Sprintf("owner_team: team %q does not exist", newTeam)}}
+		}
+	}
+
+	if !c.enforce || req.Role == "" || req.Role == auth.RoleAdmin {
+		return nil
+	}
+	var reasons []string
+	if oldTeam != "" && !contains(req.Teams, oldTeam) {
+		reasons = append(reasons, fmt.Sprintf("%s is owned by team %q, which you are not a member of", req.ConfigType, oldTeam))
+	}
+	if req.New != nil && newTeam != "" && newTeam != oldTeam && !contains(req.Teams, newTeam) {
+		reasons = append(reasons, fmt.Sprintf("cannot assign %s to team %q, which you are not a member of", req.ConfigType, newTeam))
+	}
+	if len(reasons) > 0 {
+		return &DeniedError{Source: "team ownership", Reasons: reasons}
+	}
+	return nil
+}
+
+// ownerTeam returns the owner team of a backend or route.
+func ownerTeam(v interface{}) string {
+	switch v := v.(type) {
+	case *config.Backend:
+		if v != nil {
+			return v.OwnerTeam
+		}
+	case *config.Route:
+		if v != nil {
+			return v.OwnerTeam
+		}
+	case config.Backend:
+		return v.OwnerTeam
+	case config.Route:
+		return v.OwnerTeam
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
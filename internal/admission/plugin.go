@@ -0,0 +1,75 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/schema"
+)
+
+// PluginSchemaSource looks up the schema registered for a plugin.
+type PluginSchemaSource interface {
+	GetPluginSchema(name string) (*config.PluginSchema, error)
+}
+
+// PluginController rejects backends and routes whose plugin configs do not
+// match the schemas registered for their plugins. Plugins without a schema
+// accept any config.
+type PluginController struct {
+	schemas PluginSchemaSource
+}
+
+// NewPluginController creates a PluginController reading schemas from src.
+func NewPluginController(src PluginSchemaSource) *PluginController {
+	return &PluginController{schemas: src}
+}
+
+// Admit implements Controller.
+func (c *PluginController) Admit(ctx context.Context, req *Request) error {
+	var plugins map[string]json.RawMessage
+	switch v := req.New.(type) {
+	case *config.Backend:
+		if v != nil {
+			plugins = v.Plugins
+		}
+	case *config.Route:
+		if v != nil {
+			plugins = v.Plugins
+		}
+	}
+	if len(plugins) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var reasons []string
+	for _, name := range names {
+		s, err := c.schemas.GetPluginSchema(name)
+		if err != nil {
+			return fmt.Errorf("load schema of plugin %s: %w", name, err)
+		}
+		if s == nil {
+			continue
+		}
+		msg, err := schema.Match(ctx, s.Schema, plugins[name])
+		if err != nil {
+			return fmt.Errorf("check plugins.%s: %w", name, err)
+		}
+		if msg != "" {
+			reasons = append(reasons, fmt.Sprintf("plugins.%s: %s", name, msg))
+		}
+	}
+
+	if len(reasons) > 0 {
+		return &DeniedError{Source: "plugin schema", Reasons: reasons}
+	}
+	return nil
+}
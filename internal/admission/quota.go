@@ -0,0 +1,159 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Quota scopes.
+const (
+	QuotaPerOperator = "operator"
+	QuotaGlobal      = "global"
+)
+
+// Quota limits how many changes may be made within a sliding window, either
+// by each operator or by everyone together.
+type Quota struct {
+	Scope  string
+	Limit  int
+	Window time.Duration
+}
+
+func (q Quota) String() string {
+	return fmt.Sprintf("%s:%d/%s", q.Scope, q.Limit, formatWindow(q.Window))
+}
+
+// ParseQuotas parses a comma-separated list of quotas in the form
+// scope:limit/window, e.g. "operator:50/1h,global:200/24h".
+func ParseQuotas(s string) ([]Quota, error) {
+	var quotas []Quota
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		scope, rest, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("quota %q: want scope:limit/window", item)
+		}
+		if scope != QuotaPerOperator && scope != QuotaGlobal {
+			return nil, fmt.Errorf("quota %q: scope must be %q or %q", item, QuotaPerOperator, QuotaGlobal)
+		}
+		limitText, windowText, ok := strings.Cut(rest, "/")
+		if !ok {
+			return nil, fmt.Errorf("quota %q: want scope:limit/window", item)
+		}
+		limit, err := strconv.Atoi(limitText)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("quota %q: limit must be a positive integer", item)
+		}
+		window, err := time.ParseDuration(windowText)
+		if err != nil || window < time.Second {
+			return nil, fmt.Errorf("quota %q: window must be a duration of at least 1s", item)
+		}
+
+		quotas = append(quotas, Quota{Scope: scope, Limit: limit, Window: window})
+	}
+	return quotas, nil
+}
+
+// ChangeHistorySource reports when recent changes were made.
+type ChangeHistorySource interface {
+	HistoryAges(operator *string, window time.Duration) ([]time.Duration, error)
+}
+
+// QuotaController rejects changes that would exceed a change quota,
+// protecting against runaway automation. Changes are counted from config
+// history, so quotas hold across replicas and restarts.
+type QuotaController struct {
+	history ChangeHistorySource
+	quotas  []Quota
+}
+
+// NewQuotaController creates a QuotaController enforcing quotas.
+func NewQuotaController(src ChangeHistorySource, quotas []Quota) *QuotaController {
+	return &QuotaController{history: src, quotas: quotas}
+}
+
+// Admit implements Controller.
+func (c *QuotaController) Admit(ctx context.Context, req *Request) error {
+	requested := req.Batch
+	if requested < 1 {
+		requested = 1
+	}
+
+	for _, q := range c.quotas {
+		var operator *string
+		if q.Scope == QuotaPerOperator {
+			operator = &req.Operator
+		}
+
+		ages, err := c.history.HistoryAges(operator, q.Window)
+		if err != nil {
+			return fmt.Errorf("load change history: %w", err)
+		}
+		if len(ages)+requested <= q.Limit {
+			continue
+		}
+
+		qerr := &QuotaError{Quota: q, Operator: req.Operator, Used: len(ages), Requested: requested}
+		// Enough of the oldest changes must age out of the window to make
+		// room; a batch larger than the limit never fits.
+		if excess := len(ages) + requested - q.Limit; requested <= q.Limit {
+			qerr.RetryAfter = q.Window - ages[excess-1]
+		}
+		return qerr
+	}
+	return nil
+}
+
+// QuotaError is returned when a change would exceed a quota. It counts as
+// a rejection for IsDenied.
+type QuotaError struct {
+	Quota     Quota
+	Operator  string
+	Used      int
+	Requested int
+	// RetryAfter is how long until the change would fit, or zero if it
+	// never will.
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	who := "all operators"
+	if e.Quota.Scope == QuotaPerOperator {
+		who = fmt.Sprintf("operator %q", e.Operator)
+	}
+	msg := fmt.Sprintf("change quota exceeded: %s made %d of %d changes allowed per %s, %d more requested",
+		who, e.Used, e.Quota.Limit, formatWindow(e.Quota.Window), e.Requested)
+	if e.RetryAfter > 0 {
+		msg += "; retry in " + formatWindow(e.RetryAfter.Round(time.Second))
+	} else {
+		msg += "; split the change into smaller batches"
+	}
+	return msg
+}
+
+// IsQuotaExceeded reports whether err is a quota rejection.
+func IsQuotaExceeded(err error) bool {
+	var qerr *QuotaError
+	return errors.As(err, &qerr)
+}
+
+// formatWindow formats d without trailing zero units, e.g. "1h" rather
+// than "1h0m0s".
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
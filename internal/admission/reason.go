@@ -0,0 +1,17 @@
+package admission
+
+import (
+	"context"
+	"strings"
+)
+
+// RequireReason rejects changes that do not carry a change reason.
+type RequireReason struct{}
+
+// Admit implements Controller.
+func (RequireReason) Admit(ctx context.Context, req *Request) error {
+	if strings.TrimSpace(req.Reason) == "" {
+		return &DeniedError{Source: "change reason", Reasons: []string{"change_reason is required"}}
+	}
+	return nil
+}
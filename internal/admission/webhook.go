@@ -0,0 +1,91 @@
+package admission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// webhookResponse is the decision returned by a validation webhook.
+type webhookResponse struct {
+	Allowed bool     `json:"allowed"`
+	Reasons []string `json:"reasons,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+// WebhookController posts every proposed change to an external HTTP
+// endpoint, admission-controller style, and honours its decision.
+type WebhookController struct {
+	url      string
+	client   *http.Client
+	failOpen bool
+}
+
+// NewWebhookController creates a WebhookController. When failOpen is true,
+// changes are admitted if the webhook cannot be reached or answers with a
+// non-2xx status; otherwise such failures reject the change.
+func NewWebhookController(url string, timeout time.Duration, failOpen bool) *WebhookController {
+	return &WebhookController{
+		url:      url,
+		client:   &http.Client{Timeout: timeout},
+		failOpen: failOpen,
+	}
+}
+
+// Admit implements Controller.
+func (c *WebhookController) Admit(ctx context.Context, req *Request) error {
+	resp, err := c.call(ctx, req)
+	if err != nil {
+		if c.failOpen {
+			return nil
+		}
+		return err
+	}
+
+	if resp.Allowed {
+		return nil
+	}
+
+	reasons := resp.Reasons
+	if resp.Message != "" {
+		reasons = append(reasons, resp.Message)
+	}
+	if len(reasons) == 0 {
+		reasons = []string{"rejected by validation webhook"}
+	}
+	return &DeniedError{Source: "webhook", Reasons: reasons}
+}
+
+func (c *WebhookController) call(ctx context.Context, req *Request) (*webhookResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("validation webhook: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(httpResp.Body, 512))
+		return nil, fmt.Errorf("validation webhook: unexpected status %d: %s", httpResp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var resp webhookResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("validation webhook: invalid response: %w", err)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,372 @@
+// Package anomaly watches configuration changes for unusual activity and
+// raises an alert when it sees some: a burst of changes such as 50 routes
+// deleted in a minute, a rate of changes far above the usual one, or
+// changes outside business hours by an operator who does not usually make
+// any then.
+package anomaly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+)
+
+// Alert kinds.
+const (
+	KindBurst    = "burst"
+	KindRate     = "rate"
+	KindOffHours = "off_hours"
+)
+
+const (
+	// maxChanges bounds the changes remembered for the baseline.
+	maxChanges = 200000
+	// seedPage is the number of history entries read at once by Seed.
+	seedPage = 500
+	// rateKey tracks the rate check among the rules firing.
+	rateKey = "rate"
+)
+
+// configEvents selects the events of configuration changes.
+var configEvents = events.Filter{Types: map[string]bool{events.TypeBackend: true, events.TypeRoute: true, events.TypeDescriptor: true, events.TypeSchema: true}}
+
+// Config configures a Detector.
+type Config struct {
+	// Rules flag bursts of changes.
+	Rules []Rule
+	// RateFactor flags a number of changes within RateWindow above
+	// RateFactor times the usual number, as seen over Baseline. Zero
+	// disables the check.
+	RateFactor float64
+	RateWindow time.Duration
+	// RateMin is the fewest changes within RateWindow flagged as unusual,
+	// so that a single change after a quiet week does not stand out.
+	RateMin int
+	// BusinessHours, if not nil, flags changes outside them by operators
+	// who made none outside them on another day within Baseline.
+	BusinessHours *Hours
+	// Baseline is how far back the usual activity is learnt from.
+	Baseline time.Duration
+	// Environment is prefixed to alert summaries.
+	Environment string
+}
+
+// Alert describes unusual activity.
+type Alert struct {
+	Kind    string `json:"kind"`
+	Summary string `json:"summary"`
+	// Rule is the burst rule matched.
+	Rule     string `json:"rule,omitempty"`
+	Operator string `json:"operator,omitempty"`
+	// Count is the number of changes within the window of the rule or
+	// rate check, and Usual the number expected from the baseline.
+	Count int       `json:"count,omitempty"`
+	Usual float64   `json:"usual,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// Event returns the alert as an event, for live clients and the SIEM.
+func (a Alert) Event() events.Event {
+	data, _ := json.Marshal(a)
+	return events.Event{
+		Type:      events.TypeAnomaly,
+		Operation: a.Kind,
+		Operator:  a.Operator,
+		Reason:    a.Summary,
+		Data:      data,
+		Time:      a.Time,
+	}
+}
+
+// HistorySource lists past changes.
+type HistorySource interface {
+	GetHistory(configType *string, configID *uint, limit, offset int) ([]config.ConfigHistory, int, error)
+	GetRevision() (uint64, error)
+}
+
+// change is a configuration change, as remembered by the Detector.
+type change struct {
+	at         time.Time
+	configType string
+	operation  string
+	operator   string
+}
+
+// Detector checks every configuration change against its rules and the
+// usual activity, calling the alert function for each anomaly. A
+// condition that persists, such as a burst going on, raises one alert
+// until it ends.
+type Detector struct {
+	cfg    Config
+	alert  func(Alert)
+	logger *zap.Logger
+
+	mu sync.Mutex
+	// revision is the latest change seen, to skip events redelivered or
+	// already learnt by Seed.
+	revision uint64
+	// changes are the changes within the longest window, oldest first.
+	changes []change
+	// firing holds the rules over their limit, by String.
+	firing map[string]bool
+	// offHours holds the days on which each operator made changes
+	// outside business hours.
+	offHours map[string]map[string]bool
+}
+
+// New creates a Detector calling alert for each anomaly.
+func New(cfg Config, alert func(Alert), logger *zap.Logger) *Detector {
+	if cfg.Baseline <= 0 {
+		cfg.Baseline = 14 * 24 * time.Hour
+	}
+	if cfg.RateWindow <= 0 {
+		cfg.RateWindow = 10 * time.Minute
+	}
+	return &Detector{
+		cfg:      cfg,
+		alert:    alert,
+		logger:   logger,
+		firing:   map[string]bool{},
+		offHours: map[string]map[string]bool{},
+	}
+}
+
+// retention is how long changes are remembered.
+func (d *Detector) retention() time.Duration {
+	retention := d.cfg.Baseline
+	for _, r := range d.cfg.Rules {
+		retention = max(retention, r.Window)
+	}
+	return retention
+}
+
+// Seed learns the usual activity from the history of the last Baseline,
+// without raising alerts, so that it is known right after a restart.
+func (d *Detector) Seed(history HistorySource) error {
+	revision, err := history.GetRevision()
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-d.retention())
+
+	var seeded []change
+	for offset := 0; offset < maxChanges; offset += seedPage {
+		page, _, err := history.GetHistory(nil, nil, seedPage, offset)
+		if err != nil {
+			return err
+		}
+		done := len(page) < seedPage
+		for _, h := range page {
+			if h.CreatedAt.Before(cutoff) {
+				done = true
+				break
+			}
+			// Later changes arrive as events
+			if h.Revision <= revision {
+				seeded = append(seeded, change{at: h.CreatedAt, configType: h.ConfigType, operation: h.Operation, operator: h.Operator})
+			}
+		}
+		if done {
+			break
+		}
+	}
+	slices.Reverse(seeded)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.revision = max(d.revision, revision)
+	for _, c := range seeded {
+		d.record(c)
+	}
+	d.logger.Info("anomaly detection learnt recent activity", zap.Int("changes", len(seeded)))
+	return nil
+}
+
+// Run checks the changes published to broker until ctx is cancelled.
+func (d *Detector) Run(ctx context.Context, broker *events.Broker) {
+	sub := broker.Subscribe(configEvents, 256)
+	defer func() { broker.Unsubscribe(sub) }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				d.logger.Warn("anomaly detection fell behind, some changes were not checked")
+				sub = broker.Subscribe(configEvents, 256)
+				continue
+			}
+			d.Observe(e)
+		}
+	}
+}
+
+// Name implements outbox.Sink.
+func (d *Detector) Name() string {
+	return "anomaly"
+}
+
+// Deliver implements outbox.Sink. Alerts are best effort, so it never
+// fails.
+func (d *Detector) Deliver(ctx context.Context, batch []events.Event) error {
+	for _, e := range batch {
+		d.Observe(e)
+	}
+	return nil
+}
+
+// Observe checks a configuration change event.
+func (d *Detector) Observe(e events.Event) {
+	if !configEvents.Match(e) {
+		return
+	}
+	for _, a := range d.check(e) {
+		if d.cfg.Environment != "" {
+			a.Summary = "[" + d.cfg.Environment + "] " + a.Summary
+		}
+		d.logger.Warn("anomalous configuration activity",
+			zap.String("kind", a.Kind),
+			zap.String("summary", a.Summary),
+			zap.String("operator", a.Operator),
+		)
+		if d.alert != nil {
+			d.alert(a)
+		}
+	}
+}
+
+// check records the change of e and returns the anomalies it reveals.
+func (d *Detector) check(e events.Event) []Alert {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e.Revision != 0 {
+		if e.Revision <= d.revision {
+			return nil
+		}
+		d.revision = e.Revision
+	}
+	c := change{at: e.Time, configType: e.Type, operation: e.Operation, operator: e.Operator}
+	if c.at.IsZero() {
+		c.at = time.Now()
+	}
+	firstOffHours := d.cfg.BusinessHours != nil && c.operator != "" && !d.cfg.BusinessHours.Contains(c.at) &&
+		!d.offHours[c.operator][d.cfg.BusinessHours.day(c.at)]
+	d.record(c)
+
+	var alerts []Alert
+	for _, r := range d.cfg.Rules {
+		if !r.matches(c) {
+			continue
+		}
+		n := d.count(c.at.Add(-r.Window), c.at, r.matches)
+		if d.fire(r.String(), n >= r.Limit) {
+			alerts = append(alerts, Alert{
+				Kind:    KindBurst,
+				Rule:    r.String(),
+				Count:   n,
+				Time:    c.at,
+				Summary: fmt.Sprintf("Burst of configuration changes: %d matching %s:%s within %s", n, r.Type, r.Operation, formatWindow(r.Window)),
+			})
+		}
+	}
+
+	if d.cfg.RateFactor > 0 && d.cfg.Baseline > d.cfg.RateWindow {
+		all := func(change) bool { return true }
+		recent := d.count(c.at.Add(-d.cfg.RateWindow), c.at, all)
+		earlier := d.count(c.at.Add(-d.cfg.Baseline), c.at.Add(-d.cfg.RateWindow), all)
+		usual := float64(earlier) * float64(d.cfg.RateWindow) / float64(d.cfg.Baseline-d.cfg.RateWindow)
+		unusual := recent >= d.cfg.RateMin && float64(recent) > d.cfg.RateFactor*usual
+		if d.fire(rateKey, unusual) {
+			alerts = append(alerts, Alert{
+				Kind:    KindRate,
+				Count:   recent,
+				Usual:   usual,
+				Time:    c.at,
+				Summary: fmt.Sprintf("Unusual rate of configuration changes: %d within %s, against %.1f usually", recent, formatWindow(d.cfg.RateWindow), usual),
+			})
+		}
+	}
+
+	if firstOffHours && len(d.offHours[c.operator]) == 1 {
+		// No changes outside business hours on another day
+		alerts = append(alerts, Alert{
+			Kind:     KindOffHours,
+			Operator: c.operator,
+			Time:     c.at,
+			Summary:  fmt.Sprintf("Configuration change outside business hours by %s, who does not usually make any then", c.operator),
+		})
+	}
+	return alerts
+}
+
+// record remembers a change, forgetting those past the retention.
+func (d *Detector) record(c change) {
+	cutoff := c.at.Add(-d.retention())
+	drop := 0
+	for drop < len(d.changes) && d.changes[drop].at.Before(cutoff) {
+		drop++
+	}
+	if len(d.changes)-drop >= maxChanges {
+		drop = len(d.changes) - maxChanges + 1
+	}
+	d.changes = append(d.changes[drop:], c)
+
+	if d.cfg.BusinessHours == nil || c.operator == "" {
+		return
+	}
+	if !d.cfg.BusinessHours.Contains(c.at) {
+		days := d.offHours[c.operator]
+		if days == nil {
+			days = map[string]bool{}
+			d.offHours[c.operator] = days
+		}
+		days[d.cfg.BusinessHours.day(c.at)] = true
+	}
+	oldest := d.cfg.BusinessHours.day(c.at.Add(-d.cfg.Baseline))
+	for operator, days := range d.offHours {
+		for day := range days {
+			if day < oldest {
+				delete(days, day)
+			}
+		}
+		if len(days) == 0 {
+			delete(d.offHours, operator)
+		}
+	}
+}
+
+// count returns the number of remembered changes made after from, up to
+// and including to, that match.
+func (d *Detector) count(from, to time.Time, match func(change) bool) int {
+	n := 0
+	for i := len(d.changes) - 1; i >= 0 && d.changes[i].at.After(from); i-- {
+		if !d.changes[i].at.After(to) && match(d.changes[i]) {
+			n++
+		}
+	}
+	return n
+}
+
+// fire tracks whether the check named key is over its threshold,
+// reporting whether it just went over.
+func (d *Detector) fire(key string, over bool) bool {
+	if !over {
+		delete(d.firing, key)
+		return false
+	}
+	if d.firing[key] {
+		return false
+	}
+	d.firing[key] = true
+	return true
+}
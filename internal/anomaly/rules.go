@@ -0,0 +1,170 @@
+package anomaly
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// wildcard matches every config type or operation in a Rule.
+const wildcard = "*"
+
+// Rule flags a burst of changes: Limit or more changes of a config type
+// and operation within Window, such as 50 routes deleted in a minute.
+type Rule struct {
+	// Type is the config type, or "*" for all.
+	Type string
+	// Operation is CREATE, UPDATE or DELETE, or "*" for all.
+	Operation string
+	Limit     int
+	Window    time.Duration
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%s:%s:%d/%s", r.Type, r.Operation, r.Limit, formatWindow(r.Window))
+}
+
+// matches reports whether a change counts toward the rule.
+func (r Rule) matches(c change) bool {
+	return (r.Type == wildcard || r.Type == c.configType) && (r.Operation == wildcard || r.Operation == c.operation)
+}
+
+// ParseRules parses a comma-separated list of rules in the form
+// type:operation:limit/window, e.g. "route:DELETE:50/1m,*:*:200/10m".
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("rule %q: want type:operation:limit/window", item)
+		}
+		switch parts[0] {
+		case wildcard, "backend", "route", "descriptor", "schema":
+		default:
+			return nil, fmt.Errorf("rule %q: type must be backend, route, descriptor, schema or *", item)
+		}
+		operation := strings.ToUpper(parts[1])
+		switch operation {
+		case wildcard, "CREATE", "UPDATE", "DELETE":
+		default:
+			return nil, fmt.Errorf("rule %q: operation must be CREATE, UPDATE, DELETE or *", item)
+		}
+		limitText, windowText, ok := strings.Cut(parts[2], "/")
+		if !ok {
+			return nil, fmt.Errorf("rule %q: want type:operation:limit/window", item)
+		}
+		limit, err := strconv.Atoi(limitText)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("rule %q: limit must be a positive integer", item)
+		}
+		window, err := time.ParseDuration(windowText)
+		if err != nil || window < time.Second {
+			return nil, fmt.Errorf("rule %q: window must be a duration of at least 1s", item)
+		}
+
+		rules = append(rules, Rule{Type: parts[0], Operation: operation, Limit: limit, Window: window})
+	}
+	return rules, nil
+}
+
+// Hours are the business hours of the team making changes.
+type Hours struct {
+	Days [7]bool // indexed by time.Weekday
+	// Start and End are times of day, as durations since midnight.
+	Start, End time.Duration
+	Location   *time.Location
+}
+
+// Contains reports whether t falls within business hours.
+func (h *Hours) Contains(t time.Time) bool {
+	t = t.In(h.Location)
+	if !h.Days[t.Weekday()] {
+		return false
+	}
+	since := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	return since >= h.Start && since < h.End
+}
+
+// day returns the date of t in the business hours' time zone.
+func (h *Hours) day(t time.Time) string {
+	return t.In(h.Location).Format(time.DateOnly)
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseHours parses business hours in the form "days start-end", where
+// days is a comma-separated list of days or day ranges, e.g.
+// "Mon-Fri 09:00-18:00" or "Mon,Wed,Fri 08:30-17:00", in loc.
+func ParseHours(s string, loc *time.Location) (*Hours, error) {
+	daysText, timesText, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok {
+		return nil, fmt.Errorf("business hours %q: want days start-end, e.g. Mon-Fri 09:00-18:00", s)
+	}
+	h := &Hours{Location: loc}
+	for _, item := range strings.Split(daysText, ",") {
+		from, to, isRange := strings.Cut(item, "-")
+		first, ok1 := weekdays[strings.ToLower(from)]
+		last, ok2 := first, true
+		if isRange {
+			last, ok2 = weekdays[strings.ToLower(to)]
+		}
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("business hours %q: invalid days %q", s, item)
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			h.Days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+
+	startText, endText, ok := strings.Cut(strings.TrimSpace(timesText), "-")
+	if !ok {
+		return nil, fmt.Errorf("business hours %q: want days start-end, e.g. Mon-Fri 09:00-18:00", s)
+	}
+	var err error
+	if h.Start, err = parseTimeOfDay(startText); err != nil {
+		return nil, fmt.Errorf("business hours %q: %w", s, err)
+	}
+	if h.End, err = parseTimeOfDay(endText); err != nil {
+		return nil, fmt.Errorf("business hours %q: %w", s, err)
+	}
+	if h.End <= h.Start {
+		return nil, fmt.Errorf("business hours %q: end must be after start", s)
+	}
+	return h, nil
+}
+
+// parseTimeOfDay parses a time such as "09:00", or "24:00" for midnight at
+// the end of the day.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,220 @@
+package apply
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// IsDocumentFile reports whether path has a YAML or JSON extension.
+func IsDocumentFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// DocumentFiles returns the YAML and JSON files under dir in lexical
+// order, skipping .git.
+func DocumentFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if IsDocumentFile(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// LoadDir merges every YAML or JSON file under dir into one Document.
+func LoadDir(dir string) (*Document, error) {
+	files, err := DocumentFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		part, err := ParseDocument(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		doc.Merge(part)
+	}
+	return doc, nil
+}
+
+// Lint reports fields in a YAML or JSON document that the API does not
+// know, which are otherwise silently ignored (usually typos). Fields the
+// API returns but ignores on input, such as id and has_secret, are allowed.
+func Lint(data []byte) ([]string, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(jsonData, &raw); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	problems = append(problems, unknownFields("", raw, documentFields)...)
+
+	items := func(key string) []interface{} {
+		list, _ := raw[key].([]interface{})
+		return list
+	}
+	for i, item := range items("backends") {
+		obj, _ := item.(map[string]interface{})
+		path := fmt.Sprintf("backends[%d]", i)
+		problems = append(problems, unknownFields(path, obj, backendFields)...)
+		if cred, ok := obj["credential"].(map[string]interface{}); ok {
+			problems = append(problems, unknownFields(path+".credential", cred, credentialFields)...)
+		}
+		if tls, ok := obj["tls"].(map[string]interface{}); ok {
+			problems = append(problems, unknownFields(path+".tls", tls, tlsFields)...)
+		}
+		if cb, ok := obj["circuit_breaker"].(map[string]interface{}); ok {
+			problems = append(problems, unknownFields(path+".circuit_breaker", cb, circuitBreakerFields)...)
+		}
+	}
+	for i, item := range items("routes") {
+		obj, _ := item.(map[string]interface{})
+		problems = append(problems, unknownFields(fmt.Sprintf("routes[%d]", i), obj, routeFields)...)
+		if transform, ok := obj["transform"].(map[string]interface{}); ok {
+			problems = append(problems, unknownFields(fmt.Sprintf("routes[%d].transform", i), transform, transformFields)...)
+		}
+		if cache, ok := obj["cache"].(map[string]interface{}); ok {
+			problems = append(problems, unknownFields(fmt.Sprintf("routes[%d].cache", i), cache, cacheFields)...)
+		}
+		if cb, ok := obj["circuit_breaker"].(map[string]interface{}); ok {
+			problems = append(problems, unknownFields(fmt.Sprintf("routes[%d].circuit_breaker", i), cb, circuitBreakerFields)...)
+		}
+		if rollout, ok := obj["rollout"].(map[string]interface{}); ok {
+			problems = append(problems, unknownFields(fmt.Sprintf("routes[%d].rollout", i), rollout, rolloutFields)...)
+		}
+		if mirror, ok := obj["mirror"].(map[string]interface{}); ok {
+			problems = append(problems, unknownFields(fmt.Sprintf("routes[%d].mirror", i), mirror, mirrorFields)...)
+		}
+		if deprecation, ok := obj["deprecation"].(map[string]interface{}); ok {
+			problems = append(problems, unknownFields(fmt.Sprintf("routes[%d].deprecation", i), deprecation, deprecationFields)...)
+		}
+		if docs, ok := obj["docs"].(map[string]interface{}); ok {
+			problems = append(problems, unknownFields(fmt.Sprintf("routes[%d].docs", i), docs, docsFields)...)
+		}
+		if fallback, ok := obj["fallback"].(map[string]interface{}); ok {
+			problems = append(problems, unknownFields(fmt.Sprintf("routes[%d].fallback", i), fallback, fallbackFields)...)
+		}
+		if grpc, ok := obj["grpc"].(map[string]interface{}); ok {
+			path := fmt.Sprintf("routes[%d].grpc", i)
+			problems = append(problems, unknownFields(path, grpc, grpcFields)...)
+			headers, _ := grpc["headers"].([]interface{})
+			for j, item := range headers {
+				header, _ := item.(map[string]interface{})
+				problems = append(problems, unknownFields(fmt.Sprintf("%s.headers[%d]", path, j), header, metadataHeaderFields)...)
+			}
+		}
+		queryParams, _ := obj["query_params"].([]interface{})
+		for j, item := range queryParams {
+			param, _ := item.(map[string]interface{})
+			problems = append(problems, unknownFields(fmt.Sprintf("routes[%d].query_params[%d]", i, j), param, queryParamFields)...)
+		}
+		middlewares, _ := obj["middlewares"].([]interface{})
+		for j, item := range middlewares {
+			middleware, _ := item.(map[string]interface{})
+			problems = append(problems, unknownFields(fmt.Sprintf("routes[%d].middlewares[%d]", i, j), middleware, middlewareFields)...)
+		}
+		if experiment, ok := obj["experiment"].(map[string]interface{}); ok {
+			path := fmt.Sprintf("routes[%d].experiment", i)
+			problems = append(problems, unknownFields(path, experiment, experimentFields)...)
+			variants, _ := experiment["variants"].([]interface{})
+			for j, item := range variants {
+				variant, _ := item.(map[string]interface{})
+				vpath := fmt.Sprintf("%s.variants[%d]", path, j)
+				problems = append(problems, unknownFields(vpath, variant, variantFields)...)
+				if match, ok := variant["match"].(map[string]interface{}); ok {
+					problems = append(problems, unknownFields(vpath+".match", match, variantMatchFields)...)
+				}
+			}
+		}
+	}
+	return problems, nil
+}
+
+var (
+	documentFields       = map[string]bool{"backends": true, "routes": true, "change_reason": true}
+	backendFields        = jsonFields(config.Backend{})
+	routeFields          = jsonFields(config.Route{})
+	credentialFields     = jsonFields(config.BackendCredential{}, "has_secret")
+	tlsFields            = jsonFields(config.BackendTLS{}, "has_client_key")
+	transformFields      = jsonFields(config.RouteTransform{})
+	cacheFields          = jsonFields(config.RouteCache{})
+	circuitBreakerFields = jsonFields(config.CircuitBreaker{})
+	rolloutFields        = jsonFields(config.RouteRollout{})
+	experimentFields     = jsonFields(config.RouteExperiment{})
+	variantFields        = jsonFields(config.RouteVariant{})
+	variantMatchFields   = jsonFields(config.VariantMatch{})
+	mirrorFields         = jsonFields(config.RouteMirror{})
+	fallbackFields       = jsonFields(config.RouteFallback{})
+	deprecationFields    = jsonFields(config.RouteDeprecation{})
+	docsFields           = jsonFields(config.RouteDocs{})
+	grpcFields           = jsonFields(config.RouteGRPC{})
+	metadataHeaderFields = jsonFields(config.MetadataHeader{})
+	middlewareFields     = jsonFields(config.RouteMiddleware{})
+	queryParamFields     = jsonFields(config.QueryParam{})
+)
+
+// jsonFields returns the JSON field names of a struct plus extra.
+func jsonFields(v interface{}, extra ...string) map[string]bool {
+	fields := map[string]bool{}
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	for _, name := range extra {
+		fields[name] = true
+	}
+	return fields
+}
+
+func unknownFields(path string, obj map[string]interface{}, known map[string]bool) []string {
+	var problems []string
+	for key := range obj {
+		if known[key] {
+			continue
+		}
+		if path == "" {
+			problems = append(problems, fmt.Sprintf("unknown field %q", key))
+		} else {
+			problems = append(problems, fmt.Sprintf("%s: unknown field %q", path, key))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
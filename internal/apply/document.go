@@ -0,0 +1,198 @@
+package apply
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// defaultTimeoutMS is applied to routes that do not specify a timeout,
+// matching the route API default.
+const defaultTimeoutMS = 5000
+
+// Document is the full desired configuration. Backends are identified by
+// name and routes by HTTP method and pattern; resources default to enabled.
+type Document struct {
+	Backends []config.Backend `json:"backends"`
+	Routes   []config.Route   `json:"routes"`
+
+	// ChangeReason is recorded in history for every change applied.
+	ChangeReason string `json:"change_reason,omitempty"`
+}
+
+// UnmarshalJSON decodes a Document, defaulting enabled to true for every
+// resource that does not set it explicitly.
+func (d *Document) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Backends     []json.RawMessage `json:"backends"`
+		Routes       []json.RawMessage `json:"routes"`
+		ChangeReason string            `json:"change_reason"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	d.ChangeReason = raw.ChangeReason
+
+	d.Backends = make([]config.Backend, 0, len(raw.Backends))
+	for _, item := range raw.Backends {
+		b := config.Backend{Enabled: true}
+		if err := json.Unmarshal(item, &b); err != nil {
+			return err
+		}
+		d.Backends = append(d.Backends, b)
+	}
+
+	d.Routes = make([]config.Route, 0, len(raw.Routes))
+	for _, item := range raw.Routes {
+		r := config.Route{Enabled: true}
+		if err := json.Unmarshal(item, &r); err != nil {
+			return err
+		}
+		d.Routes = append(d.Routes, r)
+	}
+
+	return nil
+}
+
+// ParseDocument decodes a Document from YAML or JSON.
+func ParseDocument(data []byte) (*Document, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc Document
+	if err := json.Unmarshal(jsonData, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Merge appends the resources of other to d.
+func (d *Document) Merge(other *Document) {
+	d.Backends = append(d.Backends, other.Backends...)
+	d.Routes = append(d.Routes, other.Routes...)
+}
+
+// RouteKey returns the identity of a route within a Document.
+func RouteKey(r *config.Route) string {
+	return strings.ToUpper(r.HTTPMethod) + " " + r.HTTPPattern
+}
+
+// ValidationError lists every problem found in a Document.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config document: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks required fields, duplicate identities and that every
+// enabled route references an enabled backend in the same document. It
+// also applies defaults, so it must be called before planning.
+func (d *Document) Validate() error {
+	var problems []string
+
+	backends := make(map[string]*config.Backend, len(d.Backends))
+	for i := range d.Backends {
+		b := &d.Backends[i]
+		if b.Name == "" {
+			problems = append(problems, fmt.Sprintf("backends[%d]: name is required", i))
+			continue
+		}
+		if b.Addr == "" {
+			problems = append(problems, fmt.Sprintf("backend %q: addr is required", b.Name))
+		}
+		if err := b.NormalizeStatus(); err != nil {
+			problems = append(problems, fmt.Sprintf("backend %q: %v", b.Name, err))
+		}
+		if c := b.Credential; c != nil && !(c.Secret == "" && c.SecretRef == "") {
+			// Credentials without a secret keep the stored one; see ComputePlan.
+			if err := c.Validate(); err != nil {
+				problems = append(problems, fmt.Sprintf("backend %q: %v", b.Name, err))
+			}
+		}
+		if t := b.TLS; t != nil && t.ClientKey != "" {
+			if err := t.Validate(); err != nil {
+				problems = append(problems, fmt.Sprintf("backend %q: %v", b.Name, err))
+			}
+		}
+		if err := b.CircuitBreaker.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("backend %q: %v", b.Name, err))
+		}
+		if err := config.ValidatePlugins(b.Plugins); err != nil {
+			problems = append(problems, fmt.Sprintf("backend %q: %v", b.Name, err))
+		}
+		if err := config.ValidateClusters(b.Clusters); err != nil {
+			problems = append(problems, fmt.Sprintf("backend %q: %v", b.Name, err))
+		}
+		if err := config.ValidateOwnerTeam(b.OwnerTeam); err != nil {
+			problems = append(problems, fmt.Sprintf("backend %q: %v", b.Name, err))
+		}
+		if _, dup := backends[b.Name]; dup {
+			problems = append(problems, fmt.Sprintf("backend %q: duplicate name", b.Name))
+		}
+		backends[b.Name] = b
+	}
+
+	routes := make(map[string]struct{}, len(d.Routes))
+	for i := range d.Routes {
+		r := &d.Routes[i]
+		r.HTTPMethod = strings.ToUpper(r.HTTPMethod)
+		if r.TimeoutMS <= 0 {
+			r.TimeoutMS = defaultTimeoutMS
+		}
+
+		if r.HTTPMethod == "" || r.HTTPPattern == "" || r.BackendName == "" ||
+			r.BackendService == "" || r.BackendMethod == "" {
+			problems = append(problems, fmt.Sprintf("routes[%d]: required fields cannot be empty", i))
+			continue
+		}
+
+		key := RouteKey(r)
+		if _, dup := routes[key]; dup {
+			problems = append(problems, fmt.Sprintf("route %q: duplicate method and pattern", key))
+		}
+		if err := r.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("route %q: %v", key, err))
+		}
+		routes[key] = struct{}{}
+
+		if !r.Enabled {
+			continue
+		}
+		if b, ok := backends[r.BackendName]; !ok || !b.Enabled {
+			problems = append(problems, fmt.Sprintf("route %q: backend %q not found or disabled", key, r.BackendName))
+		}
+		if e := r.Experiment; e != nil {
+			for _, v := range e.Variants {
+				if v.BackendName == "" {
+					continue
+				}
+				if b, ok := backends[v.BackendName]; !ok || !b.Enabled {
+					problems = append(problems, fmt.Sprintf("route %q: variant %q: backend %q not found or disabled", key, v.Name, v.BackendName))
+				}
+			}
+		}
+		if m := r.Mirror; m != nil {
+			if b, ok := backends[m.BackendName]; !ok || !b.Enabled {
+				problems = append(problems, fmt.Sprintf("route %q: mirror backend %q not found or disabled", key, m.BackendName))
+			}
+		}
+		if f := r.Fallback; f != nil && f.BackendName != "" {
+			if b, ok := backends[f.BackendName]; !ok || !b.Enabled {
+				problems = append(problems, fmt.Sprintf("route %q: fallback backend %q not found or disabled", key, f.BackendName))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
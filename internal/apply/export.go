@@ -0,0 +1,93 @@
+package apply
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// Export builds a Document describing the given configuration, sorted for
+// stable diffs. Secrets are write-only and never appear in the output; a
+// document applied without them keeps the stored secrets.
+func Export(backends []config.Backend, routes []config.Route) *Document {
+	doc := &Document{
+		Backends: append([]config.Backend(nil), backends...),
+		Routes:   append([]config.Route(nil), routes...),
+	}
+	sort.Slice(doc.Backends, func(i, j int) bool { return doc.Backends[i].Name < doc.Backends[j].Name })
+	sort.Slice(doc.Routes, func(i, j int) bool { return RouteKey(&doc.Routes[i]) < RouteKey(&doc.Routes[j]) })
+	return doc
+}
+
+// serverFields are assigned by the store and have no place in a document.
+var serverFields = []string{"id", "revision", "created_at", "updated_at"}
+
+// Marshal encodes the document as "yaml" or "json", omitting server-assigned
+// fields, secret indicators and backend statuses other than draining.
+func (d *Document) Marshal(format string) ([]byte, error) {
+	out := map[string]interface{}{
+		"backends": []interface{}{},
+		"routes":   []interface{}{},
+	}
+	if d.ChangeReason != "" {
+		out["change_reason"] = d.ChangeReason
+	}
+
+	for _, b := range d.Backends {
+		m, err := toMap(b)
+		if err != nil {
+			return nil, err
+		}
+		if cred, ok := m["credential"].(map[string]interface{}); ok {
+			delete(cred, "has_secret")
+		}
+		if tls, ok := m["tls"].(map[string]interface{}); ok {
+			delete(tls, "has_client_key")
+		}
+		// enabled says it all unless the backend is draining
+		if m["status"] != config.BackendDraining {
+			delete(m, "status")
+		}
+		out["backends"] = append(out["backends"].([]interface{}), m)
+	}
+	for _, r := range d.Routes {
+		m, err := toMap(r)
+		if err != nil {
+			return nil, err
+		}
+		out["routes"] = append(out["routes"].([]interface{}), m)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "json":
+		return append(data, '\n'), nil
+	case "yaml", "yml":
+		return yaml.JSONToYAML(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q (want yaml or json)", format)
+	}
+}
+
+// toMap converts a resource to its API JSON fields minus serverFields.
+func toMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for _, f := range serverFields {
+		delete(m, f)
+	}
+	return m, nil
+}
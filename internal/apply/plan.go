@@ -0,0 +1,426 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// Action is the operation a plan entry performs.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	ActionNoop   Action = "noop"
+)
+
+// BackendChange is a planned change to a single backend.
+type BackendChange struct {
+	Action Action          `json:"action"`
+	Name   string          `json:"name"`
+	Old    *config.Backend `json:"old,omitempty"`
+	New    *config.Backend `json:"new,omitempty"`
+}
+
+// RouteChange is a planned change to a single route.
+type RouteChange struct {
+	Action Action        `json:"action"`
+	Key    string        `json:"key"`
+	Old    *config.Route `json:"old,omitempty"`
+	New    *config.Route `json:"new,omitempty"`
+}
+
+// Plan is the set of changes required to move the current state to a
+// desired Document.
+type Plan struct {
+	Backends []BackendChange `json:"backends"`
+	Routes   []RouteChange   `json:"routes"`
+	Summary  map[Action]int  `json:"summary"`
+	// Revision is the global config revision the plan was computed at.
+	Revision uint64 `json:"revision"`
+}
+
+// ErrStalePlan is returned by Execute when the configuration changed
+// since the plan was computed.
+var ErrStalePlan = errors.New("configuration changed since the plan was computed, plan again")
+
+// HasChanges reports whether executing the plan would modify anything.
+func (p *Plan) HasChanges() bool {
+	return p.Summary[ActionCreate]+p.Summary[ActionUpdate]+p.Summary[ActionDelete] > 0
+}
+
+// CreatesOnly returns a copy of the plan for callers that only add
+// resources: updates become no-ops and deletions are dropped.
+func (p *Plan) CreatesOnly() *Plan {
+	out := &Plan{Summary: map[Action]int{}, Revision: p.Revision}
+	for _, c := range p.Backends {
+		switch c.Action {
+		case ActionDelete:
+			continue
+		case ActionUpdate:
+			c = BackendChange{Action: ActionNoop, Name: c.Name, Old: c.Old, New: c.Old}
+		}
+		out.add(c.Action)
+		out.Backends = append(out.Backends, c)
+	}
+	for _, c := range p.Routes {
+		switch c.Action {
+		case ActionDelete:
+			continue
+		case ActionUpdate:
+			c = RouteChange{Action: ActionNoop, Key: c.Key, Old: c.Old, New: c.Old}
+		}
+		out.add(c.Action)
+		out.Routes = append(out.Routes, c)
+	}
+	return out
+}
+
+// ComputePlan diffs a validated Document against the current contents of
+// the store. Resources that exist but are absent from the document are
+// planned for (soft) deletion if they are currently enabled.
+func ComputePlan(store config.Store, doc *Document) (*Plan, error) {
+	// Read before the resources, so that a change committed in between
+	// makes the plan stale rather than lost
+	revision, err := store.GetRevision()
+	if err != nil {
+		return nil, err
+	}
+	currentBackends, err := store.GetBackends(nil)
+	if err != nil {
+		return nil, err
+	}
+	currentRoutes, err := store.GetRoutes(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Summary: map[Action]int{}, Revision: revision}
+
+	backendsByName := make(map[string]*config.Backend, len(currentBackends))
+	for i := range currentBackends {
+		backendsByName[currentBackends[i].Name] = &currentBackends[i]
+	}
+
+	desiredBackends := make(map[string]struct{}, len(doc.Backends))
+	for i := range doc.Backends {
+		want := doc.Backends[i]
+		desiredBackends[want.Name] = struct{}{}
+
+		change := BackendChange{Name: want.Name, New: &want}
+		if have, ok := backendsByName[want.Name]; !ok {
+			change.Action = ActionCreate
+		} else {
+			change.Old = have
+			want.ID = have.ID
+			// Secrets are write-only, so documents usually omit them; keep
+			// the stored secret when no replacement is given.
+			if c := want.Credential; c != nil && c.Secret == "" && c.SecretRef == "" && have.Credential != nil {
+				cred := *c
+				cred.Secret = have.Credential.Secret
+				want.Credential = &cred
+			}
+			if t := want.TLS; t != nil && t.ClientKey == "" && have.TLS != nil && t.ClientCert == have.TLS.ClientCert {
+				tls := *t
+				tls.ClientKey = have.TLS.ClientKey
+				want.TLS = &tls
+			}
+			if backendEqual(have, &want) {
+				change.Action = ActionNoop
+			} else {
+				change.Action = ActionUpdate
+			}
+		}
+		plan.add(change.Action)
+		plan.Backends = append(plan.Backends, change)
+	}
+
+	for i := range currentBackends {
+		have := &currentBackends[i]
+		if _, ok := desiredBackends[have.Name]; ok || !have.Enabled {
+			continue
+		}
+		plan.add(ActionDelete)
+		plan.Backends = append(plan.Backends, BackendChange{Action: ActionDelete, Name: have.Name, Old: have})
+	}
+
+	// Match current routes by method and pattern. Where several rows share
+	// a key, the first one claims it and the rest are treated as extra.
+	routesByKey := make(map[string]*config.Route, len(currentRoutes))
+	for i := range currentRoutes {
+		key := RouteKey(&currentRoutes[i])
+		if _, ok := routesByKey[key]; !ok {
+			routesByKey[key] = &currentRoutes[i]
+		}
+	}
+
+	matched := make(map[uint]struct{}, len(doc.Routes))
+	for i := range doc.Routes {
+		want := doc.Routes[i]
+		key := RouteKey(&want)
+
+		change := RouteChange{Key: key, New: &want}
+		if have, ok := routesByKey[key]; !ok {
+			change.Action = ActionCreate
+		} else {
+			matched[have.ID] = struct{}{}
+			change.Old = have
+			want.ID = have.ID
+			if routeEqual(have, &want) {
+				change.Action = ActionNoop
+			} else {
+				change.Action = ActionUpdate
+			}
+		}
+		plan.add(change.Action)
+		plan.Routes = append(plan.Routes, change)
+	}
+
+	for i := range currentRoutes {
+		have := &currentRoutes[i]
+		if _, ok := matched[have.ID]; ok || !have.Enabled {
+			continue
+		}
+		plan.add(ActionDelete)
+		plan.Routes = append(plan.Routes, RouteChange{Action: ActionDelete, Key: RouteKey(have), Old: have})
+	}
+
+	sort.SliceStable(plan.Backends, func(i, j int) bool { return plan.Backends[i].Name < plan.Backends[j].Name })
+	sort.SliceStable(plan.Routes, func(i, j int) bool { return plan.Routes[i].Key < plan.Routes[j].Key })
+
+	return plan, nil
+}
+
+func (p *Plan) add(action Action) {
+	p.Summary[action]++
+}
+
+// Actor identifies who is applying a plan and why.
+type Actor struct {
+	Operator string   `json:"operator,omitempty"`
+	Role     string   `json:"role,omitempty"`
+	Teams    []string `json:"teams,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+	// FreezeOverride asks to apply the plan during a change freeze; only
+	// admins may set it.
+	FreezeOverride bool `json:"freeze_override,omitempty"`
+}
+
+// Execute applies every change in the plan inside a single transaction and
+// records a history entry per change, attributed to actor. It fails with
+// ErrStalePlan, changing nothing, if another change committed since the
+// plan was computed: recording history bumps the revision in order, so
+// every change must get the revision following the previous one.
+func Execute(store config.Store, plan *Plan, actor Actor) error {
+	return store.InTx(func(tx config.Store) error {
+		h := &recorder{store: tx, actor: actor, revision: plan.Revision}
+
+		// Backends must exist before routes are pointed at them, and routes
+		// must be disabled before the backends they reference.
+		for _, c := range plan.Backends {
+			switch c.Action {
+			case ActionCreate:
+				if err := tx.CreateBackend(c.New); err != nil {
+					return err
+				}
+				if err := h.record("backend", &c.New.ID, "CREATE", nil, c.New); err != nil {
+					return err
+				}
+			case ActionUpdate:
+				if err := tx.UpdateBackend(c.Name, c.New); err != nil {
+					return err
+				}
+				if err := h.record("backend", &c.New.ID, "UPDATE", c.Old, c.New); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, c := range plan.Routes {
+			switch c.Action {
+			case ActionCreate:
+				if err := tx.CreateRoute(c.New); err != nil {
+					return err
+				}
+				if err := h.record("route", &c.New.ID, "CREATE", nil, c.New); err != nil {
+					return err
+				}
+			case ActionUpdate:
+				if err := tx.UpdateRoute(c.New.ID, c.New); err != nil {
+					return err
+				}
+				if err := h.record("route", &c.New.ID, "UPDATE", c.Old, c.New); err != nil {
+					return err
+				}
+			case ActionDelete:
+				if err := tx.DeleteRoute(c.Old.ID); err != nil {
+					return err
+				}
+				old := *c.Old
+				old.Enabled = false
+				if err := h.record("route", &old.ID, "DELETE", &old, nil); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, c := range plan.Backends {
+			if c.Action != ActionDelete {
+				continue
+			}
+			if err := tx.DeleteBackend(c.Name); err != nil {
+				return err
+			}
+			old := *c.Old
+			old.Enabled = false
+			if err := h.record("backend", &old.ID, "DELETE", &old, nil); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// recorder records the history of the changes of a plan, checking that
+// each gets the revision following the previous one.
+type recorder struct {
+	store    config.Store
+	actor    Actor
+	revision uint64
+}
+
+// record writes a history entry in the same shape as the API handlers.
+func (h *recorder) record(configType string, configID *uint, operation string, oldVal, newVal interface{}) error {
+	history := &config.ConfigHistory{
+		ConfigType:     configType,
+		ConfigID:       configID,
+		Operation:      operation,
+		Operator:       h.actor.Operator,
+		Reason:         h.actor.Reason,
+		FreezeOverride: h.actor.FreezeOverride && h.actor.Role == auth.RoleAdmin,
+	}
+
+	if oldVal != nil {
+		data, err := json.Marshal(oldVal)
+		if err != nil {
+			return err
+		}
+		history.OldValue = data
+	}
+
+	if newVal != nil {
+		data, err := json.Marshal(newVal)
+		if err != nil {
+			return err
+		}
+		history.NewValue = data
+	}
+
+	if err := h.store.CreateHistory(history); err != nil {
+		return err
+	}
+	if history.Revision != h.revision+1 {
+		return ErrStalePlan
+	}
+	h.revision = history.Revision
+	return nil
+}
+
+// backendEqual compares two backends ignoring identity, revision and
+// timestamps.
+func backendEqual(a, b *config.Backend) bool {
+	x, y := *a, *b
+	x.ID, y.ID = 0, 0
+	x.Revision, y.Revision = 0, 0
+	x.CreatedAt, y.CreatedAt = time.Time{}, time.Time{}
+	x.UpdatedAt, y.UpdatedAt = time.Time{}, time.Time{}
+	return reflect.DeepEqual(x, y)
+}
+
+// routeEqual compares two routes ignoring identity, revision and
+// timestamps.
+func routeEqual(a, b *config.Route) bool {
+	x, y := *a, *b
+	x.ID, y.ID = 0, 0
+	x.Revision, y.Revision = 0, 0
+	x.CreatedAt, y.CreatedAt = time.Time{}, time.Time{}
+	x.UpdatedAt, y.UpdatedAt = time.Time{}, time.Time{}
+	return reflect.DeepEqual(x, y)
+}
+
+// Admit runs every change in the plan through the admission controllers
+// and returns the first rejection.
+func Admit(ctx context.Context, ctrl admission.Controller, plan *Plan, actor Actor) error {
+	batch := plan.Summary[ActionCreate] + plan.Summary[ActionUpdate] + plan.Summary[ActionDelete]
+
+	for _, c := range plan.Backends {
+		if c.Action == ActionNoop {
+			continue
+		}
+		req := actor.request(operation(c.Action), "backend")
+		req.Batch = batch
+		if c.Old != nil {
+			req.Old = c.Old
+		}
+		if c.Action != ActionDelete {
+			req.New = c.New
+		}
+		if err := ctrl.Admit(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range plan.Routes {
+		if c.Action == ActionNoop {
+			continue
+		}
+		req := actor.request(operation(c.Action), "route")
+		req.Batch = batch
+		if c.Old != nil {
+			req.Old = c.Old
+		}
+		if c.Action != ActionDelete {
+			req.New = c.New
+		}
+		if err := ctrl.Admit(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// request returns an admission request for a change made by the actor.
+func (a Actor) request(operation, configType string) *admission.Request {
+	return &admission.Request{
+		Operation:      operation,
+		ConfigType:     configType,
+		Operator:       a.Operator,
+		Role:           a.Role,
+		Teams:          a.Teams,
+		Reason:         a.Reason,
+		FreezeOverride: a.FreezeOverride,
+	}
+}
+
+// operation maps a plan action to the history operation name.
+func operation(action Action) string {
+	switch action {
+	case ActionCreate:
+		return "CREATE"
+	case ActionUpdate:
+		return "UPDATE"
+	default:
+		return "DELETE"
+	}
+}
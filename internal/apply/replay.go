@@ -0,0 +1,99 @@
+package apply
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// Replayed is the configuration reconstructed from the change history
+// alone, keyed by ID.
+type Replayed struct {
+	Backends map[uint]config.Backend
+	Routes   map[uint]config.Route
+	// Entries is the number of backend and route history entries read.
+	Entries int
+}
+
+// Replay reconstructs every backend and route from the change history,
+// without reading their tables: each takes the value recorded by its
+// latest change, and one whose latest change is a delete is disabled, as
+// a (soft) delete leaves it. Comparing the result with the tables reveals
+// changes made behind the service's back, such as manual edits of the
+// database. Call it within a transaction so the history is consistent.
+//
+// Secrets are never recorded in history, so the reconstructed resources
+// carry none.
+func Replay(store config.Store) (*Replayed, error) {
+	replayed := &Replayed{
+		Backends: map[uint]config.Backend{},
+		Routes:   map[uint]config.Route{},
+	}
+	seen := map[string]bool{}
+
+	for offset := 0; ; offset += historyPage {
+		entries, _, err := store.GetHistory(nil, nil, historyPage, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.ConfigID == nil || entry.ConfigType != "backend" && entry.ConfigType != "route" {
+				continue
+			}
+			replayed.Entries++
+
+			// History is read newest first, so the first entry of a
+			// resource holds its current value
+			id := *entry.ConfigID
+			key := fmt.Sprintf("%s/%d", entry.ConfigType, id)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			value := entry.NewValue
+			if entry.Operation == "DELETE" {
+				value = entry.OldValue
+			}
+
+			switch entry.ConfigType {
+			case "backend":
+				var b config.Backend
+				if err := json.Unmarshal(value, &b); err != nil {
+					return nil, fmt.Errorf("history entry %d: %w", entry.ID, err)
+				}
+				if err := b.NormalizeStatus(); err != nil {
+					return nil, fmt.Errorf("history entry %d: %w", entry.ID, err)
+				}
+				if entry.Operation == "DELETE" {
+					b.Enabled = false
+					b.Status = config.BackendDisabled
+				}
+				b.ID = id
+				replayed.Backends[id] = b
+			case "route":
+				var r config.Route
+				if err := json.Unmarshal(value, &r); err != nil {
+					return nil, fmt.Errorf("history entry %d: %w", entry.ID, err)
+				}
+				if entry.Operation == "DELETE" {
+					r.Enabled = false
+				}
+				r.ID = id
+				replayed.Routes[id] = r
+			}
+		}
+		if len(entries) < historyPage {
+			break
+		}
+	}
+	return replayed, nil
+}
+
+// Document returns the replayed configuration as a document, resolving
+// conflicts as Rewind does. Applying it makes the tables match the
+// history again, keeping the stored secrets.
+func (r *Replayed) Document() *Document {
+	return document(r.Backends, r.Routes)
+}
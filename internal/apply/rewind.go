@@ -0,0 +1,137 @@
+package apply
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// historyPage is the number of history entries read at a time while
+// rewinding.
+const historyPage = 200
+
+// Rewind reconstructs the configuration as it was at the given time by
+// reverting, newest first, every backend and route change recorded after
+// it: a create removes the resource, an update or delete restores its
+// previous value. It returns the document along with the number of
+// changes reverted. Call it within a transaction so the current state and
+// the history are consistent.
+//
+// Secrets are never recorded in history, so the document carries none and
+// applying it keeps the stored secrets. Conflicting routes are resolved
+// as by document.
+func Rewind(store config.Store, at time.Time) (*Document, int, error) {
+	currentBackends, err := store.GetBackends(nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	currentRoutes, err := store.GetRoutes(nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	backends := make(map[uint]config.Backend, len(currentBackends))
+	for _, b := range currentBackends {
+		backends[b.ID] = b
+	}
+	routes := make(map[uint]config.Route, len(currentRoutes))
+	for _, r := range currentRoutes {
+		routes[r.ID] = r
+	}
+
+	reverted := 0
+	for offset := 0; ; offset += historyPage {
+		entries, _, err := store.GetHistory(nil, nil, historyPage, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		done := len(entries) < historyPage
+		for _, entry := range entries {
+			if !entry.CreatedAt.After(at) {
+				done = true
+				break
+			}
+			if entry.ConfigID == nil {
+				continue
+			}
+			id := *entry.ConfigID
+
+			switch entry.ConfigType {
+			case "backend":
+				if entry.Operation == "CREATE" {
+					delete(backends, id)
+					break
+				}
+				var b config.Backend
+				if err := json.Unmarshal(entry.OldValue, &b); err != nil {
+					return nil, 0, fmt.Errorf("history entry %d: %w", entry.ID, err)
+				}
+				if entry.Operation == "DELETE" {
+					b.Enabled = true
+					if b.Status != config.BackendDraining {
+						b.Status = config.BackendEnabled
+					}
+				}
+				b.ID = id
+				backends[id] = b
+			case "route":
+				if entry.Operation == "CREATE" {
+					delete(routes, id)
+					break
+				}
+				var r config.Route
+				if err := json.Unmarshal(entry.OldValue, &r); err != nil {
+					return nil, 0, fmt.Errorf("history entry %d: %w", entry.ID, err)
+				}
+				if entry.Operation == "DELETE" {
+					r.Enabled = true
+				}
+				r.ID = id
+				routes[id] = r
+			default:
+				continue
+			}
+			reverted++
+		}
+		if done {
+			break
+		}
+	}
+
+	return document(backends, routes), reverted, nil
+}
+
+// document builds a Document from reconstructed backends and routes,
+// keyed by ID. Where several routes share a method and pattern, only one
+// is kept, preferring an enabled route and then the oldest; the same goes
+// for backends sharing a name.
+func document(backends map[uint]config.Backend, routes map[uint]config.Route) *Document {
+	byName := make(map[string]config.Backend, len(backends))
+	for _, b := range backends {
+		if have, ok := byName[b.Name]; ok && (have.Enabled && !b.Enabled || have.Enabled == b.Enabled && have.ID < b.ID) {
+			continue
+		}
+		byName[b.Name] = b
+	}
+	list := make([]config.Backend, 0, len(byName))
+	for _, b := range byName {
+		list = append(list, b)
+	}
+
+	byKey := make(map[string]config.Route, len(routes))
+	for _, r := range routes {
+		key := RouteKey(&r)
+		if have, ok := byKey[key]; ok && (have.Enabled && !r.Enabled || have.Enabled == r.Enabled && have.ID < r.ID) {
+			continue
+		}
+		byKey[key] = r
+	}
+	routeList := make([]config.Route, 0, len(byKey))
+	for _, r := range byKey {
+		routeList = append(routeList, r)
+	}
+
+	return Export(list, routeList)
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+)
+
+// Cookies set for browser sessions, such as the UI's, which keep their
+// tokens in cookies rather than sending them as bearer tokens.
+const (
+	// SessionCookie holds the access token.
+	SessionCookie = "agw_session"
+	// RefreshCookie holds the refresh token; it is only sent to the
+	// refresh endpoints.
+	RefreshCookie = "agw_refresh"
+	// CSRFCookie holds the CSRF token of the session, readable by scripts
+	// so that they can echo it in CSRFHeader.
+	CSRFCookie = "agw_csrf"
+	// CSRFHeader carries the CSRF token on state-changing requests
+	// authenticated by cookie.
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// RefreshCookiePaths are the only paths the refresh cookie is sent to, the
+// refresh endpoint of each API version. The cookie is set on each.
+var RefreshCookiePaths = []string{"/api/v1/auth/refresh", "/api/v2/auth/refresh"}
+
+// CookieToken returns the access token of a request authenticated by
+// cookie. A request with an Authorization header is authenticated by that
+// header instead, whatever cookies it carries.
+func CookieToken(r *http.Request) (string, bool) {
+	if r.Header.Get("Authorization") != "" {
+		return "", false
+	}
+	c, err := r.Cookie(SessionCookie)
+	if err != nil || c.Value == "" {
+		return "", false
+	}
+	return c.Value, true
+}
+
+// SetSessionCookies sets the cookies of a browser session: the access
+// token until it expires, and the refresh and CSRF tokens until the
+// session does. All are SameSite=Strict; only the CSRF token is readable
+// by scripts.
+func SetSessionCookies(w http.ResponseWriter, secure bool, token string, tokenExpires time.Time, refresh, csrf string, sessionExpires time.Time) {
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: token, Path: "/", Expires: tokenExpires,
+		HttpOnly: true, Secure: secure, SameSite: http.SameSiteStrictMode})
+	for _, path := range RefreshCookiePaths {
+		http.SetCookie(w, &http.Cookie{Name: RefreshCookie, Value: refresh, Path: path, Expires: sessionExpires,
+			HttpOnly: true, Secure: secure, SameSite: http.SameSiteStrictMode})
+	}
+	http.SetCookie(w, &http.Cookie{Name: CSRFCookie, Value: csrf, Path: "/", Expires: sessionExpires,
+		Secure: secure, SameSite: http.SameSiteStrictMode})
+}
+
+// ClearSessionCookies removes the cookies of a browser session.
+func ClearSessionCookies(w http.ResponseWriter, secure bool) {
+	cookies := []struct{ name, path string }{{SessionCookie, "/"}, {CSRFCookie, "/"}}
+	for _, path := range RefreshCookiePaths {
+		cookies = append(cookies, struct{ name, path string }{RefreshCookie, path})
+	}
+	for _, c := range cookies {
+		http.SetCookie(w, &http.Cookie{Name: c.name, Path: c.path, MaxAge: -1,
+			HttpOnly: c.name != CSRFCookie, Secure: secure, SameSite: http.SameSiteStrictMode})
+	}
+}
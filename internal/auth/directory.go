@@ -0,0 +1,17 @@
+package auth
+
+import "errors"
+
+// ErrInvalidCredentials is returned by a directory for a wrong user name or
+// password, and for users it does not let sign in.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// DirectoryUser is a user authenticated by a directory such as LDAP.
+type DirectoryUser struct {
+	Name        string
+	DisplayName string
+	// Role is the role the user's groups map to.
+	Role string
+	// Groups are the names of the groups the user belongs to.
+	Groups []string
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// roleRanks orders the roles from least to most privileged.
+var roleRanks = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleAdmin: 3}
+
+// GroupRoles maps the groups of an identity provider to roles.
+type GroupRoles map[string]string
+
+// ParseGroupRoles parses a comma-separated list of group:role mappings,
+// e.g. "Gateway Admins:admin,Gateway Editors:editor". Group names may
+// themselves contain colons: the role follows the last one.
+func ParseGroupRoles(s string) (GroupRoles, error) {
+	roles := GroupRoles{}
+	for _, item := range strings.Split(s, ",") {
+		group, role, err := cutGroupMapping(item)
+		if err != nil {
+			return nil, err
+		}
+		if group == "" {
+			continue
+		}
+		if !ValidRole(role) {
+			return nil, fmt.Errorf("group mapping %q: role must be viewer, editor or admin", item)
+		}
+		if existing, ok := roles[group]; ok && existing != role {
+			return nil, fmt.Errorf("group mapping %q: group is already mapped to %s", item, existing)
+		}
+		roles[group] = role
+	}
+	return roles, nil
+}
+
+// Role returns the most privileged role that groups map to, or "" if none
+// of them is mapped.
+func (m GroupRoles) Role(groups []string) string {
+	role := ""
+	for _, g := range groups {
+		if r, ok := m[g]; ok && roleRanks[r] > roleRanks[role] {
+			role = r
+		}
+	}
+	return role
+}
+
+// GroupTeams maps the groups of an identity provider to teams. A group
+// may map to several teams, and several groups to the same team.
+type GroupTeams map[string][]string
+
+// ParseGroupTeams parses a comma-separated list of group:team mappings,
+// e.g. "Payments Engineers:payments,SRE:payments,SRE:search-infra".
+func ParseGroupTeams(s string) (GroupTeams, error) {
+	teams := GroupTeams{}
+	for _, item := range strings.Split(s, ",") {
+		group, team, err := cutGroupMapping(item)
+		if err != nil {
+			return nil, err
+		}
+		if group == "" {
+			continue
+		}
+		teams[group] = append(teams[group], team)
+	}
+	return teams, nil
+}
+
+// Teams returns the teams that groups map to, sorted.
+func (m GroupTeams) Teams(groups []string) []string {
+	set := map[string]bool{}
+	for _, g := range groups {
+		for _, t := range m[g] {
+			set[t] = true
+		}
+	}
+	return sortedKeys(set)
+}
+
+// Managed returns every team mapped to, sorted: the teams whose members
+// follow the groups.
+func (m GroupTeams) Managed() []string {
+	set := map[string]bool{}
+	for _, teams := range m {
+		for _, t := range teams {
+			set[t] = true
+		}
+	}
+	return sortedKeys(set)
+}
+
+// cutGroupMapping splits a group:value mapping at its last colon. It
+// returns an empty group for an empty item.
+func cutGroupMapping(item string) (group, value string, err error) {
+	item = strings.TrimSpace(item)
+	if item == "" {
+		return "", "", nil
+	}
+	i := strings.LastIndex(item, ":")
+	if i <= 0 || i == len(item)-1 {
+		return "", "", fmt.Errorf("group mapping %q: want group:value", item)
+	}
+	return strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:]), nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,57 @@
+package auth
+
+import "context"
+
+// Roles understood by the admin service: viewers may only read, editors
+// may also change backends and routes, and admins may do anything.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// ValidRole reports whether role is one of the roles above.
+func ValidRole(role string) bool {
+	switch role {
+	case RoleViewer, RoleEditor, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+	// Teams are the teams the caller belongs to.
+	Teams []string `json:"teams,omitempty"`
+	// Session is the ID of the session of a local user.
+	Session string `json:"session,omitempty"`
+	// MustChangePassword is set for local users who must change their
+	// password before doing anything else.
+	MustChangePassword bool `json:"must_change_password,omitempty"`
+}
+
+// IsAdmin reports whether the principal has the admin role.
+func (p *Principal) IsAdmin() bool {
+	return p != nil && p.Role == RoleAdmin
+}
+
+// HasRole reports whether the principal has role or a more privileged
+// one. Unknown roles have none.
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && roleRanks[p.Role] > 0 && roleRanks[p.Role] >= roleRanks[role]
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, or nil.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(contextKey{}).(*Principal)
+	return p
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// FailureWindow is how long failed sign ins count towards a lockout.
+const FailureWindow = 24 * time.Hour
+
+// maxThrottled bounds the clients a Throttle keeps; beyond it, the clients
+// that failed least recently are forgotten.
+const maxThrottled = 10000
+
+// Backoff is the lockout after repeated failed sign ins: from Threshold
+// consecutive failures on, for Base, doubling with every further failure
+// up to Max.
+type Backoff struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+// Lockout returns how long to lock out after failures consecutive failed
+// sign ins, or 0 if not yet.
+func (b Backoff) Lockout(failures int) time.Duration {
+	if b.Threshold <= 0 || failures < b.Threshold {
+		return 0
+	}
+	d := b.Base
+	for i := b.Threshold; i < failures && d < b.Max; i++ {
+		d *= 2
+	}
+	return min(d, b.Max)
+}
+
+// Throttle counts failed sign ins per client IP address and locks out
+// clients that fail repeatedly, whatever users they try. It is kept in
+// memory, by each instance.
+type Throttle struct {
+	backoff Backoff
+
+	mu      sync.Mutex
+	clients map[string]*list.Element
+	// recent orders the clients by their last failure, most recent first.
+	recent *list.List
+}
+
+type throttled struct {
+	ip          string
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// NewThrottle creates a Throttle locking out clients after backoff.
+func NewThrottle(backoff Backoff) *Throttle {
+	return &Throttle{
+		backoff: backoff,
+		clients: make(map[string]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// LockedUntil returns when the lockout of ip ends, or the zero time if it
+// is not locked out at now.
+func (t *Throttle) LockedUntil(ip string, now time.Time) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e := t.clients[ip]; e != nil {
+		if c := e.Value.(*throttled); now.Before(c.lockedUntil) {
+			return c.lockedUntil
+		}
+	}
+	return time.Time{}
+}
+
+// Fail counts a failed sign in from ip at now and returns when the
+// resulting lockout ends, or the zero time if there is none. Signing in
+// successfully does not clear the failures, which could otherwise be
+// reset with an account of one's own.
+func (t *Throttle) Fail(ip string, now time.Time) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var c *throttled
+	if e := t.clients[ip]; e != nil {
+		c = e.Value.(*throttled)
+		t.recent.MoveToFront(e)
+	} else {
+		if len(t.clients) >= maxThrottled {
+			t.evict()
+		}
+		c = &throttled{ip: ip}
+		t.clients[ip] = t.recent.PushFront(c)
+	}
+	if now.Sub(c.lastFailure) > FailureWindow {
+		*c = throttled{ip: ip}
+	}
+	c.failures++
+	c.lastFailure = now
+	if d := t.backoff.Lockout(c.failures); d > 0 {
+		c.lockedUntil = now.Add(d)
+		return c.lockedUntil
+	}
+	return time.Time{}
+}
+
+// evict forgets the client that failed least recently, making room for
+// another.
+func (t *Throttle) evict() {
+	if e := t.recent.Back(); e != nil {
+		t.recent.Remove(e)
+		delete(t.clients, e.Value.(*throttled).ip)
+	}
+}
+
+// Sign in events, as audited.
+const (
+	LoginSucceeded   = "login.succeeded"
+	LoginFailed      = "login.failed"
+	LoginLockedOut   = "login.locked_out"
+	AccountLocked    = "account.locked"
+	ClientLocked     = "client.locked"
+	AccountUnlocked  = "account.unlocked"
+	TOTPEnabled      = "totp.enabled"
+	TOTPDisabled     = "totp.disabled"
+	RecoveryCodeUsed = "totp.recovery_code_used"
+)
+
+// LoginEvent is an audited sign in event.
+type LoginEvent struct {
+	// Type is one of the events above.
+	Type string
+	// User is the user signing in, or acted on.
+	User string
+	// Operator is the admin who unlocked the user, or turned off their
+	// two-factor authentication.
+	Operator string
+	// LockedUntil is when the lockout ends, for lockouts.
+	LockedUntil time.Time
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TokenPrefix starts every access token issued by the service, telling
+// them apart from other bearer tokens such as the gateway token.
+const TokenPrefix = "agw1."
+
+// RefreshTokenPrefix starts every refresh token.
+const RefreshTokenPrefix = "agwr1."
+
+// ErrInvalidToken is returned for tokens that are malformed, forged or
+// expired.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the contents of a token.
+type Claims struct {
+	// Subject is the name of the user the token was issued to.
+	Subject string `json:"sub"`
+	// Session is the ID of the session the token belongs to.
+	Session string `json:"sid"`
+	// IssuedAt and ExpiresAt are Unix times in milliseconds.
+	IssuedAt  int64 `json:"iat"`
+	ExpiresAt int64 `json:"exp"`
+}
+
+// TokenIssuer issues and verifies the access tokens of signed in users,
+// signed with HMAC-SHA256. Tokens carry only the user's name and session;
+// the role and state of both are looked up on every request, so changes
+// and revocations apply at once.
+type TokenIssuer struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewTokenIssuer creates a TokenIssuer from a base64-encoded key of at
+// least 32 bytes, issuing tokens valid for ttl.
+func NewTokenIssuer(encodedKey string, ttl time.Duration) (*TokenIssuer, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode token key: %w", err)
+	}
+	if len(key) < 32 {
+		return nil, fmt.Errorf("token key must be at least 32 bytes, got %d", len(key))
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("token lifetime must be positive")
+	}
+	return &TokenIssuer{key: key, ttl: ttl}, nil
+}
+
+// Issue returns a token for subject in session issued at now, and when it
+// expires.
+func (i *TokenIssuer) Issue(subject, session string, now time.Time) (string, time.Time) {
+	expires := now.Add(i.ttl)
+	payload, _ := json.Marshal(Claims{Subject: subject, Session: session, IssuedAt: now.UnixMilli(), ExpiresAt: expires.UnixMilli()})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return TokenPrefix + encoded + "." + i.sign(encoded), expires
+}
+
+// Verify checks the signature and expiry of token and returns its claims.
+func (i *TokenIssuer) Verify(token string, now time.Time) (*Claims, error) {
+	rest, ok := strings.CutPrefix(token, TokenPrefix)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	encoded, signature, ok := strings.Cut(rest, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(i.sign(encoded))) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" || claims.Session == "" {
+		return nil, ErrInvalidToken
+	}
+	if now.UnixMilli() >= claims.ExpiresAt {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+// CSRFToken returns the CSRF token of a session, which requests
+// authenticated by cookie must echo in the CSRFHeader. It is derived from
+// the session ID, so nothing is stored and it cannot be reused for
+// another session.
+func (i *TokenIssuer) CSRFToken(session string) string {
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write([]byte("csrf." + session))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CheckCSRFToken reports whether token is the CSRF token of session.
+func (i *TokenIssuer) CheckCSRFToken(session, token string) bool {
+	return token != "" && hmac.Equal([]byte(token), []byte(i.CSRFToken(session)))
+}
+
+func (i *TokenIssuer) sign(encoded string) string {
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write([]byte(TokenPrefix + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewSessionID returns a random session ID.
+func NewSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewRefreshToken returns a random refresh token for a session, and the
+// hash to keep of it.
+func NewRefreshToken(session string) (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	return RefreshTokenPrefix + session + "." + secret, hashSecret(secret), nil
+}
+
+// ParseRefreshToken returns the session of a refresh token and the hash
+// of its secret.
+func ParseRefreshToken(token string) (session, hash string, err error) {
+	rest, ok := strings.CutPrefix(token, RefreshTokenPrefix)
+	if !ok {
+		return "", "", ErrInvalidToken
+	}
+	session, secret, ok := strings.Cut(rest, ".")
+	if !ok || session == "" || secret == "" {
+		return "", "", ErrInvalidToken
+	}
+	return session, hashSecret(secret), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,192 @@
+// Package backup periodically writes the full configuration to an
+// S3-compatible bucket, keeping restore points for a retention period.
+package backup
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/apply"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// nameLayout names backups after the start of the interval they were
+// taken in.
+const nameLayout = "20060102T150405Z"
+
+// Options configures a Scheduler.
+type Options struct {
+	// Prefix is prepended to the object key of every backup.
+	Prefix string
+	// Interval is how often a backup is taken.
+	Interval time.Duration
+	// Retention is how long backups are kept; zero keeps them forever.
+	// The newest backup is never deleted.
+	Retention time.Duration
+}
+
+// Backup is a restore point: a configuration document in the bucket.
+type Backup struct {
+	Name      string    `json:"name"`
+	Key       string    `json:"key"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Scheduler takes backups. Backups are named after the interval they were
+// taken in, so several admin instances sharing a bucket write each backup
+// once rather than once per instance.
+type Scheduler struct {
+	store  config.Store
+	client *S3Client
+	opts   Options
+	logger *zap.Logger
+}
+
+// NewScheduler creates a new Scheduler backing up store to client.
+func NewScheduler(store config.Store, client *S3Client, opts Options, logger *zap.Logger) *Scheduler {
+	if opts.Interval <= 0 {
+		opts.Interval = 24 * time.Hour
+	}
+	return &Scheduler{store: store, client: client, opts: opts, logger: logger}
+}
+
+// Run takes a backup whenever the current interval has none yet, then
+// prunes expired ones, until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		if err := s.tick(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Warn("configuration backup failed", zap.String("bucket", s.client.Bucket()), zap.Error(err))
+		}
+
+		next := time.Now().UTC().Truncate(s.opts.Interval).Add(s.opts.Interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) error {
+	backups, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	name := time.Now().UTC().Truncate(s.opts.Interval).Format(nameLayout) + ".json"
+	taken := false
+	for _, b := range backups {
+		if b.Name == name {
+			taken = true
+			break
+		}
+	}
+	if !taken {
+		b, err := s.Take(ctx, name)
+		if err != nil {
+			return err
+		}
+		s.logger.Info("configuration backed up", zap.String("key", b.Key), zap.Int64("size", b.Size))
+		backups = append([]Backup{*b}, backups...)
+	}
+
+	return s.prune(ctx, backups)
+}
+
+// Take writes a backup of the current configuration under name.
+func (s *Scheduler) Take(ctx context.Context, name string) (*Backup, error) {
+	// Read backends and routes in one transaction so they are consistent
+	var doc *apply.Document
+	err := s.store.InTx(func(tx config.Store) error {
+		backends, err := tx.GetBackends(nil)
+		if err != nil {
+			return err
+		}
+		routes, err := tx.GetRoutes(nil)
+		if err != nil {
+			return err
+		}
+		doc = apply.Export(backends, routes)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read configuration: %w", err)
+	}
+
+	data, err := doc.Marshal("json")
+	if err != nil {
+		return nil, fmt.Errorf("encode document: %w", err)
+	}
+
+	key := s.opts.Prefix + name
+	if err := s.client.Put(ctx, key, data, "application/json"); err != nil {
+		return nil, fmt.Errorf("upload %s: %w", key, err)
+	}
+	return &Backup{Name: name, Key: key, Size: int64(len(data)), CreatedAt: time.Now().UTC()}, nil
+}
+
+// prune deletes backups past retention, except the newest. backups must
+// be newest first.
+func (s *Scheduler) prune(ctx context.Context, backups []Backup) error {
+	if s.opts.Retention <= 0 || len(backups) < 2 {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-s.opts.Retention)
+	for _, b := range backups[1:] {
+		if !b.CreatedAt.Before(cutoff) {
+			continue
+		}
+		if err := s.client.Delete(ctx, b.Key); err != nil {
+			return fmt.Errorf("delete %s: %w", b.Key, err)
+		}
+		s.logger.Debug("pruned configuration backup", zap.String("key", b.Key))
+	}
+	return nil
+}
+
+// List returns the backups in the bucket, newest first. Other objects
+// under the prefix are ignored.
+func (s *Scheduler) List(ctx context.Context) ([]Backup, error) {
+	objects, err := s.client.List(ctx, s.opts.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("list backups: %w", err)
+	}
+
+	var backups []Backup
+	for _, o := range objects {
+		name := strings.TrimPrefix(o.Key, s.opts.Prefix)
+		if !validName(name) {
+			continue
+		}
+		backups = append(backups, Backup{Name: name, Key: o.Key, Size: o.Size, CreatedAt: o.LastModified})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Name > backups[j].Name })
+	return backups, nil
+}
+
+// Get returns the configuration document of the named backup, or nil if
+// there is none.
+func (s *Scheduler) Get(ctx context.Context, name string) ([]byte, error) {
+	if !validName(name) {
+		return nil, nil
+	}
+	return s.client.Get(ctx, s.opts.Prefix+name)
+}
+
+// validName reports whether name is that of a backup.
+func validName(name string) bool {
+	stem, ok := strings.CutSuffix(name, ".json")
+	if !ok || path.Base(name) != name {
+		return false
+	}
+	_, err := time.Parse(nameLayout, stem)
+	return err == nil
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+)
+
+// keyPrefix namespaces every key written by the cache.
+const keyPrefix = "admin:gateway-config:"
+
+// ConfigCache is a Redis read-through cache for compiled gateway config.
+// Payloads are stored under the current config revision, a counter that is
+// bumped whenever backends or routes change, so stale entries are never
+// read again and simply expire. Shared by all admin replicas, it keeps the
+// database out of the gateway pull path.
+type ConfigCache struct {
+	client *redis.Client
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+// NewConfigCache connects to the Redis server at url (redis://...). Cached
+// payloads expire after ttl even if no change is seen, which bounds
+// staleness after changes made outside the service, e.g. by "admin import"
+// against the database.
+func NewConfigCache(url string, ttl time.Duration, logger *zap.Logger) (*ConfigCache, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	return &ConfigCache{
+		client: redis.NewClient(opts),
+		ttl:    ttl,
+		logger: logger,
+	}, nil
+}
+
+// Get returns the cached payload of cluster for the current revision,
+// calling build and caching its result on a miss. Redis failures are logged and fall back
+// to build, so the cache never makes the endpoint less available.
+func (c *ConfigCache) Get(ctx context.Context, cluster string, build func() ([]byte, error)) ([]byte, error) {
+	revision, err := c.revision(ctx)
+	if err != nil {
+		c.logger.Warn("config cache unavailable", zap.Error(err))
+		return build()
+	}
+
+	key := keyPrefix + "rev:" + revision
+	if cluster != "" {
+		key += ":" + cluster
+	}
+	payload, err := c.client.Get(ctx, key).Bytes()
+	if err == nil {
+		return payload, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		c.logger.Warn("config cache read failed", zap.Error(err))
+		return build()
+	}
+
+	payload, err = build()
+	if err != nil {
+		return nil, err
+	}
+	// A change committed while building leaves a newer revision behind, so
+	// storing under the revision read above never serves stale data.
+	if err := c.client.Set(ctx, key, payload, c.ttl).Err(); err != nil {
+		c.logger.Warn("config cache write failed", zap.Error(err))
+	}
+	return payload, nil
+}
+
+// revision returns the current config revision, initialising it if the key
+// is missing. A fresh revision is seeded from the clock rather than zero so
+// that entries cached before the key was lost (evicted or flushed) cannot
+// be picked up again.
+func (c *ConfigCache) revision(ctx context.Context) (string, error) {
+	key := keyPrefix + "revision"
+	revision, err := c.client.Get(ctx, key).Result()
+	if err == nil {
+		return revision, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+
+	if err := c.client.SetNX(ctx, key, time.Now().UnixNano(), 0).Err(); err != nil {
+		return "", err
+	}
+	return c.client.Get(ctx, key).Result()
+}
+
+// Invalidate moves to a new revision so that the next read rebuilds the
+// payload.
+func (c *ConfigCache) Invalidate(ctx context.Context) error {
+	return c.client.Incr(ctx, keyPrefix+"revision").Err()
+}
+
+// Watch invalidates the cache on every backend, route, descriptor set or
+// route schema change published to broker until ctx is cancelled. It also
+// invalidates on start, since changes may have been made while the service
+// was down.
+func (c *ConfigCache) Watch(ctx context.Context, broker *events.Broker) {
+	filter := events.Filter{Types: map[string]bool{events.TypeBackend: true, events.TypeRoute: true, events.TypeDescriptor: true, events.TypeSchema: true}}
+
+	for {
+		sub := broker.Subscribe(filter, 64)
+		// Changes may have been missed before (re)subscribing
+		c.invalidate(ctx)
+
+		for open := true; open; {
+			select {
+			case <-ctx.Done():
+				broker.Unsubscribe(sub)
+				return
+			case _, open = <-sub.C:
+				if open {
+					c.invalidate(ctx)
+				}
+			}
+		}
+		// The broker dropped us for falling behind; subscribe again
+	}
+}
+
+func (c *ConfigCache) invalidate(ctx context.Context) {
+	if err := c.Invalidate(ctx); err != nil && ctx.Err() == nil {
+		c.logger.Warn("failed to invalidate config cache", zap.Error(err))
+	}
+}
+
+// Close closes the Redis connection.
+func (c *ConfigCache) Close() error {
+	return c.client.Close()
+}
@@ -0,0 +1,311 @@
+package compile
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/descriptor"
+)
+
+// Config is the configuration a gateway needs to serve traffic: enabled
+// backends and the enabled routes pointing at them. Secrets are never part
+// of it; gateways fetch them separately over an authenticated channel.
+type Config struct {
+	// Revision is the latest change the config includes, as the ID of its
+	// config history entry. Gateways acknowledge it once the config is
+	// applied.
+	Revision uint64 `json:"revision"`
+	// Cluster is the gateway cluster the config was compiled for, if any.
+	Cluster  string    `json:"cluster,omitempty"`
+	Backends []Backend `json:"backends"`
+	Routes   []Route   `json:"routes"`
+}
+
+// Backend is the gateway view of a backend.
+type Backend struct {
+	Name string `json:"name"`
+	Addr string `json:"addr"`
+	// Descriptors identifies the latest descriptor set of the backend, if
+	// it has one. Gateways fetch it from the registry to transcode.
+	Descriptors *Descriptors `json:"descriptors,omitempty"`
+	// CircuitBreaker holds the backend's defaults for its routes.
+	CircuitBreaker *config.CircuitBreaker `json:"circuit_breaker,omitempty"`
+	// Draining is set if gateways must send the backend no new sessions,
+	// letting existing streams finish.
+	Draining bool `json:"draining,omitempty"`
+	// Plugins holds the backend's plugin configs.
+	Plugins map[string]json.RawMessage `json:"plugins,omitempty"`
+}
+
+// Descriptors identifies a version of a backend's descriptor set.
+type Descriptors struct {
+	Version int    `json:"version"`
+	SHA256  string `json:"sha256"`
+}
+
+// Route is the gateway view of a route.
+type Route struct {
+	ID             uint   `json:"id"`
+	HTTPMethod     string `json:"http_method"`
+	HTTPPattern    string `json:"http_pattern"`
+	BackendName    string `json:"backend_name"`
+	BackendService string `json:"backend_service"`
+	BackendMethod  string `json:"backend_method"`
+	TimeoutMS      int    `json:"timeout_ms"`
+	APIVersion     string `json:"api_version,omitempty"`
+	// Deprecated is set on routes in the deprecated lifecycle state.
+	Deprecated bool `json:"deprecated,omitempty"`
+	// DeprecationHeaders are the response headers announcing the route's
+	// deprecation, if it has one.
+	DeprecationHeaders map[string]string `json:"deprecation_headers,omitempty"`
+	// MaxRequestBytes is the route's body limit, or the default if it has
+	// none.
+	MaxRequestBytes int `json:"max_request_bytes"`
+	// ContentTypes are the request media types accepted, or the default if
+	// the route sets none.
+	ContentTypes []string `json:"content_types"`
+	// Transcoding is set if the descriptor set of the backend defines the
+	// route's RPC.
+	Transcoding *Transcoding `json:"transcoding,omitempty"`
+	// Schema is set if the route has request or response schemas to
+	// validate bodies against.
+	Schema *Schema `json:"schema,omitempty"`
+	// Transform is set if the route rewrites bodies.
+	Transform *config.RouteTransform `json:"transform,omitempty"`
+	// Cache is set if the gateway may cache the route's responses.
+	Cache *config.RouteCache `json:"cache,omitempty"`
+	// CircuitBreaker is the route's settings merged over the defaults of
+	// its backend, so gateways need not resolve the inheritance.
+	CircuitBreaker *config.CircuitBreaker `json:"circuit_breaker,omitempty"`
+	// Rollout is set if the route serves only a share of the traffic.
+	Rollout *config.RouteRollout `json:"rollout,omitempty"`
+	// Experiment is set if the route has variants. Variants whose backend
+	// is missing or disabled are left out, their traffic going to the
+	// route's own target.
+	Experiment *config.RouteExperiment `json:"experiment,omitempty"`
+	// Mirror is set if requests are copied to another backend. It is left
+	// out while that backend is missing or disabled.
+	Mirror *config.RouteMirror `json:"mirror,omitempty"`
+	// Fallback is set if another target answers when this one fails. It is
+	// left out while its backend is missing or disabled.
+	Fallback *config.RouteFallback `json:"fallback,omitempty"`
+	// GRPC is set if the route overrides how requests map to the call.
+	GRPC *config.RouteGRPC `json:"grpc,omitempty"`
+	// Middlewares is the route's middleware chain, in order.
+	Middlewares []config.RouteMiddleware `json:"middlewares,omitempty"`
+	// Plugins holds the route's plugin configs.
+	Plugins map[string]json.RawMessage `json:"plugins,omitempty"`
+	// QueryParams maps query parameters into request fields when
+	// transcoding.
+	QueryParams []config.QueryParam `json:"query_params,omitempty"`
+}
+
+// Transcoding names the protobuf messages of a route's RPC, for converting
+// between JSON and protobuf.
+type Transcoding struct {
+	RequestType  string `json:"request_type"`
+	ResponseType string `json:"response_type"`
+}
+
+// Schema is the latest schema version of a route.
+type Schema struct {
+	Version  int               `json:"version"`
+	Request  *config.SchemaRef `json:"request,omitempty"`
+	Response *config.SchemaRef `json:"response,omitempty"`
+}
+
+// Revision returns the current config revision: the store's global
+// revision, bumped by every committed change.
+func Revision(store config.Store) (uint64, error) {
+	return store.GetRevision()
+}
+
+// Build compiles the current enabled configuration from the store for the
+// gateways of cluster, which may be empty for gateways outside any
+// cluster. See Compile.
+func Build(store config.Store, cluster string) (*Config, error) {
+	// Read the revision first: a change committed meanwhile may be
+	// included, but none up to the revision can be missing
+	revision, err := Revision(store)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := true
+	backends, err := store.GetBackends(&enabled)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := store.GetRoutes(&enabled)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Compile(store, backends, routes, cluster)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Revision = revision
+	return cfg, nil
+}
+
+// Compile compiles the given enabled backends and routes for the gateways
+// of cluster. Backends and routes scoped to other clusters are left out,
+// and so are retired routes and routes whose backend is missing, disabled
+// or not in the cluster, as the gateway must not or could not serve them.
+// If the store keeps descriptor sets, backends and routes carry
+// transcoding metadata from the latest set of each backend; if it keeps
+// route schemas, routes carry their latest schemas. Backends are sorted by
+// name and routes by pattern, method and ID, so the same configuration
+// always encodes to the same bytes.
+func Compile(store config.Store, backends []config.Backend, routes []config.Route, cluster string) (*Config, error) {
+	cfg := &Config{
+		Cluster:  cluster,
+		Backends: make([]Backend, 0, len(backends)),
+		Routes:   make([]Route, 0, len(routes)),
+	}
+
+	descriptors, _ := store.(config.DescriptorStore)
+	known := make(map[string]*config.Backend, len(backends))
+	files := map[string]*protoregistry.Files{}
+	for i := range backends {
+		b := &backends[i]
+		if !config.InCluster(b.Clusters, cluster) {
+			continue
+		}
+		known[b.Name] = b
+		backend := Backend{
+			Name:           b.Name,
+			Addr:           b.Addr,
+			CircuitBreaker: b.CircuitBreaker.Merge(nil),
+			Draining:       b.Status == config.BackendDraining,
+			Plugins:        b.Plugins,
+		}
+		if descriptors != nil {
+			set, err := descriptors.GetDescriptorSet(b.Name, 0)
+			if err != nil {
+				return nil, err
+			}
+			if set != nil {
+				if files[b.Name], err = descriptor.ParseSet(set.Data); err != nil {
+					return nil, fmt.Errorf("descriptor set %d of %s: %w", set.Version, b.Name, err)
+				}
+				backend.Descriptors = &Descriptors{Version: set.Version, SHA256: set.SHA256}
+			}
+		}
+		cfg.Backends = append(cfg.Backends, backend)
+	}
+
+	schemas := map[uint]*Schema{}
+	if schemaStore, ok := store.(config.SchemaStore); ok {
+		latest, err := schemaStore.GetLatestRouteSchemas()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range latest {
+			if s.Request != nil || s.Response != nil {
+				schemas[s.RouteID] = &Schema{Version: s.Version, Request: s.Request, Response: s.Response}
+			}
+		}
+	}
+
+	for _, r := range routes {
+		b, ok := known[r.BackendName]
+		if !ok || r.Lifecycle == config.LifecycleRetired || !config.InCluster(r.Clusters, cluster) {
+			continue
+		}
+		route := Route{
+			ID:                 r.ID,
+			HTTPMethod:         r.HTTPMethod,
+			HTTPPattern:        r.HTTPPattern,
+			BackendName:        r.BackendName,
+			BackendService:     r.BackendService,
+			BackendMethod:      r.BackendMethod,
+			TimeoutMS:          r.TimeoutMS,
+			APIVersion:         r.APIVersion,
+			Deprecated:         r.Lifecycle == config.LifecycleDeprecated,
+			DeprecationHeaders: deprecationHeaders(r.Deprecation),
+			MaxRequestBytes:    r.MaxRequestBytes,
+			ContentTypes:       r.ContentTypes,
+			Schema:             schemas[r.ID],
+			Transform:          r.Transform,
+			Cache:              r.Cache,
+			CircuitBreaker:     b.CircuitBreaker.Merge(r.CircuitBreaker),
+			Rollout:            r.Rollout,
+			GRPC:               r.GRPC,
+			Middlewares:        r.Middlewares,
+			Plugins:            r.Plugins,
+			QueryParams:        r.QueryParams,
+		}
+		if route.MaxRequestBytes == 0 {
+			route.MaxRequestBytes = config.DefaultMaxRequestBytes
+		}
+		if len(route.ContentTypes) == 0 {
+			route.ContentTypes = []string{config.DefaultContentType}
+		}
+		if e := r.Experiment; e != nil {
+			experiment := &config.RouteExperiment{HashKey: e.HashKey}
+			for _, v := range e.Variants {
+				if _, ok := known[v.Target(&r).BackendName]; ok {
+					experiment.Variants = append(experiment.Variants, v)
+				}
+			}
+			if len(experiment.Variants) > 0 {
+				route.Experiment = experiment
+			}
+		}
+		if m := r.Mirror; m != nil {
+			if _, ok := known[m.BackendName]; ok {
+				route.Mirror = m
+			}
+		}
+		if f := r.Fallback; f != nil {
+			if _, ok := known[f.Target(&r).BackendName]; ok {
+				route.Fallback = f
+			}
+		}
+		if f := files[r.BackendName]; f != nil {
+			if md, err := descriptor.Method(f, r.BackendService, r.BackendMethod); err == nil {
+				route.Transcoding = &Transcoding{
+					RequestType:  string(md.Input().FullName()),
+					ResponseType: string(md.Output().FullName()),
+				}
+			}
+		}
+		cfg.Routes = append(cfg.Routes, route)
+	}
+
+	sort.Slice(cfg.Backends, func(i, j int) bool { return cfg.Backends[i].Name < cfg.Backends[j].Name })
+	sort.Slice(cfg.Routes, func(i, j int) bool {
+		if cfg.Routes[i].HTTPPattern != cfg.Routes[j].HTTPPattern {
+			return cfg.Routes[i].HTTPPattern < cfg.Routes[j].HTTPPattern
+		}
+		if cfg.Routes[i].HTTPMethod != cfg.Routes[j].HTTPMethod {
+			return cfg.Routes[i].HTTPMethod < cfg.Routes[j].HTTPMethod
+		}
+		// Routes of different API versions share a method and pattern
+		return cfg.Routes[i].ID < cfg.Routes[j].ID
+	})
+
+	return cfg, nil
+}
+
+// deprecationHeaders returns the Deprecation, Sunset and Link headers for
+// d, or nil if d is nil.
+func deprecationHeaders(d *config.RouteDeprecation) map[string]string {
+	if d == nil {
+		return nil
+	}
+	headers := map[string]string{"Deprecation": fmt.Sprintf("@%d", d.Date.Unix())}
+	if d.Sunset != nil {
+		headers["Sunset"] = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+	if d.Link != "" {
+		headers["Link"] = fmt.Sprintf(`<%s>; rel="successor-version"`, d.Link)
+	}
+	return headers
+}
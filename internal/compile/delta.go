@@ -0,0 +1,234 @@
+package compile
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// Delta is the change between two compiled configs of the same cluster.
+// Applying it to the older config, removals first, yields the newer one up
+// to the order of its backends and routes.
+type Delta struct {
+	// From is the revision of the older config.
+	From uint64 `json:"from_revision"`
+	// Revision is the revision of the newer config.
+	Revision uint64 `json:"revision"`
+	// Backends are the backends added or changed, in full.
+	Backends []Backend `json:"backends"`
+	// RemovedBackends names the backends removed.
+	RemovedBackends []string `json:"removed_backends"`
+	// Routes are the routes added or changed, in full.
+	Routes []Route `json:"routes"`
+	// RemovedRoutes are the IDs of the routes removed.
+	RemovedRoutes []uint `json:"removed_routes"`
+}
+
+// Empty reports whether the delta changes no backend or route.
+func (d *Delta) Empty() bool {
+	return len(d.Backends) == 0 && len(d.RemovedBackends) == 0 && len(d.Routes) == 0 && len(d.RemovedRoutes) == 0
+}
+
+// Diff returns the delta that turns from into to. Backends are matched by
+// name and routes by ID; a backend or route is changed if its encoding
+// differs.
+func Diff(from, to *Config) (*Delta, error) {
+	delta := &Delta{
+		From:            from.Revision,
+		Revision:        to.Revision,
+		Backends:        []Backend{},
+		RemovedBackends: []string{},
+		Routes:          []Route{},
+		RemovedRoutes:   []uint{},
+	}
+
+	oldBackends := make(map[string]*Backend, len(from.Backends))
+	for i := range from.Backends {
+		oldBackends[from.Backends[i].Name] = &from.Backends[i]
+	}
+	for i := range to.Backends {
+		b := &to.Backends[i]
+		old, ok := oldBackends[b.Name]
+		delete(oldBackends, b.Name)
+		if ok {
+			changed, err := differs(old, b)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				continue
+			}
+		}
+		delta.Backends = append(delta.Backends, *b)
+	}
+	for i := range from.Backends {
+		if _, ok := oldBackends[from.Backends[i].Name]; ok {
+			delta.RemovedBackends = append(delta.RemovedBackends, from.Backends[i].Name)
+		}
+	}
+
+	oldRoutes := make(map[uint]*Route, len(from.Routes))
+	for i := range from.Routes {
+		oldRoutes[from.Routes[i].ID] = &from.Routes[i]
+	}
+	for i := range to.Routes {
+		r := &to.Routes[i]
+		old, ok := oldRoutes[r.ID]
+		delete(oldRoutes, r.ID)
+		if ok {
+			changed, err := differs(old, r)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				continue
+			}
+		}
+		delta.Routes = append(delta.Routes, *r)
+	}
+	for i := range from.Routes {
+		if _, ok := oldRoutes[from.Routes[i].ID]; ok {
+			delta.RemovedRoutes = append(delta.RemovedRoutes, from.Routes[i].ID)
+		}
+	}
+
+	return delta, nil
+}
+
+// differs reports whether the encodings of old and new differ.
+func differs(old, new interface{}) (bool, error) {
+	a, err := json.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	b, err := json.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(a, b), nil
+}
+
+// historyPage is the number of history entries read at a time by Changes.
+const historyPage = 200
+
+// Changes returns the delta from revision since to cfg, a config compiled
+// from store, working out which backends and routes changed from the
+// history recorded after since rather than from the config at since. The
+// delta may hold more than changed: every backend and route a change may
+// have affected, in its current form, and routes removed that the gateway
+// may never have had. Applying it is no different. With since 0 it holds
+// the whole config.
+func Changes(store config.Store, cfg *Config, since uint64) (*Delta, error) {
+	if since == 0 {
+		return Diff(&Config{}, cfg)
+	}
+
+	backends := map[string]bool{}
+	routes := map[uint]bool{}
+	for offset := 0; ; offset += historyPage {
+		entries, _, err := store.GetHistory(nil, nil, historyPage, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Revision <= since {
+				return changesOf(store, cfg, since, backends, routes)
+			}
+			for _, value := range []json.RawMessage{entry.OldValue, entry.NewValue} {
+				var ref struct {
+					Name        string `json:"name"`
+					BackendName string `json:"backend_name"`
+					RouteID     uint   `json:"route_id"`
+				}
+				if len(value) == 0 || json.Unmarshal(value, &ref) != nil {
+					continue
+				}
+				switch {
+				case entry.ConfigType == "backend" && ref.Name != "":
+					backends[ref.Name] = true
+				case entry.ConfigType == "descriptor" && ref.BackendName != "":
+					backends[ref.BackendName] = true
+				case entry.ConfigType == "schema" && ref.RouteID != 0:
+					routes[ref.RouteID] = true
+				}
+			}
+			if entry.ConfigType == "route" && entry.ConfigID != nil {
+				routes[*entry.ConfigID] = true
+			}
+		}
+		if len(entries) < historyPage {
+			return changesOf(store, cfg, since, backends, routes)
+		}
+	}
+}
+
+// changesOf returns the delta from revision since to cfg given the
+// backends and routes changed in between. Routes targeting a changed
+// backend are changed too, as their compiled form depends on it.
+func changesOf(store config.Store, cfg *Config, since uint64, backends map[string]bool, routes map[uint]bool) (*Delta, error) {
+	if len(backends) > 0 {
+		stored, err := store.GetRoutes(nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range stored {
+			if routeTargets(&r, backends) {
+				routes[r.ID] = true
+			}
+		}
+	}
+
+	delta := &Delta{
+		From:            since,
+		Revision:        cfg.Revision,
+		Backends:        []Backend{},
+		RemovedBackends: []string{},
+		Routes:          []Route{},
+		RemovedRoutes:   []uint{},
+	}
+	for _, b := range cfg.Backends {
+		if backends[b.Name] {
+			delta.Backends = append(delta.Backends, b)
+			delete(backends, b.Name)
+		}
+	}
+	for name := range backends {
+		delta.RemovedBackends = append(delta.RemovedBackends, name)
+	}
+	sort.Strings(delta.RemovedBackends)
+	for _, r := range cfg.Routes {
+		if routes[r.ID] {
+			delta.Routes = append(delta.Routes, r)
+			delete(routes, r.ID)
+		}
+	}
+	for id := range routes {
+		delta.RemovedRoutes = append(delta.RemovedRoutes, id)
+	}
+	sort.Slice(delta.RemovedRoutes, func(i, j int) bool { return delta.RemovedRoutes[i] < delta.RemovedRoutes[j] })
+	return delta, nil
+}
+
+// routeTargets reports whether r sends requests to any of backends, as
+// its own target or that of its mirror, fallback or experiment variants.
+func routeTargets(r *config.Route, backends map[string]bool) bool {
+	if backends[r.BackendName] {
+		return true
+	}
+	if r.Mirror != nil && backends[r.Mirror.BackendName] {
+		return true
+	}
+	if r.Fallback != nil && backends[r.Fallback.BackendName] {
+		return true
+	}
+	if r.Experiment != nil {
+		for _, v := range r.Experiment.Variants {
+			if backends[v.BackendName] {
+				return true
+			}
+		}
+	}
+	return false
+}
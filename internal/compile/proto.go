@@ -0,0 +1,178 @@
+package compile
+
+import (
+	"encoding/json"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/pkg/api/configv1"
+)
+
+// Proto returns the config as a protobuf message, for gateways that accept
+// application/x-protobuf.
+func (c *Config) Proto() (*configv1.GatewayConfig, error) {
+	msg := &configv1.GatewayConfig{
+		Revision: c.Revision,
+		Cluster:  c.Cluster,
+		Backends: protoBackends(c.Backends),
+	}
+	var err error
+	if msg.Routes, err = protoRoutes(c.Routes); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Proto returns the delta as a protobuf message, for gateways that accept
+// application/x-protobuf.
+func (d *Delta) Proto() (*configv1.ConfigDelta, error) {
+	msg := &configv1.ConfigDelta{
+		FromRevision:    d.From,
+		Revision:        d.Revision,
+		Backends:        protoBackends(d.Backends),
+		RemovedBackends: d.RemovedBackends,
+		RemovedRoutes:   make([]uint64, len(d.RemovedRoutes)),
+	}
+	for i, id := range d.RemovedRoutes {
+		msg.RemovedRoutes[i] = uint64(id)
+	}
+	var err error
+	if msg.Routes, err = protoRoutes(d.Routes); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func protoBackends(backends []Backend) []*configv1.Backend {
+	msgs := make([]*configv1.Backend, len(backends))
+	for i, b := range backends {
+		msgs[i] = &configv1.Backend{
+			Name:           b.Name,
+			Addr:           b.Addr,
+			CircuitBreaker: protoCircuitBreaker(b.CircuitBreaker),
+			Draining:       b.Draining,
+			Plugins:        protoPlugins(b.Plugins),
+		}
+		if d := b.Descriptors; d != nil {
+			msgs[i].Descriptors = &configv1.Descriptors{Version: int32(d.Version), Sha256: d.SHA256}
+		}
+	}
+	return msgs
+}
+
+func protoRoutes(routes []Route) ([]*configv1.Route, error) {
+	msgs := make([]*configv1.Route, len(routes))
+	for i, r := range routes {
+		msg := &configv1.Route{
+			Id:                 uint64(r.ID),
+			HttpMethod:         r.HTTPMethod,
+			HttpPattern:        r.HTTPPattern,
+			BackendName:        r.BackendName,
+			BackendService:     r.BackendService,
+			BackendMethod:      r.BackendMethod,
+			TimeoutMs:          int32(r.TimeoutMS),
+			ApiVersion:         r.APIVersion,
+			Deprecated:         r.Deprecated,
+			DeprecationHeaders: r.DeprecationHeaders,
+			MaxRequestBytes:    int64(r.MaxRequestBytes),
+			ContentTypes:       r.ContentTypes,
+			CircuitBreaker:     protoCircuitBreaker(r.CircuitBreaker),
+			Plugins:            protoPlugins(r.Plugins),
+		}
+		if t := r.Transcoding; t != nil {
+			msg.Transcoding = &configv1.Transcoding{RequestType: t.RequestType, ResponseType: t.ResponseType}
+		}
+		if s := r.Schema; s != nil {
+			msg.Schema = &configv1.Schema{Version: int32(s.Version), Request: protoSchemaRef(s.Request), Response: protoSchemaRef(s.Response)}
+		}
+		if t := r.Transform; t != nil {
+			msg.Transform = &configv1.Transform{Request: protoBodyTemplate(t.Request), Response: protoBodyTemplate(t.Response)}
+		}
+		if c := r.Cache; c != nil {
+			msg.Cache = &configv1.Cache{TtlSeconds: int32(c.TTLSeconds), VaryHeaders: c.VaryHeaders, AllowPost: c.AllowPost}
+		}
+		if ro := r.Rollout; ro != nil {
+			msg.Rollout = &configv1.Rollout{Percent: int32(ro.Percent), HashKey: ro.HashKey}
+		}
+		if e := r.Experiment; e != nil {
+			msg.Experiment = &configv1.Experiment{HashKey: e.HashKey}
+			for _, v := range e.Variants {
+				variant := &configv1.Variant{
+					Name:           v.Name,
+					BackendName:    v.BackendName,
+					BackendService: v.BackendService,
+					BackendMethod:  v.BackendMethod,
+					Percent:        int32(v.Percent),
+				}
+				if m := v.Match; m != nil {
+					variant.Match = &configv1.VariantMatch{Header: m.Header, Value: m.Value}
+				}
+				msg.Experiment.Variants = append(msg.Experiment.Variants, variant)
+			}
+		}
+		if m := r.Mirror; m != nil {
+			msg.Mirror = &configv1.Mirror{BackendName: m.BackendName, BackendService: m.BackendService, BackendMethod: m.BackendMethod, Percent: int32(m.Percent)}
+		}
+		if f := r.Fallback; f != nil {
+			msg.Fallback = &configv1.Fallback{BackendName: f.BackendName, BackendService: f.BackendService, BackendMethod: f.BackendMethod}
+		}
+		if g := r.GRPC; g != nil {
+			msg.Grpc = &configv1.GRPC{PropagateDeadline: g.PropagateDeadline, Allow: g.Allow, Deny: g.Deny}
+			for _, h := range g.Headers {
+				msg.Grpc.Headers = append(msg.Grpc.Headers, &configv1.MetadataHeader{Header: h.Header, Key: h.Key})
+			}
+		}
+		for _, m := range r.Middlewares {
+			middleware := &configv1.Middleware{Name: m.Name}
+			if len(m.Params) > 0 {
+				params, err := json.Marshal(m.Params)
+				if err != nil {
+					return nil, err
+				}
+				middleware.Params = params
+			}
+			msg.Middlewares = append(msg.Middlewares, middleware)
+		}
+		for _, q := range r.QueryParams {
+			msg.QueryParams = append(msg.QueryParams, &configv1.QueryParam{Name: q.Name, Field: q.Field, Default: q.Default, Required: q.Required})
+		}
+		msgs[i] = msg
+	}
+	return msgs, nil
+}
+
+func protoCircuitBreaker(cb *config.CircuitBreaker) *configv1.CircuitBreaker {
+	if cb == nil {
+		return nil
+	}
+	return &configv1.CircuitBreaker{
+		MaxRequests:        int32(cb.MaxRequests),
+		Consecutive_5Xx:    int32(cb.Consecutive5xx),
+		EjectionSeconds:    int32(cb.EjectionSeconds),
+		MaxEjectionPercent: int32(cb.MaxEjectionPercent),
+	}
+}
+
+func protoPlugins(plugins map[string]json.RawMessage) map[string][]byte {
+	if len(plugins) == 0 {
+		return nil
+	}
+	msgs := make(map[string][]byte, len(plugins))
+	for name, cfg := range plugins {
+		msgs[name] = cfg
+	}
+	return msgs
+}
+
+func protoSchemaRef(ref *config.SchemaRef) *configv1.SchemaRef {
+	if ref == nil {
+		return nil
+	}
+	return &configv1.SchemaRef{JsonSchema: ref.JSONSchema, Message: ref.Message}
+}
+
+func protoBodyTemplate(t *config.BodyTemplate) *configv1.BodyTemplate {
+	if t == nil {
+		return nil
+	}
+	return &configv1.BodyTemplate{Language: t.Language, Template: t.Template}
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is matched by the errors a store returns while its
+// circuit breaker is open, instead of waiting for its backing service to
+// time out.
+var ErrUnavailable = errors.New("database unavailable: circuit breaker open")
+
+// UnavailableError is returned by a store while its circuit breaker is
+// open. It matches ErrUnavailable.
+type UnavailableError struct {
+	// RetryAfter is how long until the breaker next probes the backing
+	// service.
+	RetryAfter time.Duration
+}
+
+func (e *UnavailableError) Error() string {
+	return ErrUnavailable.Error()
+}
+
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// probeTimeout bounds each probe of a backing service whose breaker is
+// open.
+const probeTimeout = 2 * time.Second
+
+// BreakerState is the state of a store's circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets calls through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails calls fast until a probe succeeds.
+	BreakerOpen
+	// BreakerHalfOpen fails calls fast while a probe is running.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// BreakerPolicy sets when a store's circuit breaker opens and how it
+// finds its backing service back.
+type BreakerPolicy struct {
+	// Threshold is the number of calls in a row failing to reach the
+	// backing service that opens the breaker; zero disables it.
+	Threshold int
+	// Cooldown is the wait before each probe of the backing service
+	// while the breaker is open.
+	Cooldown time.Duration
+	// OnChange, if set, is called with the new state on every
+	// transition, with the breaker locked: it must not call the store.
+	OnChange func(BreakerState)
+}
+
+// circuit is a circuit breaker. It opens once policy.Threshold calls in a
+// row have failed with errors failed accepts, then probes every
+// policy.Cooldown until probe succeeds, failing calls with ErrUnavailable
+// meanwhile.
+type circuit struct {
+	failed func(error) bool
+	probe  func(ctx context.Context) error
+
+	mu       sync.Mutex
+	policy   BreakerPolicy
+	state    BreakerState
+	failures int
+	timer    *time.Timer
+	// probeAt is when the breaker next probes, while open.
+	probeAt time.Time
+	stopped bool
+}
+
+func (c *circuit) setPolicy(p BreakerPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.policy = p
+}
+
+func (c *circuit) State() BreakerState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// do runs fn unless the breaker is open, recording its outcome.
+func (c *circuit) do(fn func() error) error {
+	c.mu.Lock()
+	state, probeAt := c.state, c.probeAt
+	c.mu.Unlock()
+	if state != BreakerClosed {
+		return &UnavailableError{RetryAfter: max(time.Until(probeAt), 0)}
+	}
+
+	err := fn()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Calls finishing after the breaker opened are left to the probe
+	if c.state != BreakerClosed || c.policy.Threshold <= 0 {
+		return err
+	}
+	if err == nil || !c.failed(err) {
+		c.failures = 0
+		return err
+	}
+	c.failures++
+	if c.failures >= c.policy.Threshold {
+		c.open()
+	}
+	return err
+}
+
+// open opens the breaker and schedules a probe. c.mu must be held.
+func (c *circuit) open() {
+	if c.stopped {
+		return
+	}
+	c.setState(BreakerOpen)
+	c.probeAt = time.Now().Add(c.policy.Cooldown)
+	c.timer = time.AfterFunc(c.policy.Cooldown, c.runProbe)
+}
+
+// runProbe closes the breaker if the backing service answers, or opens it
+// again otherwise.
+func (c *circuit) runProbe() {
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return
+	}
+	c.setState(BreakerHalfOpen)
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	err := c.probe(ctx)
+	cancel()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.open()
+		return
+	}
+	c.failures = 0
+	c.setState(BreakerClosed)
+}
+
+// setState changes the state, reporting the transition. c.mu must be held.
+func (c *circuit) setState(s BreakerState) {
+	if c.state == s {
+		return
+	}
+	c.state = s
+	if c.policy.OnChange != nil {
+		c.policy.OnChange(s)
+	}
+}
+
+// stop cancels any pending probe, leaving the breaker as it is.
+func (c *circuit) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+}
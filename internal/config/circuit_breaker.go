@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Defaults applied to outlier detection settings left unset.
+const (
+	DefaultEjectionSeconds    = 30
+	DefaultMaxEjectionPercent = 10
+)
+
+// MaxEjectionSeconds bounds CircuitBreaker.EjectionSeconds.
+const MaxEjectionSeconds = 60 * 60
+
+// CircuitBreaker limits the load gateways put on a backend and ejects its
+// failing hosts. Set on a backend, it is the default of all its routes; set
+// on a route, every non-zero field overrides the backend's. Zero fields
+// are unset.
+type CircuitBreaker struct {
+	// MaxRequests bounds the concurrent requests; further requests fail
+	// fast with 503. Zero means unlimited.
+	MaxRequests int `json:"max_requests,omitempty"`
+	// Consecutive5xx is the number of consecutive 5xx responses after
+	// which a host is ejected. Zero disables outlier detection.
+	Consecutive5xx int `json:"consecutive_5xx,omitempty"`
+	// EjectionSeconds is how long a host is ejected the first time; it is
+	// multiplied by the number of times the host has been ejected.
+	EjectionSeconds int `json:"ejection_seconds,omitempty"`
+	// MaxEjectionPercent bounds the share of the backend's hosts ejected
+	// at once.
+	MaxEjectionPercent int `json:"max_ejection_percent,omitempty"`
+}
+
+// Validate checks the field ranges. A nil circuit breaker is valid and
+// means nothing is set.
+func (c *CircuitBreaker) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.MaxRequests < 0 || c.Consecutive5xx < 0 || c.EjectionSeconds < 0 || c.MaxEjectionPercent < 0 {
+		return errors.New("circuit_breaker fields cannot be negative")
+	}
+	if c.EjectionSeconds > MaxEjectionSeconds {
+		return fmt.Errorf("circuit_breaker.ejection_seconds must be at most %d", MaxEjectionSeconds)
+	}
+	if c.MaxEjectionPercent > 100 {
+		return errors.New("circuit_breaker.max_ejection_percent must be at most 100")
+	}
+	if *c == (CircuitBreaker{}) {
+		return errors.New("circuit_breaker must set at least one field")
+	}
+	return nil
+}
+
+// Merge returns the settings in effect for a route with override under
+// backend defaults, with outlier detection defaults applied, or nil if
+// neither sets anything.
+func (c *CircuitBreaker) Merge(override *CircuitBreaker) *CircuitBreaker {
+	if c == nil && override == nil {
+		return nil
+	}
+
+	var merged CircuitBreaker
+	if c != nil {
+		merged = *c
+	}
+	if o := override; o != nil {
+		if o.MaxRequests != 0 {
+			merged.MaxRequests = o.MaxRequests
+		}
+		if o.Consecutive5xx != 0 {
+			merged.Consecutive5xx = o.Consecutive5xx
+		}
+		if o.EjectionSeconds != 0 {
+			merged.EjectionSeconds = o.EjectionSeconds
+		}
+		if o.MaxEjectionPercent != 0 {
+			merged.MaxEjectionPercent = o.MaxEjectionPercent
+		}
+	}
+
+	if merged.Consecutive5xx > 0 {
+		if merged.EjectionSeconds == 0 {
+			merged.EjectionSeconds = DefaultEjectionSeconds
+		}
+		if merged.MaxEjectionPercent == 0 {
+			merged.MaxEjectionPercent = DefaultMaxEjectionPercent
+		}
+	}
+	return &merged
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MaxClusters bounds the gateway clusters a backend or route is scoped to.
+const MaxClusters = 16
+
+// clusterName matches gateway cluster names such as "public" or
+// "internal-eu".
+var clusterName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,62}[a-z0-9])?$`)
+
+// ValidateClusterName checks the name of a gateway cluster.
+func ValidateClusterName(name string) error {
+	if !clusterName.MatchString(name) {
+		return fmt.Errorf("invalid cluster %q (lowercase letters, digits and dashes, at most 64 characters)", name)
+	}
+	return nil
+}
+
+// ValidateClusters checks the gateway clusters a backend or route is
+// scoped to. An empty list scopes it to no cluster in particular, so it
+// is served by every gateway.
+func ValidateClusters(clusters []string) error {
+	if len(clusters) > MaxClusters {
+		return fmt.Errorf("too many clusters (at most %d)", MaxClusters)
+	}
+	seen := make(map[string]bool, len(clusters))
+	for _, c := range clusters {
+		if err := ValidateClusterName(c); err != nil {
+			return err
+		}
+		if seen[c] {
+			return fmt.Errorf("duplicate cluster %s", c)
+		}
+		seen[c] = true
+	}
+	return nil
+}
+
+// InCluster reports whether a backend or route scoped to clusters is
+// served by the gateways of cluster. Unscoped resources are served by
+// every gateway; scoped ones only by gateways of a listed cluster, which
+// excludes gateways that name no cluster.
+func InCluster(clusters []string, cluster string) bool {
+	if len(clusters) == 0 {
+		return true
+	}
+	for _, c := range clusters {
+		if c == cluster {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// DefaultContentType is what routes without content_types accept.
+const DefaultContentType = "application/json"
+
+// MaxContentTypes bounds the content types of a route.
+const MaxContentTypes = 16
+
+// validateContentTypes checks the media types a route accepts,
+// lowercasing them. Entries are "type/subtype" or "type/*", without
+// parameters.
+func validateContentTypes(types []string) error {
+	if len(types) > MaxContentTypes {
+		return fmt.Errorf("too many content_types (at most %d)", MaxContentTypes)
+	}
+	seen := make(map[string]bool, len(types))
+	for i, t := range types {
+		mediaType, params, err := mime.ParseMediaType(t)
+		if err != nil || !strings.Contains(mediaType, "/") {
+			return fmt.Errorf("invalid content_types[%d]: %q is not a media type", i, t)
+		}
+		if len(params) > 0 {
+			return fmt.Errorf("invalid content_types[%d]: %q has parameters", i, t)
+		}
+		if strings.HasPrefix(mediaType, "*/") {
+			return fmt.Errorf("invalid content_types[%d]: %q has a wildcard type", i, t)
+		}
+		if seen[mediaType] {
+			return fmt.Errorf("duplicate content_types entry %s", mediaType)
+		}
+		seen[mediaType] = true
+		types[i] = mediaType
+	}
+	return nil
+}
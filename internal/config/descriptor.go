@@ -0,0 +1,20 @@
+package config
+
+import "time"
+
+// DescriptorSet is a version of the protobuf descriptors of a backend's
+// services: a serialized FileDescriptorSet, as written by protoc
+// --descriptor_set_out, uploaded by an operator. Versions count up from 1
+// per backend and the latest one is in effect. Data is omitted from
+// listings and never encoded as JSON.
+type DescriptorSet struct {
+	ID          uint      `json:"id"`
+	BackendName string    `json:"backend_name"`
+	Version     int       `json:"version"`
+	SHA256      string    `json:"sha256"`
+	Size        int       `json:"size"`
+	Services    []string  `json:"services"`
+	UploadedBy  string    `json:"uploaded_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	Data        []byte    `json:"-"`
+}
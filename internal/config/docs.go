@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Bounds of route documentation.
+const (
+	MaxDocsSummary     = 200
+	MaxDocsExampleSize = 64 << 10
+)
+
+// RouteDocs documents a route for API consumers. It is published in the
+// OpenAPI export, so that routes are the source of truth for the API
+// reference.
+type RouteDocs struct {
+	// Summary is a one-line description of the operation; the route's
+	// description then serves as the long form.
+	Summary string `json:"summary,omitempty"`
+	// RequestExample and ResponseExample are example JSON bodies.
+	RequestExample  json.RawMessage `json:"request_example,omitempty"`
+	ResponseExample json.RawMessage `json:"response_example,omitempty"`
+	// ExternalURL links to further documentation.
+	ExternalURL string `json:"external_url,omitempty"`
+}
+
+// Validate checks the summary, examples and link of the docs. Nil docs
+// are valid.
+func (d *RouteDocs) Validate() error {
+	if d == nil {
+		return nil
+	}
+	if utf8.RuneCountInString(d.Summary) > MaxDocsSummary {
+		return fmt.Errorf("docs.summary too long (at most %d characters)", MaxDocsSummary)
+	}
+	if strings.ContainsAny(d.Summary, "\r\n") {
+		return errors.New("docs.summary must be a single line")
+	}
+	if err := validateExample("request_example", d.RequestExample); err != nil {
+		return err
+	}
+	if err := validateExample("response_example", d.ResponseExample); err != nil {
+		return err
+	}
+	if d.ExternalURL != "" && !validHTTPURL(d.ExternalURL) {
+		return fmt.Errorf("invalid docs.external_url %q (must be an absolute http or https URL)", d.ExternalURL)
+	}
+	return nil
+}
+
+// validateExample checks an optional example body of the docs.
+func validateExample(name string, example json.RawMessage) error {
+	if example == nil {
+		return nil
+	}
+	if len(example) > MaxDocsExampleSize {
+		return fmt.Errorf("docs.%s too large (at most %d bytes)", name, MaxDocsExampleSize)
+	}
+	if !json.Valid(example) {
+		return fmt.Errorf("docs.%s is not valid JSON", name)
+	}
+	return nil
+}
+
+// validHTTPURL reports whether s is an absolute http or https URL that
+// can be quoted in a Link header.
+func validHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && !strings.ContainsAny(s, "<> \"")
+}
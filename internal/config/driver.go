@@ -0,0 +1,387 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/secret"
+)
+
+// Driver opens a Store from a driver-specific data source name.
+type Driver func(dsn string) (Store, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{}
+)
+
+// RegisterDriver makes a store driver available by name, typically from
+// an init function in the file implementing it. It panics if the name is
+// already registered.
+func RegisterDriver(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if driver == nil {
+		panic("config: RegisterDriver driver is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("config: RegisterDriver called twice for driver " + name)
+	}
+	drivers[name] = driver
+}
+
+// Drivers returns the names of the registered drivers, sorted.
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open opens a Store with the named driver.
+func Open(driver, dsn string) (Store, error) {
+	driversMu.RLock()
+	open, ok := drivers[driver]
+	driversMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown store driver %q (available: %s)", driver, strings.Join(Drivers(), ", "))
+	}
+	return open(dsn)
+}
+
+// Optional capabilities a Store may implement. Callers check for them with
+// a type assertion and degrade or refuse gracefully when missing.
+type (
+	// Migrator manages the store's schema.
+	Migrator interface {
+		Migrate() error
+		MigrateTo(version int) error
+		MigrateDown() (int, error)
+		MigrationStatus() ([]MigrationState, error)
+	}
+
+	// Encrypter stores sensitive fields encrypted with a cipher.
+	Encrypter interface {
+		SetCipher(c secret.Cipher)
+		RotateSecrets() (int, error)
+	}
+
+	// Watcher picks up changes made to the store's data outside the
+	// service, such as hand edits to the files of a FileStore.
+	Watcher interface {
+		// Watch reloads the data on every outside change until ctx is
+		// done, passing the history recorded for each changed backend
+		// and route to onChange. Data that fails to load is reported to
+		// onError and the previous data kept.
+		Watch(ctx context.Context, onChange func(*ConfigHistory), onError func(error)) error
+	}
+
+	// Retrier retries operations failing with transient errors of the
+	// store's backing service.
+	Retrier interface {
+		SetRetryPolicy(p RetryPolicy)
+	}
+
+	// Breaker fails calls fast with ErrUnavailable after repeated failures
+	// to reach the store's backing service, until a probe reaches it.
+	Breaker interface {
+		SetBreakerPolicy(p BreakerPolicy)
+		BreakerState() BreakerState
+	}
+
+	// Pinger reports whether the store's backing service is reachable.
+	Pinger interface {
+		Ping(ctx context.Context) error
+	}
+
+	// LatencyStore keeps backend health check samples for reporting.
+	LatencyStore interface {
+		RecordLatency(samples []LatencySample) error
+		GetLatency(backend string, since time.Time) ([]LatencySample, error)
+		PruneLatency(before time.Time) (int64, error)
+	}
+
+	// StatsStore keeps per-route traffic aggregates reported by gateways.
+	StatsStore interface {
+		RecordRouteStats(stats []RouteStats) error
+		GetRouteStats(routeID uint, since time.Time) ([]RouteStats, error)
+		SumRouteStats(since time.Time) ([]RouteStats, error)
+		PruneRouteStats(before time.Time) (int64, error)
+	}
+
+	// SnapshotStore keeps named snapshots of the whole configuration.
+	SnapshotStore interface {
+		CreateSnapshot(snapshot *Snapshot) error
+		GetSnapshots() ([]Snapshot, error)
+		GetSnapshotByID(id uint) (*Snapshot, error)
+		DeleteSnapshot(id uint) error
+	}
+
+	// DescriptorStore keeps versioned protobuf descriptor sets of
+	// backends.
+	DescriptorStore interface {
+		CreateDescriptorSet(set *DescriptorSet) error
+		GetDescriptorSets(backend string) ([]DescriptorSet, error)
+		GetDescriptorSet(backend string, version int) (*DescriptorSet, error)
+	}
+
+	// SchemaStore keeps versioned request and response schemas of routes.
+	SchemaStore interface {
+		CreateRouteSchema(schema *RouteSchema) error
+		GetRouteSchemas(routeID uint) ([]RouteSchema, error)
+		GetRouteSchema(routeID uint, version int) (*RouteSchema, error)
+		GetLatestRouteSchemas() ([]RouteSchema, error)
+	}
+
+	// PluginSchemaStore keeps the JSON Schemas registered for plugin
+	// names.
+	PluginSchemaStore interface {
+		GetPluginSchemas() ([]PluginSchema, error)
+		GetPluginSchema(name string) (*PluginSchema, error)
+		SetPluginSchema(schema *PluginSchema) error
+		DeletePluginSchema(name string) error
+	}
+
+	// JobStore keeps background jobs, so that they survive restarts.
+	JobStore interface {
+		CreateJob(job *Job) error
+		// GetJobs returns jobs without their params, newest first,
+		// optionally limited to one kind and status.
+		GetJobs(kind, status string, limit int) ([]Job, error)
+		GetJob(id uint) (*Job, error)
+		// ClaimJob moves the oldest pending job to running and returns
+		// it, or nil if there is none.
+		ClaimJob() (*Job, error)
+		// UpdateJobProgress records the progress of a running job, which
+		// also tells that its runner is alive.
+		UpdateJobProgress(id uint, progress JobProgress) error
+		// FinishJob records the status, result and error of a job.
+		FinishJob(job *Job) error
+		// RequeueJob makes a job in status from pending again, reporting
+		// whether it was in that status.
+		RequeueJob(id uint, from string) (bool, error)
+		// RequeueStaleJobs makes running jobs not updated since before
+		// pending again, as their runner is gone.
+		RequeueStaleJobs(before time.Time) (int64, error)
+		// PruneJobs deletes jobs finished before the given time.
+		PruneJobs(before time.Time) (int64, error)
+	}
+
+	// GatewayStore keeps the registry of running gateway instances and
+	// the config each has loaded, for drift detection.
+	GatewayStore interface {
+		// RegisterGateway adds a gateway instance or replaces a known
+		// one, setting its registration and last seen times.
+		RegisterGateway(gateway *GatewayInstance) error
+		// GatewayHeartbeat marks a gateway instance as seen now, also
+		// recording its loaded config unless configSHA256 is empty and
+		// its revision unless it is 0. It reports whether the instance is
+		// known.
+		GatewayHeartbeat(id, configSHA256 string, revision uint64) (bool, error)
+		// ReportGatewayConfig records the config a gateway instance of
+		// cluster has loaded and its revision, adding the instance if it
+		// is new. A failure to apply a revision up to the loaded one is
+		// forgotten.
+		ReportGatewayConfig(id, cluster, configSHA256 string, revision uint64) error
+		// ReportGatewayFailure records that a gateway instance of
+		// cluster failed to apply a config revision, adding the instance
+		// if it is new.
+		ReportGatewayFailure(id, cluster string, revision uint64, failure string) error
+		// GetGateway returns a gateway instance, or nil if it is not
+		// known.
+		GetGateway(id string) (*GatewayInstance, error)
+		// GetGateways returns every known gateway instance, ordered by ID.
+		GetGateways() ([]GatewayInstance, error)
+		// PruneGateways deletes instances not seen since before the
+		// given time.
+		PruneGateways(before time.Time) (int64, error)
+	}
+
+	// RolloutStore keeps config rollouts and the traffic gateways report
+	// while one is in progress.
+	RolloutStore interface {
+		// CreateRollout starts a rollout, setting its ID, status and
+		// creation time. It reports false, creating nothing, if another
+		// rollout is in progress.
+		CreateRollout(rollout *ConfigRollout) (bool, error)
+		// GetRollouts returns every rollout without its baseline, newest
+		// first.
+		GetRollouts() ([]ConfigRollout, error)
+		// GetRollout returns a rollout with its baseline, or nil if it
+		// does not exist.
+		GetRollout(id uint) (*ConfigRollout, error)
+		// GetActiveRollout returns the rollout in progress without its
+		// baseline, or nil if there is none.
+		GetActiveRollout() (*ConfigRollout, error)
+		// FinishRollout moves a rollout in progress to status. It
+		// reports false if the rollout is not in progress.
+		FinishRollout(id uint, status, operator string) (bool, error)
+		// AddRolloutHealth adds to the traffic a gateway instance has
+		// reported during a rollout.
+		AddRolloutHealth(id uint, health *RolloutHealth) error
+		// GetRolloutHealth returns the traffic reported during a
+		// rollout, ordered by gateway.
+		GetRolloutHealth(id uint) ([]RolloutHealth, error)
+	}
+
+	// OutboxStore keeps change events until they are delivered to every
+	// sink, along with each sink's delivery state.
+	OutboxStore interface {
+		// AppendOutbox records an event. It must be called in the
+		// transaction of the change, after CreateHistory, with its
+		// revision.
+		AppendOutbox(entry *OutboxEntry) error
+		// GetOutbox returns up to limit entries with a revision after
+		// after, oldest first.
+		GetOutbox(after uint64, limit int) ([]OutboxEntry, error)
+		// GetOutboxSinks returns the state of every sink, ordered by
+		// name.
+		GetOutboxSinks() ([]OutboxSink, error)
+		// ClaimOutboxSink leases a sink to owner until now plus ttl, or
+		// renews its lease, and returns its state. It returns nil if
+		// another owner holds an unexpired lease. A new sink starts
+		// after the latest entry: it is not delivered past changes.
+		ClaimOutboxSink(name, owner string, ttl time.Duration) (*OutboxSink, error)
+		// AckOutboxSink records that owner delivered the entries of a
+		// sink up to revision, clearing its failures. It reports false,
+		// recording nothing, if owner no longer holds the lease.
+		AckOutboxSink(name, owner string, revision uint64) (bool, error)
+		// FailOutboxSink records a failed attempt to deliver the entries
+		// of a sink and when to try again, if owner holds the lease.
+		FailOutboxSink(name, owner, message string, retryAt time.Time) error
+		// PruneOutbox deletes the entries recorded before the given
+		// time and returns how many were removed.
+		PruneOutbox(before time.Time) (int64, error)
+	}
+
+	// TeamStore keeps the teams that own backends and routes.
+	TeamStore interface {
+		// GetTeams returns every team, ordered by name.
+		GetTeams() ([]Team, error)
+		// GetTeam returns a team by name, or nil if it does not exist.
+		GetTeam(name string) (*Team, error)
+		CreateTeam(team *Team) error
+		// UpdateTeam updates the description and contact of a team.
+		UpdateTeam(name string, team *Team) error
+		// DeleteTeam removes a team along with its members.
+		DeleteTeam(name string) error
+
+		// GetTeamMembers returns the members of a team, ordered by name.
+		GetTeamMembers(team string) ([]TeamMember, error)
+		// AddTeamMember adds a member to a team; adding an existing member
+		// again is not an error.
+		AddTeamMember(member *TeamMember) error
+		RemoveTeamMember(team, member string) error
+		// GetMemberTeams returns the names of the teams member belongs to.
+		GetMemberTeams(member string) ([]string, error)
+	}
+
+	// UserStore keeps the local users that sign in with a password.
+	UserStore interface {
+		// GetUsers returns every user, ordered by name.
+		GetUsers() ([]User, error)
+		// GetUser returns a user by name, or nil if it does not exist.
+		GetUser(name string) (*User, error)
+		CreateUser(user *User) error
+		// UpdateUser updates the display name, external ID, role and the
+		// disabled and must change password flags of a user.
+		UpdateUser(name string, user *User) error
+		// SetUserPassword replaces the password hash of a user, recording
+		// changedAt as when it was set.
+		SetUserPassword(name, hash string, mustChange bool, changedAt time.Time) error
+		// RecordUserLogin records a successful sign in, clearing the
+		// failed sign ins and any lockout.
+		RecordUserLogin(name string, at time.Time) error
+		// RecordFailedLogin counts a failed sign in at at, forgetting the
+		// failures before since, and returns the failures counted. It does
+		// nothing for a missing user.
+		RecordFailedLogin(name string, at, since time.Time) (int, error)
+		// LockUser locks a user out until until.
+		LockUser(name string, until time.Time) error
+		// UnlockUser lifts the lockout of a user and clears its failed
+		// sign ins.
+		UnlockUser(name string) error
+		// DeleteUser removes a user along with its sessions, recovery
+		// codes and group memberships.
+		DeleteUser(name string) error
+
+		// GetUserTOTP returns the TOTP authenticator of a user, or nil if
+		// it has none.
+		GetUserTOTP(name string) (*UserTOTP, error)
+		// SetUserTOTP stores a new authenticator secret for a user, not
+		// enabled until EnableUserTOTP, replacing any previous one along
+		// with its recovery codes.
+		SetUserTOTP(name, secret string) error
+		// EnableUserTOTP enables the authenticator of a user, recording
+		// step as used and storing the hashes of its recovery codes.
+		EnableUserTOTP(name string, step int64, recoveryHashes []string) error
+		// UseTOTPStep records step as used, failing if it or a later step
+		// already was.
+		UseTOTPStep(name string, step int64) error
+		// SetRecoveryCodes replaces the recovery codes of a user.
+		SetRecoveryCodes(name string, hashes []string) error
+		// UseRecoveryCode marks an unused recovery code as used, failing
+		// if there is none with that hash.
+		UseRecoveryCode(name, hash string, at time.Time) error
+		// DisableUserTOTP removes the authenticator and recovery codes of
+		// a user.
+		DisableUserTOTP(name string) error
+
+		// CreateSession stores a new session, dropping the user's sessions
+		// that have expired or were revoked.
+		CreateSession(session *Session) error
+		// GetSession returns a session by ID, or nil if it does not exist.
+		GetSession(id string) (*Session, error)
+		// GetUserSessions returns the sessions of a user that are active at
+		// now, most recently used first.
+		GetUserSessions(user string, now time.Time) ([]Session, error)
+		// RotateSession replaces the refresh token hash of an active
+		// session, provided it is still oldHash, and records its use.
+		RotateSession(id, oldHash, newHash string, usedAt time.Time) error
+		RevokeSession(id string, at time.Time) error
+		// RevokeUserSessions revokes every active session of a user.
+		RevokeUserSessions(user string, at time.Time) error
+	}
+
+	// GroupStore keeps the groups of users pushed by an identity provider
+	// over SCIM.
+	GroupStore interface {
+		// GetGroups returns every group with its members, ordered by
+		// display name.
+		GetGroups() ([]Group, error)
+		// GetGroup returns a group by ID, or nil if it does not exist.
+		GetGroup(id string) (*Group, error)
+		// CreateGroup creates a group with its members.
+		CreateGroup(group *Group) error
+		// UpdateGroup updates the display name and external ID of a group
+		// and replaces its members.
+		UpdateGroup(id string, group *Group) error
+		// AddGroupMembers adds users to a group; adding an existing member
+		// again is not an error.
+		AddGroupMembers(id string, users []string) error
+		// RemoveGroupMembers removes users from a group; removing a user
+		// that is not a member is not an error.
+		RemoveGroupMembers(id string, users []string) error
+		DeleteGroup(id string) error
+		// GetUserGroups returns the display names of the groups a user
+		// belongs to, ordered.
+		GetUserGroups(user string) ([]string, error)
+	}
+)
+
+func init() {
+	RegisterDriver("mysql", func(dsn string) (Store, error) {
+		return NewMySQLStore(dsn)
+	})
+}
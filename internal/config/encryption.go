@@ -0,0 +1,160 @@
+package config
+
+import (
+	"database/sql"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/secret"
+)
+
+// encryptField encrypts a sensitive value for storage, bound to
+// additionalData. Empty values are stored as NULL.
+func (s *MySQLStore) encryptField(plaintext string, additionalData []byte) (interface{}, error) {
+	if plaintext == "" {
+		return nil, nil
+	}
+	if s.cipher == nil {
+		return nil, ErrEncryptionDisabled
+	}
+	return s.cipher.Encrypt(plaintext, additionalData)
+}
+
+// decryptField decrypts a sensitive column value bound to additionalData.
+func (s *MySQLStore) decryptField(ciphertext sql.NullString, additionalData []byte) (string, error) {
+	if !ciphertext.Valid || ciphertext.String == "" {
+		return "", nil
+	}
+	if s.cipher == nil {
+		return "", ErrEncryptionDisabled
+	}
+	return s.cipher.Decrypt(ciphertext.String, additionalData)
+}
+
+// secretData returns the additional data binding an encrypted value to the
+// column and row it is stored in, so that it does not decrypt once copied
+// to another.
+func secretData(table, column, row string) []byte {
+	return []byte(table + "." + column + "\x00" + row)
+}
+
+// rotatable is implemented by ciphers that support key rotation.
+type rotatable interface {
+	NeedsRotation(ciphertext string) bool
+}
+
+var _ rotatable = (*secret.Keyring)(nil)
+
+// sensitiveColumns lists the encrypted columns of each table.
+var sensitiveColumns = map[string][]string{
+	"backends": {"credential_secret", "tls_client_key"},
+	"jobs":     {"params"},
+	"users":    {"totp_secret"},
+}
+
+// encryptedRows selects the rows of tables whose sensitive columns may
+// also hold plaintext, written while no cipher was configured.
+var encryptedRows = map[string]string{
+	"jobs":  "params_encrypted = 1",
+	"users": "totp_secret_encrypted = 1",
+}
+
+// keyColumns names the primary key of tables not keyed by id.
+var keyColumns = map[string]string{
+	"users": "name",
+}
+
+// boundColumns names the column identifying the row that the encrypted
+// values of a table are bound to. Values of other tables are bound to
+// nothing, as their rows are only identified once inserted.
+var boundColumns = map[string]string{
+	"backends": "name",
+	"users":    "name",
+}
+
+// RotateSecrets re-encrypts every sensitive column value that was written
+// with a non-primary key, returning the number of values rewritten. It is
+// safe to run repeatedly and while the service is serving traffic.
+func (s *MySQLStore) RotateSecrets() (int, error) {
+	if s.cipher == nil {
+		return 0, ErrEncryptionDisabled
+	}
+	r, ok := s.cipher.(rotatable)
+	if !ok {
+		return 0, nil
+	}
+
+	rotated := 0
+	for table, columns := range sensitiveColumns {
+		for _, column := range columns {
+			n, err := s.rotateColumn(r, table, column)
+			rotated += n
+			if err != nil {
+				return rotated, err
+			}
+		}
+	}
+	return rotated, nil
+}
+
+func (s *MySQLStore) rotateColumn(r rotatable, table, column string) (int, error) {
+	key := "id"
+	if k, ok := keyColumns[table]; ok {
+		key = k
+	}
+	bound, ok := boundColumns[table]
+	if !ok {
+		bound = `''`
+	}
+	query := `SELECT ` + key + `, ` + bound + `, ` + column + ` FROM ` + table + ` WHERE ` + column + ` IS NOT NULL`
+	if filter, ok := encryptedRows[table]; ok {
+		query += ` AND ` + filter
+	}
+	rows, err := s.q.Query(query)
+	if err != nil {
+		return 0, err
+	}
+
+	type value struct {
+		key        string
+		row        string
+		ciphertext string
+	}
+	var stale []value
+	for rows.Next() {
+		var v value
+		if err := rows.Scan(&v.key, &v.row, &v.ciphertext); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if r.NeedsRotation(v.ciphertext) {
+			stale = append(stale, v)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, v := range stale {
+		var data []byte
+		if ok {
+			data = secretData(table, column, v.row)
+		}
+		plaintext, err := s.cipher.Decrypt(v.ciphertext, data)
+		if err != nil {
+			return rotated, err
+		}
+		ciphertext, err := s.cipher.Encrypt(plaintext, data)
+		if err != nil {
+			return rotated, err
+		}
+		// Compare-and-swap so a concurrent write is never overwritten, and
+		// keep updated_at since the configuration itself did not change.
+		if _, err := s.q.Exec(`UPDATE `+table+` SET `+column+` = ?, updated_at = updated_at WHERE `+key+` = ? AND `+column+` = ?`,
+			ciphertext, v.key, v.ciphertext); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+	return rotated, nil
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// VariantControl names the route's own target in an experiment; variants
+// cannot take it.
+const VariantControl = "control"
+
+// MaxVariants bounds the variants of an experiment.
+const MaxVariants = 10
+
+var variantName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// RouteExperiment runs an A/B experiment on a route: requests are sent to
+// one of the variants instead of the route's own target, the control.
+// Gateways first pick the first variant whose Match the request has, then
+// split the rest by hashing HashKey (see RouteRollout), sending Percent of
+// them to each variant and the remainder to the control.
+type RouteExperiment struct {
+	HashKey  string         `json:"hash_key,omitempty"`
+	Variants []RouteVariant `json:"variants"`
+}
+
+// RouteVariant is a target of an experiment. Empty backend fields are
+// those of the route.
+type RouteVariant struct {
+	Name           string        `json:"name"`
+	BackendName    string        `json:"backend_name,omitempty"`
+	BackendService string        `json:"backend_service,omitempty"`
+	BackendMethod  string        `json:"backend_method,omitempty"`
+	Percent        int           `json:"percent,omitempty"`
+	Match          *VariantMatch `json:"match,omitempty"`
+}
+
+// VariantMatch selects a variant for requests with a header value, e.g. to
+// let testers opt in.
+type VariantMatch struct {
+	Header string `json:"header"`
+	Value  string `json:"value"`
+}
+
+// Target returns the route as called for the variant.
+func (v *RouteVariant) Target(route *Route) *Route {
+	target := *route
+	if v.BackendName != "" {
+		target.BackendName = v.BackendName
+	}
+	if v.BackendService != "" {
+		target.BackendService = v.BackendService
+	}
+	if v.BackendMethod != "" {
+		target.BackendMethod = v.BackendMethod
+	}
+	return &target
+}
+
+// Validate checks the variants and their split, canonicalizing header
+// names. Whether variant backends exist is up to the caller. A nil
+// experiment is valid and means the route has no variants.
+func (e *RouteExperiment) Validate() error {
+	if e == nil {
+		return nil
+	}
+
+	key, err := validateHashKey("experiment.hash_key", e.HashKey)
+	if err != nil {
+		return err
+	}
+	e.HashKey = key
+
+	if len(e.Variants) == 0 {
+		return errors.New("experiment.variants is required")
+	}
+	if len(e.Variants) > MaxVariants {
+		return fmt.Errorf("too many experiment.variants (at most %d)", MaxVariants)
+	}
+
+	names := make(map[string]bool, len(e.Variants))
+	total := 0
+	for i := range e.Variants {
+		v := &e.Variants[i]
+		path := fmt.Sprintf("experiment.variants[%d]", i)
+
+		switch {
+		case !variantName.MatchString(v.Name):
+			return fmt.Errorf("invalid %s.name (lowercase letters, digits, '-' and '_', at most 64)", path)
+		case v.Name == VariantControl:
+			return fmt.Errorf("%s.name cannot be %q, which names the route's own target", path, VariantControl)
+		case names[v.Name]:
+			return fmt.Errorf("duplicate variant name %q", v.Name)
+		}
+		names[v.Name] = true
+
+		if v.BackendName == "" && v.BackendService == "" && v.BackendMethod == "" {
+			return fmt.Errorf("%s must set backend_name, backend_service or backend_method", path)
+		}
+		if v.Percent < 0 || v.Percent > 100 {
+			return fmt.Errorf("%s.percent must be between 0 and 100", path)
+		}
+		if v.Percent == 0 && v.Match == nil {
+			return fmt.Errorf("%s must set percent or match", path)
+		}
+		total += v.Percent
+
+		if m := v.Match; m != nil {
+			if !httpguts.ValidHeaderFieldName(m.Header) {
+				return fmt.Errorf("invalid %s.match.header: %q is not a header name", path, m.Header)
+			}
+			if m.Value == "" {
+				return fmt.Errorf("%s.match.value is required", path)
+			}
+			m.Header = http.CanonicalHeaderKey(m.Header)
+		}
+	}
+	if total > 100 {
+		return fmt.Errorf("experiment variants take %d%% of the traffic, more than 100%%", total)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import "errors"
+
+// RouteFallback is called instead of a route's target when the target
+// fails with an unavailable or deadline error, or its circuit breaker is
+// open, e.g. a stub assistant answering from a cache. Empty backend fields
+// are those of the route.
+type RouteFallback struct {
+	BackendName    string `json:"backend_name,omitempty"`
+	BackendService string `json:"backend_service,omitempty"`
+	BackendMethod  string `json:"backend_method,omitempty"`
+}
+
+// Target returns the route as called for the fallback.
+func (f *RouteFallback) Target(route *Route) *Route {
+	target := *route
+	if f.BackendName != "" {
+		target.BackendName = f.BackendName
+	}
+	if f.BackendService != "" {
+		target.BackendService = f.BackendService
+	}
+	if f.BackendMethod != "" {
+		target.BackendMethod = f.BackendMethod
+	}
+	return &target
+}
+
+// Validate checks that the fallback of route calls something other than
+// the route's own target. Whether the backend exists is up to the caller.
+// A nil fallback is valid and means failures reach the client.
+func (f *RouteFallback) Validate(route *Route) error {
+	if f == nil {
+		return nil
+	}
+	if f.BackendName == "" && f.BackendService == "" && f.BackendMethod == "" {
+		return errors.New("fallback must set backend_name, backend_service or backend_method")
+	}
+	target := f.Target(route)
+	if target.BackendName == route.BackendName && target.BackendService == route.BackendService &&
+		target.BackendMethod == route.BackendMethod {
+		return errors.New("fallback must differ from the route's own target")
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// GetFreezeWindows returns all freeze windows.
+func (s *FileStore) GetFreezeWindows() ([]FreezeWindow, error) {
+	st := s.current()
+
+	var windows []FreezeWindow
+	for _, f := range st.windows {
+		windows = append(windows, copyFreezeWindow(f))
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].ID < windows[j].ID })
+	return windows, nil
+}
+
+// GetFreezeWindowByID returns a freeze window by ID.
+func (s *FileStore) GetFreezeWindowByID(id uint) (*FreezeWindow, error) {
+	f, ok := s.current().windows[id]
+	if !ok {
+		return nil, nil
+	}
+	c := copyFreezeWindow(f)
+	return &c, nil
+}
+
+// copyFreezeWindow returns a copy of a freeze window that shares nothing
+// with it.
+func copyFreezeWindow(f *FreezeWindow) FreezeWindow {
+	c := *f
+	if f.StartsAt != nil {
+		t := *f.StartsAt
+		c.StartsAt = &t
+	}
+	if f.EndsAt != nil {
+		t := *f.EndsAt
+		c.EndsAt = &t
+	}
+	return c
+}
+
+// CreateFreezeWindow creates a new freeze window.
+func (s *FileStore) CreateFreezeWindow(window *FreezeWindow) error {
+	return s.update(func(tx *fileTx) error {
+		now := time.Now()
+		stored := copyFreezeWindow(window)
+		stored.ID = tx.state.nextWindowID
+		stored.CreatedAt, stored.UpdatedAt = now, now
+
+		if err := tx.put(windowPath(stored.ID), &stored); err != nil {
+			return err
+		}
+		tx.state.windows[stored.ID] = &stored
+		tx.state.nextWindowID++
+
+		window.ID = stored.ID
+		window.CreatedAt, window.UpdatedAt = now, now
+		return nil
+	})
+}
+
+// UpdateFreezeWindow updates an existing freeze window.
+func (s *FileStore) UpdateFreezeWindow(id uint, window *FreezeWindow) error {
+	return s.update(func(tx *fileTx) error {
+		existing, ok := tx.state.windows[id]
+		if !ok {
+			return errors.New("freeze window not found")
+		}
+
+		stored := copyFreezeWindow(window)
+		stored.ID = id
+		stored.CreatedBy, stored.CreatedAt = existing.CreatedBy, existing.CreatedAt
+		stored.UpdatedAt = time.Now()
+
+		if err := tx.put(windowPath(id), &stored); err != nil {
+			return err
+		}
+		tx.state.windows[id] = &stored
+
+		window.ID = id
+		window.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+// DeleteFreezeWindow removes a freeze window and its file.
+func (s *FileStore) DeleteFreezeWindow(id uint) error {
+	return s.update(func(tx *fileTx) error {
+		if _, ok := tx.state.windows[id]; !ok {
+			return errors.New("freeze window not found")
+		}
+		delete(tx.state.windows, id)
+		tx.writes[windowPath(id)] = nil
+		return nil
+	})
+}
@@ -0,0 +1,987 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/secret"
+)
+
+// Layout of a FileStore directory. Backends, routes and freeze windows
+// are kept one per YAML file, named after the backend name or the ID;
+// history is an append-only JSON Lines file.
+const (
+	fileBackendsDir = "backends"
+	fileRoutesDir   = "routes"
+	fileWindowsDir  = "freeze_windows"
+	fileHistory     = "history.jsonl"
+)
+
+// FileStore implements Store on a directory of YAML files, for air-gapped
+// or GitOps-only setups without a database. All data is held in memory
+// and every change is written through to disk, each file atomically. Only
+// one admin instance may use a directory at a time.
+type FileStore struct {
+	dir    string
+	cipher secret.Cipher
+
+	// writeMu serializes transactions and reloads; mu guards the state
+	// pointer. A state is never modified once current, so readers may keep
+	// using it without holding mu.
+	writeMu sync.Mutex
+	mu      sync.RWMutex
+	state   *fileState
+
+	// tx is set on the Store passed to InTx callbacks.
+	tx *fileTx
+}
+
+// fileState is a consistent view of a FileStore's data.
+type fileState struct {
+	backends map[string]*fileBackend
+	routes   map[uint]*Route
+	windows  map[uint]*FreezeWindow
+	history  []ConfigHistory
+
+	nextBackendID uint
+	nextRouteID   uint
+	nextWindowID  uint
+	nextHistoryID uint64
+}
+
+// fileTx is a transaction: a private copy of the state and the file
+// writes that make it durable, nil data meaning removal.
+type fileTx struct {
+	state   *fileState
+	writes  map[string][]byte
+	history []ConfigHistory
+}
+
+// fileBackend is the file format of a backend. Backend's JSON encoding
+// redacts secrets, so they are kept here instead, encrypted.
+type fileBackend struct {
+	Backend
+	Credential *fileCredential `json:"credential,omitempty"`
+	TLS        *fileTLS        `json:"tls,omitempty"`
+}
+
+// fileCredential is BackendCredential with Secret encrypted.
+type fileCredential struct {
+	Type      string `json:"type"`
+	Username  string `json:"username,omitempty"`
+	SecretRef string `json:"secret_ref,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+}
+
+// fileTLS is BackendTLS with ClientKey encrypted.
+type fileTLS struct {
+	ServerName         string `json:"server_name,omitempty"`
+	CACert             string `json:"ca_cert,omitempty"`
+	ClientCert         string `json:"client_cert,omitempty"`
+	ClientKey          string `json:"client_key,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// NewFileStore creates a FileStore on dir, creating the directory if
+// needed, and loads its data.
+func NewFileStore(dir string) (*FileStore, error) {
+	if dir == "" {
+		return nil, errors.New("file store directory is required")
+	}
+	for _, sub := range []string{fileBackendsDir, fileRoutesDir, fileWindowsDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	state, err := loadFileState(dir, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, state: state}, nil
+}
+
+func init() {
+	RegisterDriver("file", func(dsn string) (Store, error) {
+		return NewFileStore(dsn)
+	})
+}
+
+// SetCipher configures the cipher used to encrypt secrets in backend
+// files. Without one, backends with inline secrets cannot be stored.
+func (s *FileStore) SetCipher(c secret.Cipher) {
+	s.cipher = c
+}
+
+// Ping checks that the directory is still there.
+func (s *FileStore) Ping(ctx context.Context) error {
+	_, err := os.Stat(s.dir)
+	return err
+}
+
+// InTx runs fn against a Store bound to a private copy of the data,
+// writing the changes to disk if fn returns nil and discarding them
+// otherwise. Nested calls reuse the outer transaction. Each file is
+// replaced atomically, but a crash while committing may leave some of a
+// transaction's files written.
+func (s *FileStore) InTx(fn func(tx Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	tx := s.current().begin()
+	if err := fn(&FileStore{dir: s.dir, cipher: s.cipher, tx: tx}); err != nil {
+		return err
+	}
+	return s.commit(tx)
+}
+
+// current returns the state seen by s.
+func (s *FileStore) current() *fileState {
+	if s.tx != nil {
+		return s.tx.state
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state
+}
+
+// update runs fn in the enclosing transaction, or in a new one.
+func (s *FileStore) update(fn func(tx *fileTx) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	return s.InTx(func(tx Store) error {
+		return fn(tx.(*FileStore).tx)
+	})
+}
+
+// begin starts a transaction on a copy of st. Records are replaced rather
+// than modified, so copying the maps is enough.
+func (st *fileState) begin() *fileTx {
+	next := *st
+	next.backends = make(map[string]*fileBackend, len(st.backends))
+	for k, v := range st.backends {
+		next.backends[k] = v
+	}
+	next.routes = make(map[uint]*Route, len(st.routes))
+	for k, v := range st.routes {
+		next.routes[k] = v
+	}
+	next.windows = make(map[uint]*FreezeWindow, len(st.windows))
+	for k, v := range st.windows {
+		next.windows[k] = v
+	}
+	// Appends must not write into the shared backing array
+	next.history = st.history[:len(st.history):len(st.history)]
+	return &fileTx{state: &next, writes: map[string][]byte{}}
+}
+
+// commit writes a transaction's files and makes its state current. If
+// writing fails the data is reloaded, so memory matches what is on disk.
+func (s *FileStore) commit(tx *fileTx) error {
+	if err := s.writeTx(tx); err != nil {
+		if state, lerr := loadFileState(s.dir, s.current()); lerr == nil {
+			s.setState(state)
+		}
+		return err
+	}
+	s.setState(tx.state)
+	return nil
+}
+
+func (s *FileStore) writeTx(tx *fileTx) error {
+	paths := make([]string, 0, len(tx.writes))
+	for path := range tx.writes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		full := filepath.Join(s.dir, path)
+		if data := tx.writes[path]; data != nil {
+			if err := writeFileAtomic(full, data); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.Remove(full); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return appendHistory(filepath.Join(s.dir, fileHistory), tx.history)
+}
+
+func (s *FileStore) setState(state *fileState) {
+	s.mu.Lock()
+	s.state = state
+	s.mu.Unlock()
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir makes a rename in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// appendHistory appends entries to the history file.
+func appendHistory(path string, entries []ConfigHistory) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadFileState reads a FileStore directory. Backends whose file has no
+// ID keep the one they had in prev, if given.
+func loadFileState(dir string, prev *fileState) (*fileState, error) {
+	st := &fileState{
+		backends: map[string]*fileBackend{},
+		routes:   map[uint]*Route{},
+		windows:  map[uint]*FreezeWindow{},
+	}
+
+	err := readYAMLDir(filepath.Join(dir, fileBackendsDir), func(stem string, data []byte, modTime time.Time) error {
+		name, err := url.PathUnescape(stem)
+		if err != nil {
+			return err
+		}
+		var b fileBackend
+		if err := yaml.Unmarshal(data, &b); err != nil {
+			return err
+		}
+		b.Name = name
+		b.Status = backendStatus(b.Enabled, b.Status == BackendDraining)
+		fileTimes(&b.CreatedAt, &b.UpdatedAt, modTime)
+		if b.ID == 0 && prev != nil && prev.backends[name] != nil {
+			b.ID = prev.backends[name].ID
+		}
+		st.backends[name] = &b
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readYAMLDir(filepath.Join(dir, fileRoutesDir), func(stem string, data []byte, modTime time.Time) error {
+		id, err := strconv.ParseUint(stem, 10, 32)
+		if err != nil || id == 0 {
+			return errors.New("file name must be the route ID")
+		}
+		var r Route
+		if err := yaml.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		r.ID = uint(id)
+		if r.Lifecycle == "" {
+			r.Lifecycle = LifecycleActive
+		}
+		fileTimes(&r.CreatedAt, &r.UpdatedAt, modTime)
+		st.routes[r.ID] = &r
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readYAMLDir(filepath.Join(dir, fileWindowsDir), func(stem string, data []byte, modTime time.Time) error {
+		id, err := strconv.ParseUint(stem, 10, 32)
+		if err != nil || id == 0 {
+			return errors.New("file name must be the freeze window ID")
+		}
+		var f FreezeWindow
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return err
+		}
+		f.ID = uint(id)
+		fileTimes(&f.CreatedAt, &f.UpdatedAt, modTime)
+		st.windows[f.ID] = &f
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if st.history, err = readHistory(filepath.Join(dir, fileHistory)); err != nil {
+		return nil, err
+	}
+
+	// Continue after the highest IDs; hand-written backends without one
+	// are numbered in name order
+	var unnumbered []string
+	for name, b := range st.backends {
+		if b.ID == 0 {
+			unnumbered = append(unnumbered, name)
+		}
+		st.nextBackendID = max(st.nextBackendID, b.ID)
+	}
+	sort.Strings(unnumbered)
+	for _, name := range unnumbered {
+		st.nextBackendID++
+		st.backends[name].ID = st.nextBackendID
+	}
+	st.nextBackendID++
+	for id := range st.routes {
+		st.nextRouteID = max(st.nextRouteID, id)
+	}
+	st.nextRouteID++
+	for id := range st.windows {
+		st.nextWindowID = max(st.nextWindowID, id)
+	}
+	st.nextWindowID++
+	for i := range st.history {
+		h := &st.history[i]
+		st.nextHistoryID = max(st.nextHistoryID, h.ID)
+		// Entries written before revisions were kept have the revision of
+		// their ID
+		if h.Revision == 0 {
+			h.Revision = h.ID
+		}
+		st.setRevision(h)
+	}
+	st.nextHistoryID++
+
+	return st, nil
+}
+
+// readYAMLDir calls fn with the name, without extension, contents and
+// modification time of every YAML file in dir. Hidden files, such as temporary files left by an
+// interrupted write, are skipped.
+func readYAMLDir(dir string, fn func(stem string, data []byte, modTime time.Time) error) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".yaml" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if err := fn(strings.TrimSuffix(name, ".yaml"), data, info.ModTime()); err != nil {
+			return fmt.Errorf("load %s: %w", filepath.Join(dir, name), err)
+		}
+	}
+	return nil
+}
+
+// fileTimes fills in the timestamps missing from a hand-written file with
+// the file's modification time.
+func fileTimes(createdAt, updatedAt *time.Time, modTime time.Time) {
+	if createdAt.IsZero() {
+		*createdAt = modTime
+	}
+	if updatedAt.IsZero() {
+		*updatedAt = modTime
+	}
+}
+
+// readHistory reads the history file, which may not exist yet.
+func readHistory(path string) ([]ConfigHistory, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var history []ConfigHistory
+	dec := json.NewDecoder(f)
+	for {
+		var h ConfigHistory
+		if err := dec.Decode(&h); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("load %s: %w", path, err)
+		}
+		history = append(history, h)
+	}
+	return history, nil
+}
+
+func backendPath(name string) string {
+	return filepath.Join(fileBackendsDir, url.PathEscape(name)+".yaml")
+}
+
+func routePath(id uint) string {
+	return filepath.Join(fileRoutesDir, strconv.FormatUint(uint64(id), 10)+".yaml")
+}
+
+func windowPath(id uint) string {
+	return filepath.Join(fileWindowsDir, strconv.FormatUint(uint64(id), 10)+".yaml")
+}
+
+// put records a new version of a file in the transaction. Revisions of
+// backends and routes are not written: they are derived from the history.
+func (tx *fileTx) put(path string, v interface{}) error {
+	switch r := v.(type) {
+	case *fileBackend:
+		c := *r
+		c.Revision = 0
+		v = &c
+	case *Route:
+		c := *r
+		c.Revision = 0
+		v = &c
+	}
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tx.writes[path] = data
+	return nil
+}
+
+// encrypt encrypts a secret for a backend file, bound to additionalData.
+func (s *FileStore) encrypt(plaintext string, additionalData []byte) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	if s.cipher == nil {
+		return "", ErrEncryptionDisabled
+	}
+	return s.cipher.Encrypt(plaintext, additionalData)
+}
+
+// decrypt decrypts a secret read from a backend file, bound to
+// additionalData.
+func (s *FileStore) decrypt(ciphertext string, additionalData []byte) (string, error) {
+	if ciphertext == "" {
+		return "", nil
+	}
+	if s.cipher == nil {
+		return "", ErrEncryptionDisabled
+	}
+	return s.cipher.Decrypt(ciphertext, additionalData)
+}
+
+// toFile converts a backend to its file format, encrypting secrets.
+func (s *FileStore) toFile(b *Backend) (*fileBackend, error) {
+	fb := &fileBackend{Backend: *b}
+	fb.Backend.Credential, fb.Backend.TLS = nil, nil
+
+	if c := b.Credential; c != nil {
+		secret, err := s.encrypt(c.Secret, secretData("backends", "credential_secret", b.Name))
+		if err != nil {
+			return nil, err
+		}
+		fb.Credential = &fileCredential{Type: c.Type, Username: c.Username, SecretRef: c.SecretRef, Secret: secret}
+	}
+	if t := b.TLS; t != nil {
+		key, err := s.encrypt(t.ClientKey, secretData("backends", "tls_client_key", b.Name))
+		if err != nil {
+			return nil, err
+		}
+		tls := fileTLS(*t)
+		tls.ClientKey = key
+		fb.TLS = &tls
+	}
+	return fb, nil
+}
+
+// fromFile returns a copy of a stored backend, decrypting secrets.
+func (s *FileStore) fromFile(fb *fileBackend) (*Backend, error) {
+	b := fb.Backend
+	b.Clusters = append([]string(nil), fb.Clusters...)
+	if len(fb.Plugins) > 0 {
+		b.Plugins = make(map[string]json.RawMessage, len(fb.Plugins))
+		for k, v := range fb.Plugins {
+			b.Plugins[k] = v
+		}
+	}
+	if fb.Backend.CircuitBreaker != nil {
+		c := *fb.Backend.CircuitBreaker
+		b.CircuitBreaker = &c
+	}
+
+	if c := fb.Credential; c != nil {
+		secret, err := s.decrypt(c.Secret, secretData("backends", "credential_secret", fb.Name))
+		if err != nil {
+			return nil, fmt.Errorf("decrypt credential for backend %s: %w", fb.Name, err)
+		}
+		b.Credential = &BackendCredential{Type: c.Type, Username: c.Username, SecretRef: c.SecretRef, Secret: secret}
+	}
+	if t := fb.TLS; t != nil {
+		key, err := s.decrypt(t.ClientKey, secretData("backends", "tls_client_key", fb.Name))
+		if err != nil {
+			return nil, fmt.Errorf("decrypt tls client key for backend %s: %w", fb.Name, err)
+		}
+		tls := BackendTLS(*t)
+		tls.ClientKey = key
+		b.TLS = &tls
+	}
+	return &b, nil
+}
+
+// redacted returns a stored backend for history records, with secrets
+// left encrypted; they are redacted when it is encoded.
+func (fb *fileBackend) redacted() *Backend {
+	b := fb.Backend
+	if c := fb.Credential; c != nil {
+		b.Credential = &BackendCredential{Type: c.Type, Username: c.Username, SecretRef: c.SecretRef, Secret: c.Secret}
+	}
+	if t := fb.TLS; t != nil {
+		tls := BackendTLS(*t)
+		b.TLS = &tls
+	}
+	return &b
+}
+
+// GetBackends returns all backend configurations, optionally filtered by enabled status.
+func (s *FileStore) GetBackends(enabled *bool) ([]Backend, error) {
+	st := s.current()
+
+	var backends []Backend
+	for _, fb := range st.backends {
+		if enabled != nil && fb.Enabled != *enabled {
+			continue
+		}
+		b, err := s.fromFile(fb)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, *b)
+	}
+	sort.Slice(backends, func(i, j int) bool { return backends[i].Name < backends[j].Name })
+	return backends, nil
+}
+
+// GetBackendByName returns a backend configuration by name.
+func (s *FileStore) GetBackendByName(name string) (*Backend, error) {
+	fb, ok := s.current().backends[name]
+	if !ok {
+		return nil, nil
+	}
+	return s.fromFile(fb)
+}
+
+// CreateBackend creates a new backend configuration.
+func (s *FileStore) CreateBackend(backend *Backend) error {
+	return s.update(func(tx *fileTx) error {
+		if _, ok := tx.state.backends[backend.Name]; ok {
+			return fmt.Errorf("backend %s already exists", backend.Name)
+		}
+
+		now := time.Now()
+		stored := *backend
+		stored.ID = tx.state.nextBackendID
+		stored.Status = backendStatus(stored.Enabled, stored.Status == BackendDraining)
+		stored.Revision = 0
+		stored.CreatedAt, stored.UpdatedAt = now, now
+
+		fb, err := s.toFile(&stored)
+		if err != nil {
+			return err
+		}
+		if err := tx.put(backendPath(stored.Name), fb); err != nil {
+			return err
+		}
+		tx.state.backends[stored.Name] = fb
+		tx.state.nextBackendID++
+
+		backend.ID = stored.ID
+		backend.Status = stored.Status
+		backend.CreatedAt, backend.UpdatedAt = now, now
+		return nil
+	})
+}
+
+// UpdateBackend updates an existing backend configuration.
+func (s *FileStore) UpdateBackend(name string, backend *Backend) error {
+	return s.update(func(tx *fileTx) error {
+		existing, ok := tx.state.backends[name]
+		if !ok {
+			return errors.New("backend not found")
+		}
+
+		stored := *backend
+		stored.ID, stored.Name = existing.ID, name
+		stored.Status = backendStatus(stored.Enabled, stored.Status == BackendDraining)
+		stored.Revision = existing.Revision
+		stored.CreatedAt, stored.UpdatedAt = existing.CreatedAt, time.Now()
+
+		fb, err := s.toFile(&stored)
+		if err != nil {
+			return err
+		}
+		if err := tx.put(backendPath(name), fb); err != nil {
+			return err
+		}
+		tx.state.backends[name] = fb
+
+		backend.Name = name
+		backend.Status = stored.Status
+		backend.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+// DeleteBackend soft deletes a backend by disabling it.
+func (s *FileStore) DeleteBackend(name string) error {
+	return s.update(func(tx *fileTx) error {
+		existing, ok := tx.state.backends[name]
+		if !ok {
+			return errors.New("backend not found")
+		}
+
+		fb := *existing
+		fb.Enabled, fb.Status, fb.UpdatedAt = false, BackendDisabled, time.Now()
+		if err := tx.put(backendPath(name), &fb); err != nil {
+			return err
+		}
+		tx.state.backends[name] = &fb
+		return nil
+	})
+}
+
+// copyRoute returns a deep copy of a route, so that callers cannot modify
+// stored records.
+func copyRoute(r *Route) (*Route, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var c Route
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetRoutes returns all route configurations, optionally filtered by enabled status.
+func (s *FileStore) GetRoutes(enabled *bool) ([]Route, error) {
+	st := s.current()
+
+	var routes []Route
+	for _, r := range st.routes {
+		if enabled != nil && r.Enabled != *enabled {
+			continue
+		}
+		c, err := copyRoute(r)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, *c)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].HTTPMethod != routes[j].HTTPMethod {
+			return routes[i].HTTPMethod < routes[j].HTTPMethod
+		}
+		if routes[i].HTTPPattern != routes[j].HTTPPattern {
+			return routes[i].HTTPPattern < routes[j].HTTPPattern
+		}
+		return routes[i].ID < routes[j].ID
+	})
+	return routes, nil
+}
+
+// GetRouteByID returns a route configuration by ID.
+func (s *FileStore) GetRouteByID(id uint) (*Route, error) {
+	r, ok := s.current().routes[id]
+	if !ok {
+		return nil, nil
+	}
+	return copyRoute(r)
+}
+
+// CreateRoute creates a new route configuration.
+func (s *FileStore) CreateRoute(route *Route) error {
+	return s.update(func(tx *fileTx) error {
+		now := time.Now()
+		stored, err := copyRoute(route)
+		if err != nil {
+			return err
+		}
+		stored.ID = tx.state.nextRouteID
+		if stored.Lifecycle == "" {
+			stored.Lifecycle = LifecycleActive
+		}
+		stored.Revision = 0
+		stored.CreatedAt, stored.UpdatedAt = now, now
+
+		if err := tx.put(routePath(stored.ID), stored); err != nil {
+			return err
+		}
+		tx.state.routes[stored.ID] = stored
+		tx.state.nextRouteID++
+
+		route.ID = stored.ID
+		route.CreatedAt, route.UpdatedAt = now, now
+		return nil
+	})
+}
+
+// UpdateRoute updates an existing route configuration.
+func (s *FileStore) UpdateRoute(id uint, route *Route) error {
+	return s.update(func(tx *fileTx) error {
+		existing, ok := tx.state.routes[id]
+		if !ok {
+			return errors.New("route not found")
+		}
+
+		stored, err := copyRoute(route)
+		if err != nil {
+			return err
+		}
+		stored.ID = id
+		if stored.Lifecycle == "" {
+			stored.Lifecycle = LifecycleActive
+		}
+		stored.Revision = existing.Revision
+		stored.CreatedAt, stored.UpdatedAt = existing.CreatedAt, time.Now()
+
+		if err := tx.put(routePath(id), stored); err != nil {
+			return err
+		}
+		tx.state.routes[id] = stored
+
+		route.ID = id
+		route.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+// DeleteRoute soft deletes a route by disabling it.
+func (s *FileStore) DeleteRoute(id uint) error {
+	return s.update(func(tx *fileTx) error {
+		existing, ok := tx.state.routes[id]
+		if !ok {
+			return errors.New("route not found")
+		}
+
+		stored := *existing
+		stored.Enabled, stored.UpdatedAt = false, time.Now()
+		if err := tx.put(routePath(id), &stored); err != nil {
+			return err
+		}
+		tx.state.routes[id] = &stored
+		return nil
+	})
+}
+
+// CreateHistory creates a new configuration change history record.
+// Transactions are serialized, so the global revision is the history ID.
+func (s *FileStore) CreateHistory(history *ConfigHistory) error {
+	return s.update(func(tx *fileTx) error {
+		h := *history
+		h.ID = tx.state.nextHistoryID
+		h.Revision = h.ID
+		h.CreatedAt = time.Now()
+		tx.state.nextHistoryID++
+		tx.state.history = append(tx.state.history, h)
+		tx.history = append(tx.history, h)
+		tx.state.setRevision(&h)
+
+		history.ID, history.Revision, history.CreatedAt = h.ID, h.Revision, h.CreatedAt
+		return nil
+	})
+}
+
+// setRevision records the revision of a history entry on the backend or
+// route it changed. Records are replaced rather than modified.
+func (st *fileState) setRevision(h *ConfigHistory) {
+	if h.ConfigID == nil {
+		return
+	}
+	switch h.ConfigType {
+	case "backend":
+		for name, fb := range st.backends {
+			if fb.ID == *h.ConfigID {
+				c := *fb
+				c.Revision = h.Revision
+				st.backends[name] = &c
+				return
+			}
+		}
+	case "route":
+		if r, ok := st.routes[*h.ConfigID]; ok {
+			c := *r
+			c.Revision = h.Revision
+			st.routes[c.ID] = &c
+		}
+	}
+}
+
+// GetRevision returns the global config revision.
+func (s *FileStore) GetRevision() (uint64, error) {
+	return s.current().nextHistoryID - 1, nil
+}
+
+// GetHistory returns configuration change history with optional filters.
+func (s *FileStore) GetHistory(configType *string, configID *uint, limit, offset int) ([]ConfigHistory, int, error) {
+	st := s.current()
+
+	// Newest first: the file is in creation order
+	var matched []ConfigHistory
+	for i := len(st.history) - 1; i >= 0; i-- {
+		h := st.history[i]
+		if configType != nil && h.ConfigType != *configType {
+			continue
+		}
+		if configID != nil && (h.ConfigID == nil || *h.ConfigID != *configID) {
+			continue
+		}
+		matched = append(matched, h)
+	}
+
+	total := len(matched)
+	if offset >= total {
+		return nil, total, nil
+	}
+	matched = matched[offset:]
+	if limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return matched, total, nil
+}
+
+// HistoryAges returns the age of every history entry recorded within
+// window, oldest first, optionally limited to one operator.
+func (s *FileStore) HistoryAges(operator *string, window time.Duration) ([]time.Duration, error) {
+	st := s.current()
+	now := time.Now()
+
+	var ages []time.Duration
+	for _, h := range st.history {
+		age := now.Sub(h.CreatedAt)
+		if age >= window || (operator != nil && h.Operator != *operator) {
+			continue
+		}
+		ages = append(ages, age)
+	}
+	return ages, nil
+}
+
+// RotateSecrets re-encrypts every backend secret that was written with a
+// non-primary key, returning the number of values rewritten.
+func (s *FileStore) RotateSecrets() (int, error) {
+	if s.cipher == nil {
+		return 0, ErrEncryptionDisabled
+	}
+	r, ok := s.cipher.(rotatable)
+	if !ok {
+		return 0, nil
+	}
+
+	rotated := 0
+	err := s.update(func(tx *fileTx) error {
+		for name, existing := range tx.state.backends {
+			fb := *existing
+			changed := false
+			if c := fb.Credential; c != nil && c.Secret != "" && r.NeedsRotation(c.Secret) {
+				cred := *c
+				if err := s.reencrypt(&cred.Secret, secretData("backends", "credential_secret", name)); err != nil {
+					return err
+				}
+				fb.Credential, changed = &cred, true
+				rotated++
+			}
+			if t := fb.TLS; t != nil && t.ClientKey != "" && r.NeedsRotation(t.ClientKey) {
+				tls := *t
+				if err := s.reencrypt(&tls.ClientKey, secretData("backends", "tls_client_key", name)); err != nil {
+					return err
+				}
+				fb.TLS, changed = &tls, true
+				rotated++
+			}
+			if !changed {
+				continue
+			}
+			// Keep updated_at since the configuration itself did not change
+			if err := tx.put(backendPath(name), &fb); err != nil {
+				return err
+			}
+			tx.state.backends[name] = &fb
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rotated, nil
+}
+
+// reencrypt encrypts a secret bound to additionalData again with the
+// primary key.
+func (s *FileStore) reencrypt(ciphertext *string, additionalData []byte) error {
+	plaintext, err := s.cipher.Decrypt(*ciphertext, additionalData)
+	if err != nil {
+		return err
+	}
+	*ciphertext, err = s.cipher.Encrypt(plaintext, additionalData)
+	return err
+}
@@ -0,0 +1,29 @@
+package config_test
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/secret"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/pkg/store/storetest"
+)
+
+// TestFileStore runs the conformance suite against a FileStore in a
+// temporary directory, with a random encryption key.
+func TestFileStore(t *testing.T) {
+	s, err := config.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := make([]byte, 32)
+	rand.Read(key)
+	keyring, err := secret.NewKeyring("test:" + base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetCipher(keyring)
+
+	storetest.TestStore(t, s)
+}
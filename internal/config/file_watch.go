@@ -0,0 +1,185 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileReloadDelay is how long Watch waits for changes to settle, so that
+// an editor saving several files, or one file in several steps, causes a
+// single reload.
+const fileReloadDelay = 250 * time.Millisecond
+
+// fileChangeReason is the change reason of history recorded for changes
+// made to the files directly.
+const fileChangeReason = "edited on disk"
+
+// Watch reloads the data whenever files are changed outside the service,
+// until ctx is done. A history entry is recorded for every backend and
+// route that changed and passed to onChange. Files that fail to load are
+// reported to onError and the previous data is kept.
+func (s *FileStore) Watch(ctx context.Context, onChange func(*ConfigHistory), onError func(error)) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	for _, sub := range []string{fileBackendsDir, fileRoutesDir, fileWindowsDir} {
+		if err := w.Add(filepath.Join(s.dir, sub)); err != nil {
+			return err
+		}
+	}
+
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			// Temporary files of atomic writes
+			if strings.HasPrefix(filepath.Base(e.Name), ".") {
+				continue
+			}
+			settle = time.After(fileReloadDelay)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			onError(err)
+		case <-settle:
+			settle = nil
+			changes, err := s.Reload()
+			if err != nil {
+				onError(err)
+				continue
+			}
+			for i := range changes {
+				onChange(&changes[i])
+			}
+		}
+	}
+}
+
+// Reload reads the data from disk again, recording and returning a history
+// entry for every backend and route that was changed there. The service's
+// own writes are already in memory and yield no entries.
+func (s *FileStore) Reload() ([]ConfigHistory, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	old := s.current()
+	next, err := loadFileState(s.dir, old)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := diffBackends(old, next)
+	changes = append(changes, diffRoutes(old, next)...)
+	now := time.Now()
+	for i := range changes {
+		changes[i].ID = next.nextHistoryID
+		changes[i].Reason = fileChangeReason
+		changes[i].CreatedAt = now
+		next.nextHistoryID++
+	}
+	if err := appendHistory(filepath.Join(s.dir, fileHistory), changes); err != nil {
+		return nil, err
+	}
+	next.history = append(next.history, changes...)
+
+	s.setState(next)
+	return changes, nil
+}
+
+// diffBackends returns history entries, without ID or time, for the
+// backends that differ between two states.
+func diffBackends(old, next *fileState) []ConfigHistory {
+	names := map[string]bool{}
+	for name := range old.backends {
+		names[name] = true
+	}
+	for name := range next.backends {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var changes []ConfigHistory
+	for _, name := range sorted {
+		before, after := old.backends[name], next.backends[name]
+		switch {
+		case before == nil:
+			changes = append(changes, fileHistoryEntry("backend", after.ID, "CREATE", nil, after.redacted()))
+		case after == nil:
+			changes = append(changes, fileHistoryEntry("backend", before.ID, "DELETE", before.redacted(), nil))
+		case !sameJSON(before, after):
+			changes = append(changes, fileHistoryEntry("backend", after.ID, "UPDATE", before.redacted(), after.redacted()))
+		}
+	}
+	return changes
+}
+
+// diffRoutes returns history entries, without ID or time, for the routes
+// that differ between two states.
+func diffRoutes(old, next *fileState) []ConfigHistory {
+	ids := map[uint]bool{}
+	for id := range old.routes {
+		ids[id] = true
+	}
+	for id := range next.routes {
+		ids[id] = true
+	}
+	sorted := make([]uint, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var changes []ConfigHistory
+	for _, id := range sorted {
+		before, after := old.routes[id], next.routes[id]
+		switch {
+		case before == nil:
+			changes = append(changes, fileHistoryEntry("route", id, "CREATE", nil, after))
+		case after == nil:
+			changes = append(changes, fileHistoryEntry("route", id, "DELETE", before, nil))
+		case !sameJSON(before, after):
+			changes = append(changes, fileHistoryEntry("route", id, "UPDATE", before, after))
+		}
+	}
+	return changes
+}
+
+// sameJSON reports whether two stored records encode the same. Backend
+// records include their encrypted secrets, so a changed secret counts.
+func sameJSON(a, b interface{}) bool {
+	x, errX := json.Marshal(a)
+	y, errY := json.Marshal(b)
+	return errX == nil && errY == nil && bytes.Equal(x, y)
+}
+
+// fileHistoryEntry returns a history entry for a change found on disk.
+func fileHistoryEntry(configType string, id uint, operation string, oldVal, newVal interface{}) ConfigHistory {
+	h := ConfigHistory{ConfigType: configType, ConfigID: &id, Operation: operation}
+	if oldVal != nil {
+		h.OldValue, _ = json.Marshal(oldVal)
+	}
+	if newVal != nil {
+		h.NewValue, _ = json.Marshal(newVal)
+	}
+	return h
+}
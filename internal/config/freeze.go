@@ -0,0 +1,147 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FreezeWindow is a period during which configuration changes are refused
+// unless an admin explicitly overrides the freeze. A window is either
+// one-off (StartsAt/EndsAt) or weekly recurring (WeeklyStart/WeeklyEnd,
+// e.g. "Fri 18:00" to "Mon 08:00" in Timezone).
+type FreezeWindow struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Reason      string     `json:"reason,omitempty"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+	WeeklyStart string     `json:"weekly_start,omitempty"`
+	WeeklyEnd   string     `json:"weekly_end,omitempty"`
+	Timezone    string     `json:"timezone,omitempty"`
+	Enabled     bool       `json:"enabled"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Validate checks that the window is either one-off or weekly and that its
+// bounds parse.
+func (f *FreezeWindow) Validate() error {
+	if f.Name == "" {
+		return errors.New("name is required")
+	}
+
+	oneOff := f.StartsAt != nil || f.EndsAt != nil
+	weekly := f.WeeklyStart != "" || f.WeeklyEnd != ""
+	switch {
+	case oneOff && weekly:
+		return errors.New("a freeze window is either one-off (starts_at/ends_at) or weekly (weekly_start/weekly_end), not both")
+	case oneOff:
+		if f.StartsAt == nil || f.EndsAt == nil {
+			return errors.New("starts_at and ends_at are required")
+		}
+		if !f.EndsAt.After(*f.StartsAt) {
+			return errors.New("ends_at must be after starts_at")
+		}
+	case weekly:
+		start, err := parseWeekly(f.WeeklyStart)
+		if err != nil {
+			return fmt.Errorf("weekly_start: %w", err)
+		}
+		end, err := parseWeekly(f.WeeklyEnd)
+		if err != nil {
+			return fmt.Errorf("weekly_end: %w", err)
+		}
+		if start == end {
+			return errors.New("weekly_start and weekly_end must differ")
+		}
+		if _, err := f.location(); err != nil {
+			return fmt.Errorf("timezone: %w", err)
+		}
+	default:
+		return errors.New("starts_at/ends_at or weekly_start/weekly_end are required")
+	}
+	return nil
+}
+
+// ActiveAt reports whether the window covers t and, if so, when it ends.
+func (f *FreezeWindow) ActiveAt(t time.Time) (bool, time.Time) {
+	if !f.Enabled {
+		return false, time.Time{}
+	}
+
+	if f.StartsAt != nil && f.EndsAt != nil {
+		if !t.Before(*f.StartsAt) && t.Before(*f.EndsAt) {
+			return true, *f.EndsAt
+		}
+		return false, time.Time{}
+	}
+
+	start, err1 := parseWeekly(f.WeeklyStart)
+	end, err2 := parseWeekly(f.WeeklyEnd)
+	loc, err3 := f.location()
+	if err1 != nil || err2 != nil || err3 != nil {
+		return false, time.Time{}
+	}
+
+	local := t.In(loc)
+	now := weekOffset(local)
+
+	var active bool
+	if start <= end {
+		active = now >= start && now < end
+	} else {
+		// The window wraps around the end of the week (e.g. Fri to Mon).
+		active = now >= start || now < end
+	}
+	if !active {
+		return false, time.Time{}
+	}
+
+	remaining := end - now
+	if remaining <= 0 {
+		remaining += week
+	}
+	return true, local.Add(remaining)
+}
+
+const week = 7 * 24 * time.Hour
+
+func (f *FreezeWindow) location() (*time.Location, error) {
+	if f.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(f.Timezone)
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWeekly parses "Fri 18:00" into an offset from Sunday 00:00.
+func parseWeekly(s string) (time.Duration, error) {
+	day, clock, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok {
+		return 0, fmt.Errorf("invalid weekly time %q (want e.g. \"Fri 18:00\")", s)
+	}
+	wd, ok := weekdays[strings.ToLower(day)[:min(3, len(day))]]
+	if !ok {
+		return 0, fmt.Errorf("invalid weekday %q", day)
+	}
+	tod, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", clock)
+	}
+	return time.Duration(wd)*24*time.Hour + time.Duration(tod.Hour())*time.Hour + time.Duration(tod.Minute())*time.Minute, nil
+}
+
+// weekOffset returns how far t is into its week, measured from Sunday 00:00.
+func weekOffset(t time.Time) time.Duration {
+	return time.Duration(t.Weekday())*24*time.Hour +
+		time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	// MaxGatewayLabels bounds the labels a gateway instance registers with.
+	MaxGatewayLabels = 16
+	// maxLabelValueLength bounds the value of a gateway label.
+	maxLabelValueLength = 63
+)
+
+// labelKey matches gateway label keys such as "zone" or "app.tier".
+var labelKey = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]{0,61}[a-z0-9])?$`)
+
+// GatewayInstance is a running gateway known to the service, either
+// registered with its version and address or only reporting the config it
+// has loaded. ConfigSHA256 is the hex SHA-256 of the gateway config
+// payload it has loaded, which the service compares with the current
+// payload to detect drift; it is empty until the gateway reports one.
+// ConfigRevision is the revision of that payload, and FailedRevision and
+// Failure tell a newer revision the gateway failed to apply, if any.
+// LastSeenAt is the time of its latest registration, heartbeat or report.
+type GatewayInstance struct {
+	ID             string            `json:"id"`
+	Version        string            `json:"version,omitempty"`
+	Addr           string            `json:"addr,omitempty"`
+	Cluster        string            `json:"cluster,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	ConfigSHA256   string            `json:"config_sha256,omitempty"`
+	ConfigRevision uint64            `json:"config_revision,omitempty"`
+	FailedRevision uint64            `json:"failed_revision,omitempty"`
+	Failure        string            `json:"failure,omitempty"`
+	RegisteredAt   *time.Time        `json:"registered_at,omitempty"`
+	LastSeenAt     time.Time         `json:"last_seen_at"`
+}
+
+// ValidateGatewayLabels checks the labels of a gateway instance or of a
+// rollout selecting gateways by label.
+func ValidateGatewayLabels(labels map[string]string) error {
+	if len(labels) > MaxGatewayLabels {
+		return fmt.Errorf("too many labels (at most %d)", MaxGatewayLabels)
+	}
+	for k, v := range labels {
+		if !labelKey.MatchString(k) {
+			return fmt.Errorf("invalid label key %q (lowercase letters, digits, dots, dashes and underscores, at most 63 characters)", k)
+		}
+		if len(v) > maxLabelValueLength {
+			return fmt.Errorf("label %s: value too long (at most %d characters)", k, maxLabelValueLength)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"strconv"
+	"time"
+)
+
+// Config rollout statuses.
+const (
+	RolloutInProgress = "in_progress"
+	RolloutPromoted   = "promoted"
+	RolloutAborted    = "aborted"
+)
+
+// ConfigRollout publishes configuration changes to a subset of gateways
+// before the rest. While it is in progress, the gateways it selects, the
+// canaries, get the current config and the others keep the config as it
+// was when the rollout started, kept in Baseline. Promoting it publishes
+// the current config to every gateway; aborting it restores the baseline.
+// At most one rollout is in progress at a time.
+type ConfigRollout struct {
+	ID     uint   `json:"id"`
+	Status string `json:"status"`
+	// Percent is the share of gateways selected, by a hash of their ID.
+	Percent int `json:"percent"`
+	// Labels, if set, restricts the selection to gateways registered with
+	// all of them.
+	Labels      map[string]string `json:"labels,omitempty"`
+	Description string            `json:"description,omitempty"`
+	// Revision is the config revision of the baseline.
+	Revision   uint64     `json:"revision"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedBy string     `json:"finished_by,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Baseline holds the enabled backends and routes at the start as an
+	// apply document without secrets. It is never part of the API output.
+	Baseline json.RawMessage `json:"-"`
+}
+
+// Selects reports whether a gateway instance is a canary of the rollout.
+// The same gateway is always selected for a given rollout and percentage,
+// and raising the percentage only adds gateways.
+func (r *ConfigRollout) Selects(g *GatewayInstance) bool {
+	for k, v := range r.Labels {
+		if have, ok := g.Labels[k]; !ok || have != v {
+			return false
+		}
+	}
+	h := fnv.New32a()
+	h.Write([]byte(strconv.FormatUint(uint64(r.ID), 10) + "/" + g.ID))
+	return int(h.Sum32()%100) < r.Percent
+}
+
+// RolloutHealth is the traffic a gateway instance reported while a
+// rollout was in progress, accumulated over its heartbeats.
+type RolloutHealth struct {
+	Gateway   string    `json:"gateway"`
+	Canary    bool      `json:"canary"`
+	Requests  int64     `json:"requests"`
+	Errors    int64     `json:"errors"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job states. A job is pending until a runner claims it, running while it
+// executes and succeeded or failed once done. Failed jobs can be retried,
+// which makes them pending again.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job is an operation run in the background, such as applying a large
+// document, because it may outlast an HTTP request. Params holds its input
+// and may contain secrets: it is never included in JSON output, and is
+// cleared once the job succeeds.
+type Job struct {
+	ID         uint            `json:"id"`
+	Kind       string          `json:"kind"`
+	Status     string          `json:"status"`
+	Params     json.RawMessage `json:"-"`
+	Progress   JobProgress     `json:"progress"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Attempts   int             `json:"attempts"`
+	CreatedBy  string          `json:"created_by,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// JobProgress counts the steps of a job done so far. Total is zero while
+// it is unknown.
+type JobProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Finished reports whether the job has succeeded or failed.
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
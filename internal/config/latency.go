@@ -0,0 +1,11 @@
+package config
+
+import "time"
+
+// LatencySample is the outcome of one health check probe of a backend.
+type LatencySample struct {
+	Backend   string        `json:"backend"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Latency   time.Duration `json:"latency_ns"`
+	Success   bool          `json:"success"`
+}
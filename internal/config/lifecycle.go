@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Route lifecycle states. Deprecated routes still serve traffic but
+// clients should move off them; retired routes are kept for reference but
+// left out of the gateway configuration, even if enabled.
+const (
+	LifecycleActive     = "active"
+	LifecycleDeprecated = "deprecated"
+	LifecycleRetired    = "retired"
+)
+
+// apiVersion matches API version names such as "v1", "v2beta1" or
+// "2024-06-01".
+var apiVersion = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,31}$`)
+
+// validateLifecycle checks the API version, lifecycle state and
+// deprecation of a route, defaulting the state to active.
+func validateLifecycle(r *Route) error {
+	if r.APIVersion != "" && !apiVersion.MatchString(r.APIVersion) {
+		return fmt.Errorf("invalid api_version %q (letters, digits, '.', '_' and '-', at most 32)", r.APIVersion)
+	}
+	switch r.Lifecycle {
+	case "":
+		r.Lifecycle = LifecycleActive
+	case LifecycleActive, LifecycleDeprecated, LifecycleRetired:
+	default:
+		return errors.New("invalid lifecycle (must be 'active', 'deprecated' or 'retired')")
+	}
+	return r.Deprecation.Validate()
+}
+
+// RouteDeprecation announces that a route is going away. Gateways answer
+// it with Deprecation (RFC 9745) and, if set, Sunset (RFC 8594) and Link
+// headers, the latter pointing clients at the replacement.
+type RouteDeprecation struct {
+	// Date is when the route is or will be deprecated.
+	Date time.Time `json:"date"`
+	// Sunset is when the route is expected to stop serving.
+	Sunset *time.Time `json:"sunset,omitempty"`
+	// Link is the absolute URL of the replacement API or its docs.
+	Link string `json:"link,omitempty"`
+}
+
+// Validate checks the dates and link of the deprecation. A nil
+// deprecation is valid and means no headers are sent.
+func (d *RouteDeprecation) Validate() error {
+	if d == nil {
+		return nil
+	}
+	if d.Date.IsZero() {
+		return errors.New("deprecation.date is required")
+	}
+	if d.Sunset != nil && !d.Sunset.After(d.Date) {
+		return errors.New("deprecation.sunset must be after deprecation.date")
+	}
+	if d.Link != "" && !validHTTPURL(d.Link) {
+		return fmt.Errorf("invalid deprecation.link %q (must be an absolute http or https URL)", d.Link)
+	}
+	return nil
+}
+
+// SunsetPassed reports whether r is enabled and still served after the
+// sunset date of its deprecation.
+func (r *Route) SunsetPassed(now time.Time) bool {
+	if !r.Enabled || r.Lifecycle == LifecycleRetired || r.Deprecation == nil || r.Deprecation.Sunset == nil {
+		return false
+	}
+	return !now.Before(*r.Deprecation.Sunset)
+}
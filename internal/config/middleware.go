@@ -0,0 +1,187 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Types of middleware parameters, as JSON values.
+const (
+	ParamString     = "string"
+	ParamInteger    = "integer"
+	ParamBoolean    = "boolean"
+	ParamStringList = "string_list"
+)
+
+// MiddlewareSpec describes a gateway middleware routes can use.
+type MiddlewareSpec struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Params      []MiddlewareParam `json:"params,omitempty"`
+}
+
+// MiddlewareParam describes a parameter of a middleware.
+type MiddlewareParam struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// middlewareCatalogue lists the middlewares gateways implement. Adding one
+// here makes it available to routes.
+var middlewareCatalogue = map[string]MiddlewareSpec{
+	"auth": {
+		Name:        "auth",
+		Description: "Authenticates the caller before the request reaches the backend.",
+		Params: []MiddlewareParam{
+			{Name: "scheme", Type: ParamString, Required: true, Enum: []string{"jwt", "api_key"}},
+			{Name: "scopes", Type: ParamStringList, Description: "Scopes the caller must have, all of them."},
+		},
+	},
+	"ratelimit": {
+		Name:        "ratelimit",
+		Description: "Limits the request rate per key.",
+		Params: []MiddlewareParam{
+			{Name: "requests_per_second", Type: ParamInteger, Required: true},
+			{Name: "burst", Type: ParamInteger},
+			{Name: "key", Type: ParamString, Description: "'ip', 'header:<name>', 'query:<name>' or 'cookie:<name>'; defaults to 'ip'."},
+		},
+	},
+	"transform": {
+		Name:        "transform",
+		Description: "Applies the route's body transforms at this point of the chain; requires transform on the route.",
+	},
+	"logging": {
+		Name:        "logging",
+		Description: "Logs requests and responses.",
+		Params: []MiddlewareParam{
+			{Name: "level", Type: ParamString, Enum: []string{"debug", "info", "warn", "error"}},
+			{Name: "log_body", Type: ParamBoolean},
+		},
+	},
+}
+
+// MaxMiddlewares bounds the middleware chain of a route.
+const MaxMiddlewares = 16
+
+// Middlewares returns the catalogue of gateway middlewares, sorted by name.
+func Middlewares() []MiddlewareSpec {
+	specs := make([]MiddlewareSpec, 0, len(middlewareCatalogue))
+	for _, spec := range middlewareCatalogue {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// RouteMiddleware is a middleware in the chain of a route. Gateways run
+// the chain in order before calling the backend.
+type RouteMiddleware struct {
+	Name   string                 `json:"name"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// validateMiddlewares checks the middleware chain of route against the
+// catalogue: each middleware is known and used once, with known
+// parameters of the right type and all required ones set. Header names in
+// rate limit keys are canonicalized.
+func validateMiddlewares(route *Route) error {
+	if len(route.Middlewares) > MaxMiddlewares {
+		return fmt.Errorf("too many middlewares (at most %d)", MaxMiddlewares)
+	}
+	seen := make(map[string]bool, len(route.Middlewares))
+	for i, m := range route.Middlewares {
+		path := fmt.Sprintf("middlewares[%d]", i)
+		spec, ok := middlewareCatalogue[m.Name]
+		if !ok {
+			return fmt.Errorf("%s: unknown middleware %q", path, m.Name)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("%s: middleware %s is already in the chain", path, m.Name)
+		}
+		seen[m.Name] = true
+
+		known := make(map[string]bool, len(spec.Params))
+		for _, p := range spec.Params {
+			known[p.Name] = true
+			value, set := m.Params[p.Name]
+			if !set {
+				if p.Required {
+					return fmt.Errorf("%s: %s.%s is required", path, m.Name, p.Name)
+				}
+				continue
+			}
+			if err := p.check(value); err != nil {
+				return fmt.Errorf("%s: %s.%s %v", path, m.Name, p.Name, err)
+			}
+		}
+		for name := range m.Params {
+			if !known[name] {
+				return fmt.Errorf("%s: unknown parameter %s.%s", path, m.Name, name)
+			}
+		}
+
+		switch m.Name {
+		case "transform":
+			if route.Transform == nil {
+				return fmt.Errorf("%s: transform middleware requires transform on the route", path)
+			}
+		case "ratelimit":
+			if key, ok := m.Params["key"].(string); ok {
+				key, err := validateHashKey(path+": ratelimit.key", key)
+				if err != nil {
+					return err
+				}
+				m.Params["key"] = key
+			}
+		}
+	}
+	return nil
+}
+
+// check checks a value of the parameter as decoded from JSON.
+func (p *MiddlewareParam) check(value interface{}) error {
+	switch p.Type {
+	case ParamString:
+		s, ok := value.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		if len(p.Enum) > 0 && !contains(p.Enum, s) {
+			return fmt.Errorf("must be one of %v", p.Enum)
+		}
+	case ParamInteger:
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) || n < 0 {
+			return errors.New("must be a non-negative integer")
+		}
+	case ParamBoolean:
+		if _, ok := value.(bool); !ok {
+			return errors.New("must be a boolean")
+		}
+	case ParamStringList:
+		list, ok := value.([]interface{})
+		if !ok {
+			return errors.New("must be a list of strings")
+		}
+		for _, item := range list {
+			if _, ok := item.(string); !ok {
+				return errors.New("must be a list of strings")
+			}
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,310 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const (
+	// migrationLock is the name of the MySQL lock held while migrating,
+	// so that replicas starting together do not apply migrations twice.
+	migrationLock = "admin_schema_migrate"
+	// migrationLockTimeout bounds the wait for another replica's
+	// migrations, in seconds.
+	migrationLockTimeout = 300
+)
+
+// migrationDB is the subset of *sql.DB and *sql.Conn migrations run on.
+type migrationDB interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Migration is a single versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrations returns the embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		// File names look like 0002_backend_credentials.up.sql
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", entry.Name())
+		}
+
+		data, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		switch direction {
+		case ".up":
+			m.Up = string(data)
+		case ".down":
+			m.Down = string(data)
+		default:
+			return nil, fmt.Errorf("invalid migration direction: %s", entry.Name())
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// MigrationState describes a migration and whether it has been applied.
+type MigrationState struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Migrate applies every pending migration in order.
+func (s *MySQLStore) Migrate() error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		return nil
+	}
+	return s.MigrateTo(migrations[len(migrations)-1].Version)
+}
+
+// MigrateTo moves the schema to version: pending migrations up to and
+// including it are applied in order, and applied migrations above it are
+// rolled back newest first. Version 0 rolls back everything. Migrations
+// run under a MySQL named lock, waiting for those of other replicas.
+func (s *MySQLStore) MigrateTo(version int) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	if version != 0 && !hasVersion(migrations, version) {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	// The lock belongs to a connection, which the migrations then use
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`, migrationLock, migrationLockTimeout).Scan(&locked); err != nil {
+		return fmt.Errorf("lock migrations: %w", err)
+	}
+	if locked.Int64 != 1 {
+		return errors.New("lock migrations: timed out waiting for another instance's migrations")
+	}
+	defer conn.ExecContext(ctx, `DO RELEASE_LOCK(?)`, migrationLock)
+
+	if err := ensureMigrationsTable(conn); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.Version > version || applied[m.Version] != nil {
+			continue
+		}
+		if err := execScript(conn, m.Up); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		if _, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name); err != nil {
+			return err
+		}
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= version || applied[m.Version] == nil {
+			continue
+		}
+		if err := execScript(conn, m.Down); err != nil {
+			return fmt.Errorf("rollback %d (%s): %w", m.Version, m.Name, err)
+		}
+		if _, err := conn.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, m.Version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MigrateDown rolls back the most recently applied migration. It returns
+// the version rolled back, or 0 if nothing was applied.
+func (s *MySQLStore) MigrateDown() (int, error) {
+	states, err := s.MigrationStatus()
+	if err != nil {
+		return 0, err
+	}
+
+	latest, previous := 0, 0
+	for _, st := range states {
+		if !st.Applied {
+			continue
+		}
+		previous, latest = latest, st.Version
+	}
+	if latest == 0 {
+		return 0, nil
+	}
+	return latest, s.MigrateTo(previous)
+}
+
+// MigrationStatus lists every known migration with its applied state.
+func (s *MySQLStore) MigrationStatus() ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(s.db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, len(migrations))
+	for i, m := range migrations {
+		states[i] = MigrationState{Version: m.Version, Name: m.Name}
+		if at := applied[m.Version]; at != nil {
+			states[i].Applied = true
+			states[i].AppliedAt = at
+		}
+	}
+	return states, nil
+}
+
+func ensureMigrationsTable(db migrationDB) error {
+	_, err := db.ExecContext(context.Background(), `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INT          NOT NULL PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+func hasVersion(migrations []Migration, version int) bool {
+	for _, m := range migrations {
+		if m.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// appliedMigrations returns when each applied version was applied.
+func appliedMigrations(db migrationDB) (map[int]*time.Time, error) {
+	rows, err := db.QueryContext(context.Background(), `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]*time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = &at
+	}
+	return applied, rows.Err()
+}
+
+// execScript runs each statement of a migration file. MySQL DDL is not
+// transactional, so statements are executed one by one. A statement ends
+// with a semicolon at the end of a line, so that semicolons within a
+// line, as in string literals and comments, are left alone.
+func execScript(db migrationDB, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := db.ExecContext(context.Background(), stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a migration file into its statements, without
+// their terminating semicolons. Comment lines never end a statement.
+func splitStatements(script string) []string {
+	var stmts []string
+	var stmt strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(stmt.String()); strings.TrimSpace(stripComments(text)) != "" {
+			stmts = append(stmts, text)
+		}
+		stmt.Reset()
+	}
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasSuffix(trimmed, ";") && !strings.HasPrefix(trimmed, "--") && !strings.HasPrefix(trimmed, "#") {
+			stmt.WriteString(strings.TrimSuffix(strings.TrimRight(line, " \t\r"), ";"))
+			flush()
+			continue
+		}
+		stmt.WriteString(line)
+		stmt.WriteByte('\n')
+	}
+	flush()
+	return stmts
+}
+
+// stripComments removes "--" line comments from a statement.
+func stripComments(stmt string) string {
+	lines := strings.Split(stmt, "\n")
+	for i, line := range lines {
+		if idx := strings.Index(line, "--"); idx >= 0 {
+			lines[i] = line[:idx]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1 @@
+-- The baseline tables are owned by assistant_gateway and are never dropped.
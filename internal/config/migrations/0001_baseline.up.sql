@@ -0,0 +1,43 @@
+-- Baseline schema shared with assistant_gateway (db/schema.sql). Tables are
+-- created only if missing so existing databases are adopted as-is.
+CREATE TABLE IF NOT EXISTS backends (
+    id          INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    name        VARCHAR(64)  NOT NULL,
+    addr        VARCHAR(255) NOT NULL,
+    description VARCHAR(255) NULL,
+    enabled     TINYINT(1)   NOT NULL DEFAULT 1,
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY uk_backends_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS routes (
+    id              INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    http_method     VARCHAR(16)  NOT NULL,
+    http_pattern    VARCHAR(255) NOT NULL,
+    backend_name    VARCHAR(64)  NOT NULL,
+    backend_service VARCHAR(255) NOT NULL,
+    backend_method  VARCHAR(128) NOT NULL,
+    timeout_ms      INT          NOT NULL DEFAULT 5000,
+    description     VARCHAR(255) NULL,
+    enabled         TINYINT(1)   NOT NULL DEFAULT 1,
+    created_at      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY idx_routes_backend_name (backend_name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS config_history (
+    id          BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    config_type VARCHAR(16)  NOT NULL,
+    config_id   INT UNSIGNED NULL,
+    operation   VARCHAR(16)  NOT NULL,
+    old_value   JSON         NULL,
+    new_value   JSON         NULL,
+    operator    VARCHAR(128) NOT NULL DEFAULT '',
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY idx_config_history_type_id (config_type, config_id),
+    KEY idx_config_history_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
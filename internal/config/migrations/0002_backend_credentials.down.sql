@@ -0,0 +1,5 @@
+ALTER TABLE backends
+    DROP COLUMN credential_secret,
+    DROP COLUMN credential_secret_ref,
+    DROP COLUMN credential_username,
+    DROP COLUMN credential_type;
@@ -0,0 +1,5 @@
+ALTER TABLE backends
+    ADD COLUMN credential_type       VARCHAR(16)  NULL AFTER enabled,
+    ADD COLUMN credential_username   VARCHAR(255) NULL AFTER credential_type,
+    ADD COLUMN credential_secret_ref VARCHAR(255) NULL AFTER credential_username,
+    ADD COLUMN credential_secret     TEXT         NULL AFTER credential_secret_ref;
@@ -0,0 +1,3 @@
+ALTER TABLE backends
+    DROP COLUMN tls_client_key,
+    DROP COLUMN tls_config;
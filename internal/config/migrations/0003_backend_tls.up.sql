@@ -0,0 +1,3 @@
+ALTER TABLE backends
+    ADD COLUMN tls_config     JSON NULL AFTER credential_secret,
+    ADD COLUMN tls_client_key TEXT NULL AFTER tls_config;
@@ -0,0 +1,2 @@
+ALTER TABLE config_history
+    DROP COLUMN change_reason;
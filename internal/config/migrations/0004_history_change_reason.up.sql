@@ -0,0 +1,2 @@
+ALTER TABLE config_history
+    ADD COLUMN change_reason VARCHAR(512) NULL AFTER operator;
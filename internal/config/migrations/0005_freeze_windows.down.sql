@@ -0,0 +1,4 @@
+ALTER TABLE config_history
+    DROP COLUMN freeze_override;
+
+DROP TABLE IF EXISTS freeze_windows;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS freeze_windows (
+    id           INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    name         VARCHAR(128) NOT NULL,
+    reason       VARCHAR(512) NULL,
+    starts_at    TIMESTAMP    NULL,
+    ends_at      TIMESTAMP    NULL,
+    weekly_start VARCHAR(16)  NULL,
+    weekly_end   VARCHAR(16)  NULL,
+    timezone     VARCHAR(64)  NULL,
+    enabled      TINYINT(1)   NOT NULL DEFAULT 1,
+    created_by   VARCHAR(128) NOT NULL DEFAULT '',
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE config_history
+    ADD COLUMN freeze_override TINYINT(1) NOT NULL DEFAULT 0 AFTER change_reason;
@@ -0,0 +1,2 @@
+ALTER TABLE config_history
+    DROP KEY idx_config_history_operator_created_at;
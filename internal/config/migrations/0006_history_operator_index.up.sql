@@ -0,0 +1,2 @@
+ALTER TABLE config_history
+    ADD KEY idx_config_history_operator_created_at (operator, created_at);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS backend_latency_samples (
+    id           BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    backend_name VARCHAR(128)    NOT NULL,
+    checked_at   DATETIME(3)     NOT NULL,
+    latency_us   INT UNSIGNED    NOT NULL,
+    success      TINYINT(1)      NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_backend_latency_backend_checked_at (backend_name, checked_at),
+    KEY idx_backend_latency_checked_at (checked_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
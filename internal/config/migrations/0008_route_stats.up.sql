@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS route_stats (
+    route_id         INT UNSIGNED    NOT NULL,
+    bucket_start     DATETIME        NOT NULL,
+    requests         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    errors           BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    latency_le_5     BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    latency_le_10    BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    latency_le_25    BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    latency_le_50    BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    latency_le_100   BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    latency_le_250   BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    latency_le_500   BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    latency_le_1000  BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    latency_le_2500  BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    latency_le_5000  BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    latency_le_10000 BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    latency_inf      BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    PRIMARY KEY (route_id, bucket_start),
+    KEY idx_route_stats_bucket_start (bucket_start)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
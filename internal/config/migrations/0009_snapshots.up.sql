@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS config_snapshots (
+    id            INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    name          VARCHAR(128) NOT NULL,
+    description   VARCHAR(512) NULL,
+    backend_count INT UNSIGNED NOT NULL DEFAULT 0,
+    route_count   INT UNSIGNED NOT NULL DEFAULT 0,
+    document      JSON         NOT NULL,
+    created_by    VARCHAR(128) NOT NULL DEFAULT '',
+    created_at    TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY idx_config_snapshots_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS backend_descriptors (
+    id           INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    backend_name VARCHAR(64)  NOT NULL,
+    version      INT UNSIGNED NOT NULL,
+    sha256       CHAR(64)     NOT NULL,
+    size         INT UNSIGNED NOT NULL,
+    services     JSON         NOT NULL,
+    data         LONGBLOB     NOT NULL,
+    uploaded_by  VARCHAR(128) NOT NULL DEFAULT '',
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY uk_backend_descriptors_version (backend_name, version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
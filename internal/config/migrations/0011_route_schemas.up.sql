@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS route_schemas (
+    id         INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    route_id   INT UNSIGNED NOT NULL,
+    version    INT UNSIGNED NOT NULL,
+    request    JSON         NULL,
+    response   JSON         NULL,
+    created_by VARCHAR(128) NOT NULL DEFAULT '',
+    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY uk_route_schemas_version (route_id, version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN transform JSON NULL AFTER description;
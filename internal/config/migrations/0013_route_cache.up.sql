@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN cache JSON NULL AFTER transform;
@@ -0,0 +1,5 @@
+ALTER TABLE routes
+    DROP COLUMN circuit_breaker;
+
+ALTER TABLE backends
+    DROP COLUMN circuit_breaker;
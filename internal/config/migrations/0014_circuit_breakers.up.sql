@@ -0,0 +1,5 @@
+ALTER TABLE backends
+    ADD COLUMN circuit_breaker JSON NULL AFTER tls_client_key;
+
+ALTER TABLE routes
+    ADD COLUMN circuit_breaker JSON NULL AFTER cache;
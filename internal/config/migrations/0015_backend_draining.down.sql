@@ -0,0 +1,2 @@
+ALTER TABLE backends
+    DROP COLUMN draining;
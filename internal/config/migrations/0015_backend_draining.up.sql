@@ -0,0 +1,2 @@
+ALTER TABLE backends
+    ADD COLUMN draining TINYINT(1) NOT NULL DEFAULT 0 AFTER enabled;
@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    DROP COLUMN rollout;
@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN rollout JSON NULL AFTER circuit_breaker;
@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN experiment JSON NULL AFTER rollout;
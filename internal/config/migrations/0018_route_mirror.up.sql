@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN mirror JSON NULL AFTER experiment;
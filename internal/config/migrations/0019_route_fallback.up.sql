@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN fallback JSON NULL AFTER mirror;
@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN grpc JSON NULL AFTER fallback;
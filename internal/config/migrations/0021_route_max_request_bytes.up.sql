@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN max_request_bytes INT NOT NULL DEFAULT 0 AFTER timeout_ms;
@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN content_types JSON NULL AFTER max_request_bytes;
@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN middlewares JSON NULL AFTER grpc;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS plugin_schemas;
+
+ALTER TABLE routes
+    DROP COLUMN plugins;
+
+ALTER TABLE backends
+    DROP COLUMN plugins;
@@ -0,0 +1,15 @@
+ALTER TABLE backends
+    ADD COLUMN plugins JSON NULL AFTER circuit_breaker;
+
+ALTER TABLE routes
+    ADD COLUMN plugins JSON NULL AFTER middlewares;
+
+CREATE TABLE IF NOT EXISTS plugin_schemas (
+    name        VARCHAR(64)  NOT NULL,
+    description VARCHAR(512) NULL,
+    schema_json JSON         NOT NULL,
+    updated_by  VARCHAR(128) NOT NULL DEFAULT '',
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
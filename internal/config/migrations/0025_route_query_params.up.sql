@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN query_params JSON NULL AFTER plugins;
@@ -0,0 +1,3 @@
+ALTER TABLE routes
+    DROP COLUMN lifecycle,
+    DROP COLUMN api_version;
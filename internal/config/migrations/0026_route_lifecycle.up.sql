@@ -0,0 +1,3 @@
+ALTER TABLE routes
+    ADD COLUMN api_version VARCHAR(32) NOT NULL DEFAULT '' AFTER description,
+    ADD COLUMN lifecycle VARCHAR(16) NOT NULL DEFAULT 'active' AFTER api_version;
@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN deprecation JSON NULL AFTER query_params;
@@ -0,0 +1,2 @@
+ALTER TABLE routes
+    ADD COLUMN docs JSON NULL AFTER deprecation;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id               INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    kind             VARCHAR(64)  NOT NULL,
+    status           VARCHAR(16)  NOT NULL DEFAULT 'pending',
+    params           MEDIUMTEXT   NULL,
+    params_encrypted TINYINT(1)   NOT NULL DEFAULT 0,
+    progress_done    INT UNSIGNED NOT NULL DEFAULT 0,
+    progress_total   INT UNSIGNED NOT NULL DEFAULT 0,
+    result           JSON         NULL,
+    error            TEXT         NULL,
+    attempts         INT UNSIGNED NOT NULL DEFAULT 0,
+    created_by       VARCHAR(128) NOT NULL DEFAULT '',
+    created_at       DATETIME(3)  NOT NULL,
+    started_at       DATETIME(3)  NULL,
+    finished_at      DATETIME(3)  NULL,
+    updated_at       DATETIME(3)  NOT NULL,
+    PRIMARY KEY (id),
+    KEY idx_jobs_status_id (status, id),
+    KEY idx_jobs_finished_at (finished_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
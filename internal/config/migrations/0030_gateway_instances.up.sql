@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS gateway_instances (
+    id            VARCHAR(128) NOT NULL,
+    config_sha256 CHAR(64)     NOT NULL,
+    reported_at   DATETIME(3)  NOT NULL,
+    PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
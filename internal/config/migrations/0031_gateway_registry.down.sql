@@ -0,0 +1,7 @@
+DELETE FROM gateway_instances WHERE config_sha256 = '';
+ALTER TABLE gateway_instances
+    DROP KEY idx_gateway_instances_last_seen_at,
+    DROP COLUMN registered_at,
+    DROP COLUMN addr,
+    DROP COLUMN version,
+    RENAME COLUMN last_seen_at TO reported_at;
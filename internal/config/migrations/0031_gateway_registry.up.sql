@@ -0,0 +1,6 @@
+ALTER TABLE gateway_instances
+    RENAME COLUMN reported_at TO last_seen_at,
+    ADD COLUMN version VARCHAR(64) NOT NULL DEFAULT '' AFTER id,
+    ADD COLUMN addr VARCHAR(255) NOT NULL DEFAULT '' AFTER version,
+    ADD COLUMN registered_at DATETIME(3) NULL AFTER config_sha256,
+    ADD KEY idx_gateway_instances_last_seen_at (last_seen_at);
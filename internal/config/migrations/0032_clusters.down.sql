@@ -0,0 +1,6 @@
+ALTER TABLE gateway_instances
+    DROP COLUMN cluster;
+ALTER TABLE routes
+    DROP COLUMN clusters;
+ALTER TABLE backends
+    DROP COLUMN clusters;
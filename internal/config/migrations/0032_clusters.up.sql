@@ -0,0 +1,6 @@
+ALTER TABLE backends
+    ADD COLUMN clusters JSON NULL AFTER description;
+ALTER TABLE routes
+    ADD COLUMN clusters JSON NULL AFTER description;
+ALTER TABLE gateway_instances
+    ADD COLUMN cluster VARCHAR(64) NOT NULL DEFAULT '' AFTER addr;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS config_rollout_health;
+DROP TABLE IF EXISTS config_rollouts;
+ALTER TABLE gateway_instances
+    DROP COLUMN labels;
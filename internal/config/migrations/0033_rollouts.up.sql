@@ -0,0 +1,27 @@
+ALTER TABLE gateway_instances
+    ADD COLUMN labels JSON NULL AFTER cluster;
+
+CREATE TABLE IF NOT EXISTS config_rollouts (
+    id          INT UNSIGNED     NOT NULL AUTO_INCREMENT,
+    status      VARCHAR(16)      NOT NULL,
+    percent     TINYINT UNSIGNED NOT NULL,
+    labels      JSON             NULL,
+    description VARCHAR(512)     NULL,
+    baseline    JSON             NOT NULL,
+    created_by  VARCHAR(128)     NOT NULL DEFAULT '',
+    created_at  DATETIME(3)      NOT NULL,
+    finished_by VARCHAR(128)     NOT NULL DEFAULT '',
+    finished_at DATETIME(3)      NULL,
+    PRIMARY KEY (id),
+    KEY idx_config_rollouts_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS config_rollout_health (
+    rollout_id INT UNSIGNED    NOT NULL,
+    gateway_id VARCHAR(128)    NOT NULL,
+    canary     TINYINT(1)      NOT NULL,
+    requests   BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    errors     BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    updated_at DATETIME(3)     NOT NULL,
+    PRIMARY KEY (rollout_id, gateway_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
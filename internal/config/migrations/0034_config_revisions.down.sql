@@ -0,0 +1,6 @@
+ALTER TABLE config_rollouts
+    DROP COLUMN revision;
+ALTER TABLE gateway_instances
+    DROP COLUMN failure,
+    DROP COLUMN failed_revision,
+    DROP COLUMN config_revision;
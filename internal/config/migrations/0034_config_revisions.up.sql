@@ -0,0 +1,6 @@
+ALTER TABLE gateway_instances
+    ADD COLUMN config_revision BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER config_sha256,
+    ADD COLUMN failed_revision BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER config_revision,
+    ADD COLUMN failure         VARCHAR(512)    NOT NULL DEFAULT '' AFTER failed_revision;
+ALTER TABLE config_rollouts
+    ADD COLUMN revision BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER description;
@@ -0,0 +1,7 @@
+ALTER TABLE routes
+    DROP COLUMN revision;
+ALTER TABLE backends
+    DROP COLUMN revision;
+ALTER TABLE config_history
+    DROP COLUMN revision;
+DROP TABLE IF EXISTS config_revision;
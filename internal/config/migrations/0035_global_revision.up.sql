@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS config_revision (
+    id       TINYINT UNSIGNED NOT NULL,
+    revision BIGINT UNSIGNED  NOT NULL,
+    PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT INTO config_revision (id, revision)
+    SELECT 1, COALESCE(MAX(id), 0) FROM config_history;
+
+ALTER TABLE config_history
+    ADD COLUMN revision BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER freeze_override;
+
+UPDATE config_history SET revision = id;
+
+ALTER TABLE backends
+    ADD COLUMN revision BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER plugins;
+
+UPDATE backends b
+    JOIN (SELECT config_id, MAX(revision) AS revision FROM config_history
+          WHERE config_type = 'backend' GROUP BY config_id) h ON h.config_id = b.id
+    SET b.revision = h.revision, b.updated_at = b.updated_at;
+
+ALTER TABLE routes
+    ADD COLUMN revision BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER enabled;
+
+UPDATE routes r
+    JOIN (SELECT config_id, MAX(revision) AS revision FROM config_history
+          WHERE config_type = 'route' GROUP BY config_id) h ON h.config_id = r.id
+    SET r.revision = h.revision, r.updated_at = r.updated_at;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS outbox_sinks;
+DROP TABLE IF EXISTS config_outbox;
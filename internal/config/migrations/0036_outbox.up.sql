@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS config_outbox (
+    revision   BIGINT UNSIGNED NOT NULL,
+    payload    JSON            NOT NULL,
+    created_at DATETIME(3)     NOT NULL,
+    PRIMARY KEY (revision),
+    KEY idx_config_outbox_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS outbox_sinks (
+    name            VARCHAR(128)    NOT NULL,
+    delivered       BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    attempts        INT UNSIGNED    NOT NULL DEFAULT 0,
+    last_error      VARCHAR(512)    NOT NULL DEFAULT '',
+    next_attempt_at DATETIME(3)     NULL,
+    lease_owner     VARCHAR(128)    NOT NULL DEFAULT '',
+    lease_until     DATETIME(3)     NULL,
+    updated_at      DATETIME(3)     NOT NULL,
+    PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
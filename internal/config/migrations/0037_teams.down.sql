@@ -0,0 +1,9 @@
+ALTER TABLE routes
+    DROP KEY idx_routes_owner_team,
+    DROP COLUMN owner_team;
+
+ALTER TABLE backends
+    DROP KEY idx_backends_owner_team,
+    DROP COLUMN owner_team;
+
+DROP TABLE IF EXISTS teams;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS teams (
+    name        VARCHAR(64)  NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    contact     VARCHAR(255) NOT NULL DEFAULT '',
+    created_by  VARCHAR(128) NOT NULL DEFAULT '',
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE backends
+    ADD COLUMN owner_team VARCHAR(64) NOT NULL DEFAULT '' AFTER plugins,
+    ADD KEY idx_backends_owner_team (owner_team);
+
+ALTER TABLE routes
+    ADD COLUMN owner_team VARCHAR(64) NOT NULL DEFAULT '' AFTER docs,
+    ADD KEY idx_routes_owner_team (owner_team);
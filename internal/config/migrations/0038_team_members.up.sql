@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS team_members (
+    team       VARCHAR(64)  NOT NULL,
+    member     VARCHAR(128) NOT NULL,
+    added_by   VARCHAR(128) NOT NULL DEFAULT '',
+    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (team, member),
+    KEY idx_team_members_member (member)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
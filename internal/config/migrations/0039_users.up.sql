@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS users (
+    name                 VARCHAR(64)  NOT NULL,
+    display_name         VARCHAR(128) NOT NULL DEFAULT '',
+    role                 VARCHAR(16)  NOT NULL,
+    password_hash        VARCHAR(255) NOT NULL,
+    must_change_password TINYINT(1)   NOT NULL DEFAULT 0,
+    disabled             TINYINT(1)   NOT NULL DEFAULT 0,
+    password_changed_at  DATETIME(3)  NOT NULL,
+    last_login_at        DATETIME(3)  NULL,
+    created_by           VARCHAR(128) NOT NULL DEFAULT '',
+    created_at           TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at           TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
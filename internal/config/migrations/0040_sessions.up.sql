@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id           VARCHAR(32)  NOT NULL,
+    user_name    VARCHAR(64)  NOT NULL,
+    refresh_hash CHAR(64)     NOT NULL,
+    user_agent   VARCHAR(255) NOT NULL DEFAULT '',
+    ip           VARCHAR(64)  NOT NULL DEFAULT '',
+    created_at   DATETIME(3)  NOT NULL,
+    last_used_at DATETIME(3)  NOT NULL,
+    expires_at   DATETIME(3)  NOT NULL,
+    revoked_at   DATETIME(3)  NULL,
+    PRIMARY KEY (id),
+    KEY idx_sessions_user_name (user_name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
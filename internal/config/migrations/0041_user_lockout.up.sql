@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN failed_logins        INT UNSIGNED NOT NULL DEFAULT 0 AFTER last_login_at,
+    ADD COLUMN last_failed_login_at DATETIME(3)  NULL AFTER failed_logins,
+    ADD COLUMN locked_until         DATETIME(3)  NULL AFTER last_failed_login_at;
@@ -0,0 +1,12 @@
+ALTER TABLE users
+    ADD COLUMN totp_secret           VARCHAR(255) NULL AFTER password_changed_at,
+    ADD COLUMN totp_secret_encrypted TINYINT(1)   NOT NULL DEFAULT 0 AFTER totp_secret,
+    ADD COLUMN totp_enabled          TINYINT(1)   NOT NULL DEFAULT 0 AFTER totp_secret_encrypted,
+    ADD COLUMN totp_last_step        BIGINT       NOT NULL DEFAULT 0 AFTER totp_enabled;
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    user_name VARCHAR(64) NOT NULL,
+    code_hash CHAR(64)    NOT NULL,
+    used_at   DATETIME(3) NULL,
+    PRIMARY KEY (user_name, code_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_group_members;
+DROP TABLE IF EXISTS user_groups;
+
+ALTER TABLE users
+    DROP COLUMN external_id;
@@ -0,0 +1,19 @@
+ALTER TABLE users
+    ADD COLUMN external_id VARCHAR(255) NOT NULL DEFAULT '' AFTER display_name;
+
+CREATE TABLE IF NOT EXISTS user_groups (
+    id           VARCHAR(64)  NOT NULL,
+    display_name VARCHAR(255) NOT NULL,
+    external_id  VARCHAR(255) NOT NULL DEFAULT '',
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY uk_user_groups_display_name (display_name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS user_group_members (
+    group_id  VARCHAR(64) NOT NULL,
+    user_name VARCHAR(64) NOT NULL,
+    PRIMARY KEY (group_id, user_name),
+    KEY idx_user_group_members_user_name (user_name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RouteMirror copies a share of a route's requests to another backend for
+// dark testing. Gateways send the copy without waiting for it and discard
+// its response and errors; clients only ever see the route's own answer.
+type RouteMirror struct {
+	BackendName string `json:"backend_name"`
+	// BackendService and BackendMethod default to those of the route.
+	BackendService string `json:"backend_service,omitempty"`
+	BackendMethod  string `json:"backend_method,omitempty"`
+	// Percent is the share of requests copied, 1–100.
+	Percent int `json:"percent"`
+}
+
+// Target returns the route as called for the mirror.
+func (m *RouteMirror) Target(route *Route) *Route {
+	target := *route
+	target.BackendName = m.BackendName
+	if m.BackendService != "" {
+		target.BackendService = m.BackendService
+	}
+	if m.BackendMethod != "" {
+		target.BackendMethod = m.BackendMethod
+	}
+	return &target
+}
+
+// Validate checks the mirror of route. The mirror backend must not be one
+// that answers the route, its own, an experiment variant's or the
+// fallback's, so mirrored
+// requests never reach the response path. Whether the backend exists is up
+// to the caller. A nil mirror is valid and means requests are not copied.
+func (m *RouteMirror) Validate(route *Route) error {
+	if m == nil {
+		return nil
+	}
+	if m.BackendName == "" {
+		return errors.New("mirror.backend_name is required")
+	}
+	if m.Percent < 1 || m.Percent > 100 {
+		return errors.New("mirror.percent must be between 1 and 100")
+	}
+
+	if m.BackendName == route.BackendName {
+		return fmt.Errorf("mirror.backend_name %s already serves the route", m.BackendName)
+	}
+	if route.Experiment != nil {
+		for _, v := range route.Experiment.Variants {
+			if v.BackendName == m.BackendName {
+				return fmt.Errorf("mirror.backend_name %s already serves variant %q", m.BackendName, v.Name)
+			}
+		}
+	}
+	if route.Fallback != nil && route.Fallback.BackendName == m.BackendName {
+		return fmt.Errorf("mirror.backend_name %s already serves the fallback", m.BackendName)
+	}
+	return nil
+}
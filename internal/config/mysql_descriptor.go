@@ -0,0 +1,117 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// CreateDescriptorSet stores the next version of a backend's descriptors,
+// setting its ID and Version.
+func (s *MySQLStore) CreateDescriptorSet(set *DescriptorSet) error {
+	services, err := json.Marshal(set.Services)
+	if err != nil {
+		return err
+	}
+
+	return s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		var version int
+		row := q.QueryRow(
+			`SELECT COALESCE(MAX(version), 0) FROM backend_descriptors WHERE backend_name = ? FOR UPDATE`,
+			set.BackendName,
+		)
+		if err := row.Scan(&version); err != nil {
+			return err
+		}
+		version++
+
+		result, err := q.Exec(
+			`INSERT INTO backend_descriptors (backend_name, version, sha256, size, services, data, uploaded_by)
+			 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			set.BackendName, version, set.SHA256, len(set.Data), string(services), set.Data, set.UploadedBy,
+		)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		set.ID = uint(id)
+		set.Version = version
+		set.Size = len(set.Data)
+		set.CreatedAt = time.Now()
+		return nil
+	})
+}
+
+// GetDescriptorSets returns the descriptor set versions of a backend
+// without their data, newest first.
+func (s *MySQLStore) GetDescriptorSets(backend string) ([]DescriptorSet, error) {
+	rows, err := s.q.Query(
+		`SELECT id, backend_name, version, sha256, size, services, uploaded_by, created_at
+		 FROM backend_descriptors WHERE backend_name = ? ORDER BY version DESC`, backend,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sets []DescriptorSet
+	for rows.Next() {
+		set, err := scanDescriptorSet(rows, nil)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, *set)
+	}
+	return sets, rows.Err()
+}
+
+// GetDescriptorSet returns a version of a backend's descriptors with its
+// data, or the latest one if version is 0. It returns nil if there is no
+// such version.
+func (s *MySQLStore) GetDescriptorSet(backend string, version int) (*DescriptorSet, error) {
+	query := `SELECT id, backend_name, version, sha256, size, services, uploaded_by, created_at, data
+	          FROM backend_descriptors WHERE backend_name = ?`
+	args := []interface{}{backend}
+	if version > 0 {
+		query += ` AND version = ?`
+		args = append(args, version)
+	}
+	query += ` ORDER BY version DESC LIMIT 1`
+
+	var data []byte
+	set, err := scanDescriptorSet(s.q.QueryRow(query, args...), &data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	set.Data = data
+	return set, nil
+}
+
+// scanDescriptorSet scans a descriptor set row, and its data into data if
+// it is not nil.
+func scanDescriptorSet(row rowScanner, data *[]byte) (*DescriptorSet, error) {
+	var set DescriptorSet
+	var services []byte
+	dest := []interface{}{&set.ID, &set.BackendName, &set.Version, &set.SHA256, &set.Size, &services, &set.UploadedBy, &set.CreatedAt}
+	if data != nil {
+		dest = append(dest, data)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(services, &set.Services); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
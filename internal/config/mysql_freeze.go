@@ -0,0 +1,162 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const freezeWindowColumns = `id, name, reason, starts_at, ends_at, weekly_start, weekly_end,
+	timezone, enabled, created_by, created_at, updated_at`
+
+func scanFreezeWindow(row rowScanner) (*FreezeWindow, error) {
+	var f FreezeWindow
+	var reason, weeklyStart, weeklyEnd, timezone sql.NullString
+	var startsAt, endsAt sql.NullTime
+	var enabledInt int
+
+	if err := row.Scan(
+		&f.ID, &f.Name, &reason, &startsAt, &endsAt, &weeklyStart, &weeklyEnd,
+		&timezone, &enabledInt, &f.CreatedBy, &f.CreatedAt, &f.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	f.Reason = reason.String
+	f.WeeklyStart = weeklyStart.String
+	f.WeeklyEnd = weeklyEnd.String
+	f.Timezone = timezone.String
+	if startsAt.Valid {
+		f.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		f.EndsAt = &endsAt.Time
+	}
+	f.Enabled = enabledInt == 1
+
+	return &f, nil
+}
+
+// freezeWindowArgs returns the writable columns of a freeze window in the
+// order used by CreateFreezeWindow and UpdateFreezeWindow.
+func freezeWindowArgs(f *FreezeWindow) []interface{} {
+	enabledInt := 0
+	if f.Enabled {
+		enabledInt = 1
+	}
+	return []interface{}{
+		f.Name, nullString(f.Reason), f.StartsAt, f.EndsAt,
+		nullString(f.WeeklyStart), nullString(f.WeeklyEnd), nullString(f.Timezone), enabledInt,
+	}
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// GetFreezeWindows returns all freeze windows.
+func (s *MySQLStore) GetFreezeWindows() ([]FreezeWindow, error) {
+	rows, err := s.q.Query(`SELECT ` + freezeWindowColumns + ` FROM freeze_windows ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []FreezeWindow
+	for rows.Next() {
+		f, err := scanFreezeWindow(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, *f)
+	}
+
+	return windows, rows.Err()
+}
+
+// GetFreezeWindowByID returns a freeze window by ID.
+func (s *MySQLStore) GetFreezeWindowByID(id uint) (*FreezeWindow, error) {
+	row := s.q.QueryRow(`SELECT `+freezeWindowColumns+` FROM freeze_windows WHERE id = ? LIMIT 1`, id)
+
+	f, err := scanFreezeWindow(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// CreateFreezeWindow creates a new freeze window.
+func (s *MySQLStore) CreateFreezeWindow(window *FreezeWindow) error {
+	query := `INSERT INTO freeze_windows (name, reason, starts_at, ends_at, weekly_start, weekly_end,
+	                                      timezone, enabled, created_by)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.q.Exec(query, append(freezeWindowArgs(window), window.CreatedBy)...)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	window.ID = uint(id)
+	window.CreatedAt = time.Now()
+	window.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// UpdateFreezeWindow updates an existing freeze window.
+func (s *MySQLStore) UpdateFreezeWindow(id uint, window *FreezeWindow) error {
+	query := `UPDATE freeze_windows
+	          SET name = ?, reason = ?, starts_at = ?, ends_at = ?, weekly_start = ?, weekly_end = ?,
+	              timezone = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP
+	          WHERE id = ?`
+
+	result, err := s.q.Exec(query, append(freezeWindowArgs(window), id)...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("freeze window not found")
+	}
+
+	window.ID = id
+	window.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// DeleteFreezeWindow removes a freeze window. Unlike backends and routes,
+// freeze windows are not referenced elsewhere and are deleted outright.
+func (s *MySQLStore) DeleteFreezeWindow(id uint) error {
+	result, err := s.q.Exec(`DELETE FROM freeze_windows WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("freeze window not found")
+	}
+
+	return nil
+}
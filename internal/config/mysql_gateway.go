@@ -0,0 +1,158 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// gatewayColumns is the column list shared by gateway instance queries; it
+// must match the order of scanGateway.
+const gatewayColumns = `id, version, addr, cluster, labels, config_sha256, config_revision, failed_revision, failure,
+	registered_at, last_seen_at`
+
+// scanGateway scans a row selected with gatewayColumns.
+func scanGateway(row rowScanner) (*GatewayInstance, error) {
+	var g GatewayInstance
+	var labels []byte
+	var registeredAt sql.NullTime
+	if err := row.Scan(&g.ID, &g.Version, &g.Addr, &g.Cluster, &labels, &g.ConfigSHA256, &g.ConfigRevision, &g.FailedRevision, &g.Failure, &registeredAt, &g.LastSeenAt); err != nil {
+		return nil, err
+	}
+	if len(labels) > 0 {
+		if err := json.Unmarshal(labels, &g.Labels); err != nil {
+			return nil, fmt.Errorf("decode labels for gateway %s: %w", g.ID, err)
+		}
+	}
+	if registeredAt.Valid {
+		g.RegisteredAt = &registeredAt.Time
+	}
+	return &g, nil
+}
+
+// RegisterGateway adds a gateway instance or, if its ID is known, replaces
+// its version, address, cluster, labels and loaded config, forgetting any
+// failure. It sets the registration and last seen times on gateway.
+func (s *MySQLStore) RegisterGateway(gateway *GatewayInstance) error {
+	labels, err := jsonArg(gateway.Labels)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = s.q.Exec(
+		`INSERT INTO gateway_instances (id, version, addr, cluster, labels, config_sha256, config_revision, registered_at, last_seen_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE version = VALUES(version), addr = VALUES(addr), cluster = VALUES(cluster), labels = VALUES(labels),
+		 config_sha256 = VALUES(config_sha256), config_revision = VALUES(config_revision), failed_revision = 0, failure = '',
+		 registered_at = VALUES(registered_at), last_seen_at = VALUES(last_seen_at)`,
+		gateway.ID, gateway.Version, gateway.Addr, gateway.Cluster, labels, gateway.ConfigSHA256, gateway.ConfigRevision, now, now,
+	)
+	if err != nil {
+		return err
+	}
+	gateway.RegisteredAt = &now
+	gateway.LastSeenAt = now
+	return nil
+}
+
+// GatewayHeartbeat marks a gateway instance as seen now, also recording
+// its loaded config unless configSHA256 is empty and its revision unless
+// it is 0. It reports whether the instance is known.
+func (s *MySQLStore) GatewayHeartbeat(id, configSHA256 string, revision uint64) (bool, error) {
+	// failure must be assigned before failed_revision, which it reads
+	result, err := s.q.Exec(
+		`UPDATE gateway_instances SET last_seen_at = ?, config_sha256 = IF(? = '', config_sha256, ?),
+		 config_revision = IF(? = 0, config_revision, ?),
+		 failure = IF(? >= failed_revision, '', failure), failed_revision = IF(? >= failed_revision, 0, failed_revision)
+		 WHERE id = ?`,
+		time.Now(), configSHA256, configSHA256, revision, revision, revision, revision, id,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 1 {
+		return true, nil
+	}
+
+	// Nothing changed if the previous heartbeat was in the same
+	// millisecond, so check whether the instance exists
+	var found int
+	err = s.q.QueryRow(`SELECT 1 FROM gateway_instances WHERE id = ?`, id).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ReportGatewayConfig records the config a gateway instance of cluster has
+// loaded and its revision, adding the instance if it is new. A failure
+// to apply a revision up to the loaded one is forgotten.
+func (s *MySQLStore) ReportGatewayConfig(id, cluster, configSHA256 string, revision uint64) error {
+	_, err := s.q.Exec(
+		`INSERT INTO gateway_instances (id, cluster, config_sha256, config_revision, last_seen_at) VALUES (?, ?, ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE cluster = VALUES(cluster), config_sha256 = VALUES(config_sha256),
+		 config_revision = VALUES(config_revision),
+		 failure = IF(VALUES(config_revision) >= failed_revision, '', failure),
+		 failed_revision = IF(VALUES(config_revision) >= failed_revision, 0, failed_revision),
+		 last_seen_at = VALUES(last_seen_at)`,
+		id, cluster, configSHA256, revision, time.Now(),
+	)
+	return err
+}
+
+// ReportGatewayFailure records that a gateway instance of cluster failed
+// to apply a config revision, adding the instance if it is new.
+func (s *MySQLStore) ReportGatewayFailure(id, cluster string, revision uint64, failure string) error {
+	_, err := s.q.Exec(
+		`INSERT INTO gateway_instances (id, cluster, config_sha256, failed_revision, failure, last_seen_at) VALUES (?, ?, '', ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE cluster = VALUES(cluster), failed_revision = VALUES(failed_revision),
+		 failure = VALUES(failure), last_seen_at = VALUES(last_seen_at)`,
+		id, cluster, revision, failure, time.Now(),
+	)
+	return err
+}
+
+// GetGateway returns a gateway instance, or nil if it is not known.
+func (s *MySQLStore) GetGateway(id string) (*GatewayInstance, error) {
+	g, err := scanGateway(s.q.QueryRow(`SELECT `+gatewayColumns+` FROM gateway_instances WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return g, err
+}
+
+// GetGateways returns every known gateway instance, ordered by ID.
+func (s *MySQLStore) GetGateways() ([]GatewayInstance, error) {
+	rows, err := s.q.Query(`SELECT ` + gatewayColumns + ` FROM gateway_instances ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var gateways []GatewayInstance
+	for rows.Next() {
+		g, err := scanGateway(rows)
+		if err != nil {
+			return nil, err
+		}
+		gateways = append(gateways, *g)
+	}
+	return gateways, rows.Err()
+}
+
+// PruneGateways deletes gateway instances not seen since before the given
+// time and returns how many were removed.
+func (s *MySQLStore) PruneGateways(before time.Time) (int64, error) {
+	result, err := s.q.Exec(`DELETE FROM gateway_instances WHERE last_seen_at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
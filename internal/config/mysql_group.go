@@ -0,0 +1,237 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+const groupColumns = `id, display_name, external_id, created_at, updated_at`
+
+func scanGroup(row rowScanner) (*Group, error) {
+	var g Group
+	if err := row.Scan(&g.ID, &g.DisplayName, &g.ExternalID, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		return nil, err
+	}
+	g.Members = []string{}
+	return &g, nil
+}
+
+// GetGroups returns every group with its members, ordered by display name.
+func (s *MySQLStore) GetGroups() ([]Group, error) {
+	rows, err := s.q.Query(`SELECT ` + groupColumns + ` FROM user_groups ORDER BY display_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []Group
+	byID := map[string]int{}
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		byID[g.ID] = len(groups)
+		groups = append(groups, *g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	members, err := s.q.Query(`SELECT group_id, user_name FROM user_group_members ORDER BY group_id, user_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer members.Close()
+	for members.Next() {
+		var id, user string
+		if err := members.Scan(&id, &user); err != nil {
+			return nil, err
+		}
+		if i, ok := byID[id]; ok {
+			groups[i].Members = append(groups[i].Members, user)
+		}
+	}
+	return groups, members.Err()
+}
+
+// GetGroup returns a group by ID, or nil if it does not exist.
+func (s *MySQLStore) GetGroup(id string) (*Group, error) {
+	g, err := scanGroup(s.q.QueryRow(`SELECT `+groupColumns+` FROM user_groups WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.q.Query(`SELECT user_name FROM user_group_members WHERE group_id = ? ORDER BY user_name`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user string
+		if err := rows.Scan(&user); err != nil {
+			return nil, err
+		}
+		g.Members = append(g.Members, user)
+	}
+	return g, rows.Err()
+}
+
+// CreateGroup creates a group with its members.
+func (s *MySQLStore) CreateGroup(group *Group) error {
+	err := s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		if _, err := q.Exec(
+			`INSERT INTO user_groups (id, display_name, external_id) VALUES (?, ?, ?)`,
+			group.ID, group.DisplayName, group.ExternalID,
+		); err != nil {
+			return err
+		}
+		return insertGroupMembers(q, group.ID, group.Members)
+	})
+	if err != nil {
+		return err
+	}
+	group.CreatedAt = time.Now()
+	group.UpdatedAt = group.CreatedAt
+	return nil
+}
+
+// UpdateGroup updates the display name and external ID of a group and
+// replaces its members.
+func (s *MySQLStore) UpdateGroup(id string, group *Group) error {
+	err := s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		if err := touchGroup(q, id, `display_name = ?, external_id = ?, `, group.DisplayName, group.ExternalID); err != nil {
+			return err
+		}
+		if _, err := q.Exec(`DELETE FROM user_group_members WHERE group_id = ?`, id); err != nil {
+			return err
+		}
+		return insertGroupMembers(q, id, group.Members)
+	})
+	if err != nil {
+		return err
+	}
+	group.ID = id
+	group.UpdatedAt = time.Now()
+	return nil
+}
+
+// AddGroupMembers adds users to a group. Adding an existing member again
+// is not an error.
+func (s *MySQLStore) AddGroupMembers(id string, users []string) error {
+	return s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		if err := touchGroup(q, id, ``); err != nil {
+			return err
+		}
+		return insertGroupMembers(q, id, users)
+	})
+}
+
+// RemoveGroupMembers removes users from a group. Removing a user that is
+// not a member is not an error.
+func (s *MySQLStore) RemoveGroupMembers(id string, users []string) error {
+	return s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		if err := touchGroup(q, id, ``); err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+		args := []any{id}
+		for _, u := range users {
+			args = append(args, u)
+		}
+		_, err := q.Exec(
+			`DELETE FROM user_group_members WHERE group_id = ? AND user_name IN (?`+strings.Repeat(`, ?`, len(users)-1)+`)`,
+			args...,
+		)
+		return err
+	})
+}
+
+// DeleteGroup removes a group along with its memberships.
+func (s *MySQLStore) DeleteGroup(id string) error {
+	return s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		result, err := q.Exec(`DELETE FROM user_groups WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return errors.New("group not found")
+		}
+		_, err = q.Exec(`DELETE FROM user_group_members WHERE group_id = ?`, id)
+		return err
+	})
+}
+
+// GetUserGroups returns the display names of the groups a user belongs
+// to, ordered.
+func (s *MySQLStore) GetUserGroups(user string) ([]string, error) {
+	rows, err := s.q.Query(
+		`SELECT g.display_name FROM user_group_members m JOIN user_groups g ON g.id = m.group_id
+		 WHERE m.user_name = ? ORDER BY g.display_name`,
+		user,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []string
+	for rows.Next() {
+		var g string
+		if err := rows.Scan(&g); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// touchGroup sets the columns of set, if any, and the update time of a
+// group, failing if it does not exist.
+func touchGroup(q querier, id, set string, args ...any) error {
+	result, err := q.Exec(`UPDATE user_groups SET `+set+`updated_at = CURRENT_TIMESTAMP WHERE id = ?`, append(args, id)...)
+	if err != nil {
+		return err
+	}
+	// An update within the same second that changes nothing affects no
+	// rows, so tell a missing group apart
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		var exists int
+		if err := q.QueryRow(`SELECT 1 FROM user_groups WHERE id = ?`, id).Scan(&exists); errors.Is(err, sql.ErrNoRows) {
+			return errors.New("group not found")
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertGroupMembers(q querier, id string, users []string) error {
+	for _, u := range users {
+		if _, err := q.Exec(`INSERT IGNORE INTO user_group_members (group_id, user_name) VALUES (?, ?)`, id, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
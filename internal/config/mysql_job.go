@@ -0,0 +1,248 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const jobColumns = `id, kind, status, progress_done, progress_total, result, error, attempts,
+	created_by, created_at, started_at, finished_at, updated_at`
+
+// scanJob scans a row of jobColumns followed by the extra columns, if
+// any.
+func scanJob(row rowScanner, extra ...interface{}) (*Job, error) {
+	var job Job
+	var result []byte
+	var errText sql.NullString
+	var startedAt, finishedAt sql.NullTime
+	dest := []interface{}{&job.ID, &job.Kind, &job.Status, &job.Progress.Done, &job.Progress.Total, &result, &errText, &job.Attempts,
+		&job.CreatedBy, &job.CreatedAt, &startedAt, &finishedAt, &job.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if len(result) > 0 {
+		job.Result = result
+	}
+	job.Error = errText.String
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+// CreateJob stores a pending job. Its params are encrypted at rest if a
+// cipher is configured.
+func (s *MySQLStore) CreateJob(job *Job) error {
+	var params interface{}
+	encrypted := false
+	if len(job.Params) > 0 {
+		params = string(job.Params)
+		if s.cipher != nil {
+			var err error
+			if params, err = s.cipher.Encrypt(string(job.Params), nil); err != nil {
+				return err
+			}
+			encrypted = true
+		}
+	}
+
+	now := time.Now()
+	result, err := s.q.Exec(
+		`INSERT INTO jobs (kind, status, params, params_encrypted, created_by, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		job.Kind, JobPending, params, encrypted, job.CreatedBy, now, now,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	job.ID = uint(id)
+	job.Status = JobPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	return nil
+}
+
+// GetJobs returns jobs without their params, newest first, optionally
+// filtered by kind and status. A limit of zero returns all of them.
+func (s *MySQLStore) GetJobs(kind, status string, limit int) ([]Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE 1 = 1`
+	var args []interface{}
+	if kind != "" {
+		query += ` AND kind = ?`
+		args = append(args, kind)
+	}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY id DESC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// GetJob returns a job with its params, or nil if it does not exist.
+func (s *MySQLStore) GetJob(id uint) (*Job, error) {
+	row := s.q.QueryRow(`SELECT `+jobColumns+`, params, params_encrypted FROM jobs WHERE id = ? LIMIT 1`, id)
+
+	var params sql.NullString
+	var encrypted bool
+	job, err := scanJob(row, &params, &encrypted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Valid {
+		data := params.String
+		if encrypted {
+			var err error
+			if data, err = s.decryptField(params, nil); err != nil {
+				return nil, err
+			}
+		}
+		job.Params = json.RawMessage(data)
+	}
+	return job, nil
+}
+
+// ClaimJob moves the oldest pending job to running and returns it, or nil
+// if there is none. Runners of several admin instances may claim jobs
+// concurrently; each job is claimed once.
+func (s *MySQLStore) ClaimJob() (*Job, error) {
+	for {
+		var id uint
+		err := s.q.QueryRow(`SELECT id FROM jobs WHERE status = ? ORDER BY id LIMIT 1`, JobPending).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		result, err := s.q.Exec(
+			`UPDATE jobs SET status = ?, attempts = attempts + 1, started_at = ?, updated_at = ?
+			 WHERE id = ? AND status = ?`,
+			JobRunning, now, now, id, JobPending,
+		)
+		if err != nil {
+			return nil, err
+		}
+		claimed, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if claimed == 1 {
+			return s.GetJob(id)
+		}
+		// Another runner claimed it first
+	}
+}
+
+// UpdateJobProgress records the progress of a running job.
+func (s *MySQLStore) UpdateJobProgress(id uint, progress JobProgress) error {
+	_, err := s.q.Exec(
+		`UPDATE jobs SET progress_done = ?, progress_total = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		progress.Done, progress.Total, time.Now(), id, JobRunning,
+	)
+	return err
+}
+
+// FinishJob records the outcome of a job. The params of a succeeded job
+// are cleared, as it cannot be retried.
+func (s *MySQLStore) FinishJob(job *Job) error {
+	now := time.Now()
+	query := `UPDATE jobs SET status = ?, progress_done = ?, progress_total = ?, result = ?, error = ?, finished_at = ?, updated_at = ?`
+	if job.Status == JobSucceeded {
+		query += `, params = NULL, params_encrypted = 0`
+	}
+	query += ` WHERE id = ?`
+
+	var result interface{}
+	if len(job.Result) > 0 {
+		result = string(job.Result)
+	}
+	if _, err := s.q.Exec(query, job.Status, job.Progress.Done, job.Progress.Total, result, nullString(job.Error), now, now, job.ID); err != nil {
+		return err
+	}
+
+	job.FinishedAt = &now
+	job.UpdatedAt = now
+	if job.Status == JobSucceeded {
+		job.Params = nil
+	}
+	return nil
+}
+
+// RequeueJob makes a job in status from pending again, clearing its
+// outcome, and reports whether it was in that status.
+func (s *MySQLStore) RequeueJob(id uint, from string) (bool, error) {
+	result, err := s.q.Exec(
+		`UPDATE jobs SET status = ?, progress_done = 0, progress_total = 0, result = NULL, error = NULL,
+		 started_at = NULL, finished_at = NULL, updated_at = ?
+		 WHERE id = ? AND status = ?`,
+		JobPending, time.Now(), id, from,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// RequeueStaleJobs makes running jobs not updated since before pending
+// again and returns how many there were.
+func (s *MySQLStore) RequeueStaleJobs(before time.Time) (int64, error) {
+	result, err := s.q.Exec(
+		`UPDATE jobs SET status = ?, updated_at = ? WHERE status = ? AND updated_at < ?`,
+		JobPending, time.Now(), JobRunning, before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PruneJobs deletes jobs finished before the given time and returns how
+// many were removed.
+func (s *MySQLStore) PruneJobs(before time.Time) (int64, error) {
+	result, err := s.q.Exec(`DELETE FROM jobs WHERE finished_at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
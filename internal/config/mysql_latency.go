@@ -0,0 +1,61 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// RecordLatency stores health check samples.
+func (s *MySQLStore) RecordLatency(samples []LatencySample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 0, len(samples))
+	args := make([]interface{}, 0, 4*len(samples))
+	for _, sample := range samples {
+		placeholders = append(placeholders, "(?, ?, ?, ?)")
+		args = append(args, sample.Backend, sample.CheckedAt, sample.Latency.Microseconds(), sample.Success)
+	}
+
+	query := `INSERT INTO backend_latency_samples (backend_name, checked_at, latency_us, success)
+	          VALUES ` + strings.Join(placeholders, ", ")
+	_, err := s.q.Exec(query, args...)
+	return err
+}
+
+// GetLatency returns the samples recorded for backend since the given
+// time, oldest first.
+func (s *MySQLStore) GetLatency(backend string, since time.Time) ([]LatencySample, error) {
+	rows, err := s.q.Query(
+		`SELECT checked_at, latency_us, success FROM backend_latency_samples
+		 WHERE backend_name = ? AND checked_at >= ? ORDER BY checked_at`,
+		backend, since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []LatencySample
+	for rows.Next() {
+		sample := LatencySample{Backend: backend}
+		var latencyUS int64
+		if err := rows.Scan(&sample.CheckedAt, &latencyUS, &sample.Success); err != nil {
+			return nil, err
+		}
+		sample.Latency = time.Duration(latencyUS) * time.Microsecond
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+// PruneLatency deletes samples recorded before the given time and returns
+// how many were removed.
+func (s *MySQLStore) PruneLatency(before time.Time) (int64, error) {
+	result, err := s.q.Exec(`DELETE FROM backend_latency_samples WHERE checked_at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
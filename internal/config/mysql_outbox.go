@@ -0,0 +1,154 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// AppendOutbox records a change event in the outbox.
+func (s *MySQLStore) AppendOutbox(entry *OutboxEntry) error {
+	entry.CreatedAt = time.Now()
+	_, err := s.q.Exec(
+		`INSERT INTO config_outbox (revision, payload, created_at) VALUES (?, ?, ?)`,
+		entry.Revision, []byte(entry.Payload), entry.CreatedAt,
+	)
+	return err
+}
+
+// GetOutbox returns up to limit entries after a revision, oldest first.
+func (s *MySQLStore) GetOutbox(after uint64, limit int) ([]OutboxEntry, error) {
+	rows, err := s.q.Query(
+		`SELECT revision, payload, created_at FROM config_outbox WHERE revision > ? ORDER BY revision LIMIT ?`,
+		after, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []OutboxEntry
+	for rows.Next() {
+		var e OutboxEntry
+		var payload []byte
+		if err := rows.Scan(&e.Revision, &payload, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Payload = payload
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+const outboxSinkColumns = `name, delivered, attempts, last_error, next_attempt_at, lease_owner, lease_until, updated_at`
+
+func scanOutboxSink(row rowScanner) (*OutboxSink, error) {
+	var sink OutboxSink
+	var nextAttemptAt, leaseUntil sql.NullTime
+	if err := row.Scan(&sink.Name, &sink.Delivered, &sink.Attempts, &sink.LastError, &nextAttemptAt, &sink.LeaseOwner, &leaseUntil, &sink.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if nextAttemptAt.Valid {
+		sink.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if leaseUntil.Valid {
+		sink.LeaseUntil = &leaseUntil.Time
+	}
+	return &sink, nil
+}
+
+// GetOutboxSinks returns the delivery state of every sink.
+func (s *MySQLStore) GetOutboxSinks() ([]OutboxSink, error) {
+	rows, err := s.q.Query(`SELECT ` + outboxSinkColumns + ` FROM outbox_sinks ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sinks []OutboxSink
+	for rows.Next() {
+		sink, err := scanOutboxSink(rows)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, *sink)
+	}
+	return sinks, rows.Err()
+}
+
+// ClaimOutboxSink leases a sink to owner, creating its state if it is new.
+func (s *MySQLStore) ClaimOutboxSink(name, owner string, ttl time.Duration) (*OutboxSink, error) {
+	now := time.Now()
+	// A new sink starts after the latest entry; revisions commit in
+	// order, so none committed later can be older
+	if _, err := s.q.Exec(
+		`INSERT IGNORE INTO outbox_sinks (name, delivered, updated_at)
+		 SELECT ?, COALESCE(MAX(revision), 0), ? FROM config_outbox`,
+		name, now,
+	); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.q.Exec(
+		`UPDATE outbox_sinks SET lease_owner = ?, lease_until = ?
+		 WHERE name = ? AND (lease_owner = ? OR lease_until IS NULL OR lease_until < ?)`,
+		owner, now.Add(ttl), name, owner, now,
+	); err != nil {
+		return nil, err
+	}
+
+	// The lease may be unchanged within the same millisecond, so tell the
+	// outcome from the owner rather than the rows affected
+	sink, err := scanOutboxSink(s.q.QueryRow(`SELECT `+outboxSinkColumns+` FROM outbox_sinks WHERE name = ?`, name))
+	if err != nil {
+		return nil, err
+	}
+	if sink.LeaseOwner != owner {
+		return nil, nil
+	}
+	return sink, nil
+}
+
+// AckOutboxSink records the delivery of a sink's entries up to revision.
+func (s *MySQLStore) AckOutboxSink(name, owner string, revision uint64) (bool, error) {
+	now := time.Now()
+	result, err := s.q.Exec(
+		`UPDATE outbox_sinks SET delivered = ?, attempts = 0, last_error = '', next_attempt_at = NULL, updated_at = ?
+		 WHERE name = ? AND lease_owner = ? AND lease_until >= ?`,
+		revision, now, name, owner, now,
+	)
+	if err != nil {
+		return false, err
+	}
+	acked, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return acked == 1, nil
+}
+
+// FailOutboxSink records a failed delivery attempt of a sink.
+func (s *MySQLStore) FailOutboxSink(name, owner, message string, retryAt time.Time) error {
+	result, err := s.q.Exec(
+		`UPDATE outbox_sinks SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?, updated_at = ?
+		 WHERE name = ? AND lease_owner = ?`,
+		message, retryAt, time.Now(), name, owner,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.New("outbox sink lease lost")
+	}
+	return nil
+}
+
+// PruneOutbox deletes entries recorded before the given time and returns
+// how many were removed.
+func (s *MySQLStore) PruneOutbox(before time.Time) (int64, error) {
+	result, err := s.q.Exec(`DELETE FROM config_outbox WHERE created_at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
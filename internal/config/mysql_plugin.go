@@ -0,0 +1,104 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const pluginSchemaColumns = `name, description, schema_json, updated_by, created_at, updated_at`
+
+func scanPluginSchema(row rowScanner) (*PluginSchema, error) {
+	var p PluginSchema
+	var description sql.NullString
+	var schema []byte
+
+	if err := row.Scan(&p.Name, &description, &schema, &p.UpdatedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	p.Description = description.String
+	p.Schema = schema
+
+	return &p, nil
+}
+
+// GetPluginSchemas returns all registered plugin schemas, by name.
+func (s *MySQLStore) GetPluginSchemas() ([]PluginSchema, error) {
+	rows, err := s.q.Query(`SELECT ` + pluginSchemaColumns + ` FROM plugin_schemas ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schemas []PluginSchema
+	for rows.Next() {
+		p, err := scanPluginSchema(rows)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, *p)
+	}
+
+	return schemas, rows.Err()
+}
+
+// GetPluginSchema returns the schema of a plugin, or nil if it has none.
+func (s *MySQLStore) GetPluginSchema(name string) (*PluginSchema, error) {
+	row := s.q.QueryRow(`SELECT `+pluginSchemaColumns+` FROM plugin_schemas WHERE name = ? LIMIT 1`, name)
+
+	p, err := scanPluginSchema(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetPluginSchema registers the schema of a plugin, replacing any previous
+// one.
+func (s *MySQLStore) SetPluginSchema(schema *PluginSchema) error {
+	query := `INSERT INTO plugin_schemas (name, description, schema_json, updated_by)
+	          VALUES (?, ?, ?, ?)
+	          ON DUPLICATE KEY UPDATE description = VALUES(description), schema_json = VALUES(schema_json),
+	                                  updated_by = VALUES(updated_by), updated_at = CURRENT_TIMESTAMP`
+
+	_, err := s.q.Exec(query, schema.Name, nullString(schema.Description), []byte(schema.Schema), schema.UpdatedBy)
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.GetPluginSchema(schema.Name)
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		schema.CreatedAt = stored.CreatedAt
+		schema.UpdatedAt = stored.UpdatedAt
+	} else {
+		schema.CreatedAt = time.Now()
+		schema.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// DeletePluginSchema removes the schema of a plugin; configs of the plugin
+// are no longer validated.
+func (s *MySQLStore) DeletePluginSchema(name string) error {
+	result, err := s.q.Exec(`DELETE FROM plugin_schemas WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("plugin schema not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL server errors after which the statement, or the transaction, was
+// rolled back and may be run again.
+const (
+	mysqlLockWaitTimeout = 1205
+	mysqlDeadlock        = 1213
+)
+
+// RetryPolicy bounds the retries of statements and transactions that fail
+// with transient database errors.
+type RetryPolicy struct {
+	// Attempts is the number of tries in all; 1 disables retries.
+	Attempts int
+	// Backoff is the longest wait before the first retry. It doubles with
+	// every retry up to MaxBackoff, each wait being drawn at random below
+	// it so that clients retrying together spread out.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy of new MySQLStores.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Backoff: 50 * time.Millisecond, MaxBackoff: time.Second}
+
+// wait sleeps before retry number n, counting from 1.
+func (p RetryPolicy) wait(n int) {
+	ceiling := min(p.Backoff<<(n-1), p.MaxBackoff)
+	if ceiling > 0 {
+		time.Sleep(rand.N(ceiling))
+	}
+}
+
+// retry runs fn until it succeeds, fails with an error retryable rejects,
+// or has been tried p.Attempts times.
+func (p RetryPolicy) retry(retryable func(error) bool, fn func() error) error {
+	err := fn()
+	for n := 1; n < p.Attempts && err != nil && retryable(err); n++ {
+		p.wait(n)
+		err = fn()
+	}
+	return err
+}
+
+// transientError reports whether err is a database blip that may pass on
+// a new try: a deadlock, a lock wait timeout or a connection failure.
+func transientError(err error) bool {
+	return lockError(err) || connectionError(err)
+}
+
+// notApplied reports whether err is transient and known to leave no
+// effect of the statement, which may then be run again even if it writes.
+// Connections lost midway are not: the statement may have been applied.
+func notApplied(err error) bool {
+	var opErr *net.OpError
+	return lockError(err) || errors.Is(err, driver.ErrBadConn) || errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// lockError reports whether err is a deadlock or a lock wait timeout, after
+// which MySQL has rolled the statement back.
+func lockError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == mysqlDeadlock || mysqlErr.Number == mysqlLockWaitTimeout)
+}
+
+// connectionError reports whether err means the database could not be
+// reached, or stopped answering.
+func connectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.As(err, &netErr)
+}
+
+// guardedDB runs the statements of a MySQLStore outside transactions
+// through its circuit breaker, retrying them on transient errors: reads on
+// any, writes only on those that leave no effect.
+type guardedDB struct {
+	db      *sql.DB
+	policy  *RetryPolicy
+	breaker *circuit
+}
+
+func (g *guardedDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := g.breaker.do(func() error {
+		return g.policy.retry(notApplied, func() error {
+			var err error
+			result, err = g.db.Exec(query, args...)
+			return err
+		})
+	})
+	return result, err
+}
+
+func (g *guardedDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := g.breaker.do(func() error {
+		return g.policy.retry(transientError, func() error {
+			var err error
+			rows, err = g.db.Query(query, args...)
+			return err
+		})
+	})
+	return rows, err
+}
+
+func (g *guardedDB) QueryRow(query string, args ...interface{}) rowScanner {
+	var row *sql.Row
+	err := g.breaker.do(func() error {
+		return g.policy.retry(transientError, func() error {
+			row = g.db.QueryRow(query, args...)
+			return row.Err()
+		})
+	})
+	if row == nil {
+		return errRow{err}
+	}
+	return row
+}
+
+// txQuerier runs the statements of a MySQLStore in a transaction.
+type txQuerier struct {
+	*sql.Tx
+}
+
+func (t txQuerier) QueryRow(query string, args ...interface{}) rowScanner {
+	return t.Tx.QueryRow(query, args...)
+}
+
+// errRow is a row that failed before its query was run.
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
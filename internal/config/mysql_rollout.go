@@ -0,0 +1,167 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// rolloutColumns is the column list shared by rollout queries; it must
+// match the order of scanRollout.
+const rolloutColumns = `id, status, percent, labels, description, revision, created_by, created_at, finished_by, finished_at`
+
+// scanRollout scans a row of rolloutColumns followed by the extra
+// columns, if any.
+func scanRollout(row rowScanner, extra ...interface{}) (*ConfigRollout, error) {
+	var r ConfigRollout
+	var labels []byte
+	var description sql.NullString
+	var finishedAt sql.NullTime
+	dest := []interface{}{&r.ID, &r.Status, &r.Percent, &labels, &description, &r.Revision, &r.CreatedBy, &r.CreatedAt, &r.FinishedBy, &finishedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if len(labels) > 0 {
+		if err := json.Unmarshal(labels, &r.Labels); err != nil {
+			return nil, fmt.Errorf("decode labels for rollout %d: %w", r.ID, err)
+		}
+	}
+	r.Description = description.String
+	if finishedAt.Valid {
+		r.FinishedAt = &finishedAt.Time
+	}
+	return &r, nil
+}
+
+// CreateRollout starts a rollout unless another one is in progress.
+func (s *MySQLStore) CreateRollout(rollout *ConfigRollout) (bool, error) {
+	labels, err := jsonArg(rollout.Labels)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	result, err := s.q.Exec(
+		`INSERT INTO config_rollouts (status, percent, labels, description, revision, baseline, created_by, created_at)
+		 SELECT ?, ?, ?, ?, ?, ?, ?, ? FROM DUAL
+		 WHERE NOT EXISTS (SELECT 1 FROM config_rollouts WHERE status = ?)`,
+		RolloutInProgress, rollout.Percent, labels, nullString(rollout.Description), rollout.Revision, string(rollout.Baseline),
+		rollout.CreatedBy, now, RolloutInProgress,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	rollout.ID = uint(id)
+	rollout.Status = RolloutInProgress
+	rollout.CreatedAt = now
+	return true, nil
+}
+
+// GetRollouts returns every rollout without its baseline, newest first.
+func (s *MySQLStore) GetRollouts() ([]ConfigRollout, error) {
+	rows, err := s.q.Query(`SELECT ` + rolloutColumns + ` FROM config_rollouts ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rollouts []ConfigRollout
+	for rows.Next() {
+		r, err := scanRollout(rows)
+		if err != nil {
+			return nil, err
+		}
+		rollouts = append(rollouts, *r)
+	}
+	return rollouts, rows.Err()
+}
+
+// GetRollout returns a rollout with its baseline, or nil if it does not
+// exist.
+func (s *MySQLStore) GetRollout(id uint) (*ConfigRollout, error) {
+	var baseline []byte
+	r, err := scanRollout(s.q.QueryRow(`SELECT `+rolloutColumns+`, baseline FROM config_rollouts WHERE id = ?`, id), &baseline)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.Baseline = baseline
+	return r, nil
+}
+
+// GetActiveRollout returns the rollout in progress without its baseline,
+// or nil if there is none.
+func (s *MySQLStore) GetActiveRollout() (*ConfigRollout, error) {
+	r, err := scanRollout(s.q.QueryRow(`SELECT `+rolloutColumns+` FROM config_rollouts WHERE status = ? LIMIT 1`, RolloutInProgress))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// FinishRollout moves a rollout in progress to status.
+func (s *MySQLStore) FinishRollout(id uint, status, operator string) (bool, error) {
+	result, err := s.q.Exec(
+		`UPDATE config_rollouts SET status = ?, finished_by = ?, finished_at = ? WHERE id = ? AND status = ?`,
+		status, operator, time.Now(), id, RolloutInProgress,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// AddRolloutHealth adds to the traffic a gateway instance has reported
+// during a rollout.
+func (s *MySQLStore) AddRolloutHealth(id uint, health *RolloutHealth) error {
+	canary := 0
+	if health.Canary {
+		canary = 1
+	}
+	_, err := s.q.Exec(
+		`INSERT INTO config_rollout_health (rollout_id, gateway_id, canary, requests, errors, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE canary = VALUES(canary), requests = requests + VALUES(requests),
+		 errors = errors + VALUES(errors), updated_at = VALUES(updated_at)`,
+		id, health.Gateway, canary, health.Requests, health.Errors, time.Now(),
+	)
+	return err
+}
+
+// GetRolloutHealth returns the traffic reported during a rollout, ordered
+// by gateway.
+func (s *MySQLStore) GetRolloutHealth(id uint) ([]RolloutHealth, error) {
+	rows, err := s.q.Query(
+		`SELECT gateway_id, canary, requests, errors, updated_at FROM config_rollout_health
+		 WHERE rollout_id = ? ORDER BY gateway_id`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var health []RolloutHealth
+	for rows.Next() {
+		var h RolloutHealth
+		var canary int
+		if err := rows.Scan(&h.Gateway, &canary, &h.Requests, &h.Errors, &h.UpdatedAt); err != nil {
+			return nil, err
+		}
+		h.Canary = canary == 1
+		health = append(health, h)
+	}
+	return health, rows.Err()
+}
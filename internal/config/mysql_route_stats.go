@@ -0,0 +1,118 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// routeStatsLatencyColumns are the histogram columns of route_stats, in the
+// order of RouteStats.LatencyBuckets.
+var routeStatsLatencyColumns = func() []string {
+	cols := make([]string, 0, len(LatencyBucketBounds)+1)
+	for _, b := range LatencyBucketBounds {
+		cols = append(cols, "latency_le_"+strconv.Itoa(b))
+	}
+	return append(cols, "latency_inf")
+}()
+
+// RecordRouteStats adds traffic counts to the per-minute aggregates, so
+// reports from several gateways for the same minute are summed.
+func (s *MySQLStore) RecordRouteStats(stats []RouteStats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	cols := append([]string{"route_id", "bucket_start", "requests", "errors"}, routeStatsLatencyColumns...)
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ") + ")"
+
+	rows := make([]string, 0, len(stats))
+	args := make([]interface{}, 0, len(cols)*len(stats))
+	for _, st := range stats {
+		rows = append(rows, row)
+		args = append(args, st.RouteID, st.BucketStart.UTC().Truncate(time.Minute), st.Requests, st.Errors)
+		for i := range routeStatsLatencyColumns {
+			var n int64
+			if i < len(st.LatencyBuckets) {
+				n = st.LatencyBuckets[i]
+			}
+			args = append(args, n)
+		}
+	}
+
+	updates := make([]string, 0, len(cols)-2)
+	for _, c := range cols[2:] {
+		updates = append(updates, c+" = "+c+" + VALUES("+c+")")
+	}
+
+	query := "INSERT INTO route_stats (" + strings.Join(cols, ", ") + ") VALUES " + strings.Join(rows, ", ") +
+		" ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+	_, err := s.q.Exec(query, args...)
+	return err
+}
+
+// GetRouteStats returns the per-minute aggregates of a route since the
+// given time, oldest first.
+func (s *MySQLStore) GetRouteStats(routeID uint, since time.Time) ([]RouteStats, error) {
+	query := "SELECT bucket_start, requests, errors, " + strings.Join(routeStatsLatencyColumns, ", ") +
+		" FROM route_stats WHERE route_id = ? AND bucket_start >= ? ORDER BY bucket_start"
+	rows, err := s.q.Query(query, routeID, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []RouteStats
+	for rows.Next() {
+		st := RouteStats{RouteID: routeID, LatencyBuckets: make([]int64, len(routeStatsLatencyColumns))}
+		dest := []interface{}{&st.BucketStart, &st.Requests, &st.Errors}
+		for i := range st.LatencyBuckets {
+			dest = append(dest, &st.LatencyBuckets[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+// SumRouteStats returns the totals of every route with aggregates since the
+// given time. BucketStart is left zero.
+func (s *MySQLStore) SumRouteStats(since time.Time) ([]RouteStats, error) {
+	sums := make([]string, 0, len(routeStatsLatencyColumns)+2)
+	for _, c := range append([]string{"requests", "errors"}, routeStatsLatencyColumns...) {
+		sums = append(sums, "SUM("+c+")")
+	}
+	query := "SELECT route_id, " + strings.Join(sums, ", ") +
+		" FROM route_stats WHERE bucket_start >= ? GROUP BY route_id"
+	rows, err := s.q.Query(query, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []RouteStats
+	for rows.Next() {
+		st := RouteStats{LatencyBuckets: make([]int64, len(routeStatsLatencyColumns))}
+		dest := []interface{}{&st.RouteID, &st.Requests, &st.Errors}
+		for i := range st.LatencyBuckets {
+			dest = append(dest, &st.LatencyBuckets[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+// PruneRouteStats deletes aggregates for minutes before the given time and
+// returns how many rows were removed.
+func (s *MySQLStore) PruneRouteStats(before time.Time) (int64, error) {
+	result, err := s.q.Exec(`DELETE FROM route_stats WHERE bucket_start < ?`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// routeSchemaColumns is the column list shared by all route schema
+// queries; it must match the order of scanRouteSchema.
+const routeSchemaColumns = `s.id, s.route_id, s.version, s.request, s.response, s.created_by, s.created_at`
+
+// CreateRouteSchema stores the next version of a route's schemas, setting
+// its ID and Version.
+func (s *MySQLStore) CreateRouteSchema(schema *RouteSchema) error {
+	request, err := marshalSchemaRef(schema.Request)
+	if err != nil {
+		return err
+	}
+	response, err := marshalSchemaRef(schema.Response)
+	if err != nil {
+		return err
+	}
+
+	return s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		var version int
+		row := q.QueryRow(
+			`SELECT COALESCE(MAX(version), 0) FROM route_schemas WHERE route_id = ? FOR UPDATE`,
+			schema.RouteID,
+		)
+		if err := row.Scan(&version); err != nil {
+			return err
+		}
+		version++
+
+		result, err := q.Exec(
+			`INSERT INTO route_schemas (route_id, version, request, response, created_by) VALUES (?, ?, ?, ?, ?)`,
+			schema.RouteID, version, request, response, schema.CreatedBy,
+		)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		schema.ID = uint(id)
+		schema.Version = version
+		schema.CreatedAt = time.Now()
+		return nil
+	})
+}
+
+// GetRouteSchemas returns the schema versions of a route, newest first.
+func (s *MySQLStore) GetRouteSchemas(routeID uint) ([]RouteSchema, error) {
+	return s.queryRouteSchemas(
+		`SELECT `+routeSchemaColumns+` FROM route_schemas s WHERE s.route_id = ? ORDER BY s.version DESC`, routeID,
+	)
+}
+
+// GetRouteSchema returns a version of a route's schemas, or the latest one
+// if version is 0. It returns nil if there is no such version.
+func (s *MySQLStore) GetRouteSchema(routeID uint, version int) (*RouteSchema, error) {
+	query := `SELECT ` + routeSchemaColumns + ` FROM route_schemas s WHERE s.route_id = ?`
+	args := []interface{}{routeID}
+	if version > 0 {
+		query += ` AND s.version = ?`
+		args = append(args, version)
+	}
+	query += ` ORDER BY s.version DESC LIMIT 1`
+
+	schema, err := scanRouteSchema(s.q.QueryRow(query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return schema, nil
+}
+
+// GetLatestRouteSchemas returns the latest schema version of every route
+// that has one.
+func (s *MySQLStore) GetLatestRouteSchemas() ([]RouteSchema, error) {
+	return s.queryRouteSchemas(
+		`SELECT ` + routeSchemaColumns + ` FROM route_schemas s
+		 JOIN (SELECT route_id, MAX(version) AS version FROM route_schemas GROUP BY route_id) latest
+		   ON s.route_id = latest.route_id AND s.version = latest.version
+		 ORDER BY s.route_id`,
+	)
+}
+
+func (s *MySQLStore) queryRouteSchemas(query string, args ...interface{}) ([]RouteSchema, error) {
+	rows, err := s.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schemas []RouteSchema
+	for rows.Next() {
+		schema, err := scanRouteSchema(rows)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, *schema)
+	}
+	return schemas, rows.Err()
+}
+
+// scanRouteSchema scans a row selected with routeSchemaColumns.
+func scanRouteSchema(row rowScanner) (*RouteSchema, error) {
+	var schema RouteSchema
+	var request, response []byte
+	if err := row.Scan(&schema.ID, &schema.RouteID, &schema.Version, &request, &response, &schema.CreatedBy, &schema.CreatedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if schema.Request, err = unmarshalSchemaRef(request); err != nil {
+		return nil, err
+	}
+	if schema.Response, err = unmarshalSchemaRef(response); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func marshalSchemaRef(ref *SchemaRef) (interface{}, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(ref)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func unmarshalSchemaRef(data []byte) (*SchemaRef, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var ref SchemaRef
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return nil, err
+	}
+	return &ref, nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CreateSnapshot stores a configuration snapshot.
+func (s *MySQLStore) CreateSnapshot(snapshot *Snapshot) error {
+	query := `INSERT INTO config_snapshots (name, description, backend_count, route_count, document, created_by)
+	          VALUES (?, ?, ?, ?, ?, ?)`
+
+	result, err := s.q.Exec(query,
+		snapshot.Name, nullString(snapshot.Description), snapshot.Backends, snapshot.Routes,
+		string(snapshot.Document), snapshot.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	snapshot.ID = uint(id)
+	snapshot.CreatedAt = time.Now()
+
+	return nil
+}
+
+// GetSnapshots returns all snapshots without their documents, newest
+// first.
+func (s *MySQLStore) GetSnapshots() ([]Snapshot, error) {
+	rows, err := s.q.Query(
+		`SELECT id, name, description, backend_count, route_count, created_by, created_at
+		 FROM config_snapshots ORDER BY created_at DESC, id DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []Snapshot
+	for rows.Next() {
+		var snap Snapshot
+		var description sql.NullString
+		if err := rows.Scan(&snap.ID, &snap.Name, &description, &snap.Backends, &snap.Routes, &snap.CreatedBy, &snap.CreatedAt); err != nil {
+			return nil, err
+		}
+		snap.Description = description.String
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, rows.Err()
+}
+
+// GetSnapshotByID returns a snapshot with its document, or nil if it does
+// not exist.
+func (s *MySQLStore) GetSnapshotByID(id uint) (*Snapshot, error) {
+	row := s.q.QueryRow(
+		`SELECT id, name, description, backend_count, route_count, document, created_by, created_at
+		 FROM config_snapshots WHERE id = ? LIMIT 1`, id,
+	)
+
+	var snap Snapshot
+	var description sql.NullString
+	var document []byte
+	if err := row.Scan(&snap.ID, &snap.Name, &description, &snap.Backends, &snap.Routes, &document, &snap.CreatedBy, &snap.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	snap.Description = description.String
+	snap.Document = document
+	return &snap, nil
+}
+
+// DeleteSnapshot deletes a snapshot.
+func (s *MySQLStore) DeleteSnapshot(id uint) error {
+	result, err := s.q.Exec(`DELETE FROM config_snapshots WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("snapshot not found")
+	}
+	return nil
+}
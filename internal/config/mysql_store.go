@@ -1,16 +1,36 @@
 package config
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/secret"
 )
 
-// MySQLStore implements Store using MySQL database.
+// querier is the subset of *sql.DB and *sql.Tx used by MySQLStore, so the
+// same methods can run inside or outside a transaction.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) rowScanner
+}
+
+// MySQLStore implements Store using MySQL database. Statements and
+// transactions failing with transient errors are retried as its
+// RetryPolicy allows, and fail fast while its circuit breaker is open.
 type MySQLStore struct {
-	db *sql.DB
+	db      *sql.DB
+	q       querier
+	cipher  secret.Cipher
+	retry   *RetryPolicy
+	breaker *circuit
 }
 
 // NewMySQLStore creates a new MySQLStore instance.
@@ -29,29 +49,230 @@ func NewMySQLStore(dsn string) (*MySQLStore, error) {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
-	return &MySQLStore{db: db}, nil
+	retry := DefaultRetryPolicy
+	breaker := &circuit{failed: connectionError, probe: db.PingContext}
+	return &MySQLStore{
+		db:      db,
+		q:       &guardedDB{db: db, policy: &retry, breaker: breaker},
+		retry:   &retry,
+		breaker: breaker,
+	}, nil
+}
+
+// SetRetryPolicy sets the retries of statements and transactions failing
+// with transient errors. It must be called before the store is used.
+func (s *MySQLStore) SetRetryPolicy(p RetryPolicy) {
+	*s.retry = p
+}
+
+// SetBreakerPolicy sets when the circuit breaker opens and how often it
+// probes the database once open. The breaker is off until a policy with a
+// threshold is set.
+func (s *MySQLStore) SetBreakerPolicy(p BreakerPolicy) {
+	s.breaker.setPolicy(p)
+}
+
+// BreakerState reports the state of the circuit breaker.
+func (s *MySQLStore) BreakerState() BreakerState {
+	return s.breaker.State()
+}
+
+// SetCipher configures the cipher used to encrypt sensitive columns at
+// rest. Without one, backends with inline secrets cannot be stored.
+func (s *MySQLStore) SetCipher(c secret.Cipher) {
+	s.cipher = c
+}
+
+// Ping checks that the database is reachable.
+func (s *MySQLStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
 }
 
 // Close closes the database connection.
 func (s *MySQLStore) Close() error {
+	s.breaker.stop()
 	return s.db.Close()
 }
 
+// InTx runs fn against a Store bound to a single database transaction,
+// committing if fn returns nil and rolling back otherwise. Nested calls
+// reuse the outer transaction. A transaction failing with a transient
+// error, such as a deadlock, is rolled back and run again, so fn must have
+// no effect outside tx; one failing to commit is not, as it may have been
+// committed. While the circuit breaker is open, no transaction is begun.
+func (s *MySQLStore) InTx(fn func(tx Store) error) error {
+	if _, ok := s.q.(txQuerier); ok {
+		return fn(s)
+	}
+
+	return s.breaker.do(func() error {
+		var commitErr error
+		err := s.retry.retry(transientError, func() error {
+			tx, err := s.db.Begin()
+			if err != nil {
+				return err
+			}
+			if err := fn(&MySQLStore{db: s.db, q: txQuerier{tx}, cipher: s.cipher, retry: s.retry, breaker: s.breaker}); err != nil {
+				tx.Rollback()
+				return err
+			}
+			commitErr = tx.Commit()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return commitErr
+	})
+}
+
+// backendColumns is the column list shared by all backend queries; it must
+// match the order of scanBackend.
+const backendColumns = `id, name, addr, description, clusters, enabled, draining,
+	credential_type, credential_username, credential_secret_ref, credential_secret,
+	tls_config, tls_client_key, circuit_breaker, plugins, owner_team,
+	revision, created_at, updated_at`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBackend scans a row selected with backendColumns, transparently
+// decrypting sensitive columns.
+func (s *MySQLStore) scanBackend(row rowScanner) (*Backend, error) {
+	var b Backend
+	var enabledInt, drainingInt int
+	var desc, credType, credUser, credRef, credSecret, tlsClientKey sql.NullString
+	var clusters, tlsConfig, circuitBreaker, plugins []byte
+
+	if err := row.Scan(
+		&b.ID, &b.Name, &b.Addr, &desc, &clusters, &enabledInt, &drainingInt,
+		&credType, &credUser, &credRef, &credSecret,
+		&tlsConfig, &tlsClientKey, &circuitBreaker, &plugins, &b.OwnerTeam,
+		&b.Revision, &b.CreatedAt, &b.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if desc.Valid {
+		b.Description = desc.String
+	}
+	if len(clusters) > 0 {
+		if err := json.Unmarshal(clusters, &b.Clusters); err != nil {
+			return nil, fmt.Errorf("decode clusters for backend %s: %w", b.Name, err)
+		}
+	}
+	b.Enabled = enabledInt == 1
+	b.Status = backendStatus(b.Enabled, drainingInt == 1)
+
+	if credType.Valid && credType.String != "" {
+		secret, err := s.decryptField(credSecret, secretData("backends", "credential_secret", b.Name))
+		if err != nil {
+			return nil, fmt.Errorf("decrypt credential for backend %s: %w", b.Name, err)
+		}
+		b.Credential = &BackendCredential{
+			Type:      credType.String,
+			Username:  credUser.String,
+			SecretRef: credRef.String,
+			Secret:    secret,
+		}
+	}
+
+	if len(tlsConfig) > 0 {
+		// BackendTLS redacts ClientKey when marshalled, so the column holds
+		// only the non-sensitive settings; the key lives in tls_client_key.
+		var t BackendTLS
+		if err := json.Unmarshal(tlsConfig, &t); err != nil {
+			return nil, fmt.Errorf("decode tls config for backend %s: %w", b.Name, err)
+		}
+		key, err := s.decryptField(tlsClientKey, secretData("backends", "tls_client_key", b.Name))
+		if err != nil {
+			return nil, fmt.Errorf("decrypt tls client key for backend %s: %w", b.Name, err)
+		}
+		t.ClientKey = key
+		b.TLS = &t
+	}
+
+	if len(circuitBreaker) > 0 {
+		var c CircuitBreaker
+		if err := json.Unmarshal(circuitBreaker, &c); err != nil {
+			return nil, fmt.Errorf("decode circuit breaker for backend %s: %w", b.Name, err)
+		}
+		b.CircuitBreaker = &c
+	}
+	if len(plugins) > 0 {
+		if err := json.Unmarshal(plugins, &b.Plugins); err != nil {
+			return nil, fmt.Errorf("decode plugins for backend %s: %w", b.Name, err)
+		}
+	}
+
+	return &b, nil
+}
+
+// backendStatus returns the status of a backend. Only enabled backends
+// can be draining.
+func backendStatus(enabled, draining bool) string {
+	switch {
+	case !enabled:
+		return BackendDisabled
+	case draining:
+		return BackendDraining
+	}
+	return BackendEnabled
+}
+
+// drainingArg returns the draining column value for a backend.
+func drainingArg(backend *Backend) int {
+	if backend.Enabled && backend.Status == BackendDraining {
+		return 1
+	}
+	return 0
+}
+
+// sensitiveArgs returns the credential and TLS column values for the
+// backend named name, encrypting secrets.
+func (s *MySQLStore) sensitiveArgs(name string, backend *Backend) ([]interface{}, error) {
+	args := []interface{}{nil, nil, nil, nil, nil, nil}
+
+	if c := backend.Credential; c != nil {
+		secret, err := s.encryptField(c.Secret, secretData("backends", "credential_secret", name))
+		if err != nil {
+			return nil, err
+		}
+		args[0], args[1], args[2], args[3] = c.Type, c.Username, c.SecretRef, secret
+	}
+
+	if t := backend.TLS; t != nil {
+		tlsConfig, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		key, err := s.encryptField(t.ClientKey, secretData("backends", "tls_client_key", name))
+		if err != nil {
+			return nil, err
+		}
+		args[4], args[5] = tlsConfig, key
+	}
+
+	return args, nil
+}
+
 // GetBackends returns all backend configurations, optionally filtered by enabled status.
 func (s *MySQLStore) GetBackends(enabled *bool) ([]Backend, error) {
 	var query string
 	var args []interface{}
 
 	if enabled != nil {
-		query = `SELECT id, name, addr, description, enabled, created_at, updated_at 
+		query = `SELECT ` + backendColumns + ` 
 		         FROM backends WHERE enabled = ? ORDER BY name`
 		args = []interface{}{*enabled}
 	} else {
-		query = `SELECT id, name, addr, description, enabled, created_at, updated_at 
+		query = `SELECT ` + backendColumns + ` 
 		         FROM backends ORDER BY name`
 	}
 
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.q.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -59,20 +280,11 @@ func (s *MySQLStore) GetBackends(enabled *bool) ([]Backend, error) {
 
 	var backends []Backend
 	for rows.Next() {
-		var b Backend
-		var enabledInt int
-		var desc sql.NullString
-
-		if err := rows.Scan(&b.ID, &b.Name, &b.Addr, &desc, &enabledInt, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		b, err := s.scanBackend(rows)
+		if err != nil {
 			return nil, err
 		}
-
-		if desc.Valid {
-			b.Description = desc.String
-		}
-		b.Enabled = enabledInt == 1
-
-		backends = append(backends, b)
+		backends = append(backends, *b)
 	}
 
 	return backends, rows.Err()
@@ -80,16 +292,10 @@ func (s *MySQLStore) GetBackends(enabled *bool) ([]Backend, error) {
 
 // GetBackendByName returns a backend configuration by name.
 func (s *MySQLStore) GetBackendByName(name string) (*Backend, error) {
-	query := `SELECT id, name, addr, description, enabled, created_at, updated_at 
+	query := `SELECT ` + backendColumns + ` 
 	          FROM backends WHERE name = ? LIMIT 1`
 
-	var b Backend
-	var enabledInt int
-	var desc sql.NullString
-
-	err := s.db.QueryRow(query, name).Scan(
-		&b.ID, &b.Name, &b.Addr, &desc, &enabledInt, &b.CreatedAt, &b.UpdatedAt,
-	)
+	b, err := s.scanBackend(s.q.QueryRow(query, name))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -97,25 +303,43 @@ func (s *MySQLStore) GetBackendByName(name string) (*Backend, error) {
 		return nil, err
 	}
 
-	if desc.Valid {
-		b.Description = desc.String
-	}
-	b.Enabled = enabledInt == 1
-
-	return &b, nil
+	return b, nil
 }
 
 // CreateBackend creates a new backend configuration.
 func (s *MySQLStore) CreateBackend(backend *Backend) error {
-	query := `INSERT INTO backends (name, addr, description, enabled) 
-	          VALUES (?, ?, ?, ?)`
+	query := `INSERT INTO backends (name, addr, description, clusters, enabled, draining, 
+	                                credential_type, credential_username, credential_secret_ref, credential_secret, 
+	                                tls_config, tls_client_key, circuit_breaker, plugins, owner_team) 
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	enabledInt := 0
 	if backend.Enabled {
 		enabledInt = 1
 	}
 
-	result, err := s.db.Exec(query, backend.Name, backend.Addr, backend.Description, enabledInt)
+	sensitive, err := s.sensitiveArgs(backend.Name, backend)
+	if err != nil {
+		return err
+	}
+
+	circuitBreaker, err := jsonArg(backend.CircuitBreaker)
+	if err != nil {
+		return err
+	}
+	plugins, err := jsonArg(backend.Plugins)
+	if err != nil {
+		return err
+	}
+	clusters, err := jsonArg(backend.Clusters)
+	if err != nil {
+		return err
+	}
+
+	draining := drainingArg(backend)
+	args := append([]interface{}{backend.Name, backend.Addr, backend.Description, clusters, enabledInt, draining}, sensitive...)
+	args = append(args, circuitBreaker, plugins, backend.OwnerTeam)
+	result, err := s.q.Exec(query, args...)
 	if err != nil {
 		return err
 	}
@@ -126,6 +350,7 @@ func (s *MySQLStore) CreateBackend(backend *Backend) error {
 	}
 
 	backend.ID = uint(id)
+	backend.Status = backendStatus(backend.Enabled, draining == 1)
 	backend.CreatedAt = time.Now()
 	backend.UpdatedAt = time.Now()
 
@@ -135,7 +360,10 @@ func (s *MySQLStore) CreateBackend(backend *Backend) error {
 // UpdateBackend updates an existing backend configuration.
 func (s *MySQLStore) UpdateBackend(name string, backend *Backend) error {
 	query := `UPDATE backends 
-	          SET addr = ?, description = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP 
+	          SET addr = ?, description = ?, clusters = ?, enabled = ?, draining = ?, 
+	              credential_type = ?, credential_username = ?, credential_secret_ref = ?, credential_secret = ?, 
+	              tls_config = ?, tls_client_key = ?, circuit_breaker = ?, plugins = ?, owner_team = ?, 
+	              updated_at = CURRENT_TIMESTAMP 
 	          WHERE name = ?`
 
 	enabledInt := 0
@@ -143,7 +371,28 @@ func (s *MySQLStore) UpdateBackend(name string, backend *Backend) error {
 		enabledInt = 1
 	}
 
-	result, err := s.db.Exec(query, backend.Addr, backend.Description, enabledInt, name)
+	sensitive, err := s.sensitiveArgs(name, backend)
+	if err != nil {
+		return err
+	}
+
+	circuitBreaker, err := jsonArg(backend.CircuitBreaker)
+	if err != nil {
+		return err
+	}
+	plugins, err := jsonArg(backend.Plugins)
+	if err != nil {
+		return err
+	}
+	clusters, err := jsonArg(backend.Clusters)
+	if err != nil {
+		return err
+	}
+
+	draining := drainingArg(backend)
+	args := append([]interface{}{backend.Addr, backend.Description, clusters, enabledInt, draining}, sensitive...)
+	args = append(args, circuitBreaker, plugins, backend.OwnerTeam, name)
+	result, err := s.q.Exec(query, args...)
 	if err != nil {
 		return err
 	}
@@ -158,6 +407,7 @@ func (s *MySQLStore) UpdateBackend(name string, backend *Backend) error {
 	}
 
 	backend.Name = name
+	backend.Status = backendStatus(backend.Enabled, draining == 1)
 	backend.UpdatedAt = time.Now()
 
 	return nil
@@ -165,9 +415,9 @@ func (s *MySQLStore) UpdateBackend(name string, backend *Backend) error {
 
 // DeleteBackend soft deletes a backend by setting enabled=0.
 func (s *MySQLStore) DeleteBackend(name string) error {
-	query := `UPDATE backends SET enabled = 0, updated_at = CURRENT_TIMESTAMP WHERE name = ?`
+	query := `UPDATE backends SET enabled = 0, draining = 0, updated_at = CURRENT_TIMESTAMP WHERE name = ?`
 
-	result, err := s.db.Exec(query, name)
+	result, err := s.q.Exec(query, name)
 	if err != nil {
 		return err
 	}
@@ -184,23 +434,166 @@ func (s *MySQLStore) DeleteBackend(name string) error {
 	return nil
 }
 
+// routeColumns is the column list shared by all route queries; it must
+// match the order of scanRoute.
+const routeColumns = `id, http_method, http_pattern, backend_name, backend_service,
+	backend_method, timeout_ms, max_request_bytes, content_types, description, clusters, api_version, lifecycle, transform, cache, circuit_breaker, rollout,
+	experiment, mirror, fallback, grpc, middlewares, plugins, query_params, deprecation, docs, owner_team, enabled, revision, created_at, updated_at`
+
+// scanRoute scans a row selected with routeColumns.
+func scanRoute(row rowScanner) (*Route, error) {
+	var r Route
+	var enabledInt int
+	var desc sql.NullString
+	var contentTypes, clusters, transform, cache, circuitBreaker, rollout, experiment, mirror, fallback, grpc, middlewares, plugins, queryParams, deprecation, docs []byte
+
+	if err := row.Scan(
+		&r.ID, &r.HTTPMethod, &r.HTTPPattern, &r.BackendName, &r.BackendService,
+		&r.BackendMethod, &r.TimeoutMS, &r.MaxRequestBytes, &contentTypes, &desc, &clusters, &r.APIVersion, &r.Lifecycle, &transform, &cache, &circuitBreaker, &rollout, &experiment,
+		&mirror, &fallback, &grpc, &middlewares, &plugins, &queryParams, &deprecation, &docs, &r.OwnerTeam, &enabledInt, &r.Revision, &r.CreatedAt, &r.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if desc.Valid {
+		r.Description = desc.String
+	}
+	r.Enabled = enabledInt == 1
+
+	if len(contentTypes) > 0 {
+		if err := json.Unmarshal(contentTypes, &r.ContentTypes); err != nil {
+			return nil, fmt.Errorf("decode content types for route %d: %w", r.ID, err)
+		}
+	}
+	if len(clusters) > 0 {
+		if err := json.Unmarshal(clusters, &r.Clusters); err != nil {
+			return nil, fmt.Errorf("decode clusters for route %d: %w", r.ID, err)
+		}
+	}
+	if len(transform) > 0 {
+		var t RouteTransform
+		if err := json.Unmarshal(transform, &t); err != nil {
+			return nil, fmt.Errorf("decode transform for route %d: %w", r.ID, err)
+		}
+		r.Transform = &t
+	}
+	if len(cache) > 0 {
+		var c RouteCache
+		if err := json.Unmarshal(cache, &c); err != nil {
+			return nil, fmt.Errorf("decode cache for route %d: %w", r.ID, err)
+		}
+		r.Cache = &c
+	}
+	if len(circuitBreaker) > 0 {
+		var c CircuitBreaker
+		if err := json.Unmarshal(circuitBreaker, &c); err != nil {
+			return nil, fmt.Errorf("decode circuit breaker for route %d: %w", r.ID, err)
+		}
+		r.CircuitBreaker = &c
+	}
+	if len(rollout) > 0 {
+		var ro RouteRollout
+		if err := json.Unmarshal(rollout, &ro); err != nil {
+			return nil, fmt.Errorf("decode rollout for route %d: %w", r.ID, err)
+		}
+		r.Rollout = &ro
+	}
+	if len(experiment) > 0 {
+		var e RouteExperiment
+		if err := json.Unmarshal(experiment, &e); err != nil {
+			return nil, fmt.Errorf("decode experiment for route %d: %w", r.ID, err)
+		}
+		r.Experiment = &e
+	}
+	if len(mirror) > 0 {
+		var m RouteMirror
+		if err := json.Unmarshal(mirror, &m); err != nil {
+			return nil, fmt.Errorf("decode mirror for route %d: %w", r.ID, err)
+		}
+		r.Mirror = &m
+	}
+	if len(fallback) > 0 {
+		var f RouteFallback
+		if err := json.Unmarshal(fallback, &f); err != nil {
+			return nil, fmt.Errorf("decode fallback for route %d: %w", r.ID, err)
+		}
+		r.Fallback = &f
+	}
+	if len(grpc) > 0 {
+		var g RouteGRPC
+		if err := json.Unmarshal(grpc, &g); err != nil {
+			return nil, fmt.Errorf("decode grpc settings for route %d: %w", r.ID, err)
+		}
+		r.GRPC = &g
+	}
+	if len(middlewares) > 0 {
+		if err := json.Unmarshal(middlewares, &r.Middlewares); err != nil {
+			return nil, fmt.Errorf("decode middlewares for route %d: %w", r.ID, err)
+		}
+	}
+	if len(plugins) > 0 {
+		if err := json.Unmarshal(plugins, &r.Plugins); err != nil {
+			return nil, fmt.Errorf("decode plugins for route %d: %w", r.ID, err)
+		}
+	}
+	if len(queryParams) > 0 {
+		if err := json.Unmarshal(queryParams, &r.QueryParams); err != nil {
+			return nil, fmt.Errorf("decode query params for route %d: %w", r.ID, err)
+		}
+	}
+	if len(deprecation) > 0 {
+		if err := json.Unmarshal(deprecation, &r.Deprecation); err != nil {
+			return nil, fmt.Errorf("decode deprecation for route %d: %w", r.ID, err)
+		}
+	}
+	if len(docs) > 0 {
+		if err := json.Unmarshal(docs, &r.Docs); err != nil {
+			return nil, fmt.Errorf("decode docs for route %d: %w", r.ID, err)
+		}
+	}
+
+	return &r, nil
+}
+
+// settingsArgs returns the JSON column values of a route's optional
+// settings, in the order of routeColumns.
+func settingsArgs(route *Route) ([]interface{}, error) {
+	var args []interface{}
+	for _, v := range []interface{}{route.Transform, route.Cache, route.CircuitBreaker, route.Rollout, route.Experiment, route.Mirror, route.Fallback, route.GRPC,
+		route.Middlewares, route.Plugins, route.QueryParams, route.Deprecation, route.Docs} {
+		arg, err := jsonArg(v)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// jsonArg returns the value of a nullable JSON column holding v, which is
+// a pointer, slice or map; nil is NULL.
+func jsonArg(v interface{}) (interface{}, error) {
+	if rv := reflect.ValueOf(v); (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map) && rv.IsNil() {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
 // GetRoutes returns all route configurations, optionally filtered by enabled status.
 func (s *MySQLStore) GetRoutes(enabled *bool) ([]Route, error) {
 	var query string
 	var args []interface{}
 
 	if enabled != nil {
-		query = `SELECT id, http_method, http_pattern, backend_name, backend_service, 
-		                backend_method, timeout_ms, description, enabled, created_at, updated_at 
+		query = `SELECT ` + routeColumns + `
 		         FROM routes WHERE enabled = ? ORDER BY http_method, http_pattern`
 		args = []interface{}{*enabled}
 	} else {
-		query = `SELECT id, http_method, http_pattern, backend_name, backend_service, 
-		                backend_method, timeout_ms, description, enabled, created_at, updated_at 
+		query = `SELECT ` + routeColumns + `
 		         FROM routes ORDER BY http_method, http_pattern`
 	}
 
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.q.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -208,23 +601,11 @@ func (s *MySQLStore) GetRoutes(enabled *bool) ([]Route, error) {
 
 	var routes []Route
 	for rows.Next() {
-		var r Route
-		var enabledInt int
-		var desc sql.NullString
-
-		if err := rows.Scan(
-			&r.ID, &r.HTTPMethod, &r.HTTPPattern, &r.BackendName, &r.BackendService,
-			&r.BackendMethod, &r.TimeoutMS, &desc, &enabledInt, &r.CreatedAt, &r.UpdatedAt,
-		); err != nil {
+		r, err := scanRoute(rows)
+		if err != nil {
 			return nil, err
 		}
-
-		if desc.Valid {
-			r.Description = desc.String
-		}
-		r.Enabled = enabledInt == 1
-
-		routes = append(routes, r)
+		routes = append(routes, *r)
 	}
 
 	return routes, rows.Err()
@@ -232,18 +613,9 @@ func (s *MySQLStore) GetRoutes(enabled *bool) ([]Route, error) {
 
 // GetRouteByID returns a route configuration by ID.
 func (s *MySQLStore) GetRouteByID(id uint) (*Route, error) {
-	query := `SELECT id, http_method, http_pattern, backend_name, backend_service, 
-	                 backend_method, timeout_ms, description, enabled, created_at, updated_at 
-	          FROM routes WHERE id = ? LIMIT 1`
+	query := `SELECT ` + routeColumns + ` FROM routes WHERE id = ? LIMIT 1`
 
-	var r Route
-	var enabledInt int
-	var desc sql.NullString
-
-	err := s.db.QueryRow(query, id).Scan(
-		&r.ID, &r.HTTPMethod, &r.HTTPPattern, &r.BackendName, &r.BackendService,
-		&r.BackendMethod, &r.TimeoutMS, &desc, &enabledInt, &r.CreatedAt, &r.UpdatedAt,
-	)
+	r, err := scanRoute(s.q.QueryRow(query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -251,29 +623,42 @@ func (s *MySQLStore) GetRouteByID(id uint) (*Route, error) {
 		return nil, err
 	}
 
-	if desc.Valid {
-		r.Description = desc.String
-	}
-	r.Enabled = enabledInt == 1
-
-	return &r, nil
+	return r, nil
 }
 
 // CreateRoute creates a new route configuration.
 func (s *MySQLStore) CreateRoute(route *Route) error {
 	query := `INSERT INTO routes (http_method, http_pattern, backend_name, backend_service, 
-	                              backend_method, timeout_ms, description, enabled) 
-	          VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	                              backend_method, timeout_ms, max_request_bytes, content_types, description, clusters, 
+	                              api_version, lifecycle, transform, cache, circuit_breaker, rollout, experiment, mirror, fallback, grpc, 
+	                              middlewares, plugins, query_params, deprecation, docs, owner_team, enabled) 
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	enabledInt := 0
 	if route.Enabled {
 		enabledInt = 1
 	}
+	lifecycle := route.Lifecycle
+	if lifecycle == "" {
+		lifecycle = LifecycleActive
+	}
+	contentTypes, err := jsonArg(route.ContentTypes)
+	if err != nil {
+		return err
+	}
+	clusters, err := jsonArg(route.Clusters)
+	if err != nil {
+		return err
+	}
+	settings, err := settingsArgs(route)
+	if err != nil {
+		return err
+	}
 
-	result, err := s.db.Exec(
+	result, err := s.q.Exec(
 		query, route.HTTPMethod, route.HTTPPattern, route.BackendName,
-		route.BackendService, route.BackendMethod, route.TimeoutMS,
-		route.Description, enabledInt,
+		route.BackendService, route.BackendMethod, route.TimeoutMS, route.MaxRequestBytes,
+		contentTypes, route.Description, clusters, route.APIVersion, lifecycle, settings[0], settings[1], settings[2], settings[3], settings[4], settings[5], settings[6], settings[7], settings[8], settings[9], settings[10], settings[11], settings[12], route.OwnerTeam, enabledInt,
 	)
 	if err != nil {
 		return err
@@ -295,7 +680,8 @@ func (s *MySQLStore) CreateRoute(route *Route) error {
 func (s *MySQLStore) UpdateRoute(id uint, route *Route) error {
 	query := `UPDATE routes 
 	          SET http_method = ?, http_pattern = ?, backend_name = ?, backend_service = ?, 
-	              backend_method = ?, timeout_ms = ?, description = ?, enabled = ?, 
+	              backend_method = ?, timeout_ms = ?, max_request_bytes = ?, content_types = ?, description = ?, clusters = ?, api_version = ?, lifecycle = ?, 
+	              transform = ?, cache = ?, circuit_breaker = ?, rollout = ?, experiment = ?, mirror = ?, fallback = ?, grpc = ?, middlewares = ?, plugins = ?, query_params = ?, deprecation = ?, docs = ?, owner_team = ?, enabled = ?, 
 	              updated_at = CURRENT_TIMESTAMP 
 	          WHERE id = ?`
 
@@ -303,11 +689,27 @@ func (s *MySQLStore) UpdateRoute(id uint, route *Route) error {
 	if route.Enabled {
 		enabledInt = 1
 	}
+	lifecycle := route.Lifecycle
+	if lifecycle == "" {
+		lifecycle = LifecycleActive
+	}
+	contentTypes, err := jsonArg(route.ContentTypes)
+	if err != nil {
+		return err
+	}
+	clusters, err := jsonArg(route.Clusters)
+	if err != nil {
+		return err
+	}
+	settings, err := settingsArgs(route)
+	if err != nil {
+		return err
+	}
 
-	result, err := s.db.Exec(
+	result, err := s.q.Exec(
 		query, route.HTTPMethod, route.HTTPPattern, route.BackendName,
-		route.BackendService, route.BackendMethod, route.TimeoutMS,
-		route.Description, enabledInt, id,
+		route.BackendService, route.BackendMethod, route.TimeoutMS, route.MaxRequestBytes,
+		contentTypes, route.Description, clusters, route.APIVersion, lifecycle, settings[0], settings[1], settings[2], settings[3], settings[4], settings[5], settings[6], settings[7], settings[8], settings[9], settings[10], settings[11], settings[12], route.OwnerTeam, enabledInt, id,
 	)
 	if err != nil {
 		return err
@@ -332,7 +734,7 @@ func (s *MySQLStore) UpdateRoute(id uint, route *Route) error {
 func (s *MySQLStore) DeleteRoute(id uint) error {
 	query := `UPDATE routes SET enabled = 0, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
 
-	result, err := s.db.Exec(query, id)
+	result, err := s.q.Exec(query, id)
 	if err != nil {
 		return err
 	}
@@ -349,18 +751,100 @@ func (s *MySQLStore) DeleteRoute(id uint) error {
 	return nil
 }
 
-// CreateHistory creates a new configuration change history record.
+// CreateHistory creates a new configuration change history record,
+// bumping the global revision in the same transaction. The revision row
+// stays locked until the transaction commits, so revisions commit in
+// order. The revision is also recorded on the backend or route changed.
 func (s *MySQLStore) CreateHistory(history *ConfigHistory) error {
-	query := `INSERT INTO config_history (config_type, config_id, operation, old_value, new_value, operator) 
-	          VALUES (?, ?, ?, ?, ?, ?)`
+	return s.InTx(func(tx Store) error {
+		return tx.(*MySQLStore).createHistory(history)
+	})
+}
+
+func (s *MySQLStore) createHistory(history *ConfigHistory) error {
+	result, err := s.q.Exec(`UPDATE config_revision SET revision = LAST_INSERT_ID(revision + 1) WHERE id = 1`)
+	if err != nil {
+		return err
+	}
+	revision, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO config_history (config_type, config_id, operation, old_value, new_value, operator, change_reason, freeze_override, revision) 
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	var reason interface{}
+	if history.Reason != "" {
+		reason = history.Reason
+	}
 
-	_, err := s.db.Exec(
+	result, err = s.q.Exec(
 		query, history.ConfigType, history.ConfigID, history.Operation,
-		history.OldValue, history.NewValue, history.Operator,
+		history.OldValue, history.NewValue, history.Operator, reason, history.FreezeOverride, revision,
 	)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	history.ID, history.Revision = uint64(id), uint64(revision)
+
+	if history.ConfigID == nil {
+		return nil
+	}
+	// updated_at keeps its value: the change itself already set it
+	switch history.ConfigType {
+	case "backend":
+		_, err = s.q.Exec(`UPDATE backends SET revision = ?, updated_at = updated_at WHERE id = ?`, revision, *history.ConfigID)
+	case "route":
+		_, err = s.q.Exec(`UPDATE routes SET revision = ?, updated_at = updated_at WHERE id = ?`, revision, *history.ConfigID)
+	}
 	return err
 }
 
+// GetRevision returns the global config revision.
+func (s *MySQLStore) GetRevision() (uint64, error) {
+	var revision uint64
+	if err := s.q.QueryRow(`SELECT revision FROM config_revision WHERE id = 1`).Scan(&revision); err != nil {
+		return 0, err
+	}
+	return revision, nil
+}
+
+// HistoryAges returns the age of every history entry recorded within
+// window, oldest first, optionally limited to one operator. Ages are
+// computed by the database so that they do not depend on clock or time
+// zone agreement with the service.
+func (s *MySQLStore) HistoryAges(operator *string, window time.Duration) ([]time.Duration, error) {
+	query := `SELECT TIMESTAMPDIFF(SECOND, created_at, NOW()) FROM config_history
+	          WHERE created_at > NOW() - INTERVAL ? SECOND`
+	args := []interface{}{int64(window / time.Second)}
+	if operator != nil {
+		query += " AND operator = ?"
+		args = append(args, *operator)
+	}
+	query += " ORDER BY created_at, id"
+
+	rows, err := s.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ages []time.Duration
+	for rows.Next() {
+		var seconds int64
+		if err := rows.Scan(&seconds); err != nil {
+			return nil, err
+		}
+		ages = append(ages, time.Duration(seconds)*time.Second)
+	}
+	return ages, rows.Err()
+}
+
 // GetHistory returns configuration change history with optional filters.
 func (s *MySQLStore) GetHistory(configType *string, configID *uint, limit, offset int) ([]ConfigHistory, int, error) {
 	// Build WHERE clause
@@ -380,17 +864,17 @@ func (s *MySQLStore) GetHistory(configType *string, configID *uint, limit, offse
 	// Get total count
 	countQuery := "SELECT COUNT(*) FROM config_history WHERE " + where
 	var total int
-	if err := s.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+	if err := s.q.QueryRow(countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated results
-	query := `SELECT id, config_type, config_id, operation, old_value, new_value, operator, created_at 
+	query := `SELECT id, config_type, config_id, operation, old_value, new_value, operator, change_reason, freeze_override, revision, created_at 
 	          FROM config_history WHERE ` + where + ` 
-	          ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	          ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
 	args = append(args, limit, offset)
 
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.q.Query(query, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -400,10 +884,11 @@ func (s *MySQLStore) GetHistory(configType *string, configID *uint, limit, offse
 	for rows.Next() {
 		var h ConfigHistory
 		var configIDPtr *uint
+		var reason sql.NullString
 
 		if err := rows.Scan(
 			&h.ID, &h.ConfigType, &configIDPtr, &h.Operation,
-			&h.OldValue, &h.NewValue, &h.Operator, &h.CreatedAt,
+			&h.OldValue, &h.NewValue, &h.Operator, &reason, &h.FreezeOverride, &h.Revision, &h.CreatedAt,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -411,6 +896,9 @@ func (s *MySQLStore) GetHistory(configType *string, configID *uint, limit, offse
 		if configIDPtr != nil {
 			h.ConfigID = configIDPtr
 		}
+		if reason.Valid {
+			h.Reason = reason.String
+		}
 
 		histories = append(histories, h)
 	}
@@ -0,0 +1,15 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/pkg/store/mysqltest"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/pkg/store/storetest"
+)
+
+// TestMySQLStore runs the conformance suite against a migrated MySQL
+// database: ADMIN_TEST_DB_DSN if set, or else a container, skipping when
+// Docker is not available.
+func TestMySQLStore(t *testing.T) {
+	storetest.TestStore(t, mysqltest.NewStore(t))
+}
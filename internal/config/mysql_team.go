@@ -0,0 +1,174 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const teamColumns = `name, description, contact, created_by, created_at, updated_at`
+
+func scanTeam(row rowScanner) (*Team, error) {
+	var t Team
+	if err := row.Scan(&t.Name, &t.Description, &t.Contact, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetTeams returns every team, ordered by name.
+func (s *MySQLStore) GetTeams() ([]Team, error) {
+	rows, err := s.q.Query(`SELECT ` + teamColumns + ` FROM teams ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var teams []Team
+	for rows.Next() {
+		t, err := scanTeam(rows)
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, *t)
+	}
+	return teams, rows.Err()
+}
+
+// GetTeam returns a team by name, or nil if it does not exist.
+func (s *MySQLStore) GetTeam(name string) (*Team, error) {
+	t, err := scanTeam(s.q.QueryRow(`SELECT `+teamColumns+` FROM teams WHERE name = ?`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return t, err
+}
+
+// CreateTeam creates a new team.
+func (s *MySQLStore) CreateTeam(team *Team) error {
+	_, err := s.q.Exec(
+		`INSERT INTO teams (name, description, contact, created_by) VALUES (?, ?, ?, ?)`,
+		team.Name, team.Description, team.Contact, team.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+	team.CreatedAt = time.Now()
+	team.UpdatedAt = team.CreatedAt
+	return nil
+}
+
+// UpdateTeam updates the description and contact of a team.
+func (s *MySQLStore) UpdateTeam(name string, team *Team) error {
+	result, err := s.q.Exec(
+		`UPDATE teams SET description = ?, contact = ?, updated_at = CURRENT_TIMESTAMP WHERE name = ?`,
+		team.Description, team.Contact, name,
+	)
+	if err != nil {
+		return err
+	}
+	// An update within the same second that changes nothing affects no
+	// rows, so tell a missing team apart
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		if existing, err := s.GetTeam(name); err != nil {
+			return err
+		} else if existing == nil {
+			return errors.New("team not found")
+		}
+	}
+	team.Name = name
+	team.UpdatedAt = time.Now()
+	return nil
+}
+
+// DeleteTeam removes a team along with its members. Backends and routes
+// it owned keep its name as their owner team.
+func (s *MySQLStore) DeleteTeam(name string) error {
+	return s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		result, err := q.Exec(`DELETE FROM teams WHERE name = ?`, name)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return errors.New("team not found")
+		}
+		_, err = q.Exec(`DELETE FROM team_members WHERE team = ?`, name)
+		return err
+	})
+}
+
+// GetTeamMembers returns the members of a team, ordered by name.
+func (s *MySQLStore) GetTeamMembers(team string) ([]TeamMember, error) {
+	rows, err := s.q.Query(
+		`SELECT team, member, added_by, created_at FROM team_members WHERE team = ? ORDER BY member`,
+		team,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []TeamMember
+	for rows.Next() {
+		var m TeamMember
+		if err := rows.Scan(&m.Team, &m.Member, &m.AddedBy, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// AddTeamMember adds a member to a team. Adding an existing member again
+// keeps who added it first.
+func (s *MySQLStore) AddTeamMember(member *TeamMember) error {
+	_, err := s.q.Exec(
+		`INSERT IGNORE INTO team_members (team, member, added_by) VALUES (?, ?, ?)`,
+		member.Team, member.Member, member.AddedBy,
+	)
+	if err != nil {
+		return err
+	}
+	member.CreatedAt = time.Now()
+	return nil
+}
+
+// RemoveTeamMember removes a member from a team.
+func (s *MySQLStore) RemoveTeamMember(team, member string) error {
+	result, err := s.q.Exec(`DELETE FROM team_members WHERE team = ? AND member = ?`, team, member)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("team member not found")
+	}
+	return nil
+}
+
+// GetMemberTeams returns the names of the teams member belongs to, ordered
+// by name.
+func (s *MySQLStore) GetMemberTeams(member string) ([]string, error) {
+	rows, err := s.q.Query(`SELECT team FROM team_members WHERE member = ? ORDER BY team`, member)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var teams []string
+	for rows.Next() {
+		var team string
+		if err := rows.Scan(&team); err != nil {
+			return nil, err
+		}
+		teams = append(teams, team)
+	}
+	return teams, rows.Err()
+}
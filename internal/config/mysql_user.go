@@ -0,0 +1,462 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const userColumns = `name, display_name, external_id, role, password_hash, must_change_password, disabled,
+	password_changed_at, totp_enabled, last_login_at, failed_logins, last_failed_login_at, locked_until,
+	created_by, created_at, updated_at`
+
+func scanUser(row rowScanner) (*User, error) {
+	var u User
+	var lastLogin, lastFailed, lockedUntil sql.NullTime
+	if err := row.Scan(&u.Name, &u.DisplayName, &u.ExternalID, &u.Role, &u.PasswordHash, &u.MustChangePassword, &u.Disabled,
+		&u.PasswordChangedAt, &u.TOTPEnabled, &lastLogin, &u.FailedLogins, &lastFailed, &lockedUntil,
+		&u.CreatedBy, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if lastLogin.Valid {
+		u.LastLoginAt = &lastLogin.Time
+	}
+	if lastFailed.Valid {
+		u.LastFailedLoginAt = &lastFailed.Time
+	}
+	if lockedUntil.Valid {
+		u.LockedUntil = &lockedUntil.Time
+	}
+	return &u, nil
+}
+
+// GetUsers returns every user, ordered by name.
+func (s *MySQLStore) GetUsers() ([]User, error) {
+	rows, err := s.q.Query(`SELECT ` + userColumns + ` FROM users ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *u)
+	}
+	return users, rows.Err()
+}
+
+// GetUser returns a user by name, or nil if it does not exist.
+func (s *MySQLStore) GetUser(name string) (*User, error) {
+	u, err := scanUser(s.q.QueryRow(`SELECT `+userColumns+` FROM users WHERE name = ?`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return u, err
+}
+
+// CreateUser creates a new user.
+func (s *MySQLStore) CreateUser(user *User) error {
+	if user.PasswordChangedAt.IsZero() {
+		user.PasswordChangedAt = time.Now().Truncate(time.Millisecond)
+	}
+	_, err := s.q.Exec(
+		`INSERT INTO users (name, display_name, external_id, role, password_hash, must_change_password, disabled, password_changed_at, created_by)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.Name, user.DisplayName, user.ExternalID, user.Role, user.PasswordHash, user.MustChangePassword, user.Disabled,
+		user.PasswordChangedAt, user.CreatedBy,
+	)
+	if err != nil {
+		return err
+	}
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	return nil
+}
+
+// UpdateUser updates the display name, external ID, role and the disabled
+// and must change password flags of a user.
+func (s *MySQLStore) UpdateUser(name string, user *User) error {
+	result, err := s.q.Exec(
+		`UPDATE users SET display_name = ?, external_id = ?, role = ?, disabled = ?, must_change_password = ? WHERE name = ?`,
+		user.DisplayName, user.ExternalID, user.Role, user.Disabled, user.MustChangePassword, name,
+	)
+	if err != nil {
+		return err
+	}
+	if err := s.checkUserUpdated(result, name); err != nil {
+		return err
+	}
+	user.Name = name
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetUserPassword replaces the password hash of a user.
+func (s *MySQLStore) SetUserPassword(name, hash string, mustChange bool, changedAt time.Time) error {
+	result, err := s.q.Exec(
+		`UPDATE users SET password_hash = ?, must_change_password = ?, password_changed_at = ? WHERE name = ?`,
+		hash, mustChange, changedAt, name,
+	)
+	if err != nil {
+		return err
+	}
+	return s.checkUserUpdated(result, name)
+}
+
+// RecordUserLogin records a successful sign in, clearing the failed sign
+// ins and any lockout.
+func (s *MySQLStore) RecordUserLogin(name string, at time.Time) error {
+	// Signing in is not a change of the user
+	_, err := s.q.Exec(
+		`UPDATE users SET last_login_at = ?, failed_logins = 0, locked_until = NULL, updated_at = updated_at WHERE name = ?`,
+		at, name,
+	)
+	return err
+}
+
+// RecordFailedLogin counts a failed sign in at at, forgetting the
+// failures before since, and returns the failures counted.
+func (s *MySQLStore) RecordFailedLogin(name string, at, since time.Time) (int, error) {
+	var failures int
+	err := s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		// The assignments are made in order: the count is updated before
+		// the time of the last failure it depends on
+		if _, err := q.Exec(
+			`UPDATE users SET failed_logins = IF(last_failed_login_at >= ?, failed_logins + 1, 1),
+			 last_failed_login_at = ?, updated_at = updated_at WHERE name = ?`,
+			since, at, name,
+		); err != nil {
+			return err
+		}
+		err := q.QueryRow(`SELECT failed_logins FROM users WHERE name = ?`, name).Scan(&failures)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+	return failures, err
+}
+
+// LockUser locks a user out until until.
+func (s *MySQLStore) LockUser(name string, until time.Time) error {
+	_, err := s.q.Exec(`UPDATE users SET locked_until = ?, updated_at = updated_at WHERE name = ?`, until, name)
+	return err
+}
+
+// UnlockUser lifts the lockout of a user and clears its failed sign ins.
+func (s *MySQLStore) UnlockUser(name string) error {
+	result, err := s.q.Exec(`UPDATE users SET failed_logins = 0, locked_until = NULL WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+	return s.checkUserUpdated(result, name)
+}
+
+// DeleteUser removes a user along with its sessions, recovery codes and
+// group memberships.
+func (s *MySQLStore) DeleteUser(name string) error {
+	return s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		result, err := q.Exec(`DELETE FROM users WHERE name = ?`, name)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return errors.New("user not found")
+		}
+		if _, err := q.Exec(`DELETE FROM sessions WHERE user_name = ?`, name); err != nil {
+			return err
+		}
+		if _, err := q.Exec(`DELETE FROM user_recovery_codes WHERE user_name = ?`, name); err != nil {
+			return err
+		}
+		_, err = q.Exec(`DELETE FROM user_group_members WHERE user_name = ?`, name)
+		return err
+	})
+}
+
+// checkUserUpdated tells a missing user apart from an update that changed
+// nothing, both of which affect no rows.
+func (s *MySQLStore) checkUserUpdated(result sql.Result, name string) error {
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n > 0 {
+		return nil
+	}
+	existing, err := s.GetUser(name)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+const sessionColumns = `id, user_name, refresh_hash, user_agent, ip, created_at, last_used_at, expires_at, revoked_at`
+
+func scanSession(row rowScanner) (*Session, error) {
+	var sess Session
+	var revoked sql.NullTime
+	if err := row.Scan(&sess.ID, &sess.User, &sess.RefreshHash, &sess.UserAgent, &sess.IP,
+		&sess.CreatedAt, &sess.LastUsedAt, &sess.ExpiresAt, &revoked); err != nil {
+		return nil, err
+	}
+	if revoked.Valid {
+		sess.RevokedAt = &revoked.Time
+	}
+	return &sess, nil
+}
+
+// CreateSession stores a new session, dropping the user's sessions that
+// have expired or were revoked.
+func (s *MySQLStore) CreateSession(session *Session) error {
+	return s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		if _, err := q.Exec(
+			`DELETE FROM sessions WHERE user_name = ? AND (expires_at <= ? OR revoked_at IS NOT NULL)`,
+			session.User, session.CreatedAt,
+		); err != nil {
+			return err
+		}
+		_, err := q.Exec(
+			`INSERT INTO sessions (id, user_name, refresh_hash, user_agent, ip, created_at, last_used_at, expires_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			session.ID, session.User, session.RefreshHash, session.UserAgent, session.IP,
+			session.CreatedAt, session.LastUsedAt, session.ExpiresAt,
+		)
+		return err
+	})
+}
+
+// GetSession returns a session by ID, or nil if it does not exist.
+func (s *MySQLStore) GetSession(id string) (*Session, error) {
+	sess, err := scanSession(s.q.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return sess, err
+}
+
+// GetUserSessions returns the sessions of a user that are active at now,
+// most recently used first.
+func (s *MySQLStore) GetUserSessions(user string, now time.Time) ([]Session, error) {
+	rows, err := s.q.Query(
+		`SELECT `+sessionColumns+` FROM sessions
+		 WHERE user_name = ? AND revoked_at IS NULL AND expires_at > ?
+		 ORDER BY last_used_at DESC`,
+		user, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		sess, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *sess)
+	}
+	return sessions, rows.Err()
+}
+
+// RotateSession replaces the refresh token hash of an active session,
+// provided it is still oldHash, so that of two concurrent refreshes with
+// the same token only one succeeds.
+func (s *MySQLStore) RotateSession(id, oldHash, newHash string, usedAt time.Time) error {
+	result, err := s.q.Exec(
+		`UPDATE sessions SET refresh_hash = ?, last_used_at = ?
+		 WHERE id = ? AND refresh_hash = ? AND revoked_at IS NULL AND expires_at > ?`,
+		newHash, usedAt, id, oldHash, usedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// RevokeSession revokes a session.
+func (s *MySQLStore) RevokeSession(id string, at time.Time) error {
+	result, err := s.q.Exec(`UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, at, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of a user.
+func (s *MySQLStore) RevokeUserSessions(user string, at time.Time) error {
+	_, err := s.q.Exec(`UPDATE sessions SET revoked_at = ? WHERE user_name = ? AND revoked_at IS NULL`, at, user)
+	return err
+}
+
+// GetUserTOTP returns the TOTP authenticator of a user, or nil if it has
+// none.
+func (s *MySQLStore) GetUserTOTP(name string) (*UserTOTP, error) {
+	var secret sql.NullString
+	var encrypted bool
+	var totp UserTOTP
+	err := s.q.QueryRow(
+		`SELECT totp_secret, totp_secret_encrypted, totp_enabled, totp_last_step,
+		 (SELECT COUNT(*) FROM user_recovery_codes WHERE user_name = users.name AND used_at IS NULL)
+		 FROM users WHERE name = ?`, name,
+	).Scan(&secret, &encrypted, &totp.Enabled, &totp.LastStep, &totp.RecoveryCodesLeft)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !secret.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	totp.Secret = secret.String
+	if encrypted {
+		if totp.Secret, err = s.decryptField(secret, secretData("users", "totp_secret", name)); err != nil {
+			return nil, err
+		}
+	}
+	return &totp, nil
+}
+
+// SetUserTOTP stores a new authenticator secret for a user, encrypted at
+// rest if a cipher is configured.
+func (s *MySQLStore) SetUserTOTP(name, secret string) error {
+	var stored interface{} = secret
+	encrypted := false
+	if s.cipher != nil {
+		var err error
+		if stored, err = s.cipher.Encrypt(secret, secretData("users", "totp_secret", name)); err != nil {
+			return err
+		}
+		encrypted = true
+	}
+	return s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		result, err := q.Exec(
+			`UPDATE users SET totp_secret = ?, totp_secret_encrypted = ?, totp_enabled = 0, totp_last_step = 0 WHERE name = ?`,
+			stored, encrypted, name,
+		)
+		if err != nil {
+			return err
+		}
+		if err := tx.(*MySQLStore).checkUserUpdated(result, name); err != nil {
+			return err
+		}
+		_, err = q.Exec(`DELETE FROM user_recovery_codes WHERE user_name = ?`, name)
+		return err
+	})
+}
+
+// EnableUserTOTP enables the authenticator of a user, recording step as
+// used and storing the hashes of its recovery codes.
+func (s *MySQLStore) EnableUserTOTP(name string, step int64, recoveryHashes []string) error {
+	return s.InTx(func(tx Store) error {
+		result, err := tx.(*MySQLStore).q.Exec(
+			`UPDATE users SET totp_enabled = 1, totp_last_step = ? WHERE name = ? AND totp_secret IS NOT NULL AND totp_enabled = 0`,
+			step, name,
+		)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return errors.New("totp enrollment not found")
+		}
+		return tx.(*MySQLStore).SetRecoveryCodes(name, recoveryHashes)
+	})
+}
+
+// UseTOTPStep records step as used, failing if it or a later step already
+// was, so that a code cannot be replayed.
+func (s *MySQLStore) UseTOTPStep(name string, step int64) error {
+	result, err := s.q.Exec(
+		`UPDATE users SET totp_last_step = ?, updated_at = updated_at WHERE name = ? AND totp_last_step < ?`,
+		step, name, step,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("totp code already used")
+	}
+	return nil
+}
+
+// SetRecoveryCodes replaces the recovery codes of a user.
+func (s *MySQLStore) SetRecoveryCodes(name string, hashes []string) error {
+	return s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		if _, err := q.Exec(`DELETE FROM user_recovery_codes WHERE user_name = ?`, name); err != nil {
+			return err
+		}
+		for _, hash := range hashes {
+			if _, err := q.Exec(`INSERT INTO user_recovery_codes (user_name, code_hash) VALUES (?, ?)`, name, hash); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UseRecoveryCode marks an unused recovery code as used.
+func (s *MySQLStore) UseRecoveryCode(name, hash string, at time.Time) error {
+	result, err := s.q.Exec(
+		`UPDATE user_recovery_codes SET used_at = ? WHERE user_name = ? AND code_hash = ? AND used_at IS NULL`,
+		at, name, hash,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("recovery code not found")
+	}
+	return nil
+}
+
+// DisableUserTOTP removes the authenticator and recovery codes of a user.
+func (s *MySQLStore) DisableUserTOTP(name string) error {
+	return s.InTx(func(tx Store) error {
+		q := tx.(*MySQLStore).q
+
+		result, err := q.Exec(
+			`UPDATE users SET totp_secret = NULL, totp_secret_encrypted = 0, totp_enabled = 0, totp_last_step = 0 WHERE name = ?`,
+			name,
+		)
+		if err != nil {
+			return err
+		}
+		if err := tx.(*MySQLStore).checkUserUpdated(result, name); err != nil {
+			return err
+		}
+		_, err = q.Exec(`DELETE FROM user_recovery_codes WHERE user_name = ?`, name)
+		return err
+	})
+}
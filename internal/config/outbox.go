@@ -0,0 +1,33 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEntry is a change event recorded in the transaction of the change
+// it describes, so that it is delivered even if the service stops right
+// after the commit. Entries are keyed by the revision of the change, so
+// they commit in order.
+type OutboxEntry struct {
+	Revision  uint64          `json:"revision"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// OutboxSink is the delivery state of a consumer of the outbox, such as a
+// webhook. Each sink is delivered the entries in order by one service
+// replica at a time, the holder of its lease.
+type OutboxSink struct {
+	Name string `json:"name"`
+	// Delivered is the revision of the last entry delivered.
+	Delivered uint64 `json:"delivered"`
+	// Attempts counts the failed attempts to deliver the entries after
+	// Delivered; LastError and NextAttemptAt are those of the last.
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LeaseOwner    string     `json:"lease_owner,omitempty"`
+	LeaseUntil    *time.Time `json:"lease_until,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
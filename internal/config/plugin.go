@@ -0,0 +1,61 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Bounds of plugin configs attached to routes and backends.
+const (
+	MaxPlugins          = 32
+	MaxPluginConfigSize = 64 << 10
+)
+
+var pluginName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// PluginSchema is the JSON Schema registered for a plugin name. Configs of
+// the plugin are validated against it; plugins without a schema accept
+// any JSON.
+type PluginSchema struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema"`
+	UpdatedBy   string          `json:"updated_by,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// ValidPluginName reports whether name can name a plugin.
+func ValidPluginName(name string) bool {
+	return pluginName.MatchString(name)
+}
+
+// ValidatePlugins checks the plugin configs of a route or backend: valid
+// names, at most MaxPlugins, each a JSON value of at most
+// MaxPluginConfigSize bytes. Schemas are checked by the admission chain.
+func ValidatePlugins(plugins map[string]json.RawMessage) error {
+	if len(plugins) > MaxPlugins {
+		return fmt.Errorf("too many plugins (at most %d)", MaxPlugins)
+	}
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !ValidPluginName(name) {
+			return fmt.Errorf("invalid plugin name %q (lowercase letters, digits, '-', '_' and '.', at most 64)", name)
+		}
+		raw := plugins[name]
+		if len(raw) > MaxPluginConfigSize {
+			return fmt.Errorf("plugins.%s too large (at most %d bytes)", name, MaxPluginConfigSize)
+		}
+		if !json.Valid(raw) {
+			return fmt.Errorf("plugins.%s is not valid JSON", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// MaxQueryParams bounds the query parameter mappings of a route.
+const MaxQueryParams = 32
+
+// fieldPath matches a protobuf field path such as "page.size".
+var fieldPath = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// QueryParam maps a query parameter into a field of the backend request
+// when gateways transcode. Parameters without a mapping are handled by the
+// gateway default, matching field names.
+type QueryParam struct {
+	Name string `json:"name"`
+	// Field is the request field path, e.g. "page.size"; empty means Name.
+	Field string `json:"field,omitempty"`
+	// Default is used when the parameter is absent; Required rejects such
+	// requests with 400 instead. Only one can be set.
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// validateQueryParams checks the query parameter mappings of a route,
+// filling in fields. Whether fields exist in the request message is
+// checked against descriptor sets by the caller.
+func validateQueryParams(params []QueryParam) error {
+	if len(params) > MaxQueryParams {
+		return fmt.Errorf("too many query_params (at most %d)", MaxQueryParams)
+	}
+	names := make(map[string]bool, len(params))
+	fields := make(map[string]bool, len(params))
+	for i := range params {
+		p := &params[i]
+		path := fmt.Sprintf("query_params[%d]", i)
+		if p.Name == "" {
+			return errors.New(path + ".name is required")
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicate query_params name %s", p.Name)
+		}
+		names[p.Name] = true
+
+		if p.Field == "" {
+			p.Field = p.Name
+		}
+		if !fieldPath.MatchString(p.Field) {
+			return fmt.Errorf("invalid %s.field: %q is not a field path", path, p.Field)
+		}
+		if fields[p.Field] {
+			return fmt.Errorf("duplicate query_params field %s", p.Field)
+		}
+		fields[p.Field] = true
+
+		if p.Required && p.Default != "" {
+			return fmt.Errorf("%s cannot set both required and default", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// RolloutKeyIP hashes the client IP; it is the default rollout key.
+const RolloutKeyIP = "ip"
+
+// RouteRollout serves a route to only a share of the traffic, e.g. to ramp
+// up a new endpoint. Gateways hash the key of each request and serve the
+// route if the hash falls within Percent; other requests get 404 as if the
+// route did not exist. The same key always gets the same answer for a
+// given percentage, and raising it only adds keys.
+type RouteRollout struct {
+	Percent int `json:"percent"`
+	// HashKey is "ip", "header:<name>", "query:<name>" or "cookie:<name>";
+	// empty means "ip". Requests without the key are hashed as empty.
+	HashKey string `json:"hash_key,omitempty"`
+}
+
+// Validate checks the percentage and key, canonicalizing header names. A
+// nil rollout is valid and means the route serves all traffic.
+func (r *RouteRollout) Validate() error {
+	if r == nil {
+		return nil
+	}
+	if r.Percent < 0 || r.Percent > 100 {
+		return errors.New("rollout.percent must be between 0 and 100")
+	}
+
+	key, err := validateHashKey("rollout.hash_key", r.HashKey)
+	if err != nil {
+		return err
+	}
+	r.HashKey = key
+	return nil
+}
+
+// validateHashKey checks a key requests are hashed by, named field in
+// errors, and returns it with header names canonicalized.
+func validateHashKey(field, key string) (string, error) {
+	if key == "" || key == RolloutKeyIP {
+		return key, nil
+	}
+	invalid := fmt.Errorf("invalid %s (must be 'ip', 'header:<name>', 'query:<name>' or 'cookie:<name>')", field)
+	source, name, ok := strings.Cut(key, ":")
+	if !ok || name == "" {
+		return "", invalid
+	}
+	switch source {
+	case "header":
+		if !httpguts.ValidHeaderFieldName(name) {
+			return "", fmt.Errorf("invalid %s: %q is not a header name", field, name)
+		}
+		return "header:" + http.CanonicalHeaderKey(name), nil
+	case "query":
+	case "cookie":
+		if !httpguts.ValidHeaderFieldName(name) {
+			return "", fmt.Errorf("invalid %s: %q is not a cookie name", field, name)
+		}
+	default:
+		return "", invalid
+	}
+	return key, nil
+}
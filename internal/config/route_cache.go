@@ -0,0 +1,68 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// Bounds of route cache settings.
+const (
+	MaxCacheTTLSeconds  = 24 * 60 * 60
+	MaxCacheVaryHeaders = 16
+)
+
+// RouteCache lets gateways cache the responses of a route. The cache key
+// is the method, path and query, plus the VaryHeaders values and, for POST
+// routes, the request body. Only successful responses are cached.
+type RouteCache struct {
+	TTLSeconds  int      `json:"ttl_seconds"`
+	VaryHeaders []string `json:"vary_headers,omitempty"`
+	// AllowPost must be set to cache a POST route, confirming that its
+	// RPC has no side effects.
+	AllowPost bool `json:"allow_post,omitempty"`
+}
+
+// Validate checks the cache settings of a route with the given HTTP
+// method, canonicalizing the header names. GET and HEAD routes can be
+// cached, POST routes only with AllowPost, other methods never. A nil
+// cache is valid and means responses are not cached.
+func (c *RouteCache) Validate(method string) error {
+	if c == nil {
+		return nil
+	}
+
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		if !c.AllowPost {
+			return errors.New("cache on a POST route requires cache.allow_post, confirming the RPC has no side effects")
+		}
+	default:
+		return fmt.Errorf("%s routes cannot be cached", strings.ToUpper(method))
+	}
+
+	if c.TTLSeconds <= 0 || c.TTLSeconds > MaxCacheTTLSeconds {
+		return fmt.Errorf("cache.ttl_seconds must be between 1 and %d", MaxCacheTTLSeconds)
+	}
+
+	if len(c.VaryHeaders) > MaxCacheVaryHeaders {
+		return fmt.Errorf("too many cache.vary_headers (at most %d)", MaxCacheVaryHeaders)
+	}
+	seen := make(map[string]bool, len(c.VaryHeaders))
+	for i, name := range c.VaryHeaders {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("invalid cache.vary_headers[%d]: %q is not a header name", i, name)
+		}
+		name = http.CanonicalHeaderKey(name)
+		if seen[name] {
+			return fmt.Errorf("duplicate cache.vary_headers entry %s", name)
+		}
+		seen[name] = true
+		c.VaryHeaders[i] = name
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// MaxMetadataEntries bounds each list of route gRPC settings.
+const MaxMetadataEntries = 32
+
+// metadataKey matches gRPC metadata keys, which are lowercase; binary
+// values use the "-bin" suffix.
+var metadataKey = regexp.MustCompile(`^[0-9a-z_.-]+$`)
+
+// RouteGRPC controls what gateways pass from HTTP requests to the gRPC
+// call of a route.
+type RouteGRPC struct {
+	// PropagateDeadline lets a client shorten the call with a grpc-timeout
+	// header; the route timeout still bounds it.
+	PropagateDeadline bool `json:"propagate_deadline,omitempty"`
+	// Headers are the request headers sent as metadata.
+	Headers []MetadataHeader `json:"headers,omitempty"`
+	// Allow, if set, lists the only metadata keys sent; Deny lists keys
+	// never sent. They apply to all metadata, mapped headers included,
+	// and only one can be set.
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// MetadataHeader sends a request header as gRPC metadata. Key defaults to
+// the lowercase header name.
+type MetadataHeader struct {
+	Header string `json:"header"`
+	Key    string `json:"key,omitempty"`
+}
+
+// Validate checks the settings, canonicalizing header names and filling
+// in metadata keys. A nil RouteGRPC is valid and means gateway defaults.
+func (g *RouteGRPC) Validate() error {
+	if g == nil {
+		return nil
+	}
+	if len(g.Allow) > 0 && len(g.Deny) > 0 {
+		return errors.New("grpc.allow and grpc.deny cannot both be set")
+	}
+
+	allow, err := metadataKeys("grpc.allow", g.Allow)
+	if err != nil {
+		return err
+	}
+	deny, err := metadataKeys("grpc.deny", g.Deny)
+	if err != nil {
+		return err
+	}
+
+	if len(g.Headers) > MaxMetadataEntries {
+		return fmt.Errorf("too many grpc.headers (at most %d)", MaxMetadataEntries)
+	}
+	keys := make(map[string]bool, len(g.Headers))
+	for i := range g.Headers {
+		h := &g.Headers[i]
+		path := fmt.Sprintf("grpc.headers[%d]", i)
+		if !httpguts.ValidHeaderFieldName(h.Header) {
+			return fmt.Errorf("invalid %s.header: %q is not a header name", path, h.Header)
+		}
+		h.Header = http.CanonicalHeaderKey(h.Header)
+		if h.Key == "" {
+			h.Key = strings.ToLower(h.Header)
+		}
+		if err := checkMetadataKey(path+".key", h.Key); err != nil {
+			return err
+		}
+
+		switch {
+		case keys[h.Key]:
+			return fmt.Errorf("duplicate grpc.headers key %s", h.Key)
+		case deny[h.Key]:
+			return fmt.Errorf("%s.key %s is in grpc.deny", path, h.Key)
+		case allow != nil && !allow[h.Key]:
+			return fmt.Errorf("%s.key %s is not in grpc.allow", path, h.Key)
+		}
+		keys[h.Key] = true
+	}
+	return nil
+}
+
+// metadataKeys checks a list of metadata keys, named field in errors, and
+// returns them as a set, or nil if the list is empty.
+func metadataKeys(field string, keys []string) (map[string]bool, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if len(keys) > MaxMetadataEntries {
+		return nil, fmt.Errorf("too many %s entries (at most %d)", field, MaxMetadataEntries)
+	}
+	set := make(map[string]bool, len(keys))
+	for i, key := range keys {
+		if err := checkMetadataKey(fmt.Sprintf("%s[%d]", field, i), key); err != nil {
+			return nil, err
+		}
+		if set[key] {
+			return nil, fmt.Errorf("duplicate %s entry %s", field, key)
+		}
+		set[key] = true
+	}
+	return set, nil
+}
+
+// checkMetadataKey checks that key can be sent as gRPC metadata.
+func checkMetadataKey(field, key string) error {
+	if !metadataKey.MatchString(key) {
+		return fmt.Errorf("invalid %s: %q is not a metadata key (lowercase letters, digits, '-', '_' and '.')", field, key)
+	}
+	if strings.HasPrefix(key, "grpc-") {
+		return fmt.Errorf("invalid %s: %q is reserved for gRPC", field, key)
+	}
+	return nil
+}
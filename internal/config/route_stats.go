@@ -0,0 +1,18 @@
+package config
+
+import "time"
+
+// LatencyBucketBounds are the upper bounds, in milliseconds, of the latency
+// histogram gateways report per route. RouteStats.LatencyBuckets has one
+// more entry than this, for requests slower than the last bound.
+var LatencyBucketBounds = []int{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// RouteStats is the traffic a route served in one minute, summed across
+// gateways.
+type RouteStats struct {
+	RouteID        uint      `json:"route_id"`
+	BucketStart    time.Time `json:"bucket_start"`
+	Requests       int64     `json:"requests"`
+	Errors         int64     `json:"errors"`
+	LatencyBuckets []int64   `json:"latency_buckets"`
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SchemaRef is the schema of a route's request or response body: either an
+// inline JSON Schema document or the full name of a protobuf message
+// defined in the descriptor set of the route's backend.
+type SchemaRef struct {
+	JSONSchema json.RawMessage `json:"json_schema,omitempty"`
+	Message    string          `json:"message,omitempty"`
+}
+
+// RouteSchema is a version of the schemas gateways validate a route's
+// bodies against. Versions count up from 1 per route and the latest one is
+// in effect; a version without schemas turns validation off.
+type RouteSchema struct {
+	ID        uint       `json:"id"`
+	RouteID   uint       `json:"route_id"`
+	Version   int        `json:"version"`
+	Request   *SchemaRef `json:"request,omitempty"`
+	Response  *SchemaRef `json:"response,omitempty"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Snapshot is a named copy of the whole configuration, taken by an
+// operator to return to later. Document holds the configuration as an
+// apply document without secrets; it is omitted from listings.
+type Snapshot struct {
+	ID          uint            `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Backends    int             `json:"backends"`
+	Routes      int             `json:"routes"`
+	CreatedBy   string          `json:"created_by,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Document    json.RawMessage `json:"document,omitempty"`
+}
@@ -2,45 +2,283 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 )
 
 // Backend represents a backend service configuration.
 type Backend struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Addr        string    `json:"addr"`
-	Description string    `json:"description,omitempty"`
-	Enabled     bool      `json:"enabled"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uint                       `json:"id"`
+	Name           string                     `json:"name"`
+	Addr           string                     `json:"addr"`
+	Description    string                     `json:"description,omitempty"`
+	Clusters       []string                   `json:"clusters,omitempty"`
+	Enabled        bool                       `json:"enabled"`
+	Status         string                     `json:"status"`
+	Credential     *BackendCredential         `json:"credential,omitempty"`
+	TLS            *BackendTLS                `json:"tls,omitempty"`
+	CircuitBreaker *CircuitBreaker            `json:"circuit_breaker,omitempty"`
+	Plugins        map[string]json.RawMessage `json:"plugins,omitempty"`
+	OwnerTeam      string                     `json:"owner_team,omitempty"`
+	// Revision is the global config revision of the last change to the
+	// backend. It is maintained by the store.
+	Revision  uint64    `json:"revision,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Backend states. An enabled backend serves traffic; a draining one is
+// still enabled, but gateways send it no new sessions and let existing
+// streams finish, e.g. before maintenance.
+const (
+	BackendEnabled  = "enabled"
+	BackendDisabled = "disabled"
+	BackendDraining = "draining"
+)
+
+// NormalizeStatus reconciles Status and Enabled: an empty Status is
+// derived from Enabled, otherwise Status takes precedence and sets
+// Enabled.
+func (b *Backend) NormalizeStatus() error {
+	switch b.Status {
+	case "":
+		b.Status = BackendDisabled
+		if b.Enabled {
+			b.Status = BackendEnabled
+		}
+	case BackendEnabled, BackendDraining:
+		b.Enabled = true
+	case BackendDisabled:
+		b.Enabled = false
+	default:
+		return errors.New("invalid status (must be 'enabled', 'disabled' or 'draining')")
+	}
+	return nil
+}
+
+// Credential types supported for authenticated upstreams.
+const (
+	CredentialNone   = "none"
+	CredentialBearer = "bearer"
+	CredentialBasic  = "basic"
+)
+
+// BackendCredential holds the authentication the gateway uses when calling
+// a backend. The secret is either stored inline (encrypted at rest) or
+// referenced via SecretRef (e.g. "env:ACCOUNT_TOKEN") and resolved by the
+// gateway. Secret is write-only: it is never included in JSON output.
+type BackendCredential struct {
+	Type      string `json:"type"`
+	Username  string `json:"username,omitempty"`
+	SecretRef string `json:"secret_ref,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+}
+
+// MarshalJSON redacts the inline secret, reporting only whether one is set.
+func (c BackendCredential) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type      string `json:"type"`
+		Username  string `json:"username,omitempty"`
+		SecretRef string `json:"secret_ref,omitempty"`
+		HasSecret bool   `json:"has_secret"`
+	}{
+		Type:      c.Type,
+		Username:  c.Username,
+		SecretRef: c.SecretRef,
+		HasSecret: c.Secret != "",
+	})
+}
+
+// Validate checks that the credential is well formed. A nil credential is
+// valid and means the backend needs no authentication.
+func (c *BackendCredential) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	switch c.Type {
+	case CredentialNone:
+		return nil
+	case CredentialBearer:
+	case CredentialBasic:
+		if c.Username == "" {
+			return errors.New("credential.username is required for basic auth")
+		}
+	default:
+		return errors.New("invalid credential.type (must be 'none', 'bearer' or 'basic')")
+	}
+
+	if c.Secret == "" && c.SecretRef == "" {
+		return errors.New("credential.secret or credential.secret_ref is required")
+	}
+	if c.Secret != "" && c.SecretRef != "" {
+		return errors.New("credential.secret and credential.secret_ref are mutually exclusive")
+	}
+	return nil
+}
+
+// BackendTLS configures TLS for connections from the gateway to a backend.
+// ClientKey is sensitive: it is encrypted at rest and never included in
+// JSON output.
+type BackendTLS struct {
+	ServerName         string `json:"server_name,omitempty"`
+	CACert             string `json:"ca_cert,omitempty"`
+	ClientCert         string `json:"client_cert,omitempty"`
+	ClientKey          string `json:"client_key,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// MarshalJSON redacts the client key, reporting only whether one is set.
+func (t BackendTLS) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ServerName         string `json:"server_name,omitempty"`
+		CACert             string `json:"ca_cert,omitempty"`
+		ClientCert         string `json:"client_cert,omitempty"`
+		HasClientKey       bool   `json:"has_client_key"`
+		InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+	}{
+		ServerName:         t.ServerName,
+		CACert:             t.CACert,
+		ClientCert:         t.ClientCert,
+		HasClientKey:       t.ClientKey != "",
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	})
+}
+
+// Validate checks that client certificate and key are configured together.
+func (t *BackendTLS) Validate() error {
+	if t == nil {
+		return nil
+	}
+	if (t.ClientCert == "") != (t.ClientKey == "") {
+		return errors.New("tls.client_cert and tls.client_key must be set together")
+	}
+	return nil
+}
+
+// ErrEncryptionDisabled is returned when an inline secret is stored without
+// an encryption key configured.
+var ErrEncryptionDisabled = errors.New("storing secrets requires an encryption key (ADMIN_ENCRYPTION_KEY or ADMIN_ENCRYPTION_KEYS)")
+
+// Request body limits. Routes without max_request_bytes get
+// DefaultMaxRequestBytes; larger uploads must be allowed per route.
+const (
+	DefaultMaxRequestBytes = 1 << 20
+	MinMaxRequestBytes     = 1 << 10
+	MaxMaxRequestBytes     = 1 << 30
+)
+
 // Route represents a route configuration.
 type Route struct {
-	ID             uint      `json:"id"`
-	HTTPMethod     string    `json:"http_method"`
-	HTTPPattern    string    `json:"http_pattern"`
-	BackendName    string    `json:"backend_name"`
-	BackendService string    `json:"backend_service"`
-	BackendMethod  string    `json:"backend_method"`
-	TimeoutMS      int       `json:"timeout_ms"`
-	Description    string    `json:"description,omitempty"`
-	Enabled        bool      `json:"enabled"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID              uint                       `json:"id"`
+	HTTPMethod      string                     `json:"http_method"`
+	HTTPPattern     string                     `json:"http_pattern"`
+	BackendName     string                     `json:"backend_name"`
+	BackendService  string                     `json:"backend_service"`
+	BackendMethod   string                     `json:"backend_method"`
+	TimeoutMS       int                        `json:"timeout_ms"`
+	MaxRequestBytes int                        `json:"max_request_bytes,omitempty"`
+	ContentTypes    []string                   `json:"content_types,omitempty"`
+	Description     string                     `json:"description,omitempty"`
+	Clusters        []string                   `json:"clusters,omitempty"`
+	APIVersion      string                     `json:"api_version,omitempty"`
+	Lifecycle       string                     `json:"lifecycle"`
+	Deprecation     *RouteDeprecation          `json:"deprecation,omitempty"`
+	Docs            *RouteDocs                 `json:"docs,omitempty"`
+	Transform       *RouteTransform            `json:"transform,omitempty"`
+	Cache           *RouteCache                `json:"cache,omitempty"`
+	CircuitBreaker  *CircuitBreaker            `json:"circuit_breaker,omitempty"`
+	Rollout         *RouteRollout              `json:"rollout,omitempty"`
+	Experiment      *RouteExperiment           `json:"experiment,omitempty"`
+	Mirror          *RouteMirror               `json:"mirror,omitempty"`
+	Fallback        *RouteFallback             `json:"fallback,omitempty"`
+	GRPC            *RouteGRPC                 `json:"grpc,omitempty"`
+	Middlewares     []RouteMiddleware          `json:"middlewares,omitempty"`
+	Plugins         map[string]json.RawMessage `json:"plugins,omitempty"`
+	QueryParams     []QueryParam               `json:"query_params,omitempty"`
+	OwnerTeam       string                     `json:"owner_team,omitempty"`
+	Enabled         bool                       `json:"enabled"`
+	// Revision is the global config revision of the last change to the
+	// route. It is maintained by the store.
+	Revision  uint64    `json:"revision,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the optional settings of the route: request size and
+// content types, gateway clusters, owner team, API version, lifecycle and deprecation, docs, body
+// transforms, caching, circuit breaker overrides, rollout, experiment,
+// mirror, fallback, gRPC propagation, the middleware chain, plugins and
+// query parameter mappings.
+func (r *Route) Validate() error {
+	if r.MaxRequestBytes != 0 && (r.MaxRequestBytes < MinMaxRequestBytes || r.MaxRequestBytes > MaxMaxRequestBytes) {
+		return fmt.Errorf("max_request_bytes must be between %d and %d", MinMaxRequestBytes, MaxMaxRequestBytes)
+	}
+	if err := validateContentTypes(r.ContentTypes); err != nil {
+		return err
+	}
+	if err := ValidateClusters(r.Clusters); err != nil {
+		return err
+	}
+	if err := ValidateOwnerTeam(r.OwnerTeam); err != nil {
+		return err
+	}
+	if err := validateLifecycle(r); err != nil {
+		return err
+	}
+	if err := r.Docs.Validate(); err != nil {
+		return err
+	}
+	if err := r.Transform.Validate(); err != nil {
+		return err
+	}
+	if err := r.Cache.Validate(r.HTTPMethod); err != nil {
+		return err
+	}
+	if err := r.CircuitBreaker.Validate(); err != nil {
+		return err
+	}
+	if err := r.Rollout.Validate(); err != nil {
+		return err
+	}
+	if err := r.Experiment.Validate(); err != nil {
+		return err
+	}
+	if err := r.Mirror.Validate(r); err != nil {
+		return err
+	}
+	if err := r.Fallback.Validate(r); err != nil {
+		return err
+	}
+	if err := r.GRPC.Validate(); err != nil {
+		return err
+	}
+	if err := validateMiddlewares(r); err != nil {
+		return err
+	}
+	if err := ValidatePlugins(r.Plugins); err != nil {
+		return err
+	}
+	return validateQueryParams(r.QueryParams)
 }
 
 // ConfigHistory represents a configuration change history record.
 type ConfigHistory struct {
 	ID         uint64          `json:"id"`
-	ConfigType string          `json:"config_type"` // "backend" or "route"
+	ConfigType string          `json:"config_type"` // "backend", "route", "descriptor" or "schema"
 	ConfigID   *uint           `json:"config_id,omitempty"`
 	Operation  string          `json:"operation"` // "CREATE", "UPDATE", "DELETE"
 	OldValue   json.RawMessage `json:"old_value,omitempty"`
 	NewValue   json.RawMessage `json:"new_value,omitempty"`
 	Operator   string          `json:"operator,omitempty"`
-	CreatedAt  time.Time       `json:"created_at"`
+	Reason     string          `json:"change_reason,omitempty"`
+	// FreezeOverride is set when an admin made the change during a freeze window.
+	FreezeOverride bool `json:"freeze_override,omitempty"`
+	// Revision is the global config revision the change committed; it is
+	// set by CreateHistory.
+	Revision  uint64    `json:"revision"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // Store defines the interface for configuration storage operations.
@@ -62,4 +300,23 @@ type Store interface {
 	// History operations
 	CreateHistory(history *ConfigHistory) error
 	GetHistory(configType *string, configID *uint, limit, offset int) ([]ConfigHistory, int, error)
+	// HistoryAges returns the age of every history entry recorded within
+	// window, oldest first, optionally limited to one operator.
+	HistoryAges(operator *string, window time.Duration) ([]time.Duration, error)
+	// GetRevision returns the global config revision: the number of
+	// changes committed, bumped by CreateHistory in the transaction that
+	// records each. Revisions commit in order, so a reader that saw
+	// revision N has seen every change up to N.
+	GetRevision() (uint64, error)
+
+	// Freeze window operations
+	GetFreezeWindows() ([]FreezeWindow, error)
+	GetFreezeWindowByID(id uint) (*FreezeWindow, error)
+	CreateFreezeWindow(window *FreezeWindow) error
+	UpdateFreezeWindow(id uint, window *FreezeWindow) error
+	DeleteFreezeWindow(id uint) error
+
+	// InTx runs fn within a transaction; all changes made through the
+	// Store passed to fn are committed or rolled back together.
+	InTx(fn func(tx Store) error) error
 }
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Team is a group of operators that owns backends and routes. Operators
+// belong to the teams they are members of, along with those their
+// authenticating proxy names in the X-Operator-Teams header.
+type Team struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Contact tells how to reach the team, e.g. a chat channel or an
+	// email address.
+	Contact   string    `json:"contact,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TeamMember records that an operator, as named by the X-Operator header,
+// belongs to a team.
+type TeamMember struct {
+	Team      string    `json:"team"`
+	Member    string    `json:"member"`
+	AddedBy   string    `json:"added_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MaxMemberLength is the maximum length of a team member name.
+const MaxMemberLength = 128
+
+// teamName matches team names such as "payments" or "search-infra".
+var teamName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,62}[a-z0-9])?$`)
+
+// ValidateTeamName checks the name of a team.
+func ValidateTeamName(name string) error {
+	if !teamName.MatchString(name) {
+		return fmt.Errorf("invalid team %q (lowercase letters, digits and dashes, at most 64 characters)", name)
+	}
+	return nil
+}
+
+// ValidateOwnerTeam checks the owner team of a backend or route, which
+// may be empty for resources owned by no team.
+func ValidateOwnerTeam(team string) error {
+	if team == "" {
+		return nil
+	}
+	if err := ValidateTeamName(team); err != nil {
+		return fmt.Errorf("owner_team: %w", err)
+	}
+	return nil
+}
+
+// ValidateTeamMember checks the name of a team member.
+func ValidateTeamMember(member string) error {
+	if strings.TrimSpace(member) == "" {
+		return fmt.Errorf("member is required")
+	}
+	if len(member) > MaxMemberLength || strings.ContainsAny(member, ",\r\n") {
+		return fmt.Errorf("invalid member %q (at most %d characters, without commas or line breaks)", member, MaxMemberLength)
+	}
+	return nil
+}
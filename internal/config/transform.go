@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/template"
+)
+
+// TemplateGo is the language of body templates written as Go text/template.
+const TemplateGo = "go_template"
+
+// MaxTemplateSize bounds a body template.
+const MaxTemplateSize = 64 << 10
+
+// TemplateFuncs are the functions available to Go body templates besides
+// the text/template builtins. Gateways provide the same functions.
+var TemplateFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. {{json .user.name}}.
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// default returns value, or def if value is missing or empty.
+	"default": func(def, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return def
+		}
+		return value
+	},
+}
+
+// RouteTransform maps the bodies of a route between what clients send and
+// receive and what the backend expects, e.g. to keep legacy clients
+// working against a new backend. Gateways apply it to the JSON body.
+type RouteTransform struct {
+	Request  *BodyTemplate `json:"request,omitempty"`
+	Response *BodyTemplate `json:"response,omitempty"`
+}
+
+// BodyTemplate renders a new body from the decoded JSON body, which is the
+// template's data.
+type BodyTemplate struct {
+	Language string `json:"language"`
+	Template string `json:"template"`
+}
+
+// Validate checks that the templates parse. A nil transform is valid and
+// means bodies are passed through.
+func (t *RouteTransform) Validate() error {
+	if t == nil {
+		return nil
+	}
+	if t.Request == nil && t.Response == nil {
+		return errors.New("transform.request or transform.response is required")
+	}
+	if err := t.Request.validate("transform.request"); err != nil {
+		return err
+	}
+	return t.Response.validate("transform.response")
+}
+
+func (b *BodyTemplate) validate(path string) error {
+	if b == nil {
+		return nil
+	}
+	if b.Language != TemplateGo {
+		return fmt.Errorf("invalid %s.language (must be '%s')", path, TemplateGo)
+	}
+	if b.Template == "" {
+		return fmt.Errorf("%s.template is required", path)
+	}
+	if len(b.Template) > MaxTemplateSize {
+		return fmt.Errorf("%s.template too large (at most %d bytes)", path, MaxTemplateSize)
+	}
+	if _, err := template.New(path).Funcs(TemplateFuncs).Parse(b.Template); err != nil {
+		return fmt.Errorf("invalid %s.template: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// User is a local account, for installations without an identity
+// provider in front of the service. Users sign in with a password and
+// act under their name and role.
+type User struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name,omitempty"`
+	// ExternalID identifies the user in the identity provider that
+	// provisioned it over SCIM.
+	ExternalID string `json:"external_id,omitempty"`
+	Role       string `json:"role"`
+	// PasswordHash is the bcrypt hash of the password; it is never
+	// returned by the API.
+	PasswordHash string `json:"-"`
+	// MustChangePassword restricts the user to changing their password
+	// until they do, e.g. after an admin set it.
+	MustChangePassword bool `json:"must_change_password"`
+	// Disabled users cannot sign in, and the tokens issued to them are no
+	// longer accepted.
+	Disabled bool `json:"disabled"`
+	// PasswordChangedAt is when the password was last set; tokens issued
+	// before then are no longer accepted.
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+	// TOTPEnabled is set once the user has enrolled an authenticator app,
+	// whose code is then required when signing in.
+	TOTPEnabled bool       `json:"totp_enabled"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	// FailedLogins counts the failed sign ins since the last successful
+	// one, forgetting those long past.
+	FailedLogins      int        `json:"failed_logins"`
+	LastFailedLoginAt *time.Time `json:"last_failed_login_at,omitempty"`
+	// LockedUntil is set while the user is locked out after repeated
+	// failed sign ins.
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// userName matches user names such as "alice" or "j.doe@example.com".
+var userName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@-]{0,63}$`)
+
+// ValidateUserName checks the name of a user.
+func ValidateUserName(name string) error {
+	if !userName.MatchString(name) {
+		return fmt.Errorf("invalid user name %q (letters, digits, '.', '_', '@' and '-', at most 64 characters)", name)
+	}
+	return nil
+}
+
+// Group is a group of users pushed by an identity provider over SCIM. The
+// groups of a user decide its role and teams, as mapped in the
+// configuration.
+type Group struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	ExternalID  string `json:"external_id,omitempty"`
+	// Members are the names of the users in the group, ordered.
+	Members   []string  `json:"members"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserTOTP is the TOTP authenticator of a user.
+type UserTOTP struct {
+	// Secret is the base32-encoded shared secret.
+	Secret string
+	// Enabled is set once the user confirmed the enrollment with a code.
+	Enabled bool
+	// LastStep is the time step of the last code accepted, which cannot
+	// be used again.
+	LastStep int64
+	// RecoveryCodesLeft is the number of unused recovery codes.
+	RecoveryCodesLeft int
+}
+
+// Session is a signed in local user on one device. The session is
+// renewed with its refresh token, of which only a hash is kept, and ends
+// when it expires or is revoked.
+type Session struct {
+	ID   string `json:"id"`
+	User string `json:"user"`
+	// RefreshHash is the SHA-256 of the current refresh token, which
+	// changes on every use; it is never returned by the API.
+	RefreshHash string     `json:"-"`
+	UserAgent   string     `json:"user_agent,omitempty"`
+	IP          string     `json:"ip,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  time.Time  `json:"last_used_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// ActiveAt reports whether the session can still be used at now.
+func (s *Session) ActiveAt(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// WarnTimeoutMS is the route timeout above which a warning is raised:
+// clients and load balancers in front of the gateway commonly give up
+// after 30 seconds.
+const WarnTimeoutMS = 30000
+
+// Warnings returns findings about r that do not make it invalid but are
+// likely mistakes. Checks that need more than the route itself, such as
+// backend health, are up to the caller.
+func (r *Route) Warnings(now time.Time) []string {
+	var warnings []string
+	if r.TimeoutMS > WarnTimeoutMS {
+		warnings = append(warnings, fmt.Sprintf("timeout_ms %d is over %d", r.TimeoutMS, WarnTimeoutMS))
+	}
+	if r.SunsetPassed(now) {
+		warnings = append(warnings, "route is enabled past its sunset date; retire or disable it")
+	}
+	return warnings
+}
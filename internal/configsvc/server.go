@@ -0,0 +1,272 @@
+// Package configsvc serves the gRPC ConfigService, over which gateways
+// subscribe to their config instead of polling for it.
+package configsvc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/compile"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/signing"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/pkg/api/configv1"
+)
+
+const (
+	// maxGatewayIDLength bounds the instance IDs gateways subscribe under,
+	// as over HTTP.
+	maxGatewayIDLength = 128
+	// maxGatewayFailureLength bounds the error gateways acknowledge a
+	// revision with, as over HTTP.
+	maxGatewayFailureLength = 512
+	// subscriptionBuffer is how many change events a subscription may fall
+	// behind by before it is dropped and resynced.
+	subscriptionBuffer = 64
+)
+
+// sha256Hex matches a lowercase hex SHA-256.
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// configEvents selects the changes that may change the compiled config.
+var configEvents = events.Filter{Types: map[string]bool{events.TypeBackend: true, events.TypeRoute: true, events.TypeDescriptor: true, events.TypeSchema: true}}
+
+// PayloadFunc returns the encoded config served to the gateway instance
+// gateway, which may be empty, in cluster.
+type PayloadFunc func(ctx context.Context, gateway, cluster string) ([]byte, error)
+
+// Server implements ConfigService. Subscriptions are told of changes made
+// through this service by its event broker, and recompile the config
+// every resync interval besides to pick up changes made elsewhere, such
+// as by other replicas or through a config rollout ending.
+type Server struct {
+	configv1.UnimplementedConfigServiceServer
+
+	payload  PayloadFunc
+	gateways config.GatewayStore
+	signer   *signing.Signer
+	broker   *events.Broker
+	token    string
+	resync   time.Duration
+	logger   *zap.Logger
+}
+
+// NewServer creates a new Server. Callers must present token, the shared
+// gateway token. gateways may be nil, in which case acknowledgements are
+// answered Unimplemented; signer may be nil, in which case full configs
+// are sent unsigned.
+func NewServer(payload PayloadFunc, gateways config.GatewayStore, signer *signing.Signer, broker *events.Broker, token string, resync time.Duration, logger *zap.Logger) *Server {
+	return &Server{
+		payload:  payload,
+		gateways: gateways,
+		signer:   signer,
+		broker:   broker,
+		token:    token,
+		resync:   resync,
+		logger:   logger,
+	}
+}
+
+// Subscribe sends the full config served to the gateway, then a delta
+// each time it changes, until the gateway disconnects.
+func (s *Server) Subscribe(req *configv1.SubscribeRequest, stream configv1.ConfigService_SubscribeServer) error {
+	ctx := stream.Context()
+	if err := s.authorize(ctx); err != nil {
+		return err
+	}
+	if err := validateGateway(req.Gateway, req.Cluster); err != nil {
+		return err
+	}
+
+	// Subscribe before compiling, so that no change made meanwhile is
+	// missed
+	sub := s.broker.Subscribe(configEvents, subscriptionBuffer)
+	defer func() { s.broker.Unsubscribe(sub) }()
+
+	payload, current, err := s.compile(ctx, req.Gateway, req.Cluster)
+	if err != nil {
+		s.logger.Error("failed to compile config", zap.String("gateway", req.Gateway), zap.Error(err))
+		return serverError(err)
+	}
+	sum := payloadSHA256(payload)
+	update := &configv1.ConfigUpdate{Revision: current.Revision, Config: payload, ConfigSha256: sum}
+	if s.signer != nil {
+		update.Signature = s.signer.Sign(payload)
+		update.SignatureKeyId = s.signer.KeyID()
+	}
+	if err := stream.Send(update); err != nil {
+		return err
+	}
+	s.logger.Info("gateway subscribed to config", zap.String("gateway", req.Gateway), zap.String("cluster", req.Cluster), zap.Uint64("revision", current.Revision))
+
+	ticker := time.NewTicker(s.resync)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, open := <-sub.C:
+			if !open {
+				// The broker dropped us for falling behind; subscribe
+				// again, recompiling anyway
+				sub = s.broker.Subscribe(configEvents, subscriptionBuffer)
+			}
+			drain(sub)
+		case <-ticker.C:
+		}
+
+		payload, next, err := s.compile(ctx, req.Gateway, req.Cluster)
+		if err != nil {
+			if ctx.Err() == nil {
+				// Retried on the next change or resync
+				s.logger.Warn("failed to compile config", zap.String("gateway", req.Gateway), zap.Error(err))
+			}
+			continue
+		}
+		nextSum := payloadSHA256(payload)
+		if nextSum == sum {
+			continue
+		}
+
+		delta, err := compile.Diff(current, next)
+		if err != nil {
+			s.logger.Error("failed to compute config delta", zap.String("gateway", req.Gateway), zap.Error(err))
+			return status.Error(codes.Internal, "internal server error")
+		}
+		encoded, err := json.Marshal(delta)
+		if err != nil {
+			s.logger.Error("failed to encode config delta", zap.String("gateway", req.Gateway), zap.Error(err))
+			return status.Error(codes.Internal, "internal server error")
+		}
+		if err := stream.Send(&configv1.ConfigUpdate{Revision: next.Revision, Delta: encoded, ConfigSha256: nextSum}); err != nil {
+			return err
+		}
+		current, sum = next, nextSum
+	}
+}
+
+// Acknowledge records the revision a gateway has applied, or failed to
+// apply, like a config report over HTTP.
+func (s *Server) Acknowledge(ctx context.Context, req *configv1.AcknowledgeRequest) (*configv1.AcknowledgeResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if s.gateways == nil {
+		return nil, status.Error(codes.Unimplemented, "gateway reports are not supported by this store")
+	}
+	if err := validateGateway(req.Gateway, req.Cluster); err != nil {
+		return nil, err
+	}
+	if req.Error != "" {
+		if req.Revision == 0 || len(req.Error) > maxGatewayFailureLength {
+			return nil, status.Error(codes.InvalidArgument, "a failure needs the revision and an error of at most 512 characters")
+		}
+	} else if !sha256Hex.MatchString(req.ConfigSha256) {
+		return nil, status.Error(codes.InvalidArgument, "config_sha256 must be a lowercase hex SHA-256")
+	}
+
+	payload, err := s.payload(ctx, req.Gateway, req.Cluster)
+	if err != nil {
+		s.logger.Error("failed to compile config", zap.Error(err))
+		return nil, serverError(err)
+	}
+
+	if req.Error != "" {
+		err = s.gateways.ReportGatewayFailure(req.Gateway, req.Cluster, req.Revision, req.Error)
+		s.logger.Warn("gateway failed to apply config", zap.String("gateway", req.Gateway), zap.Uint64("revision", req.Revision), zap.String("error", req.Error))
+	} else {
+		err = s.gateways.ReportGatewayConfig(req.Gateway, req.Cluster, req.ConfigSha256, req.Revision)
+	}
+	if err != nil {
+		s.logger.Error("failed to record gateway config", zap.String("gateway", req.Gateway), zap.Error(err))
+		return nil, serverError(err)
+	}
+
+	expected := payloadSHA256(payload)
+	return &configv1.AcknowledgeResponse{
+		InSync:         req.Error == "" && req.ConfigSha256 == expected,
+		ExpectedSha256: expected,
+	}, nil
+}
+
+// compile returns the encoded config served to the gateway and its
+// decoding.
+func (s *Server) compile(ctx context.Context, gateway, cluster string) ([]byte, *compile.Config, error) {
+	payload, err := s.payload(ctx, gateway, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cfg compile.Config
+	if err := json.Unmarshal(payload, &cfg); err != nil {
+		return nil, nil, err
+	}
+	return payload, &cfg, nil
+}
+
+// authorize checks that the call carries the gateway token as a bearer
+// token.
+func (s *Server) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		presented, ok := strings.CutPrefix(value, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(presented), []byte(s.token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+// validateGateway checks the instance ID and cluster a gateway calls with.
+func validateGateway(gateway, cluster string) error {
+	if gateway == "" || len(gateway) > maxGatewayIDLength {
+		return status.Error(codes.InvalidArgument, "gateway is required (at most 128 characters)")
+	}
+	if cluster != "" {
+		if err := config.ValidateClusterName(cluster); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	return nil
+}
+
+// drain discards the events pending on sub, which one recompile covers.
+func drain(sub *events.Subscription) {
+	for {
+		select {
+		case _, open := <-sub.C:
+			if !open {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// payloadSHA256 returns the hex SHA-256 of a gateway config payload.
+func payloadSHA256(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// serverError is the status of a call that failed with err through no
+// fault of the caller: Unavailable while the store's circuit breaker is
+// open, so that gateways retry, and Internal otherwise.
+func serverError(err error) error {
+	if errors.Is(err, config.ErrUnavailable) {
+		return status.Error(codes.Unavailable, "database unavailable")
+	}
+	return status.Error(codes.Internal, "internal server error")
+}
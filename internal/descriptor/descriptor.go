@@ -0,0 +1,281 @@
+// Package descriptor derives gateway routes from gRPC service descriptors.
+package descriptor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// DefaultTimeoutMS is the timeout of proposed routes.
+const DefaultTimeoutMS = 5000
+
+// Sources of a proposed route.
+const (
+	// SourceAnnotation routes follow a google.api.http rule of the method.
+	SourceAnnotation = "http_annotation"
+	// SourceDefault routes use POST /<package.Service>/<Method>, the path
+	// of the method in gRPC itself.
+	SourceDefault = "default"
+)
+
+// maxDescription is the length of the route description column.
+const maxDescription = 255
+
+// Proposal is a route proposed for one binding of an RPC. It is not stored
+// until created.
+type Proposal struct {
+	config.Route
+	Source string `json:"source"`
+	// ExistingRouteID is set if a route with the same method and pattern
+	// is already configured.
+	ExistingRouteID *uint `json:"existing_route_id,omitempty"`
+}
+
+// Skipped is an RPC for which no route is proposed.
+type Skipped struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+	Reason  string `json:"reason"`
+}
+
+// ParseSet decodes a serialized FileDescriptorSet, as written by
+// protoc --descriptor_set_out. Files must be given with their imports
+// (--include_imports), although unresolvable ones are tolerated.
+func ParseSet(data []byte) (*protoregistry.Files, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("invalid FileDescriptorSet: %w", err)
+	}
+	return NewFiles(set)
+}
+
+// NewFiles builds a registry of the files in set.
+func NewFiles(set *descriptorpb.FileDescriptorSet) (*protoregistry.Files, error) {
+	if len(set.GetFile()) == 0 {
+		return nil, fmt.Errorf("FileDescriptorSet contains no files")
+	}
+	files, err := protodesc.FileOptions{AllowUnresolvable: true}.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FileDescriptorSet: %w", err)
+	}
+	return files, nil
+}
+
+// Propose returns a route for every binding of every unary RPC in files,
+// calling backend, ordered by service and method. Streaming RPCs cannot be
+// called through the gateway and are skipped, as are gRPC's own services
+// (grpc.*).
+func Propose(files *protoregistry.Files, backend string) ([]Proposal, []Skipped) {
+	var services []protoreflect.ServiceDescriptor
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		for i := 0; i < fd.Services().Len(); i++ {
+			services = append(services, fd.Services().Get(i))
+		}
+		return true
+	})
+	sort.Slice(services, func(i, j int) bool { return services[i].FullName() < services[j].FullName() })
+
+	proposals := []Proposal{}
+	skipped := []Skipped{}
+	for _, sd := range services {
+		service := string(sd.FullName())
+		if strings.HasPrefix(service, "grpc.") {
+			continue
+		}
+		for i := 0; i < sd.Methods().Len(); i++ {
+			md := sd.Methods().Get(i)
+			if md.IsStreamingClient() || md.IsStreamingServer() {
+				skipped = append(skipped, Skipped{Service: service, Method: string(md.Name()), Reason: "streaming RPC"})
+				continue
+			}
+
+			route := config.Route{
+				BackendName:    backend,
+				BackendService: service,
+				BackendMethod:  string(md.Name()),
+				TimeoutMS:      DefaultTimeoutMS,
+				Description:    comment(md),
+				Enabled:        true,
+			}
+			rules := httpRules(md)
+			if len(rules) == 0 {
+				route.HTTPMethod = "POST"
+				route.HTTPPattern = "/" + service + "/" + string(md.Name())
+				proposals = append(proposals, Proposal{Route: route, Source: SourceDefault})
+				continue
+			}
+			for _, rule := range rules {
+				method, path, ok := binding(rule)
+				if !ok {
+					skipped = append(skipped, Skipped{Service: service, Method: string(md.Name()), Reason: "google.api.http rule without a path"})
+					continue
+				}
+				route.HTTPMethod = method
+				route.HTTPPattern = Pattern(path)
+				proposals = append(proposals, Proposal{Route: route, Source: SourceAnnotation})
+			}
+		}
+	}
+	return proposals, skipped
+}
+
+// httpRules returns the google.api.http rule of a method followed by its
+// additional bindings, or nil if it has none.
+func httpRules(md protoreflect.MethodDescriptor) []*annotations.HttpRule {
+	opts, ok := md.Options().(*descriptorpb.MethodOptions)
+	if !ok || opts == nil || !proto.HasExtension(opts, annotations.E_Http) {
+		return nil
+	}
+	rule, ok := proto.GetExtension(opts, annotations.E_Http).(*annotations.HttpRule)
+	if !ok || rule == nil {
+		return nil
+	}
+	return append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...)
+}
+
+// binding returns the HTTP method and path template of a rule.
+func binding(rule *annotations.HttpRule) (method, path string, ok bool) {
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		method, path = "GET", p.Get
+	case *annotations.HttpRule_Put:
+		method, path = "PUT", p.Put
+	case *annotations.HttpRule_Post:
+		method, path = "POST", p.Post
+	case *annotations.HttpRule_Delete:
+		method, path = "DELETE", p.Delete
+	case *annotations.HttpRule_Patch:
+		method, path = "PATCH", p.Patch
+	case *annotations.HttpRule_Custom:
+		method, path = strings.ToUpper(p.Custom.GetKind()), p.Custom.GetPath()
+	}
+	return method, path, method != "" && path != ""
+}
+
+// segmentVar matches a path variable with a segment pattern, as in
+// {name=projects/*}.
+var segmentVar = regexp.MustCompile(`\{([^}=]+)=[^}]*\}`)
+
+// Pattern converts a google.api.http path template to a gateway route
+// pattern: variables keep only their field path, so {name=projects/*}
+// becomes {name}. Custom verbs such as :batchGet are kept.
+func Pattern(template string) string {
+	return segmentVar.ReplaceAllString(template, "{$1}")
+}
+
+// comment returns the leading comment of a method, if the descriptor was
+// built with source info, trimmed to fit a route description.
+func comment(md protoreflect.MethodDescriptor) string {
+	loc := md.ParentFile().SourceLocations().ByDescriptor(md)
+	text := strings.Join(strings.Fields(loc.LeadingComments), " ")
+	if runes := []rune(text); len(runes) > maxDescription {
+		text = strings.TrimSpace(string(runes[:maxDescription-3])) + "..."
+	}
+	return text
+}
+
+// Services returns the fully qualified names of the services in files,
+// other than gRPC's own, sorted.
+func Services(files *protoregistry.Files) []string {
+	services := []string{}
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		for i := 0; i < fd.Services().Len(); i++ {
+			if name := string(fd.Services().Get(i).FullName()); !strings.HasPrefix(name, "grpc.") {
+				services = append(services, name)
+			}
+		}
+		return true
+	})
+	sort.Strings(services)
+	return services
+}
+
+// Method finds a unary RPC in files, returning an error that says what is
+// wrong if there is none.
+func Method(files *protoregistry.Files, service, method string) (protoreflect.MethodDescriptor, error) {
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("service %s is not defined", service)
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("service %s has no method %s", service, method)
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, fmt.Errorf("%s/%s is a streaming RPC", service, method)
+	}
+	return md, nil
+}
+
+// Field finds the field at path, e.g. "page.size", in msg. Fields on the
+// way must be singular messages; the field itself must be a scalar, an
+// enum or a repeated scalar, as query parameters can only set those.
+func Field(msg protoreflect.MessageDescriptor, path string) (protoreflect.FieldDescriptor, error) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := msg.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return nil, fmt.Errorf("message %s has no field %s", msg.FullName(), name)
+		}
+		if i < len(names)-1 {
+			if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+				return nil, fmt.Errorf("field %s of %s is not a singular message", name, msg.FullName())
+			}
+			msg = fd.Message()
+			continue
+		}
+		if fd.IsMap() || fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+			return nil, fmt.Errorf("field %s of %s is not a scalar", name, msg.FullName())
+		}
+		return fd, nil
+	}
+	return nil, fmt.Errorf("empty field path")
+}
+
+// CheckValue returns an error if s cannot be parsed as a value of fd, as
+// gateways parse query parameters.
+func CheckValue(fd protoreflect.FieldDescriptor, s string) error {
+	var err error
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		_, err = strconv.ParseBool(s)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		_, err = strconv.ParseInt(s, 10, 32)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		_, err = strconv.ParseInt(s, 10, 64)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		_, err = strconv.ParseUint(s, 10, 32)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		_, err = strconv.ParseUint(s, 10, 64)
+	case protoreflect.FloatKind:
+		_, err = strconv.ParseFloat(s, 32)
+	case protoreflect.DoubleKind:
+		_, err = strconv.ParseFloat(s, 64)
+	case protoreflect.EnumKind:
+		if fd.Enum().Values().ByName(protoreflect.Name(s)) == nil {
+			if _, nerr := strconv.ParseInt(s, 10, 32); nerr != nil {
+				return fmt.Errorf("%q is not a value of enum %s", s, fd.Enum().FullName())
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%q is not a valid %s", s, fd.Kind())
+	}
+	return nil
+}
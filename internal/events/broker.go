@@ -0,0 +1,123 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	TypeBackend    = "backend"
+	TypeRoute      = "route"
+	TypeDescriptor = "descriptor"
+	TypeSchema     = "schema"
+	TypeHealth     = "health"
+	TypeAnomaly    = "anomaly"
+)
+
+// Event is a change notification pushed to live subscribers.
+type Event struct {
+	Type      string `json:"type"`      // "backend", "route", "descriptor", "schema", "health" or "anomaly"
+	Operation string `json:"operation"` // "CREATE", "UPDATE", "DELETE", a health status or an anomaly kind
+	ID        *uint  `json:"id,omitempty"`
+	// Backend is the backend the change concerns: the backend itself, or
+	// the backend a route points at or a descriptor set belongs to.
+	// Subscribers filter on it.
+	Backend  string          `json:"backend,omitempty"`
+	Operator string          `json:"operator,omitempty"`
+	Reason   string          `json:"reason,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	// Revision is the global config revision a config change committed;
+	// health events have none.
+	Revision uint64    `json:"revision,omitempty"`
+	Time     time.Time `json:"time"`
+
+	// Old is the value before an update, for in-process subscribers that
+	// summarise changes; it is not sent to clients.
+	Old json.RawMessage `json:"-"`
+}
+
+// Filter selects the events a subscriber receives. Empty sets match
+// everything.
+type Filter struct {
+	Types    map[string]bool
+	Backends map[string]bool
+}
+
+// Match reports whether e passes the filter.
+func (f Filter) Match(e Event) bool {
+	if len(f.Types) > 0 && !f.Types[e.Type] {
+		return false
+	}
+	if len(f.Backends) > 0 && !f.Backends[e.Backend] {
+		return false
+	}
+	return true
+}
+
+// Subscription receives matching events on C until it is closed, either by
+// Broker.Unsubscribe or because the subscriber fell too far behind.
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	filter Filter
+}
+
+// Broker fans events out to subscribers. Publishing never blocks: a
+// subscriber whose buffer is full is dropped and its channel closed.
+type Broker struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// NewBroker creates an empty Broker.
+func NewBroker() *Broker {
+	return &Broker{subs: map[*Subscription]struct{}{}}
+}
+
+// Subscribe registers a subscriber with room for buffer pending events.
+func (b *Broker) Subscribe(filter Filter, buffer int) *Subscription {
+	ch := make(chan Event, buffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Unsubscribe removes sub and closes its channel. It is safe to call more
+// than once.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Publish delivers e to every matching subscriber.
+func (b *Broker) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if !sub.filter.Match(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// NotifyingStore wraps a Store and publishes an event for every history
+// record it writes. Every API, apply and GitOps change records history, so
+// this covers all configuration changes without touching the callers.
+// Changes made inside InTx are published only after the commit.
+type NotifyingStore struct {
+	config.Store
+	broker *Broker
+	outbox bool
+}
+
+// NewNotifyingStore creates a NotifyingStore publishing to broker.
+func NewNotifyingStore(store config.Store, broker *Broker) *NotifyingStore {
+	return &NotifyingStore{Store: store, broker: broker}
+}
+
+// RecordOutbox makes s also record every event in the store's outbox, in
+// the transaction of the change, for reliable delivery to sinks that must
+// not miss any. The store must implement config.OutboxStore.
+func (s *NotifyingStore) RecordOutbox() {
+	s.outbox = true
+}
+
+// CreateHistory implements config.Store.
+func (s *NotifyingStore) CreateHistory(history *config.ConfigHistory) error {
+	if s.outbox {
+		// The event must be recorded in a transaction with the change
+		return s.InTx(func(tx config.Store) error {
+			return tx.CreateHistory(history)
+		})
+	}
+	if err := s.Store.CreateHistory(history); err != nil {
+		return err
+	}
+	s.broker.Publish(FromHistory(history))
+	return nil
+}
+
+// InTx implements config.Store.
+func (s *NotifyingStore) InTx(fn func(tx config.Store) error) error {
+	var pending []Event
+	err := s.Store.InTx(func(tx config.Store) error {
+		// The transaction may be run again
+		pending = pending[:0]
+		return fn(&pendingStore{Store: tx, pending: &pending, outbox: s.outbox})
+	})
+	if err != nil {
+		return err
+	}
+	for _, e := range pending {
+		s.broker.Publish(e)
+	}
+	return nil
+}
+
+// pendingStore collects events for changes made inside a transaction,
+// recording them in the outbox if enabled.
+type pendingStore struct {
+	config.Store
+	pending *[]Event
+	outbox  bool
+}
+
+func (s *pendingStore) CreateHistory(history *config.ConfigHistory) error {
+	if err := s.Store.CreateHistory(history); err != nil {
+		return err
+	}
+	e := FromHistory(history)
+	e.Time = time.Now()
+	if s.outbox {
+		payload, err := EncodeOutbox(e)
+		if err != nil {
+			return err
+		}
+		if err := s.Store.(config.OutboxStore).AppendOutbox(&config.OutboxEntry{Revision: history.Revision, Payload: payload}); err != nil {
+			return err
+		}
+	}
+	*s.pending = append(*s.pending, e)
+	return nil
+}
+
+func (s *pendingStore) InTx(fn func(tx config.Store) error) error {
+	return fn(s)
+}
+
+// FromHistory converts a history record into an event. The recorded value
+// is already redacted, so it is safe to push to clients as-is.
+func FromHistory(h *config.ConfigHistory) Event {
+	e := Event{
+		Type:      h.ConfigType,
+		Operation: h.Operation,
+		ID:        h.ConfigID,
+		Operator:  h.Operator,
+		Reason:    h.Reason,
+		Data:      h.NewValue,
+		Revision:  h.Revision,
+	}
+	if e.Data == nil {
+		e.Data = h.OldValue
+	} else {
+		e.Old = h.OldValue
+	}
+
+	var ref struct {
+		Name        string `json:"name"`
+		BackendName string `json:"backend_name"`
+	}
+	if len(e.Data) > 0 && json.Unmarshal(e.Data, &ref) == nil {
+		if h.ConfigType == TypeRoute || h.ConfigType == TypeDescriptor {
+			e.Backend = ref.BackendName
+		} else {
+			e.Backend = ref.Name
+		}
+	}
+	return e
+}
+
+// outboxEvent is the encoding of an event in the outbox, which unlike the
+// one sent to clients keeps the old value.
+type outboxEvent struct {
+	Event
+	Old json.RawMessage `json:"old,omitempty"`
+}
+
+// EncodeOutbox encodes an event for the outbox.
+func EncodeOutbox(e Event) (json.RawMessage, error) {
+	return json.Marshal(outboxEvent{Event: e, Old: e.Old})
+}
+
+// DecodeOutbox decodes an event recorded by EncodeOutbox.
+func DecodeOutbox(payload json.RawMessage) (Event, error) {
+	var oe outboxEvent
+	if err := json.Unmarshal(payload, &oe); err != nil {
+		return Event{}, err
+	}
+	e := oe.Event
+	e.Old = oe.Old
+	return e, nil
+}
@@ -0,0 +1,266 @@
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/apply"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// operator is recorded in config_history for changes made by the syncer.
+const operator = "gitops"
+
+// Options configures a Syncer.
+type Options struct {
+	// RepoURL is the Git remote to clone.
+	RepoURL string
+	// Branch is the branch to track.
+	Branch string
+	// Path is the directory within the repository holding config files.
+	Path string
+	// WorkDir is the local checkout directory.
+	WorkDir string
+	// Interval is the polling period; zero disables polling.
+	Interval time.Duration
+	// WebhookSecret, if set, is required to sign webhook triggers.
+	WebhookSecret string
+	// Paused, if set, is consulted before each sync; syncs are skipped
+	// while it returns true (e.g. in read-only mode).
+	Paused func() bool
+	// OnSync, if set, is called after every sync that was not skipped with
+	// the resulting status and error.
+	OnSync func(status Status, err error)
+}
+
+// Status describes the outcome of the most recent sync.
+type Status struct {
+	RepoURL    string               `json:"repo_url"`
+	Branch     string               `json:"branch"`
+	Path       string               `json:"path"`
+	LastCommit string               `json:"last_commit,omitempty"`
+	LastSyncAt *time.Time           `json:"last_sync_at,omitempty"`
+	LastResult string               `json:"last_result,omitempty"` // "success", "error" or "skipped"
+	LastError  string               `json:"last_error,omitempty"`
+	Summary    map[apply.Action]int `json:"summary,omitempty"`
+	Syncing    bool                 `json:"syncing"`
+}
+
+// Syncer keeps the store in line with a Git repository of YAML config by
+// periodically pulling it and running the declarative apply pipeline.
+type Syncer struct {
+	opts      Options
+	store     config.Store
+	admission admission.Controller
+	logger    *zap.Logger
+	trigger   chan struct{}
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewSyncer creates a new Syncer.
+func NewSyncer(opts Options, store config.Store, admission admission.Controller, logger *zap.Logger) *Syncer {
+	if opts.Branch == "" {
+		opts.Branch = "main"
+	}
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if opts.WorkDir == "" {
+		opts.WorkDir = filepath.Join(os.TempDir(), "admin-gitops")
+	}
+
+	return &Syncer{
+		opts:      opts,
+		store:     store,
+		admission: admission,
+		logger:    logger,
+		trigger:   make(chan struct{}, 1),
+		status: Status{
+			RepoURL: opts.RepoURL,
+			Branch:  opts.Branch,
+			Path:    opts.Path,
+		},
+	}
+}
+
+// WebhookSecret returns the secret webhook triggers must be signed with.
+func (s *Syncer) WebhookSecret() string {
+	return s.opts.WebhookSecret
+}
+
+// Status returns a copy of the current sync status.
+func (s *Syncer) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Trigger requests an immediate sync. It never blocks; concurrent
+// triggers are coalesced.
+func (s *Syncer) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run syncs once at startup and then on every interval tick or trigger
+// until ctx is cancelled.
+func (s *Syncer) Run(ctx context.Context) {
+	var tick <-chan time.Time
+	if s.opts.Interval > 0 {
+		ticker := time.NewTicker(s.opts.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	s.sync(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-s.trigger:
+		}
+		s.sync(ctx)
+	}
+}
+
+// sync performs a single pull-and-apply cycle and records its outcome.
+func (s *Syncer) sync(ctx context.Context) {
+	if s.opts.Paused != nil && s.opts.Paused() {
+		now := time.Now()
+		s.mu.Lock()
+		s.status.LastSyncAt = &now
+		s.status.LastResult = "skipped"
+		s.status.LastError = "sync paused"
+		s.mu.Unlock()
+		return
+	}
+
+	s.mu.Lock()
+	s.status.Syncing = true
+	s.mu.Unlock()
+
+	commit, plan, err := s.syncOnce(ctx)
+
+	status := s.record(commit, plan, err)
+	if s.opts.OnSync != nil {
+		s.opts.OnSync(status, err)
+	}
+}
+
+// record stores the outcome of a sync and returns the new status.
+func (s *Syncer) record(commit string, plan *apply.Plan, err error) Status {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Syncing = false
+	s.status.LastSyncAt = &now
+	if commit != "" {
+		s.status.LastCommit = commit
+	}
+	if err != nil {
+		s.status.LastResult = "error"
+		s.status.LastError = err.Error()
+		s.logger.Error("gitops sync failed", zap.String("commit", commit), zap.Error(err))
+		return s.status
+	}
+	s.status.LastResult = "success"
+	s.status.LastError = ""
+	s.status.Summary = plan.Summary
+	if plan.HasChanges() {
+		s.logger.Info("gitops sync applied changes", zap.String("commit", commit), zap.Any("summary", plan.Summary))
+	}
+	return s.status
+}
+
+func (s *Syncer) syncOnce(ctx context.Context) (string, *apply.Plan, error) {
+	if err := s.pull(ctx); err != nil {
+		return "", nil, err
+	}
+
+	commit, err := s.git(ctx, s.opts.WorkDir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", nil, err
+	}
+
+	doc, err := apply.LoadDir(filepath.Join(s.opts.WorkDir, s.opts.Path))
+	if err != nil {
+		return commit, nil, err
+	}
+	if err := doc.Validate(); err != nil {
+		return commit, nil, err
+	}
+
+	plan, err := apply.ComputePlan(s.store, doc)
+	if err != nil {
+		return commit, nil, err
+	}
+	if plan.HasChanges() {
+		op := operator + "@" + shortCommit(commit)
+		subject, err := s.git(ctx, s.opts.WorkDir, "log", "-1", "--format=%s")
+		if err != nil {
+			return commit, nil, err
+		}
+		reason := "gitops sync: " + subject
+		actor := apply.Actor{Operator: op, Reason: reason}
+		if err := apply.Admit(ctx, s.admission, plan, actor); err != nil {
+			return commit, nil, err
+		}
+		if err := apply.Execute(s.store, plan, actor); err != nil {
+			return commit, nil, err
+		}
+	}
+
+	return commit, plan, nil
+}
+
+// pull clones the repository on first use and fast-forwards it afterwards.
+func (s *Syncer) pull(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(s.opts.WorkDir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(s.opts.WorkDir), 0o755); err != nil {
+			return err
+		}
+		_, err := s.git(ctx, "", "clone", "--depth", "1", "--branch", s.opts.Branch, s.opts.RepoURL, s.opts.WorkDir)
+		return err
+	}
+
+	if _, err := s.git(ctx, s.opts.WorkDir, "fetch", "--depth", "1", "origin", s.opts.Branch); err != nil {
+		return err
+	}
+	_, err := s.git(ctx, s.opts.WorkDir, "reset", "--hard", "FETCH_HEAD")
+	return err
+}
+
+// git runs a git command and returns its trimmed stdout.
+func (s *Syncer) git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -0,0 +1,564 @@
+// Package graphqlapi exposes configuration and history as a read-only
+// GraphQL schema.
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// NewSchema builds the schema. Field names match the JSON fields of the
+// REST API; secrets are never exposed, only whether they are set.
+func NewSchema() (graphql.Schema, error) {
+	credentialType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BackendCredential",
+		Fields: graphql.Fields{
+			"type":       &graphql.Field{Type: graphql.String},
+			"username":   &graphql.Field{Type: graphql.String},
+			"secret_ref": &graphql.Field{Type: graphql.String},
+			"has_secret": &graphql.Field{
+				Type: graphql.Boolean,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*config.BackendCredential).Secret != "", nil
+				},
+			},
+		},
+	})
+
+	tlsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BackendTLS",
+		Fields: graphql.Fields{
+			"server_name":          &graphql.Field{Type: graphql.String},
+			"ca_cert":              &graphql.Field{Type: graphql.String},
+			"client_cert":          &graphql.Field{Type: graphql.String},
+			"insecure_skip_verify": &graphql.Field{Type: graphql.Boolean},
+			"has_client_key": &graphql.Field{
+				Type: graphql.Boolean,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*config.BackendTLS).ClientKey != "", nil
+				},
+			},
+		},
+	})
+
+	bodyTemplateType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BodyTemplate",
+		Fields: graphql.Fields{
+			"language": &graphql.Field{Type: graphql.String},
+			"template": &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	transformType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RouteTransform",
+		Fields: graphql.Fields{
+			"request":  &graphql.Field{Type: bodyTemplateType},
+			"response": &graphql.Field{Type: bodyTemplateType},
+		},
+	})
+
+	circuitBreakerType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CircuitBreaker",
+		Fields: graphql.Fields{
+			"max_requests":         &graphql.Field{Type: graphql.Int},
+			"consecutive_5xx":      &graphql.Field{Type: graphql.Int},
+			"ejection_seconds":     &graphql.Field{Type: graphql.Int},
+			"max_ejection_percent": &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	rolloutType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RouteRollout",
+		Fields: graphql.Fields{
+			"percent":  &graphql.Field{Type: graphql.Int},
+			"hash_key": &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	variantMatchType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "VariantMatch",
+		Fields: graphql.Fields{
+			"header": &graphql.Field{Type: graphql.String},
+			"value":  &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	variantType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RouteVariant",
+		Fields: graphql.Fields{
+			"name":            &graphql.Field{Type: graphql.String},
+			"backend_name":    &graphql.Field{Type: graphql.String},
+			"backend_service": &graphql.Field{Type: graphql.String},
+			"backend_method":  &graphql.Field{Type: graphql.String},
+			"percent":         &graphql.Field{Type: graphql.Int},
+			"match":           &graphql.Field{Type: variantMatchType},
+		},
+	})
+
+	experimentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RouteExperiment",
+		Fields: graphql.Fields{
+			"hash_key": &graphql.Field{Type: graphql.String},
+			"variants": &graphql.Field{Type: graphql.NewList(variantType)},
+		},
+	})
+
+	mirrorType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RouteMirror",
+		Fields: graphql.Fields{
+			"backend_name":    &graphql.Field{Type: graphql.String},
+			"backend_service": &graphql.Field{Type: graphql.String},
+			"backend_method":  &graphql.Field{Type: graphql.String},
+			"percent":         &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	fallbackType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RouteFallback",
+		Fields: graphql.Fields{
+			"backend_name":    &graphql.Field{Type: graphql.String},
+			"backend_service": &graphql.Field{Type: graphql.String},
+			"backend_method":  &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	deprecationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RouteDeprecation",
+		Fields: graphql.Fields{
+			"date":   &graphql.Field{Type: graphql.DateTime},
+			"sunset": &graphql.Field{Type: graphql.DateTime},
+			"link":   &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	docsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RouteDocs",
+		Fields: graphql.Fields{
+			"summary":          &graphql.Field{Type: graphql.String},
+			"request_example":  &graphql.Field{Type: jsonScalar},
+			"response_example": &graphql.Field{Type: jsonScalar},
+			"external_url":     &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	metadataHeaderType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MetadataHeader",
+		Fields: graphql.Fields{
+			"header": &graphql.Field{Type: graphql.String},
+			"key":    &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	grpcType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RouteGRPC",
+		Fields: graphql.Fields{
+			"propagate_deadline": &graphql.Field{Type: graphql.Boolean},
+			"headers":            &graphql.Field{Type: graphql.NewList(metadataHeaderType)},
+			"allow":              &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"deny":               &graphql.Field{Type: graphql.NewList(graphql.String)},
+		},
+	})
+
+	middlewareType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RouteMiddleware",
+		Fields: graphql.Fields{
+			"name":   &graphql.Field{Type: graphql.String},
+			"params": &graphql.Field{Type: jsonScalar},
+		},
+	})
+
+	queryParamType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "QueryParam",
+		Fields: graphql.Fields{
+			"name":     &graphql.Field{Type: graphql.String},
+			"field":    &graphql.Field{Type: graphql.String},
+			"default":  &graphql.Field{Type: graphql.String},
+			"required": &graphql.Field{Type: graphql.Boolean},
+		},
+	})
+
+	cacheType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RouteCache",
+		Fields: graphql.Fields{
+			"ttl_seconds":  &graphql.Field{Type: graphql.Int},
+			"vary_headers": &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"allow_post":   &graphql.Field{Type: graphql.Boolean},
+		},
+	})
+
+	backendType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Backend",
+		Fields: graphql.Fields{
+			"id":              &graphql.Field{Type: graphql.Int},
+			"name":            &graphql.Field{Type: graphql.String},
+			"addr":            &graphql.Field{Type: graphql.String},
+			"description":     &graphql.Field{Type: graphql.String},
+			"clusters":        &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"enabled":         &graphql.Field{Type: graphql.Boolean},
+			"status":          &graphql.Field{Type: graphql.String},
+			"credential":      &graphql.Field{Type: credentialType},
+			"tls":             &graphql.Field{Type: tlsType},
+			"circuit_breaker": &graphql.Field{Type: circuitBreakerType},
+			"plugins":         &graphql.Field{Type: jsonScalar},
+			"owner_team":      &graphql.Field{Type: graphql.String},
+			"revision":        &graphql.Field{Type: graphql.Int},
+			"created_at":      &graphql.Field{Type: graphql.DateTime},
+			"updated_at":      &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	routeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Route",
+		Fields: graphql.Fields{
+			"id":                &graphql.Field{Type: graphql.Int},
+			"http_method":       &graphql.Field{Type: graphql.String},
+			"http_pattern":      &graphql.Field{Type: graphql.String},
+			"backend_name":      &graphql.Field{Type: graphql.String},
+			"backend_service":   &graphql.Field{Type: graphql.String},
+			"backend_method":    &graphql.Field{Type: graphql.String},
+			"timeout_ms":        &graphql.Field{Type: graphql.Int},
+			"max_request_bytes": &graphql.Field{Type: graphql.Int},
+			"content_types":     &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"description":       &graphql.Field{Type: graphql.String},
+			"clusters":          &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"api_version":       &graphql.Field{Type: graphql.String},
+			"lifecycle":         &graphql.Field{Type: graphql.String},
+			"deprecation":       &graphql.Field{Type: deprecationType},
+			"docs":              &graphql.Field{Type: docsType},
+			"transform":         &graphql.Field{Type: transformType},
+			"cache":             &graphql.Field{Type: cacheType},
+			"circuit_breaker":   &graphql.Field{Type: circuitBreakerType},
+			"rollout":           &graphql.Field{Type: rolloutType},
+			"experiment":        &graphql.Field{Type: experimentType},
+			"mirror":            &graphql.Field{Type: mirrorType},
+			"fallback":          &graphql.Field{Type: fallbackType},
+			"grpc":              &graphql.Field{Type: grpcType},
+			"middlewares":       &graphql.Field{Type: graphql.NewList(middlewareType)},
+			"plugins":           &graphql.Field{Type: jsonScalar},
+			"query_params":      &graphql.Field{Type: graphql.NewList(queryParamType)},
+			"owner_team":        &graphql.Field{Type: graphql.String},
+			"enabled":           &graphql.Field{Type: graphql.Boolean},
+			"revision":          &graphql.Field{Type: graphql.Int},
+			"created_at":        &graphql.Field{Type: graphql.DateTime},
+			"updated_at":        &graphql.Field{Type: graphql.DateTime},
+			"backend": &graphql.Field{
+				Type: backendType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loaderFrom(p.Context).backend(p.Source.(*config.Route).BackendName)
+				},
+			},
+		},
+	})
+
+	// Routes are added after the route type exists to allow the cycle
+	backendType.AddFieldConfig("routes", &graphql.Field{
+		Type: graphql.NewList(routeType),
+		Args: graphql.FieldConfigArgument{
+			"enabled": &graphql.ArgumentConfig{Type: graphql.Boolean},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return loaderFrom(p.Context).routesFor(p.Source.(*config.Backend).Name, boolArg(p, "enabled"))
+		},
+	})
+
+	historyType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ConfigHistory",
+		Fields: graphql.Fields{
+			"id":              &graphql.Field{Type: graphql.Int},
+			"config_type":     &graphql.Field{Type: graphql.String},
+			"config_id":       &graphql.Field{Type: graphql.Int},
+			"operation":       &graphql.Field{Type: graphql.String},
+			"old_value":       &graphql.Field{Type: jsonScalar},
+			"new_value":       &graphql.Field{Type: jsonScalar},
+			"operator":        &graphql.Field{Type: graphql.String},
+			"change_reason":   &graphql.Field{Type: graphql.String},
+			"freeze_override": &graphql.Field{Type: graphql.Boolean},
+			"revision":        &graphql.Field{Type: graphql.Int},
+			"created_at":      &graphql.Field{Type: graphql.DateTime},
+			"backend": &graphql.Field{
+				Type:        backendType,
+				Description: "Current state of the backend the entry refers to",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					h := p.Source.(*config.ConfigHistory)
+					if h.ConfigType != "backend" {
+						return nil, nil
+					}
+					return loaderFrom(p.Context).backend(historyName(h))
+				},
+			},
+			"route": &graphql.Field{
+				Type:        routeType,
+				Description: "Current state of the route the entry refers to",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					h := p.Source.(*config.ConfigHistory)
+					if h.ConfigType != "route" || h.ConfigID == nil {
+						return nil, nil
+					}
+					return loaderFrom(p.Context).route(*h.ConfigID)
+				},
+			},
+		},
+	})
+
+	historyPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "HistoryPage",
+		Fields: graphql.Fields{
+			"total": &graphql.Field{Type: graphql.Int},
+			"items": &graphql.Field{Type: graphql.NewList(historyType)},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"backends": &graphql.Field{
+				Type: graphql.NewList(backendType),
+				Args: graphql.FieldConfigArgument{
+					"enabled": &graphql.ArgumentConfig{Type: graphql.Boolean},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loaderFrom(p.Context).backends(boolArg(p, "enabled"))
+				},
+			},
+			"backend": &graphql.Field{
+				Type: backendType,
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loaderFrom(p.Context).backend(p.Args["name"].(string))
+				},
+			},
+			"routes": &graphql.Field{
+				Type: graphql.NewList(routeType),
+				Args: graphql.FieldConfigArgument{
+					"enabled":     &graphql.ArgumentConfig{Type: graphql.Boolean},
+					"backend":     &graphql.ArgumentConfig{Type: graphql.String},
+					"api_version": &graphql.ArgumentConfig{Type: graphql.String},
+					"lifecycle":   &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					l := loaderFrom(p.Context)
+					var routes []*config.Route
+					var err error
+					if name, ok := p.Args["backend"].(string); ok {
+						routes, err = l.routesFor(name, boolArg(p, "enabled"))
+					} else {
+						routes, err = l.routes(boolArg(p, "enabled"))
+					}
+					if err != nil {
+						return nil, err
+					}
+					version, hasVersion := p.Args["api_version"].(string)
+					lifecycle, hasLifecycle := p.Args["lifecycle"].(string)
+					out := routes[:0]
+					for _, r := range routes {
+						if (!hasVersion || r.APIVersion == version) && (!hasLifecycle || r.Lifecycle == lifecycle) {
+							out = append(out, r)
+						}
+					}
+					return out, nil
+				},
+			},
+			"route": &graphql.Field{
+				Type: routeType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loaderFrom(p.Context).route(uint(p.Args["id"].(int)))
+				},
+			},
+			"history": &graphql.Field{
+				Type: historyPageType,
+				Args: graphql.FieldConfigArgument{
+					"config_type": &graphql.ArgumentConfig{Type: graphql.String},
+					"config_id":   &graphql.ArgumentConfig{Type: graphql.Int},
+					"limit":       &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 50},
+					"offset":      &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var configType *string
+					if v, ok := p.Args["config_type"].(string); ok {
+						configType = &v
+					}
+					var configID *uint
+					if v, ok := p.Args["config_id"].(int); ok {
+						id := uint(v)
+						configID = &id
+					}
+					limit := p.Args["limit"].(int)
+					if limit < 1 || limit > 1000 {
+						limit = 50
+					}
+					offset := p.Args["offset"].(int)
+					if offset < 0 {
+						offset = 0
+					}
+
+					items, total, err := loaderFrom(p.Context).store.GetHistory(configType, configID, limit, offset)
+					if err != nil {
+						return nil, err
+					}
+					page := map[string]interface{}{"total": total}
+					entries := make([]*config.ConfigHistory, len(items))
+					for i := range items {
+						entries[i] = &items[i]
+					}
+					page["items"] = entries
+					return page, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// jsonScalar passes stored JSON values and other JSON-encodable values
+// through as structured data.
+var jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "Arbitrary JSON value",
+	Serialize: func(value interface{}) interface{} {
+		raw, ok := value.(json.RawMessage)
+		if !ok {
+			var err error
+			if raw, err = json.Marshal(value); err != nil {
+				return nil
+			}
+		}
+		if len(raw) == 0 {
+			return nil
+		}
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil
+		}
+		return v
+	},
+})
+
+func boolArg(p graphql.ResolveParams, name string) *bool {
+	if v, ok := p.Args[name].(bool); ok {
+		return &v
+	}
+	return nil
+}
+
+// historyName returns the backend name recorded in a history entry.
+func historyName(h *config.ConfigHistory) string {
+	value := h.NewValue
+	if len(value) == 0 {
+		value = h.OldValue
+	}
+	var ref struct {
+		Name string `json:"name"`
+	}
+	json.Unmarshal(value, &ref)
+	return ref.Name
+}
+
+type loaderKey struct{}
+
+// WithLoader returns a context carrying a per-request loader for store.
+// The loader caches backends and routes so that nested fields do not query
+// the store once per parent object.
+func WithLoader(ctx context.Context, store config.Store) context.Context {
+	return context.WithValue(ctx, loaderKey{}, &loader{store: store})
+}
+
+func loaderFrom(ctx context.Context) *loader {
+	return ctx.Value(loaderKey{}).(*loader)
+}
+
+type loader struct {
+	store config.Store
+
+	once         sync.Once
+	err          error
+	allBackends  []config.Backend
+	allRoutes    []config.Route
+	backendIndex map[string]*config.Backend
+	routeIndex   map[uint]*config.Route
+}
+
+// load fetches every backend and route once per request.
+func (l *loader) load() error {
+	l.once.Do(func() {
+		if l.allBackends, l.err = l.store.GetBackends(nil); l.err != nil {
+			return
+		}
+		if l.allRoutes, l.err = l.store.GetRoutes(nil); l.err != nil {
+			return
+		}
+		l.backendIndex = make(map[string]*config.Backend, len(l.allBackends))
+		for i := range l.allBackends {
+			l.backendIndex[l.allBackends[i].Name] = &l.allBackends[i]
+		}
+		l.routeIndex = make(map[uint]*config.Route, len(l.allRoutes))
+		for i := range l.allRoutes {
+			l.routeIndex[l.allRoutes[i].ID] = &l.allRoutes[i]
+		}
+	})
+	return l.err
+}
+
+func (l *loader) backends(enabled *bool) ([]*config.Backend, error) {
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	var out []*config.Backend
+	for i := range l.allBackends {
+		if enabled == nil || l.allBackends[i].Enabled == *enabled {
+			out = append(out, &l.allBackends[i])
+		}
+	}
+	return out, nil
+}
+
+func (l *loader) backend(name string) (*config.Backend, error) {
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	return l.backendIndex[name], nil
+}
+
+func (l *loader) routes(enabled *bool) ([]*config.Route, error) {
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	var out []*config.Route
+	for i := range l.allRoutes {
+		if enabled == nil || l.allRoutes[i].Enabled == *enabled {
+			out = append(out, &l.allRoutes[i])
+		}
+	}
+	return out, nil
+}
+
+func (l *loader) routesFor(backend string, enabled *bool) ([]*config.Route, error) {
+	all, err := l.routes(enabled)
+	if err != nil {
+		return nil, err
+	}
+	var out []*config.Route
+	for _, r := range all {
+		if r.BackendName == backend {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (l *loader) route(id uint) (*config.Route, error) {
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	return l.routeIndex[id], nil
+}
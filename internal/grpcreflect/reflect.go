@@ -0,0 +1,297 @@
+// Package grpcreflect queries backends over the gRPC server reflection
+// protocol.
+package grpcreflect
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// ListServices returns the fully qualified names of the services a backend
+// exposes, sorted. The backend must enable server reflection; both the v1
+// and the older v1alpha protocol are supported. The connection uses the
+// backend's TLS settings and, if stored inline, its credential.
+func ListServices(ctx context.Context, backend *config.Backend) ([]string, error) {
+	conn, err := Dial(backend)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	c := &client{conn: conn}
+	services, err := c.listServices(WithCredential(ctx, backend.Credential))
+	if err != nil {
+		return nil, fmt.Errorf("server reflection on %s: %w", backend.Addr, err)
+	}
+	return services, nil
+}
+
+// FileDescriptors returns the descriptors of every service a backend
+// exposes, other than gRPC's own (grpc.*), with all their dependencies.
+func FileDescriptors(ctx context.Context, backend *config.Backend) (*descriptorpb.FileDescriptorSet, error) {
+	conn, err := Dial(backend)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ctx = WithCredential(ctx, backend.Credential)
+	c := &client{conn: conn}
+	set, err := c.fileDescriptors(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("server reflection on %s: %w", backend.Addr, err)
+	}
+	return set, nil
+}
+
+// Dial creates a client connection to a backend using its TLS settings.
+// The connection is established lazily by the first call.
+func Dial(backend *config.Backend) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if t := backend.TLS; t != nil {
+		cfg, err := tlsConfig(t)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(cfg)
+	}
+	return grpc.NewClient(backend.Addr, grpc.WithTransportCredentials(creds))
+}
+
+// WithCredential attaches an inline credential to outgoing calls as an
+// authorization header. Credentials referenced via SecretRef are resolved
+// by the gateway only and are not sent.
+func WithCredential(ctx context.Context, c *config.BackendCredential) context.Context {
+	if c == nil || c.Secret == "" {
+		return ctx
+	}
+	switch c.Type {
+	case config.CredentialBearer:
+		return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.Secret)
+	case config.CredentialBasic:
+		token := base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Secret))
+		return metadata.AppendToOutgoingContext(ctx, "authorization", "Basic "+token)
+	}
+	return ctx
+}
+
+func tlsConfig(t *config.BackendTLS) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if t.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(t.CACert)) {
+			return nil, errors.New("tls.ca_cert contains no valid certificate")
+		}
+		cfg.RootCAs = pool
+	}
+	if t.ClientCert != "" {
+		cert, err := tls.X509KeyPair([]byte(t.ClientCert), []byte(t.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("tls client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// client sends reflection requests over the v1 protocol, falling back to
+// v1alpha for servers that only implement the older one. The messages of
+// both are wire compatible, so v1 types are used throughout.
+type client struct {
+	conn  *grpc.ClientConn
+	alpha bool
+}
+
+// roundTrip sends reqs on one stream and returns the responses in order.
+func (c *client) roundTrip(ctx context.Context, reqs []*reflectionv1.ServerReflectionRequest) ([]*reflectionv1.ServerReflectionResponse, error) {
+	if !c.alpha {
+		resps, err := c.roundTripV1(ctx, reqs)
+		if status.Code(err) != codes.Unimplemented {
+			return resps, err
+		}
+		c.alpha = true
+	}
+	return c.roundTripV1alpha(ctx, reqs)
+}
+
+func (c *client) roundTripV1(ctx context.Context, reqs []*reflectionv1.ServerReflectionRequest) ([]*reflectionv1.ServerReflectionResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := reflectionv1.NewServerReflectionClient(c.conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resps := make([]*reflectionv1.ServerReflectionResponse, 0, len(reqs))
+	for _, req := range reqs {
+		if err := stream.Send(req); err != nil {
+			return nil, recvError(stream.RecvMsg, err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		resps = append(resps, resp)
+	}
+	return resps, stream.CloseSend()
+}
+
+func (c *client) roundTripV1alpha(ctx context.Context, reqs []*reflectionv1.ServerReflectionRequest) ([]*reflectionv1.ServerReflectionResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := reflectionv1alpha.NewServerReflectionClient(c.conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resps := make([]*reflectionv1.ServerReflectionResponse, 0, len(reqs))
+	for _, req := range reqs {
+		alphaReq := &reflectionv1alpha.ServerReflectionRequest{}
+		if err := convert(req, alphaReq); err != nil {
+			return nil, err
+		}
+		if err := stream.Send(alphaReq); err != nil {
+			return nil, recvError(stream.RecvMsg, err)
+		}
+		alphaResp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		resp := &reflectionv1.ServerReflectionResponse{}
+		if err := convert(alphaResp, resp); err != nil {
+			return nil, err
+		}
+		resps = append(resps, resp)
+	}
+	return resps, stream.CloseSend()
+}
+
+// recvError returns the status that ended a stream when sending on it
+// failed with io.EOF, which is how a send learns that the call is over.
+func recvError(recv func(interface{}) error, err error) error {
+	if !errors.Is(err, io.EOF) {
+		return err
+	}
+	if rerr := recv(&reflectionv1.ServerReflectionResponse{}); rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+// convert copies a message into its wire-compatible counterpart.
+func convert(from, to proto.Message) error {
+	data, err := proto.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, to)
+}
+
+// responseError turns an error response into an error.
+func responseError(resp *reflectionv1.ServerReflectionResponse) error {
+	if e := resp.GetErrorResponse(); e != nil {
+		return status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+	}
+	return nil
+}
+
+func (c *client) listServices(ctx context.Context) ([]string, error) {
+	resps, err := c.roundTrip(ctx, []*reflectionv1.ServerReflectionRequest{{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_ListServices{},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	if err := responseError(resps[0]); err != nil {
+		return nil, err
+	}
+
+	var services []string
+	for _, s := range resps[0].GetListServicesResponse().GetService() {
+		services = append(services, s.GetName())
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+func (c *client) fileDescriptors(ctx context.Context) (*descriptorpb.FileDescriptorSet, error) {
+	services, err := c.listServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reqs []*reflectionv1.ServerReflectionRequest
+	for _, s := range services {
+		if strings.HasPrefix(s, "grpc.") {
+			continue
+		}
+		reqs = append(reqs, &reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: s},
+		})
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+	// Servers usually send dependencies along; fetch the rest by name
+	for len(reqs) > 0 {
+		resps, err := c.roundTrip(ctx, reqs)
+		if err != nil {
+			return nil, err
+		}
+		reqs = nil
+
+		var added []*descriptorpb.FileDescriptorProto
+		for _, resp := range resps {
+			if err := responseError(resp); err != nil {
+				return nil, err
+			}
+			for _, data := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+				file := &descriptorpb.FileDescriptorProto{}
+				if err := proto.Unmarshal(data, file); err != nil {
+					return nil, fmt.Errorf("invalid file descriptor: %w", err)
+				}
+				if !seen[file.GetName()] {
+					seen[file.GetName()] = true
+					set.File = append(set.File, file)
+					added = append(added, file)
+				}
+			}
+		}
+
+		requested := map[string]bool{}
+		for _, file := range added {
+			for _, dep := range file.GetDependency() {
+				if !seen[dep] && !requested[dep] {
+					requested[dep] = true
+					reqs = append(reqs, &reflectionv1.ServerReflectionRequest{
+						MessageRequest: &reflectionv1.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+					})
+				}
+			}
+		}
+	}
+	return set, nil
+}
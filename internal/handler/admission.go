@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+)
+
+// writeAdmissionError responds to a failed admission check: rejections are
+// returned to the caller verbatim, exceeded quotas as 429 Too Many Requests,
+// and anything else is an internal error.
+func writeAdmissionError(w http.ResponseWriter, logger *zap.Logger, err error) {
+	var qerr *admission.QuotaError
+	if errors.As(err, &qerr) {
+		if qerr.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(qerr.RetryAfter.Seconds()))))
+		}
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if admission.IsDenied(err) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	logger.Error("admission check failed", zap.Error(err))
+	writeServerError(w, err)
+}
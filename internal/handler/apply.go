@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/apply"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/jobs"
+)
+
+// ApplyHandler handles declarative desired-state configuration requests.
+type ApplyHandler struct {
+	store     config.Store
+	admission admission.Controller
+	jobs      *jobs.Runner
+	logger    *zap.Logger
+}
+
+// NewApplyHandler creates a new ApplyHandler. If runner is not nil,
+// documents can be applied in the background.
+func NewApplyHandler(store config.Store, admission admission.Controller, runner *jobs.Runner, logger *zap.Logger) *ApplyHandler {
+	h := &ApplyHandler{
+		store:     store,
+		admission: admission,
+		jobs:      runner,
+		logger:    logger,
+	}
+	if runner != nil {
+		runner.Register(jobApply, h.runApply)
+	}
+	return h
+}
+
+// applyParams are the params of an apply job. The document is kept as
+// sent, since encoding a Document redacts inline secrets.
+type applyParams struct {
+	Document json.RawMessage `json:"document"`
+	Actor    apply.Actor     `json:"actor"`
+	PlanOnly bool            `json:"plan_only,omitempty"`
+}
+
+// applyResponse is returned by Apply.
+type applyResponse struct {
+	Applied bool        `json:"applied"`
+	Plan    *apply.Plan `json:"plan"`
+}
+
+// Apply reconciles the stored configuration with a full desired document.
+// With plan_only=true the computed plan is returned without being applied.
+// With async=true the document is validated and then applied by a
+// background job, whose result is the response Apply would have returned.
+// POST /api/v1/apply?plan_only=true&async=true
+func (h *ApplyHandler) Apply(w http.ResponseWriter, r *http.Request) {
+	planOnly := false
+	if param := r.URL.Query().Get("plan_only"); param != "" {
+		val, err := strconv.ParseBool(param)
+		if err != nil {
+			http.Error(w, "invalid plan_only parameter", http.StatusBadRequest)
+			return
+		}
+		planOnly = val
+	}
+
+	async, ok := parseAsync(w, r, h.jobs)
+	if !ok {
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var doc apply.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	if err := doc.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	actor := apply.Actor{
+		Operator:       r.Header.Get("X-Operator"),
+		Role:           operatorRole(r),
+		Teams:          operatorTeams(r),
+		Reason:         changeReason(r, doc.ChangeReason),
+		FreezeOverride: freezeOverride(r),
+	}
+	if async {
+		submitJob(w, r, h.jobs, jobApply, applyParams{Document: data, Actor: actor, PlanOnly: planOnly}, h.logger)
+		return
+	}
+
+	plan, err := apply.ComputePlan(h.store, &doc)
+	if err != nil {
+		h.logger.Error("failed to compute apply plan", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	if err := apply.Admit(r.Context(), h.admission, plan, actor); err != nil {
+		writeAdmissionError(w, h.logger, err)
+		return
+	}
+
+	response := applyResponse{Plan: plan}
+	if !planOnly && plan.HasChanges() {
+		if err := apply.Execute(h.store, plan, actor); err != nil {
+			writeExecuteError(w, h.logger, "failed to apply plan", err)
+			return
+		}
+		response.Applied = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode apply response", zap.Error(err))
+	}
+}
+
+// runApply runs an apply job.
+func (h *ApplyHandler) runApply(ctx context.Context, params json.RawMessage, progress func(done, total int)) (interface{}, error) {
+	var p applyParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("decode params: %w", err)
+	}
+	var doc apply.Document
+	if err := json.Unmarshal(p.Document, &doc); err != nil {
+		return nil, fmt.Errorf("decode document: %w", err)
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return runPlan(ctx, h.store, h.admission, &doc, p.Actor, p.PlanOnly, progress)
+}
+
+// runPlan brings the configuration in line with doc from a job, as Apply
+// does, counting executed changes as progress.
+func runPlan(ctx context.Context, store config.Store, ctrl admission.Controller, doc *apply.Document, actor apply.Actor, planOnly bool, progress func(done, total int)) (*applyResponse, error) {
+	plan, err := apply.ComputePlan(store, doc)
+	if err != nil {
+		return nil, fmt.Errorf("compute plan: %w", err)
+	}
+	if err := apply.Admit(ctx, ctrl, plan, actor); err != nil {
+		return nil, err
+	}
+
+	response := &applyResponse{Plan: plan}
+	if planOnly || !plan.HasChanges() {
+		return response, nil
+	}
+
+	changes := plan.Summary[apply.ActionCreate] + plan.Summary[apply.ActionUpdate] + plan.Summary[apply.ActionDelete]
+	progress(0, changes)
+	if err := apply.Execute(store, plan, actor); err != nil {
+		return nil, fmt.Errorf("execute plan: %w", err)
+	}
+	progress(changes, changes)
+	response.Applied = true
+	return response, nil
+}
+
+// writeExecuteError responds to a plan that failed to execute: 409 if the
+// configuration changed since it was computed, so that the caller can
+// plan again, and a logged server error otherwise.
+func writeExecuteError(w http.ResponseWriter, logger *zap.Logger, msg string, err error, fields ...zap.Field) {
+	if errors.Is(err, apply.ErrStalePlan) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	logger.Error(msg, append(fields, zap.Error(err))...)
+	writeServerError(w, err)
+}
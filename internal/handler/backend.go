@@ -2,32 +2,44 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
 	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
 )
 
 // BackendHandler handles backend management API requests.
 type BackendHandler struct {
-	store  config.Store
-	logger *zap.Logger
+	store     config.Store
+	admission admission.Controller
+	logger    *zap.Logger
 }
 
 // NewBackendHandler creates a new BackendHandler.
-func NewBackendHandler(store config.Store, logger *zap.Logger) *BackendHandler {
+func NewBackendHandler(store config.Store, admission admission.Controller, logger *zap.Logger) *BackendHandler {
 	return &BackendHandler{
-		store:  store,
-		logger: logger,
+		store:     store,
+		admission: admission,
+		logger:    logger,
 	}
 }
 
-// ListBackends returns all backends, optionally filtered by enabled status.
-// GET /api/v1/backends?enabled=true
+// ListBackends returns all backends, optionally filtered by enabled status
+// and owner team; an empty team selects the backends no team owns.
+// GET /api/v1/backends?enabled=true&team=payments&fields=name,addr&format=csv
 func (h *BackendHandler) ListBackends(w http.ResponseWriter, r *http.Request) {
+	fields, err := parseFields(r, config.Backend{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	enabledParam := r.URL.Query().Get("enabled")
 	var enabled *bool
 
@@ -43,12 +55,35 @@ func (h *BackendHandler) ListBackends(w http.ResponseWriter, r *http.Request) {
 	backends, err := h.store.GetBackends(enabled)
 	if err != nil {
 		h.logger.Error("failed to get backends", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
+		return
+	}
+	if query := r.URL.Query(); query.Has("team") {
+		filtered := backends[:0]
+		for _, backend := range backends {
+			if backend.OwnerTeam == query.Get("team") {
+				filtered = append(filtered, backend)
+			}
+		}
+		backends = filtered
+	}
+
+	if wantsCSV(r) {
+		if err := writeCSV(w, "backends.csv", backends, fields); err != nil {
+			h.logger.Warn("failed to write backends csv", zap.Error(err))
+		}
+		return
+	}
+
+	result, err := project(backends, fields)
+	if err != nil {
+		h.logger.Error("failed to project backends", zap.Error(err))
+		writeServerError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(backends); err != nil {
+	if err := json.NewEncoder(w).Encode(result); err != nil {
 		h.logger.Warn("failed to encode backends", zap.Error(err))
 	}
 }
@@ -61,7 +96,7 @@ func (h *BackendHandler) GetBackend(w http.ResponseWriter, r *http.Request) {
 	backend, err := h.store.GetBackendByName(name)
 	if err != nil {
 		h.logger.Error("failed to get backend", zap.String("name", name), zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
 		return
 	}
 
@@ -77,22 +112,31 @@ func (h *BackendHandler) GetBackend(w http.ResponseWriter, r *http.Request) {
 }
 
 // CreateBackend creates a new backend.
-// POST /api/v1/backends
+// POST /api/v1/backends?dry_run=true
 func (h *BackendHandler) CreateBackend(w http.ResponseWriter, r *http.Request) {
-	var backend config.Backend
-	if err := json.NewDecoder(r.Body).Decode(&backend); err != nil {
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		config.Backend
+		ChangeReason string `json:"change_reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
 	defer r.Body.Close()
 
+	backend := req.Backend
+	reason := changeReason(r, req.ChangeReason)
+
 	// Validation
 	if backend.Name == "" {
 		http.Error(w, "name is required", http.StatusBadRequest)
 		return
 	}
-	if backend.Addr == "" {
-		http.Error(w, "addr is required", http.StatusBadRequest)
+	if !validBackend(w, &backend) {
 		return
 	}
 
@@ -100,7 +144,7 @@ func (h *BackendHandler) CreateBackend(w http.ResponseWriter, r *http.Request) {
 	existing, err := h.store.GetBackendByName(backend.Name)
 	if err != nil {
 		h.logger.Error("failed to check backend existence", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
 		return
 	}
 	if existing != nil {
@@ -112,16 +156,32 @@ func (h *BackendHandler) CreateBackend(w http.ResponseWriter, r *http.Request) {
 	if !r.URL.Query().Has("enabled") {
 		backend.Enabled = true
 	}
+	if err := backend.NormalizeStatus(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Create backend
-	if err := h.store.CreateBackend(&backend); err != nil {
-		h.logger.Error("failed to create backend", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+	// Admission checks
+	if !h.admit(w, r, "CREATE", nil, &backend, reason) {
 		return
 	}
 
-	// Record history
-	h.recordHistory("backend", &backend.ID, "CREATE", nil, &backend, r)
+	// Create backend and record history
+	err = commit(h.store, w, dryRun, func(tx config.Store) error {
+		if err := tx.CreateBackend(&backend); err != nil {
+			return err
+		}
+		return tx.CreateHistory(newHistory("backend", &backend.ID, "CREATE", nil, &backend, reason, r))
+	})
+	if err != nil {
+		h.logger.Error("failed to create backend", zap.Error(err))
+		if errors.Is(err, config.ErrEncryptionDisabled) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeServerError(w, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -131,15 +191,19 @@ func (h *BackendHandler) CreateBackend(w http.ResponseWriter, r *http.Request) {
 }
 
 // UpdateBackend updates an existing backend.
-// PUT /api/v1/backends/{name}
+// PUT /api/v1/backends/{name}?dry_run=true
 func (h *BackendHandler) UpdateBackend(w http.ResponseWriter, r *http.Request) {
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
 	name := chi.URLParam(r, "name")
 
 	// Get existing backend
 	oldBackend, err := h.store.GetBackendByName(name)
 	if err != nil {
 		h.logger.Error("failed to get backend", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
 		return
 	}
 	if oldBackend == nil {
@@ -155,6 +219,9 @@ func (h *BackendHandler) UpdateBackend(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	bodyReason, _ := backendUpdate["change_reason"].(string)
+	reason := changeReason(r, bodyReason)
+
 	// Check if enabled field is present in the request
 	enabledPresent := false
 	var enabledValue bool
@@ -180,9 +247,34 @@ func (h *BackendHandler) UpdateBackend(w http.ResponseWriter, r *http.Request) {
 		backend.Enabled = enabledValue
 	}
 
+	// Status takes precedence over enabled; without it, a backend that
+	// stays enabled keeps draining
+	if _, ok := backendUpdate["status"]; !ok {
+		backend.Status = ""
+		if backend.Enabled && oldBackend.Status == config.BackendDraining {
+			backend.Status = config.BackendDraining
+		}
+	}
+	if err := backend.NormalizeStatus(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Credentials are preserved unless the field is present; secrets are
+	// write-only, so an update without a new secret keeps the stored one.
+	if _, ok := backendUpdate["credential"]; !ok {
+		backend.Credential = oldBackend.Credential
+	} else if c := backend.Credential; c != nil && c.Secret == "" && c.SecretRef == "" && oldBackend.Credential != nil {
+		c.Secret = oldBackend.Credential.Secret
+	}
+	if _, ok := backendUpdate["tls"]; !ok {
+		backend.TLS = oldBackend.TLS
+	} else if t := backend.TLS; t != nil && t.ClientKey == "" && oldBackend.TLS != nil && t.ClientCert == oldBackend.TLS.ClientCert {
+		t.ClientKey = oldBackend.TLS.ClientKey
+	}
+
 	// Validation
-	if backend.Addr == "" {
-		http.Error(w, "addr is required", http.StatusBadRequest)
+	if !validBackend(w, &backend) {
 		return
 	}
 
@@ -190,36 +282,69 @@ func (h *BackendHandler) UpdateBackend(w http.ResponseWriter, r *http.Request) {
 	backend.ID = oldBackend.ID
 	backend.Name = name
 
-	// Update backend
-	if err := h.store.UpdateBackend(name, &backend); err != nil {
+	// Enabled routes must not be left pointing at a disabled backend
+	cascade := &cascadePlan{}
+	if !backend.Enabled {
+		var ok bool
+		if cascade, ok = h.planCascade(w, r, name, true); !ok {
+			return
+		}
+	}
+
+	// Admission checks
+	if !h.admit(w, r, "UPDATE", oldBackend, &backend, reason) {
+		return
+	}
+	if !h.admitCascade(w, r, cascade, reason) {
+		return
+	}
+
+	// Update backend and cascade to its routes, recording history, as one
+	// transaction
+	err = commit(h.store, w, dryRun, func(tx config.Store) error {
+		if err := applyCascade(tx, cascade, reason, r); err != nil {
+			return err
+		}
+		if err := tx.UpdateBackend(name, &backend); err != nil {
+			return err
+		}
+		return tx.CreateHistory(newHistory("backend", &backend.ID, "UPDATE", oldBackend, &backend, reason, r))
+	})
+	if err != nil {
 		h.logger.Error("failed to update backend", zap.Error(err))
 		if err.Error() == "backend not found" {
 			http.Error(w, "backend not found", http.StatusNotFound)
 			return
 		}
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		if errors.Is(err, config.ErrEncryptionDisabled) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeServerError(w, err)
 		return
 	}
 
-	// Record history
-	h.recordHistory("backend", &backend.ID, "UPDATE", oldBackend, &backend, r)
-
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(backend); err != nil {
 		h.logger.Warn("failed to encode backend", zap.Error(err))
 	}
 }
 
-// DeleteBackend soft deletes a backend.
-// DELETE /api/v1/backends/{name}
+// DeleteBackend soft deletes a backend. Its enabled routes, if any, must be
+// cascaded: disabled, deleted or reassigned to another backend.
+// DELETE /api/v1/backends/{name}?cascade=disable|delete|reassign&to=name&dry_run=true
 func (h *BackendHandler) DeleteBackend(w http.ResponseWriter, r *http.Request) {
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
 	name := chi.URLParam(r, "name")
 
 	// Get existing backend
 	oldBackend, err := h.store.GetBackendByName(name)
 	if err != nil {
 		h.logger.Error("failed to get backend", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
 		return
 	}
 	if oldBackend == nil {
@@ -227,31 +352,87 @@ func (h *BackendHandler) DeleteBackend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete backend (soft delete)
-	if err := h.store.DeleteBackend(name); err != nil {
+	// Enabled routes must not be left pointing at a deleted backend
+	cascade, ok := h.planCascade(w, r, name, true)
+	if !ok {
+		return
+	}
+
+	// Admission checks
+	reason := changeReason(r, "")
+	if !h.admit(w, r, "DELETE", oldBackend, nil, reason) {
+		return
+	}
+	if !h.admitCascade(w, r, cascade, reason) {
+		return
+	}
+
+	// Delete backend (soft delete) and cascade to its routes, recording
+	// history, as one transaction
+	oldBackend.Enabled = false
+	err = commit(h.store, w, dryRun, func(tx config.Store) error {
+		if err := applyCascade(tx, cascade, reason, r); err != nil {
+			return err
+		}
+		if err := tx.DeleteBackend(name); err != nil {
+			return err
+		}
+		return tx.CreateHistory(newHistory("backend", &oldBackend.ID, "DELETE", oldBackend, nil, reason, r))
+	})
+	if err != nil {
 		h.logger.Error("failed to delete backend", zap.Error(err))
 		if err.Error() == "backend not found" {
 			http.Error(w, "backend not found", http.StatusNotFound)
 			return
 		}
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
 		return
 	}
 
-	// Record history
-	oldBackend.Enabled = false
-	h.recordHistory("backend", &oldBackend.ID, "DELETE", oldBackend, nil, r)
+	// Report what happened to the routes, if anything
+	if len(cascade.changes) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cascade); err != nil {
+		h.logger.Warn("failed to encode cascade", zap.Error(err))
+	}
+}
+
+// admit runs the admission controllers for a proposed change and writes an
+// error response if the change is rejected.
+func (h *BackendHandler) admit(w http.ResponseWriter, r *http.Request, operation string, oldVal, newVal interface{}, reason string) bool {
+	req := &admission.Request{
+		Operation:  operation,
+		ConfigType: "backend",
+		Operator:   r.Header.Get("X-Operator"),
+		Role:       operatorRole(r),
+		Teams:      operatorTeams(r),
+		Reason:     reason,
+		Old:        oldVal,
+		New:        newVal,
+
+		FreezeOverride: freezeOverride(r),
+	}
 
-	w.WriteHeader(http.StatusNoContent)
+	if err := h.admission.Admit(r.Context(), req); err != nil {
+		writeAdmissionError(w, h.logger, err)
+		return false
+	}
+	return true
 }
 
-// recordHistory records a configuration change history.
-func (h *BackendHandler) recordHistory(configType string, configID *uint, operation string, oldVal, newVal interface{}, r *http.Request) {
+// newHistory builds the history record of a change made by the caller.
+func newHistory(configType string, configID *uint, operation string, oldVal, newVal interface{}, reason string, r *http.Request) *config.ConfigHistory {
 	history := &config.ConfigHistory{
 		ConfigType: configType,
 		ConfigID:   configID,
 		Operation:  operation,
 		Operator:   r.Header.Get("X-Operator"), // Future: extract from auth token
+		Reason:     reason,
+
+		FreezeOverride: freezeOverride(r) && operatorRole(r) == auth.RoleAdmin,
 	}
 
 	if oldVal != nil {
@@ -266,7 +447,38 @@ func (h *BackendHandler) recordHistory(configType string, configID *uint, operat
 		}
 	}
 
-	if err := h.store.CreateHistory(history); err != nil {
-		h.logger.Warn("failed to record history", zap.Error(err))
+	return history
+}
+
+// validBackend validates a backend, writing a 400 if it is invalid.
+func validBackend(w http.ResponseWriter, backend *config.Backend) bool {
+	if backend.Addr == "" {
+		http.Error(w, "addr is required", http.StatusBadRequest)
+		return false
+	}
+	if err := backend.Credential.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err := backend.TLS.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err := backend.CircuitBreaker.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err := config.ValidatePlugins(backend.Plugins); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err := config.ValidateClusters(backend.Clusters); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err := config.ValidateOwnerTeam(backend.OwnerTeam); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
 	}
+	return true
 }
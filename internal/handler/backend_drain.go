@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// DrainBackend puts an enabled backend in the draining state: gateways
+// send it no new sessions but let existing streams finish. Its routes are
+// kept. Draining a draining backend changes nothing.
+// POST /api/v1/backends/{name}/drain?dry_run=true
+func (h *BackendHandler) DrainBackend(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, config.BackendDraining)
+}
+
+// UndrainBackend ends draining, returning the backend to the enabled
+// state.
+// DELETE /api/v1/backends/{name}/drain?dry_run=true
+func (h *BackendHandler) UndrainBackend(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, config.BackendEnabled)
+}
+
+// setStatus moves an enabled backend between the enabled and draining
+// states.
+func (h *BackendHandler) setStatus(w http.ResponseWriter, r *http.Request, status string) {
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	name := chi.URLParam(r, "name")
+
+	oldBackend, err := h.store.GetBackendByName(name)
+	if err != nil {
+		h.logger.Error("failed to get backend", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if oldBackend == nil {
+		http.Error(w, "backend not found", http.StatusNotFound)
+		return
+	}
+	if !oldBackend.Enabled {
+		http.Error(w, "backend is disabled; enable it first", http.StatusConflict)
+		return
+	}
+
+	backend := *oldBackend
+	if backend.Status != status {
+		backend.Status = status
+
+		reason := changeReason(r, "")
+		if !h.admit(w, r, "UPDATE", oldBackend, &backend, reason) {
+			return
+		}
+		err := commit(h.store, w, dryRun, func(tx config.Store) error {
+			if err := tx.UpdateBackend(name, &backend); err != nil {
+				return err
+			}
+			return tx.CreateHistory(newHistory("backend", &backend.ID, "UPDATE", oldBackend, &backend, reason, r))
+		})
+		if err != nil {
+			h.logger.Error("failed to update backend", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(backend); err != nil {
+		h.logger.Warn("failed to encode backend", zap.Error(err))
+	}
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// Cascade modes for the enabled routes of a backend being deleted or
+// disabled, selected with ?cascade=.
+const (
+	cascadeDisable  = "disable"
+	cascadeDelete   = "delete"
+	cascadeReassign = "reassign"
+)
+
+// forceDisableRoutes is the older spelling of cascade=disable, passed as
+// ?force=.
+const forceDisableRoutes = "disable-routes"
+
+// routeChange is one change to a dependent route.
+type routeChange struct {
+	operation string
+	old, new  *config.Route
+}
+
+// cascadePlan is what happens to the enabled routes of a backend being
+// deleted or disabled.
+type cascadePlan struct {
+	Mode    string         `json:"cascade"`
+	To      string         `json:"to,omitempty"`
+	Routes  []config.Route `json:"routes"`
+	changes []routeChange
+}
+
+// planCascade guards deleting or disabling a backend: no enabled route may
+// reference it, or the gateway would be left with routes to nowhere. If
+// some do and no cascade mode was given, it writes a 409 listing them and
+// returns false. Otherwise it plans the cascade for applyCascade, which is
+// empty when there are no dependent routes. Cascading is only offered if
+// cascadable is set.
+func (h *BackendHandler) planCascade(w http.ResponseWriter, r *http.Request, name string, cascadable bool) (*cascadePlan, bool) {
+	plan := &cascadePlan{}
+	if cascadable {
+		query := r.URL.Query()
+		plan.Mode = query.Get("cascade")
+		switch force := query.Get("force"); {
+		case force == forceDisableRoutes && plan.Mode == "":
+			plan.Mode = cascadeDisable
+		case force != "" && force != forceDisableRoutes:
+			http.Error(w, "invalid force parameter (must be '"+forceDisableRoutes+"')", http.StatusBadRequest)
+			return nil, false
+		}
+
+		switch plan.Mode {
+		case "", cascadeDisable, cascadeDelete:
+		case cascadeReassign:
+			plan.To = query.Get("to")
+			if !h.checkReplacement(w, name, plan.To) {
+				return nil, false
+			}
+		default:
+			http.Error(w, "invalid cascade parameter (must be 'disable', 'delete' or 'reassign')", http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	enabled := true
+	routes, err := h.store.GetRoutes(&enabled)
+	if err != nil {
+		h.logger.Error("failed to get routes", zap.Error(err))
+		writeServerError(w, err)
+		return nil, false
+	}
+
+	var dependents []config.Route
+	for _, route := range routes {
+		if route.BackendName == name {
+			dependents = append(dependents, route)
+		}
+	}
+
+	if len(dependents) > 0 && plan.Mode == "" {
+		var msg strings.Builder
+		fmt.Fprintf(&msg, "backend %s is used by %d enabled route(s); disable or move them first", name, len(dependents))
+		if cascadable {
+			msg.WriteString(", or use cascade=disable, cascade=delete or cascade=reassign&to=<backend>")
+		}
+		msg.WriteString(":")
+		for _, route := range dependents {
+			fmt.Fprintf(&msg, "\n  %d %s %s", route.ID, route.HTTPMethod, route.HTTPPattern)
+		}
+		http.Error(w, msg.String(), http.StatusConflict)
+		return nil, false
+	}
+
+	plan.Routes = []config.Route{}
+	for i := range dependents {
+		old := &dependents[i]
+		changed := *old
+		change := routeChange{operation: "UPDATE", old: old, new: &changed}
+		switch plan.Mode {
+		case cascadeDisable:
+			changed.Enabled = false
+		case cascadeDelete:
+			changed.Enabled = false
+			change.operation, change.new = "DELETE", nil
+		case cascadeReassign:
+			changed.BackendName = plan.To
+		}
+		plan.changes = append(plan.changes, change)
+		plan.Routes = append(plan.Routes, changed)
+	}
+	return plan, true
+}
+
+// checkReplacement verifies that routes can be reassigned from backend
+// name to backend to, writing an error response if not.
+func (h *BackendHandler) checkReplacement(w http.ResponseWriter, name, to string) bool {
+	if to == "" {
+		http.Error(w, "cascade=reassign requires to=<backend>", http.StatusBadRequest)
+		return false
+	}
+	if to == name {
+		http.Error(w, "cannot reassign routes to the backend being removed", http.StatusBadRequest)
+		return false
+	}
+
+	backend, err := h.store.GetBackendByName(to)
+	if err != nil {
+		h.logger.Error("failed to check backend", zap.Error(err))
+		writeServerError(w, err)
+		return false
+	}
+	if backend == nil || !backend.Enabled {
+		http.Error(w, "replacement backend not found or disabled", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// admitCascade runs the admission controllers for every route change of
+// the plan, counted as one batch with the backend change, and writes an
+// error response if any is rejected.
+func (h *BackendHandler) admitCascade(w http.ResponseWriter, r *http.Request, plan *cascadePlan, reason string) bool {
+	for _, c := range plan.changes {
+		req := &admission.Request{
+			Operation:  c.operation,
+			ConfigType: "route",
+			Operator:   r.Header.Get("X-Operator"),
+			Role:       operatorRole(r),
+			Teams:      operatorTeams(r),
+			Reason:     reason,
+			Old:        c.old,
+			Batch:      len(plan.changes) + 1,
+
+			FreezeOverride: freezeOverride(r),
+		}
+		if c.new != nil {
+			req.New = c.new
+		}
+		if err := h.admission.Admit(r.Context(), req); err != nil {
+			writeAdmissionError(w, h.logger, err)
+			return false
+		}
+	}
+	return true
+}
+
+// applyCascade makes the route changes of the plan within tx, recording
+// history for each.
+func applyCascade(tx config.Store, plan *cascadePlan, reason string, r *http.Request) error {
+	for _, c := range plan.changes {
+		var newVal interface{}
+		if c.new != nil {
+			if err := tx.UpdateRoute(c.old.ID, c.new); err != nil {
+				return fmt.Errorf("update route %d: %w", c.old.ID, err)
+			}
+			newVal = c.new
+		} else {
+			if err := tx.DeleteRoute(c.old.ID); err != nil {
+				return fmt.Errorf("delete route %d: %w", c.old.ID, err)
+			}
+			c.old.Enabled = false
+		}
+		if err := tx.CreateHistory(newHistory("route", &c.old.ID, c.operation, c.old, newVal, reason, r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
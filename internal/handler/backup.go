@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/backup"
+)
+
+// BackupHandler handles requests for the scheduled configuration backups
+// kept in object storage.
+type BackupHandler struct {
+	scheduler *backup.Scheduler
+	logger    *zap.Logger
+}
+
+// NewBackupHandler creates a new BackupHandler.
+func NewBackupHandler(scheduler *backup.Scheduler, logger *zap.Logger) *BackupHandler {
+	return &BackupHandler{
+		scheduler: scheduler,
+		logger:    logger,
+	}
+}
+
+// ListBackups returns the available restore points, newest first.
+// GET /api/v1/backups
+func (h *BackupHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	backups, err := h.scheduler.List(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list backups", zap.Error(err))
+		http.Error(w, "failed to list backups", http.StatusBadGateway)
+		return
+	}
+	if backups == nil {
+		backups = []backup.Backup{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(backups); err != nil {
+		h.logger.Warn("failed to encode backups", zap.Error(err))
+	}
+}
+
+// GetBackup returns the configuration document of a backup, which can be
+// restored with POST /api/v1/apply.
+// GET /api/v1/backups/{name}
+func (h *BackupHandler) GetBackup(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	data, err := h.scheduler.Get(r.Context(), name)
+	if err != nil {
+		h.logger.Error("failed to get backup", zap.String("name", name), zap.Error(err))
+		http.Error(w, "failed to get backup", http.StatusBadGateway)
+		return
+	}
+	if data == nil {
+		http.Error(w, "backup not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
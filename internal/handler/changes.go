@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/compile"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+)
+
+const (
+	// changesPollInterval is how often waiting requests look for changes
+	// made elsewhere than through this service, such as by other replicas.
+	changesPollInterval = 2 * time.Second
+	// changesWriteMargin is the time left to write the response once a
+	// wait times out.
+	changesWriteMargin = 10 * time.Second
+)
+
+// changeEvents selects the changes that may change the compiled config.
+var changeEvents = events.Filter{Types: map[string]bool{events.TypeBackend: true, events.TypeRoute: true, events.TypeDescriptor: true, events.TypeSchema: true}}
+
+// ChangesHandler long-polls for config changes, for gateways that cannot
+// subscribe over gRPC. Its routes must only be mounted behind gateway
+// authentication.
+type ChangesHandler struct {
+	gateway *GatewayHandler
+	broker  *events.Broker
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+// NewChangesHandler creates a new ChangesHandler serving the config of
+// gateway. Requests wait at most timeout for a change.
+func NewChangesHandler(gateway *GatewayHandler, broker *events.Broker, timeout time.Duration, logger *zap.Logger) *ChangesHandler {
+	return &ChangesHandler{
+		gateway: gateway,
+		broker:  broker,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+// Wait blocks until the config served to the ?gateway= in its ?cluster=
+// has a revision newer than ?since=, then returns the delta from since,
+// with the SHA-256 and revision of the full config in X-Config-SHA256 and
+// X-Config-Revision. It answers 204 if none comes within ?timeout=, which
+// defaults to and may not exceed the configured timeout. since=0 returns
+// the whole config as a delta. Gateways sending Accept:
+// application/x-protobuf receive the delta as a configv1.ConfigDelta
+// message instead.
+// GET /api/v1/changes/wait?since=42&cluster=&gateway=&timeout=30s
+func (h *ChangesHandler) Wait(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	since, err := strconv.ParseUint(query.Get("since"), 10, 64)
+	if err != nil {
+		http.Error(w, "since must be a config revision", http.StatusBadRequest)
+		return
+	}
+	cluster := query.Get("cluster")
+	if cluster != "" {
+		if err := config.ValidateClusterName(cluster); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	gatewayID := query.Get("gateway")
+	if len(gatewayID) > maxGatewayIDLength {
+		http.Error(w, "gateway too long (at most 128 characters)", http.StatusBadRequest)
+		return
+	}
+	timeout := h.timeout
+	if param := query.Get("timeout"); param != "" {
+		d, err := time.ParseDuration(param)
+		if err != nil || d <= 0 || d > h.timeout {
+			http.Error(w, "invalid timeout: must be a positive duration of at most "+h.timeout.String(), http.StatusBadRequest)
+			return
+		}
+		timeout = d
+	}
+
+	// The wait may outlast the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(timeout + changesWriteMargin)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.logger.Warn("failed to extend write deadline", zap.Error(err))
+	}
+
+	// Subscribe before the first check, so that no change made meanwhile
+	// is missed
+	sub := h.broker.Subscribe(changeEvents, 64)
+	defer func() { h.broker.Unsubscribe(sub) }()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(changesPollInterval)
+	defer ticker.Stop()
+
+	for {
+		payload, cfg, err := h.newer(r.Context(), gatewayID, cluster, since)
+		if err != nil {
+			if r.Context().Err() != nil {
+				return
+			}
+			h.logger.Error("failed to compile config", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		if cfg != nil {
+			h.writeDelta(w, r, payload, cfg, since)
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timer.C:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case _, open := <-sub.C:
+			if !open {
+				// The broker dropped us for falling behind
+				sub = h.broker.Subscribe(changeEvents, 64)
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// newer returns the config served to the gateway, encoded and decoded, if
+// its revision is newer than since, and a nil config otherwise.
+func (h *ChangesHandler) newer(ctx context.Context, gatewayID, cluster string, since uint64) ([]byte, *compile.Config, error) {
+	// Nothing is newer while the store is not
+	current, err := compile.Revision(h.gateway.store)
+	if err != nil || current <= since {
+		return nil, nil, err
+	}
+
+	payload, err := h.gateway.ServedPayload(ctx, gatewayID, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cfg compile.Config
+	if err := json.Unmarshal(payload, &cfg); err != nil {
+		return nil, nil, err
+	}
+	// Gateways served a rollout baseline stay at its revision
+	if cfg.Revision <= since {
+		return nil, nil, nil
+	}
+	return payload, &cfg, nil
+}
+
+// writeDelta writes the delta from since to cfg, whose encoding is
+// payload, in the encoding r accepts.
+func (h *ChangesHandler) writeDelta(w http.ResponseWriter, r *http.Request, payload []byte, cfg *compile.Config, since uint64) {
+	delta, err := compile.Changes(h.gateway.store, cfg, since)
+	if err != nil {
+		h.logger.Error("failed to compute config delta", zap.Uint64("since", since), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Config-SHA256", payloadSHA256(payload))
+	w.Header().Set("X-Config-Revision", strconv.FormatUint(cfg.Revision, 10))
+	w.Header().Add("Vary", "Accept")
+	if acceptsProtobuf(r) {
+		msg, err := delta.Proto()
+		if err != nil {
+			h.logger.Error("failed to encode config delta", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		data, err := protoMarshal.Marshal(msg)
+		if err != nil {
+			h.logger.Error("failed to encode config delta", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", protobufContentType)
+		if _, err := w.Write(data); err != nil {
+			h.logger.Warn("failed to write config delta", zap.Error(err))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(delta); err != nil {
+		h.logger.Warn("failed to encode config delta", zap.Error(err))
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"encoding/csv"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	type row struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Weight      int    `json:"weight"`
+	}
+	rows := []row{
+		{"a", "=HYPERLINK(\"http://example.com\")", -1},
+		{"b", "+1", 0},
+		{"c", "-1", 0},
+		{"d", "@SUM(A1)", 0},
+		{"e", "\tx", 0},
+		{"f", "\rx", 0},
+		{"g", "plain = text", 0},
+		{"h", "", 0},
+	}
+	want := []string{"'=HYPERLINK(\"http://example.com\")", "'+1", "'-1", "'@SUM(A1)", "'\tx", "'\rx", "plain = text", ""}
+
+	w := httptest.NewRecorder()
+	if err := writeCSV(w, "rows.csv", rows, nil); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(rows)+1 {
+		t.Fatalf("got %d records, want %d", len(records), len(rows)+1)
+	}
+	for i, record := range records[1:] {
+		if record[1] != want[i] {
+			t.Errorf("row %d: description %q, want %q", i, record[1], want[i])
+		}
+	}
+	if records[1][2] != "-1" {
+		t.Errorf("numbers are escaped: weight %q, want %q", records[1][2], "-1")
+	}
+}
@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/descriptor"
+)
+
+// maxDescriptorSetSize bounds an uploaded FileDescriptorSet.
+const maxDescriptorSetSize = 16 << 20
+
+// DescriptorHandler handles the protobuf descriptor registry: versioned
+// FileDescriptorSets of backends, against which routes are validated and
+// from which gateways get transcoding metadata.
+type DescriptorHandler struct {
+	store       config.Store
+	descriptors config.DescriptorStore
+	logger      *zap.Logger
+}
+
+// NewDescriptorHandler creates a new DescriptorHandler. Descriptor sets are
+// kept in descriptors; uploads are recorded in the history of store.
+func NewDescriptorHandler(store config.Store, descriptors config.DescriptorStore, logger *zap.Logger) *DescriptorHandler {
+	return &DescriptorHandler{
+		store:       store,
+		descriptors: descriptors,
+		logger:      logger,
+	}
+}
+
+// UploadDescriptors stores a serialized FileDescriptorSet, sent as the
+// body, as the next version of a backend's descriptors. Uploading the
+// same set as the latest version returns that version unchanged with 200.
+// POST /api/v1/backends/{name}/descriptors
+func (h *DescriptorHandler) UploadDescriptors(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	backend, err := h.store.GetBackendByName(name)
+	if err != nil {
+		h.logger.Error("failed to get backend", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if backend == nil {
+		http.Error(w, "backend not found", http.StatusNotFound)
+		return
+	}
+
+	data, ok := readDescriptorSet(w, r)
+	if !ok {
+		return
+	}
+	if len(data) == 0 {
+		http.Error(w, "body must be a serialized FileDescriptorSet", http.StatusBadRequest)
+		return
+	}
+	files, err := descriptor.ParseSet(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	set := &config.DescriptorSet{
+		BackendName: backend.Name,
+		SHA256:      hex.EncodeToString(sum[:]),
+		Size:        len(data),
+		Services:    descriptor.Services(files),
+		UploadedBy:  r.Header.Get("X-Operator"),
+		Data:        data,
+	}
+
+	latest, err := h.descriptors.GetDescriptorSet(backend.Name, 0)
+	if err != nil {
+		h.logger.Error("failed to get descriptor set", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if latest != nil && latest.SHA256 == set.SHA256 {
+		h.writeDescriptorSet(w, http.StatusOK, latest)
+		return
+	}
+
+	if err := h.descriptors.CreateDescriptorSet(set); err != nil {
+		h.logger.Error("failed to create descriptor set", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	reason := changeReason(r, "")
+	if err := h.store.CreateHistory(newHistory("descriptor", &set.ID, "CREATE", nil, set, reason, r)); err != nil {
+		h.logger.Warn("failed to record history", zap.Error(err))
+	}
+
+	h.writeDescriptorSet(w, http.StatusCreated, set)
+}
+
+// ListDescriptors returns the descriptor set versions of a backend, newest
+// first.
+// GET /api/v1/backends/{name}/descriptors
+func (h *DescriptorHandler) ListDescriptors(w http.ResponseWriter, r *http.Request) {
+	sets, err := h.descriptors.GetDescriptorSets(chi.URLParam(r, "name"))
+	if err != nil {
+		h.logger.Error("failed to get descriptor sets", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if sets == nil {
+		sets = []config.DescriptorSet{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sets); err != nil {
+		h.logger.Warn("failed to encode descriptor sets", zap.Error(err))
+	}
+}
+
+// GetDescriptors returns a descriptor set version of a backend; {version}
+// may be "latest".
+// GET /api/v1/backends/{name}/descriptors/{version}
+func (h *DescriptorHandler) GetDescriptors(w http.ResponseWriter, r *http.Request) {
+	set, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+	h.writeDescriptorSet(w, http.StatusOK, set)
+}
+
+// DownloadDescriptors returns a descriptor set version as uploaded.
+// GET /api/v1/backends/{name}/descriptors/{version}/download
+func (h *DescriptorHandler) DownloadDescriptors(w http.ResponseWriter, r *http.Request) {
+	set, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeDescriptorData(w, h.logger, set)
+}
+
+// lookup loads the descriptor set named by the {name} and {version} URL
+// parameters, writing an error response if it cannot.
+func (h *DescriptorHandler) lookup(w http.ResponseWriter, r *http.Request) (*config.DescriptorSet, bool) {
+	version := 0
+	if param := chi.URLParam(r, "version"); param != "latest" {
+		v, err := strconv.Atoi(param)
+		if err != nil || v < 1 {
+			http.Error(w, "invalid descriptor version (must be a positive number or 'latest')", http.StatusBadRequest)
+			return nil, false
+		}
+		version = v
+	}
+
+	set, err := h.descriptors.GetDescriptorSet(chi.URLParam(r, "name"), version)
+	if err != nil {
+		h.logger.Error("failed to get descriptor set", zap.Error(err))
+		writeServerError(w, err)
+		return nil, false
+	}
+	if set == nil {
+		http.Error(w, "descriptor set not found", http.StatusNotFound)
+		return nil, false
+	}
+	return set, true
+}
+
+func (h *DescriptorHandler) writeDescriptorSet(w http.ResponseWriter, status int, set *config.DescriptorSet) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(set); err != nil {
+		h.logger.Warn("failed to encode descriptor set", zap.Error(err))
+	}
+}
+
+// writeDescriptorData writes the serialized FileDescriptorSet of set.
+func writeDescriptorData(w http.ResponseWriter, logger *zap.Logger, set *config.DescriptorSet) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-v%d.pb"`, set.BackendName, set.Version))
+	w.Header().Set("X-Descriptor-Version", strconv.Itoa(set.Version))
+	w.Header().Set("X-Descriptor-SHA256", set.SHA256)
+	if _, err := w.Write(set.Data); err != nil {
+		logger.Warn("failed to write descriptor set", zap.Error(err))
+	}
+}
+
+// readDescriptorSet reads a request body of at most maxDescriptorSetSize
+// bytes, writing an error response if it cannot.
+func readDescriptorSet(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	defer r.Body.Close()
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDescriptorSetSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("descriptor set too large (at most %d bytes)", maxDescriptorSetSize), http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return nil, false
+	}
+	return data, true
+}
+
+// descriptorCheck checks that routes call a unary RPC defined in the
+// latest descriptor set of their backend. Backends without descriptor
+// sets are not checked. Each set is loaded once.
+type descriptorCheck struct {
+	store config.DescriptorStore
+	sets  map[string]*parsedDescriptors
+}
+
+// parsedDescriptors is a loaded descriptor set, nil for a backend without
+// one.
+type parsedDescriptors struct {
+	version int
+	files   *protoregistry.Files
+}
+
+// newDescriptorCheck returns a check against store, which may be nil to
+// check nothing.
+func newDescriptorCheck(store config.DescriptorStore) *descriptorCheck {
+	return &descriptorCheck{store: store, sets: map[string]*parsedDescriptors{}}
+}
+
+// check returns why route does not match the descriptors of its backend,
+// or "" if it does or there are none. Query parameters must map to fields
+// of the request message.
+func (c *descriptorCheck) check(route *config.Route) (string, error) {
+	parsed, err := c.load(route.BackendName)
+	if err != nil || parsed == nil {
+		return "", err
+	}
+
+	md, err := descriptor.Method(parsed.files, route.BackendService, route.BackendMethod)
+	if err != nil {
+		return fmt.Sprintf("%v in descriptor set version %d of backend %s", err, parsed.version, route.BackendName), nil
+	}
+	for i, p := range route.QueryParams {
+		fd, err := descriptor.Field(md.Input(), p.Field)
+		if err == nil && p.Default != "" {
+			err = descriptor.CheckValue(fd, p.Default)
+		}
+		if err != nil {
+			return fmt.Sprintf("query_params[%d]: %v in descriptor set version %d of backend %s", i, err, parsed.version, route.BackendName), nil
+		}
+	}
+	return "", nil
+}
+
+// load returns the latest descriptor set of backend, or nil if it has
+// none.
+func (c *descriptorCheck) load(backend string) (*parsedDescriptors, error) {
+	if c.store == nil {
+		return nil, nil
+	}
+	if parsed, ok := c.sets[backend]; ok {
+		return parsed, nil
+	}
+
+	set, err := c.store.GetDescriptorSet(backend, 0)
+	if err != nil {
+		return nil, err
+	}
+	var parsed *parsedDescriptors
+	if set != nil {
+		files, err := descriptor.ParseSet(set.Data)
+		if err != nil {
+			return nil, fmt.Errorf("descriptor set %d of %s: %w", set.Version, set.BackendName, err)
+		}
+		parsed = &parsedDescriptors{version: set.Version, files: files}
+	}
+	c.sets[backend] = parsed
+	return parsed, nil
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/apply"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/seed"
+)
+
+// DevHandler serves development-only endpoints. It is only mounted when
+// ADMIN_DEV_ENDPOINTS is enabled.
+type DevHandler struct {
+	store     config.Store
+	admission admission.Controller
+	logger    *zap.Logger
+}
+
+// NewDevHandler creates a new DevHandler.
+func NewDevHandler(store config.Store, admission admission.Controller, logger *zap.Logger) *DevHandler {
+	return &DevHandler{
+		store:     store,
+		admission: admission,
+		logger:    logger,
+	}
+}
+
+// Seed loads the sample backends and routes, leaving existing resources
+// untouched. Admin only.
+// POST /api/v1/dev/seed
+func (h *DevHandler) Seed(w http.ResponseWriter, r *http.Request) {
+	plan, err := seed.Plan(h.store)
+	if err != nil {
+		h.logger.Error("failed to plan seed", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	actor := apply.Actor{
+		Operator:       r.Header.Get("X-Operator"),
+		Role:           operatorRole(r),
+		Teams:          operatorTeams(r),
+		Reason:         changeReason(r, seed.Reason),
+		FreezeOverride: freezeOverride(r),
+	}
+	if err := apply.Admit(r.Context(), h.admission, plan, actor); err != nil {
+		writeAdmissionError(w, h.logger, err)
+		return
+	}
+
+	response := applyResponse{Plan: plan}
+	if plan.HasChanges() {
+		if err := apply.Execute(h.store, plan, actor); err != nil {
+			writeExecuteError(w, h.logger, "failed to seed", err)
+			return
+		}
+		response.Applied = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode seed response", zap.Error(err))
+	}
+}
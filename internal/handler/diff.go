@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// ignoredChangeFields are bookkeeping fields left out of history changes.
+var ignoredChangeFields = map[string]bool{"id": true, "revision": true, "created_at": true, "updated_at": true}
+
+// FieldChange is one field that differs between the old and new value of a
+// history entry. Nested objects are compared field by field and named by
+// their dotted path, e.g. "credential.type"; arrays are compared whole. A
+// field missing on one side has a null value there.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// historyEntry is a history entry as returned by the API.
+type historyEntry struct {
+	config.ConfigHistory
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// diffValues lists the fields that differ between two JSON objects, sorted
+// by path. Either side may be empty, as for creates and deletes.
+func diffValues(old, cur json.RawMessage) []FieldChange {
+	var o, c map[string]interface{}
+	if len(old) > 0 {
+		json.Unmarshal(old, &o)
+	}
+	if len(cur) > 0 {
+		json.Unmarshal(cur, &c)
+	}
+
+	changes := []FieldChange{}
+	diffObjects("", o, c, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func diffObjects(prefix string, old, cur map[string]interface{}, changes *[]FieldChange) {
+	keys := map[string]bool{}
+	for k := range old {
+		keys[k] = true
+	}
+	for k := range cur {
+		keys[k] = true
+	}
+
+	for k := range keys {
+		if prefix == "" && ignoredChangeFields[k] {
+			continue
+		}
+		o, c := old[k], cur[k]
+		if reflect.DeepEqual(o, c) {
+			continue
+		}
+		oo, oIsObject := o.(map[string]interface{})
+		co, cIsObject := c.(map[string]interface{})
+		if (oIsObject || o == nil) && (cIsObject || c == nil) {
+			diffObjects(prefix+k+".", oo, co, changes)
+			continue
+		}
+		*changes = append(*changes, FieldChange{Field: prefix + k, Old: o, New: c})
+	}
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+const (
+	// maxGatewayIDLength bounds the instance IDs gateways report under.
+	maxGatewayIDLength = 128
+	// maxGatewayFailureLength bounds the error gateways report when they
+	// fail to apply a config.
+	maxGatewayFailureLength = 512
+	// defaultDriftWindow is how recently a gateway must have been seen to
+	// be considered by the drift report.
+	defaultDriftWindow = time.Hour
+)
+
+// sha256Hex matches a lowercase hex SHA-256.
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// gatewayStore returns the store's gateway capability, answering 501 if it
+// has none.
+func (h *GatewayHandler) gatewayStore(w http.ResponseWriter) (config.GatewayStore, bool) {
+	gs, ok := h.store.(config.GatewayStore)
+	if !ok {
+		http.Error(w, "gateway reports are not supported by this store", http.StatusNotImplemented)
+	}
+	return gs, ok
+}
+
+// ReportConfig records which config a gateway instance has loaded, as the
+// X-Config-SHA256 and revision of the payload it last applied, and answers
+// whether that is the current config of its cluster. A gateway that failed
+// to apply a payload reports its revision with the error instead.
+// POST /api/v1/gateway/config-status
+func (h *GatewayHandler) ReportConfig(w http.ResponseWriter, r *http.Request) {
+	gs, ok := h.gatewayStore(w)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Gateway      string `json:"gateway"`
+		Cluster      string `json:"cluster"`
+		ConfigSHA256 string `json:"config_sha256"`
+		Revision     uint64 `json:"revision"`
+		Error        string `json:"error"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Gateway == "" || len(req.Gateway) > maxGatewayIDLength {
+		http.Error(w, "gateway is required (at most 128 characters)", http.StatusBadRequest)
+		return
+	}
+	if req.Cluster != "" {
+		if err := config.ValidateClusterName(req.Cluster); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Error != "" {
+		if req.Revision == 0 || len(req.Error) > maxGatewayFailureLength {
+			http.Error(w, "a failure needs the revision and an error of at most 512 characters", http.StatusBadRequest)
+			return
+		}
+	} else if !sha256Hex.MatchString(req.ConfigSHA256) {
+		http.Error(w, "config_sha256 must be a lowercase hex SHA-256", http.StatusBadRequest)
+		return
+	}
+
+	payload, err := h.ServedPayload(r.Context(), req.Gateway, req.Cluster)
+	if err != nil {
+		h.logger.Error("failed to compile config", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	if req.Error != "" {
+		err = gs.ReportGatewayFailure(req.Gateway, req.Cluster, req.Revision, req.Error)
+		h.logger.Warn("gateway failed to apply config", zap.String("gateway", req.Gateway), zap.Uint64("revision", req.Revision), zap.String("error", req.Error))
+	} else {
+		err = gs.ReportGatewayConfig(req.Gateway, req.Cluster, req.ConfigSHA256, req.Revision)
+	}
+	if err != nil {
+		h.logger.Error("failed to record gateway config", zap.String("gateway", req.Gateway), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	expected := payloadSHA256(payload)
+	response := map[string]interface{}{
+		"in_sync":         req.Error == "" && req.ConfigSHA256 == expected,
+		"expected_sha256": expected,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode config status", zap.Error(err))
+	}
+}
+
+// ListDrift returns the gateways whose loaded config differs from the
+// current one of their cluster, among those seen within window (default
+// 1h) that have reported a config. expected_sha256 is keyed by cluster,
+// with "" for gateways outside any cluster. While a config rollout is in
+// progress, gateways other than its canaries are expected to have the
+// baseline, listed as baseline_sha256.
+// GET /api/v1/gateways/drift?window=1h
+func (h *GatewayHandler) ListDrift(w http.ResponseWriter, r *http.Request) {
+	gs, ok := h.gatewayStore(w)
+	if !ok {
+		return
+	}
+
+	window, ok := gatewayWindow(w, r)
+	if !ok {
+		return
+	}
+
+	gateways, err := gs.GetGateways()
+	if err != nil {
+		h.logger.Error("failed to get gateways", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	rollout, err := h.activeRollout()
+	if err != nil {
+		h.logger.Error("failed to get active rollout", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	since := time.Now().Add(-window)
+	expected := map[string]string{}
+	baseline := map[string]string{}
+	inSync := 0
+	drifted := []config.GatewayInstance{}
+	for _, g := range gateways {
+		if g.LastSeenAt.Before(since) || g.ConfigSHA256 == "" {
+			continue
+		}
+		sums := expected
+		if rollout != nil && !rollout.Selects(&g) {
+			sums = baseline
+		}
+		sum, ok := sums[g.Cluster]
+		if !ok {
+			payload, err := h.configPayload(r.Context(), rollout, &g, g.Cluster)
+			if err != nil {
+				h.logger.Error("failed to compile config", zap.String("cluster", g.Cluster), zap.Error(err))
+				writeServerError(w, err)
+				return
+			}
+			sum = payloadSHA256(payload)
+			sums[g.Cluster] = sum
+		}
+		if g.ConfigSHA256 == sum {
+			inSync++
+		} else {
+			drifted = append(drifted, g)
+		}
+	}
+
+	response := map[string]interface{}{
+		"expected_sha256": expected,
+		"in_sync":         inSync,
+		"out_of_sync":     drifted,
+	}
+	if rollout != nil {
+		response["baseline_sha256"] = baseline
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode drift report", zap.Error(err))
+	}
+}
+
+// gatewayWindow returns the ?window= of the request, how recently
+// gateways must have been seen to be considered, writing an error
+// response if it is invalid.
+func gatewayWindow(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
+	param := r.URL.Query().Get("window")
+	if param == "" {
+		return defaultDriftWindow, true
+	}
+	d, err := parseWindow(param)
+	if err != nil || d <= 0 {
+		http.Error(w, "invalid window: must be a positive duration", http.StatusBadRequest)
+		return 0, false
+	}
+	return d, true
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// errDryRun rolls back the transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// parseDryRun reads the dry_run query parameter of a change, writing an
+// error response if it is invalid.
+func parseDryRun(w http.ResponseWriter, r *http.Request) (dryRun, ok bool) {
+	param := r.URL.Query().Get("dry_run")
+	if param == "" {
+		return false, true
+	}
+	dryRun, err := strconv.ParseBool(param)
+	if err != nil {
+		http.Error(w, "invalid dry_run parameter", http.StatusBadRequest)
+		return false, false
+	}
+	return dryRun, true
+}
+
+// commit runs fn, which makes a change and records its history, in a
+// transaction. On a dry run the transaction is rolled back once fn has
+// succeeded, so that the store's own checks (such as uniqueness) still
+// apply but nothing is persisted, and the response is marked with an
+// X-Dry-Run header.
+func commit(store config.Store, w http.ResponseWriter, dryRun bool, fn func(tx config.Store) error) error {
+	err := store.InTx(func(tx config.Store) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if dryRun && errors.Is(err, errDryRun) {
+		w.Header().Set("X-Dry-Run", "true")
+		return nil
+	}
+	return err
+}
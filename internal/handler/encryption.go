@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/jobs"
+)
+
+// SecretRotator re-encrypts sensitive values with the current primary key.
+type SecretRotator interface {
+	RotateSecrets() (int, error)
+}
+
+// EncryptionHandler handles encryption key management requests.
+type EncryptionHandler struct {
+	rotator SecretRotator
+	jobs    *jobs.Runner
+	logger  *zap.Logger
+}
+
+// NewEncryptionHandler creates a new EncryptionHandler. If runner is not
+// nil, secrets can be rotated in the background.
+func NewEncryptionHandler(rotator SecretRotator, runner *jobs.Runner, logger *zap.Logger) *EncryptionHandler {
+	h := &EncryptionHandler{
+		rotator: rotator,
+		jobs:    runner,
+		logger:  logger,
+	}
+	if runner != nil {
+		runner.Register(jobSecretRotation, h.runRotation)
+	}
+	return h
+}
+
+// RotateKeys re-encrypts every stored secret that was written with an old
+// key. Run it after promoting a new primary key, before retiring the old one.
+// With async=true the secrets are rotated by a background job, whose result
+// is the response RotateKeys would have returned.
+// POST /api/v1/encryption/rotate?async=true
+func (h *EncryptionHandler) RotateKeys(w http.ResponseWriter, r *http.Request) {
+	async, ok := parseAsync(w, r, h.jobs)
+	if !ok {
+		return
+	}
+	if async {
+		submitJob(w, r, h.jobs, jobSecretRotation, struct{}{}, h.logger)
+		return
+	}
+
+	rotated, err := h.rotator.RotateSecrets()
+	if err != nil {
+		h.logger.Error("failed to rotate secrets", zap.Int("rotated", rotated), zap.Error(err))
+		if errors.Is(err, config.ErrEncryptionDisabled) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("rotated secrets", zap.Int("rotated", rotated))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]int{"rotated": rotated}); err != nil {
+		h.logger.Warn("failed to encode rotation result", zap.Error(err))
+	}
+}
+
+// runRotation runs a secret rotation job.
+func (h *EncryptionHandler) runRotation(ctx context.Context, params json.RawMessage, progress func(done, total int)) (interface{}, error) {
+	rotated, err := h.rotator.RotateSecrets()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int{"rotated": rotated}, nil
+}
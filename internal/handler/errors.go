@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// writeServerError responds to a request that failed with err through no
+// fault of the caller: 503 Service Unavailable with Retry-After while the
+// store's circuit breaker is open, and 500 otherwise. The caller logs err.
+func writeServerError(w http.ResponseWriter, err error) {
+	if unavailable(w, err) {
+		http.Error(w, "database unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+// unavailable reports whether err comes from an open circuit breaker, and
+// if so sets the Retry-After header to when it next probes the database.
+func unavailable(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, config.ErrUnavailable) {
+		return false
+	}
+	retryAfter := 1
+	var uerr *config.UnavailableError
+	if errors.As(err, &uerr) {
+		retryAfter = max(retryAfter, int(math.Ceil(uerr.RetryAfter.Seconds())))
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	return true
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/openapi"
+)
+
+// defaultAPITitle is the title of exported OpenAPI documents.
+const defaultAPITitle = "API Gateway"
+
+// ExportHandler exports the configuration in formats meant for other
+// tools.
+type ExportHandler struct {
+	store  config.Store
+	logger *zap.Logger
+}
+
+// NewExportHandler creates a new ExportHandler.
+func NewExportHandler(store config.Store, logger *zap.Logger) *ExportHandler {
+	return &ExportHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// ExportOpenAPI describes the routes the gateway serves as an OpenAPI 3
+// document, in JSON or, with format=yaml, YAML. title and version set the
+// document info; server adds the base URL consumers call the gateway at.
+// GET /api/v1/export/openapi?format=yaml&title=&version=&server=
+func (h *ExportHandler) ExportOpenAPI(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "yaml" {
+		http.Error(w, "invalid format parameter (must be 'json' or 'yaml')", http.StatusBadRequest)
+		return
+	}
+
+	info := openapi.Info{Title: query.Get("title"), Version: query.Get("version")}
+	if info.Title == "" {
+		info.Title = defaultAPITitle
+	}
+
+	enabled := true
+	var doc *openapi.Document
+	err := h.store.InTx(func(tx config.Store) error {
+		backends, err := tx.GetBackends(&enabled)
+		if err != nil {
+			return err
+		}
+		routes, err := tx.GetRoutes(&enabled)
+		if err != nil {
+			return err
+		}
+		doc = openapi.Build(backends, routes, info)
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("failed to read configuration for openapi export", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if server := query.Get("server"); server != "" {
+		doc.Servers = []openapi.Server{{URL: server}}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err == nil && format == "yaml" {
+		data, err = yaml.JSONToYAML(data)
+	}
+	if err != nil {
+		h.logger.Error("failed to encode openapi document", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	if format == "yaml" {
+		w.Header().Set("Content-Type", "application/yaml")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	if _, err := w.Write(data); err != nil {
+		h.logger.Warn("failed to write openapi document", zap.Error(err))
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// fieldSet is the set of top-level JSON fields requested via ?fields=.
+// A nil fieldSet means no projection was requested.
+type fieldSet map[string]struct{}
+
+// parseFields parses the ?fields=a,b,c query parameter and validates every
+// name against the JSON fields of the given item type.
+func parseFields(r *http.Request, item interface{}) (fieldSet, error) {
+	param := r.URL.Query().Get("fields")
+	if param == "" {
+		return nil, nil
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(item))
+	fields := fieldSet{}
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := known[name]; !ok {
+			return nil, fmt.Errorf("unknown field: %s", name)
+		}
+		fields[name] = struct{}{}
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// project reduces a slice of items to the requested fields. It returns the
+// input unchanged when no projection was requested.
+func project(items interface{}, fields fieldSet) (interface{}, error) {
+	if fields == nil {
+		return items, nil
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		for key := range row {
+			if _, ok := fields[key]; !ok {
+				delete(row, key)
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+// jsonFieldNames returns the JSON names of the exported fields of a struct type.
+func jsonFieldNames(t reflect.Type) map[string]struct{} {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	names := map[string]struct{}{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		// Untagged embedded structs are flattened by encoding/json
+		if f.Anonymous && f.Tag.Get("json") == "" && f.Type.Kind() == reflect.Struct {
+			for name := range jsonFieldNames(f.Type) {
+				names[name] = struct{}{}
+			}
+			continue
+		}
+		if name, ok := jsonFieldName(f); ok {
+			names[name] = struct{}{}
+		}
+	}
+	return names
+}
+
+// jsonFieldName returns the JSON name of a struct field, or false if the
+// field is unexported or excluded from encoding.
+func jsonFieldName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	name := f.Name
+	if tag := f.Tag.Get("json"); tag != "" {
+		if tag == "-" {
+			return "", false
+		}
+		if n := strings.Split(tag, ",")[0]; n != "" {
+			name = n
+		}
+	}
+	return name, true
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// FreezeHandler handles change freeze window requests.
+type FreezeHandler struct {
+	store  config.Store
+	logger *zap.Logger
+}
+
+// NewFreezeHandler creates a new FreezeHandler.
+func NewFreezeHandler(store config.Store, logger *zap.Logger) *FreezeHandler {
+	return &FreezeHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// freezeWindowView is a freeze window together with its current state.
+type freezeWindowView struct {
+	config.FreezeWindow
+	Active      bool       `json:"active"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+}
+
+// ListFreezeWindows returns all freeze windows, optionally only the active ones.
+// GET /api/v1/freeze-windows?active=true
+func (h *FreezeHandler) ListFreezeWindows(w http.ResponseWriter, r *http.Request) {
+	var activeOnly bool
+	if param := r.URL.Query().Get("active"); param != "" {
+		val, err := strconv.ParseBool(param)
+		if err != nil {
+			http.Error(w, "invalid active parameter", http.StatusBadRequest)
+			return
+		}
+		activeOnly = val
+	}
+
+	windows, err := h.store.GetFreezeWindows()
+	if err != nil {
+		h.logger.Error("failed to get freeze windows", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	now := time.Now()
+	views := []freezeWindowView{}
+	for _, fw := range windows {
+		view := newFreezeWindowView(fw, now)
+		if activeOnly && !view.Active {
+			continue
+		}
+		views = append(views, view)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(views); err != nil {
+		h.logger.Warn("failed to encode freeze windows", zap.Error(err))
+	}
+}
+
+// CreateFreezeWindow creates a new freeze window. Admin only.
+// POST /api/v1/freeze-windows
+func (h *FreezeHandler) CreateFreezeWindow(w http.ResponseWriter, r *http.Request) {
+	window := config.FreezeWindow{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&window); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := window.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	window.CreatedBy = auth.FromContext(r.Context()).Name
+
+	if err := h.store.CreateFreezeWindow(&window); err != nil {
+		h.logger.Error("failed to create freeze window", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Warn("freeze window created",
+		zap.Uint("id", window.ID),
+		zap.String("name", window.Name),
+		zap.String("operator", window.CreatedBy),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(newFreezeWindowView(window, time.Now())); err != nil {
+		h.logger.Warn("failed to encode freeze window", zap.Error(err))
+	}
+}
+
+// UpdateFreezeWindow replaces an existing freeze window. Admin only.
+// PUT /api/v1/freeze-windows/{id}
+func (h *FreezeHandler) UpdateFreezeWindow(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.windowID(w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := h.store.GetFreezeWindowByID(id)
+	if err != nil {
+		h.logger.Error("failed to get freeze window", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "freeze window not found", http.StatusNotFound)
+		return
+	}
+
+	window := config.FreezeWindow{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&window); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := window.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.UpdateFreezeWindow(id, &window); err != nil {
+		h.logger.Error("failed to update freeze window", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	window.CreatedBy = existing.CreatedBy
+	window.CreatedAt = existing.CreatedAt
+
+	h.logger.Warn("freeze window updated",
+		zap.Uint("id", id),
+		zap.String("name", window.Name),
+		zap.String("operator", auth.FromContext(r.Context()).Name),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(newFreezeWindowView(window, time.Now())); err != nil {
+		h.logger.Warn("failed to encode freeze window", zap.Error(err))
+	}
+}
+
+// DeleteFreezeWindow removes a freeze window. Admin only.
+// DELETE /api/v1/freeze-windows/{id}
+func (h *FreezeHandler) DeleteFreezeWindow(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.windowID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteFreezeWindow(id); err != nil {
+		if err.Error() == "freeze window not found" {
+			http.Error(w, "freeze window not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to delete freeze window", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Warn("freeze window deleted",
+		zap.Uint("id", id),
+		zap.String("operator", auth.FromContext(r.Context()).Name),
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *FreezeHandler) windowID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func newFreezeWindowView(fw config.FreezeWindow, now time.Time) freezeWindowView {
+	view := freezeWindowView{FreezeWindow: fw}
+	if active, until := fw.ActiveAt(now); active {
+		view.Active = true
+		view.ActiveUntil = &until
+	}
+	return view
+}
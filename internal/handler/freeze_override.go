@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+)
+
+// freezeOverride reports whether the caller asked to bypass an active
+// change freeze, via the X-Freeze-Override header or the freeze_override
+// query parameter. Whether the caller may do so is left to admission.
+func freezeOverride(r *http.Request) bool {
+	value := r.Header.Get("X-Freeze-Override")
+	if value == "" {
+		value = r.URL.Query().Get("freeze_override")
+	}
+	override, _ := strconv.ParseBool(value)
+	return override
+}
+
+// operatorRole returns the role of the authenticated caller, if any.
+func operatorRole(r *http.Request) string {
+	if p := auth.FromContext(r.Context()); p != nil {
+		return p.Role
+	}
+	return ""
+}
+
+// operatorTeams returns the teams of the authenticated caller, if any.
+func operatorTeams(r *http.Request) []string {
+	if p := auth.FromContext(r.Context()); p != nil {
+		return p.Teams
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/compile"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/signing"
+)
+
+// ConfigCache caches the compiled gateway config payload of each gateway
+// cluster.
+type ConfigCache interface {
+	Get(ctx context.Context, cluster string, build func() ([]byte, error)) ([]byte, error)
+}
+
+// GatewayHandler serves configuration to gateway instances. Its routes,
+// except GetSigningKey and ExportFile, must only be mounted behind gateway
+// authentication.
+type GatewayHandler struct {
+	store  config.Store
+	signer *signing.Signer
+	cache  ConfigCache
+	logger *zap.Logger
+
+	// baselines holds compiled rollout baselines, which never change.
+	mu        sync.Mutex
+	baselines map[baselineKey][]byte
+	// protos holds the protobuf encodings of recent payloads by checksum.
+	protos map[string][]byte
+}
+
+// NewGatewayHandler creates a new GatewayHandler. signer may be nil, in
+// which case config payloads are served unsigned; cache may be nil, in
+// which case config is compiled from the store on every request.
+func NewGatewayHandler(store config.Store, signer *signing.Signer, cache ConfigCache, logger *zap.Logger) *GatewayHandler {
+	return &GatewayHandler{
+		store:     store,
+		signer:    signer,
+		cache:     cache,
+		logger:    logger,
+		baselines: map[baselineKey][]byte{},
+		protos:    map[string][]byte{},
+	}
+}
+
+// GetConfig returns the compiled configuration for gateways. Its SHA-256
+// is sent in the X-Config-SHA256 header, for gateways to report back once
+// loaded, and its revision in X-Config-Revision. When signing is enabled, a detached Ed25519 signature over the
+// exact response body is sent in the X-Config-Signature header. Gateways
+// of a cluster pass it as ?cluster= to receive the backends and routes
+// scoped to it besides the unscoped ones. While a config rollout is in
+// progress, gateways pass their instance ID as ?gateway= to be told apart
+// as canaries. Gateways sending Accept: application/x-protobuf receive the
+// config as a configv1.GatewayConfig message instead; X-Config-SHA256 is
+// still that of the JSON encoding, which gateways report back, while the
+// signature covers the protobuf body.
+// GET /api/v1/gateway/config?cluster=&gateway=
+func (h *GatewayHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	payload, ok := h.payload(w, r)
+	if !ok {
+		return
+	}
+
+	contentType := "application/json"
+	if acceptsProtobuf(r) {
+		data, err := h.protoPayload(payload)
+		if err != nil {
+			h.logger.Error("failed to encode config", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		payload, contentType = data, protobufContentType
+	}
+	h.sign(w, payload)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	if _, err := w.Write(payload); err != nil {
+		h.logger.Warn("failed to write config", zap.Error(err))
+	}
+}
+
+// ExportFile returns the compiled configuration as the config file that
+// file-based gateway deployments load and hot-reload. It is byte for byte
+// the payload of GetConfig, signed the same way, and like it holds no
+// backend credentials.
+// GET /api/v1/export/gateway-file?cluster=&gateway=
+func (h *GatewayHandler) ExportFile(w http.ResponseWriter, r *http.Request) {
+	payload, ok := h.payload(w, r)
+	if !ok {
+		return
+	}
+
+	h.sign(w, payload)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="gateway.json"`)
+	if _, err := w.Write(payload); err != nil {
+		h.logger.Warn("failed to write gateway file", zap.Error(err))
+	}
+}
+
+// compiledArtifact is the current config of a cluster as a pinnable
+// build.
+type compiledArtifact struct {
+	Revision uint64 `json:"revision"`
+	Cluster  string `json:"cluster,omitempty"`
+	// SHA256 is the checksum of the exact bytes of Config.
+	SHA256         string          `json:"sha256"`
+	Signature      string          `json:"signature,omitempty"`
+	SignatureKeyID string          `json:"signature_key_id,omitempty"`
+	Config         json.RawMessage `json:"config"`
+}
+
+// GetCompiledConfig returns the current config of the ?cluster= as a
+// single deterministic artifact: the enabled backends and routes, sorted
+// and with defaults applied, along with its revision and the SHA-256 of
+// the config bytes, so that gateways and CI can verify and pin exact
+// builds. The same configuration always yields the same bytes. Unlike
+// GetConfig, it ignores config rollouts. When signing is enabled, the
+// config bytes are signed as well.
+// GET /api/v1/gateway/config/compiled?cluster=
+func (h *GatewayHandler) GetCompiledConfig(w http.ResponseWriter, r *http.Request) {
+	cluster := r.URL.Query().Get("cluster")
+	if cluster != "" {
+		if err := config.ValidateClusterName(cluster); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	payload, err := h.currentPayload(r.Context(), cluster)
+	if err != nil {
+		h.logger.Error("failed to compile config", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	revision, err := payloadRevision(payload)
+	if err != nil {
+		h.logger.Error("failed to decode config", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	artifact := compiledArtifact{
+		Revision: revision,
+		Cluster:  cluster,
+		SHA256:   payloadSHA256(payload),
+		Config:   payload,
+	}
+	if h.signer != nil {
+		artifact.Signature = h.signer.Sign(payload)
+		artifact.SignatureKeyID = h.signer.KeyID()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Config-SHA256", artifact.SHA256)
+	w.Header().Set("X-Config-Revision", strconv.FormatUint(revision, 10))
+	if err := json.NewEncoder(w).Encode(artifact); err != nil {
+		h.logger.Warn("failed to encode compiled config", zap.Error(err))
+	}
+}
+
+// payload returns the encoded gateway config served to the ?gateway= of
+// the request in its ?cluster=, and sets its checksum and revision
+// headers. It writes an error response if the parameters are invalid or
+// the config cannot be compiled.
+func (h *GatewayHandler) payload(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	query := r.URL.Query()
+	cluster := query.Get("cluster")
+	if cluster != "" {
+		if err := config.ValidateClusterName(cluster); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	gatewayID := query.Get("gateway")
+	if len(gatewayID) > maxGatewayIDLength {
+		http.Error(w, "gateway too long (at most 128 characters)", http.StatusBadRequest)
+		return nil, false
+	}
+
+	payload, err := h.ServedPayload(r.Context(), gatewayID, cluster)
+	if err != nil {
+		h.logger.Error("failed to compile config", zap.Error(err))
+		writeServerError(w, err)
+		return nil, false
+	}
+	revision, err := payloadRevision(payload)
+	if err != nil {
+		h.logger.Error("failed to decode config", zap.Error(err))
+		writeServerError(w, err)
+		return nil, false
+	}
+
+	w.Header().Set("X-Config-SHA256", payloadSHA256(payload))
+	w.Header().Set("X-Config-Revision", strconv.FormatUint(revision, 10))
+	return payload, true
+}
+
+// sign sets the signature headers over body when signing is enabled.
+func (h *GatewayHandler) sign(w http.ResponseWriter, body []byte) {
+	if h.signer == nil {
+		return
+	}
+	w.Header().Set("X-Config-Signature", h.signer.Sign(body))
+	w.Header().Set("X-Config-Signature-Algorithm", signing.Algorithm)
+	w.Header().Set("X-Config-Signature-Key-Id", h.signer.KeyID())
+}
+
+// currentPayload returns the encoded gateway config of cluster, from the
+// cache if there is one.
+func (h *GatewayHandler) currentPayload(ctx context.Context, cluster string) ([]byte, error) {
+	build := func() ([]byte, error) { return h.buildConfig(cluster) }
+	if h.cache != nil {
+		return h.cache.Get(ctx, cluster, build)
+	}
+	return build()
+}
+
+// payloadSHA256 returns the hex SHA-256 of a gateway config payload.
+func payloadSHA256(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// payloadRevision returns the revision of a gateway config payload.
+func payloadRevision(payload []byte) (uint64, error) {
+	var header struct {
+		Revision uint64 `json:"revision"`
+	}
+	if err := json.Unmarshal(payload, &header); err != nil {
+		return 0, err
+	}
+	return header.Revision, nil
+}
+
+// buildConfig compiles and encodes the current gateway config of cluster.
+func (h *GatewayHandler) buildConfig(cluster string) ([]byte, error) {
+	cfg, err := compile.Build(h.store, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(cfg)
+}
+
+// GetSigningKey returns the public key gateways use to verify config
+// signatures.
+// GET /api/v1/gateway/signing-key
+func (h *GatewayHandler) GetSigningKey(w http.ResponseWriter, r *http.Request) {
+	if h.signer == nil {
+		http.Error(w, "config signing is not enabled", http.StatusNotFound)
+		return
+	}
+
+	response := map[string]string{
+		"key_id":     h.signer.KeyID(),
+		"algorithm":  signing.Algorithm,
+		"public_key": h.signer.PublicKey(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode signing key", zap.Error(err))
+	}
+}
+
+// gatewayCredential is the plaintext credential and TLS material delivered
+// to gateways.
+type gatewayCredential struct {
+	Backend   string      `json:"backend"`
+	Type      string      `json:"type,omitempty"`
+	Username  string      `json:"username,omitempty"`
+	Secret    string      `json:"secret,omitempty"`
+	SecretRef string      `json:"secret_ref,omitempty"`
+	TLS       *gatewayTLS `json:"tls,omitempty"`
+}
+
+// gatewayTLS is BackendTLS without redaction of the client key.
+type gatewayTLS struct {
+	ServerName         string `json:"server_name,omitempty"`
+	CACert             string `json:"ca_cert,omitempty"`
+	ClientCert         string `json:"client_cert,omitempty"`
+	ClientKey          string `json:"client_key,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// ListCredentials returns decrypted credentials and TLS material for all
+// enabled backends.
+// GET /api/v1/gateway/credentials
+func (h *GatewayHandler) ListCredentials(w http.ResponseWriter, r *http.Request) {
+	enabled := true
+	backends, err := h.store.GetBackends(&enabled)
+	if err != nil {
+		h.logger.Error("failed to get backends", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	credentials := []gatewayCredential{}
+	for _, b := range backends {
+		hasCredential := b.Credential != nil && b.Credential.Type != config.CredentialNone
+		if !hasCredential && b.TLS == nil {
+			continue
+		}
+
+		c := gatewayCredential{Backend: b.Name}
+		if hasCredential {
+			c.Type = b.Credential.Type
+			c.Username = b.Credential.Username
+			c.Secret = b.Credential.Secret
+			c.SecretRef = b.Credential.SecretRef
+		}
+		if b.TLS != nil {
+			tls := gatewayTLS(*b.TLS)
+			c.TLS = &tls
+		}
+		credentials = append(credentials, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(credentials); err != nil {
+		h.logger.Warn("failed to encode credentials", zap.Error(err))
+	}
+}
+
+// GetDescriptors returns a backend's descriptor set as uploaded: the
+// version given by ?version=, or the latest one, which the compiled config
+// references.
+// GET /api/v1/gateway/descriptors/{name}?version=
+func (h *GatewayHandler) GetDescriptors(w http.ResponseWriter, r *http.Request) {
+	descriptors, ok := h.store.(config.DescriptorStore)
+	if !ok {
+		http.Error(w, "descriptor sets are not supported by this store", http.StatusNotImplemented)
+		return
+	}
+
+	version := 0
+	if param := r.URL.Query().Get("version"); param != "" {
+		v, err := strconv.Atoi(param)
+		if err != nil || v < 1 {
+			http.Error(w, "invalid version parameter", http.StatusBadRequest)
+			return
+		}
+		version = v
+	}
+
+	set, err := descriptors.GetDescriptorSet(chi.URLParam(r, "name"), version)
+	if err != nil {
+		h.logger.Error("failed to get descriptor set", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if set == nil {
+		http.Error(w, "descriptor set not found", http.StatusNotFound)
+		return
+	}
+	writeDescriptorData(w, h.logger, set)
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/compile"
+)
+
+const (
+	// protobufContentType is the media type of config payloads encoded as
+	// configv1 messages.
+	protobufContentType = "application/x-protobuf"
+	// maxProtoPayloads bounds the protobuf encodings kept of recent config
+	// payloads.
+	maxProtoPayloads = 64
+)
+
+// protoMarshal encodes deterministically, so that the same config always
+// encodes to the same bytes, as in JSON.
+var protoMarshal = proto.MarshalOptions{Deterministic: true}
+
+// acceptsProtobuf reports whether the Accept header of r prefers
+// application/x-protobuf (or application/protobuf) to JSON.
+func acceptsProtobuf(r *http.Request) bool {
+	protoQ, jsonQ := 0.0, 0.0
+	for _, item := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if param, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(param, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case protobufContentType, "application/protobuf":
+			protoQ = max(protoQ, q)
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return protoQ > 0 && protoQ >= jsonQ
+}
+
+// protoPayload returns a gateway config payload encoded as a
+// configv1.GatewayConfig. Encodings are kept by checksum, as gateways
+// mostly pull the same few payloads.
+func (h *GatewayHandler) protoPayload(payload []byte) ([]byte, error) {
+	sum := payloadSHA256(payload)
+	h.mu.Lock()
+	data, ok := h.protos[sum]
+	h.mu.Unlock()
+	if ok {
+		return data, nil
+	}
+
+	var cfg compile.Config
+	if err := json.Unmarshal(payload, &cfg); err != nil {
+		return nil, err
+	}
+	msg, err := cfg.Proto()
+	if err != nil {
+		return nil, err
+	}
+	if data, err = protoMarshal.Marshal(msg); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	if len(h.protos) >= maxProtoPayloads {
+		clear(h.protos)
+	}
+	h.protos[sum] = data
+	h.mu.Unlock()
+	return data, nil
+}
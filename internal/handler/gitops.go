@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/gitops"
+)
+
+// maxWebhookBodySize bounds the body of a webhook request.
+const maxWebhookBodySize = 1 << 20
+
+// GitOpsHandler handles GitOps sync status and webhook requests.
+type GitOpsHandler struct {
+	syncer *gitops.Syncer
+	logger *zap.Logger
+}
+
+// NewGitOpsHandler creates a new GitOpsHandler.
+func NewGitOpsHandler(syncer *gitops.Syncer, logger *zap.Logger) *GitOpsHandler {
+	return &GitOpsHandler{
+		syncer: syncer,
+		logger: logger,
+	}
+}
+
+// GetStatus returns the outcome of the most recent sync.
+// GET /api/v1/gitops/status
+func (h *GitOpsHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.syncer.Status()); err != nil {
+		h.logger.Warn("failed to encode gitops status", zap.Error(err))
+	}
+}
+
+// TriggerSync schedules an immediate sync. When a webhook secret is
+// configured the body must carry a GitHub-style X-Hub-Signature-256 header.
+// POST /api/v1/gitops/sync
+func (h *GitOpsHandler) TriggerSync(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("request body too large (at most %d bytes)", maxWebhookBodySize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if secret := h.syncer.WebhookSecret(); secret != "" {
+		if !validSignature(secret, body, r.Header.Get("X-Hub-Signature-256")) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	h.syncer.Trigger()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validSignature checks a "sha256=<hex>" HMAC signature over body.
+func validSignature(secret string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/graphqlapi"
+)
+
+// GraphQLHandler serves read-only GraphQL queries over the configuration.
+type GraphQLHandler struct {
+	store  config.Store
+	schema graphql.Schema
+	logger *zap.Logger
+}
+
+// NewGraphQLHandler creates a new GraphQLHandler.
+func NewGraphQLHandler(store config.Store, logger *zap.Logger) (*GraphQLHandler, error) {
+	schema, err := graphqlapi.NewSchema()
+	if err != nil {
+		return nil, err
+	}
+	return &GraphQLHandler{
+		store:  store,
+		schema: schema,
+		logger: logger,
+	}, nil
+}
+
+// graphQLRequest is the standard GraphQL-over-HTTP request body.
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Query executes a GraphQL query, sent either as a JSON body or, for GET,
+// in the query parameter.
+// GET /api/v1/graphql?query={backends{name routes{http_pattern}}}
+// POST /api/v1/graphql
+func (h *GraphQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	if r.Method == http.MethodGet {
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if vars := r.URL.Query().Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				http.Error(w, "invalid variables", http.StatusBadRequest)
+				return
+			}
+		}
+	} else {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	if req.Query == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        graphqlapi.WithLoader(r.Context(), h.store),
+	})
+	if result.HasErrors() {
+		h.logger.Debug("graphql query returned errors", zap.Any("errors", result.Errors))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.logger.Warn("failed to encode graphql response", zap.Error(err))
+	}
+}
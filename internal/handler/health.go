@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// Pinger checks that a dependency is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthHandler serves liveness and readiness probes.
+type HealthHandler struct {
+	db       Pinger
+	breaker  config.Breaker
+	draining atomic.Bool
+	logger   *zap.Logger
+}
+
+// NewHealthHandler creates a new HealthHandler. db may be nil for stores
+// without a backing service to check.
+func NewHealthHandler(db Pinger, logger *zap.Logger) *HealthHandler {
+	return &HealthHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SetBreaker makes readiness fail without pinging the database while the
+// store's circuit breaker is not closed.
+func (h *HealthHandler) SetBreaker(b config.Breaker) {
+	h.breaker = b
+}
+
+// SetDraining marks the service as shutting down so that readiness fails
+// and load balancers stop sending new requests.
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Live reports that the process is up.
+// GET /health, GET /health/live
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	h.write(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Ready reports whether the service can serve requests: it is not
+// shutting down, the database circuit breaker is closed and the database
+// answers within two seconds.
+// GET /health/ready
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		h.write(w, http.StatusServiceUnavailable, map[string]string{"status": "draining"})
+		return
+	}
+
+	if h.breaker != nil {
+		if state := h.breaker.BreakerState(); state != config.BreakerClosed {
+			h.write(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "database": "circuit breaker " + state.String()})
+			return
+		}
+	}
+
+	if err := h.ping(r.Context()); err != nil {
+		h.logger.Warn("readiness check failed", zap.Error(err))
+		h.write(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "database": err.Error()})
+		return
+	}
+
+	h.write(w, http.StatusOK, map[string]string{"status": "ok", "database": "ok"})
+}
+
+func (h *HealthHandler) write(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Warn("failed to encode health response", zap.Error(err))
+	}
+}
+
+// ping checks the database, if there is one to check.
+func (h *HealthHandler) ping(ctx context.Context) error {
+	if h.db == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	return h.db.Ping(ctx)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 
 	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
@@ -25,28 +26,71 @@ func NewHistoryHandler(store config.Store, logger *zap.Logger) *HistoryHandler {
 }
 
 // ListHistory returns configuration change history with optional filters.
-// GET /api/v1/history?config_type=backend&config_id=1&limit=10&offset=0
+// Each entry carries the changed fields computed from its old and new
+// values unless changes=false asks for the raw values only.
+// GET /api/v1/history?config_type=backend&config_id=1&limit=10&offset=0&fields=id,operation&changes=false
 func (h *HistoryHandler) ListHistory(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	var configType *string
-	var configID *uint
+	configType, configID, ok := parseHistoryFilter(w, r)
+	if !ok {
+		return
+	}
+	h.writeHistory(w, r, configType, configID)
+}
 
-	if typeParam := r.URL.Query().Get("config_type"); typeParam != "" {
-		if typeParam != "backend" && typeParam != "route" {
-			http.Error(w, "invalid config_type (must be 'backend' or 'route')", http.StatusBadRequest)
-			return
-		}
-		configType = &typeParam
+// GetBackendHistory returns the change timeline of one backend, newest
+// first, with the same paging and parameters as ListHistory. Backends are
+// found by name, including deleted ones.
+// GET /api/v1/backends/{name}/history?limit=10&offset=0&fields=id,operation&changes=false
+func (h *HistoryHandler) GetBackendHistory(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	backend, err := h.store.GetBackendByName(name)
+	if err != nil {
+		h.logger.Error("failed to get backend", zap.String("name", name), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if backend == nil {
+		http.Error(w, "backend not found", http.StatusNotFound)
+		return
 	}
 
-	if idParam := r.URL.Query().Get("config_id"); idParam != "" {
-		id, err := strconv.ParseUint(idParam, 10, 32)
-		if err != nil {
-			http.Error(w, "invalid config_id", http.StatusBadRequest)
-			return
-		}
-		idUint := uint(id)
-		configID = &idUint
+	configType := "backend"
+	h.writeHistory(w, r, &configType, &backend.ID)
+}
+
+// GetRouteHistory returns the change timeline of one route, newest first,
+// with the same paging and parameters as ListHistory.
+// GET /api/v1/routes/{id}/history?limit=10&offset=0&fields=id,operation&changes=false
+func (h *HistoryHandler) GetRouteHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid route id", http.StatusBadRequest)
+		return
+	}
+
+	route, err := h.store.GetRouteByID(uint(id))
+	if err != nil {
+		h.logger.Error("failed to get route", zap.Uint64("id", id), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if route == nil {
+		http.Error(w, "route not found", http.StatusNotFound)
+		return
+	}
+
+	configType := "route"
+	h.writeHistory(w, r, &configType, &route.ID)
+}
+
+// writeHistory returns a page of the history entries matching configType
+// and configID, as asked by the limit, offset, fields and changes
+// parameters.
+func (h *HistoryHandler) writeHistory(w http.ResponseWriter, r *http.Request, configType *string, configID *uint) {
+	fields, withChanges, ok := parseHistoryView(w, r)
+	if !ok {
+		return
 	}
 
 	limit := 50 // default limit
@@ -66,12 +110,27 @@ func (h *HistoryHandler) ListHistory(w http.ResponseWriter, r *http.Request) {
 	histories, total, err := h.store.GetHistory(configType, configID, limit, offset)
 	if err != nil {
 		h.logger.Error("failed to get history", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
+		return
+	}
+
+	entries := make([]historyEntry, len(histories))
+	for i, history := range histories {
+		entries[i].ConfigHistory = history
+		if withChanges {
+			entries[i].Changes = diffValues(history.OldValue, history.NewValue)
+		}
+	}
+
+	items, err := project(entries, fields)
+	if err != nil {
+		h.logger.Error("failed to project history", zap.Error(err))
+		writeServerError(w, err)
 		return
 	}
 
 	response := map[string]interface{}{
-		"items":  histories,
+		"items":  items,
 		"total":  total,
 		"limit":  limit,
 		"offset": offset,
@@ -82,3 +141,49 @@ func (h *HistoryHandler) ListHistory(w http.ResponseWriter, r *http.Request) {
 		h.logger.Warn("failed to encode history", zap.Error(err))
 	}
 }
+
+// parseHistoryFilter parses the config_type and config_id parameters,
+// writing an error response if they are invalid.
+func parseHistoryFilter(w http.ResponseWriter, r *http.Request) (*string, *uint, bool) {
+	var configType *string
+	var configID *uint
+
+	if typeParam := r.URL.Query().Get("config_type"); typeParam != "" {
+		if typeParam != "backend" && typeParam != "route" && typeParam != "descriptor" && typeParam != "schema" {
+			http.Error(w, "invalid config_type (must be 'backend', 'route', 'descriptor' or 'schema')", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		configType = &typeParam
+	}
+
+	if idParam := r.URL.Query().Get("config_id"); idParam != "" {
+		id, err := strconv.ParseUint(idParam, 10, 32)
+		if err != nil {
+			http.Error(w, "invalid config_id", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		idUint := uint(id)
+		configID = &idUint
+	}
+	return configType, configID, true
+}
+
+// parseHistoryView parses the fields and changes parameters, writing an
+// error response if they are invalid.
+func parseHistoryView(w http.ResponseWriter, r *http.Request) (fieldSet, bool, bool) {
+	fields, err := parseFields(r, historyEntry{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false, false
+	}
+
+	withChanges := true
+	if param := r.URL.Query().Get("changes"); param != "" {
+		withChanges, err = strconv.ParseBool(param)
+		if err != nil {
+			http.Error(w, "invalid changes parameter", http.StatusBadRequest)
+			return nil, false, false
+		}
+	}
+	return fields, withChanges, true
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"cmp"
+	"net/http"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+)
+
+const (
+	// historyTailPoll is how often the tail checks the store for changes
+	// made by other replicas, which are not published to this one.
+	historyTailPoll = 5 * time.Second
+	historyTailPage = 100
+)
+
+// HistoryTailHandler streams new configuration history entries over
+// WebSocket, for dashboards watching changes as they are made.
+type HistoryTailHandler struct {
+	store    config.Store
+	broker   *events.Broker
+	upgrader websocket.Upgrader
+	logger   *zap.Logger
+}
+
+// NewHistoryTailHandler creates a new HistoryTailHandler. Upgrades are only
+// accepted from allowedOrigins ("*" allows any origin).
+func NewHistoryTailHandler(store config.Store, broker *events.Broker, allowedOrigins []string, logger *zap.Logger) *HistoryTailHandler {
+	h := &HistoryTailHandler{
+		store:  store,
+		broker: broker,
+		logger: logger,
+	}
+	h.upgrader = websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			return originAllowed(r, allowedOrigins)
+		},
+	}
+	return h
+}
+
+// Tail upgrades the connection and sends each history entry written from
+// then on as a message, oldest first, until the client disconnects. It
+// takes the filters and fields of ListHistory; entries carry their changed
+// fields unless changes=false.
+// GET /api/v1/history/tail?config_type=backend&config_id=1&fields=id,operation&changes=false
+func (h *HistoryTailHandler) Tail(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	if principal == nil || principal.Name == "" {
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	configType, configID, ok := parseHistoryFilter(w, r)
+	if !ok {
+		return
+	}
+	fields, withChanges, ok := parseHistoryView(w, r)
+	if !ok {
+		return
+	}
+
+	// Entries are sent from the current revision on; those of later
+	// revisions are the ones committed since
+	since, err := h.store.GetRevision()
+	if err != nil {
+		h.logger.Error("failed to get revision", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an error response
+		h.logger.Debug("websocket upgrade failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	// Events only wake the tail up; the entries are read from the store
+	filter := events.Filter{Types: map[string]bool{
+		events.TypeBackend: true, events.TypeRoute: true, events.TypeDescriptor: true, events.TypeSchema: true,
+	}}
+	if configType != nil {
+		filter.Types = map[string]bool{*configType: true}
+	}
+	sub := h.broker.Subscribe(filter, liveBufferSize)
+	defer func() { h.broker.Unsubscribe(sub) }()
+
+	h.logger.Info("history tail connected", zap.String("operator", principal.Name))
+
+	// The read loop only handles control frames and notices disconnects
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadDeadline(time.Now().Add(livePongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(livePongTimeout))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(livePingInterval)
+	defer ping.Stop()
+	poll := time.NewTicker(historyTailPoll)
+	defer poll.Stop()
+
+	for {
+		select {
+		case _, ok := <-sub.C:
+			if !ok {
+				// Dropped by the broker for falling behind; nothing is
+				// lost, as the store is read anyway
+				sub = h.broker.Subscribe(filter, liveBufferSize)
+			}
+		case <-poll.C:
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+			continue
+		case <-done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+
+		revision, err := h.store.GetRevision()
+		if err != nil {
+			h.logger.Warn("failed to get revision", zap.Error(err))
+			continue
+		}
+		if revision <= since {
+			continue
+		}
+		entries, err := h.newer(configType, configID, since)
+		if err != nil {
+			h.logger.Warn("failed to get history", zap.Error(err))
+			continue
+		}
+		since = revision
+		if len(entries) == 0 {
+			continue
+		}
+		// Entries committed after the revision was read may be included
+		since = max(since, entries[len(entries)-1].Revision)
+
+		items := make([]historyEntry, len(entries))
+		for i, history := range entries {
+			items[i].ConfigHistory = history
+			if withChanges {
+				items[i].Changes = diffValues(history.OldValue, history.NewValue)
+			}
+		}
+		projected, err := project(items, fields)
+		if err != nil {
+			h.logger.Error("failed to project history", zap.Error(err))
+			return
+		}
+		v := reflect.ValueOf(projected)
+		for i := range v.Len() {
+			conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			if err := conn.WriteJSON(v.Index(i).Interface()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// newer returns the entries matching configType and configID of the
+// revisions after since, oldest first.
+func (h *HistoryTailHandler) newer(configType *string, configID *uint, since uint64) ([]config.ConfigHistory, error) {
+	var newer []config.ConfigHistory
+	seen := map[uint64]bool{}
+	for offset := 0; ; offset += historyTailPage {
+		page, _, err := h.store.GetHistory(configType, configID, historyTailPage, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range page {
+			// Entries written meanwhile shift the pages
+			if e.Revision > since && !seen[e.ID] {
+				seen[e.ID] = true
+				newer = append(newer, e)
+			}
+		}
+		// Finish the page that reaches older revisions, in case entries
+		// committed close together are listed slightly out of order
+		if len(page) < historyTailPage || page[len(page)-1].Revision <= since {
+			break
+		}
+	}
+	slices.SortFunc(newer, func(a, b config.ConfigHistory) int {
+		return cmp.Or(cmp.Compare(a.Revision, b.Revision), cmp.Compare(a.ID, b.ID))
+	})
+	return newer, nil
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/jobs"
+)
+
+// Kinds of the jobs run by handlers.
+const (
+	jobApply           = "apply"
+	jobSnapshot        = "snapshot"
+	jobSnapshotRestore = "snapshot_restore"
+	jobSecretRotation  = "secret_rotation"
+)
+
+// maxJobLimit bounds the number of jobs listed at once.
+const maxJobLimit = 500
+
+// JobHandler handles background job requests.
+type JobHandler struct {
+	store  config.JobStore
+	runner *jobs.Runner
+	logger *zap.Logger
+}
+
+// NewJobHandler creates a new JobHandler. Jobs are read from store and
+// retried through runner.
+func NewJobHandler(store config.JobStore, runner *jobs.Runner, logger *zap.Logger) *JobHandler {
+	return &JobHandler{
+		store:  store,
+		runner: runner,
+		logger: logger,
+	}
+}
+
+// ListJobs returns jobs, newest first, optionally filtered by kind and
+// status.
+// GET /api/v1/jobs?kind=apply&status=failed&limit=100
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	status := query.Get("status")
+	switch status {
+	case "", config.JobPending, config.JobRunning, config.JobSucceeded, config.JobFailed:
+	default:
+		http.Error(w, "invalid status parameter (must be 'pending', 'running', 'succeeded' or 'failed')", http.StatusBadRequest)
+		return
+	}
+
+	limit := 100
+	if param := query.Get("limit"); param != "" {
+		val, err := strconv.Atoi(param)
+		if err != nil || val <= 0 || val > maxJobLimit {
+			http.Error(w, fmt.Sprintf("invalid limit parameter (must be between 1 and %d)", maxJobLimit), http.StatusBadRequest)
+			return
+		}
+		limit = val
+	}
+
+	list, err := h.store.GetJobs(query.Get("kind"), status, limit)
+	if err != nil {
+		h.logger.Error("failed to get jobs", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if list == nil {
+		list = []config.Job{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		h.logger.Warn("failed to encode jobs", zap.Error(err))
+	}
+}
+
+// GetJob returns the status, progress and, once finished, the result or
+// error of a job.
+// GET /api/v1/jobs/{id}
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		h.logger.Warn("failed to encode job", zap.Error(err))
+	}
+}
+
+// RetryJob runs a failed job again with the same params.
+// POST /api/v1/jobs/{id}/retry
+func (h *JobHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	retried, err := h.runner.Retry(job.ID)
+	if err != nil {
+		h.logger.Error("failed to retry job", zap.Uint("id", job.ID), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if !retried {
+		http.Error(w, "only failed jobs can be retried", http.StatusConflict)
+		return
+	}
+
+	if job, ok = h.lookup(w, r); !ok {
+		return
+	}
+	writeJob(w, job, h.logger)
+}
+
+// lookup loads the job named by the {id} URL parameter, writing an error
+// response if it cannot.
+func (h *JobHandler) lookup(w http.ResponseWriter, r *http.Request) (*config.Job, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid job id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	job, err := h.store.GetJob(uint(id))
+	if err != nil {
+		h.logger.Error("failed to get job", zap.Uint64("id", id), zap.Error(err))
+		writeServerError(w, err)
+		return nil, false
+	}
+	if job == nil {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return nil, false
+	}
+	return job, true
+}
+
+// parseAsync reads the async query parameter of a long operation, writing
+// an error response if it is invalid or the store keeps no jobs.
+func parseAsync(w http.ResponseWriter, r *http.Request, runner *jobs.Runner) (async, ok bool) {
+	param := r.URL.Query().Get("async")
+	if param == "" {
+		return false, true
+	}
+	async, err := strconv.ParseBool(param)
+	if err != nil {
+		http.Error(w, "invalid async parameter", http.StatusBadRequest)
+		return false, false
+	}
+	if async && runner == nil {
+		http.Error(w, "background jobs are not supported by the configured store", http.StatusBadRequest)
+		return false, false
+	}
+	return async, true
+}
+
+// submitJob queues a job and responds 202 Accepted with it.
+func submitJob(w http.ResponseWriter, r *http.Request, runner *jobs.Runner, kind string, params interface{}, logger *zap.Logger) {
+	job, err := runner.Submit(kind, params, r.Header.Get("X-Operator"))
+	if err != nil {
+		logger.Error("failed to submit job", zap.String("kind", kind), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	writeJob(w, job, logger)
+}
+
+// writeJob responds 202 Accepted with a queued job and its location.
+func writeJob(w http.ResponseWriter, job *config.Job, logger *zap.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/jobs/%d", job.ID))
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		logger.Warn("failed to encode job", zap.Error(err))
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/healthcheck"
+)
+
+const (
+	// maxLatencyWindow bounds how far back a latency report may look.
+	maxLatencyWindow = 31 * 24 * time.Hour
+	// maxLatencyBuckets bounds the length of a latency time series.
+	maxLatencyBuckets = 1000
+	// defaultLatencyBuckets is the series length when no step is given.
+	defaultLatencyBuckets = 48
+)
+
+// LatencyHandler reports backend latency recorded by the health checker.
+type LatencyHandler struct {
+	store  config.Store
+	logger *zap.Logger
+}
+
+// NewLatencyHandler creates a new LatencyHandler.
+func NewLatencyHandler(store config.Store, logger *zap.Logger) *LatencyHandler {
+	return &LatencyHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// GetLatency returns latency percentiles and a time series for a backend
+// over a window (default 24h), bucketed by step (default window/48).
+// GET /api/v1/backends/{name}/latency?window=24h&step=30m
+func (h *LatencyHandler) GetLatency(w http.ResponseWriter, r *http.Request) {
+	latency, ok := h.store.(config.LatencyStore)
+	if !ok {
+		http.Error(w, "latency history is not supported by this store", http.StatusNotImplemented)
+		return
+	}
+
+	window := 24 * time.Hour
+	if param := r.URL.Query().Get("window"); param != "" {
+		d, err := parseWindow(param)
+		if err != nil || d <= 0 || d > maxLatencyWindow {
+			http.Error(w, "invalid window: must be a positive duration up to 31d", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	step := (window / defaultLatencyBuckets).Round(time.Second)
+	if param := r.URL.Query().Get("step"); param != "" {
+		d, err := time.ParseDuration(param)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid step", http.StatusBadRequest)
+			return
+		}
+		step = d
+	}
+	if step < time.Second {
+		step = time.Second
+	}
+	if (window+step-1)/step > maxLatencyBuckets {
+		http.Error(w, "step too small for window: at most 1000 buckets", http.StatusBadRequest)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	backend, err := h.store.GetBackendByName(name)
+	if err != nil {
+		h.logger.Error("failed to get backend", zap.String("name", name), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if backend == nil {
+		http.Error(w, "backend not found", http.StatusNotFound)
+		return
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-window)
+	samples, err := latency.GetLatency(name, from)
+	if err != nil {
+		h.logger.Error("failed to get latency samples", zap.String("name", name), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	report := healthcheck.NewReport(name, samples, from, to, step)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		h.logger.Warn("failed to encode latency report", zap.Error(err))
+	}
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+)
+
+const (
+	liveWriteTimeout = 10 * time.Second
+	livePingInterval = 30 * time.Second
+	livePongTimeout  = 2 * livePingInterval
+	liveBufferSize   = 64
+)
+
+// LiveHandler streams configuration change events to UI clients over
+// WebSocket.
+type LiveHandler struct {
+	store    config.Store
+	broker   *events.Broker
+	upgrader websocket.Upgrader
+	logger   *zap.Logger
+}
+
+// NewLiveHandler creates a new LiveHandler, looking up the owner teams of
+// the backends and routes of events in store. Upgrades are only accepted
+// from allowedOrigins ("*" allows any origin).
+func NewLiveHandler(store config.Store, broker *events.Broker, allowedOrigins []string, logger *zap.Logger) *LiveHandler {
+	h := &LiveHandler{
+		store:  store,
+		broker: broker,
+		logger: logger,
+	}
+	h.upgrader = websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			return originAllowed(r, allowedOrigins)
+		},
+	}
+	return h
+}
+
+// Serve upgrades the connection and pushes matching events until the
+// client disconnects. Clients narrow the stream with the types and backends
+// query parameters, e.g. to the backends owned by their tenant. Callers
+// other than admins only receive the events of backends and routes owned
+// by one of their teams or by no team, and no anomaly alerts.
+// GET /ws?types=backend,route&backends=user-service,order-service
+func (h *LiveHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	if principal == nil || principal.Name == "" {
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	filter := events.Filter{
+		Types:    csvSet(r.URL.Query().Get("types")),
+		Backends: csvSet(r.URL.Query().Get("backends")),
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an error response
+		h.logger.Debug("websocket upgrade failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	sub := h.broker.Subscribe(filter, liveBufferSize)
+	defer h.broker.Unsubscribe(sub)
+
+	h.logger.Info("live client connected", zap.String("operator", principal.Name))
+
+	// The read loop only handles control frames and notices disconnects
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadDeadline(time.Now().Add(livePongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(livePongTimeout))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(livePingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case e, ok := <-sub.C:
+			conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			if !ok {
+				// Dropped by the broker for falling behind; the client
+				// should reconnect and reload.
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client too slow"))
+				return
+			}
+			if !h.visible(principal, e) {
+				continue
+			}
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// visible reports whether p may receive e: admins receive every event,
+// others those of resources owned by one of their teams or by no team,
+// as the ownership admission controller lets them edit. Events whose owner
+// cannot be looked up are withheld.
+func (h *LiveHandler) visible(p *auth.Principal, e events.Event) bool {
+	if p.IsAdmin() {
+		return true
+	}
+
+	var owner string
+	switch e.Type {
+	case events.TypeAnomaly:
+		// Alerts about operators are for admins
+		return false
+	case events.TypeBackend, events.TypeRoute:
+		var ref struct {
+			OwnerTeam string `json:"owner_team"`
+		}
+		if len(e.Data) > 0 && json.Unmarshal(e.Data, &ref) != nil {
+			return false
+		}
+		owner = ref.OwnerTeam
+	case events.TypeSchema:
+		var ref struct {
+			RouteID uint `json:"route_id"`
+		}
+		if json.Unmarshal(e.Data, &ref) != nil {
+			return false
+		}
+		route, err := h.store.GetRouteByID(ref.RouteID)
+		if err != nil {
+			h.logger.Warn("failed to look up owner of live event", zap.String("type", e.Type), zap.Error(err))
+			return false
+		}
+		if route != nil {
+			owner = route.OwnerTeam
+		}
+	default:
+		if e.Backend == "" {
+			return true
+		}
+		backend, err := h.store.GetBackendByName(e.Backend)
+		if err != nil {
+			h.logger.Warn("failed to look up owner of live event", zap.String("type", e.Type), zap.Error(err))
+			return false
+		}
+		if backend != nil {
+			owner = backend.OwnerTeam
+		}
+	}
+	return owner == "" || slices.Contains(p.Teams, owner)
+}
+
+// originAllowed reports whether the request's Origin is in allowed.
+// Requests without an Origin header come from non-browser clients.
+func originAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, a := range allowed {
+		if a = strings.TrimSpace(a); a == "*" || strings.EqualFold(a, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// csvSet parses a comma-separated query value into a set.
+func csvSet(value string) map[string]bool {
+	set := map[string]bool{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			set[item] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,546 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// LoginHandler signs local users in, renews and ends their sessions, and
+// lets them change their password.
+type LoginHandler struct {
+	users  config.UserStore
+	issuer *auth.TokenIssuer
+	opts   LoginOptions
+	logger *zap.Logger
+}
+
+// LoginAuditor records the sign ins and lockouts of local users, such as
+// to a SIEM.
+type LoginAuditor interface {
+	AuditLogin(r *http.Request, ev auth.LoginEvent)
+}
+
+// LoginOptions configures a LoginHandler.
+type LoginOptions struct {
+	// SessionTTL is how long sessions last.
+	SessionTTL time.Duration
+	// SecureCookies restricts the cookies of browser sessions to HTTPS.
+	SecureCookies bool
+	// Lockout locks users out after repeated failed sign ins.
+	Lockout auth.Backoff
+	// Throttle, if not nil, locks out the client addresses from which
+	// sign ins fail repeatedly.
+	Throttle *auth.Throttle
+	// TrustedProxies report the client addresses of the requests they
+	// forward.
+	TrustedProxies auth.TrustedProxies
+	// Audit, if not nil, records sign ins and lockouts.
+	Audit LoginAuditor
+	// TOTPIssuer names the service in authenticator apps.
+	TOTPIssuer string
+	// Directory, if not nil, checks the passwords of users who have no
+	// local one, such as against LDAP. Users it lets in for the first
+	// time are created.
+	Directory Directory
+}
+
+// Directory authenticates users against an external directory.
+type Directory interface {
+	// Authenticate checks the password of a user, returning
+	// auth.ErrInvalidCredentials if it is wrong or the user may not sign
+	// in.
+	Authenticate(ctx context.Context, username, password string) (*auth.DirectoryUser, error)
+}
+
+// directoryOperator is recorded as the creator of users created when they
+// first sign in with the directory.
+const directoryOperator = "directory"
+
+// NewLoginHandler creates a new LoginHandler.
+func NewLoginHandler(users config.UserStore, issuer *auth.TokenIssuer, opts LoginOptions, logger *zap.Logger) *LoginHandler {
+	return &LoginHandler{
+		users:  users,
+		issuer: issuer,
+		opts:   opts,
+		logger: logger,
+	}
+}
+
+// loginResponse is returned by Login, Refresh and ChangePassword. For
+// browser sessions the tokens are set as cookies instead, and the CSRF
+// token is returned.
+type loginResponse struct {
+	// Token is the short-lived access token.
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// RefreshToken renews the session once, for a new access token and a
+	// new refresh token.
+	RefreshToken       string    `json:"refresh_token,omitempty"`
+	RefreshExpiresAt   time.Time `json:"refresh_expires_at"`
+	CSRFToken          string    `json:"csrf_token,omitempty"`
+	Session            string    `json:"session"`
+	MustChangePassword bool      `json:"must_change_password"`
+}
+
+// Login checks a user's password and starts a session, returning an access
+// token to send as a bearer token in the Authorization header and a
+// refresh token to renew it. With "cookie": true, as for the UI, a browser
+// session is started instead: the tokens are set as cookies, and the CSRF
+// token to send with state-changing requests is returned. Users who must
+// change their password can only do that with it.
+// Users with two-factor authentication must also send a code from their
+// authenticator, or a recovery code, as "otp"; without one they get 401
+// "second factor required". Repeated failures lock the user, and the
+// client address they come from, out for a while; sign ins are then
+// refused with 429.
+// With a directory, users without a local password sign in with their
+// directory password, and are created on their first sign in; 503 is
+// returned while the directory cannot be reached.
+// POST /api/v1/auth/login
+func (h *LoginHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		OTP      string `json:"otp"`
+		Cookie   bool   `json:"cookie"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	now := time.Now().Truncate(time.Millisecond)
+	if h.opts.Throttle != nil {
+		if until := h.opts.Throttle.LockedUntil(h.opts.TrustedProxies.ClientIP(r), now); !until.IsZero() {
+			h.audit(r, auth.LoginEvent{Type: auth.LoginLockedOut, User: req.Username, LockedUntil: until})
+			lockedOut(w, now, until)
+			return
+		}
+	}
+	user, err := h.users.GetUser(req.Username)
+	if err != nil {
+		h.logger.Error("failed to get user", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if user != nil && user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		h.audit(r, auth.LoginEvent{Type: auth.LoginLockedOut, User: user.Name, LockedUntil: *user.LockedUntil})
+		lockedOut(w, now, *user.LockedUntil)
+		return
+	}
+	var ok bool
+	if h.viaDirectory(user) {
+		user, ok, err = h.directoryLogin(r, user, req.Username, req.Password, now)
+		if err != nil {
+			h.logger.Error("failed to sign in with the directory", zap.String("user", req.Username), zap.Error(err))
+			http.Error(w, "directory unavailable", http.StatusServiceUnavailable)
+			return
+		}
+	} else {
+		if user == nil {
+			auth.CheckNoPassword(req.Password)
+		}
+		ok = user != nil && auth.CheckPassword(user.PasswordHash, req.Password)
+	}
+	if !ok || user.Disabled {
+		h.failLogin(r, req.Username, user != nil, now)
+		http.Error(w, "invalid username or password", http.StatusUnauthorized)
+		return
+	}
+	if user.TOTPEnabled {
+		if req.OTP == "" {
+			http.Error(w, "second factor required", http.StatusUnauthorized)
+			return
+		}
+		ok, err := h.secondFactor(r, user.Name, req.OTP, now)
+		if err != nil {
+			h.logger.Error("failed to check second factor", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		if !ok {
+			h.failLogin(r, user.Name, true, now)
+			http.Error(w, "invalid second factor code", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	response, err := h.startSession(r, user, now)
+	if err != nil {
+		h.logger.Error("failed to start session", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if err := h.users.RecordUserLogin(user.Name, now); err != nil {
+		h.logger.Warn("failed to record sign in", zap.String("user", user.Name), zap.Error(err))
+	}
+
+	h.logger.Info("user signed in", zap.String("user", user.Name), zap.String("session", response.Session))
+	h.audit(r, auth.LoginEvent{Type: auth.LoginSucceeded, User: user.Name})
+
+	h.writeLogin(w, response, req.Cookie)
+}
+
+// Refresh renews a session: the refresh token is exchanged for a new
+// access token and a new refresh token, and cannot be used again. Using a
+// refresh token that was already exchanged ends the session, as it may
+// have been stolen. Browser sessions send no body; their refresh token is
+// taken from the cookie, and the request must carry the CSRF token.
+// POST /api/v1/auth/refresh
+func (h *LoginHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	cookie := false
+	if req.RefreshToken == "" {
+		if c, err := r.Cookie(auth.RefreshCookie); err == nil {
+			req.RefreshToken, cookie = c.Value, true
+		}
+	}
+	id, hash, err := auth.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		http.Error(w, "invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+	if cookie && !h.issuer.CheckCSRFToken(id, r.Header.Get(auth.CSRFHeader)) {
+		http.Error(w, "missing or invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	now := time.Now().Truncate(time.Millisecond)
+	session, err := h.users.GetSession(id)
+	if err != nil {
+		h.logger.Error("failed to get session", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if session == nil || !session.ActiveAt(now) {
+		http.Error(w, "invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(session.RefreshHash)) != 1 {
+		h.logger.Warn("refresh token reused, ending session", zap.String("user", session.User), zap.String("session", session.ID))
+		if err := h.users.RevokeSession(session.ID, now); err != nil {
+			h.logger.Error("failed to revoke session", zap.Error(err))
+		}
+		http.Error(w, "invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+	user, err := h.users.GetUser(session.User)
+	if err != nil {
+		h.logger.Error("failed to get user", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if user == nil || user.Disabled {
+		http.Error(w, "invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	refresh, newHash, err := auth.NewRefreshToken(session.ID)
+	if err != nil {
+		h.logger.Error("failed to generate refresh token", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if err := h.users.RotateSession(session.ID, hash, newHash, now); err != nil {
+		if err.Error() == "session not found" {
+			// Refreshed concurrently, or ended meanwhile
+			http.Error(w, "invalid or expired refresh token", http.StatusUnauthorized)
+			return
+		}
+		h.logger.Error("failed to rotate session", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	token, expires := h.issuer.Issue(user.Name, session.ID, now)
+
+	h.writeLogin(w, &loginResponse{
+		Token:              token,
+		ExpiresAt:          expires,
+		RefreshToken:       refresh,
+		RefreshExpiresAt:   session.ExpiresAt,
+		Session:            session.ID,
+		MustChangePassword: user.MustChangePassword,
+	}, cookie)
+}
+
+// ChangePassword replaces the caller's own password, given the current
+// one. Every session of the caller ends; a new one is started and
+// returned. A wrong current password counts as a failed sign in. The
+// passwords of users who sign in with the directory cannot be changed.
+// POST /api/v1/auth/password
+func (h *LoginHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	p := auth.FromContext(r.Context())
+	if p == nil {
+		http.Error(w, "sign in required", http.StatusUnauthorized)
+		return
+	}
+	user, err := h.users.GetUser(p.Name)
+	if err != nil {
+		h.logger.Error("failed to get user", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	now := time.Now().Truncate(time.Millisecond)
+	if user != nil && user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		h.audit(r, auth.LoginEvent{Type: auth.LoginLockedOut, User: user.Name, LockedUntil: *user.LockedUntil})
+		lockedOut(w, now, *user.LockedUntil)
+		return
+	}
+	if user != nil && h.viaDirectory(user) {
+		http.Error(w, "password is managed by the directory", http.StatusConflict)
+		return
+	}
+	if user == nil || !auth.CheckPassword(user.PasswordHash, req.CurrentPassword) {
+		h.failLogin(r, p.Name, user != nil, now)
+		http.Error(w, "current password is incorrect", http.StatusForbidden)
+		return
+	}
+	if err := auth.ValidatePassword(req.NewPassword); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		http.Error(w, "new password must differ from the current one", http.StatusBadRequest)
+		return
+	}
+
+	hash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		h.logger.Error("failed to hash password", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	// The database keeps milliseconds, and the new token must not predate
+	// the change
+	if err := h.users.SetUserPassword(user.Name, hash, false, now); err != nil {
+		h.logger.Error("failed to set password", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if err := h.users.RevokeUserSessions(user.Name, now); err != nil {
+		h.logger.Error("failed to revoke sessions", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	user.MustChangePassword = false
+	response, err := h.startSession(r, user, now)
+	if err != nil {
+		h.logger.Error("failed to start session", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("password changed", zap.String("user", user.Name))
+
+	_, cookie := auth.CookieToken(r)
+	h.writeLogin(w, response, cookie)
+}
+
+// startSession starts a session for user on the caller's device and
+// issues its tokens.
+func (h *LoginHandler) startSession(r *http.Request, user *config.User, now time.Time) (*loginResponse, error) {
+	id, err := auth.NewSessionID()
+	if err != nil {
+		return nil, err
+	}
+	refresh, hash, err := auth.NewRefreshToken(id)
+	if err != nil {
+		return nil, err
+	}
+	session := config.Session{
+		ID:          id,
+		User:        user.Name,
+		RefreshHash: hash,
+		UserAgent:   r.UserAgent(),
+		CreatedAt:   now,
+		LastUsedAt:  now,
+		ExpiresAt:   now.Add(h.opts.SessionTTL),
+	}
+	if len(session.UserAgent) > 255 {
+		session.UserAgent = strings.ToValidUTF8(session.UserAgent[:255], "")
+	}
+	session.IP = h.opts.TrustedProxies.ClientIP(r)
+	if err := h.users.CreateSession(&session); err != nil {
+		return nil, err
+	}
+
+	token, expires := h.issuer.Issue(user.Name, id, now)
+	return &loginResponse{
+		Token:              token,
+		ExpiresAt:          expires,
+		RefreshToken:       refresh,
+		RefreshExpiresAt:   session.ExpiresAt,
+		Session:            id,
+		MustChangePassword: user.MustChangePassword,
+	}, nil
+}
+
+// viaDirectory reports whether user, nil if it does not exist yet, signs
+// in with the directory. Users with a local password keep using it, so
+// that admins can still sign in while the directory is down.
+func (h *LoginHandler) viaDirectory(user *config.User) bool {
+	return h.opts.Directory != nil && (user == nil || user.PasswordHash == "")
+}
+
+// directoryLogin checks the password of a user with the directory. Users
+// signing in for the first time are created; the role and display name of
+// those created so are kept in line with the directory.
+func (h *LoginHandler) directoryLogin(r *http.Request, user *config.User, name, password string, now time.Time) (*config.User, bool, error) {
+	if config.ValidateUserName(name) != nil {
+		return user, false, nil
+	}
+	du, err := h.opts.Directory.Authenticate(r.Context(), name, password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		h.logger.Info("directory refused sign in", zap.String("user", name), zap.Error(err))
+		return user, false, nil
+	}
+	if err != nil {
+		return user, false, err
+	}
+
+	if user == nil {
+		user = &config.User{
+			Name:              name,
+			DisplayName:       du.DisplayName,
+			Role:              du.Role,
+			PasswordChangedAt: now,
+			CreatedBy:         directoryOperator,
+		}
+		if err := h.users.CreateUser(user); err != nil {
+			return nil, false, err
+		}
+		h.logger.Info("user created from directory", zap.String("user", name), zap.String("role", user.Role))
+		return user, true, nil
+	}
+	if user.CreatedBy == directoryOperator && (user.Role != du.Role || user.DisplayName != du.DisplayName) {
+		previous := user.Role
+		user.Role, user.DisplayName = du.Role, du.DisplayName
+		if err := h.users.UpdateUser(name, user); err != nil {
+			return nil, false, err
+		}
+		h.logger.Info("user synced from directory",
+			zap.String("user", name),
+			zap.String("previous_role", previous),
+			zap.String("role", user.Role),
+		)
+	}
+	return user, true, nil
+}
+
+// checkPassword checks the password of user, with the directory if they
+// sign in with it.
+func (h *LoginHandler) checkPassword(r *http.Request, user *config.User, password string) (bool, error) {
+	if !h.viaDirectory(user) {
+		return auth.CheckPassword(user.PasswordHash, password), nil
+	}
+	_, err := h.opts.Directory.Authenticate(r.Context(), user.Name, password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// failLogin records a failed sign in as name, locking the user out, if it
+// exists, and the client address after too many.
+func (h *LoginHandler) failLogin(r *http.Request, name string, exists bool, now time.Time) {
+	ip := h.opts.TrustedProxies.ClientIP(r)
+	h.logger.Warn("failed sign in", zap.String("user", name), zap.String("ip", ip))
+	h.audit(r, auth.LoginEvent{Type: auth.LoginFailed, User: name})
+
+	if h.opts.Throttle != nil {
+		if until := h.opts.Throttle.Fail(ip, now); !until.IsZero() {
+			h.logger.Warn("client locked out after failed sign ins", zap.String("ip", ip), zap.Time("until", until))
+			h.audit(r, auth.LoginEvent{Type: auth.ClientLocked, User: name, LockedUntil: until})
+		}
+	}
+	if !exists {
+		return
+	}
+	failures, err := h.users.RecordFailedLogin(name, now, now.Add(-auth.FailureWindow))
+	if err != nil {
+		h.logger.Error("failed to record failed sign in", zap.String("user", name), zap.Error(err))
+		return
+	}
+	if d := h.opts.Lockout.Lockout(failures); d > 0 {
+		until := now.Add(d)
+		if err := h.users.LockUser(name, until); err != nil {
+			h.logger.Error("failed to lock user out", zap.String("user", name), zap.Error(err))
+			return
+		}
+		h.logger.Warn("user locked out after failed sign ins", zap.String("user", name), zap.Int("failures", failures), zap.Time("until", until))
+		h.audit(r, auth.LoginEvent{Type: auth.AccountLocked, User: name, LockedUntil: until})
+	}
+}
+
+func (h *LoginHandler) audit(r *http.Request, ev auth.LoginEvent) {
+	if h.opts.Audit != nil {
+		h.opts.Audit.AuditLogin(r, ev)
+	}
+}
+
+// lockedOut refuses a sign in during a lockout ending at until.
+func lockedOut(w http.ResponseWriter, now, until time.Time) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(until.Sub(now).Seconds())+1))
+	http.Error(w, "too many failed sign ins, try again later", http.StatusTooManyRequests)
+}
+
+// writeLogin returns the tokens of a session, as cookies for a browser
+// session.
+func (h *LoginHandler) writeLogin(w http.ResponseWriter, response *loginResponse, cookie bool) {
+	if cookie {
+		response.CSRFToken = h.issuer.CSRFToken(response.Session)
+		auth.SetSessionCookies(w, h.opts.SecureCookies, response.Token, response.ExpiresAt,
+			response.RefreshToken, response.CSRFToken, response.RefreshExpiresAt)
+		response.Token, response.RefreshToken = "", ""
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode login response", zap.Error(err))
+	}
+}
+
+// Me returns the caller's identity: name, role and teams.
+// GET /api/v1/auth/me
+func (h *LoginHandler) Me(w http.ResponseWriter, r *http.Request) {
+	p := auth.FromContext(r.Context())
+	if p == nil {
+		http.Error(w, "sign in required", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		h.logger.Warn("failed to encode principal", zap.Error(err))
+	}
+}
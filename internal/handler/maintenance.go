@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/maintenance"
+)
+
+// MaintenanceHandler handles read-only mode requests.
+type MaintenanceHandler struct {
+	mode   *maintenance.Mode
+	logger *zap.Logger
+}
+
+// NewMaintenanceHandler creates a new MaintenanceHandler.
+func NewMaintenanceHandler(mode *maintenance.Mode, logger *zap.Logger) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		mode:   mode,
+		logger: logger,
+	}
+}
+
+// GetReadOnly returns the current read-only state.
+// GET /api/v1/maintenance/read-only
+func (h *MaintenanceHandler) GetReadOnly(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.mode.Status()); err != nil {
+		h.logger.Warn("failed to encode read-only status", zap.Error(err))
+	}
+}
+
+// SetReadOnly switches read-only mode on or off. Admin only.
+// PUT /api/v1/maintenance/read-only
+func (h *MaintenanceHandler) SetReadOnly(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ReadOnly *bool  `json:"read_only"`
+		Message  string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.ReadOnly == nil {
+		http.Error(w, "read_only is required", http.StatusBadRequest)
+		return
+	}
+
+	operator := auth.FromContext(r.Context()).Name
+	h.mode.Set(*req.ReadOnly, req.Message, operator)
+	h.logger.Warn("read-only mode changed",
+		zap.Bool("read_only", *req.ReadOnly),
+		zap.String("message", req.Message),
+		zap.String("operator", operator),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.mode.Status()); err != nil {
+		h.logger.Warn("failed to encode read-only status", zap.Error(err))
+	}
+}
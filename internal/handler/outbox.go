@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// OutboxHandler reports on the delivery of change events from the outbox.
+type OutboxHandler struct {
+	store  config.Store
+	outbox config.OutboxStore
+	logger *zap.Logger
+}
+
+// NewOutboxHandler creates a new OutboxHandler.
+func NewOutboxHandler(store config.Store, outbox config.OutboxStore, logger *zap.Logger) *OutboxHandler {
+	return &OutboxHandler{
+		store:  store,
+		outbox: outbox,
+		logger: logger,
+	}
+}
+
+// outboxStatus is the delivery state of every outbox sink, along with the
+// current revision they are catching up to.
+type outboxStatus struct {
+	Revision uint64              `json:"revision"`
+	Sinks    []config.OutboxSink `json:"sinks"`
+}
+
+// ListSinks returns the delivery state of every sink: the revision last
+// delivered and, while failing, the last error and when it is retried.
+// GET /api/v1/outbox/sinks
+func (h *OutboxHandler) ListSinks(w http.ResponseWriter, r *http.Request) {
+	revision, err := h.store.GetRevision()
+	if err != nil {
+		h.logger.Error("failed to get config revision", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	sinks, err := h.outbox.GetOutboxSinks()
+	if err != nil {
+		h.logger.Error("failed to get outbox sinks", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if sinks == nil {
+		sinks = []config.OutboxSink{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(outboxStatus{Revision: revision, Sinks: sinks}); err != nil {
+		h.logger.Warn("failed to encode outbox sinks", zap.Error(err))
+	}
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/schema"
+)
+
+// PluginHandler handles the JSON Schemas registered for plugin names.
+type PluginHandler struct {
+	schemas config.PluginSchemaStore
+	logger  *zap.Logger
+}
+
+// NewPluginHandler creates a new PluginHandler.
+func NewPluginHandler(schemas config.PluginSchemaStore, logger *zap.Logger) *PluginHandler {
+	return &PluginHandler{
+		schemas: schemas,
+		logger:  logger,
+	}
+}
+
+// ListPluginSchemas returns the registered plugin schemas.
+// GET /api/v1/plugins
+func (h *PluginHandler) ListPluginSchemas(w http.ResponseWriter, r *http.Request) {
+	schemas, err := h.schemas.GetPluginSchemas()
+	if err != nil {
+		h.logger.Error("failed to get plugin schemas", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if schemas == nil {
+		schemas = []config.PluginSchema{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(schemas); err != nil {
+		h.logger.Warn("failed to encode plugin schemas", zap.Error(err))
+	}
+}
+
+// GetPluginSchema returns the schema registered for a plugin.
+// GET /api/v1/plugins/{name}/schema
+func (h *PluginHandler) GetPluginSchema(w http.ResponseWriter, r *http.Request) {
+	s, err := h.schemas.GetPluginSchema(chi.URLParam(r, "name"))
+	if err != nil {
+		h.logger.Error("failed to get plugin schema", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if s == nil {
+		http.Error(w, "plugin schema not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		h.logger.Warn("failed to encode plugin schema", zap.Error(err))
+	}
+}
+
+// SetPluginSchema registers the schema of a plugin, replacing any previous
+// one. Configs already stored are not rechecked; the schema applies to
+// later changes. Admin only.
+// PUT /api/v1/plugins/{name}/schema
+func (h *PluginHandler) SetPluginSchema(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if !config.ValidPluginName(name) {
+		http.Error(w, "invalid plugin name (lowercase letters, digits, '-', '_' and '.', at most 64)", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Description string          `json:"description"`
+		Schema      json.RawMessage `json:"schema"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	msg, err := schema.CheckDocument(r.Context(), req.Schema)
+	if err != nil {
+		h.logger.Error("failed to check plugin schema", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if msg != "" {
+		http.Error(w, "schema: "+msg, http.StatusBadRequest)
+		return
+	}
+
+	s := config.PluginSchema{
+		Name:        name,
+		Description: req.Description,
+		Schema:      req.Schema,
+		UpdatedBy:   auth.FromContext(r.Context()).Name,
+	}
+	if err := h.schemas.SetPluginSchema(&s); err != nil {
+		h.logger.Error("failed to set plugin schema", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("plugin schema set",
+		zap.String("plugin", name),
+		zap.String("operator", s.UpdatedBy),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		h.logger.Warn("failed to encode plugin schema", zap.Error(err))
+	}
+}
+
+// DeletePluginSchema removes the schema of a plugin, so its configs are
+// no longer validated. Admin only.
+// DELETE /api/v1/plugins/{name}/schema
+func (h *PluginHandler) DeletePluginSchema(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if err := h.schemas.DeletePluginSchema(name); err != nil {
+		if err.Error() == "plugin schema not found" {
+			http.Error(w, "plugin schema not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to delete plugin schema", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("plugin schema deleted",
+		zap.String("plugin", name),
+		zap.String("operator", auth.FromContext(r.Context()).Name),
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/compile"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// propagationReport tells which gateways have applied a config revision.
+type propagationReport struct {
+	Revision        uint64                   `json:"revision"`
+	CurrentRevision uint64                   `json:"current_revision"`
+	Complete        bool                     `json:"complete"`
+	Acknowledged    []config.GatewayInstance `json:"acknowledged"`
+	Pending         []config.GatewayInstance `json:"pending"`
+	Failed          []config.GatewayInstance `json:"failed"`
+}
+
+// GetPropagation tells how far a config revision has propagated among
+// the registered gateways seen within window (default 1h). A gateway has
+// acknowledged the revision once it reports having applied it or a newer
+// one, and has failed if it reports failing to apply it or a newer one
+// since; other gateways are pending. The revision is live everywhere once
+// complete is set.
+// GET /api/v1/versions/{rev}/propagation?window=1h
+func (h *GatewayHandler) GetPropagation(w http.ResponseWriter, r *http.Request) {
+	gs, ok := h.gatewayStore(w)
+	if !ok {
+		return
+	}
+
+	revision, err := strconv.ParseUint(chi.URLParam(r, "rev"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid revision", http.StatusBadRequest)
+		return
+	}
+	window, ok := gatewayWindow(w, r)
+	if !ok {
+		return
+	}
+
+	current, err := compile.Revision(h.store)
+	if err != nil {
+		h.logger.Error("failed to get config revision", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if revision == 0 || revision > current {
+		http.Error(w, "revision not found", http.StatusNotFound)
+		return
+	}
+
+	gateways, err := gs.GetGateways()
+	if err != nil {
+		h.logger.Error("failed to get gateways", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	report := propagationReport{
+		Revision:        revision,
+		CurrentRevision: current,
+		Acknowledged:    []config.GatewayInstance{},
+		Pending:         []config.GatewayInstance{},
+		Failed:          []config.GatewayInstance{},
+	}
+	since := time.Now().Add(-window)
+	for _, g := range gateways {
+		switch {
+		case g.RegisteredAt == nil, g.LastSeenAt.Before(since):
+		case g.ConfigRevision >= revision:
+			report.Acknowledged = append(report.Acknowledged, g)
+		case g.FailedRevision >= revision:
+			report.Failed = append(report.Failed, g)
+		default:
+			report.Pending = append(report.Pending, g)
+		}
+	}
+	report.Complete = len(report.Pending) == 0 && len(report.Failed) == 0
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		h.logger.Warn("failed to encode propagation report", zap.Error(err))
+	}
+}
@@ -0,0 +1,335 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/descriptor"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/grpcreflect"
+)
+
+// maxBulkRoutes bounds the routes created by one CreateRoutes call.
+const maxBulkRoutes = 1000
+
+// Sources of the descriptors routes are proposed from.
+const (
+	proposeFromUpload     = "upload"
+	proposeFromReflection = "reflection"
+	proposeFromRegistry   = "registry"
+)
+
+// proposeResponse is returned by ProposeRoutes.
+type proposeResponse struct {
+	Backend string                `json:"backend"`
+	Source  string                `json:"source"`
+	Routes  []descriptor.Proposal `json:"routes"`
+	Skipped []descriptor.Skipped  `json:"skipped"`
+}
+
+// ProposeRoutes proposes a route to backend {name} for every unary RPC of
+// a serialized FileDescriptorSet sent as the body, or, if the body is
+// empty, of the services the backend exposes via gRPC server reflection;
+// source=registry uses the latest descriptor set uploaded for the backend
+// instead. google.api.http annotations are honored; other RPCs get POST
+// /<package.Service>/<Method>. Nothing is stored: the proposals can be
+// reviewed and then created with CreateRoutes.
+// POST /api/v1/backends/{name}/routes:propose?source=registry
+func (h *RouteHandler) ProposeRoutes(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	backend, err := h.store.GetBackendByName(name)
+	if err != nil {
+		h.logger.Error("failed to get backend", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if backend == nil {
+		http.Error(w, "backend not found", http.StatusNotFound)
+		return
+	}
+
+	data, ok := readDescriptorSet(w, r)
+	if !ok {
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	switch {
+	case source == "" && len(data) > 0:
+		source = proposeFromUpload
+	case source == "":
+		source = proposeFromReflection
+	case source != proposeFromUpload && source != proposeFromReflection && source != proposeFromRegistry:
+		http.Error(w, "invalid source parameter (must be 'upload', 'reflection' or 'registry')", http.StatusBadRequest)
+		return
+	}
+
+	var files *protoregistry.Files
+	switch source {
+	case proposeFromUpload:
+		if len(data) == 0 {
+			http.Error(w, "body must be a serialized FileDescriptorSet", http.StatusBadRequest)
+			return
+		}
+		if files, err = descriptor.ParseSet(data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case proposeFromRegistry:
+		if h.descriptors == nil {
+			http.Error(w, "descriptor sets are not supported by this store", http.StatusNotImplemented)
+			return
+		}
+		set, err := h.descriptors.GetDescriptorSet(backend.Name, 0)
+		if err != nil {
+			h.logger.Error("failed to get descriptor set", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		if set == nil {
+			http.Error(w, "no descriptor set uploaded for "+backend.Name, http.StatusNotFound)
+			return
+		}
+		if files, err = descriptor.ParseSet(set.Data); err != nil {
+			h.logger.Error("failed to parse stored descriptor set", zap.String("backend", backend.Name), zap.Int("version", set.Version), zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+	case proposeFromReflection:
+		ctx, cancel := context.WithTimeout(r.Context(), reflectionTimeout)
+		set, err := grpcreflect.FileDescriptors(ctx, backend)
+		cancel()
+		if err != nil {
+			h.logger.Warn("failed to fetch backend descriptors", zap.String("backend", name), zap.Error(err))
+			http.Error(w, fmt.Sprintf("cannot fetch the descriptors of %s: %v; upload a FileDescriptorSet instead", name, err), http.StatusBadGateway)
+			return
+		}
+		if files, err = descriptor.NewFiles(set); err != nil {
+			http.Error(w, fmt.Sprintf("%s returned %v", name, err), http.StatusBadGateway)
+			return
+		}
+	}
+
+	proposals, skipped := descriptor.Propose(files, backend.Name)
+
+	routes, err := h.store.GetRoutes(nil)
+	if err != nil {
+		h.logger.Error("failed to get routes", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	existing := routesByBinding(routes)
+	for i := range proposals {
+		if id, ok := existing[bindingKey(&proposals[i].Route)]; ok {
+			proposals[i].ExistingRouteID = &id
+		}
+	}
+
+	response := proposeResponse{Backend: backend.Name, Source: source, Routes: proposals, Skipped: skipped}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode route proposals", zap.Error(err))
+	}
+}
+
+// bulkCreateRequest is the body of CreateRoutes.
+type bulkCreateRequest struct {
+	Routes []config.Route `json:"routes"`
+	// SkipExisting skips routes whose method and pattern are already
+	// configured instead of refusing the whole request.
+	SkipExisting bool   `json:"skip_existing,omitempty"`
+	ChangeReason string `json:"change_reason,omitempty"`
+}
+
+// bulkCreateResponse is returned by CreateRoutes.
+type bulkCreateResponse struct {
+	Created []config.Route `json:"created"`
+	Skipped []config.Route `json:"skipped"`
+}
+
+// CreateRoutes creates several routes in one transaction, e.g. reviewed
+// proposals of ProposeRoutes. Each route is validated like CreateRoute,
+// including against the descriptor set of its backend, and the request
+// fails as a whole if any is invalid or rejected by admission. Routes whose method and pattern are already configured,
+// enabled or not, are refused unless skip_existing is set.
+// POST /api/v1/routes:bulk?dry_run=true
+func (h *RouteHandler) CreateRoutes(w http.ResponseWriter, r *http.Request) {
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	var req bulkCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if len(req.Routes) == 0 {
+		http.Error(w, "routes is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Routes) > maxBulkRoutes {
+		http.Error(w, fmt.Sprintf("too many routes (at most %d)", maxBulkRoutes), http.StatusBadRequest)
+		return
+	}
+
+	routes, err := h.store.GetRoutes(nil)
+	if err != nil {
+		h.logger.Error("failed to get routes", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	existing := routesByBinding(routes)
+
+	backends := map[string]bool{}
+	descriptors := newDescriptorCheck(h.descriptors)
+	seen := map[string]int{}
+	response := bulkCreateResponse{Created: []config.Route{}, Skipped: []config.Route{}}
+	var conflicts, warnings []string
+	for i := range req.Routes {
+		route := req.Routes[i]
+		route.ID = 0
+		if field := missingRouteField(&route); field != "" {
+			http.Error(w, fmt.Sprintf("routes[%d]: %s is required", i, field), http.StatusBadRequest)
+			return
+		}
+		if err := route.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("routes[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+
+		key := bindingKey(&route)
+		if j, ok := seen[key]; ok {
+			http.Error(w, fmt.Sprintf("routes[%d]: %s duplicates routes[%d]", i, key, j), http.StatusBadRequest)
+			return
+		}
+		seen[key] = i
+		if id, ok := existing[key]; ok {
+			if req.SkipExisting {
+				response.Skipped = append(response.Skipped, route)
+				continue
+			}
+			conflicts = append(conflicts, fmt.Sprintf("%s (route %d)", key, id))
+			continue
+		}
+
+		if _, checked := backends[route.BackendName]; !checked {
+			backend, err := h.store.GetBackendByName(route.BackendName)
+			if err != nil {
+				h.logger.Error("failed to check backend", zap.Error(err))
+				writeServerError(w, err)
+				return
+			}
+			backends[route.BackendName] = backend != nil && backend.Enabled
+		}
+		if !backends[route.BackendName] {
+			http.Error(w, fmt.Sprintf("routes[%d]: backend %s not found or disabled", i, route.BackendName), http.StatusBadRequest)
+			return
+		}
+		msg, err := descriptors.check(&route)
+		if err != nil {
+			h.logger.Error("failed to check route against descriptors", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		if msg == "" {
+			msg, err = checkTargets(h.store, descriptors, &route)
+		}
+		if err != nil {
+			h.logger.Error("failed to check route variants", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		if msg != "" {
+			http.Error(w, fmt.Sprintf("routes[%d]: %s", i, msg), http.StatusBadRequest)
+			return
+		}
+
+		if route.TimeoutMS <= 0 {
+			route.TimeoutMS = 5000
+		}
+		if !r.URL.Query().Has("enabled") {
+			route.Enabled = true
+		}
+		for _, warning := range h.warnings.route(&route) {
+			warnings = append(warnings, fmt.Sprintf("routes[%d]: %s", i, warning))
+		}
+		response.Created = append(response.Created, route)
+	}
+	if len(conflicts) > 0 {
+		http.Error(w, "routes already configured; remove them or set skip_existing:\n  "+strings.Join(conflicts, "\n  "), http.StatusConflict)
+		return
+	}
+	if !h.warnings.check(w, warnings) {
+		return
+	}
+
+	reason := changeReason(r, req.ChangeReason)
+	for i := range response.Created {
+		areq := &admission.Request{
+			Operation:  "CREATE",
+			ConfigType: "route",
+			Operator:   r.Header.Get("X-Operator"),
+			Role:       operatorRole(r),
+			Teams:      operatorTeams(r),
+			Reason:     reason,
+			New:        &response.Created[i],
+			Batch:      len(response.Created),
+
+			FreezeOverride: freezeOverride(r),
+		}
+		if err := h.admission.Admit(r.Context(), areq); err != nil {
+			writeAdmissionError(w, h.logger, err)
+			return
+		}
+	}
+
+	err = commit(h.store, w, dryRun, func(tx config.Store) error {
+		for i := range response.Created {
+			route := &response.Created[i]
+			if err := tx.CreateRoute(route); err != nil {
+				return fmt.Errorf("create route %s: %w", bindingKey(route), err)
+			}
+			if err := tx.CreateHistory(newHistory("route", &route.ID, "CREATE", nil, route, reason, r)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("failed to create routes", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode created routes", zap.Error(err))
+	}
+}
+
+// bindingKey identifies the HTTP binding of a route, e.g. "GET /v1/users".
+func bindingKey(route *config.Route) string {
+	return strings.ToUpper(route.HTTPMethod) + " " + route.HTTPPattern
+}
+
+// routesByBinding maps the HTTP binding of each route to its ID.
+func routesByBinding(routes []config.Route) map[string]uint {
+	byBinding := make(map[string]uint, len(routes))
+	for i := range routes {
+		byBinding[bindingKey(&routes[i])] = routes[i].ID
+	}
+	return byBinding
+}
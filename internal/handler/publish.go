@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/publish"
+)
+
+// PublishHandler reports on the publishing of the compiled config to
+// external key-value stores.
+type PublishHandler struct {
+	publishers []*publish.Publisher
+	logger     *zap.Logger
+}
+
+// NewPublishHandler creates a new PublishHandler reporting on publishers,
+// which may be empty.
+func NewPublishHandler(publishers []*publish.Publisher, logger *zap.Logger) *PublishHandler {
+	return &PublishHandler{
+		publishers: publishers,
+		logger:     logger,
+	}
+}
+
+// ListPublishers returns the state of each configured publisher: per
+// cluster, the config last published and the last error, if any.
+// GET /api/v1/publishers
+func (h *PublishHandler) ListPublishers(w http.ResponseWriter, r *http.Request) {
+	statuses := make([]publish.Status, len(h.publishers))
+	for i, p := range h.publishers {
+		statuses[i] = p.Status()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(statuses); err != nil {
+		h.logger.Warn("failed to encode publishers", zap.Error(err))
+	}
+}
@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// changeReason returns why a change is being made, taken from the request
+// body's change_reason field, the X-Change-Reason header, or (for bodiless
+// requests such as DELETE) the change_reason query parameter.
+func changeReason(r *http.Request, bodyReason string) string {
+	if reason := strings.TrimSpace(bodyReason); reason != "" {
+		return reason
+	}
+	if reason := strings.TrimSpace(r.Header.Get("X-Change-Reason")); reason != "" {
+		return reason
+	}
+	return strings.TrimSpace(r.URL.Query().Get("change_reason"))
+}
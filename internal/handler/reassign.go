@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/grpcreflect"
+)
+
+// reflectionTimeout bounds a server reflection query to a backend.
+const reflectionTimeout = 5 * time.Second
+
+// reassignRequest is the body of ReassignRoutes.
+type reassignRequest struct {
+	FromBackend string `json:"from_backend"`
+	ToBackend   string `json:"to_backend"`
+	// RouteIDs limits the reassignment to some routes of FromBackend;
+	// by default all of them, enabled or not, are moved.
+	RouteIDs []uint `json:"route_ids,omitempty"`
+	// SkipServiceCheck reassigns without asking ToBackend, via server
+	// reflection, whether it exposes the routes' services.
+	SkipServiceCheck bool   `json:"skip_service_check,omitempty"`
+	ChangeReason     string `json:"change_reason,omitempty"`
+}
+
+// reassignResponse is returned by ReassignRoutes.
+type reassignResponse struct {
+	FromBackend string         `json:"from_backend"`
+	ToBackend   string         `json:"to_backend"`
+	Routes      []config.Route `json:"routes"`
+}
+
+// ReassignRoutes points routes of one backend at another in a single
+// transaction, e.g. while migrating a service. Unless skipped, the new
+// backend must expose every service the routes call, as reported by gRPC
+// server reflection.
+// POST /api/v1/routes:reassign?dry_run=true
+func (h *RouteHandler) ReassignRoutes(w http.ResponseWriter, r *http.Request) {
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	var req reassignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.FromBackend == "" || req.ToBackend == "" {
+		http.Error(w, "from_backend and to_backend are required", http.StatusBadRequest)
+		return
+	}
+	if req.FromBackend == req.ToBackend {
+		http.Error(w, "from_backend and to_backend must differ", http.StatusBadRequest)
+		return
+	}
+
+	target, err := h.store.GetBackendByName(req.ToBackend)
+	if err != nil {
+		h.logger.Error("failed to check backend", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if target == nil || !target.Enabled {
+		http.Error(w, "to_backend not found or disabled", http.StatusBadRequest)
+		return
+	}
+
+	routes, err := h.store.GetRoutes(nil)
+	if err != nil {
+		h.logger.Error("failed to get routes", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	selected, err := selectRoutes(routes, req.FromBackend, req.RouteIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !req.SkipServiceCheck && len(selected) > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), reflectionTimeout)
+		exposed, err := grpcreflect.ListServices(ctx, target)
+		cancel()
+		if err != nil {
+			h.logger.Warn("failed to list backend services", zap.String("backend", target.Name), zap.Error(err))
+			http.Error(w, fmt.Sprintf("cannot verify the services of %s: %v; set skip_service_check to reassign anyway", target.Name, err), http.StatusBadGateway)
+			return
+		}
+		if missing := missingServices(selected, exposed); len(missing) > 0 {
+			http.Error(w, fmt.Sprintf("%s does not expose %s", target.Name, strings.Join(missing, ", ")), http.StatusConflict)
+			return
+		}
+	}
+
+	reason := changeReason(r, req.ChangeReason)
+	changes := make([]config.Route, len(selected))
+	for i, route := range selected {
+		changes[i] = route
+		changes[i].BackendName = target.Name
+
+		areq := &admission.Request{
+			Operation:  "UPDATE",
+			ConfigType: "route",
+			Operator:   r.Header.Get("X-Operator"),
+			Role:       operatorRole(r),
+			Teams:      operatorTeams(r),
+			Reason:     reason,
+			Old:        &selected[i],
+			New:        &changes[i],
+			Batch:      len(selected),
+
+			FreezeOverride: freezeOverride(r),
+		}
+		if err := h.admission.Admit(r.Context(), areq); err != nil {
+			writeAdmissionError(w, h.logger, err)
+			return
+		}
+	}
+
+	err = commit(h.store, w, dryRun, func(tx config.Store) error {
+		for i := range changes {
+			if err := tx.UpdateRoute(changes[i].ID, &changes[i]); err != nil {
+				return fmt.Errorf("update route %d: %w", changes[i].ID, err)
+			}
+			if err := tx.CreateHistory(newHistory("route", &changes[i].ID, "UPDATE", &selected[i], &changes[i], reason, r)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("failed to reassign routes", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	response := reassignResponse{FromBackend: req.FromBackend, ToBackend: target.Name, Routes: changes}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode reassigned routes", zap.Error(err))
+	}
+}
+
+// selectRoutes returns the routes of backend, or only those with the given
+// IDs, which must all belong to it.
+func selectRoutes(routes []config.Route, backend string, ids []uint) ([]config.Route, error) {
+	byID := make(map[uint]config.Route, len(routes))
+	var all []config.Route
+	for _, route := range routes {
+		if route.BackendName == backend {
+			byID[route.ID] = route
+			all = append(all, route)
+		}
+	}
+	if len(ids) == 0 {
+		return all, nil
+	}
+
+	selected := make([]config.Route, 0, len(ids))
+	seen := map[uint]bool{}
+	for _, id := range ids {
+		route, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("route %d does not exist or does not belong to %s", id, backend)
+		}
+		if !seen[id] {
+			seen[id] = true
+			selected = append(selected, route)
+		}
+	}
+	return selected, nil
+}
+
+// missingServices lists the services called by routes that are not among
+// exposed, sorted.
+func missingServices(routes []config.Route, exposed []string) []string {
+	have := make(map[string]bool, len(exposed))
+	for _, s := range exposed {
+		have[s] = true
+	}
+
+	missing := map[string]bool{}
+	for _, route := range routes {
+		if !have[route.BackendService] {
+			missing[route.BackendService] = true
+		}
+	}
+
+	names := make([]string, 0, len(missing))
+	for s := range missing {
+		names = append(names, s)
+	}
+	sort.Strings(names)
+	return names
+}
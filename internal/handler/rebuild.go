@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/apply"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// Kinds of divergence between the change history and the stored
+// configuration.
+const (
+	// divergenceMissing is a resource the history has but its table lacks,
+	// with no other resource of the same name in its place.
+	divergenceMissing = "missing"
+	// divergenceUntracked is a resource its table has without any history.
+	divergenceUntracked = "untracked"
+	// divergenceModified is a resource whose stored value differs from the
+	// one recorded by its latest change.
+	divergenceModified = "modified"
+)
+
+// historyDivergence is a resource whose stored state the change history
+// does not account for. Changes compare the value recorded in history
+// (old) with the stored one (new).
+type historyDivergence struct {
+	ConfigType string        `json:"config_type"`
+	ConfigID   uint          `json:"config_id"`
+	Name       string        `json:"name"`
+	Kind       string        `json:"kind"`
+	Changes    []FieldChange `json:"changes,omitempty"`
+}
+
+// historyVerification is returned by VerifyHistory.
+type historyVerification struct {
+	Consistent  bool                `json:"consistent"`
+	Entries     int                 `json:"entries"`
+	Divergences []historyDivergence `json:"divergences"`
+}
+
+// rebuildResponse is returned by RebuildFromHistory.
+type rebuildResponse struct {
+	applyResponse
+	// Entries is the number of history entries replayed.
+	Entries int `json:"entries"`
+}
+
+// VerifyHistory replays the change history and compares the backends and
+// routes it describes with those stored, listing every resource that
+// differs, such as after manual edits of the database. A resource missing
+// from its table is not reported when another one of the same name (or
+// method and pattern) replaced it, as a rebuild does. Secrets are not
+// recorded in history and are not compared.
+// GET /api/v1/history/verify
+func (h *RestoreHandler) VerifyHistory(w http.ResponseWriter, r *http.Request) {
+	var replayed *apply.Replayed
+	var backends []config.Backend
+	var routes []config.Route
+	err := h.store.InTx(func(tx config.Store) error {
+		var err error
+		if replayed, err = apply.Replay(tx); err != nil {
+			return err
+		}
+		if backends, err = tx.GetBackends(nil); err != nil {
+			return err
+		}
+		routes, err = tx.GetRoutes(nil)
+		return err
+	})
+	if err != nil {
+		h.logger.Error("failed to replay history", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	divergences := []historyDivergence{}
+	for i := range backends {
+		b := &backends[i]
+		d := historyDivergence{ConfigType: "backend", ConfigID: b.ID, Name: b.Name}
+		recorded, ok := replayed.Backends[b.ID]
+		delete(replayed.Backends, b.ID)
+		if !ok {
+			d.Kind = divergenceUntracked
+		} else if d.Changes = diffValues(backendValue(&recorded), backendValue(b)); len(d.Changes) > 0 {
+			d.Kind = divergenceModified
+		} else {
+			continue
+		}
+		divergences = append(divergences, d)
+	}
+	names := make(map[string]bool, len(backends))
+	for i := range backends {
+		names[backends[i].Name] = true
+	}
+	for id, b := range replayed.Backends {
+		if names[b.Name] {
+			continue
+		}
+		divergences = append(divergences, historyDivergence{ConfigType: "backend", ConfigID: id, Name: b.Name, Kind: divergenceMissing})
+	}
+	for i := range routes {
+		rt := &routes[i]
+		d := historyDivergence{ConfigType: "route", ConfigID: rt.ID, Name: apply.RouteKey(rt)}
+		recorded, ok := replayed.Routes[rt.ID]
+		delete(replayed.Routes, rt.ID)
+		if !ok {
+			d.Kind = divergenceUntracked
+		} else if d.Changes = diffValues(routeValue(&recorded), routeValue(rt)); len(d.Changes) > 0 {
+			d.Kind = divergenceModified
+		} else {
+			continue
+		}
+		divergences = append(divergences, d)
+	}
+	keys := make(map[string]bool, len(routes))
+	for i := range routes {
+		keys[apply.RouteKey(&routes[i])] = true
+	}
+	for id, rt := range replayed.Routes {
+		if keys[apply.RouteKey(&rt)] {
+			continue
+		}
+		divergences = append(divergences, historyDivergence{ConfigType: "route", ConfigID: id, Name: apply.RouteKey(&rt), Kind: divergenceMissing})
+	}
+	sort.Slice(divergences, func(i, j int) bool {
+		if divergences[i].ConfigType != divergences[j].ConfigType {
+			return divergences[i].ConfigType < divergences[j].ConfigType
+		}
+		return divergences[i].ConfigID < divergences[j].ConfigID
+	})
+
+	response := historyVerification{
+		Consistent:  len(divergences) == 0,
+		Entries:     replayed.Entries,
+		Divergences: divergences,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode history verification", zap.Error(err))
+	}
+}
+
+// backendValue encodes a backend for comparison with history, without
+// its secrets.
+func backendValue(b *config.Backend) json.RawMessage {
+	v := *b
+	if v.Credential != nil {
+		cred := *v.Credential
+		cred.Secret = ""
+		v.Credential = &cred
+	}
+	if v.TLS != nil {
+		tls := *v.TLS
+		tls.ClientKey = ""
+		v.TLS = &tls
+	}
+	data, _ := json.Marshal(&v)
+	return data
+}
+
+// routeValue encodes a route for comparison with history.
+func routeValue(r *config.Route) json.RawMessage {
+	data, _ := json.Marshal(r)
+	return data
+}
+
+// RebuildFromHistory makes the stored backends and routes match the
+// change history again: resources are reconstructed from their latest
+// recorded change and applied like a desired-state document, so missing
+// ones are recreated, modified ones restored and untracked ones deleted.
+// The stored secrets are kept. The resulting plan goes through the same
+// admission checks and history as an apply. With plan_only=true the plan
+// is returned as a preview without being applied.
+// POST /api/v1/history/rebuild?plan_only=true
+func (h *RestoreHandler) RebuildFromHistory(w http.ResponseWriter, r *http.Request) {
+	planOnly := false
+	if param := r.URL.Query().Get("plan_only"); param != "" {
+		val, err := strconv.ParseBool(param)
+		if err != nil {
+			http.Error(w, "invalid plan_only parameter", http.StatusBadRequest)
+			return
+		}
+		planOnly = val
+	}
+
+	var replayed *apply.Replayed
+	err := h.store.InTx(func(tx config.Store) error {
+		var err error
+		replayed, err = apply.Replay(tx)
+		return err
+	})
+	if err != nil {
+		h.logger.Error("failed to replay history", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	doc := replayed.Document()
+	if err := doc.Validate(); err != nil {
+		http.Error(w, "cannot rebuild: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	reason := changeReason(r, "")
+	if reason == "" {
+		reason = "rebuild from history"
+	}
+	actor := apply.Actor{
+		Operator:       r.Header.Get("X-Operator"),
+		Role:           operatorRole(r),
+		Teams:          operatorTeams(r),
+		Reason:         reason,
+		FreezeOverride: freezeOverride(r),
+	}
+
+	plan, err := apply.ComputePlan(h.store, doc)
+	if err != nil {
+		h.logger.Error("failed to compute rebuild plan", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	if err := apply.Admit(r.Context(), h.admission, plan, actor); err != nil {
+		writeAdmissionError(w, h.logger, err)
+		return
+	}
+
+	response := rebuildResponse{
+		applyResponse: applyResponse{Plan: plan},
+		Entries:       replayed.Entries,
+	}
+	if !planOnly && plan.HasChanges() {
+		if err := apply.Execute(h.store, plan, actor); err != nil {
+			writeExecuteError(w, h.logger, "failed to rebuild configuration from history", err)
+			return
+		}
+		response.Applied = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode rebuild response", zap.Error(err))
+	}
+}
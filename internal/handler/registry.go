@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/registry"
+)
+
+const (
+	// maxGatewayVersionLength bounds the version gateways register with.
+	maxGatewayVersionLength = 64
+	// maxGatewayAddrLength bounds the address gateways register with.
+	maxGatewayAddrLength = 255
+)
+
+// RegistryHandler handles the registry of running gateway instances.
+// Register and Heartbeat must only be mounted behind gateway
+// authentication.
+type RegistryHandler struct {
+	store    config.GatewayStore
+	rollouts config.RolloutStore
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+// NewRegistryHandler creates a new RegistryHandler. Gateways are asked to
+// send a heartbeat every interval. rollouts may be nil, in which case the
+// traffic gateways report with heartbeats is ignored.
+func NewRegistryHandler(store config.GatewayStore, rollouts config.RolloutStore, interval time.Duration, logger *zap.Logger) *RegistryHandler {
+	return &RegistryHandler{
+		store:    store,
+		rollouts: rollouts,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// gatewayEntry is a gateway instance as listed, with whether it is
+// currently considered running.
+type gatewayEntry struct {
+	config.GatewayInstance
+	Online bool `json:"online"`
+}
+
+// Register adds a gateway instance to the registry, or refreshes it when a
+// known instance restarts, and answers how often to send heartbeats. The
+// cluster it registers with is the one its config is compiled for; its
+// labels let config rollouts select it.
+// POST /api/v1/gateways/register
+func (h *RegistryHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req config.GatewayInstance
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.ID == "" || len(req.ID) > maxGatewayIDLength {
+		http.Error(w, "id is required (at most 128 characters)", http.StatusBadRequest)
+		return
+	}
+	if len(req.Version) > maxGatewayVersionLength || len(req.Addr) > maxGatewayAddrLength {
+		http.Error(w, "version or addr too long (at most 64 and 255 characters)", http.StatusBadRequest)
+		return
+	}
+	if req.Cluster != "" {
+		if err := config.ValidateClusterName(req.Cluster); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := config.ValidateGatewayLabels(req.Labels); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ConfigSHA256 != "" && !sha256Hex.MatchString(req.ConfigSHA256) {
+		http.Error(w, "config_sha256 must be a lowercase hex SHA-256", http.StatusBadRequest)
+		return
+	}
+
+	gateway := config.GatewayInstance{ID: req.ID, Version: req.Version, Addr: req.Addr, Cluster: req.Cluster, Labels: req.Labels,
+		ConfigSHA256: req.ConfigSHA256, ConfigRevision: req.ConfigRevision}
+	if err := h.store.RegisterGateway(&gateway); err != nil {
+		h.logger.Error("failed to register gateway", zap.String("gateway", gateway.ID), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	h.logger.Info("gateway registered", zap.String("gateway", gateway.ID), zap.String("version", gateway.Version), zap.String("addr", gateway.Addr))
+
+	response := map[string]interface{}{
+		"gateway":                    gateway,
+		"heartbeat_interval_seconds": int(h.interval / time.Second),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode gateway", zap.Error(err))
+	}
+}
+
+// Heartbeat tells that a registered gateway instance is still running,
+// optionally with the config it has loaded, as its X-Config-SHA256 and
+// revision, and the requests and errors it has served since its previous
+// heartbeat, which are added to the health of the config rollout in
+// progress. An unknown instance, such as one pruned after a long outage,
+// is answered 404 and must register again.
+// POST /api/v1/gateways/heartbeat
+func (h *RegistryHandler) Heartbeat(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID           string `json:"id"`
+		ConfigSHA256 string `json:"config_sha256"`
+		Revision     uint64 `json:"revision"`
+		Requests     int64  `json:"requests"`
+		Errors       int64  `json:"errors"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.ID == "" || len(req.ID) > maxGatewayIDLength {
+		http.Error(w, "id is required (at most 128 characters)", http.StatusBadRequest)
+		return
+	}
+	if req.ConfigSHA256 != "" && !sha256Hex.MatchString(req.ConfigSHA256) {
+		http.Error(w, "config_sha256 must be a lowercase hex SHA-256", http.StatusBadRequest)
+		return
+	}
+	if req.Requests < 0 || req.Errors < 0 || req.Errors > req.Requests {
+		http.Error(w, "requests and errors must not be negative, nor errors exceed requests", http.StatusBadRequest)
+		return
+	}
+
+	known, err := h.store.GatewayHeartbeat(req.ID, req.ConfigSHA256, req.Revision)
+	if err != nil {
+		h.logger.Error("failed to record gateway heartbeat", zap.String("gateway", req.ID), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if !known {
+		http.Error(w, "gateway not registered", http.StatusNotFound)
+		return
+	}
+	if h.rollouts != nil && req.Requests > 0 {
+		if err := h.addRolloutHealth(req.ID, req.Requests, req.Errors); err != nil {
+			// The heartbeat itself was recorded
+			h.logger.Warn("failed to record rollout health", zap.String("gateway", req.ID), zap.Error(err))
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addRolloutHealth adds the traffic a gateway instance reported to the
+// config rollout in progress, if any, on the side the instance is on.
+func (h *RegistryHandler) addRolloutHealth(id string, requests, errors int64) error {
+	rollout, err := h.rollouts.GetActiveRollout()
+	if err != nil || rollout == nil {
+		return err
+	}
+	gateway, err := h.store.GetGateway(id)
+	if err != nil || gateway == nil {
+		return err
+	}
+
+	return h.rollouts.AddRolloutHealth(rollout.ID, &config.RolloutHealth{
+		Gateway:  id,
+		Canary:   rollout.Selects(gateway),
+		Requests: requests,
+		Errors:   errors,
+	})
+}
+
+// ListGateways returns every known gateway instance, ordered by ID, with
+// whether it is online: seen within three heartbeat intervals.
+// GET /api/v1/gateways
+func (h *RegistryHandler) ListGateways(w http.ResponseWriter, r *http.Request) {
+	gateways, err := h.store.GetGateways()
+	if err != nil {
+		h.logger.Error("failed to get gateways", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	now := time.Now()
+	entries := make([]gatewayEntry, len(gateways))
+	for i := range gateways {
+		entries[i] = gatewayEntry{GatewayInstance: gateways[i], Online: registry.Online(&gateways[i], h.interval, now)}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		h.logger.Warn("failed to encode gateways", zap.Error(err))
+	}
+}
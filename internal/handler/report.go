@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/stats"
+)
+
+const (
+	// defaultTopRoutes is the number of routes listed when no limit is given.
+	defaultTopRoutes = 20
+	// maxTopRoutes bounds the number of routes listed.
+	maxTopRoutes = 500
+)
+
+// ReportHandler serves reports built from gateway traffic stats.
+type ReportHandler struct {
+	store  config.Store
+	logger *zap.Logger
+}
+
+// NewReportHandler creates a new ReportHandler.
+func NewReportHandler(store config.Store, logger *zap.Logger) *ReportHandler {
+	return &ReportHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// topRoutesResponse is the top routes report.
+type topRoutesResponse struct {
+	From   time.Time          `json:"from"`
+	To     time.Time          `json:"to"`
+	By     string             `json:"by"`
+	Routes []stats.RouteTotal `json:"routes"`
+}
+
+// TopRoutes ranks configured routes by traffic over a window (default 7d),
+// optionally only those of one backend.
+// GET /api/v1/reports/top-routes?by=requests|errors|error_rate|p99&window=7d&backend=&limit=20
+func (h *ReportHandler) TopRoutes(w http.ResponseWriter, r *http.Request) {
+	ss, ok := h.store.(config.StatsStore)
+	if !ok {
+		http.Error(w, "route stats are not supported by this store", http.StatusNotImplemented)
+		return
+	}
+
+	query := r.URL.Query()
+
+	by := query.Get("by")
+	if by == "" {
+		by = stats.ByRequests
+	}
+
+	window := 7 * 24 * time.Hour
+	if param := query.Get("window"); param != "" {
+		d, err := parseWindow(param)
+		if err != nil || d <= 0 || d > maxStatsWindow {
+			http.Error(w, "invalid window: must be a positive duration up to 31d", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	limit := defaultTopRoutes
+	if param := query.Get("limit"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n <= 0 || n > maxTopRoutes {
+			http.Error(w, "invalid limit: must be between 1 and 500", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	// Only routes that currently exist are ranked; disabled ones are kept
+	// since they may still have served traffic within the window.
+	routes, err := h.store.GetRoutes(nil)
+	if err != nil {
+		h.logger.Error("failed to get routes", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if backend := query.Get("backend"); backend != "" {
+		filtered := routes[:0]
+		for _, route := range routes {
+			if route.BackendName == backend {
+				filtered = append(filtered, route)
+			}
+		}
+		routes = filtered
+	}
+
+	to := time.Now().UTC().Truncate(time.Minute).Add(time.Minute)
+	from := to.Add(-window)
+	totals, err := ss.SumRouteStats(from)
+	if err != nil {
+		h.logger.Error("failed to sum route stats", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	ranked, err := stats.TopRoutes(routes, totals, by, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := topRoutesResponse{From: from, To: to, By: by, Routes: ranked}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode top routes report", zap.Error(err))
+	}
+}
+
+// deprecatedRoutesResponse is the deprecated routes report.
+type deprecatedRoutesResponse struct {
+	From   time.Time          `json:"from"`
+	To     time.Time          `json:"to"`
+	Routes []stats.RouteTotal `json:"routes"`
+}
+
+// DeprecatedRoutes lists deprecated routes that still received traffic
+// over a window (default 7d), busiest first, optionally only those of one
+// API version.
+// GET /api/v1/reports/deprecated-routes?window=7d&api_version=v1
+func (h *ReportHandler) DeprecatedRoutes(w http.ResponseWriter, r *http.Request) {
+	ss, ok := h.store.(config.StatsStore)
+	if !ok {
+		http.Error(w, "route stats are not supported by this store", http.StatusNotImplemented)
+		return
+	}
+
+	query := r.URL.Query()
+
+	window := 7 * 24 * time.Hour
+	if param := query.Get("window"); param != "" {
+		d, err := parseWindow(param)
+		if err != nil || d <= 0 || d > maxStatsWindow {
+			http.Error(w, "invalid window: must be a positive duration up to 31d", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	routes, err := h.store.GetRoutes(nil)
+	if err != nil {
+		h.logger.Error("failed to get routes", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	deprecated := routes[:0]
+	for _, route := range routes {
+		if route.Lifecycle != config.LifecycleDeprecated {
+			continue
+		}
+		if query.Has("api_version") && route.APIVersion != query.Get("api_version") {
+			continue
+		}
+		deprecated = append(deprecated, route)
+	}
+
+	to := time.Now().UTC().Truncate(time.Minute).Add(time.Minute)
+	from := to.Add(-window)
+	totals, err := ss.SumRouteStats(from)
+	if err != nil {
+		h.logger.Error("failed to sum route stats", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	ranked, err := stats.TopRoutes(deprecated, totals, stats.ByRequests, 0)
+	if err != nil {
+		h.logger.Error("failed to rank deprecated routes", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	// Ranked by requests, so the routes without traffic come last
+	used := len(ranked)
+	for used > 0 && ranked[used-1].Requests == 0 {
+		used--
+	}
+
+	response := deprecatedRoutesResponse{From: from, To: to, Routes: ranked[:used]}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode deprecated routes report", zap.Error(err))
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/apply"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// RestoreHandler handles point-in-time restores, which rebuild an earlier
+// configuration from the change history and apply it like a
+// desired-state document.
+type RestoreHandler struct {
+	store     config.Store
+	admission admission.Controller
+	logger    *zap.Logger
+}
+
+// NewRestoreHandler creates a new RestoreHandler.
+func NewRestoreHandler(store config.Store, admission admission.Controller, logger *zap.Logger) *RestoreHandler {
+	return &RestoreHandler{
+		store:     store,
+		admission: admission,
+		logger:    logger,
+	}
+}
+
+// restoreResponse is returned by RestorePointInTime.
+type restoreResponse struct {
+	applyResponse
+	Timestamp time.Time `json:"timestamp"`
+	// Reverted is the number of history entries recorded after Timestamp.
+	Reverted int `json:"reverted"`
+}
+
+// RestorePointInTime returns the configuration to its state at a moment in
+// the past, reconstructed by reverting every backend and route change
+// recorded since. The resulting plan goes through the same admission
+// checks and history as an apply. With plan_only=true the plan is
+// returned as a preview without being applied.
+// POST /api/v1/restore/point-in-time?plan_only=true
+func (h *RestoreHandler) RestorePointInTime(w http.ResponseWriter, r *http.Request) {
+	planOnly := false
+	if param := r.URL.Query().Get("plan_only"); param != "" {
+		val, err := strconv.ParseBool(param)
+		if err != nil {
+			http.Error(w, "invalid plan_only parameter", http.StatusBadRequest)
+			return
+		}
+		planOnly = val
+	}
+
+	var req struct {
+		Timestamp    time.Time `json:"timestamp"`
+		ChangeReason string    `json:"change_reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json (timestamp must be RFC 3339)", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Timestamp.IsZero() {
+		http.Error(w, "timestamp is required", http.StatusBadRequest)
+		return
+	}
+	if req.Timestamp.After(time.Now()) {
+		http.Error(w, "timestamp must not be in the future", http.StatusBadRequest)
+		return
+	}
+
+	var doc *apply.Document
+	var reverted int
+	err := h.store.InTx(func(tx config.Store) error {
+		var err error
+		doc, reverted, err = apply.Rewind(tx, req.Timestamp)
+		return err
+	})
+	if err != nil {
+		h.logger.Error("failed to rewind configuration", zap.Time("timestamp", req.Timestamp), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if err := doc.Validate(); err != nil {
+		http.Error(w, "cannot restore: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	reason := changeReason(r, req.ChangeReason)
+	if reason == "" {
+		reason = "restore to " + req.Timestamp.UTC().Format(time.RFC3339)
+	}
+	actor := apply.Actor{
+		Operator:       r.Header.Get("X-Operator"),
+		Role:           operatorRole(r),
+		Teams:          operatorTeams(r),
+		Reason:         reason,
+		FreezeOverride: freezeOverride(r),
+	}
+
+	plan, err := apply.ComputePlan(h.store, doc)
+	if err != nil {
+		h.logger.Error("failed to compute restore plan", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	if err := apply.Admit(r.Context(), h.admission, plan, actor); err != nil {
+		writeAdmissionError(w, h.logger, err)
+		return
+	}
+
+	response := restoreResponse{
+		applyResponse: applyResponse{Plan: plan},
+		Timestamp:     req.Timestamp,
+		Reverted:      reverted,
+	}
+	if !planOnly && plan.HasChanges() {
+		if err := apply.Execute(h.store, plan, actor); err != nil {
+			writeExecuteError(w, h.logger, "failed to restore configuration", err, zap.Time("timestamp", req.Timestamp))
+			return
+		}
+		response.Applied = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode restore response", zap.Error(err))
+	}
+}
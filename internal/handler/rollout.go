@@ -0,0 +1,475 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/apply"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/compile"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// RolloutHandler handles config rollouts, which publish configuration
+// changes to a subset of gateways before the rest.
+type RolloutHandler struct {
+	store     config.Store
+	rollouts  config.RolloutStore
+	gateways  config.GatewayStore
+	admission admission.Controller
+	logger    *zap.Logger
+}
+
+// NewRolloutHandler creates a new RolloutHandler. gateways may be nil, in
+// which case rollouts cannot select gateways by label.
+func NewRolloutHandler(store config.Store, rollouts config.RolloutStore, gateways config.GatewayStore, admission admission.Controller, logger *zap.Logger) *RolloutHandler {
+	return &RolloutHandler{
+		store:     store,
+		rollouts:  rollouts,
+		gateways:  gateways,
+		admission: admission,
+		logger:    logger,
+	}
+}
+
+// rolloutTraffic sums the traffic reported by one side of a rollout.
+type rolloutTraffic struct {
+	Gateways  int     `json:"gateways"`
+	Requests  int64   `json:"requests"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// add adds the traffic of a gateway.
+func (t *rolloutTraffic) add(h *config.RolloutHealth) {
+	t.Gateways++
+	t.Requests += h.Requests
+	t.Errors += h.Errors
+	if t.Requests > 0 {
+		t.ErrorRate = float64(t.Errors) / float64(t.Requests)
+	}
+}
+
+// rolloutReport is a rollout with the gateways it selects and the traffic
+// reported since it started, by canaries and by the other gateways.
+type rolloutReport struct {
+	config.ConfigRollout
+	Canaries []string               `json:"canaries"`
+	Canary   rolloutTraffic         `json:"canary"`
+	Baseline rolloutTraffic         `json:"baseline"`
+	Health   []config.RolloutHealth `json:"health"`
+}
+
+// ListRollouts returns every rollout, newest first.
+// GET /api/v1/rollouts
+func (h *RolloutHandler) ListRollouts(w http.ResponseWriter, r *http.Request) {
+	rollouts, err := h.rollouts.GetRollouts()
+	if err != nil {
+		h.logger.Error("failed to get rollouts", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if rollouts == nil {
+		rollouts = []config.ConfigRollout{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rollouts); err != nil {
+		h.logger.Warn("failed to encode rollouts", zap.Error(err))
+	}
+}
+
+// CreateRollout starts a rollout: from now on, changes reach only the
+// gateways it selects, by percentage of instance IDs and optionally by
+// label, until it is promoted or aborted. Only one rollout can be in
+// progress at a time.
+// POST /api/v1/rollouts
+func (h *RolloutHandler) CreateRollout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Percent     int               `json:"percent"`
+		Labels      map[string]string `json:"labels"`
+		Description string            `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Percent < 0 || req.Percent > 100 {
+		http.Error(w, "percent must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+	if len(req.Labels) > 0 {
+		if h.gateways == nil {
+			http.Error(w, "selecting gateways by label requires the gateway registry", http.StatusBadRequest)
+			return
+		}
+		if err := config.ValidateGatewayLabels(req.Labels); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Percent == 0 {
+			req.Percent = 100
+		}
+	}
+	if req.Percent == 0 {
+		http.Error(w, "percent or labels is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Description) > 512 {
+		http.Error(w, "description too long (at most 512 characters)", http.StatusBadRequest)
+		return
+	}
+
+	// Read backends and routes in one transaction so they are consistent
+	var doc *apply.Document
+	var revision uint64
+	err := h.store.InTx(func(tx config.Store) error {
+		var err error
+		if revision, err = compile.Revision(tx); err != nil {
+			return err
+		}
+		enabled := true
+		backends, err := tx.GetBackends(&enabled)
+		if err != nil {
+			return err
+		}
+		routes, err := tx.GetRoutes(&enabled)
+		if err != nil {
+			return err
+		}
+		doc = apply.Export(backends, routes)
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("failed to read configuration", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	baseline, err := json.Marshal(doc)
+	if err != nil {
+		h.logger.Error("failed to encode rollout baseline", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	rollout := &config.ConfigRollout{
+		Percent:     req.Percent,
+		Labels:      req.Labels,
+		Description: strings.TrimSpace(req.Description),
+		Revision:    revision,
+		CreatedBy:   r.Header.Get("X-Operator"),
+		Baseline:    baseline,
+	}
+	created, err := h.rollouts.CreateRollout(rollout)
+	if err != nil {
+		h.logger.Error("failed to create rollout", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if !created {
+		http.Error(w, "another rollout is in progress", http.StatusConflict)
+		return
+	}
+	h.logger.Info("config rollout started", zap.Uint("id", rollout.ID), zap.Int("percent", rollout.Percent), zap.String("operator", rollout.CreatedBy))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(rollout); err != nil {
+		h.logger.Warn("failed to encode rollout", zap.Error(err))
+	}
+}
+
+// GetRollout returns a rollout with the registered gateways it selects
+// and the traffic gateways have reported with their heartbeats since it
+// started, summed for canaries and for the others.
+// GET /api/v1/rollouts/{id}
+func (h *RolloutHandler) GetRollout(w http.ResponseWriter, r *http.Request) {
+	rollout, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	health, err := h.rollouts.GetRolloutHealth(rollout.ID)
+	if err != nil {
+		h.logger.Error("failed to get rollout health", zap.Uint("id", rollout.ID), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	report := rolloutReport{ConfigRollout: *rollout, Canaries: []string{}, Health: []config.RolloutHealth{}}
+	if h.gateways != nil && rollout.Status == config.RolloutInProgress {
+		gateways, err := h.gateways.GetGateways()
+		if err != nil {
+			h.logger.Error("failed to get gateways", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		for i := range gateways {
+			if rollout.Selects(&gateways[i]) {
+				report.Canaries = append(report.Canaries, gateways[i].ID)
+			}
+		}
+	}
+	for i := range health {
+		if health[i].Canary {
+			report.Canary.add(&health[i])
+		} else {
+			report.Baseline.add(&health[i])
+		}
+		report.Health = append(report.Health, health[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		h.logger.Warn("failed to encode rollout", zap.Error(err))
+	}
+}
+
+// PromoteRollout ends a rollout in progress, publishing the current
+// configuration to every gateway.
+// POST /api/v1/rollouts/{id}/promote
+func (h *RolloutHandler) PromoteRollout(w http.ResponseWriter, r *http.Request) {
+	rollout, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	operator := r.Header.Get("X-Operator")
+	if !h.finish(w, rollout, config.RolloutPromoted, operator) {
+		return
+	}
+	h.logger.Info("config rollout promoted", zap.Uint("id", rollout.ID), zap.String("operator", operator))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rollout); err != nil {
+		h.logger.Warn("failed to encode rollout", zap.Error(err))
+	}
+}
+
+// rolloutAbortResponse is returned by AbortRollout.
+type rolloutAbortResponse struct {
+	applyResponse
+	Rollout *config.ConfigRollout `json:"rollout"`
+}
+
+// AbortRollout ends a rollout in progress by restoring its baseline:
+// backends and routes are created, updated or disabled to match the
+// configuration at its start, through the same admission checks and
+// history as an apply, so that every gateway gets the baseline again.
+// POST /api/v1/rollouts/{id}/abort
+func (h *RolloutHandler) AbortRollout(w http.ResponseWriter, r *http.Request) {
+	rollout, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+	if rollout.Status != config.RolloutInProgress {
+		http.Error(w, "rollout is not in progress", http.StatusConflict)
+		return
+	}
+
+	var doc apply.Document
+	if err := json.Unmarshal(rollout.Baseline, &doc); err != nil {
+		h.logger.Error("failed to decode rollout baseline", zap.Uint("id", rollout.ID), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if err := doc.Validate(); err != nil {
+		http.Error(w, "cannot restore baseline: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	reason := changeReason(r, "")
+	if reason == "" {
+		reason = fmt.Sprintf("abort rollout %d", rollout.ID)
+	}
+	actor := apply.Actor{
+		Operator:       r.Header.Get("X-Operator"),
+		Role:           operatorRole(r),
+		Teams:          operatorTeams(r),
+		Reason:         reason,
+		FreezeOverride: freezeOverride(r),
+	}
+
+	plan, err := apply.ComputePlan(h.store, &doc)
+	if err != nil {
+		h.logger.Error("failed to compute rollout abort plan", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if err := apply.Admit(r.Context(), h.admission, plan, actor); err != nil {
+		writeAdmissionError(w, h.logger, err)
+		return
+	}
+
+	response := rolloutAbortResponse{applyResponse: applyResponse{Plan: plan}, Rollout: rollout}
+	if plan.HasChanges() {
+		if err := apply.Execute(h.store, plan, actor); err != nil {
+			writeExecuteError(w, h.logger, "failed to restore rollout baseline", err, zap.Uint("id", rollout.ID))
+			return
+		}
+		response.Applied = true
+	}
+	if !h.finish(w, rollout, config.RolloutAborted, actor.Operator) {
+		return
+	}
+	h.logger.Info("config rollout aborted", zap.Uint("id", rollout.ID), zap.String("operator", actor.Operator))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode rollout", zap.Error(err))
+	}
+}
+
+// finish moves a rollout in progress to status, updating rollout. It
+// writes an error response if the rollout is not in progress.
+func (h *RolloutHandler) finish(w http.ResponseWriter, rollout *config.ConfigRollout, status, operator string) bool {
+	finished, err := h.rollouts.FinishRollout(rollout.ID, status, operator)
+	if err != nil {
+		h.logger.Error("failed to finish rollout", zap.Uint("id", rollout.ID), zap.Error(err))
+		writeServerError(w, err)
+		return false
+	}
+	if !finished {
+		http.Error(w, "rollout is not in progress", http.StatusConflict)
+		return false
+	}
+
+	updated, err := h.rollouts.GetRollout(rollout.ID)
+	if err != nil || updated == nil {
+		h.logger.Error("failed to get rollout", zap.Uint("id", rollout.ID), zap.Error(err))
+		writeServerError(w, err)
+		return false
+	}
+	*rollout = *updated
+	return true
+}
+
+// lookup returns the rollout named by the id URL parameter, writing an
+// error response if there is none.
+func (h *RolloutHandler) lookup(w http.ResponseWriter, r *http.Request) (*config.ConfigRollout, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid rollout id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	rollout, err := h.rollouts.GetRollout(uint(id))
+	if err != nil {
+		h.logger.Error("failed to get rollout", zap.Uint64("id", id), zap.Error(err))
+		writeServerError(w, err)
+		return nil, false
+	}
+	if rollout == nil {
+		http.Error(w, "rollout not found", http.StatusNotFound)
+		return nil, false
+	}
+	return rollout, true
+}
+
+// baselineKey identifies a compiled rollout baseline.
+type baselineKey struct {
+	rollout uint
+	cluster string
+}
+
+// activeRollout returns the config rollout in progress, or nil if there is
+// none or the store does not keep rollouts.
+func (h *GatewayHandler) activeRollout() (*config.ConfigRollout, error) {
+	rollouts, ok := h.store.(config.RolloutStore)
+	if !ok {
+		return nil, nil
+	}
+	return rollouts.GetActiveRollout()
+}
+
+// ServedPayload returns the encoded config served to the gateway instance
+// gatewayID, which may be empty, in cluster. It is what GetConfig answers,
+// for other transports to serve the same config.
+func (h *GatewayHandler) ServedPayload(ctx context.Context, gatewayID, cluster string) ([]byte, error) {
+	rollout, err := h.activeRollout()
+	if err != nil {
+		return nil, fmt.Errorf("get active rollout: %w", err)
+	}
+
+	var gateway *config.GatewayInstance
+	if rollout != nil && gatewayID != "" {
+		gateway = &config.GatewayInstance{ID: gatewayID}
+		// Labels are only known to the registry
+		if gs, ok := h.store.(config.GatewayStore); ok && len(rollout.Labels) > 0 {
+			registered, err := gs.GetGateway(gatewayID)
+			if err != nil {
+				return nil, fmt.Errorf("get gateway: %w", err)
+			}
+			if registered != nil {
+				gateway = registered
+			}
+		}
+	}
+	return h.configPayload(ctx, rollout, gateway, cluster)
+}
+
+// configPayload returns the encoded config served to gateway in cluster:
+// the current config, from the cache if there is one, or the baseline of
+// rollout unless gateway is one of its canaries. rollout is nil if none is
+// in progress and gateway is nil if the request does not identify one.
+func (h *GatewayHandler) configPayload(ctx context.Context, rollout *config.ConfigRollout, gateway *config.GatewayInstance, cluster string) ([]byte, error) {
+	if rollout == nil || gateway != nil && rollout.Selects(gateway) {
+		return h.currentPayload(ctx, cluster)
+	}
+
+	key := baselineKey{rollout: rollout.ID, cluster: cluster}
+	h.mu.Lock()
+	payload, ok := h.baselines[key]
+	h.mu.Unlock()
+	if ok {
+		return payload, nil
+	}
+
+	payload, err := h.buildBaseline(rollout.ID, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	for k := range h.baselines {
+		if k.rollout != rollout.ID {
+			delete(h.baselines, k)
+		}
+	}
+	h.baselines[key] = payload
+	h.mu.Unlock()
+	return payload, nil
+}
+
+// buildBaseline compiles and encodes the baseline of a rollout for
+// cluster. Descriptor sets and route schemas are the latest ones, as for
+// the current config.
+func (h *GatewayHandler) buildBaseline(id uint, cluster string) ([]byte, error) {
+	rollout, err := h.store.(config.RolloutStore).GetRollout(id)
+	if err != nil {
+		return nil, err
+	}
+	if rollout == nil {
+		return nil, fmt.Errorf("rollout %d not found", id)
+	}
+
+	var doc apply.Document
+	if err := json.Unmarshal(rollout.Baseline, &doc); err != nil {
+		return nil, fmt.Errorf("decode baseline of rollout %d: %w", id, err)
+	}
+	cfg, err := compile.Compile(h.store, doc.Backends, doc.Routes, cluster)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Revision = rollout.Revision
+	return json.Marshal(cfg)
+}
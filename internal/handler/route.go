@@ -2,32 +2,52 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
 	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
 )
 
 // RouteHandler handles route management API requests.
 type RouteHandler struct {
-	store  config.Store
-	logger *zap.Logger
+	store       config.Store
+	descriptors config.DescriptorStore
+	admission   admission.Controller
+	warnings    Warnings
+	logger      *zap.Logger
 }
 
-// NewRouteHandler creates a new RouteHandler.
-func NewRouteHandler(store config.Store, logger *zap.Logger) *RouteHandler {
+// NewRouteHandler creates a new RouteHandler. descriptors may be nil, in
+// which case routes are not checked against backend descriptor sets.
+func NewRouteHandler(store config.Store, descriptors config.DescriptorStore, admission admission.Controller, warnings Warnings, logger *zap.Logger) *RouteHandler {
 	return &RouteHandler{
-		store:  store,
-		logger: logger,
+		store:       store,
+		descriptors: descriptors,
+		admission:   admission,
+		warnings:    warnings,
+		logger:      logger,
 	}
 }
 
-// ListRoutes returns all routes, optionally filtered by enabled status.
-// GET /api/v1/routes?enabled=true&limit=10&offset=0
+// ListRoutes returns all routes, optionally filtered by enabled status,
+// API version, lifecycle state, owner team and whether they are still
+// served past their sunset date. An empty team selects the routes no team
+// owns.
+// GET /api/v1/routes?enabled=true&api_version=v1&lifecycle=deprecated&sunset_passed=true&team=payments&fields=id,http_method,http_pattern&format=csv
 func (h *RouteHandler) ListRoutes(w http.ResponseWriter, r *http.Request) {
+	fields, err := parseFields(r, config.Route{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	enabledParam := r.URL.Query().Get("enabled")
 	var enabled *bool
 
@@ -40,15 +60,60 @@ func (h *RouteHandler) ListRoutes(w http.ResponseWriter, r *http.Request) {
 		enabled = &enabledVal
 	}
 
+	query := r.URL.Query()
+	lifecycle := query.Get("lifecycle")
+	switch lifecycle {
+	case "", config.LifecycleActive, config.LifecycleDeprecated, config.LifecycleRetired:
+	default:
+		http.Error(w, "invalid lifecycle parameter", http.StatusBadRequest)
+		return
+	}
+	var sunsetPassed *bool
+	if param := query.Get("sunset_passed"); param != "" {
+		v, err := strconv.ParseBool(param)
+		if err != nil {
+			http.Error(w, "invalid sunset_passed parameter", http.StatusBadRequest)
+			return
+		}
+		sunsetPassed = &v
+	}
+
 	routes, err := h.store.GetRoutes(enabled)
 	if err != nil {
 		h.logger.Error("failed to get routes", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
+		return
+	}
+	if query.Has("api_version") || lifecycle != "" || sunsetPassed != nil || query.Has("team") {
+		now := time.Now()
+		filtered := routes[:0]
+		for _, route := range routes {
+			if (!query.Has("api_version") || route.APIVersion == query.Get("api_version")) &&
+				(lifecycle == "" || route.Lifecycle == lifecycle) &&
+				(sunsetPassed == nil || route.SunsetPassed(now) == *sunsetPassed) &&
+				(!query.Has("team") || route.OwnerTeam == query.Get("team")) {
+				filtered = append(filtered, route)
+			}
+		}
+		routes = filtered
+	}
+
+	if wantsCSV(r) {
+		if err := writeCSV(w, "routes.csv", routes, fields); err != nil {
+			h.logger.Warn("failed to write routes csv", zap.Error(err))
+		}
+		return
+	}
+
+	result, err := project(routes, fields)
+	if err != nil {
+		h.logger.Error("failed to project routes", zap.Error(err))
+		writeServerError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(routes); err != nil {
+	if err := json.NewEncoder(w).Encode(result); err != nil {
 		h.logger.Warn("failed to encode routes", zap.Error(err))
 	}
 }
@@ -66,7 +131,7 @@ func (h *RouteHandler) GetRoute(w http.ResponseWriter, r *http.Request) {
 	route, err := h.store.GetRouteByID(uint(id))
 	if err != nil {
 		h.logger.Error("failed to get route", zap.Uint64("id", id), zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
 		return
 	}
 
@@ -75,41 +140,41 @@ func (h *RouteHandler) GetRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	writeWarnings(w, route.Warnings(time.Now()))
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(route); err != nil {
 		h.logger.Warn("failed to encode route", zap.Error(err))
 	}
 }
 
-// CreateRoute creates a new route.
-// POST /api/v1/routes
+// CreateRoute creates a new route. If its backend has a descriptor set,
+// the route must call a unary RPC defined in the latest version.
+// POST /api/v1/routes?dry_run=true
 func (h *RouteHandler) CreateRoute(w http.ResponseWriter, r *http.Request) {
-	var route config.Route
-	if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		config.Route
+		ChangeReason string `json:"change_reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
 	defer r.Body.Close()
 
+	route := req.Route
+	reason := changeReason(r, req.ChangeReason)
+
 	// Validation
-	if route.HTTPMethod == "" {
-		http.Error(w, "http_method is required", http.StatusBadRequest)
+	if field := missingRouteField(&route); field != "" {
+		http.Error(w, field+" is required", http.StatusBadRequest)
 		return
 	}
-	if route.HTTPPattern == "" {
-		http.Error(w, "http_pattern is required", http.StatusBadRequest)
-		return
-	}
-	if route.BackendName == "" {
-		http.Error(w, "backend_name is required", http.StatusBadRequest)
-		return
-	}
-	if route.BackendService == "" {
-		http.Error(w, "backend_service is required", http.StatusBadRequest)
-		return
-	}
-	if route.BackendMethod == "" {
-		http.Error(w, "backend_method is required", http.StatusBadRequest)
+	if err := route.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -117,7 +182,7 @@ func (h *RouteHandler) CreateRoute(w http.ResponseWriter, r *http.Request) {
 	backend, err := h.store.GetBackendByName(route.BackendName)
 	if err != nil {
 		h.logger.Error("failed to check backend", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
 		return
 	}
 	if backend == nil || !backend.Enabled {
@@ -125,6 +190,10 @@ func (h *RouteHandler) CreateRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.checkDescriptors(w, &route) || !h.checkTargets(w, &route) {
+		return
+	}
+
 	// Default values
 	if route.TimeoutMS <= 0 {
 		route.TimeoutMS = 5000
@@ -133,15 +202,29 @@ func (h *RouteHandler) CreateRoute(w http.ResponseWriter, r *http.Request) {
 		route.Enabled = true
 	}
 
-	// Create route
-	if err := h.store.CreateRoute(&route); err != nil {
-		h.logger.Error("failed to create route", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+	// Warnings are returned with the response, or reject the change in
+	// strict mode
+	if !h.warnings.check(w, h.warnings.route(&route)) {
 		return
 	}
 
-	// Record history
-	h.recordHistory("route", &route.ID, "CREATE", nil, &route, r)
+	// Admission checks
+	if !h.admit(w, r, "CREATE", nil, &route, reason) {
+		return
+	}
+
+	// Create route and record history
+	err = commit(h.store, w, dryRun, func(tx config.Store) error {
+		if err := tx.CreateRoute(&route); err != nil {
+			return err
+		}
+		return tx.CreateHistory(newHistory("route", &route.ID, "CREATE", nil, &route, reason, r))
+	})
+	if err != nil {
+		h.logger.Error("failed to create route", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -151,8 +234,12 @@ func (h *RouteHandler) CreateRoute(w http.ResponseWriter, r *http.Request) {
 }
 
 // UpdateRoute updates an existing route.
-// PUT /api/v1/routes/{id}
+// PUT /api/v1/routes/{id}?dry_run=true
 func (h *RouteHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
@@ -164,7 +251,7 @@ func (h *RouteHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
 	oldRoute, err := h.store.GetRouteByID(uint(id))
 	if err != nil {
 		h.logger.Error("failed to get route", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
 		return
 	}
 	if oldRoute == nil {
@@ -180,6 +267,9 @@ func (h *RouteHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	bodyReason, _ := routeUpdate["change_reason"].(string)
+	reason := changeReason(r, bodyReason)
+
 	// Check if enabled field is present in the request
 	enabledPresent := false
 	var enabledValue bool
@@ -205,19 +295,12 @@ func (h *RouteHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
 		route.Enabled = enabledValue
 	}
 
-	// Validation
-	if route.HTTPMethod == "" || route.HTTPPattern == "" || route.BackendName == "" ||
-		route.BackendService == "" || route.BackendMethod == "" {
-		http.Error(w, "required fields cannot be empty", http.StatusBadRequest)
-		return
-	}
-
 	// Verify backend exists if changed
 	if route.BackendName != oldRoute.BackendName {
 		backend, err := h.store.GetBackendByName(route.BackendName)
 		if err != nil {
 			h.logger.Error("failed to check backend", zap.Error(err))
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			writeServerError(w, err)
 			return
 		}
 		if backend == nil || !backend.Enabled {
@@ -229,20 +312,32 @@ func (h *RouteHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
 	// Preserve ID
 	route.ID = uint(id)
 
-	// Update route
-	if err := h.store.UpdateRoute(uint(id), &route); err != nil {
+	if !h.checkUpdate(w, oldRoute, &route) {
+		return
+	}
+
+	// Admission checks
+	if !h.admit(w, r, "UPDATE", oldRoute, &route, reason) {
+		return
+	}
+
+	// Update route and record history
+	err = commit(h.store, w, dryRun, func(tx config.Store) error {
+		if err := tx.UpdateRoute(uint(id), &route); err != nil {
+			return err
+		}
+		return tx.CreateHistory(newHistory("route", &route.ID, "UPDATE", oldRoute, &route, reason, r))
+	})
+	if err != nil {
 		h.logger.Error("failed to update route", zap.Error(err))
 		if err.Error() == "route not found" {
 			http.Error(w, "route not found", http.StatusNotFound)
 			return
 		}
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
 		return
 	}
 
-	// Record history
-	h.recordHistory("route", &route.ID, "UPDATE", oldRoute, &route, r)
-
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(route); err != nil {
 		h.logger.Warn("failed to encode route", zap.Error(err))
@@ -250,8 +345,12 @@ func (h *RouteHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
 }
 
 // DeleteRoute soft deletes a route.
-// DELETE /api/v1/routes/{id}
+// DELETE /api/v1/routes/{id}?dry_run=true
 func (h *RouteHandler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
@@ -263,7 +362,7 @@ func (h *RouteHandler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
 	oldRoute, err := h.store.GetRouteByID(uint(id))
 	if err != nil {
 		h.logger.Error("failed to get route", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
 		return
 	}
 	if oldRoute == nil {
@@ -271,46 +370,174 @@ func (h *RouteHandler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete route (soft delete)
-	if err := h.store.DeleteRoute(uint(id)); err != nil {
+	// Admission checks
+	reason := changeReason(r, "")
+	if !h.admit(w, r, "DELETE", oldRoute, nil, reason) {
+		return
+	}
+
+	// Delete route (soft delete) and record history
+	oldRoute.Enabled = false
+	err = commit(h.store, w, dryRun, func(tx config.Store) error {
+		if err := tx.DeleteRoute(uint(id)); err != nil {
+			return err
+		}
+		return tx.CreateHistory(newHistory("route", &oldRoute.ID, "DELETE", oldRoute, nil, reason, r))
+	})
+	if err != nil {
 		h.logger.Error("failed to delete route", zap.Error(err))
 		if err.Error() == "route not found" {
 			http.Error(w, "route not found", http.StatusNotFound)
 			return
 		}
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeServerError(w, err)
 		return
 	}
 
-	// Record history
-	oldRoute.Enabled = false
-	h.recordHistory("route", &oldRoute.ID, "DELETE", oldRoute, nil, r)
-
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// recordHistory records a configuration change history.
-func (h *RouteHandler) recordHistory(configType string, configID *uint, operation string, oldVal, newVal interface{}, r *http.Request) {
-	history := &config.ConfigHistory{
-		ConfigType: configType,
-		ConfigID:   configID,
+// admit runs the admission controllers for a proposed change and writes an
+// error response if the change is rejected.
+func (h *RouteHandler) admit(w http.ResponseWriter, r *http.Request, operation string, oldVal, newVal interface{}, reason string) bool {
+	req := &admission.Request{
 		Operation:  operation,
-		Operator:   r.Header.Get("X-Operator"), // Future: extract from auth token
+		ConfigType: "route",
+		Operator:   r.Header.Get("X-Operator"),
+		Role:       operatorRole(r),
+		Teams:      operatorTeams(r),
+		Reason:     reason,
+		Old:        oldVal,
+		New:        newVal,
+
+		FreezeOverride: freezeOverride(r),
+	}
+
+	if err := h.admission.Admit(r.Context(), req); err != nil {
+		writeAdmissionError(w, h.logger, err)
+		return false
 	}
+	return true
+}
+
+// missingRouteField returns the name of the first required field a new
+// route lacks, or "" if it has them all.
+func missingRouteField(route *config.Route) string {
+	switch {
+	case route.HTTPMethod == "":
+		return "http_method"
+	case route.HTTPPattern == "":
+		return "http_pattern"
+	case route.BackendName == "":
+		return "backend_name"
+	case route.BackendService == "":
+		return "backend_service"
+	case route.BackendMethod == "":
+		return "backend_method"
+	}
+	return ""
+}
 
-	if oldVal != nil {
-		if data, err := json.Marshal(oldVal); err == nil {
-			history.OldValue = data
+// checkUpdate validates route as the new value of oldRoute, writing an
+// error response if it is invalid. Warnings are returned with the
+// response, or reject the change in strict mode.
+func (h *RouteHandler) checkUpdate(w http.ResponseWriter, oldRoute, route *config.Route) bool {
+	if route.HTTPMethod == "" || route.HTTPPattern == "" || route.BackendName == "" ||
+		route.BackendService == "" || route.BackendMethod == "" {
+		http.Error(w, "required fields cannot be empty", http.StatusBadRequest)
+		return false
+	}
+	if err := route.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	// Check the RPC and query parameters if they change on an enabled route
+	if route.Enabled && (route.BackendName != oldRoute.BackendName ||
+		route.BackendService != oldRoute.BackendService || route.BackendMethod != oldRoute.BackendMethod ||
+		!reflect.DeepEqual(route.QueryParams, oldRoute.QueryParams)) {
+		if !h.checkDescriptors(w, route) {
+			return false
 		}
 	}
+	if route.Enabled && !h.checkTargets(w, route) {
+		return false
+	}
+	return h.warnings.check(w, h.warnings.route(route))
+}
 
-	if newVal != nil {
-		if data, err := json.Marshal(newVal); err == nil {
-			history.NewValue = data
+// checkDescriptors verifies that route calls a unary RPC defined in the
+// latest descriptor set of its backend, if it has one, and that its query
+// parameters map to fields of the request, writing an error response if
+// not.
+func (h *RouteHandler) checkDescriptors(w http.ResponseWriter, route *config.Route) bool {
+	msg, err := newDescriptorCheck(h.descriptors).check(route)
+	if err != nil {
+		h.logger.Error("failed to check route against descriptors", zap.Error(err))
+		writeServerError(w, err)
+		return false
+	}
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// checkTargets checks that the experiment variants, mirror and fallback of
+// route call enabled backends and, where the backends have descriptor sets,
+// defined RPCs, writing a 400 if not.
+func (h *RouteHandler) checkTargets(w http.ResponseWriter, route *config.Route) bool {
+	msg, err := checkTargets(h.store, newDescriptorCheck(h.descriptors), route)
+	if err != nil {
+		h.logger.Error("failed to check route targets", zap.Error(err))
+		writeServerError(w, err)
+		return false
+	}
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// checkTargets returns why a variant, the mirror or the fallback of route
+// cannot be called, or "" if all can.
+func checkTargets(store config.Store, descriptors *descriptorCheck, route *config.Route) (string, error) {
+	var targets []*config.Route
+	var paths []string
+	if route.Experiment != nil {
+		for i := range route.Experiment.Variants {
+			targets = append(targets, route.Experiment.Variants[i].Target(route))
+			paths = append(paths, fmt.Sprintf("experiment.variants[%d]", i))
 		}
 	}
+	if route.Mirror != nil {
+		targets = append(targets, route.Mirror.Target(route))
+		paths = append(paths, "mirror")
+	}
+	if route.Fallback != nil {
+		targets = append(targets, route.Fallback.Target(route))
+		paths = append(paths, "fallback")
+	}
 
-	if err := h.store.CreateHistory(history); err != nil {
-		h.logger.Warn("failed to record history", zap.Error(err))
+	for i, target := range targets {
+		if target.BackendName != route.BackendName {
+			backend, err := store.GetBackendByName(target.BackendName)
+			if err != nil {
+				return "", err
+			}
+			if backend == nil || !backend.Enabled {
+				return fmt.Sprintf("%s: backend %s not found or disabled", paths[i], target.BackendName), nil
+			}
+		}
+		msg, err := descriptors.check(target)
+		if err != nil || msg != "" {
+			if msg != "" {
+				msg = fmt.Sprintf("%s: %s", paths[i], msg)
+			}
+			return msg, err
+		}
 	}
+	return "", nil
 }
@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// ListMiddlewares returns the catalogue of gateway middlewares routes can
+// put in their chain, with their parameters.
+// GET /api/v1/middlewares
+func (h *RouteHandler) ListMiddlewares(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(config.Middlewares()); err != nil {
+		h.logger.Warn("failed to encode middlewares", zap.Error(err))
+	}
+}
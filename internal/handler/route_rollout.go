@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// SetRouteRollout sets the share of traffic a route serves, keeping the
+// rest of the route. hash_key is kept unless given; percent 100 ends the
+// rollout and serves all traffic.
+// PATCH /api/v1/routes/{id}/rollout?dry_run=true
+func (h *RouteHandler) SetRouteRollout(w http.ResponseWriter, r *http.Request) {
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid route id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Percent      *int    `json:"percent"`
+		HashKey      *string `json:"hash_key"`
+		ChangeReason string  `json:"change_reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Percent == nil {
+		http.Error(w, "percent is required", http.StatusBadRequest)
+		return
+	}
+
+	oldRoute, err := h.store.GetRouteByID(uint(id))
+	if err != nil {
+		h.logger.Error("failed to get route", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if oldRoute == nil {
+		http.Error(w, "route not found", http.StatusNotFound)
+		return
+	}
+
+	rollout := &config.RouteRollout{Percent: *req.Percent}
+	switch {
+	case req.HashKey != nil:
+		rollout.HashKey = *req.HashKey
+	case oldRoute.Rollout != nil:
+		rollout.HashKey = oldRoute.Rollout.HashKey
+	}
+	if err := rollout.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	route := *oldRoute
+	route.Rollout = rollout
+	if rollout.Percent == 100 {
+		route.Rollout = nil
+	}
+
+	reason := changeReason(r, req.ChangeReason)
+	if !h.admit(w, r, "UPDATE", oldRoute, &route, reason) {
+		return
+	}
+
+	err = commit(h.store, w, dryRun, func(tx config.Store) error {
+		if err := tx.UpdateRoute(route.ID, &route); err != nil {
+			return err
+		}
+		return tx.CreateHistory(newHistory("route", &route.ID, "UPDATE", oldRoute, &route, reason, r))
+	})
+	if err != nil {
+		h.logger.Error("failed to update route", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(route); err != nil {
+		h.logger.Warn("failed to encode route", zap.Error(err))
+	}
+}
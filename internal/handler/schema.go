@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/schema"
+)
+
+// SchemaHandler handles the versioned request and response schemas that
+// gateways validate route bodies against.
+type SchemaHandler struct {
+	store       config.Store
+	schemas     config.SchemaStore
+	descriptors config.DescriptorStore
+	logger      *zap.Logger
+}
+
+// NewSchemaHandler creates a new SchemaHandler. Schemas are kept in
+// schemas and changes recorded in the history of store. descriptors may be
+// nil, in which case schemas cannot reference protobuf messages.
+func NewSchemaHandler(store config.Store, schemas config.SchemaStore, descriptors config.DescriptorStore, logger *zap.Logger) *SchemaHandler {
+	return &SchemaHandler{
+		store:       store,
+		schemas:     schemas,
+		descriptors: descriptors,
+		logger:      logger,
+	}
+}
+
+// SetRouteSchemas stores the request and response schemas of a route as
+// its next version. Each is an inline JSON Schema document or a message of
+// the backend's descriptor set, and is checked before being stored; omit
+// both to turn validation off. Setting the same schemas as the latest
+// version returns that version unchanged with 200.
+// POST /api/v1/routes/{id}/schemas
+func (h *SchemaHandler) SetRouteSchemas(w http.ResponseWriter, r *http.Request) {
+	route, ok := h.route(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Request      *config.SchemaRef `json:"request"`
+		Response     *config.SchemaRef `json:"response"`
+		ChangeReason string            `json:"change_reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var files *protoregistry.Files
+	if (req.Request != nil && req.Request.Message != "") || (req.Response != nil && req.Response.Message != "") {
+		parsed, err := newDescriptorCheck(h.descriptors).load(route.BackendName)
+		if err != nil {
+			h.logger.Error("failed to load descriptor set", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		if parsed != nil {
+			files = parsed.files
+		}
+	}
+	for _, part := range []struct {
+		name string
+		ref  *config.SchemaRef
+	}{{"request", req.Request}, {"response", req.Response}} {
+		if part.ref == nil {
+			continue
+		}
+		msg, err := schema.Check(r.Context(), part.ref, files)
+		if err != nil {
+			h.logger.Error("failed to check schema", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		if msg != "" {
+			http.Error(w, part.name+": "+msg, http.StatusBadRequest)
+			return
+		}
+	}
+
+	s := &config.RouteSchema{
+		RouteID:   route.ID,
+		Request:   req.Request,
+		Response:  req.Response,
+		CreatedBy: r.Header.Get("X-Operator"),
+	}
+
+	latest, err := h.schemas.GetRouteSchema(route.ID, 0)
+	if err != nil {
+		h.logger.Error("failed to get route schema", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if latest != nil && sameSchemaRef(latest.Request, s.Request) && sameSchemaRef(latest.Response, s.Response) {
+		h.writeSchema(w, http.StatusOK, latest)
+		return
+	}
+	if latest == nil && s.Request == nil && s.Response == nil {
+		http.Error(w, "request or response is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.schemas.CreateRouteSchema(s); err != nil {
+		h.logger.Error("failed to create route schema", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	reason := changeReason(r, req.ChangeReason)
+	if err := h.store.CreateHistory(newHistory("schema", &s.ID, "CREATE", latest, s, reason, r)); err != nil {
+		h.logger.Warn("failed to record history", zap.Error(err))
+	}
+
+	h.writeSchema(w, http.StatusCreated, s)
+}
+
+// ListRouteSchemas returns the schema versions of a route, newest first.
+// GET /api/v1/routes/{id}/schemas
+func (h *SchemaHandler) ListRouteSchemas(w http.ResponseWriter, r *http.Request) {
+	route, ok := h.route(w, r)
+	if !ok {
+		return
+	}
+
+	schemas, err := h.schemas.GetRouteSchemas(route.ID)
+	if err != nil {
+		h.logger.Error("failed to get route schemas", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if schemas == nil {
+		schemas = []config.RouteSchema{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(schemas); err != nil {
+		h.logger.Warn("failed to encode route schemas", zap.Error(err))
+	}
+}
+
+// GetRouteSchema returns a schema version of a route; {version} may be
+// "latest".
+// GET /api/v1/routes/{id}/schemas/{version}
+func (h *SchemaHandler) GetRouteSchema(w http.ResponseWriter, r *http.Request) {
+	route, ok := h.route(w, r)
+	if !ok {
+		return
+	}
+
+	version := 0
+	if param := chi.URLParam(r, "version"); param != "latest" {
+		v, err := strconv.Atoi(param)
+		if err != nil || v < 1 {
+			http.Error(w, "invalid schema version (must be a positive number or 'latest')", http.StatusBadRequest)
+			return
+		}
+		version = v
+	}
+
+	s, err := h.schemas.GetRouteSchema(route.ID, version)
+	if err != nil {
+		h.logger.Error("failed to get route schema", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if s == nil {
+		http.Error(w, "route schema not found", http.StatusNotFound)
+		return
+	}
+	h.writeSchema(w, http.StatusOK, s)
+}
+
+// route loads the route named by the {id} URL parameter, writing an error
+// response if it cannot.
+func (h *SchemaHandler) route(w http.ResponseWriter, r *http.Request) (*config.Route, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid route id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	route, err := h.store.GetRouteByID(uint(id))
+	if err != nil {
+		h.logger.Error("failed to get route", zap.Uint64("id", id), zap.Error(err))
+		writeServerError(w, err)
+		return nil, false
+	}
+	if route == nil {
+		http.Error(w, "route not found", http.StatusNotFound)
+		return nil, false
+	}
+	return route, true
+}
+
+func (h *SchemaHandler) writeSchema(w http.ResponseWriter, status int, s *config.RouteSchema) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		h.logger.Warn("failed to encode route schema", zap.Error(err))
+	}
+}
+
+// sameSchemaRef reports whether two schemas are equal, comparing JSON
+// Schema documents by value.
+func sameSchemaRef(a, b *config.SchemaRef) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Message != b.Message {
+		return false
+	}
+	var av, bv interface{}
+	json.Unmarshal(a.JSONSchema, &av)
+	json.Unmarshal(b.JSONSchema, &bv)
+	return reflect.DeepEqual(av, bv)
+}
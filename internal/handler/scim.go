@@ -0,0 +1,671 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// SCIM schemas and messages (RFC 7643, RFC 7644).
+const (
+	scimUserSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimConfigSchema       = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimResourceTypeSchema = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	scimListSchema         = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema        = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	scimContentType = "application/scim+json"
+)
+
+// scimOperator is recorded as the creator of users provisioned over SCIM,
+// and as who added the team members it manages.
+const scimOperator = "scim"
+
+// scimMaxResults bounds the resources returned by a list request.
+const scimMaxResults = 200
+
+// SCIMOptions configures how the groups of the identity provider map to
+// roles and teams.
+type SCIMOptions struct {
+	// GroupRoles maps groups to roles. When set, the role of users
+	// provisioned over SCIM follows their groups: the most privileged
+	// role mapped, or DefaultRole.
+	GroupRoles auth.GroupRoles
+	// DefaultRole is the role of provisioned users in no group mapped to
+	// a role.
+	DefaultRole string
+	// GroupTeams maps groups to teams. Users provisioned over SCIM are
+	// members of the teams their groups map to, and of no other mapped
+	// team.
+	GroupTeams auth.GroupTeams
+}
+
+// SCIMHandler lets an identity provider provision local users and their
+// groups over SCIM 2.0, so that access follows the identity provider
+// without managing users by hand.
+type SCIMHandler struct {
+	users  config.UserStore
+	groups config.GroupStore
+	teams  config.TeamStore
+	opts   SCIMOptions
+	logger *zap.Logger
+}
+
+// NewSCIMHandler creates a new SCIMHandler. teams may be nil when no
+// group maps to a team.
+func NewSCIMHandler(users config.UserStore, groups config.GroupStore, teams config.TeamStore, opts SCIMOptions, logger *zap.Logger) *SCIMHandler {
+	return &SCIMHandler{
+		users:  users,
+		groups: groups,
+		teams:  teams,
+		opts:   opts,
+		logger: logger,
+	}
+}
+
+type scimMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+type scimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// displayName returns the name to display, or "" if there is none.
+func (n *scimName) displayName() string {
+	if n == nil {
+		return ""
+	}
+	if n.Formatted != "" {
+		return n.Formatted
+	}
+	return strings.TrimSpace(n.GivenName + " " + n.FamilyName)
+}
+
+type scimRole struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary"`
+}
+
+// scimUser is the SCIM representation of a user. Its ID is the user name,
+// which cannot change.
+type scimUser struct {
+	Schemas     []string  `json:"schemas"`
+	ID          string    `json:"id,omitempty"`
+	ExternalID  string    `json:"externalId,omitempty"`
+	UserName    string    `json:"userName"`
+	DisplayName string    `json:"displayName,omitempty"`
+	Name        *scimName `json:"name,omitempty"`
+	Active      *bool     `json:"active,omitempty"`
+	// Password is only ever received, to set the password of the user.
+	Password string     `json:"password,omitempty"`
+	Roles    []scimRole `json:"roles,omitempty"`
+	Meta     *scimMeta  `json:"meta,omitempty"`
+}
+
+func scimUserResource(u *config.User) scimUser {
+	active := !u.Disabled
+	return scimUser{
+		Schemas:     []string{scimUserSchema},
+		ID:          u.Name,
+		ExternalID:  u.ExternalID,
+		UserName:    u.Name,
+		DisplayName: u.DisplayName,
+		Active:      &active,
+		Roles:       []scimRole{{Value: u.Role, Primary: true}},
+		Meta: &scimMeta{
+			ResourceType: "User",
+			Created:      u.CreatedAt,
+			LastModified: u.UpdatedAt,
+			Location:     "/scim/v2/Users/" + u.Name,
+		},
+	}
+}
+
+// scimPatch is a PATCH request, applying operations in order.
+type scimPatch struct {
+	Operations []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	} `json:"Operations"`
+}
+
+// GetServiceProviderConfig describes the SCIM features supported.
+// GET /scim/v2/ServiceProviderConfig
+func (h *SCIMHandler) GetServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	h.write(w, http.StatusOK, map[string]any{
+		"schemas":        []string{scimConfigSchema},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": scimMaxResults},
+		"changePassword": map[string]bool{"supported": true},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "The SCIM token configured with ADMIN_SCIM_TOKEN",
+			"primary":     true,
+		}},
+	})
+}
+
+// ListResourceTypes describes the resources that can be provisioned.
+// GET /scim/v2/ResourceTypes
+func (h *SCIMHandler) ListResourceTypes(w http.ResponseWriter, r *http.Request) {
+	types := []any{
+		map[string]any{"schemas": []string{scimResourceTypeSchema}, "id": "User", "name": "User", "endpoint": "/Users", "schema": scimUserSchema},
+		map[string]any{"schemas": []string{scimResourceTypeSchema}, "id": "Group", "name": "Group", "endpoint": "/Groups", "schema": scimGroupSchema},
+	}
+	h.writeList(w, r, types)
+}
+
+// ListUsers returns the users, optionally filtered with an "eq" filter on
+// userName, externalId or id, such as `userName eq "alice"`.
+// GET /scim/v2/Users
+func (h *SCIMHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseSCIMFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		scimError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+		return
+	}
+	users, err := h.users.GetUsers()
+	if err != nil {
+		h.internalError(w, "failed to get users", err)
+		return
+	}
+
+	resources := []any{}
+	for i := range users {
+		u := &users[i]
+		if filter.match(map[string]string{"id": u.Name, "username": u.Name, "externalid": u.ExternalID}) {
+			resources = append(resources, scimUserResource(u))
+		}
+	}
+	h.writeList(w, r, resources)
+}
+
+// GetUser returns a user.
+// GET /scim/v2/Users/{id}
+func (h *SCIMHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+	h.write(w, http.StatusOK, scimUserResource(user))
+}
+
+// CreateUser provisions a user. Without a password from the identity
+// provider, the user cannot sign in until an admin sets one. Its role is
+// the default role until its groups map to another.
+// POST /scim/v2/Users
+func (h *SCIMHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req scimUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "invalid json")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := config.ValidateUserName(req.UserName); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	existing, err := h.users.GetUser(req.UserName)
+	if err != nil {
+		h.internalError(w, "failed to get user", err)
+		return
+	}
+	if existing != nil {
+		scimError(w, http.StatusConflict, "uniqueness", "user already exists")
+		return
+	}
+	var hash string
+	if req.Password != "" {
+		var ok bool
+		if hash, ok = h.hashPassword(w, req.Password); !ok {
+			return
+		}
+	}
+
+	displayName := req.DisplayName
+	if displayName == "" {
+		displayName = req.Name.displayName()
+	}
+	user := config.User{
+		Name:              req.UserName,
+		DisplayName:       displayName,
+		ExternalID:        req.ExternalID,
+		Role:              h.opts.DefaultRole,
+		PasswordHash:      hash,
+		Disabled:          req.Active != nil && !*req.Active,
+		PasswordChangedAt: time.Now().Truncate(time.Millisecond),
+		CreatedBy:         scimOperator,
+	}
+	if err := h.users.CreateUser(&user); err != nil {
+		h.internalError(w, "failed to create user", err)
+		return
+	}
+	if err := h.sync(user.Name); err != nil {
+		h.internalError(w, "failed to sync user from groups", err)
+		return
+	}
+
+	h.logger.Info("user provisioned",
+		zap.String("user", user.Name),
+		zap.String("external_id", user.ExternalID),
+		zap.Bool("disabled", user.Disabled),
+	)
+
+	created, err := h.users.GetUser(user.Name)
+	if err != nil || created == nil {
+		created = &user
+	}
+	h.write(w, http.StatusCreated, scimUserResource(created))
+}
+
+// ReplaceUser replaces the display name, external ID and active flag of a
+// user, and sets its password if given. Deactivating a user ends its
+// sessions. The userName cannot change.
+// PUT /scim/v2/Users/{id}
+func (h *SCIMHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+
+	var req scimUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "invalid json")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.UserName != existing.Name {
+		scimError(w, http.StatusBadRequest, "mutability", "userName cannot be changed")
+		return
+	}
+	user := *existing
+	user.DisplayName = req.DisplayName
+	if user.DisplayName == "" {
+		user.DisplayName = req.Name.displayName()
+	}
+	user.ExternalID = req.ExternalID
+	if req.Active != nil {
+		user.Disabled = !*req.Active
+	}
+	h.saveUser(w, existing, &user, req.Password)
+}
+
+// PatchUser changes a user with SCIM patch operations, typically to
+// deactivate it. Deactivating a user ends its sessions. Attributes the
+// service does not keep, such as emails, are ignored.
+// PATCH /scim/v2/Users/{id}
+func (h *SCIMHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+
+	var req scimPatch
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "invalid json")
+		return
+	}
+	defer r.Body.Close()
+
+	user := *existing
+	var password string
+	for _, op := range req.Operations {
+		var err error
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+			if op.Path != "" {
+				err = patchUserAttribute(&user, &password, op.Path, op.Value)
+				break
+			}
+			var values map[string]json.RawMessage
+			if err = json.Unmarshal(op.Value, &values); err != nil {
+				err = fmt.Errorf("value must be an object without a path")
+				break
+			}
+			for attr, value := range values {
+				if err = patchUserAttribute(&user, &password, attr, value); err != nil {
+					break
+				}
+			}
+		case "remove":
+			switch strings.ToLower(op.Path) {
+			case "externalid":
+				user.ExternalID = ""
+			case "displayname", "name", "name.formatted":
+				user.DisplayName = ""
+			default:
+				scimError(w, http.StatusBadRequest, "noTarget", fmt.Sprintf("cannot remove %q", op.Path))
+				return
+			}
+		default:
+			err = fmt.Errorf("unsupported op %q", op.Op)
+		}
+		if err != nil {
+			scimError(w, http.StatusBadRequest, "invalidValue", err.Error())
+			return
+		}
+	}
+	h.saveUser(w, existing, &user, password)
+}
+
+// patchUserAttribute sets an attribute of a user from a patch operation.
+func patchUserAttribute(user *config.User, password *string, attr string, value json.RawMessage) error {
+	var err error
+	switch strings.ToLower(attr) {
+	case "active":
+		var active bool
+		active, err = scimBool(value)
+		user.Disabled = !active
+	case "displayname", "name.formatted":
+		err = json.Unmarshal(value, &user.DisplayName)
+	case "name":
+		var name scimName
+		if err = json.Unmarshal(value, &name); err == nil && name.displayName() != "" {
+			user.DisplayName = name.displayName()
+		}
+	case "externalid":
+		err = json.Unmarshal(value, &user.ExternalID)
+	case "username":
+		var name string
+		if err = json.Unmarshal(value, &name); err == nil && name != user.Name {
+			err = fmt.Errorf("userName cannot be changed")
+		}
+	case "password":
+		err = json.Unmarshal(value, password)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", attr, err)
+	}
+	return nil
+}
+
+// scimBool decodes a boolean, which some identity providers send as the
+// string "True" or "False".
+func scimBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, fmt.Errorf("want a boolean")
+	}
+	return strconv.ParseBool(strings.ToLower(s))
+}
+
+// saveUser stores the changes to a user, along with its new password if
+// not empty, and writes the user.
+func (h *SCIMHandler) saveUser(w http.ResponseWriter, existing, user *config.User, password string) {
+	now := time.Now().Truncate(time.Millisecond)
+	if password != "" {
+		hash, ok := h.hashPassword(w, password)
+		if !ok {
+			return
+		}
+		if err := h.users.SetUserPassword(user.Name, hash, false, now); err != nil {
+			h.internalError(w, "failed to set password", err)
+			return
+		}
+		user.PasswordChangedAt = now
+	}
+	if err := h.users.UpdateUser(user.Name, user); err != nil {
+		h.internalError(w, "failed to update user", err)
+		return
+	}
+	if password != "" || (user.Disabled && !existing.Disabled) {
+		if err := h.users.RevokeUserSessions(user.Name, now); err != nil {
+			h.internalError(w, "failed to revoke sessions", err)
+			return
+		}
+	}
+
+	switch {
+	case user.Disabled && !existing.Disabled:
+		h.logger.Info("user deprovisioned", zap.String("user", user.Name))
+	case !user.Disabled && existing.Disabled:
+		h.logger.Info("user reprovisioned", zap.String("user", user.Name))
+	default:
+		h.logger.Info("provisioned user updated", zap.String("user", user.Name), zap.Bool("password", password != ""))
+	}
+
+	h.write(w, http.StatusOK, scimUserResource(user))
+}
+
+// DeleteUser removes a user, along with its sessions and team
+// memberships.
+// DELETE /scim/v2/Users/{id}
+func (h *SCIMHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "id")
+	if err := h.users.DeleteUser(name); err != nil {
+		if err.Error() == "user not found" {
+			scimError(w, http.StatusNotFound, "", "user not found")
+			return
+		}
+		h.internalError(w, "failed to delete user", err)
+		return
+	}
+	if h.teams != nil {
+		teams, err := h.teams.GetMemberTeams(name)
+		if err != nil {
+			h.internalError(w, "failed to get member teams", err)
+			return
+		}
+		for _, team := range teams {
+			if err := h.teams.RemoveTeamMember(team, name); err != nil && err.Error() != "team member not found" {
+				h.internalError(w, "failed to remove team member", err)
+				return
+			}
+		}
+	}
+
+	h.logger.Info("provisioned user deleted", zap.String("user", name))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sync brings the role and teams of a user provisioned over SCIM in line
+// with its groups. Other users are left alone.
+func (h *SCIMHandler) sync(name string) error {
+	user, err := h.users.GetUser(name)
+	if err != nil || user == nil || user.CreatedBy != scimOperator {
+		return err
+	}
+	groups, err := h.groups.GetUserGroups(name)
+	if err != nil {
+		return err
+	}
+
+	if len(h.opts.GroupRoles) > 0 {
+		role := h.opts.GroupRoles.Role(groups)
+		if role == "" {
+			role = h.opts.DefaultRole
+		}
+		if role != user.Role {
+			previous := user.Role
+			user.Role = role
+			if err := h.users.UpdateUser(name, user); err != nil {
+				return err
+			}
+			h.logger.Info("user role synced from groups",
+				zap.String("user", name),
+				zap.String("previous_role", previous),
+				zap.String("role", role),
+			)
+		}
+	}
+
+	if h.teams == nil || len(h.opts.GroupTeams) == 0 {
+		return nil
+	}
+	want := h.opts.GroupTeams.Teams(groups)
+	current, err := h.teams.GetMemberTeams(name)
+	if err != nil {
+		return err
+	}
+	for _, team := range h.opts.GroupTeams.Managed() {
+		switch member, wanted := slices.Contains(current, team), slices.Contains(want, team); {
+		case wanted && !member:
+			if err := h.teams.AddTeamMember(&config.TeamMember{Team: team, Member: name, AddedBy: scimOperator}); err != nil {
+				return err
+			}
+			h.logger.Info("team member synced from groups", zap.String("user", name), zap.String("team", team))
+		case member && !wanted:
+			if err := h.teams.RemoveTeamMember(team, name); err != nil && err.Error() != "team member not found" {
+				return err
+			}
+			h.logger.Info("team member removed by groups", zap.String("user", name), zap.String("team", team))
+		}
+	}
+	return nil
+}
+
+// syncAll syncs users, stopping at the first error.
+func (h *SCIMHandler) syncAll(users []string) error {
+	for _, u := range users {
+		if err := h.sync(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// user loads the user named in the URL, writing an error response if it
+// does not exist.
+func (h *SCIMHandler) user(w http.ResponseWriter, r *http.Request) (*config.User, bool) {
+	user, err := h.users.GetUser(chi.URLParam(r, "id"))
+	if err != nil {
+		h.internalError(w, "failed to get user", err)
+		return nil, false
+	}
+	if user == nil {
+		scimError(w, http.StatusNotFound, "", "user not found")
+		return nil, false
+	}
+	return user, true
+}
+
+// hashPassword checks and hashes a password set by the identity provider,
+// writing an error response if it cannot be used.
+func (h *SCIMHandler) hashPassword(w http.ResponseWriter, password string) (string, bool) {
+	if err := auth.ValidatePassword(password); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidValue", err.Error())
+		return "", false
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		h.internalError(w, "failed to hash password", err)
+		return "", false
+	}
+	return hash, true
+}
+
+// scimFilter is an "eq" filter on one attribute; the zero value matches
+// everything.
+type scimFilter struct {
+	attr  string
+	value string
+}
+
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*([a-z.]+)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+// parseSCIMFilter parses the filters identity providers use to look up a
+// resource, such as `userName eq "alice"`.
+func parseSCIMFilter(s string) (scimFilter, error) {
+	if strings.TrimSpace(s) == "" {
+		return scimFilter{}, nil
+	}
+	m := scimFilterPattern.FindStringSubmatch(s)
+	if m == nil {
+		return scimFilter{}, fmt.Errorf("unsupported filter %q (only `attribute eq \"value\"` is supported)", s)
+	}
+	f := scimFilter{attr: strings.ToLower(m[1])}
+	if err := json.Unmarshal([]byte(m[2]), &f.value); err != nil {
+		return scimFilter{}, fmt.Errorf("invalid filter value %s", m[2])
+	}
+	return f, nil
+}
+
+// match reports whether a resource with the attributes, keyed by lowercase
+// name, passes the filter. Values are compared ignoring case.
+func (f scimFilter) match(attrs map[string]string) bool {
+	if f.attr == "" {
+		return true
+	}
+	v, ok := attrs[f.attr]
+	return ok && strings.EqualFold(v, f.value)
+}
+
+// writeList writes the page of resources asked for by the startIndex and
+// count parameters.
+func (h *SCIMHandler) writeList(w http.ResponseWriter, r *http.Request, resources []any) {
+	start, count := 1, scimMaxResults
+	if v, err := strconv.Atoi(r.URL.Query().Get("startIndex")); err == nil && v > 1 {
+		start = v
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("count")); err == nil && v >= 0 && v < count {
+		count = v
+	}
+	total := len(resources)
+	page := resources[min(start-1, total):min(start-1+count, total)]
+	h.write(w, http.StatusOK, map[string]any{
+		"schemas":      []string{scimListSchema},
+		"totalResults": total,
+		"startIndex":   start,
+		"itemsPerPage": len(page),
+		"Resources":    page,
+	})
+}
+
+func (h *SCIMHandler) write(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Warn("failed to encode scim response", zap.Error(err))
+	}
+}
+
+func (h *SCIMHandler) internalError(w http.ResponseWriter, msg string, err error) {
+	h.logger.Error(msg, zap.Error(err))
+	if unavailable(w, err) {
+		scimError(w, http.StatusServiceUnavailable, "", "database unavailable")
+		return
+	}
+	scimError(w, http.StatusInternalServerError, "", "internal server error")
+}
+
+// scimError writes an error in the form SCIM clients expect.
+func scimError(w http.ResponseWriter, status int, scimType, detail string) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Schemas  []string `json:"schemas"`
+		Status   string   `json:"status"`
+		SCIMType string   `json:"scimType,omitempty"`
+		Detail   string   `json:"detail"`
+	}{[]string{scimErrorSchema}, strconv.Itoa(status), scimType, detail})
+}
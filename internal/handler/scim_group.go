@@ -0,0 +1,420 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// maxGroupNameLength is the maximum length of a group display name.
+const maxGroupNameLength = 255
+
+type scimMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// scimGroup is the SCIM representation of a group.
+type scimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []scimMember `json:"members,omitempty"`
+	Meta        *scimMeta    `json:"meta,omitempty"`
+}
+
+func scimGroupResource(g *config.Group, members bool) scimGroup {
+	resource := scimGroup{
+		Schemas:     []string{scimGroupSchema},
+		ID:          g.ID,
+		ExternalID:  g.ExternalID,
+		DisplayName: g.DisplayName,
+		Meta: &scimMeta{
+			ResourceType: "Group",
+			Created:      g.CreatedAt,
+			LastModified: g.UpdatedAt,
+			Location:     "/scim/v2/Groups/" + g.ID,
+		},
+	}
+	if members {
+		for _, m := range g.Members {
+			resource.Members = append(resource.Members, scimMember{Value: m, Display: m})
+		}
+	}
+	return resource
+}
+
+// memberNames returns the user names of members.
+func memberNames(members []scimMember) []string {
+	names := make([]string, 0, len(members))
+	for _, m := range members {
+		if !slices.Contains(names, m.Value) {
+			names = append(names, m.Value)
+		}
+	}
+	return names
+}
+
+// ListGroups returns the groups, optionally filtered with an "eq" filter
+// on displayName, externalId or id. Members are left out with
+// excludedAttributes=members.
+// GET /scim/v2/Groups
+func (h *SCIMHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseSCIMFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		scimError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+		return
+	}
+	groups, err := h.groups.GetGroups()
+	if err != nil {
+		h.internalError(w, "failed to get groups", err)
+		return
+	}
+
+	members := !strings.Contains(strings.ToLower(r.URL.Query().Get("excludedAttributes")), "members")
+	resources := []any{}
+	for i := range groups {
+		g := &groups[i]
+		if filter.match(map[string]string{"id": g.ID, "displayname": g.DisplayName, "externalid": g.ExternalID}) {
+			resources = append(resources, scimGroupResource(g, members))
+		}
+	}
+	h.writeList(w, r, resources)
+}
+
+// GetGroup returns a group with its members.
+// GET /scim/v2/Groups/{id}
+func (h *SCIMHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := h.group(w, r)
+	if !ok {
+		return
+	}
+	h.write(w, http.StatusOK, scimGroupResource(group, true))
+}
+
+// CreateGroup creates a group of provisioned users, whose roles and teams
+// then follow it if it is mapped.
+// POST /scim/v2/Groups
+func (h *SCIMHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req scimGroup
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "invalid json")
+		return
+	}
+	defer r.Body.Close()
+
+	group := config.Group{
+		DisplayName: req.DisplayName,
+		ExternalID:  req.ExternalID,
+		Members:     memberNames(req.Members),
+	}
+	if !h.checkGroup(w, "", &group) {
+		return
+	}
+	id, err := newGroupID()
+	if err != nil {
+		h.internalError(w, "failed to generate group id", err)
+		return
+	}
+	group.ID = id
+	if err := h.groups.CreateGroup(&group); err != nil {
+		h.internalError(w, "failed to create group", err)
+		return
+	}
+	if err := h.syncAll(group.Members); err != nil {
+		h.internalError(w, "failed to sync users from groups", err)
+		return
+	}
+
+	h.logger.Info("group provisioned",
+		zap.String("group", group.DisplayName),
+		zap.Int("members", len(group.Members)),
+	)
+
+	h.write(w, http.StatusCreated, scimGroupResource(&group, true))
+}
+
+// ReplaceGroup replaces the display name, external ID and members of a
+// group.
+// PUT /scim/v2/Groups/{id}
+func (h *SCIMHandler) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.group(w, r)
+	if !ok {
+		return
+	}
+
+	var req scimGroup
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "invalid json")
+		return
+	}
+	defer r.Body.Close()
+
+	group := *existing
+	group.DisplayName = req.DisplayName
+	group.ExternalID = req.ExternalID
+	group.Members = memberNames(req.Members)
+	if !h.checkGroup(w, existing.ID, &group) {
+		return
+	}
+	if err := h.groups.UpdateGroup(existing.ID, &group); err != nil {
+		h.internalError(w, "failed to update group", err)
+		return
+	}
+	h.groupUpdated(w, existing, &group)
+}
+
+// scimMemberPath matches the path removing one member, such as
+// `members[value eq "alice"]`.
+var scimMemberPath = regexp.MustCompile(`(?i)^members\[value eq ("(?:[^"\\]|\\.)*")\]$`)
+
+// PatchGroup changes a group with SCIM patch operations, typically adding
+// or removing members.
+// PATCH /scim/v2/Groups/{id}
+func (h *SCIMHandler) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.group(w, r)
+	if !ok {
+		return
+	}
+
+	var req scimPatch
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "invalid json")
+		return
+	}
+	defer r.Body.Close()
+
+	group := *existing
+	group.Members = slices.Clone(existing.Members)
+	// Changes of members only are applied member by member, so that
+	// concurrent changes of other members are kept
+	membersOnly := true
+	for _, op := range req.Operations {
+		var err error
+		switch kind, path := strings.ToLower(op.Op), strings.ToLower(op.Path); {
+		case (kind == "add" || kind == "replace") && path == "":
+			var values map[string]json.RawMessage
+			if err = json.Unmarshal(op.Value, &values); err != nil {
+				err = fmt.Errorf("value must be an object without a path")
+				break
+			}
+			for attr, value := range values {
+				if err = patchGroupAttribute(&group, &membersOnly, kind, attr, value); err != nil {
+					break
+				}
+			}
+		case kind == "add" || kind == "replace":
+			err = patchGroupAttribute(&group, &membersOnly, kind, op.Path, op.Value)
+		case kind == "remove" && path == "members" && len(op.Value) == 0:
+			group.Members = nil
+			membersOnly = false
+		case kind == "remove" && path == "members":
+			var members []scimMember
+			if err = json.Unmarshal(op.Value, &members); err != nil {
+				err = fmt.Errorf("members: %w", err)
+				break
+			}
+			group.Members = slices.DeleteFunc(group.Members, func(m string) bool {
+				return slices.Contains(memberNames(members), m)
+			})
+		case kind == "remove" && scimMemberPath.MatchString(op.Path):
+			var member string
+			if err = json.Unmarshal([]byte(scimMemberPath.FindStringSubmatch(op.Path)[1]), &member); err != nil {
+				break
+			}
+			group.Members = slices.DeleteFunc(group.Members, func(m string) bool { return m == member })
+		case kind == "remove" && path == "externalid":
+			group.ExternalID = ""
+			membersOnly = false
+		case kind == "remove":
+			scimError(w, http.StatusBadRequest, "noTarget", fmt.Sprintf("cannot remove %q", op.Path))
+			return
+		default:
+			err = fmt.Errorf("unsupported op %q", op.Op)
+		}
+		if err != nil {
+			scimError(w, http.StatusBadRequest, "invalidValue", err.Error())
+			return
+		}
+	}
+	if !h.checkGroup(w, existing.ID, &group) {
+		return
+	}
+
+	var err error
+	if membersOnly {
+		var added, removed []string
+		for _, m := range group.Members {
+			if !slices.Contains(existing.Members, m) {
+				added = append(added, m)
+			}
+		}
+		for _, m := range existing.Members {
+			if !slices.Contains(group.Members, m) {
+				removed = append(removed, m)
+			}
+		}
+		if err = h.groups.AddGroupMembers(existing.ID, added); err == nil {
+			err = h.groups.RemoveGroupMembers(existing.ID, removed)
+		}
+	} else {
+		err = h.groups.UpdateGroup(existing.ID, &group)
+	}
+	if err != nil {
+		h.internalError(w, "failed to update group", err)
+		return
+	}
+	h.groupUpdated(w, existing, &group)
+}
+
+// patchGroupAttribute sets, or for members with "add" extends, an
+// attribute of a group from a patch operation. It clears membersOnly for
+// changes other than adding members.
+func patchGroupAttribute(group *config.Group, membersOnly *bool, kind, attr string, value json.RawMessage) error {
+	var err error
+	switch strings.ToLower(attr) {
+	case "displayname":
+		err = json.Unmarshal(value, &group.DisplayName)
+		*membersOnly = false
+	case "externalid":
+		err = json.Unmarshal(value, &group.ExternalID)
+		*membersOnly = false
+	case "members":
+		var members []scimMember
+		if err = json.Unmarshal(value, &members); err != nil {
+			break
+		}
+		if kind == "replace" {
+			group.Members = nil
+			*membersOnly = false
+		}
+		for _, m := range memberNames(members) {
+			if !slices.Contains(group.Members, m) {
+				group.Members = append(group.Members, m)
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", attr, err)
+	}
+	return nil
+}
+
+// DeleteGroup removes a group. Its members no longer get the role and
+// teams it maps to.
+// DELETE /scim/v2/Groups/{id}
+func (h *SCIMHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.group(w, r)
+	if !ok {
+		return
+	}
+	if err := h.groups.DeleteGroup(existing.ID); err != nil {
+		if err.Error() == "group not found" {
+			scimError(w, http.StatusNotFound, "", "group not found")
+			return
+		}
+		h.internalError(w, "failed to delete group", err)
+		return
+	}
+	if err := h.syncAll(existing.Members); err != nil {
+		h.internalError(w, "failed to sync users from groups", err)
+		return
+	}
+
+	h.logger.Info("group deleted", zap.String("group", existing.DisplayName))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// groupUpdated syncs the users that joined or left a group, or all of its
+// members if it was renamed, and writes the group.
+func (h *SCIMHandler) groupUpdated(w http.ResponseWriter, existing, group *config.Group) {
+	var users []string
+	for _, m := range append(slices.Clone(existing.Members), group.Members...) {
+		changed := slices.Contains(existing.Members, m) != slices.Contains(group.Members, m)
+		if (changed || group.DisplayName != existing.DisplayName) && !slices.Contains(users, m) {
+			users = append(users, m)
+		}
+	}
+	if err := h.syncAll(users); err != nil {
+		h.internalError(w, "failed to sync users from groups", err)
+		return
+	}
+
+	h.logger.Info("group updated",
+		zap.String("group", group.DisplayName),
+		zap.Int("members", len(group.Members)),
+		zap.Int("synced", len(users)),
+	)
+
+	slices.Sort(group.Members)
+	h.write(w, http.StatusOK, scimGroupResource(group, true))
+}
+
+// checkGroup checks the display name and members of a group, writing an
+// error response if they are not valid. id is the group being replaced,
+// if any.
+func (h *SCIMHandler) checkGroup(w http.ResponseWriter, id string, group *config.Group) bool {
+	if strings.TrimSpace(group.DisplayName) == "" || len(group.DisplayName) > maxGroupNameLength {
+		scimError(w, http.StatusBadRequest, "invalidValue", fmt.Sprintf("displayName is required, at most %d characters", maxGroupNameLength))
+		return false
+	}
+	groups, err := h.groups.GetGroups()
+	if err != nil {
+		h.internalError(w, "failed to get groups", err)
+		return false
+	}
+	for _, g := range groups {
+		if g.ID != id && g.DisplayName == group.DisplayName {
+			scimError(w, http.StatusConflict, "uniqueness", "a group with this displayName already exists")
+			return false
+		}
+	}
+	for _, m := range group.Members {
+		user, err := h.users.GetUser(m)
+		if err != nil {
+			h.internalError(w, "failed to get user", err)
+			return false
+		}
+		if user == nil {
+			scimError(w, http.StatusBadRequest, "invalidValue", fmt.Sprintf("member %q is not a user", m))
+			return false
+		}
+	}
+	return true
+}
+
+// group loads the group named in the URL, writing an error response if it
+// does not exist.
+func (h *SCIMHandler) group(w http.ResponseWriter, r *http.Request) (*config.Group, bool) {
+	group, err := h.groups.GetGroup(chi.URLParam(r, "id"))
+	if err != nil {
+		h.internalError(w, "failed to get group", err)
+		return nil, false
+	}
+	if group == nil {
+		scimError(w, http.StatusNotFound, "", "group not found")
+		return nil, false
+	}
+	return group, true
+}
+
+// newGroupID returns a random group ID.
+func newGroupID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// sessionView is a session of the caller, marking the one of the request.
+type sessionView struct {
+	config.Session
+	Current bool `json:"current"`
+}
+
+// ListSessions returns the caller's active sessions, most recently used
+// first.
+// GET /api/v1/sessions
+func (h *LoginHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	p := auth.FromContext(r.Context())
+	if p == nil {
+		http.Error(w, "sign in required", http.StatusUnauthorized)
+		return
+	}
+	sessions, err := h.users.GetUserSessions(p.Name, time.Now())
+	if err != nil {
+		h.logger.Error("failed to get sessions", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	views := make([]sessionView, 0, len(sessions))
+	for _, s := range sessions {
+		views = append(views, sessionView{Session: s, Current: s.ID == p.Session})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(views); err != nil {
+		h.logger.Warn("failed to encode sessions", zap.Error(err))
+	}
+}
+
+// RevokeSession ends one of the caller's sessions, such as the current
+// one to sign out. Its tokens are no longer accepted, and the cookies of
+// the current browser session are cleared.
+// DELETE /api/v1/sessions/{id}
+func (h *LoginHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	p := auth.FromContext(r.Context())
+	if p == nil {
+		http.Error(w, "sign in required", http.StatusUnauthorized)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	session, err := h.users.GetSession(id)
+	if err != nil {
+		h.logger.Error("failed to get session", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	now := time.Now()
+	if session == nil || session.User != p.Name || !session.ActiveAt(now) {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.users.RevokeSession(id, now); err != nil {
+		if err.Error() == "session not found" {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to revoke session", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("session revoked", zap.String("user", p.Name), zap.String("session", id))
+
+	if _, cookie := auth.CookieToken(r); cookie && id == p.Session {
+		auth.ClearSessionCookies(w, h.opts.SecureCookies)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeSessions ends every session of the caller, including the current
+// one, signing them out everywhere.
+// DELETE /api/v1/sessions
+func (h *LoginHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	p := auth.FromContext(r.Context())
+	if p == nil {
+		http.Error(w, "sign in required", http.StatusUnauthorized)
+		return
+	}
+	if err := h.users.RevokeUserSessions(p.Name, time.Now()); err != nil {
+		h.logger.Error("failed to revoke sessions", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("all sessions revoked", zap.String("user", p.Name))
+
+	if _, cookie := auth.CookieToken(r); cookie {
+		auth.ClearSessionCookies(w, h.opts.SecureCookies)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,340 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/apply"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/jobs"
+)
+
+// SnapshotHandler handles named configuration snapshots. Restoring a
+// snapshot applies it like a desired-state document.
+type SnapshotHandler struct {
+	store     config.Store
+	snapshots config.SnapshotStore
+	admission admission.Controller
+	jobs      *jobs.Runner
+	logger    *zap.Logger
+}
+
+// NewSnapshotHandler creates a new SnapshotHandler. Snapshots are kept in
+// snapshots and restored into store. If runner is not nil, snapshots can
+// be taken and restored in the background.
+func NewSnapshotHandler(store config.Store, snapshots config.SnapshotStore, admission admission.Controller, runner *jobs.Runner, logger *zap.Logger) *SnapshotHandler {
+	h := &SnapshotHandler{
+		store:     store,
+		snapshots: snapshots,
+		admission: admission,
+		jobs:      runner,
+		logger:    logger,
+	}
+	if runner != nil {
+		runner.Register(jobSnapshot, h.runSnapshot)
+		runner.Register(jobSnapshotRestore, h.runRestore)
+	}
+	return h
+}
+
+// snapshotParams are the params of a snapshot job.
+type snapshotParams struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	CreatedBy   string `json:"created_by,omitempty"`
+}
+
+// restoreParams are the params of a snapshot restore job.
+type restoreParams struct {
+	SnapshotID uint        `json:"snapshot_id"`
+	Actor      apply.Actor `json:"actor"`
+	PlanOnly   bool        `json:"plan_only,omitempty"`
+}
+
+// ListSnapshots returns all snapshots, newest first, without their
+// configuration.
+// GET /api/v1/snapshots
+func (h *SnapshotHandler) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := h.snapshots.GetSnapshots()
+	if err != nil {
+		h.logger.Error("failed to get snapshots", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if snapshots == nil {
+		snapshots = []config.Snapshot{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(snapshots); err != nil {
+		h.logger.Warn("failed to encode snapshots", zap.Error(err))
+	}
+}
+
+// GetSnapshot returns a snapshot including its configuration document.
+// GET /api/v1/snapshots/{id}
+func (h *SnapshotHandler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(snap); err != nil {
+		h.logger.Warn("failed to encode snapshot", zap.Error(err))
+	}
+}
+
+// CreateSnapshot captures the current configuration, including disabled
+// resources, under a name. With async=true it is captured by a background
+// job, whose result is the snapshot.
+// POST /api/v1/snapshots?async=true
+func (h *SnapshotHandler) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	async, ok := parseAsync(w, r, h.jobs)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Name) > 128 || len(req.Description) > 512 {
+		http.Error(w, "name or description too long (at most 128 and 512 characters)", http.StatusBadRequest)
+		return
+	}
+
+	params := snapshotParams{Name: req.Name, Description: req.Description, CreatedBy: r.Header.Get("X-Operator")}
+	if async {
+		submitJob(w, r, h.jobs, jobSnapshot, params, h.logger)
+		return
+	}
+
+	snap, err := h.take(params)
+	if err != nil {
+		h.logger.Error("failed to create snapshot", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(snap); err != nil {
+		h.logger.Warn("failed to encode snapshot", zap.Error(err))
+	}
+}
+
+// take captures the current configuration as a snapshot, returned without
+// its document.
+func (h *SnapshotHandler) take(params snapshotParams) (*config.Snapshot, error) {
+	// Read backends and routes in one transaction so they are consistent
+	var doc *apply.Document
+	err := h.store.InTx(func(tx config.Store) error {
+		backends, err := tx.GetBackends(nil)
+		if err != nil {
+			return err
+		}
+		routes, err := tx.GetRoutes(nil)
+		if err != nil {
+			return err
+		}
+		doc = apply.Export(backends, routes)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read configuration: %w", err)
+	}
+
+	data, err := doc.Marshal("json")
+	if err != nil {
+		return nil, fmt.Errorf("encode document: %w", err)
+	}
+
+	snap := &config.Snapshot{
+		Name:        params.Name,
+		Description: params.Description,
+		Backends:    len(doc.Backends),
+		Routes:      len(doc.Routes),
+		CreatedBy:   params.CreatedBy,
+		Document:    data,
+	}
+	if err := h.snapshots.CreateSnapshot(snap); err != nil {
+		return nil, err
+	}
+
+	// The document can be large; fetch it with GetSnapshot
+	snap.Document = nil
+	return snap, nil
+}
+
+// runSnapshot runs a snapshot job.
+func (h *SnapshotHandler) runSnapshot(ctx context.Context, params json.RawMessage, progress func(done, total int)) (interface{}, error) {
+	var p snapshotParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("decode params: %w", err)
+	}
+	return h.take(p)
+}
+
+// RestoreSnapshot returns the configuration to a snapshot: resources are
+// created, updated or disabled to match it, through the same admission
+// checks and history as an apply. With plan_only=true the plan is returned
+// without being applied. With async=true it is restored by a background
+// job, whose result is the response RestoreSnapshot would have returned.
+// POST /api/v1/snapshots/{id}/restore?plan_only=true&async=true
+func (h *SnapshotHandler) RestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	async, ok := parseAsync(w, r, h.jobs)
+	if !ok {
+		return
+	}
+
+	planOnly := false
+	if param := r.URL.Query().Get("plan_only"); param != "" {
+		val, err := strconv.ParseBool(param)
+		if err != nil {
+			http.Error(w, "invalid plan_only parameter", http.StatusBadRequest)
+			return
+		}
+		planOnly = val
+	}
+
+	snap, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	doc, err := apply.ParseDocument(snap.Document)
+	if err != nil {
+		h.logger.Error("failed to decode snapshot document", zap.Uint("id", snap.ID), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if err := doc.Validate(); err != nil {
+		http.Error(w, "cannot restore snapshot: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	reason := changeReason(r, "")
+	if reason == "" {
+		reason = fmt.Sprintf("restore snapshot %d (%s)", snap.ID, snap.Name)
+	}
+	actor := apply.Actor{
+		Operator:       r.Header.Get("X-Operator"),
+		Role:           operatorRole(r),
+		Teams:          operatorTeams(r),
+		Reason:         reason,
+		FreezeOverride: freezeOverride(r),
+	}
+	if async {
+		submitJob(w, r, h.jobs, jobSnapshotRestore, restoreParams{SnapshotID: snap.ID, Actor: actor, PlanOnly: planOnly}, h.logger)
+		return
+	}
+
+	plan, err := apply.ComputePlan(h.store, doc)
+	if err != nil {
+		h.logger.Error("failed to compute restore plan", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	if err := apply.Admit(r.Context(), h.admission, plan, actor); err != nil {
+		writeAdmissionError(w, h.logger, err)
+		return
+	}
+
+	response := applyResponse{Plan: plan}
+	if !planOnly && plan.HasChanges() {
+		if err := apply.Execute(h.store, plan, actor); err != nil {
+			writeExecuteError(w, h.logger, "failed to restore snapshot", err, zap.Uint("id", snap.ID))
+			return
+		}
+		response.Applied = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Warn("failed to encode restore response", zap.Error(err))
+	}
+}
+
+// runRestore runs a snapshot restore job.
+func (h *SnapshotHandler) runRestore(ctx context.Context, params json.RawMessage, progress func(done, total int)) (interface{}, error) {
+	var p restoreParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("decode params: %w", err)
+	}
+
+	snap, err := h.snapshots.GetSnapshotByID(p.SnapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, fmt.Errorf("snapshot %d not found", p.SnapshotID)
+	}
+	doc, err := apply.ParseDocument(snap.Document)
+	if err != nil {
+		return nil, fmt.Errorf("decode snapshot document: %w", err)
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, fmt.Errorf("cannot restore snapshot: %w", err)
+	}
+	return runPlan(ctx, h.store, h.admission, doc, p.Actor, p.PlanOnly, progress)
+}
+
+// DeleteSnapshot deletes a snapshot. The configuration is not changed.
+// DELETE /api/v1/snapshots/{id}
+func (h *SnapshotHandler) DeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.snapshots.DeleteSnapshot(snap.ID); err != nil {
+		h.logger.Error("failed to delete snapshot", zap.Uint("id", snap.ID), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lookup loads the snapshot named by the {id} URL parameter, writing an
+// error response if it cannot.
+func (h *SnapshotHandler) lookup(w http.ResponseWriter, r *http.Request) (*config.Snapshot, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid snapshot id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	snap, err := h.snapshots.GetSnapshotByID(uint(id))
+	if err != nil {
+		h.logger.Error("failed to get snapshot", zap.Uint64("id", id), zap.Error(err))
+		writeServerError(w, err)
+		return nil, false
+	}
+	if snap == nil {
+		http.Error(w, "snapshot not found", http.StatusNotFound)
+		return nil, false
+	}
+	return snap, true
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/stats"
+)
+
+const (
+	// maxStatsWindow bounds how far back a route stats report may look.
+	maxStatsWindow = 31 * 24 * time.Hour
+	// maxStatsBuckets bounds the length of a route stats time series.
+	maxStatsBuckets = 1000
+	// defaultStatsBuckets is the series length when no step is given.
+	defaultStatsBuckets = 48
+	// maxStatsBodyBytes bounds the size of a stats push.
+	maxStatsBodyBytes = 8 << 20
+)
+
+// StatsHandler ingests runtime traffic stats from gateways and reports
+// them per route.
+type StatsHandler struct {
+	store  config.Store
+	logger *zap.Logger
+}
+
+// NewStatsHandler creates a new StatsHandler.
+func NewStatsHandler(store config.Store, logger *zap.Logger) *StatsHandler {
+	return &StatsHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// statsStore returns the store's stats capability, answering 501 if it
+// has none.
+func (h *StatsHandler) statsStore(w http.ResponseWriter) (config.StatsStore, bool) {
+	ss, ok := h.store.(config.StatsStore)
+	if !ok {
+		http.Error(w, "route stats are not supported by this store", http.StatusNotImplemented)
+	}
+	return ss, ok
+}
+
+// Push records per-route traffic counts reported by a gateway. It must
+// only be mounted behind gateway authentication.
+// POST /api/v1/stats
+func (h *StatsHandler) Push(w http.ResponseWriter, r *http.Request) {
+	ss, ok := h.statsStore(w)
+	if !ok {
+		return
+	}
+
+	var push stats.Push
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxStatsBodyBytes)).Decode(&push); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := push.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ss.RecordRouteStats(push.Routes); err != nil {
+		h.logger.Error("failed to record route stats", zap.String("gateway", push.Gateway), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRouteStats returns traffic totals, error rate and latency percentiles
+// for a route over a window (default 24h), with a time series bucketed by
+// step (default window/48, at least one minute).
+// GET /api/v1/routes/{id}/stats?window=24h&step=1h
+func (h *StatsHandler) GetRouteStats(w http.ResponseWriter, r *http.Request) {
+	ss, ok := h.statsStore(w)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid route id", http.StatusBadRequest)
+		return
+	}
+
+	window := 24 * time.Hour
+	if param := r.URL.Query().Get("window"); param != "" {
+		d, err := parseWindow(param)
+		if err != nil || d <= 0 || d > maxStatsWindow {
+			http.Error(w, "invalid window: must be a positive duration up to 31d", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	step := window / defaultStatsBuckets
+	if param := r.URL.Query().Get("step"); param != "" {
+		d, err := time.ParseDuration(param)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid step", http.StatusBadRequest)
+			return
+		}
+		step = d
+	}
+	// Aggregates are kept per minute
+	step = step.Truncate(time.Minute)
+	if step < time.Minute {
+		step = time.Minute
+	}
+	if (window+step-1)/step > maxStatsBuckets {
+		http.Error(w, "step too small for window: at most 1000 buckets", http.StatusBadRequest)
+		return
+	}
+
+	route, err := h.store.GetRouteByID(uint(id))
+	if err != nil {
+		h.logger.Error("failed to get route", zap.Uint64("id", id), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if route == nil {
+		http.Error(w, "route not found", http.StatusNotFound)
+		return
+	}
+
+	// Align to whole minutes so every aggregate falls in one bucket
+	to := time.Now().UTC().Truncate(time.Minute).Add(time.Minute)
+	from := to.Add(-window)
+	rows, err := ss.GetRouteStats(route.ID, from)
+	if err != nil {
+		h.logger.Error("failed to get route stats", zap.Uint64("id", id), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	report := stats.NewReport(route.ID, rows, from, to, step)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		h.logger.Warn("failed to encode route stats", zap.Error(err))
+	}
+}
@@ -0,0 +1,335 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// TeamHandler handles the teams that own backends and routes.
+type TeamHandler struct {
+	store  config.Store
+	teams  config.TeamStore
+	logger *zap.Logger
+}
+
+// NewTeamHandler creates a new TeamHandler.
+func NewTeamHandler(store config.Store, teams config.TeamStore, logger *zap.Logger) *TeamHandler {
+	return &TeamHandler{
+		store:  store,
+		teams:  teams,
+		logger: logger,
+	}
+}
+
+// teamRequest is the body of CreateTeam and UpdateTeam.
+type teamRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Contact     string `json:"contact"`
+}
+
+// ListTeams returns every team.
+// GET /api/v1/teams
+func (h *TeamHandler) ListTeams(w http.ResponseWriter, r *http.Request) {
+	teams, err := h.teams.GetTeams()
+	if err != nil {
+		h.logger.Error("failed to get teams", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if teams == nil {
+		teams = []config.Team{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(teams); err != nil {
+		h.logger.Warn("failed to encode teams", zap.Error(err))
+	}
+}
+
+// teamView is a team together with its members.
+type teamView struct {
+	config.Team
+	Members []string `json:"members"`
+}
+
+// GetTeam returns a team and the names of its members.
+// GET /api/v1/teams/{name}
+func (h *TeamHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
+	team, ok := h.team(w, r)
+	if !ok {
+		return
+	}
+	members, err := h.teams.GetTeamMembers(team.Name)
+	if err != nil {
+		h.logger.Error("failed to get team members", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	view := teamView{Team: *team, Members: make([]string, 0, len(members))}
+	for _, m := range members {
+		view.Members = append(view.Members, m.Member)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(view); err != nil {
+		h.logger.Warn("failed to encode team", zap.Error(err))
+	}
+}
+
+// CreateTeam creates a new team, which backends and routes may then name
+// as their owner_team. Admin only.
+// POST /api/v1/teams
+func (h *TeamHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	var req teamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := config.ValidateTeamName(req.Name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existing, err := h.teams.GetTeam(req.Name)
+	if err != nil {
+		h.logger.Error("failed to get team", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if existing != nil {
+		http.Error(w, "team already exists", http.StatusConflict)
+		return
+	}
+
+	team := config.Team{
+		Name:        req.Name,
+		Description: req.Description,
+		Contact:     req.Contact,
+		CreatedBy:   auth.FromContext(r.Context()).Name,
+	}
+	if err := h.teams.CreateTeam(&team); err != nil {
+		h.logger.Error("failed to create team", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("team created",
+		zap.String("team", team.Name),
+		zap.String("operator", team.CreatedBy),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(team); err != nil {
+		h.logger.Warn("failed to encode team", zap.Error(err))
+	}
+}
+
+// UpdateTeam replaces the description and contact of a team. Admin only.
+// PUT /api/v1/teams/{name}
+func (h *TeamHandler) UpdateTeam(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.team(w, r)
+	if !ok {
+		return
+	}
+	name := existing.Name
+
+	var req teamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	if req.Name != "" && req.Name != name {
+		http.Error(w, "team name cannot be changed", http.StatusBadRequest)
+		return
+	}
+
+	team := *existing
+	team.Description = req.Description
+	team.Contact = req.Contact
+	if err := h.teams.UpdateTeam(name, &team); err != nil {
+		h.logger.Error("failed to update team", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("team updated",
+		zap.String("team", name),
+		zap.String("operator", auth.FromContext(r.Context()).Name),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(team); err != nil {
+		h.logger.Warn("failed to encode team", zap.Error(err))
+	}
+}
+
+// DeleteTeam removes a team and its memberships. A team still owning
+// backends or routes cannot be deleted; reassign them first. Admin only.
+// DELETE /api/v1/teams/{name}
+func (h *TeamHandler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	backends, err := h.store.GetBackends(nil)
+	if err != nil {
+		h.logger.Error("failed to get backends", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	routes, err := h.store.GetRoutes(nil)
+	if err != nil {
+		h.logger.Error("failed to get routes", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	var owned, ownedRoutes int
+	for i := range backends {
+		if backends[i].OwnerTeam == name {
+			owned++
+		}
+	}
+	for i := range routes {
+		if routes[i].OwnerTeam == name {
+			ownedRoutes++
+		}
+	}
+	if owned > 0 || ownedRoutes > 0 {
+		http.Error(w, fmt.Sprintf("team still owns %d backends and %d routes", owned, ownedRoutes), http.StatusConflict)
+		return
+	}
+
+	if err := h.teams.DeleteTeam(name); err != nil {
+		if err.Error() == "team not found" {
+			http.Error(w, "team not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to delete team", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("team deleted",
+		zap.String("team", name),
+		zap.String("operator", auth.FromContext(r.Context()).Name),
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListTeamMembers returns the members of a team.
+// GET /api/v1/teams/{name}/members
+func (h *TeamHandler) ListTeamMembers(w http.ResponseWriter, r *http.Request) {
+	team, ok := h.team(w, r)
+	if !ok {
+		return
+	}
+	members, err := h.teams.GetTeamMembers(team.Name)
+	if err != nil {
+		h.logger.Error("failed to get team members", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if members == nil {
+		members = []config.TeamMember{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(members); err != nil {
+		h.logger.Warn("failed to encode team members", zap.Error(err))
+	}
+}
+
+// AddTeamMember adds an operator, as named by the X-Operator header of
+// their requests, to a team. Admin only.
+// POST /api/v1/teams/{name}/members
+func (h *TeamHandler) AddTeamMember(w http.ResponseWriter, r *http.Request) {
+	team, ok := h.team(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Member string `json:"member"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	if err := config.ValidateTeamMember(req.Member); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	member := config.TeamMember{
+		Team:    team.Name,
+		Member:  req.Member,
+		AddedBy: auth.FromContext(r.Context()).Name,
+	}
+	if err := h.teams.AddTeamMember(&member); err != nil {
+		h.logger.Error("failed to add team member", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("team member added",
+		zap.String("team", team.Name),
+		zap.String("member", member.Member),
+		zap.String("operator", member.AddedBy),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(member); err != nil {
+		h.logger.Warn("failed to encode team member", zap.Error(err))
+	}
+}
+
+// RemoveTeamMember removes an operator from a team. Admin only.
+// DELETE /api/v1/teams/{name}/members/{member}
+func (h *TeamHandler) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	name, member := chi.URLParam(r, "name"), chi.URLParam(r, "member")
+	if err := h.teams.RemoveTeamMember(name, member); err != nil {
+		if err.Error() == "team member not found" {
+			http.Error(w, "team member not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to remove team member", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("team member removed",
+		zap.String("team", name),
+		zap.String("member", member),
+		zap.String("operator", auth.FromContext(r.Context()).Name),
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// team loads the team named in the URL, writing an error response if it
+// does not exist.
+func (h *TeamHandler) team(w http.ResponseWriter, r *http.Request) (*config.Team, bool) {
+	team, err := h.teams.GetTeam(chi.URLParam(r, "name"))
+	if err != nil {
+		h.logger.Error("failed to get team", zap.Error(err))
+		writeServerError(w, err)
+		return nil, false
+	}
+	if team == nil {
+		http.Error(w, "team not found", http.StatusNotFound)
+		return nil, false
+	}
+	return team, true
+}
@@ -0,0 +1,333 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// totpStatus is returned by GetTOTP.
+type totpStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// GetTOTP returns whether the caller has enabled two-factor
+// authentication, and how many recovery codes they have left.
+// GET /api/v1/auth/totp
+func (h *LoginHandler) GetTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	totp, err := h.users.GetUserTOTP(user.Name)
+	if err != nil {
+		h.logger.Error("failed to get totp", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	var status totpStatus
+	if totp != nil && totp.Enabled {
+		status = totpStatus{Enabled: true, RecoveryCodesLeft: totp.RecoveryCodesLeft}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		h.logger.Warn("failed to encode totp status", zap.Error(err))
+	}
+}
+
+// EnrollTOTP starts enrolling an authenticator app for the caller,
+// returning its secret and the otpauth:// URI to show as a QR code. The
+// enrollment takes effect once confirmed with a code from the app.
+// POST /api/v1/auth/totp
+func (h *LoginHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabled {
+		http.Error(w, "two-factor authentication is already enabled, disable it first", http.StatusConflict)
+		return
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		h.logger.Error("failed to generate totp secret", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if err := h.users.SetUserTOTP(user.Name, secret); err != nil {
+		h.logger.Error("failed to set totp", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"secret": secret,
+		"uri":    auth.TOTPURI(h.opts.TOTPIssuer, user.Name, secret),
+	}); err != nil {
+		h.logger.Warn("failed to encode totp enrollment", zap.Error(err))
+	}
+}
+
+// ConfirmTOTP enables the authenticator enrolled by EnrollTOTP, given a
+// code from it, and returns the caller's recovery codes. They are shown
+// only this once; each can replace a code once.
+// POST /api/v1/auth/totp/confirm
+func (h *LoginHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	user, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	totp, err := h.users.GetUserTOTP(user.Name)
+	if err != nil {
+		h.logger.Error("failed to get totp", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if totp == nil || totp.Enabled {
+		http.Error(w, "no two-factor enrollment in progress", http.StatusConflict)
+		return
+	}
+	step, ok := auth.CheckTOTP(totp.Secret, req.Code, time.Now(), totp.LastStep)
+	if !ok {
+		http.Error(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := auth.NewRecoveryCodes()
+	if err != nil {
+		h.logger.Error("failed to generate recovery codes", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if err := h.users.EnableUserTOTP(user.Name, step, hashes); err != nil {
+		if err.Error() == "totp enrollment not found" {
+			http.Error(w, "no two-factor enrollment in progress", http.StatusConflict)
+			return
+		}
+		h.logger.Error("failed to enable totp", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("two-factor authentication enabled", zap.String("user", user.Name))
+	h.audit(r, auth.LoginEvent{Type: auth.TOTPEnabled, User: user.Name})
+
+	h.writeRecoveryCodes(w, codes)
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes, given a
+// code from their authenticator, and returns the new ones.
+// POST /api/v1/auth/totp/recovery-codes
+func (h *LoginHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	user, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	if !user.TOTPEnabled {
+		http.Error(w, "two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+	if !h.checkTOTPCode(w, r, user, req.Code) {
+		return
+	}
+
+	codes, hashes, err := auth.NewRecoveryCodes()
+	if err != nil {
+		h.logger.Error("failed to generate recovery codes", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if err := h.users.SetRecoveryCodes(user.Name, hashes); err != nil {
+		h.logger.Error("failed to set recovery codes", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("recovery codes regenerated", zap.String("user", user.Name))
+
+	h.writeRecoveryCodes(w, codes)
+}
+
+// DisableTOTP turns two-factor authentication off for the caller, given
+// their password and a code from their authenticator or a recovery code.
+// POST /api/v1/auth/totp/disable
+func (h *LoginHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	user, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+	if !user.TOTPEnabled {
+		http.Error(w, "two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+	now := time.Now().Truncate(time.Millisecond)
+	ok, err := h.checkPassword(r, user, req.Password)
+	if err != nil {
+		h.logger.Error("failed to check password with the directory", zap.Error(err))
+		http.Error(w, "directory unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if !ok {
+		h.failLogin(r, user.Name, true, now)
+		http.Error(w, "password is incorrect", http.StatusForbidden)
+		return
+	}
+	if !h.checkTOTPCode(w, r, user, req.Code) {
+		return
+	}
+
+	if err := h.users.DisableUserTOTP(user.Name); err != nil {
+		h.logger.Error("failed to disable totp", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("two-factor authentication disabled", zap.String("user", user.Name))
+	h.audit(r, auth.LoginEvent{Type: auth.TOTPDisabled, User: user.Name})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifySecondFactor checks a code from the authenticator of user, or one
+// of their recovery codes, as a step up before destructive operations.
+// Users without two-factor authentication cannot pass it. A wrong code
+// counts as a failed sign in.
+func (h *LoginHandler) VerifySecondFactor(r *http.Request, user, code string) (bool, error) {
+	u, err := h.users.GetUser(user)
+	if err != nil || u == nil || !u.TOTPEnabled {
+		return false, err
+	}
+	now := time.Now().Truncate(time.Millisecond)
+	if u.LockedUntil != nil && now.Before(*u.LockedUntil) {
+		return false, nil
+	}
+	ok, err := h.secondFactor(r, u.Name, code, now)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		h.failLogin(r, u.Name, true, now)
+	}
+	return ok, nil
+}
+
+// secondFactor checks and uses up a code from the authenticator of user,
+// or one of their recovery codes.
+func (h *LoginHandler) secondFactor(r *http.Request, user, code string, now time.Time) (bool, error) {
+	if code == "" {
+		return false, nil
+	}
+	totp, err := h.users.GetUserTOTP(user)
+	if err != nil || totp == nil || !totp.Enabled {
+		return false, err
+	}
+
+	if auth.IsTOTPCode(code) {
+		step, ok := auth.CheckTOTP(totp.Secret, code, now, totp.LastStep)
+		if !ok {
+			return false, nil
+		}
+		if err := h.users.UseTOTPStep(user, step); err != nil {
+			if err.Error() == "totp code already used" {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+
+	if err := h.users.UseRecoveryCode(user, auth.HashRecoveryCode(code), now); err != nil {
+		if err.Error() == "recovery code not found" {
+			return false, nil
+		}
+		return false, err
+	}
+	h.logger.Info("recovery code used", zap.String("user", user), zap.Int("left", totp.RecoveryCodesLeft-1))
+	h.audit(r, auth.LoginEvent{Type: auth.RecoveryCodeUsed, User: user})
+	return true, nil
+}
+
+// checkTOTPCode checks and uses up a code from the caller's authenticator
+// or a recovery code, writing an error response if it is not valid.
+func (h *LoginHandler) checkTOTPCode(w http.ResponseWriter, r *http.Request, user *config.User, code string) bool {
+	now := time.Now().Truncate(time.Millisecond)
+	ok, err := h.secondFactor(r, user.Name, code, now)
+	if err != nil {
+		h.logger.Error("failed to check second factor", zap.Error(err))
+		writeServerError(w, err)
+		return false
+	}
+	if !ok {
+		h.failLogin(r, user.Name, true, now)
+		http.Error(w, "invalid code", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// caller loads the user of the request, writing an error response if there
+// is none.
+func (h *LoginHandler) caller(w http.ResponseWriter, r *http.Request) (*config.User, bool) {
+	p := auth.FromContext(r.Context())
+	if p == nil {
+		http.Error(w, "sign in required", http.StatusUnauthorized)
+		return nil, false
+	}
+	user, err := h.users.GetUser(p.Name)
+	if err != nil {
+		h.logger.Error("failed to get user", zap.Error(err))
+		writeServerError(w, err)
+		return nil, false
+	}
+	if user == nil {
+		http.Error(w, "sign in required", http.StatusUnauthorized)
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *LoginHandler) writeRecoveryCodes(w http.ResponseWriter, codes []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": codes}); err != nil {
+		h.logger.Warn("failed to encode recovery codes", zap.Error(err))
+	}
+}
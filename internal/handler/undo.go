@@ -0,0 +1,305 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// latestChange returns the most recent history entry of a resource, or nil
+// if it has none.
+func latestChange(store config.Store, configType string, id uint) (*config.ConfigHistory, error) {
+	histories, _, err := store.GetHistory(&configType, &id, 1, 0)
+	if err != nil || len(histories) == 0 {
+		return nil, err
+	}
+	return &histories[0], nil
+}
+
+// checkUndo verifies that the caller may undo entry: it must have been
+// made by the caller unless ?force=true is given. It writes an error
+// response and returns false otherwise.
+func checkUndo(w http.ResponseWriter, r *http.Request, entry *config.ConfigHistory) bool {
+	if entry == nil {
+		http.Error(w, "no change to undo", http.StatusNotFound)
+		return false
+	}
+
+	force := false
+	if param := r.URL.Query().Get("force"); param != "" {
+		var err error
+		if force, err = strconv.ParseBool(param); err != nil {
+			http.Error(w, "invalid force parameter", http.StatusBadRequest)
+			return false
+		}
+	}
+
+	if operator := r.Header.Get("X-Operator"); entry.Operator != operator && !force {
+		by := entry.Operator
+		if by == "" {
+			by = "an unknown operator"
+		}
+		http.Error(w, fmt.Sprintf("latest change (history entry %d) was made by %s; use force=true to undo it anyway", entry.ID, by), http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// undoReason is the change reason recorded for an undo: the caller's, or
+// a reference to the undone entry.
+func undoReason(r *http.Request, entry *config.ConfigHistory) string {
+	if reason := changeReason(r, ""); reason != "" {
+		return reason
+	}
+	return fmt.Sprintf("undo of history entry %d", entry.ID)
+}
+
+// UndoRoute reverts the latest change to a route: a create is undone by
+// deleting the route, an update or delete by restoring its previous value.
+// POST /api/v1/routes/{id}/undo?force=true&dry_run=true
+func (h *RouteHandler) UndoRoute(w http.ResponseWriter, r *http.Request) {
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid route id", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.store.GetRouteByID(uint(id))
+	if err != nil {
+		h.logger.Error("failed to get route", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if current == nil {
+		http.Error(w, "route not found", http.StatusNotFound)
+		return
+	}
+
+	entry, err := latestChange(h.store, "route", current.ID)
+	if err != nil {
+		h.logger.Error("failed to get route history", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if !checkUndo(w, r, entry) {
+		return
+	}
+	reason := undoReason(r, entry)
+
+	if entry.Operation == "CREATE" {
+		if !h.admit(w, r, "DELETE", current, nil, reason) {
+			return
+		}
+		old := *current
+		old.Enabled = false
+		err := commit(h.store, w, dryRun, func(tx config.Store) error {
+			if err := tx.DeleteRoute(current.ID); err != nil {
+				return err
+			}
+			return tx.CreateHistory(newHistory("route", &old.ID, "DELETE", &old, nil, reason, r))
+		})
+		if err != nil {
+			h.logger.Error("failed to delete route", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var route config.Route
+	if err := json.Unmarshal(entry.OldValue, &route); err != nil {
+		h.logger.Error("failed to decode route history", zap.Uint64("history_id", entry.ID), zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	route.ID = current.ID
+	if entry.Operation == "DELETE" {
+		route.Enabled = true
+	}
+
+	if route.BackendName != current.BackendName {
+		backend, err := h.store.GetBackendByName(route.BackendName)
+		if err != nil {
+			h.logger.Error("failed to check backend", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		if backend == nil || !backend.Enabled {
+			http.Error(w, "cannot undo: backend "+route.BackendName+" not found or disabled", http.StatusConflict)
+			return
+		}
+	}
+
+	// The rules may have changed since the previous value was recorded
+	if !h.checkUpdate(w, current, &route) {
+		return
+	}
+
+	if !h.admit(w, r, "UPDATE", current, &route, reason) {
+		return
+	}
+	err = commit(h.store, w, dryRun, func(tx config.Store) error {
+		if err := tx.UpdateRoute(route.ID, &route); err != nil {
+			return err
+		}
+		return tx.CreateHistory(newHistory("route", &route.ID, "UPDATE", current, &route, reason, r))
+	})
+	if err != nil {
+		h.logger.Error("failed to update route", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(route); err != nil {
+		h.logger.Warn("failed to encode route", zap.Error(err))
+	}
+}
+
+// UndoBackend reverts the latest change to a backend like UndoRoute.
+// Secrets are write-only and never recorded in history, so the stored
+// inline secret and TLS client key are kept; the undo is refused if the
+// previous value needs one that is no longer stored.
+// POST /api/v1/backends/{name}/undo?force=true&dry_run=true
+func (h *BackendHandler) UndoBackend(w http.ResponseWriter, r *http.Request) {
+	dryRun, ok := parseDryRun(w, r)
+	if !ok {
+		return
+	}
+	name := chi.URLParam(r, "name")
+
+	current, err := h.store.GetBackendByName(name)
+	if err != nil {
+		h.logger.Error("failed to get backend", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if current == nil {
+		http.Error(w, "backend not found", http.StatusNotFound)
+		return
+	}
+
+	entry, err := latestChange(h.store, "backend", current.ID)
+	if err != nil {
+		h.logger.Error("failed to get backend history", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if !checkUndo(w, r, entry) {
+		return
+	}
+	reason := undoReason(r, entry)
+
+	if entry.Operation == "CREATE" {
+		if _, ok := h.planCascade(w, r, name, false); !ok {
+			return
+		}
+		if !h.admit(w, r, "DELETE", current, nil, reason) {
+			return
+		}
+		old := *current
+		old.Enabled = false
+		err := commit(h.store, w, dryRun, func(tx config.Store) error {
+			if err := tx.DeleteBackend(name); err != nil {
+				return err
+			}
+			return tx.CreateHistory(newHistory("backend", &old.ID, "DELETE", &old, nil, reason, r))
+		})
+		if err != nil {
+			h.logger.Error("failed to delete backend", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	backend, err := restoreBackend(entry.OldValue, current)
+	if err != nil {
+		http.Error(w, "cannot undo: "+err.Error(), http.StatusConflict)
+		return
+	}
+	if entry.Operation == "DELETE" {
+		backend.Enabled = true
+	}
+	// The rules may have changed since the previous value was recorded
+	if !validBackend(w, backend) {
+		return
+	}
+	if !backend.Enabled {
+		if _, ok := h.planCascade(w, r, name, false); !ok {
+			return
+		}
+	}
+
+	if !h.admit(w, r, "UPDATE", current, backend, reason) {
+		return
+	}
+	err = commit(h.store, w, dryRun, func(tx config.Store) error {
+		if err := tx.UpdateBackend(name, backend); err != nil {
+			return err
+		}
+		return tx.CreateHistory(newHistory("backend", &backend.ID, "UPDATE", current, backend, reason, r))
+	})
+	if err != nil {
+		h.logger.Error("failed to update backend", zap.Error(err))
+		if errors.Is(err, config.ErrEncryptionDisabled) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(backend); err != nil {
+		h.logger.Warn("failed to encode backend", zap.Error(err))
+	}
+}
+
+// restoreBackend decodes a backend recorded in history, filling in the
+// redacted secrets from the current backend.
+func restoreBackend(data json.RawMessage, current *config.Backend) (*config.Backend, error) {
+	var backend config.Backend
+	if err := json.Unmarshal(data, &backend); err != nil {
+		return nil, fmt.Errorf("invalid history value: %w", err)
+	}
+	var redacted struct {
+		Credential *struct {
+			HasSecret bool `json:"has_secret"`
+		} `json:"credential"`
+		TLS *struct {
+			HasClientKey bool `json:"has_client_key"`
+		} `json:"tls"`
+	}
+	json.Unmarshal(data, &redacted)
+
+	if redacted.Credential != nil && redacted.Credential.HasSecret {
+		if current.Credential == nil || current.Credential.Secret == "" {
+			return nil, errors.New("the previous inline credential secret is no longer stored")
+		}
+		backend.Credential.Secret = current.Credential.Secret
+	}
+	if redacted.TLS != nil && redacted.TLS.HasClientKey {
+		if current.TLS == nil || current.TLS.ClientKey == "" || current.TLS.ClientCert != backend.TLS.ClientCert {
+			return nil, errors.New("the previous TLS client key is no longer stored")
+		}
+		backend.TLS.ClientKey = current.TLS.ClientKey
+	}
+
+	backend.ID = current.ID
+	backend.Name = current.Name
+	return &backend, nil
+}
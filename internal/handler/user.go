@@ -0,0 +1,352 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// UserHandler manages the local users that sign in with a password.
+type UserHandler struct {
+	users  config.UserStore
+	audit  LoginAuditor
+	logger *zap.Logger
+}
+
+// NewUserHandler creates a new UserHandler. Unlocking users and resetting
+// their two-factor authentication are recorded with audit, if not nil.
+func NewUserHandler(users config.UserStore, audit LoginAuditor, logger *zap.Logger) *UserHandler {
+	return &UserHandler{
+		users:  users,
+		audit:  audit,
+		logger: logger,
+	}
+}
+
+// ListUsers returns every user. Admin only.
+// GET /api/v1/users
+func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.users.GetUsers()
+	if err != nil {
+		h.logger.Error("failed to get users", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if users == nil {
+		users = []config.User{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(users); err != nil {
+		h.logger.Warn("failed to encode users", zap.Error(err))
+	}
+}
+
+// GetUser returns a user. Admin only.
+// GET /api/v1/users/{name}
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		h.logger.Warn("failed to encode user", zap.Error(err))
+	}
+}
+
+// CreateUser creates a user with an initial password, which the user must
+// change when first signing in unless must_change_password is false.
+// Admin only.
+// POST /api/v1/users
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name               string `json:"name"`
+		DisplayName        string `json:"display_name"`
+		Role               string `json:"role"`
+		Password           string `json:"password"`
+		MustChangePassword *bool  `json:"must_change_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := config.ValidateUserName(req.Name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !auth.ValidRole(req.Role) {
+		http.Error(w, "invalid role (must be viewer, editor or admin)", http.StatusBadRequest)
+		return
+	}
+	if err := auth.ValidatePassword(req.Password); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existing, err := h.users.GetUser(req.Name)
+	if err != nil {
+		h.logger.Error("failed to get user", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if existing != nil {
+		http.Error(w, "user already exists", http.StatusConflict)
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		h.logger.Error("failed to hash password", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	user := config.User{
+		Name:               req.Name,
+		DisplayName:        req.DisplayName,
+		Role:               req.Role,
+		PasswordHash:       hash,
+		MustChangePassword: req.MustChangePassword == nil || *req.MustChangePassword,
+		PasswordChangedAt:  time.Now().Truncate(time.Millisecond),
+		CreatedBy:          auth.FromContext(r.Context()).Name,
+	}
+	if err := h.users.CreateUser(&user); err != nil {
+		h.logger.Error("failed to create user", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("user created",
+		zap.String("user", user.Name),
+		zap.String("role", user.Role),
+		zap.String("operator", user.CreatedBy),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		h.logger.Warn("failed to encode user", zap.Error(err))
+	}
+}
+
+// UpdateUser changes the display name and role of a user, disables or
+// enables it, and sets or clears its forced password change. Disabling a
+// user ends its sessions. Admins cannot disable or demote themselves.
+// Admin only.
+// PUT /api/v1/users/{name}
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		DisplayName        string `json:"display_name"`
+		Role               string `json:"role"`
+		Disabled           bool   `json:"disabled"`
+		MustChangePassword bool   `json:"must_change_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if !auth.ValidRole(req.Role) {
+		http.Error(w, "invalid role (must be viewer, editor or admin)", http.StatusBadRequest)
+		return
+	}
+	operator := auth.FromContext(r.Context()).Name
+	if existing.Name == operator && (req.Disabled || req.Role != auth.RoleAdmin) {
+		http.Error(w, "cannot disable or demote your own user", http.StatusConflict)
+		return
+	}
+
+	user := *existing
+	user.DisplayName = req.DisplayName
+	user.Role = req.Role
+	user.Disabled = req.Disabled
+	user.MustChangePassword = req.MustChangePassword
+	if err := h.users.UpdateUser(user.Name, &user); err != nil {
+		h.logger.Error("failed to update user", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if user.Disabled {
+		if err := h.users.RevokeUserSessions(user.Name, time.Now()); err != nil {
+			h.logger.Error("failed to revoke sessions", zap.Error(err))
+			writeServerError(w, err)
+			return
+		}
+	}
+
+	h.logger.Info("user updated",
+		zap.String("user", user.Name),
+		zap.String("role", user.Role),
+		zap.Bool("disabled", user.Disabled),
+		zap.String("operator", operator),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		h.logger.Warn("failed to encode user", zap.Error(err))
+	}
+}
+
+// SetPassword resets the password of a user, which must then change it
+// when next signing in unless must_change_password is false. The user's
+// sessions end. Admin only.
+// PUT /api/v1/users/{name}/password
+func (h *UserHandler) SetPassword(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Password           string `json:"password"`
+		MustChangePassword *bool  `json:"must_change_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	if err := auth.ValidatePassword(req.Password); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		h.logger.Error("failed to hash password", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	mustChange := req.MustChangePassword == nil || *req.MustChangePassword
+	now := time.Now().Truncate(time.Millisecond)
+	if err := h.users.SetUserPassword(existing.Name, hash, mustChange, now); err != nil {
+		h.logger.Error("failed to set password", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+	if err := h.users.RevokeUserSessions(existing.Name, now); err != nil {
+		h.logger.Error("failed to revoke sessions", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("user password reset",
+		zap.String("user", existing.Name),
+		zap.String("operator", auth.FromContext(r.Context()).Name),
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlockUser lifts the lockout of a user after repeated failed sign ins,
+// and clears its failures. Admin only.
+// POST /api/v1/users/{name}/unlock
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if err := h.users.UnlockUser(name); err != nil {
+		if err.Error() == "user not found" {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to unlock user", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	operator := auth.FromContext(r.Context()).Name
+	h.logger.Info("user unlocked",
+		zap.String("user", name),
+		zap.String("operator", operator),
+	)
+	if h.audit != nil {
+		h.audit.AuditLogin(r, auth.LoginEvent{Type: auth.AccountUnlocked, User: name, Operator: operator})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetTOTP turns two-factor authentication off for a user who lost their
+// authenticator and recovery codes, so that they can sign in with their
+// password and enroll again. Admin only.
+// DELETE /api/v1/users/{name}/totp
+func (h *UserHandler) ResetTOTP(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if err := h.users.DisableUserTOTP(name); err != nil {
+		if err.Error() == "user not found" {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to disable totp", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	operator := auth.FromContext(r.Context()).Name
+	h.logger.Info("two-factor authentication reset",
+		zap.String("user", name),
+		zap.String("operator", operator),
+	)
+	if h.audit != nil {
+		h.audit.AuditLogin(r, auth.LoginEvent{Type: auth.TOTPDisabled, User: name, Operator: operator})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteUser removes a user. Admins cannot delete themselves. Admin only.
+// DELETE /api/v1/users/{name}
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	operator := auth.FromContext(r.Context()).Name
+	if name == operator {
+		http.Error(w, "cannot delete your own user", http.StatusConflict)
+		return
+	}
+
+	if err := h.users.DeleteUser(name); err != nil {
+		if err.Error() == "user not found" {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to delete user", zap.Error(err))
+		writeServerError(w, err)
+		return
+	}
+
+	h.logger.Info("user deleted",
+		zap.String("user", name),
+		zap.String("operator", operator),
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// user loads the user named in the URL, writing an error response if it
+// does not exist.
+func (h *UserHandler) user(w http.ResponseWriter, r *http.Request) (*config.User, bool) {
+	user, err := h.users.GetUser(chi.URLParam(r, "name"))
+	if err != nil {
+		h.logger.Error("failed to get user", zap.Error(err))
+		writeServerError(w, err)
+		return nil, false
+	}
+	if user == nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// BackendHealth tells whether a backend is currently down. It is
+// implemented by healthcheck.Checker.
+type BackendHealth interface {
+	Down(backend string) bool
+}
+
+// Warnings configures validation warnings: findings about a route that
+// are returned as Warning headers but do not block the change, unless
+// Strict is set, in which case the change is rejected with 400. Health
+// may be nil if backends are not health checked.
+type Warnings struct {
+	Health BackendHealth
+	Strict bool
+}
+
+// route returns the warnings about route.
+func (v Warnings) route(route *config.Route) []string {
+	warnings := route.Warnings(time.Now())
+	if v.Health != nil && route.Enabled && v.Health.Down(route.BackendName) {
+		warnings = append(warnings, fmt.Sprintf("backend %s is currently unhealthy", route.BackendName))
+	}
+	return warnings
+}
+
+// check adds warnings to the response as Warning headers. In strict mode
+// it instead rejects the request with 400 and returns false.
+func (v Warnings) check(w http.ResponseWriter, warnings []string) bool {
+	if len(warnings) == 0 {
+		return true
+	}
+	if v.Strict {
+		http.Error(w, "strict validation: "+strings.Join(warnings, "; "), http.StatusBadRequest)
+		return false
+	}
+	writeWarnings(w, warnings)
+	return true
+}
+
+// writeWarnings adds a Warning header for each of warnings.
+func writeWarnings(w http.ResponseWriter, warnings []string) {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	for _, warning := range warnings {
+		w.Header().Add("Warning", `299 - "`+escape.Replace(warning)+`"`)
+	}
+}
@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseWindow parses a report window: a Go duration such as "24h", or a
+// whole number of days such as "7d".
+func parseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
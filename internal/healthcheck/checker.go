@@ -0,0 +1,262 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+)
+
+// Backend health states, also used as the operation of health events.
+const (
+	StatusUp   = "UP"
+	StatusDown = "DOWN"
+)
+
+// Options configures a Checker.
+type Options struct {
+	// Interval is the time between probe rounds.
+	Interval time.Duration
+	// Timeout bounds a single probe.
+	Timeout time.Duration
+	// FailureThreshold is the number of consecutive failed probes after
+	// which a backend is marked down.
+	FailureThreshold int
+	// SuccessThreshold is the number of consecutive successful probes
+	// after which a down backend is marked up again.
+	SuccessThreshold int
+	// Retention is how long latency samples are kept when the store
+	// implements config.LatencyStore; zero keeps them forever.
+	Retention time.Duration
+}
+
+// Result is the health of one backend.
+type Result struct {
+	Backend   string        `json:"backend"`
+	Addr      string        `json:"addr"`
+	Status    string        `json:"status"`
+	Since     time.Time     `json:"since"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Latency   time.Duration `json:"latency_ns"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// state tracks consecutive probe outcomes for one backend.
+type state struct {
+	result    Result
+	failures  int
+	successes int
+}
+
+// Checker periodically probes every enabled backend with a TCP connect and
+// publishes an events.TypeHealth event whenever a backend goes down or
+// recovers. Backends start out assumed up. If the store implements
+// config.LatencyStore, every probe is recorded there for reporting.
+type Checker struct {
+	store  config.Store
+	broker *events.Broker
+	opts   Options
+	logger *zap.Logger
+	probe  func(ctx context.Context, addr string) error
+
+	mu       sync.Mutex
+	states   map[string]*state
+	prunedAt time.Time
+}
+
+// NewChecker creates a new Checker.
+func NewChecker(store config.Store, broker *events.Broker, opts Options, logger *zap.Logger) *Checker {
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 3
+	}
+	if opts.SuccessThreshold <= 0 {
+		opts.SuccessThreshold = 2
+	}
+	return &Checker{
+		store:  store,
+		broker: broker,
+		opts:   opts,
+		logger: logger,
+		probe:  dial,
+		states: map[string]*state{},
+	}
+}
+
+// Run probes backends until ctx is cancelled.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.checkAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns the latest health of every probed backend, by name.
+func (c *Checker) Status() []Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	results := make([]Result, 0, len(c.states))
+	for _, s := range c.states {
+		results = append(results, s.result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Backend < results[j].Backend })
+	return results
+}
+
+// Down reports whether backend is currently marked down.
+func (c *Checker) Down(backend string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.states[backend]
+	return ok && s.result.Status == StatusDown
+}
+
+// checkAll probes every enabled backend concurrently, records the samples
+// and forgets backends that were removed or disabled.
+func (c *Checker) checkAll(ctx context.Context) {
+	enabled := true
+	backends, err := c.store.GetBackends(&enabled)
+	if err != nil {
+		c.logger.Warn("health check: failed to list backends", zap.Error(err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	samples := make([]config.LatencySample, len(backends))
+	current := make(map[string]bool, len(backends))
+	for i, b := range backends {
+		current[b.Name] = true
+		wg.Add(1)
+		go func(i int, name, addr string) {
+			defer wg.Done()
+			samples[i] = c.check(ctx, name, addr)
+		}(i, b.Name, b.Addr)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	c.mu.Lock()
+	for name := range c.states {
+		if !current[name] {
+			delete(c.states, name)
+		}
+	}
+	c.mu.Unlock()
+
+	c.record(samples)
+}
+
+// record stores latency samples and prunes old ones about once an hour.
+func (c *Checker) record(samples []config.LatencySample) {
+	latency, ok := c.store.(config.LatencyStore)
+	if !ok {
+		return
+	}
+	if err := latency.RecordLatency(samples); err != nil {
+		c.logger.Warn("health check: failed to record latency", zap.Error(err))
+	}
+
+	if c.opts.Retention <= 0 || time.Since(c.prunedAt) < time.Hour {
+		return
+	}
+	c.prunedAt = time.Now()
+	if n, err := latency.PruneLatency(time.Now().Add(-c.opts.Retention)); err != nil {
+		c.logger.Warn("health check: failed to prune latency samples", zap.Error(err))
+	} else if n > 0 {
+		c.logger.Debug("pruned latency samples", zap.Int64("count", n))
+	}
+}
+
+// check probes one backend and records the outcome, publishing an event if
+// its status changes.
+func (c *Checker) check(ctx context.Context, name, addr string) config.LatencySample {
+	probeCtx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	start := time.Now()
+	err := c.probe(probeCtx, addr)
+	latency := time.Since(start)
+	cancel()
+
+	sample := config.LatencySample{Backend: name, CheckedAt: start, Latency: latency, Success: err == nil}
+	if ctx.Err() != nil {
+		return sample
+	}
+
+	c.mu.Lock()
+	s, ok := c.states[name]
+	if !ok {
+		s = &state{result: Result{Backend: name, Status: StatusUp, Since: start}}
+		c.states[name] = s
+	}
+	s.result.Addr = addr
+	s.result.CheckedAt = start
+	s.result.Latency = latency
+	s.result.Error = ""
+
+	changed := false
+	if err != nil {
+		s.result.Error = err.Error()
+		s.failures++
+		s.successes = 0
+		if s.result.Status == StatusUp && s.failures >= c.opts.FailureThreshold {
+			s.result.Status, s.result.Since, changed = StatusDown, start, true
+		}
+	} else {
+		s.successes++
+		s.failures = 0
+		if s.result.Status == StatusDown && s.successes >= c.opts.SuccessThreshold {
+			s.result.Status, s.result.Since, changed = StatusUp, start, true
+		}
+	}
+	result := s.result
+	c.mu.Unlock()
+
+	if !changed {
+		return sample
+	}
+
+	c.logger.Info("backend health changed",
+		zap.String("backend", name), zap.String("status", result.Status), zap.String("error", result.Error))
+	data, _ := json.Marshal(result)
+	c.broker.Publish(events.Event{
+		Type:      events.TypeHealth,
+		Operation: result.Status,
+		Backend:   name,
+		Data:      data,
+		Time:      start,
+	})
+	return sample
+}
+
+// dial is the default probe: the backend is healthy if it accepts a TCP
+// connection.
+func dial(ctx context.Context, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
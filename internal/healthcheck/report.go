@@ -0,0 +1,110 @@
+package healthcheck
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// Percentiles summarises the latency of successful probes, in
+// milliseconds.
+type Percentiles struct {
+	P50 float64 `json:"p50_ms"`
+	P90 float64 `json:"p90_ms"`
+	P95 float64 `json:"p95_ms"`
+	P99 float64 `json:"p99_ms"`
+	Max float64 `json:"max_ms"`
+}
+
+// Bucket summarises the probes in one step of a time series.
+type Bucket struct {
+	Start    time.Time    `json:"start"`
+	Samples  int          `json:"samples"`
+	Failures int          `json:"failures"`
+	Latency  *Percentiles `json:"latency,omitempty"`
+}
+
+// Report is the latency history of a backend over a window.
+type Report struct {
+	Backend  string       `json:"backend"`
+	From     time.Time    `json:"from"`
+	To       time.Time    `json:"to"`
+	Step     string       `json:"step"`
+	Samples  int          `json:"samples"`
+	Failures int          `json:"failures"`
+	Latency  *Percentiles `json:"latency,omitempty"`
+	Series   []Bucket     `json:"series"`
+}
+
+// NewReport summarises samples taken in [from, to) overall and as a time
+// series of buckets of length step. Failed probes count towards failures
+// but not towards latency; buckets without successful probes have no
+// latency.
+func NewReport(backend string, samples []config.LatencySample, from, to time.Time, step time.Duration) *Report {
+	report := &Report{
+		Backend: backend,
+		From:    from,
+		To:      to,
+		Step:    step.String(),
+		Series:  []Bucket{},
+	}
+
+	n := int((to.Sub(from) + step - 1) / step)
+	latencies := make([][]time.Duration, n)
+	for i := 0; i < n; i++ {
+		report.Series = append(report.Series, Bucket{Start: from.Add(time.Duration(i) * step)})
+	}
+
+	var all []time.Duration
+	for _, s := range samples {
+		if s.CheckedAt.Before(from) || !s.CheckedAt.Before(to) {
+			continue
+		}
+		i := int(s.CheckedAt.Sub(from) / step)
+		b := &report.Series[i]
+		b.Samples++
+		report.Samples++
+		if !s.Success {
+			b.Failures++
+			report.Failures++
+			continue
+		}
+		latencies[i] = append(latencies[i], s.Latency)
+		all = append(all, s.Latency)
+	}
+
+	report.Latency = percentiles(all)
+	for i := range report.Series {
+		report.Series[i].Latency = percentiles(latencies[i])
+	}
+	return report
+}
+
+// percentiles computes nearest-rank percentiles, or nil for no samples.
+func percentiles(latencies []time.Duration) *Percentiles {
+	if len(latencies) == 0 {
+		return nil
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(latencies)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return milliseconds(latencies[i])
+	}
+	return &Percentiles{
+		P50: rank(0.50),
+		P90: rank(0.90),
+		P95: rank(0.95),
+		P99: rank(0.99),
+		Max: milliseconds(latencies[len(latencies)-1]),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
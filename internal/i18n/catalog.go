@@ -0,0 +1,264 @@
+package i18n
+
+// catalog lists the messages of the API with their codes and translations.
+// Patterns are tried in order, so the more specific come first. Codes are
+// part of the API: once published they must not change.
+var catalog = []entry{
+	// General
+	{"internal_server_error", "internal server error", "服务器内部错误"},
+	{"too_many_requests", "too many concurrent requests", "并发请求过多，请稍后重试"},
+	{"database_unavailable", "database unavailable", "数据库暂时不可用，请稍后重试"},
+	{"invalid_json", "invalid json", "请求体不是有效的 JSON"},
+	{"invalid_yaml", "invalid yaml", "请求体不是有效的 YAML"},
+	{"invalid_json", "invalid json (timestamp must be RFC 3339)", "请求体不是有效的 JSON（timestamp 须为 RFC 3339 格式）"},
+	{"invalid_body", "failed to read body", "读取请求体失败"},
+	{"invalid_body", "failed to read request body", "读取请求体失败"},
+	{"body_too_large", "request body too large (at most {0} bytes)", "请求体过大（最多 {0} 字节）"},
+	{"invalid_id", "invalid id", "ID 无效"},
+	{"invalid_parameter", "invalid {0} parameter", "参数 {0} 无效"},
+	{"invalid_parameter", "invalid {0} parameter (must be {1})", "参数 {0} 无效（须为 {1}）"},
+	{"invalid_parameter", "invalid limit parameter (must be between 1 and {0})", "参数 limit 无效（须在 1 到 {0} 之间）"},
+	{"invalid_parameter", "invalid limit: must be between 1 and {0}", "limit 无效：须在 1 到 {0} 之间"},
+	{"invalid_parameter", "invalid force parameter (must be '{0}')", "参数 force 无效（须为 '{0}'）"},
+	{"invalid_window", "invalid window: must be a positive duration", "window 无效：须为正的时长"},
+	{"invalid_window", "invalid window: must be a positive duration up to 31d", "window 无效：须为不超过 31d 的正时长"},
+	{"invalid_step", "invalid step", "step 无效"},
+	{"invalid_step", "step too small for window: at most 1000 buckets", "相对于 window，step 过小：最多 1000 个区间"},
+	{"invalid_timeout", "invalid timeout: must be a positive duration of at most {0}", "timeout 无效：须为不超过 {0} 的正时长"},
+	{"field_required", "required fields cannot be empty", "必填字段不能为空"},
+	{"field_required", "{0} is required", "{0} 为必填项"},
+	{"field_required", "{0} is required (at most {1} characters)", "{0} 为必填项（最多 {1} 个字符）"},
+	{"field_too_long", "{0} too long (at most {1} characters)", "{0} 过长（最多 {1} 个字符）"},
+	{"field_too_long", "{0} too long (at most {1} bytes)", "{0} 过大（最多 {1} 字节）"},
+	{"not_supported", "{0} are not supported by this store", "当前存储不支持{0}"},
+	{"not_supported", "{0} is not supported by this store", "当前存储不支持{0}"},
+	{"not_supported", "background jobs are not supported by the configured store", "当前存储不支持后台任务"},
+	{"read_only", "service is in read-only mode", "服务处于只读模式"},
+	{"read_only", "service is in read-only mode: {0}", "服务处于只读模式：{0}"},
+
+	// Authentication
+	{"unauthorized", "unauthorized", "未认证"},
+	{"authentication_required", "authentication required", "需要认证"},
+	{"sign_in_required", "sign in required", "请先登录"},
+	{"invalid_token", "invalid or expired token", "令牌无效或已过期"},
+	{"invalid_token", "invalid or expired refresh token", "刷新令牌无效或已过期"},
+	{"invalid_csrf_token", "missing or invalid CSRF token", "CSRF 令牌缺失或无效"},
+	{"admin_required", "admin role required", "需要管理员角色"},
+	{"editor_required", "editor role required", "需要编辑者角色"},
+	{"password_change_required", "password change required", "请先修改密码"},
+	{"totp_required", "this operation requires a two-factor authentication code in the {0} header", "此操作需要在 {0} 头中提供两步验证码"},
+	{"invalid_credentials", "invalid username or password", "用户名或密码错误"},
+	{"locked_out", "too many failed sign ins, try again later", "登录失败次数过多，请稍后再试"},
+	{"second_factor_required", "second factor required", "需要两步验证"},
+	{"invalid_second_factor", "invalid second factor code", "两步验证码无效"},
+	{"invalid_code", "invalid code", "验证码无效"},
+	{"directory_unavailable", "directory unavailable", "目录服务不可用"},
+	{"password_managed_by_directory", "password is managed by the directory", "密码由目录服务管理"},
+	{"incorrect_password", "password is incorrect", "密码错误"},
+	{"incorrect_password", "current password is incorrect", "当前密码错误"},
+	{"password_unchanged", "new password must differ from the current one", "新密码不能与当前密码相同"},
+	{"invalid_password", "password must be at least {0} characters", "密码至少需要 {0} 个字符"},
+	{"invalid_password", "password must be at most {0} bytes", "密码最多 {0} 字节"},
+	{"totp_not_enabled", "two-factor authentication is not enabled", "未启用两步验证"},
+	{"totp_already_enabled", "two-factor authentication is already enabled, disable it first", "已启用两步验证，请先停用"},
+	{"totp_not_enrolling", "no two-factor enrollment in progress", "没有进行中的两步验证注册"},
+	{"session_not_found", "session not found", "会话不存在"},
+
+	// Users and teams
+	{"user_not_found", "user not found", "用户不存在"},
+	{"user_exists", "user already exists", "用户已存在"},
+	{"invalid_role", "invalid role (must be viewer, editor or admin)", "角色无效（须为 viewer、editor 或 admin）"},
+	{"invalid_user_name", "invalid user name {0} (letters, digits, '.', '_', '@' and '-', at most 64 characters)", "用户名 {0} 无效（字母、数字、'.'、'_'、'@' 和 '-'，最多 64 个字符）"},
+	{"own_user", "cannot delete your own user", "不能删除自己的用户"},
+	{"own_user", "cannot disable or demote your own user", "不能停用自己的用户或降低自己的角色"},
+	{"team_not_found", "team not found", "团队不存在"},
+	{"team_exists", "team already exists", "团队已存在"},
+	{"team_member_not_found", "team member not found", "团队成员不存在"},
+	{"team_rename", "team name cannot be changed", "团队名称不能修改"},
+	{"team_in_use", "team still owns {0} backends and {1} routes", "团队仍拥有 {0} 个后端和 {1} 条路由"},
+	{"invalid_team", "invalid team {0} (lowercase letters, digits and dashes, at most 64 characters)", "团队名 {0} 无效（小写字母、数字和短横线，最多 64 个字符）"},
+	{"invalid_member", "invalid member {0} (at most {1} characters, without commas or line breaks)", "成员 {0} 无效（最多 {1} 个字符，不能包含逗号或换行）"},
+	{"field_too_long", "name or description too long (at most 128 and 512 characters)", "名称或描述过长（分别最多 128 和 512 个字符）"},
+
+	// Backends
+	{"backend_not_found", "backend not found", "后端不存在"},
+	{"backend_not_found", "backend not found or disabled", "后端不存在或已停用"},
+	{"backend_not_found", "to_backend not found or disabled", "to_backend 不存在或已停用"},
+	{"backend_not_found", "replacement backend not found or disabled", "替代后端不存在或已停用"},
+	{"backend_exists", "backend already exists", "后端已存在"},
+	{"backend_exists", "backend {0} already exists", "后端 {0} 已存在"},
+	{"backend_disabled", "backend is disabled; enable it first", "后端已停用，请先启用"},
+	{"invalid_status", "invalid status (must be 'enabled', 'disabled' or 'draining')", "状态无效（须为 'enabled'、'disabled' 或 'draining'）"},
+	{"invalid_credential", "invalid credential.type (must be 'none', 'bearer' or 'basic')", "credential.type 无效（须为 'none'、'bearer' 或 'basic'）"},
+	{"invalid_credential", "credential.username is required for basic auth", "basic 认证需要 credential.username"},
+	{"invalid_credential", "credential.secret or credential.secret_ref is required", "需要 credential.secret 或 credential.secret_ref"},
+	{"invalid_credential", "credential.secret and credential.secret_ref are mutually exclusive", "credential.secret 与 credential.secret_ref 不能同时设置"},
+	{"invalid_tls", "tls.client_cert and tls.client_key must be set together", "tls.client_cert 与 tls.client_key 须同时设置"},
+	{"encryption_disabled", "storing secrets requires an encryption key (ADMIN_ENCRYPTION_KEY or ADMIN_ENCRYPTION_KEYS)", "保存密钥需要配置加密密钥（ADMIN_ENCRYPTION_KEY 或 ADMIN_ENCRYPTION_KEYS）"},
+	{"same_backend", "from_backend and to_backend must differ", "from_backend 与 to_backend 不能相同"},
+	{"field_required", "from_backend and to_backend are required", "from_backend 和 to_backend 为必填项"},
+	{"invalid_cascade", "cascade=reassign requires to=<backend>", "cascade=reassign 需要指定 to=<后端>"},
+	{"same_backend", "cannot reassign routes to the backend being removed", "不能将路由迁移到正在删除的后端"},
+	{"services_unavailable", "cannot verify the services of {0}: {1}; set skip_service_check to reassign anyway", "无法校验 {0} 的服务：{1}；设置 skip_service_check 可跳过校验"},
+	{"services_missing", "{0} does not expose {1}", "{0} 未提供 {1}"},
+
+	// Routes
+	{"route_not_found", "route not found", "路由不存在"},
+	{"invalid_route_id", "invalid route id", "路由 ID 无效"},
+	{"too_many_routes", "too many routes (at most {0})", "路由过多（最多 {0} 条）"},
+	{"duplicate_route", "routes[{0}]: {1} duplicates routes[{2}]", "routes[{0}]：{1} 与 routes[{2}] 重复"},
+	{"backend_not_found", "routes[{0}]: backend {1} not found or disabled", "routes[{0}]：后端 {1} 不存在或已停用"},
+	{"routes_exist", "routes already configured; remove them or set skip_existing:\n  {0}", "路由已存在；请先删除或设置 skip_existing：\n  {0}"},
+	{"invalid_request_size", "max_request_bytes must be between {0} and {1}", "max_request_bytes 须在 {0} 到 {1} 之间"},
+	{"invalid_lifecycle", "invalid lifecycle (must be 'active', 'deprecated' or 'retired')", "lifecycle 无效（须为 'active'、'deprecated' 或 'retired'）"},
+	{"invalid_api_version", "invalid api_version {0} (letters, digits, '.', '_' and '-', at most 32)", "api_version {0} 无效（字母、数字、'.'、'_' 和 '-'，最多 32 个字符）"},
+	{"invalid_deprecation", "deprecation.date is required", "deprecation.date 为必填项"},
+	{"invalid_deprecation", "deprecation.sunset must be after deprecation.date", "deprecation.sunset 须晚于 deprecation.date"},
+	{"invalid_url", "invalid {0} {1} (must be an absolute http or https URL)", "{0} {1} 无效（须为 http 或 https 绝对地址）"},
+	{"invalid_docs", "docs.summary must be a single line", "docs.summary 须为单行"},
+	{"invalid_json_field", "{0} is not valid JSON", "{0} 不是有效的 JSON"},
+	{"invalid_transform", "transform.request or transform.response is required", "需要 transform.request 或 transform.response"},
+	{"invalid_transform", "invalid {0}.language (must be '{1}')", "{0}.language 无效（须为 '{1}'）"},
+	{"invalid_transform", "invalid {0}.template: {1}", "{0}.template 无效：{1}"},
+	{"invalid_cache", "cache on a POST route requires cache.allow_post, confirming the RPC has no side effects", "POST 路由开启缓存需要设置 cache.allow_post，确认该 RPC 没有副作用"},
+	{"invalid_cache", "{0} routes cannot be cached", "{0} 路由不能缓存"},
+	{"invalid_cache", "cache.ttl_seconds must be between 1 and {0}", "cache.ttl_seconds 须在 1 到 {0} 之间"},
+	{"invalid_circuit_breaker", "circuit_breaker must set at least one field", "circuit_breaker 至少需要设置一个字段"},
+	{"invalid_circuit_breaker", "circuit_breaker fields cannot be negative", "circuit_breaker 的字段不能为负数"},
+	{"invalid_circuit_breaker", "circuit_breaker.max_ejection_percent must be at most 100", "circuit_breaker.max_ejection_percent 最大为 100"},
+	{"invalid_circuit_breaker", "circuit_breaker.ejection_seconds must be at most {0}", "circuit_breaker.ejection_seconds 最大为 {0}"},
+	{"invalid_percent", "{0} must be between 0 and 100", "{0} 须在 0 到 100 之间"},
+	{"invalid_percent", "{0} must be between 1 and 100", "{0} 须在 1 到 100 之间"},
+	{"invalid_experiment", "experiment variants take {0}% of the traffic, more than 100%", "实验分组共占 {0}% 的流量，超过了 100%"},
+	{"invalid_experiment", "duplicate variant name {0}", "分组名称 {0} 重复"},
+	{"invalid_experiment", "{0} must set percent or match", "{0} 须设置 percent 或 match"},
+	{"invalid_mirror", "mirror.backend_name {0} already serves the route", "mirror.backend_name {0} 已经是路由的后端"},
+	{"invalid_mirror", "mirror.backend_name {0} already serves the fallback", "mirror.backend_name {0} 已经是降级后端"},
+	{"invalid_mirror", "mirror.backend_name {0} already serves variant {1}", "mirror.backend_name {0} 已经是分组 {1} 的后端"},
+	{"invalid_fallback", "fallback must set backend_name, backend_service or backend_method", "fallback 须设置 backend_name、backend_service 或 backend_method"},
+	{"invalid_fallback", "fallback must differ from the route's own target", "fallback 不能与路由自身的目标相同"},
+	{"invalid_target", "{0} must set backend_name, backend_service or backend_method", "{0} 须设置 backend_name、backend_service 或 backend_method"},
+	{"invalid_target", "{0}.name cannot be {1}, which names the route's own target", "{0}.name 不能为 {1}，它是路由自身的目标"},
+	{"invalid_grpc", "grpc.allow and grpc.deny cannot both be set", "grpc.allow 与 grpc.deny 不能同时设置"},
+	{"invalid_grpc", "{0}.key {1} is in grpc.deny", "{0}.key {1} 在 grpc.deny 中"},
+	{"invalid_grpc", "{0}.key {1} is not in grpc.allow", "{0}.key {1} 不在 grpc.allow 中"},
+	{"invalid_middleware", "{0}: unknown middleware {1}", "{0}：未知的中间件 {1}"},
+	{"invalid_middleware", "{0}: middleware {1} is already in the chain", "{0}：中间件 {1} 已在链中"},
+	{"invalid_middleware", "{0}: transform middleware requires transform on the route", "{0}：transform 中间件需要路由设置 transform"},
+	{"invalid_middleware", "{0}: unknown parameter {1}", "{0}：未知参数 {1}"},
+	{"invalid_query_param", "{0} cannot set both required and default", "{0} 不能同时设置 required 和 default"},
+	{"invalid_plugin_name", "invalid plugin name (lowercase letters, digits, '-', '_' and '.', at most 64)", "插件名无效（小写字母、数字、'-'、'_' 和 '.'，最多 64 个字符）"},
+	{"invalid_plugin_name", "invalid plugin name {0} (lowercase letters, digits, '-', '_' and '.', at most 64)", "插件名 {0} 无效（小写字母、数字、'-'、'_' 和 '.'，最多 64 个字符）"},
+	{"invalid_cluster", "invalid cluster {0} (lowercase letters, digits and dashes, at most 64 characters)", "集群名 {0} 无效（小写字母、数字和短横线，最多 64 个字符）"},
+	{"invalid_header", "invalid {0}: {1} is not a header name", "{0} 无效：{1} 不是合法的请求头名称"},
+	{"invalid_header", "invalid {0}: {1} is not a cookie name", "{0} 无效：{1} 不是合法的 Cookie 名称"},
+	{"invalid_header", "invalid {0}: {1} is reserved for gRPC", "{0} 无效：{1} 为 gRPC 保留"},
+	{"invalid_content_type", "invalid {0}: {1} is not a media type", "{0} 无效：{1} 不是合法的媒体类型"},
+	{"invalid_content_type", "invalid {0}: {1} has a wildcard type", "{0} 无效：{1} 包含通配类型"},
+	{"invalid_content_type", "invalid {0}: {1} has parameters", "{0} 无效：{1} 不能带参数"},
+	{"duplicate_entry", "duplicate {0}", "{0} 重复"},
+	{"too_many_entries", "too many {0} (at most {1})", "{0} 过多（最多 {1} 个）"},
+	{"invalid_value", "must be a boolean", "须为布尔值"},
+	{"invalid_value", "must be a string", "须为字符串"},
+	{"invalid_value", "must be a list of strings", "须为字符串列表"},
+	{"invalid_value", "must be a non-negative integer", "须为非负整数"},
+	{"invalid_value", "must be one of {0}", "须为 {0} 之一"},
+
+	// Descriptors and schemas
+	{"descriptor_not_found", "descriptor set not found", "描述符集不存在"},
+	{"descriptor_not_found", "no descriptor set uploaded for {0}", "{0} 尚未上传描述符集"},
+	{"invalid_descriptor", "body must be a serialized FileDescriptorSet", "请求体须为序列化的 FileDescriptorSet"},
+	{"invalid_descriptor_version", "invalid descriptor version (must be a positive number or 'latest')", "描述符版本无效（须为正整数或 'latest'）"},
+	{"descriptor_too_large", "descriptor set too large (at most {0} bytes)", "描述符集过大（最多 {0} 字节）"},
+	{"descriptors_unavailable", "cannot fetch the descriptors of {0}: {1}; upload a FileDescriptorSet instead", "无法获取 {0} 的描述符：{1}；请改为上传 FileDescriptorSet"},
+	{"descriptors_unavailable", "{0} returned {1}", "{0} 返回 {1}"},
+	{"schema_not_found", "route schema not found", "路由 Schema 不存在"},
+	{"plugin_schema_not_found", "plugin schema not found", "插件 Schema 不存在"},
+	{"invalid_schema_version", "invalid schema version (must be a positive number or 'latest')", "Schema 版本无效（须为正整数或 'latest'）"},
+	{"field_required", "request or response is required", "需要 request 或 response"},
+	{"invalid_schema", "schema: {0:msg}", "Schema：{0}"},
+
+	// Changes, history and recovery
+	{"change_denied", "{0:msg} denied change: {1:list}", "{0}拒绝了变更：{1}"},
+	{"", "change freeze", "变更冻结"},
+	{"", "policy", "策略"},
+	{"", "team ownership", "团队归属"},
+	{"", "plugin schema", "插件 Schema"},
+	{"", "change reason", "变更原因"},
+	{"", "webhook", "外部校验"},
+	{"change_frozen", "change freeze {0} is in effect until {1} ({2})", "变更冻结 {0} 生效中，直到 {1}（{2}）"},
+	{"change_frozen", "change freeze {0} is in effect until {1}", "变更冻结 {0} 生效中，直到 {1}"},
+	{"", "freeze_override requires the admin role", "freeze_override 需要管理员角色"},
+	{"", "admins may override with freeze_override=true", "管理员可设置 freeze_override=true 强制变更"},
+	{"reason_required", "change_reason is required", "change_reason 为必填项"},
+	{"team_not_found", "owner_team: team {0} does not exist", "owner_team：团队 {0} 不存在"},
+	{"quota_exceeded", "change quota exceeded: {0:msg} made {1} of {2} changes allowed per {3}, {4} more requested; retry in {5}", "超出变更配额：{0}已在每 {3} 允许的 {2} 次变更中进行了 {1} 次，本次还需 {4} 次；请在 {5} 后重试"},
+	{"quota_exceeded", "change quota exceeded: {0:msg} made {1} of {2} changes allowed per {3}, {4} more requested; split the change into smaller batches", "超出变更配额：{0}已在每 {3} 允许的 {2} 次变更中进行了 {1} 次，本次还需 {4} 次；请拆分为更小的批次"},
+	{"", "all operators", "所有操作人"},
+	{"", "operator {0}", "操作人 {0} "},
+	{"strict_validation", "strict validation: {0:list}", "严格校验：{0}"},
+	{"", "backend {0} is currently unhealthy", "后端 {0} 当前不健康"},
+	{"", "route is enabled past its sunset date; retire or disable it", "路由已过下线日期仍处于启用状态，请将其退役或停用"},
+	{"", "timeout_ms {0} is over {1}", "timeout_ms {0} 超过了 {1}"},
+	{"no_change", "no change to undo", "没有可撤销的变更"},
+	{"undo_conflict", "latest change (history entry {0}) was made by {1}; use force=true to undo it anyway", "最近一次变更（历史记录 {0}）由 {1} 做出；使用 force=true 可强制撤销"},
+	{"undo_conflict", "cannot undo: backend {0} not found or disabled", "无法撤销：后端 {0} 不存在或已停用"},
+	{"undo_conflict", "cannot undo: {0:msg}", "无法撤销：{0}"},
+	{"restore_conflict", "cannot restore: {0:msg}", "无法恢复：{0}"},
+	{"restore_conflict", "cannot restore snapshot: {0:msg}", "无法恢复快照：{0}"},
+	{"restore_conflict", "cannot restore baseline: {0:msg}", "无法恢复基线：{0}"},
+	{"rebuild_conflict", "cannot rebuild: {0:msg}", "无法重建：{0}"},
+	{"plan_stale", "configuration changed since the plan was computed, plan again", "计划生成后配置已被修改，请重新生成计划"},
+	{"invalid_config_type", "invalid config_type (must be 'backend', 'route', 'descriptor' or 'schema')", "config_type 无效（须为 'backend'、'route'、'descriptor' 或 'schema'）"},
+	{"invalid_config_id", "invalid config_id", "config_id 无效"},
+	{"invalid_revision", "invalid revision", "版本号无效"},
+	{"invalid_revision", "since must be a config revision", "since 须为配置版本号"},
+	{"revision_not_found", "revision not found", "版本不存在"},
+	{"invalid_timestamp", "timestamp must not be in the future", "timestamp 不能晚于当前时间"},
+	{"snapshot_not_found", "snapshot not found", "快照不存在"},
+	{"invalid_snapshot_id", "invalid snapshot id", "快照 ID 无效"},
+	{"backup_not_found", "backup not found", "备份不存在"},
+	{"backup_unavailable", "failed to list backups", "获取备份列表失败"},
+	{"backup_unavailable", "failed to get backup", "获取备份失败"},
+
+	// Freeze windows
+	{"freeze_window_not_found", "freeze window not found", "冻结窗口不存在"},
+	{"invalid_freeze_window", "starts_at and ends_at are required", "starts_at 和 ends_at 为必填项"},
+	{"invalid_freeze_window", "ends_at must be after starts_at", "ends_at 须晚于 starts_at"},
+	{"invalid_freeze_window", "starts_at/ends_at or weekly_start/weekly_end are required", "需要 starts_at/ends_at 或 weekly_start/weekly_end"},
+	{"invalid_freeze_window", "a freeze window is either one-off (starts_at/ends_at) or weekly (weekly_start/weekly_end), not both", "冻结窗口只能是一次性的（starts_at/ends_at）或每周的（weekly_start/weekly_end），不能同时设置"},
+	{"invalid_freeze_window", "weekly_start and weekly_end must differ", "weekly_start 与 weekly_end 不能相同"},
+
+	// Jobs
+	{"job_not_found", "job not found", "任务不存在"},
+	{"invalid_job_id", "invalid job id", "任务 ID 无效"},
+	{"job_not_retryable", "only failed jobs can be retried", "只有失败的任务可以重试"},
+
+	// Gateways and rollouts
+	{"gateway_not_registered", "gateway not registered", "网关未注册"},
+	{"field_too_long", "version or addr too long (at most 64 and 255 characters)", "version 或 addr 过长（分别最多 64 和 255 个字符）"},
+	{"invalid_config_hash", "config_sha256 must be a lowercase hex SHA-256", "config_sha256 须为小写十六进制的 SHA-256"},
+	{"invalid_report", "a failure needs the revision and an error of at most 512 characters", "失败上报需要版本号和不超过 512 个字符的错误信息"},
+	{"invalid_report", "requests and errors must not be negative, nor errors exceed requests", "requests 和 errors 不能为负数，errors 不能超过 requests"},
+	{"invalid_report", "at most {0} entries per push", "每次上报最多 {0} 条"},
+	{"invalid_report", "routes[{0}]: bucket_start is in the future", "routes[{0}]：bucket_start 晚于当前时间"},
+	{"invalid_report", "routes[{0}]: counts cannot be negative", "routes[{0}]：计数不能为负数"},
+	{"invalid_report", "routes[{0}]: errors cannot exceed requests", "routes[{0}]：errors 不能超过 requests"},
+	{"invalid_report", "routes[{0}]: latency_buckets cannot count more than requests", "routes[{0}]：latency_buckets 的计数不能超过 requests"},
+	{"invalid_report", "routes[{0}]: latency_buckets must have {1} entries", "routes[{0}]：latency_buckets 须有 {1} 项"},
+	{"invalid_labels", "invalid label key {0} (lowercase letters, digits, dots, dashes and underscores, at most 63 characters)", "标签键 {0} 无效（小写字母、数字、点、短横线和下划线，最多 63 个字符）"},
+	{"invalid_labels", "label {0}: value too long (at most {1} characters)", "标签 {0}：值过长（最多 {1} 个字符）"},
+	{"signing_disabled", "config signing is not enabled", "未启用配置签名"},
+	{"invalid_signature", "invalid signature", "签名无效"},
+	{"rollout_not_found", "rollout not found", "灰度发布不存在"},
+	{"invalid_rollout_id", "invalid rollout id", "灰度发布 ID 无效"},
+	{"rollout_not_in_progress", "rollout is not in progress", "灰度发布未在进行中"},
+	{"rollout_in_progress", "another rollout is in progress", "已有其他灰度发布在进行中"},
+	{"field_required", "percent or labels is required", "需要 percent 或 labels"},
+	{"registry_required", "selecting gateways by label requires the gateway registry", "按标签选择网关需要启用网关注册"},
+	{"invalid_variables", "invalid variables", "variables 无效"},
+
+	// Nested messages, tried last as they match the most
+	{"", "routes[{0}]: {1:msg}", "routes[{0}]：{1}"},
+	{"", "{0}: {1:msg}", "{0}：{1}"},
+}
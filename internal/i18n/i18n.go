@@ -0,0 +1,183 @@
+// Package i18n translates the error and validation messages of the API
+// into the language of the operator, using a catalog of the messages the
+// service returns. Every message in the catalog also has a machine-readable
+// code, which is the same whatever the language.
+package i18n
+
+import (
+	"cmp"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Supported languages.
+const (
+	English = "en"
+	Chinese = "zh"
+)
+
+// entry is a message of the catalog. The English text is a pattern where
+// {n} stands for the n-th argument, copied as is; {n:msg} for an argument
+// that is itself a message, translated in turn; and {n:list} for a list of
+// messages separated by "; ". The Chinese text refers to the arguments as
+// {n}. An entry without a code takes that of its first argument that has
+// one.
+type entry struct {
+	code string
+	en   string
+	zh   string
+}
+
+// Argument kinds.
+const (
+	argText = ""
+	argMsg  = "msg"
+	argList = "list"
+)
+
+// pattern is a compiled entry with arguments.
+type pattern struct {
+	entry
+	re *regexp.Regexp
+	// args holds the index and kind of each argument, in the order they
+	// appear in the English text.
+	args []arg
+}
+
+type arg struct {
+	index int
+	kind  string
+}
+
+var (
+	placeholder = regexp.MustCompile(`\{(\d+)(?::(msg|list))?\}`)
+	// literals holds the entries without arguments, by English text.
+	literals = map[string]entry{}
+	patterns []pattern
+)
+
+func init() {
+	for _, e := range catalog {
+		matches := placeholder.FindAllStringSubmatchIndex(e.en, -1)
+		if len(matches) == 0 {
+			literals[e.en] = e
+			continue
+		}
+
+		p := pattern{entry: e}
+		var expr strings.Builder
+		expr.WriteString(`(?s)^`)
+		last := 0
+		for _, m := range matches {
+			expr.WriteString(regexp.QuoteMeta(e.en[last:m[0]]))
+			expr.WriteString(`(.+?)`)
+			index, _ := strconv.Atoi(e.en[m[2]:m[3]])
+			kind := argText
+			if m[4] >= 0 {
+				kind = e.en[m[4]:m[5]]
+			}
+			p.args = append(p.args, arg{index: index, kind: kind})
+			last = m[1]
+		}
+		expr.WriteString(regexp.QuoteMeta(e.en[last:]))
+		expr.WriteString(`$`)
+		p.re = regexp.MustCompile(expr.String())
+		patterns = append(patterns, p)
+	}
+}
+
+// Negotiate returns the supported language preferred by an Accept-Language
+// header, or English if it prefers none of them.
+func Negotiate(acceptLanguage string) string {
+	best, bestQ := English, 0.0
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if (primary == English || primary == Chinese) && q > bestQ {
+			best, bestQ = primary, q
+		}
+	}
+	return best
+}
+
+// Translate returns msg in lang along with its code. Messages missing from
+// the catalog are returned as is, with no code.
+func Translate(msg, lang string) (text, code string) {
+	text, code, ok := translate(msg, lang)
+	if !ok || lang == English {
+		return msg, code
+	}
+	return text, code
+}
+
+// Code returns the code of an error response: that of its message, or one
+// derived from the status, such as "not_found", if the message is missing
+// from the catalog.
+func Code(msg string, status int) string {
+	if _, code := Translate(msg, English); code != "" {
+		return code
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+func translate(msg, lang string) (string, string, bool) {
+	if e, ok := literals[msg]; ok {
+		return e.text(lang), e.code, true
+	}
+	for _, p := range patterns {
+		values := p.re.FindStringSubmatch(msg)
+		if values == nil {
+			continue
+		}
+		code := p.code
+		args := make([]string, len(p.args))
+		for i, a := range p.args {
+			value := values[i+1]
+			switch a.kind {
+			case argMsg:
+				text, nested, _ := translate(value, lang)
+				value = text
+				code = cmp.Or(code, nested)
+			case argList:
+				items := strings.Split(value, "; ")
+				for j, item := range items {
+					text, nested, _ := translate(item, lang)
+					items[j] = text
+					code = cmp.Or(code, nested)
+				}
+				value = strings.Join(items, listSeparator(lang))
+			}
+			args[a.index] = value
+		}
+		text := placeholder.ReplaceAllStringFunc(p.text(lang), func(s string) string {
+			i, _ := strconv.Atoi(placeholder.FindStringSubmatch(s)[1])
+			return args[i]
+		})
+		return text, code, true
+	}
+	return msg, "", false
+}
+
+// text returns the pattern of e in lang.
+func (e entry) text(lang string) string {
+	if lang == Chinese && e.zh != "" {
+		return e.zh
+	}
+	return e.en
+}
+
+func listSeparator(lang string) string {
+	if lang == Chinese {
+		return "；"
+	}
+	return "; "
+}
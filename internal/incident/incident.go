@@ -0,0 +1,136 @@
+package incident
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// source identifies this service in incidents.
+const source = "assistant-gateway-admin"
+
+// Incident is a critical condition that should page someone.
+type Incident struct {
+	// Key identifies the condition; triggers with the same key are
+	// deduplicated and a resolve closes the incident.
+	Key     string
+	Summary string
+	Details map[string]interface{}
+}
+
+// Sender delivers incidents to an alerting service.
+type Sender interface {
+	Trigger(ctx context.Context, dedupKey string, inc Incident) error
+	Resolve(ctx context.Context, dedupKey string) error
+}
+
+// Manager opens and resolves incidents on every configured Sender. It
+// remembers which incidents it has opened so that a condition that keeps
+// failing is only sent once and only open incidents are resolved. Keys
+// are prefixed with the environment so that deployments sharing an
+// integration do not resolve each other's incidents.
+type Manager struct {
+	environment string
+	senders     []Sender
+	logger      *zap.Logger
+
+	mu   sync.Mutex
+	open map[string]bool
+}
+
+// NewManager creates a Manager.
+func NewManager(environment string, senders []Sender, logger *zap.Logger) *Manager {
+	return &Manager{
+		environment: environment,
+		senders:     senders,
+		logger:      logger,
+		open:        map[string]bool{},
+	}
+}
+
+// Trigger opens inc unless it is already open.
+func (m *Manager) Trigger(ctx context.Context, inc Incident) {
+	m.mu.Lock()
+	if m.open[inc.Key] {
+		m.mu.Unlock()
+		return
+	}
+	m.open[inc.Key] = true
+	m.mu.Unlock()
+
+	if m.environment != "" {
+		inc.Summary = "[" + m.environment + "] " + inc.Summary
+	}
+	m.logger.Warn("opening incident", zap.String("key", inc.Key), zap.String("summary", inc.Summary))
+	for _, s := range m.senders {
+		if err := s.Trigger(ctx, m.dedupKey(inc.Key), inc); err != nil {
+			m.logger.Error("failed to open incident", zap.String("key", inc.Key), zap.Error(err))
+		}
+	}
+}
+
+// Resolve closes the incident with key if it is open.
+func (m *Manager) Resolve(ctx context.Context, key string) {
+	m.mu.Lock()
+	if !m.open[key] {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.open, key)
+	m.mu.Unlock()
+
+	m.logger.Info("resolving incident", zap.String("key", key))
+	for _, s := range m.senders {
+		if err := s.Resolve(ctx, m.dedupKey(key)); err != nil {
+			m.logger.Error("failed to resolve incident", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+func (m *Manager) dedupKey(key string) string {
+	parts := []string{source}
+	if m.environment != "" {
+		parts = append(parts, m.environment)
+	}
+	return strings.Join(append(parts, key), ":")
+}
+
+// client is shared by the senders.
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// post sends a JSON request and fails on a non-2xx response.
+func post(ctx context.Context, url string, header http.Header, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
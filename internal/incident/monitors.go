@@ -0,0 +1,152 @@
+package incident
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/admission"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/gitops"
+)
+
+// Incident keys.
+const (
+	keyBackendDown  = "backend-down:"
+	keyGitOpsSync   = "gitops-sync-failed"
+	keyDatabaseDown = "database-unreachable"
+)
+
+// WatchBackends opens an incident for every backend the health checker
+// has reported down for longer than downFor, and resolves it when the
+// backend recovers. It runs until ctx is cancelled.
+func (m *Manager) WatchBackends(ctx context.Context, broker *events.Broker, downFor time.Duration) {
+	filter := events.Filter{Types: map[string]bool{events.TypeHealth: true}}
+	sub := broker.Subscribe(filter, 64)
+	defer func() { broker.Unsubscribe(sub) }()
+
+	type downBackend struct {
+		since time.Time
+		data  map[string]interface{}
+	}
+	down := map[string]downBackend{}
+	check := downFor / 4
+	if check < time.Second {
+		check = time.Second
+	}
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				sub = broker.Subscribe(filter, 64)
+				continue
+			}
+			if e.Operation == "DOWN" {
+				var data map[string]interface{}
+				json.Unmarshal(e.Data, &data)
+				down[e.Backend] = downBackend{since: e.Time, data: data}
+				continue
+			}
+			delete(down, e.Backend)
+			m.Resolve(ctx, keyBackendDown+e.Backend)
+		case now := <-ticker.C:
+			for name, b := range down {
+				if now.Sub(b.since) < downFor {
+					continue
+				}
+				m.Trigger(ctx, Incident{
+					Key:     keyBackendDown + name,
+					Summary: fmt.Sprintf("Backend %s has been down for more than %s", name, downFor),
+					Details: b.data,
+				})
+			}
+		}
+	}
+}
+
+// GitOpsSynced is a gitops.Options.OnSync callback that opens an incident
+// when a sync fails and resolves it on the next successful sync. Changes
+// rejected by admission controllers (freezes, policies, quotas) are
+// expected and do not page.
+func (m *Manager) GitOpsSynced(status gitops.Status, err error) {
+	ctx := context.Background()
+	if err == nil {
+		m.Resolve(ctx, keyGitOpsSync)
+		return
+	}
+	if admission.IsDenied(err) {
+		return
+	}
+	m.Trigger(ctx, Incident{
+		Key:     keyGitOpsSync,
+		Summary: "GitOps config sync failed: " + err.Error(),
+		Details: map[string]interface{}{
+			"repo_url":    status.RepoURL,
+			"branch":      status.Branch,
+			"last_commit": status.LastCommit,
+			"error":       err.Error(),
+		},
+	})
+}
+
+// Pinger checks that the database is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// WatchDatabase pings the database every interval and opens an incident
+// once it has been unreachable for longer than downFor, resolving it when
+// a ping succeeds again. It runs until ctx is cancelled.
+func (m *Manager) WatchDatabase(ctx context.Context, db Pinger, interval, downFor time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var failingSince time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, interval)
+			err := db.Ping(pingCtx)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err == nil {
+				failingSince = time.Time{}
+				m.Resolve(ctx, keyDatabaseDown)
+				continue
+			}
+			if failingSince.IsZero() {
+				failingSince = now
+			}
+			if now.Sub(failingSince) >= downFor {
+				m.Trigger(ctx, Incident{
+					Key:     keyDatabaseDown,
+					Summary: "Config database unreachable: " + err.Error(),
+					Details: map[string]interface{}{
+						"failing_since": failingSince.UTC().Format(time.RFC3339),
+						"error":         err.Error(),
+					},
+				})
+			}
+		}
+	}
+}
+
+// toString formats a detail value for services that only accept strings.
+func toString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
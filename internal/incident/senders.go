@@ -0,0 +1,110 @@
+package incident
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty sends incidents through the PagerDuty Events API v2.
+type PagerDuty struct {
+	url        string
+	routingKey string
+}
+
+// NewPagerDuty creates a PagerDuty sender for the integration with the
+// given routing key. url is normally PagerDutyEventsURL.
+func NewPagerDuty(url, routingKey string) *PagerDuty {
+	return &PagerDuty{url: url, routingKey: routingKey}
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// Trigger implements Sender.
+func (p *PagerDuty) Trigger(ctx context.Context, dedupKey string, inc Incident) error {
+	return post(ctx, p.url, nil, pagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "trigger",
+		DedupKey:    dedupKey,
+		Payload: &pagerDutyPayload{
+			Summary:       inc.Summary,
+			Source:        source,
+			Severity:      "critical",
+			CustomDetails: inc.Details,
+		},
+	})
+}
+
+// Resolve implements Sender.
+func (p *PagerDuty) Resolve(ctx context.Context, dedupKey string) error {
+	return post(ctx, p.url, nil, pagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "resolve",
+		DedupKey:    dedupKey,
+	})
+}
+
+// OpsgenieAPIURL is the Opsgenie API base URL; EU accounts use
+// https://api.eu.opsgenie.com.
+const OpsgenieAPIURL = "https://api.opsgenie.com"
+
+// Opsgenie sends incidents as Opsgenie alerts, using the dedup key as the
+// alert alias.
+type Opsgenie struct {
+	baseURL string
+	header  http.Header
+}
+
+// NewOpsgenie creates an Opsgenie sender authenticating with an API
+// integration key.
+func NewOpsgenie(baseURL, apiKey string) *Opsgenie {
+	return &Opsgenie{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		header:  http.Header{"Authorization": {"GenieKey " + apiKey}},
+	}
+}
+
+// Trigger implements Sender.
+func (o *Opsgenie) Trigger(ctx context.Context, dedupKey string, inc Incident) error {
+	details := make(map[string]string, len(inc.Details))
+	for k, v := range inc.Details {
+		details[k] = toString(v)
+	}
+
+	message := inc.Summary
+	// Opsgenie truncates messages over 130 characters
+	if r := []rune(message); len(r) > 130 {
+		message = string(r[:130])
+	}
+
+	return post(ctx, o.baseURL+"/v2/alerts", o.header, map[string]interface{}{
+		"message":     message,
+		"alias":       dedupKey,
+		"description": inc.Summary,
+		"source":      source,
+		"priority":    "P1",
+		"details":     details,
+	})
+}
+
+// Resolve implements Sender.
+func (o *Opsgenie) Resolve(ctx context.Context, dedupKey string) error {
+	u := o.baseURL + "/v2/alerts/" + url.PathEscape(dedupKey) + "/close?identifierType=alias"
+	return post(ctx, u, o.header, map[string]string{"source": source})
+}
@@ -0,0 +1,254 @@
+// Package jobs runs long operations, such as applying a large document or
+// taking a backup, in the background. Jobs are kept in a config.JobStore,
+// so pending work survives restarts and failed jobs can be retried.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// Func runs a job of one kind: it decodes params, reports progress as it
+// goes and returns the job's result, which is stored as JSON.
+type Func func(ctx context.Context, params json.RawMessage, progress func(done, total int)) (interface{}, error)
+
+// Options configures a Runner.
+type Options struct {
+	// PollInterval is how often the store is checked for pending jobs
+	// submitted elsewhere, e.g. by another admin instance.
+	PollInterval time.Duration
+	// StaleAfter is how long a running job may go without an update
+	// before it is considered abandoned, e.g. by a crashed instance, and
+	// run again.
+	StaleAfter time.Duration
+	// Retention is how long finished jobs are kept; zero keeps them
+	// forever.
+	Retention time.Duration
+	// Paused, if set, stops jobs from being started while it returns
+	// true, e.g. in read-only mode.
+	Paused func() bool
+}
+
+// Runner runs jobs one at a time. Every admin instance may run one; each
+// job is claimed by a single runner.
+type Runner struct {
+	store  config.JobStore
+	opts   Options
+	logger *zap.Logger
+	wake   chan struct{}
+
+	mu    sync.Mutex
+	funcs map[string]Func
+}
+
+// NewRunner creates a new Runner.
+func NewRunner(store config.JobStore, opts Options, logger *zap.Logger) *Runner {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.StaleAfter <= 0 {
+		opts.StaleAfter = 2 * time.Minute
+	}
+	return &Runner{
+		store:  store,
+		opts:   opts,
+		logger: logger,
+		wake:   make(chan struct{}, 1),
+		funcs:  map[string]Func{},
+	}
+}
+
+// Register sets the function that runs jobs of kind.
+func (r *Runner) Register(kind string, fn Func) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs[kind] = fn
+}
+
+// Submit queues a job of kind with params, encoded as JSON, on behalf of
+// createdBy.
+func (r *Runner) Submit(kind string, params interface{}, createdBy string) (*config.Job, error) {
+	r.mu.Lock()
+	_, ok := r.funcs[kind]
+	r.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown job kind %q", kind)
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	job := &config.Job{Kind: kind, Params: data, CreatedBy: createdBy}
+	if err := r.store.CreateJob(job); err != nil {
+		return nil, err
+	}
+	r.notify()
+	return job, nil
+}
+
+// Retry queues a failed job again, reporting whether it was failed.
+func (r *Runner) Retry(id uint) (bool, error) {
+	ok, err := r.store.RequeueJob(id, config.JobFailed)
+	if ok {
+		r.notify()
+	}
+	return ok, err
+}
+
+// notify wakes the runner without waiting for the next poll.
+func (r *Runner) notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run runs pending jobs until ctx is done. Jobs abandoned by a runner that
+// stopped are run again, and finished jobs are pruned once past retention.
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	var prunedAt time.Time
+	for {
+		if n, err := r.store.RequeueStaleJobs(time.Now().Add(-r.opts.StaleAfter)); err != nil {
+			r.logger.Warn("failed to requeue stale jobs", zap.Error(err))
+		} else if n > 0 {
+			r.logger.Info("requeued abandoned jobs", zap.Int64("count", n))
+		}
+		if r.opts.Retention > 0 && time.Since(prunedAt) > time.Hour {
+			if n, err := r.store.PruneJobs(time.Now().Add(-r.opts.Retention)); err != nil {
+				r.logger.Warn("failed to prune jobs", zap.Error(err))
+			} else if n > 0 {
+				r.logger.Debug("pruned jobs", zap.Int64("count", n))
+			}
+			prunedAt = time.Now()
+		}
+
+		// Run jobs until none is left, then wait for more
+		for ctx.Err() == nil && (r.opts.Paused == nil || !r.opts.Paused()) {
+			job, err := r.store.ClaimJob()
+			if err != nil {
+				r.logger.Warn("failed to claim job", zap.Error(err))
+				break
+			}
+			if job == nil {
+				break
+			}
+			r.run(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// run runs a claimed job and records its outcome. A job interrupted by
+// shutdown is made pending again, to be run after the restart.
+func (r *Runner) run(ctx context.Context, job *config.Job) {
+	logger := r.logger.With(zap.Uint("job", job.ID), zap.String("kind", job.Kind))
+	logger.Info("running job", zap.Int("attempt", job.Attempts))
+
+	r.mu.Lock()
+	fn, ok := r.funcs[job.Kind]
+	r.mu.Unlock()
+	if !ok {
+		job.Status = config.JobFailed
+		job.Error = fmt.Sprintf("unknown job kind %q", job.Kind)
+		r.finish(logger, job)
+		return
+	}
+
+	// Progress doubles as a heartbeat, so that long steps reporting no
+	// progress do not make the job look abandoned
+	var mu sync.Mutex
+	update := func() {
+		mu.Lock()
+		progress := job.Progress
+		mu.Unlock()
+		if err := r.store.UpdateJobProgress(job.ID, progress); err != nil {
+			logger.Warn("failed to update job progress", zap.Error(err))
+		}
+	}
+	progress := func(done, total int) {
+		mu.Lock()
+		job.Progress = config.JobProgress{Done: done, Total: total}
+		mu.Unlock()
+		update()
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(r.opts.StaleAfter / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				update()
+			}
+		}
+	}()
+
+	result, err := call(ctx, fn, job.Params, progress)
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err != nil && ctx.Err() != nil {
+		if _, rerr := r.store.RequeueJob(job.ID, config.JobRunning); rerr != nil {
+			logger.Warn("failed to requeue interrupted job", zap.Error(rerr))
+		}
+		logger.Info("job interrupted by shutdown", zap.Error(err))
+		return
+	}
+
+	if err != nil {
+		job.Status = config.JobFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = config.JobSucceeded
+		if result != nil {
+			if job.Result, err = json.Marshal(result); err != nil {
+				job.Status = config.JobFailed
+				job.Error = "encode result: " + err.Error()
+			}
+		}
+	}
+	r.finish(logger, job)
+}
+
+// call runs fn, turning a panic into an error.
+func call(ctx context.Context, fn Func, params json.RawMessage, progress func(done, total int)) (result interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+	return fn(ctx, params, progress)
+}
+
+// finish records the outcome of a job.
+func (r *Runner) finish(logger *zap.Logger, job *config.Job) {
+	if err := r.store.FinishJob(job); err != nil {
+		logger.Error("failed to record job outcome", zap.String("status", job.Status), zap.Error(err))
+		return
+	}
+	if job.Status == config.JobFailed {
+		logger.Warn("job failed", zap.String("error", job.Error))
+		return
+	}
+	logger.Info("job succeeded")
+}
@@ -0,0 +1,174 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Universal BER tags used by LDAP (RFC 4511, section 5.1).
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+)
+
+// maxPacketSize bounds the messages read from the server.
+const maxPacketSize = 1 << 20
+
+// packet is a decoded BER element. The children of constructed elements
+// are decoded too.
+type packet struct {
+	tag      byte
+	value    []byte
+	children []*packet
+}
+
+// int decodes the value of an INTEGER or ENUMERATED element.
+func (p *packet) int() int {
+	n := 0
+	for i, b := range p.value {
+		if i == 0 && b&0x80 != 0 {
+			n = -1
+		}
+		n = n<<8 | int(b)
+	}
+	return n
+}
+
+func (p *packet) child(i int) *packet {
+	if i < len(p.children) {
+		return p.children[i]
+	}
+	return &packet{}
+}
+
+// encode encodes an element from its tag and content.
+func encode(tag byte, content ...[]byte) []byte {
+	n := 0
+	for _, c := range content {
+		n += len(c)
+	}
+	b := append([]byte{tag}, encodeLength(n)...)
+	for _, c := range content {
+		b = append(b, c...)
+	}
+	return b
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func encodeString(tag byte, s string) []byte {
+	return encode(tag, []byte(s))
+}
+
+// encodeInt encodes a non-negative INTEGER or ENUMERATED.
+func encodeInt(tag byte, n int) []byte {
+	b := []byte{byte(n)}
+	for n >>= 8; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return encode(tag, b)
+}
+
+func encodeBool(v bool) []byte {
+	if v {
+		return encode(tagBoolean, []byte{0xff})
+	}
+	return encode(tagBoolean, []byte{0})
+}
+
+// readPacket reads and decodes one element.
+func readPacket(r *bufio.Reader) (*packet, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	n := int(first)
+	if first&0x80 != 0 {
+		size := int(first & 0x7f)
+		if size == 0 || size > 4 {
+			return nil, errors.New("ldap: unsupported BER length")
+		}
+		n = 0
+		for range size {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			n = n<<8 | int(b)
+		}
+	}
+	if n > maxPacketSize {
+		return nil, fmt.Errorf("ldap: message of %d bytes is too large", n)
+	}
+	value := make([]byte, n)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	return decodeContent(tag, value)
+}
+
+// decodeContent decodes an element given its tag and content.
+func decodeContent(tag byte, value []byte) (*packet, error) {
+	p := &packet{tag: tag, value: value}
+	if tag&0x20 == 0 {
+		return p, nil
+	}
+	for rest := value; len(rest) > 0; {
+		child, n, err := decode(rest)
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+		rest = rest[n:]
+	}
+	return p, nil
+}
+
+// decode decodes the element at the start of b, returning its size.
+func decode(b []byte) (*packet, int, error) {
+	if len(b) < 2 {
+		return nil, 0, errors.New("ldap: truncated BER element")
+	}
+	n, header := int(b[1]), 2
+	if b[1]&0x80 != 0 {
+		size := int(b[1] & 0x7f)
+		if size == 0 || size > 4 || len(b) < 2+size {
+			return nil, 0, errors.New("ldap: invalid BER length")
+		}
+		n = 0
+		for _, c := range b[2 : 2+size] {
+			n = n<<8 | int(c)
+		}
+		header += size
+	}
+	if n < 0 || len(b) < header+n {
+		return nil, 0, errors.New("ldap: truncated BER element")
+	}
+	p, err := decodeContent(b[0], b[header:header+n])
+	if err != nil {
+		return nil, 0, err
+	}
+	return p, header + n, nil
+}
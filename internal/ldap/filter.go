@@ -0,0 +1,153 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Search filter choices (RFC 4511, section 4.5.1).
+const (
+	filterAnd        = 0xa0
+	filterOr         = 0xa1
+	filterNot        = 0xa2
+	filterEquality   = 0xa3
+	filterSubstrings = 0xa4
+	filterPresent    = 0x87
+
+	substringInitial = 0x80
+	substringAny     = 0x81
+	substringFinal   = 0x82
+)
+
+// EscapeFilter escapes a value for use in a search filter, so that a user
+// name cannot change the filter.
+func EscapeFilter(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// compileFilter encodes a search filter given in its string form (RFC
+// 4515), such as "(&(objectClass=person)(uid=alice))". Equality,
+// presence and substring matches can be combined; other matches are not
+// supported.
+func compileFilter(s string) ([]byte, error) {
+	f, rest, err := parseFilter(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", s, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid filter %q: unexpected %q", s, rest)
+	}
+	return f, nil
+}
+
+func parseFilter(s string) ([]byte, string, error) {
+	if len(s) < 2 || s[0] != '(' {
+		return nil, "", fmt.Errorf("want a parenthesized filter")
+	}
+	s = s[1:]
+
+	switch s[0] {
+	case '&', '|':
+		tag := byte(filterAnd)
+		if s[0] == '|' {
+			tag = filterOr
+		}
+		s = s[1:]
+		var parts [][]byte
+		for strings.HasPrefix(s, "(") {
+			part, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			parts, s = append(parts, part), rest
+		}
+		if len(parts) == 0 || !strings.HasPrefix(s, ")") {
+			return nil, "", fmt.Errorf("unterminated %c filter", tag)
+		}
+		return encode(tag, parts...), s[1:], nil
+	case '!':
+		part, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		if !strings.HasPrefix(rest, ")") {
+			return nil, "", fmt.Errorf("unterminated ! filter")
+		}
+		return encode(filterNot, part), rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", fmt.Errorf("unterminated filter")
+	}
+	item, rest := s[:end], s[end+1:]
+	attr, value, ok := strings.Cut(item, "=")
+	if !ok || attr == "" || strings.ContainsAny(attr, "~<>:()") {
+		return nil, "", fmt.Errorf("unsupported filter item %q", item)
+	}
+	if value == "*" {
+		return encodeString(filterPresent, attr), rest, nil
+	}
+	if !strings.Contains(value, "*") {
+		v, err := unescapeFilter(value)
+		if err != nil {
+			return nil, "", err
+		}
+		return encode(filterEquality, encodeString(tagOctetString, attr), encodeString(tagOctetString, v)), rest, nil
+	}
+
+	parts := strings.Split(value, "*")
+	var subs [][]byte
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		v, err := unescapeFilter(part)
+		if err != nil {
+			return nil, "", err
+		}
+		tag := byte(substringAny)
+		switch i {
+		case 0:
+			tag = substringInitial
+		case len(parts) - 1:
+			tag = substringFinal
+		}
+		subs = append(subs, encodeString(tag, v))
+	}
+	return encode(filterSubstrings, encodeString(tagOctetString, attr), encode(tagSequence, subs...)), rest, nil
+}
+
+// unescapeFilter decodes the \XX escapes of a filter value.
+func unescapeFilter(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 > len(s) {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		c, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		b.Write(c)
+		i += 2
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,415 @@
+// Package ldap authenticates users against an LDAP directory or Active
+// Directory, with the small part of LDAPv3 (RFC 4511) that signing in
+// needs: simple binds, searches and StartTLS.
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+)
+
+// Protocol operations, as BER application tags.
+const (
+	opBindRequest      = 0x60
+	opBindResponse     = 0x61
+	opUnbindRequest    = 0x42
+	opSearchRequest    = 0x63
+	opSearchEntry      = 0x64
+	opSearchDone       = 0x65
+	opSearchReference  = 0x73
+	opExtendedRequest  = 0x77
+	opExtendedResponse = 0x78
+)
+
+// Result codes.
+const (
+	resultSuccess            = 0
+	resultSizeLimitExceeded  = 4
+	resultInvalidCredentials = 49
+)
+
+const (
+	startTLSOID       = "1.3.6.1.4.1.1466.20037"
+	scopeWholeSubtree = 2
+	derefNever        = 0
+	// searchSizeLimit is enough to tell that a user filter is ambiguous.
+	searchSizeLimit = 2
+
+	defaultTimeout        = 10 * time.Second
+	defaultUserFilter     = "(uid=%s)"
+	defaultGroupAttribute = "memberOf"
+	displayNameAttribute  = "displayName"
+	commonNameAttribute   = "cn"
+)
+
+// Config configures an Authenticator.
+type Config struct {
+	// URL is the directory server: ldap://host[:389] or ldaps://host[:636].
+	URL string
+	// StartTLS upgrades ldap:// connections to TLS before binding.
+	StartTLS bool
+	// TLS configures ldaps:// and StartTLS connections. The server name
+	// defaults to the host of URL.
+	TLS *tls.Config
+	// BindDN and BindPassword are the account that searches for users,
+	// or empty to search anonymously.
+	BindDN       string
+	BindPassword string
+	// BaseDN is where users are searched, e.g. "ou=people,dc=example,dc=com".
+	BaseDN string
+	// UserFilter finds the user signing in, %s being replaced by the
+	// escaped user name: "(uid=%s)" by default, or "(sAMAccountName=%s)"
+	// for Active Directory.
+	UserFilter string
+	// GroupAttribute lists the DNs of the groups of a user: memberOf by
+	// default.
+	GroupAttribute string
+	// GroupRoles maps groups, by common name, to roles.
+	GroupRoles auth.GroupRoles
+	// DefaultRole is the role of users in no group mapped to a role, or
+	// empty to refuse them.
+	DefaultRole string
+	// Timeout bounds each sign in.
+	Timeout time.Duration
+}
+
+// Authenticator checks passwords by binding to the directory as the user.
+type Authenticator struct {
+	cfg  Config
+	addr string
+	tls  bool
+}
+
+// New creates an Authenticator from cfg.
+func New(cfg Config) (*Authenticator, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid LDAP URL %q (want ldap:// or ldaps://host[:port])", cfg.URL)
+	}
+	a := &Authenticator{cfg: cfg}
+	port := u.Port()
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = "389"
+		}
+	case "ldaps":
+		if cfg.StartTLS {
+			return nil, errors.New("StartTLS does not apply to ldaps:// URLs")
+		}
+		a.tls = true
+		if port == "" {
+			port = "636"
+		}
+	default:
+		return nil, fmt.Errorf("invalid LDAP URL %q (want ldap:// or ldaps://host[:port])", cfg.URL)
+	}
+	a.addr = net.JoinHostPort(u.Hostname(), port)
+
+	if a.cfg.TLS == nil {
+		a.cfg.TLS = &tls.Config{}
+	} else {
+		a.cfg.TLS = a.cfg.TLS.Clone()
+	}
+	if a.cfg.TLS.ServerName == "" {
+		a.cfg.TLS.ServerName = u.Hostname()
+	}
+	if a.cfg.BaseDN == "" {
+		return nil, errors.New("LDAP base DN is required")
+	}
+	if a.cfg.UserFilter == "" {
+		a.cfg.UserFilter = defaultUserFilter
+	}
+	if strings.Count(a.cfg.UserFilter, "%s") != 1 {
+		return nil, fmt.Errorf("LDAP user filter %q must contain %%s once", a.cfg.UserFilter)
+	}
+	if _, err := compileFilter(fmt.Sprintf(a.cfg.UserFilter, "user")); err != nil {
+		return nil, err
+	}
+	if a.cfg.GroupAttribute == "" {
+		a.cfg.GroupAttribute = defaultGroupAttribute
+	}
+	if a.cfg.Timeout <= 0 {
+		a.cfg.Timeout = defaultTimeout
+	}
+	return a, nil
+}
+
+// Authenticate checks the password of a user: it finds the user's entry,
+// binds as it with password, and maps its groups to a role. It returns
+// auth.ErrInvalidCredentials if the user does not exist, the password is
+// wrong, or no role applies.
+func (a *Authenticator) Authenticate(ctx context.Context, username, password string) (*auth.DirectoryUser, error) {
+	// An empty password would make an unauthenticated bind, which
+	// servers accept
+	if username == "" || password == "" {
+		return nil, auth.ErrInvalidCredentials
+	}
+	ctx, cancel := context.WithTimeout(ctx, a.cfg.Timeout)
+	defer cancel()
+
+	c, err := a.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.close()
+
+	if a.cfg.BindDN != "" {
+		if err := c.bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("bind as %s: %w", a.cfg.BindDN, err)
+		}
+	}
+	filter, err := compileFilter(fmt.Sprintf(a.cfg.UserFilter, EscapeFilter(username)))
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.search(a.cfg.BaseDN, filter, []string{a.cfg.GroupAttribute, displayNameAttribute, commonNameAttribute})
+	if err != nil {
+		return nil, fmt.Errorf("search for user: %w", err)
+	}
+	if len(entries) != 1 {
+		return nil, fmt.Errorf("%w: %d entries match the user", auth.ErrInvalidCredentials, len(entries))
+	}
+	e := entries[0]
+
+	if err := c.bind(e.dn, password); err != nil {
+		var re *resultError
+		if errors.As(err, &re) && re.code == resultInvalidCredentials {
+			return nil, auth.ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("bind as user: %w", err)
+	}
+
+	user := &auth.DirectoryUser{Name: username, DisplayName: e.first(displayNameAttribute)}
+	if user.DisplayName == "" {
+		user.DisplayName = e.first(commonNameAttribute)
+	}
+	for _, dn := range e.attrs[strings.ToLower(a.cfg.GroupAttribute)] {
+		if cn := commonName(dn); cn != "" {
+			user.Groups = append(user.Groups, cn)
+		}
+	}
+	user.Role = a.cfg.GroupRoles.Role(user.Groups)
+	if user.Role == "" {
+		user.Role = a.cfg.DefaultRole
+	}
+	if user.Role == "" {
+		return nil, fmt.Errorf("%w: no group of the user maps to a role", auth.ErrInvalidCredentials)
+	}
+	return user, nil
+}
+
+// commonName returns the value of the first RDN of a DN, such as
+// "Gateway Admins" for "CN=Gateway Admins,OU=Groups,DC=example,DC=com".
+func commonName(dn string) string {
+	var b strings.Builder
+	inValue := false
+	for i := 0; i < len(dn); i++ {
+		c := dn[i]
+		switch {
+		case c == '\\' && i+1 < len(dn):
+			i++
+			if inValue {
+				b.WriteByte(dn[i])
+			}
+		case c == '=' && !inValue:
+			inValue = true
+		case c == ',' || c == '+':
+			return strings.TrimSpace(b.String())
+		case inValue:
+			b.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// resultError is an LDAP operation that did not succeed.
+type resultError struct {
+	code    int
+	message string
+}
+
+func (e *resultError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("ldap result code %d", e.code)
+	}
+	return fmt.Sprintf("ldap result code %d: %s", e.code, e.message)
+}
+
+// entry is a search result, its attribute names lowercased.
+type entry struct {
+	dn    string
+	attrs map[string][]string
+}
+
+func (e *entry) first(attr string) string {
+	if v := e.attrs[strings.ToLower(attr)]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// conn is a connection to the directory, used for one sign in.
+type conn struct {
+	c  net.Conn
+	r  *bufio.Reader
+	id int
+}
+
+func (a *Authenticator) dial(ctx context.Context) (*conn, error) {
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", a.addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+	}
+	if a.tls {
+		nc = tls.Client(nc, a.cfg.TLS)
+	}
+	c := &conn{c: nc, r: bufio.NewReader(nc)}
+	if a.cfg.StartTLS {
+		if err := c.startTLS(a.cfg.TLS); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("StartTLS: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (c *conn) close() {
+	c.send(encode(opUnbindRequest))
+	c.c.Close()
+}
+
+// send sends an operation in a new message and returns its ID.
+func (c *conn) send(op []byte) (int, error) {
+	c.id++
+	_, err := c.c.Write(encode(tagSequence, encodeInt(tagInteger, c.id), op))
+	return c.id, err
+}
+
+// receive reads the next operation sent in response to message id.
+func (c *conn) receive(id int) (*packet, error) {
+	for {
+		msg, err := readPacket(c.r)
+		if err != nil {
+			return nil, err
+		}
+		if msg.tag != tagSequence || len(msg.children) < 2 {
+			return nil, errors.New("ldap: malformed message")
+		}
+		if msg.children[0].int() == id {
+			return msg.children[1], nil
+		}
+	}
+}
+
+// result checks the LDAPResult of a response.
+func result(op *packet, tag byte) error {
+	if op.tag != tag {
+		return fmt.Errorf("ldap: unexpected response 0x%02x", op.tag)
+	}
+	if code := op.child(0).int(); code != resultSuccess {
+		return &resultError{code: code, message: string(op.child(2).value)}
+	}
+	return nil
+}
+
+func (c *conn) startTLS(cfg *tls.Config) error {
+	id, err := c.send(encode(opExtendedRequest, encodeString(0x80, startTLSOID)))
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if err := result(op, opExtendedResponse); err != nil {
+		return err
+	}
+	tc := tls.Client(c.c, cfg)
+	if err := tc.Handshake(); err != nil {
+		return err
+	}
+	c.c, c.r = tc, bufio.NewReader(tc)
+	return nil
+}
+
+func (c *conn) bind(dn, password string) error {
+	id, err := c.send(encode(opBindRequest,
+		encodeInt(tagInteger, 3),
+		encodeString(tagOctetString, dn),
+		encodeString(0x80, password),
+	))
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	return result(op, opBindResponse)
+}
+
+// search returns the entries under base matching filter, at most
+// searchSizeLimit of them.
+func (c *conn) search(base string, filter []byte, attrs []string) ([]entry, error) {
+	var attrList [][]byte
+	for _, a := range attrs {
+		attrList = append(attrList, encodeString(tagOctetString, a))
+	}
+	id, err := c.send(encode(opSearchRequest,
+		encodeString(tagOctetString, base),
+		encodeInt(tagEnumerated, scopeWholeSubtree),
+		encodeInt(tagEnumerated, derefNever),
+		encodeInt(tagInteger, searchSizeLimit),
+		encodeInt(tagInteger, 0),
+		encodeBool(false),
+		filter,
+		encode(tagSequence, attrList...),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch op.tag {
+		case opSearchEntry:
+			e := entry{dn: string(op.child(0).value), attrs: map[string][]string{}}
+			for _, attr := range op.child(1).children {
+				name := strings.ToLower(string(attr.child(0).value))
+				for _, v := range attr.child(1).children {
+					e.attrs[name] = append(e.attrs[name], string(v.value))
+				}
+			}
+			entries = append(entries, e)
+		case opSearchReference:
+			// Referrals to other servers are not followed
+		case opSearchDone:
+			err := result(op, opSearchDone)
+			var re *resultError
+			if errors.As(err, &re) && re.code == resultSizeLimitExceeded {
+				return entries, nil
+			}
+			return entries, err
+		default:
+			return nil, fmt.Errorf("ldap: unexpected response 0x%02x", op.tag)
+		}
+	}
+}
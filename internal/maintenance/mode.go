@@ -0,0 +1,64 @@
+package maintenance
+
+import (
+	"sync"
+	"time"
+)
+
+// Status describes the current read-only state.
+type Status struct {
+	ReadOnly bool       `json:"read_only"`
+	Message  string     `json:"message,omitempty"`
+	Since    *time.Time `json:"since,omitempty"`
+	SetBy    string     `json:"set_by,omitempty"`
+}
+
+// Mode is the process-wide read-only switch. It is held in memory so it
+// keeps working while the database itself is under maintenance; each
+// instance must be toggled separately.
+type Mode struct {
+	mu     sync.RWMutex
+	status Status
+}
+
+// NewMode creates a Mode, initially read-only if enabled is true.
+func NewMode(enabled bool, message string) *Mode {
+	m := &Mode{}
+	if enabled {
+		m.Set(true, message, "environment")
+	}
+	return m
+}
+
+// ReadOnly reports whether mutations are currently refused.
+func (m *Mode) ReadOnly() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status.ReadOnly
+}
+
+// Status returns a copy of the current state.
+func (m *Mode) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// Set switches read-only mode on or off.
+func (m *Mode) Set(enabled bool, message, operator string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !enabled {
+		m.status = Status{}
+		return
+	}
+
+	now := time.Now()
+	m.status = Status{
+		ReadOnly: true,
+		Message:  message,
+		Since:    &now,
+		SetBy:    operator,
+	}
+}
@@ -0,0 +1,323 @@
+// Package metrics keeps the service's own metrics and serves them in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contentType is that of the Prometheus text exposition format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds of histogram buckets, in seconds,
+// suited to request durations.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds metrics, each a family of series told apart by their
+// label values.
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+type family interface {
+	write(w *bufio.Writer)
+}
+
+// NewRegistry creates a Registry with the Go runtime and process metrics.
+func NewRegistry() *Registry {
+	r := &Registry{}
+	start := float64(time.Now().Unix())
+	r.GaugeFunc("process_start_time_seconds", "Start time of the process since the Unix epoch in seconds.", func() float64 { return start })
+	r.GaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 { return float64(runtime.NumGoroutine()) })
+	r.GaugeFunc("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", func() float64 {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return float64(m.HeapAlloc)
+	})
+	return r
+}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+}
+
+// Handler serves the metrics.
+// GET /metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		families := append([]family(nil), r.families...)
+		r.mu.Unlock()
+
+		w.Header().Set("Content-Type", contentType)
+		bw := bufio.NewWriter(w)
+		for _, f := range families {
+			f.write(bw)
+		}
+		bw.Flush()
+	})
+}
+
+// vec holds the series of a family by their label values.
+type vec[T any] struct {
+	name, help, kind string
+	labels           []string
+	newSeries        func() *T
+
+	mu     sync.Mutex
+	series map[string]*T
+	values map[string][]string
+}
+
+func newVec[T any](name, help, kind string, labels []string, newSeries func() *T) *vec[T] {
+	return &vec[T]{name: name, help: help, kind: kind, labels: labels, newSeries: newSeries, series: map[string]*T{}, values: map[string][]string{}}
+}
+
+// with returns the series of the label values, creating it if needed.
+func (v *vec[T]) with(values []string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, got %d values", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = v.newSeries()
+		v.series[key] = s
+		v.values[key] = append([]string(nil), values...)
+	}
+	return s
+}
+
+// each calls f with the label pairs of every series, in a stable order.
+func (v *vec[T]) each(f func(labels string, s *T)) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	v.mu.Unlock()
+	sort.Strings(keys)
+	for _, key := range keys {
+		v.mu.Lock()
+		s, values := v.series[key], v.values[key]
+		v.mu.Unlock()
+		f(labelPairs(v.labels, values), s)
+	}
+}
+
+func (v *vec[T]) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind)
+}
+
+// value is a float64 guarded by its own lock.
+type value struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (x *value) add(d float64) {
+	x.mu.Lock()
+	x.v += d
+	x.mu.Unlock()
+}
+
+func (x *value) set(v float64) {
+	x.mu.Lock()
+	x.v = v
+	x.mu.Unlock()
+}
+
+func (x *value) get() float64 {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.v
+}
+
+// CounterVec is a family of counters.
+type CounterVec struct {
+	*vec[value]
+}
+
+// Counter registers a family of counters with the label names labels.
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, "counter", labels, func() *value { return &value{} })}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the counter of the label values.
+func (c *CounterVec) Inc(values ...string) {
+	c.with(values).add(1)
+}
+
+// Add adds d, which must not be negative, to the counter of the label
+// values.
+func (c *CounterVec) Add(d float64, values ...string) {
+	c.with(values).add(d)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.header(w)
+	c.each(func(labels string, s *value) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labels, formatFloat(s.get()))
+	})
+}
+
+// GaugeVec is a family of gauges.
+type GaugeVec struct {
+	*vec[value]
+}
+
+// Gauge registers a family of gauges with the label names labels.
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newVec(name, help, "gauge", labels, func() *value { return &value{} })}
+	r.register(g)
+	return g
+}
+
+// Set sets the gauge of the label values.
+func (g *GaugeVec) Set(v float64, values ...string) {
+	g.with(values).set(v)
+}
+
+// Add adds d to the gauge of the label values.
+func (g *GaugeVec) Add(d float64, values ...string) {
+	g.with(values).add(d)
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.header(w)
+	g.each(func(labels string, s *value) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, labels, formatFloat(s.get()))
+	})
+}
+
+// gaugeFunc is a gauge read when the metrics are served.
+type gaugeFunc struct {
+	name, help string
+	f          func() float64
+}
+
+// GaugeFunc registers a gauge whose value is f's when the metrics are
+// served.
+func (r *Registry) GaugeFunc(name, help string, f func() float64) {
+	r.register(&gaugeFunc{name: name, help: help, f: f})
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", g.name, g.help, g.name, g.name, formatFloat(g.f()))
+}
+
+// histogram counts observations in cumulative buckets.
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec is a family of histograms.
+type HistogramVec struct {
+	*vec[histogram]
+	buckets []float64
+}
+
+// Histogram registers a family of histograms with the label names labels
+// and the bucket upper bounds buckets, in increasing order.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		vec:     newVec(name, help, "histogram", labels, func() *histogram { return &histogram{counts: make([]uint64, len(buckets))} }),
+		buckets: buckets,
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records v in the histogram of the label values.
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	s := h.with(values)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.header(w)
+	h.each(func(labels string, s *histogram) {
+		s.mu.Lock()
+		counts, count, sum := append([]uint64(nil), s.counts...), s.count, s.sum
+		s.mu.Unlock()
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(labels, "le", formatFloat(upper)), counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(labels, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labels, formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels, count)
+	})
+}
+
+// labelPairs formats label names and values as {name="value",...}, or
+// the empty string if there are none.
+func labelPairs(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeValue(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// withLabel adds a label to formatted label pairs.
+func withLabel(labels, name, value string) string {
+	pair := name + `="` + value + `"`
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return strings.TrimSuffix(labels, "}") + "," + pair + "}"
+}
+
+var valueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeValue(v string) string {
+	return valueEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
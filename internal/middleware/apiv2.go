@@ -0,0 +1,317 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/i18n"
+)
+
+const (
+	v1Prefix = "/api/v1/"
+	v2Prefix = "/api/v2/"
+
+	// v2DefaultLimit and v2MaxLimit bound the pages of lists.
+	v2DefaultLimit = 100
+	v2MaxLimit     = 1000
+)
+
+// v1Deprecated is when /api/v1 was deprecated in favour of /api/v2.
+var v1Deprecated = time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)
+
+// V2Envelope is the body of /api/v2 responses: the result in Data, or the
+// failure in Error.
+type V2Envelope struct {
+	Data       json.RawMessage `json:"data,omitempty"`
+	Pagination *V2Pagination   `json:"pagination,omitempty"`
+	Error      *V2Error        `json:"error,omitempty"`
+	Meta       V2Meta          `json:"meta"`
+}
+
+// V2Pagination describes the page of a list returned in Data.
+type V2Pagination struct {
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	// NextOffset is the offset of the next page, if there is one.
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
+// V2Error describes a failed request.
+type V2Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Status  int    `json:"status"`
+	// Details holds the JSON body of the error, if any.
+	Details json.RawMessage `json:"details,omitempty"`
+}
+
+// V2Meta holds the versions a response was served at, and the validation
+// warnings of a change.
+type V2Meta struct {
+	APIVersion string `json:"api_version"`
+	// Revision is the global config revision once the request was served.
+	Revision uint64   `json:"revision,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// APIVersions serves /api/v2 with the /api/v1 handlers, adapting their
+// responses: JSON results are wrapped in a V2Envelope, lists are paged with
+// the limit and offset parameters, and errors become JSON. Responses of
+// /api/v1 carry Deprecation, Sunset and successor Link headers. Paths under
+// the raw prefixes, such as those of gateways, are served the same in both
+// versions and are not deprecated. It must run before routing.
+func APIVersions(revision func() (uint64, error), sunset time.Time, raw ...string) func(next http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(v1Deprecated.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rest, v1 := strings.CutPrefix(r.URL.Path, v1Prefix)
+			if !v1 {
+				var v2 bool
+				if rest, v2 = strings.CutPrefix(r.URL.Path, v2Prefix); !v2 {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			for _, prefix := range raw {
+				if strings.HasPrefix(v1Prefix+rest, prefix) {
+					if !v1 {
+						r = withPath(r, v1Prefix+rest)
+					}
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			if v1 {
+				w.Header().Set("Deprecation", deprecation)
+				w.Header().Set("Sunset", sunsetDate)
+				w.Header().Add("Link", `<`+v2Prefix+rest+`>; rel="successor-version"`)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			page, err := parsePage(r)
+			if err != nil {
+				msg, code := i18n.Translate(err.Error(), i18n.Negotiate(r.Header.Get("Accept-Language")))
+				writeV2Error(w, http.StatusBadRequest, code, msg, nil)
+				return
+			}
+			r = withPath(r, v1Prefix+rest)
+			native := pagesItself(r.URL.Path)
+			if !native {
+				// Lists are paged here rather than by the handler
+				query := r.URL.Query()
+				query.Del("limit")
+				query.Del("offset")
+				r.URL.RawQuery = query.Encode()
+			}
+
+			vw := &v2Writer{ResponseWriter: w}
+			next.ServeHTTP(vw, r)
+			vw.finish(page, native, revision)
+		})
+	}
+}
+
+// withPath returns a copy of r for path, keeping the original for logging.
+func withPath(r *http.Request, path string) *http.Request {
+	r = r.Clone(r.Context())
+	r.URL.Path = path
+	r.URL.RawPath = ""
+	return r
+}
+
+// pagesItself reports whether the /api/v1 handler of path takes the limit
+// and offset parameters itself: the history lists page themselves, and
+// jobs are limited to the newest.
+func pagesItself(path string) bool {
+	return strings.HasSuffix(path, "/history") || path == v1Prefix+"jobs" || path == v1Prefix+"reports/top-routes"
+}
+
+type v2Page struct {
+	limit, offset int
+}
+
+// parsePage parses the limit and offset parameters of lists.
+func parsePage(r *http.Request) (v2Page, error) {
+	page := v2Page{limit: v2DefaultLimit}
+	query := r.URL.Query()
+	if param := query.Get("limit"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n <= 0 || n > v2MaxLimit {
+			return page, errors.New("invalid limit: must be between 1 and " + strconv.Itoa(v2MaxLimit))
+		}
+		page.limit = n
+	}
+	if param := query.Get("offset"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 {
+			return page, errors.New("invalid offset parameter")
+		}
+		page.offset = n
+	}
+	return page, nil
+}
+
+// v2Writer holds back JSON responses and error messages until the handler
+// is done, so that they can be wrapped. Other responses, such as CSV
+// exports and WebSocket upgrades, are passed through.
+type v2Writer struct {
+	http.ResponseWriter
+	status int
+	// body is set while the response is held back.
+	body *bytes.Buffer
+}
+
+func (vw *v2Writer) WriteHeader(code int) {
+	if vw.status != 0 {
+		return
+	}
+	vw.status = code
+	contentType := vw.Header().Get("Content-Type")
+	if strings.HasPrefix(contentType, "application/json") ||
+		code >= http.StatusBadRequest && strings.HasPrefix(contentType, "text/plain") {
+		vw.body = &bytes.Buffer{}
+		return
+	}
+	if location := vw.Header().Get("Location"); strings.HasPrefix(location, v1Prefix) {
+		vw.Header().Set("Location", v2Prefix+strings.TrimPrefix(location, v1Prefix))
+	}
+	vw.ResponseWriter.WriteHeader(code)
+}
+
+func (vw *v2Writer) Write(p []byte) (int, error) {
+	if vw.status == 0 {
+		vw.WriteHeader(http.StatusOK)
+	}
+	if vw.body != nil {
+		return vw.body.Write(p)
+	}
+	return vw.ResponseWriter.Write(p)
+}
+
+// finish writes the response held back, if any, in a V2Envelope. Lists
+// are paged unless native, in which case the handler paged them itself.
+func (vw *v2Writer) finish(page v2Page, native bool, revision func() (uint64, error)) {
+	if vw.body == nil {
+		return
+	}
+	h := vw.Header()
+	body := bytes.TrimSpace(vw.body.Bytes())
+
+	if vw.status >= http.StatusBadRequest {
+		code := h.Get(ErrorCodeHeader)
+		if !strings.HasPrefix(h.Get("Content-Type"), "application/json") {
+			msg := string(body)
+			writeV2Error(vw.ResponseWriter, vw.status, cmp.Or(code, i18n.Code(msg, vw.status)), msg, nil)
+			return
+		}
+		writeV2Error(vw.ResponseWriter, vw.status, cmp.Or(code, i18n.Code("", vw.status)), http.StatusText(vw.status), body)
+		return
+	}
+
+	env := V2Envelope{Data: body, Meta: V2Meta{APIVersion: "v2", Warnings: parseWarnings(h.Values("Warning"))}}
+	if rev, err := revision(); err == nil {
+		env.Meta.Revision = rev
+	}
+	switch {
+	case !native && len(body) > 0 && body[0] == '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(body, &items); err == nil {
+			env.Data, env.Pagination = paginate(items, page)
+		}
+	case native && len(body) > 0 && body[0] == '{':
+		var paged struct {
+			Items  json.RawMessage `json:"items"`
+			Total  *int            `json:"total"`
+			Limit  int             `json:"limit"`
+			Offset int             `json:"offset"`
+		}
+		if err := json.Unmarshal(body, &paged); err == nil && paged.Items != nil && paged.Total != nil {
+			env.Data = paged.Items
+			env.Pagination = &V2Pagination{Total: *paged.Total, Limit: paged.Limit, Offset: paged.Offset}
+			if next := paged.Offset + paged.Limit; next < *paged.Total {
+				env.Pagination.NextOffset = &next
+			}
+		}
+	}
+
+	h.Set("Content-Type", "application/json")
+	h.Del("Content-Length")
+	if location := h.Get("Location"); strings.HasPrefix(location, v1Prefix) {
+		h.Set("Location", v2Prefix+strings.TrimPrefix(location, v1Prefix))
+	}
+	vw.ResponseWriter.WriteHeader(vw.status)
+	json.NewEncoder(vw.ResponseWriter).Encode(env)
+}
+
+// paginate returns the page of items.
+func paginate(items []json.RawMessage, page v2Page) (json.RawMessage, *V2Pagination) {
+	p := &V2Pagination{Total: len(items), Limit: page.limit, Offset: page.offset}
+	from := min(page.offset, len(items))
+	to := min(from+page.limit, len(items))
+	if to < len(items) {
+		p.NextOffset = &to
+	}
+	data, _ := json.Marshal(items[from:to])
+	return data, p
+}
+
+// parseWarnings returns the texts of Warning headers.
+func parseWarnings(values []string) []string {
+	var warnings []string
+	unescape := strings.NewReplacer(`\\`, `\`, `\"`, `"`)
+	for _, v := range values {
+		_, text, ok := strings.Cut(v, `"`)
+		if !ok {
+			continue
+		}
+		warnings = append(warnings, unescape.Replace(strings.TrimSuffix(text, `"`)))
+	}
+	return warnings
+}
+
+func writeV2Error(w http.ResponseWriter, status int, code, msg string, details json.RawMessage) {
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Del("Content-Length")
+	h.Del("X-Content-Type-Options")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(V2Envelope{
+		Error: &V2Error{Code: code, Message: msg, Status: status, Details: details},
+		Meta:  V2Meta{APIVersion: "v2"},
+	})
+}
+
+// FlushError holds back flushes while the response is held back.
+func (vw *v2Writer) FlushError() error {
+	if vw.body != nil {
+		return nil
+	}
+	return http.NewResponseController(vw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (vw *v2Writer) Unwrap() http.ResponseWriter {
+	return vw.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades take over the connection.
+func (vw *v2Writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := vw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import "net/http"
+
+// AuditRefusals calls record for every request refused as unauthenticated
+// (401) or forbidden (403), whether by authorization or admission checks.
+func AuditRefusals(record func(r *http.Request, status int)) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			if wrapped.statusCode == http.StatusUnauthorized || wrapped.statusCode == http.StatusForbidden {
+				record(r, wrapped.statusCode)
+			}
+		})
+	}
+}
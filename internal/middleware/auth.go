@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+)
+
+// HeaderIdentity builds the request principal from the X-Operator,
+// X-Operator-Role and X-Operator-Teams (comma-separated) headers, which
+// are expected to be set by a trusted authenticating proxy in front of the
+// service. Callers without a role header are editors.
+func HeaderIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := r.Header.Get("X-Operator-Role")
+		if role == "" {
+			role = auth.RoleEditor
+		}
+		p := &auth.Principal{Name: r.Header.Get("X-Operator"), Role: role}
+		for _, team := range strings.Split(r.Header.Get("X-Operator-Teams"), ",") {
+			if team = strings.TrimSpace(team); team != "" {
+				p.Teams = append(p.Teams, team)
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+	})
+}
+
+// MemberTeams adds the teams the principal is a member of, as returned by
+// lookup, to those named in its headers. If the lookup fails the request
+// goes on with the header teams alone, which can only narrow what the
+// caller may change.
+func MemberTeams(lookup func(member string) ([]string, error), logger *zap.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := auth.FromContext(r.Context())
+			if p == nil || p.Name == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			teams, err := lookup(p.Name)
+			if err != nil {
+				logger.Warn("failed to look up operator teams", zap.String("operator", p.Name), zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(teams) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			member := *p
+			member.Teams = append([]string(nil), p.Teams...)
+			for _, team := range teams {
+				if !contains(member.Teams, team) {
+					member.Teams = append(member.Teams, team)
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), &member)))
+		})
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireAdmin rejects requests whose principal is not an admin.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.FromContext(r.Context()).IsAdmin() {
+			http.Error(w, "admin role required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole rejects requests that may change something, those not GET,
+// HEAD or OPTIONS, whose principal does not have role or a more
+// privileged one, except those to the exempt paths; an exempt path ending
+// in "/" covers every path under it.
+func RequireRole(role string, exempt ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !auth.FromContext(r.Context()).HasRole(role) && !matchPath(r.URL.Path, exempt) {
+				http.Error(w, role+" role required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
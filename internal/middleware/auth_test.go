@@ -0,0 +1,47 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/middleware"
+)
+
+func TestRequireRole(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := middleware.RequireRole(auth.RoleEditor, "/api/v1/auth/", "/api/v1/stats")(ok)
+
+	tests := []struct {
+		role   string
+		method string
+		path   string
+		want   int
+	}{
+		{auth.RoleViewer, http.MethodGet, "/api/v1/backends", http.StatusOK},
+		{auth.RoleViewer, http.MethodHead, "/api/v1/backends", http.StatusOK},
+		{auth.RoleViewer, http.MethodPost, "/api/v1/backends", http.StatusForbidden},
+		{auth.RoleViewer, http.MethodPut, "/api/v1/routes/1", http.StatusForbidden},
+		{auth.RoleViewer, http.MethodPatch, "/api/v1/routes/1/rollout", http.StatusForbidden},
+		{auth.RoleViewer, http.MethodDelete, "/api/v1/backends/b", http.StatusForbidden},
+		{auth.RoleViewer, http.MethodPost, "/api/v1/apply", http.StatusForbidden},
+		{auth.RoleViewer, http.MethodPost, "/api/v1/auth/password", http.StatusOK},
+		{auth.RoleViewer, http.MethodPost, "/api/v1/stats", http.StatusOK},
+		{"", http.MethodPost, "/api/v1/backends", http.StatusForbidden},
+		{"owner", http.MethodPost, "/api/v1/backends", http.StatusForbidden},
+		{auth.RoleEditor, http.MethodPost, "/api/v1/backends", http.StatusOK},
+		{auth.RoleAdmin, http.MethodDelete, "/api/v1/backends/b", http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.role != "" {
+			r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Name: "alice", Role: tt.role}))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s %s as %q: got %d, want %d", tt.method, tt.path, tt.role, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// Breaker answers requests 503 with Retry-After while the store's circuit
+// breaker is not closed, rather than letting them fail on the database.
+// retryAfter is the time between probes of the database.
+func Breaker(breaker config.Breaker, retryAfter time.Duration) func(next http.Handler) http.Handler {
+	retryAfterText := strconv.Itoa(max(1, int(math.Ceil(retryAfter.Seconds()))))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if breaker.BreakerState() == config.BreakerClosed {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Retry-After", retryAfterText)
+			http.Error(w, "database unavailable", http.StatusServiceUnavailable)
+		})
+	}
+}
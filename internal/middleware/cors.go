@@ -12,7 +12,7 @@ func CORSMiddleware(next http.Handler) http.Handler {
 	// Get allowed origins from environment variable, default to allow all for development
 	allowedOrigins := getEnv("CORS_ALLOWED_ORIGINS", "*")
 	allowedMethods := getEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE,OPTIONS,PATCH")
-	allowedHeaders := getEnv("CORS_ALLOWED_HEADERS", "Content-Type,Authorization,X-Requested-With")
+	allowedHeaders := getEnv("CORS_ALLOWED_HEADERS", "Content-Type,Authorization,X-Requested-With,X-CSRF-Token,X-TOTP-Code")
 	allowCredentials := getEnv("CORS_ALLOW_CREDENTIALS", "true") == "true"
 
 	// Parse allowed origins
@@ -58,6 +58,7 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		}
 		w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
 		w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+		w.Header().Set("Access-Control-Expose-Headers", ErrorCodeHeader+", Deprecation, Sunset, Link")
 		if allowCredentials {
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 		}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// GatewayAuth restricts gateway-facing endpoints to callers presenting the
+// shared gateway token as a bearer token.
+func GatewayAuth(token string) func(next http.Handler) http.Handler {
+	return BearerAuth(token)
+}
+
+// BearerAuth restricts endpoints to callers presenting token as a bearer
+// token, such as the identity provider provisioning users over SCIM.
+func BearerAuth(token string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/metrics"
+)
+
+// limitWriteMargin is the time left to write a response once the timeout
+// of its group has cancelled the request.
+const limitWriteMargin = 5 * time.Second
+
+// EndpointLimit bounds the requests of a path group.
+type EndpointLimit struct {
+	// Prefix selects the paths of the group. The longest matching prefix
+	// applies.
+	Prefix string
+	// Timeout bounds each request, overriding the server's read and write
+	// timeouts; zero means none, for streaming endpoints.
+	Timeout time.Duration
+	// MaxInFlight bounds the requests served at once; further requests
+	// are answered 503. Zero means unlimited.
+	MaxInFlight int
+}
+
+// ParseEndpointLimits parses a comma-separated list of limits in the form
+// prefix=timeout[/max_in_flight], e.g.
+// "/api/v1/export/=5m/4,/api/v1/history/tail=0".
+func ParseEndpointLimits(s string) ([]EndpointLimit, error) {
+	var limits []EndpointLimit
+	seen := map[string]bool{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		prefix, rest, ok := strings.Cut(item, "=")
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("limit %q: want /prefix=timeout[/max_in_flight]", item)
+		}
+		if seen[prefix] {
+			return nil, fmt.Errorf("limit %q: %s is already limited", item, prefix)
+		}
+		seen[prefix] = true
+		timeoutText, maxText, hasMax := strings.Cut(rest, "/")
+		limit := EndpointLimit{Prefix: prefix}
+		var err error
+		if timeoutText != "0" {
+			if limit.Timeout, err = time.ParseDuration(timeoutText); err != nil || limit.Timeout < time.Second {
+				return nil, fmt.Errorf("limit %q: timeout must be 0 or a duration of at least 1s", item)
+			}
+		}
+		if hasMax {
+			if limit.MaxInFlight, err = strconv.Atoi(maxText); err != nil || limit.MaxInFlight <= 0 {
+				return nil, fmt.Errorf("limit %q: max_in_flight must be a positive integer", item)
+			}
+		}
+		limits = append(limits, limit)
+	}
+	return limits, nil
+}
+
+// EndpointLimits applies the limit of the group of each request's path:
+// its timeout cancels the request's context and replaces the server's
+// read and write deadlines, and requests over its in-flight bound are
+// answered 503 with Retry-After. Requests in no group keep the server's
+// timeouts. In-flight and rejected requests are recorded in reg.
+func EndpointLimits(limits []EndpointLimit, reg *metrics.Registry, logger *zap.Logger) func(next http.Handler) http.Handler {
+	groups := make([]*limitGroup, len(limits))
+	for i, l := range limits {
+		groups[i] = &limitGroup{EndpointLimit: l}
+		if l.MaxInFlight > 0 {
+			groups[i].slots = make(chan struct{}, l.MaxInFlight)
+		}
+	}
+	// Longest prefix first
+	sort.Slice(groups, func(i, j int) bool { return len(groups[i].Prefix) > len(groups[j].Prefix) })
+	inFlight := reg.Gauge("admin_http_in_flight_requests", "Requests being served, by path group.", "group")
+	rejected := reg.Counter("admin_http_rejected_requests_total", "Requests answered 503 for exceeding the in-flight bound of their path group.", "group")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var g *limitGroup
+			for _, candidate := range groups {
+				if strings.HasPrefix(r.URL.Path, candidate.Prefix) {
+					g = candidate
+					break
+				}
+			}
+			if g == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if g.slots != nil {
+				select {
+				case g.slots <- struct{}{}:
+					defer func() { <-g.slots }()
+				default:
+					rejected.Inc(g.Prefix)
+					w.Header().Set("Retry-After", "1")
+					http.Error(w, "too many concurrent requests", http.StatusServiceUnavailable)
+					return
+				}
+			}
+			inFlight.Add(1, g.Prefix)
+			defer inFlight.Add(-1, g.Prefix)
+
+			// Lift the server's deadlines, or replace them with the group's
+			var deadline time.Time
+			if g.Timeout > 0 {
+				ctx, cancel := context.WithTimeout(r.Context(), g.Timeout)
+				defer cancel()
+				r = r.WithContext(ctx)
+				deadline = time.Now().Add(g.Timeout + limitWriteMargin)
+			}
+			rc := http.NewResponseController(w)
+			if err := rc.SetReadDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				logger.Warn("failed to set read deadline", zap.Error(err))
+			}
+			if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				logger.Warn("failed to set write deadline", zap.Error(err))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitGroup is an EndpointLimit with its in-flight requests.
+type limitGroup struct {
+	EndpointLimit
+	// slots holds a token per request in flight, if bounded.
+	slots chan struct{}
+}
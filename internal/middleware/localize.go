@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/i18n"
+)
+
+// ErrorCodeHeader carries the machine-readable code of an error response,
+// which stays the same whatever the language of the message.
+const ErrorCodeHeader = "X-Error-Code"
+
+// Localize translates plain-text error messages into the language asked
+// for with Accept-Language, and adds their code in the X-Error-Code header.
+// Other responses, including JSON errors, are passed through unchanged.
+func Localize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lw := &localizingWriter{ResponseWriter: w, lang: i18n.Negotiate(r.Header.Get("Accept-Language"))}
+		next.ServeHTTP(lw, r)
+		lw.finish()
+	})
+}
+
+// localizingWriter holds back plain-text error messages until the handler
+// is done, so that they can be translated as a whole.
+type localizingWriter struct {
+	http.ResponseWriter
+	lang   string
+	status int
+	// message is set while an error message is held back.
+	message *bytes.Buffer
+}
+
+func (lw *localizingWriter) WriteHeader(code int) {
+	if lw.status != 0 {
+		return
+	}
+	lw.status = code
+	if code >= http.StatusBadRequest && strings.HasPrefix(lw.Header().Get("Content-Type"), "text/plain") {
+		lw.message = &bytes.Buffer{}
+		return
+	}
+	lw.ResponseWriter.WriteHeader(code)
+}
+
+func (lw *localizingWriter) Write(p []byte) (int, error) {
+	if lw.status == 0 {
+		lw.WriteHeader(http.StatusOK)
+	}
+	if lw.message != nil {
+		return lw.message.Write(p)
+	}
+	return lw.ResponseWriter.Write(p)
+}
+
+// finish writes the error message held back, if any.
+func (lw *localizingWriter) finish() {
+	if lw.message == nil {
+		return
+	}
+	msg := strings.TrimSuffix(lw.message.String(), "\n")
+	text, _ := i18n.Translate(msg, lw.lang)
+
+	h := lw.Header()
+	h.Set(ErrorCodeHeader, i18n.Code(msg, lw.status))
+	h.Set("Content-Language", lw.lang)
+	h.Add("Vary", "Accept-Language")
+	h.Del("Content-Length")
+	lw.ResponseWriter.WriteHeader(lw.status)
+	lw.ResponseWriter.Write([]byte(text + "\n"))
+}
+
+// FlushError holds back flushes while an error message is held back.
+func (lw *localizingWriter) FlushError() error {
+	if lw.message != nil {
+		return nil
+	}
+	return http.NewResponseController(lw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g.
+// to flush streamed responses.
+func (lw *localizingWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades take over the connection.
+func (lw *localizingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := lw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/metrics"
+)
+
+// Metrics counts requests and records their durations in reg, by method,
+// route pattern and status code. Requests matching no route are counted
+// under the route "unmatched", so that paths cannot grow the series
+// without bound.
+func Metrics(reg *metrics.Registry) func(next http.Handler) http.Handler {
+	requests := reg.Counter("admin_http_requests_total", "HTTP requests served, by method, route and status code.", "method", "route", "code")
+	durations := reg.Histogram("admin_http_request_duration_seconds", "Duration of HTTP requests, by method and route.", metrics.DefaultBuckets, "method", "route")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			requests.Inc(r.Method, route, strconv.Itoa(wrapped.statusCode))
+			durations.Observe(time.Since(start).Seconds(), r.Method, route)
+		})
+	}
+}
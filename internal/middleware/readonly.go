@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/maintenance"
+)
+
+// ReadOnly rejects mutating requests with 503 while read-only mode is on.
+// Requests to the exempt paths (such as the toggle itself) always pass.
+func ReadOnly(mode *maintenance.Mode, exempt ...string) func(next http.Handler) http.Handler {
+	exemptPaths := make(map[string]struct{}, len(exempt))
+	for _, p := range exempt {
+		exemptPaths[p] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := exemptPaths[r.URL.Path]; ok || !mode.ReadOnly() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			msg := "service is in read-only mode"
+			if status := mode.Status(); status.Message != "" {
+				msg += ": " + status.Message
+			}
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, msg, http.StatusServiceUnavailable)
+		})
+	}
+}
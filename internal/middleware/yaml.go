@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// maxYAMLBody bounds the YAML request bodies converted to JSON.
+const maxYAMLBody = 32 << 20
+
+// yamlTypes are the media types accepted for YAML.
+var yamlTypes = map[string]bool{"application/yaml": true, "application/x-yaml": true, "text/yaml": true, "text/x-yaml": true}
+
+// DecodeYAML converts request bodies sent as YAML to JSON before they reach
+// the handlers, so that clients can send YAML to the JSON API.
+func DecodeYAML(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); !yamlTypes[mediaType] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxYAMLBody))
+		r.Body.Close()
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			http.Error(w, "invalid yaml", http.StatusBadRequest)
+			return
+		}
+		r = r.Clone(r.Context())
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		r.ContentLength = int64(len(data))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Content-Length", strconv.Itoa(len(data)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// EncodeYAML converts JSON responses to YAML for clients that prefer it in
+// their Accept header. Responses whose body has a digest header, such as
+// the gateway config, are always sent as is.
+func EncodeYAML(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !prefersYAML(r.Header.Get("Accept")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		yw := &yamlWriter{ResponseWriter: w}
+		next.ServeHTTP(yw, r)
+		yw.finish()
+	})
+}
+
+// prefersYAML reports whether an Accept header prefers YAML to JSON.
+func prefersYAML(accept string) bool {
+	yamlQ, jsonQ := 0.0, 0.0
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if param, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(param, 64); err != nil {
+				continue
+			}
+		}
+		switch {
+		case yamlTypes[mediaType]:
+			yamlQ = max(yamlQ, q)
+		case mediaType == "application/json":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return yamlQ > 0 && yamlQ >= jsonQ
+}
+
+// yamlWriter holds back JSON responses until the handler is done, so that
+// they can be converted. Other responses are passed through.
+type yamlWriter struct {
+	http.ResponseWriter
+	status int
+	// body is set while the response is held back.
+	body *bytes.Buffer
+}
+
+func (yw *yamlWriter) WriteHeader(code int) {
+	if yw.status != 0 {
+		return
+	}
+	yw.status = code
+	h := yw.Header()
+	if strings.HasPrefix(h.Get("Content-Type"), "application/json") && h.Get("X-Config-SHA256") == "" {
+		yw.body = &bytes.Buffer{}
+		return
+	}
+	yw.ResponseWriter.WriteHeader(code)
+}
+
+func (yw *yamlWriter) Write(p []byte) (int, error) {
+	if yw.status == 0 {
+		yw.WriteHeader(http.StatusOK)
+	}
+	if yw.body != nil {
+		return yw.body.Write(p)
+	}
+	return yw.ResponseWriter.Write(p)
+}
+
+// finish writes the response held back, if any, as YAML.
+func (yw *yamlWriter) finish() {
+	if yw.body == nil {
+		return
+	}
+	h := yw.Header()
+	h.Del("Content-Length")
+	h.Add("Vary", "Accept")
+	data, err := yaml.JSONToYAML(yw.body.Bytes())
+	if err != nil {
+		// Not JSON after all
+		yw.ResponseWriter.WriteHeader(yw.status)
+		yw.ResponseWriter.Write(yw.body.Bytes())
+		return
+	}
+	h.Set("Content-Type", "application/yaml")
+	yw.ResponseWriter.WriteHeader(yw.status)
+	yw.ResponseWriter.Write(data)
+}
+
+// FlushError holds back flushes while the response is held back.
+func (yw *yamlWriter) FlushError() error {
+	if yw.body != nil {
+		return nil
+	}
+	return http.NewResponseController(yw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (yw *yamlWriter) Unwrap() http.ResponseWriter {
+	return yw.ResponseWriter
+}
+
+// Hijack lets WebSocket upgrades take over the connection.
+func (yw *yamlWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := yw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+)
+
+// SMTPConfig configures outgoing mail.
+type SMTPConfig struct {
+	// Addr is the server's host:port.
+	Addr string
+	// Username and Password enable PLAIN authentication when set; the
+	// server must then support STARTTLS (or be localhost).
+	Username string
+	Password string
+	From     string
+	To       []string
+	// Timeout bounds delivery of one message.
+	Timeout time.Duration
+}
+
+// RouteSource lists routes.
+type RouteSource interface {
+	GetRoutes(enabled *bool) ([]config.Route, error)
+}
+
+// healthResult is the part of a health event payload used in alerts.
+type healthResult struct {
+	Addr  string    `json:"addr"`
+	Since time.Time `json:"since"`
+	Error string    `json:"error"`
+}
+
+// EmailAlerter emails a recipient list whenever the health checker marks a
+// backend down or recovered, listing the routes that depend on it.
+type EmailAlerter struct {
+	smtp        SMTPConfig
+	environment string
+	routes      RouteSource
+	logger      *zap.Logger
+}
+
+// NewEmailAlerter creates a new EmailAlerter.
+func NewEmailAlerter(cfg SMTPConfig, environment string, routes RouteSource, logger *zap.Logger) *EmailAlerter {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &EmailAlerter{
+		smtp:        cfg,
+		environment: environment,
+		routes:      routes,
+		logger:      logger,
+	}
+}
+
+// Run sends alerts for health events published to broker until ctx is
+// cancelled.
+func (a *EmailAlerter) Run(ctx context.Context, broker *events.Broker) {
+	filter := events.Filter{Types: map[string]bool{events.TypeHealth: true}}
+	sub := broker.Subscribe(filter, 64)
+	defer func() { broker.Unsubscribe(sub) }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				a.logger.Warn("health alerts fell behind, some transitions were not emailed")
+				sub = broker.Subscribe(filter, 64)
+				continue
+			}
+			subject, body := a.compose(e)
+			if err := a.send(ctx, subject, body); err != nil {
+				a.logger.Warn("failed to send health alert", zap.String("backend", e.Backend), zap.Error(err))
+			}
+		}
+	}
+}
+
+// compose builds the subject and plain-text body of an alert.
+func (a *EmailAlerter) compose(e events.Event) (string, string) {
+	var result healthResult
+	json.Unmarshal(e.Data, &result)
+
+	prefix := ""
+	if a.environment != "" {
+		prefix = "[" + a.environment + "] "
+	}
+	subject := prefix + "Backend " + e.Backend + " is DOWN"
+	if e.Operation != "DOWN" {
+		subject = prefix + "Backend " + e.Backend + " has recovered"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Backend:  %s\n", e.Backend)
+	fmt.Fprintf(&b, "Address:  %s\n", result.Addr)
+	fmt.Fprintf(&b, "Status:   %s since %s\n", e.Operation, result.Since.UTC().Format(time.RFC3339))
+	if a.environment != "" {
+		fmt.Fprintf(&b, "Env:      %s\n", a.environment)
+	}
+	if result.Error != "" {
+		fmt.Fprintf(&b, "Error:    %s\n", result.Error)
+	}
+
+	b.WriteString("\nAffected routes:\n")
+	enabled := true
+	routes, err := a.routes.GetRoutes(&enabled)
+	if err != nil {
+		fmt.Fprintf(&b, "  (could not list routes: %v)\n", err)
+		return subject, b.String()
+	}
+	n := 0
+	for _, r := range routes {
+		if r.BackendName != e.Backend {
+			continue
+		}
+		fmt.Fprintf(&b, "  %s %s -> %s/%s\n", r.HTTPMethod, r.HTTPPattern, r.BackendService, r.BackendMethod)
+		n++
+	}
+	if n == 0 {
+		b.WriteString("  (none)\n")
+	}
+	return subject, b.String()
+}
+
+// send delivers one message to every recipient, upgrading to TLS when the
+// server offers STARTTLS.
+func (a *EmailAlerter) send(ctx context.Context, subject, body string) error {
+	ctx, cancel := context.WithTimeout(ctx, a.smtp.Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", a.smtp.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(a.smtp.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if a.smtp.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", a.smtp.Username, a.smtp.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(a.smtp.From); err != nil {
+		return err
+	}
+	for _, to := range a.smtp.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(a.message(subject, body)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message formats an RFC 5322 message.
+func (a *EmailAlerter) message(subject, body string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", a.smtp.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(a.smtp.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return b.Bytes()
+}
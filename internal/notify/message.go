@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+)
+
+const (
+	// maxChanges is the number of changes listed in one message.
+	maxChanges = 20
+	// maxFieldChanges is the number of changed fields listed per update.
+	maxFieldChanges = 5
+	// maxValueLen truncates long field values such as certificates.
+	maxValueLen = 60
+)
+
+// ignoredFields never appear in diff summaries.
+var ignoredFields = map[string]bool{"id": true, "revision": true, "created_at": true, "updated_at": true}
+
+// message is a chat-neutral notification.
+type message struct {
+	title  string
+	reason string
+	lines  []string
+}
+
+// newMessage summarises changes made in environment.
+func newMessage(environment string, changes []events.Event) *message {
+	var operators, reasons []string
+	seenOperator := map[string]bool{}
+	seenReason := map[string]bool{}
+	for _, e := range changes {
+		if op := e.Operator; op != "" && !seenOperator[op] {
+			seenOperator[op] = true
+			operators = append(operators, op)
+		}
+		if !seenReason[e.Reason] {
+			seenReason[e.Reason] = true
+			reasons = append(reasons, e.Reason)
+		}
+	}
+
+	m := &message{}
+	if environment != "" {
+		m.title = "[" + environment + "] "
+	}
+	if len(changes) == 1 {
+		m.title += "Configuration changed"
+	} else {
+		m.title += fmt.Sprintf("%d configuration changes", len(changes))
+	}
+	if len(operators) > 0 {
+		m.title += " by " + strings.Join(operators, ", ")
+	}
+	// A shared reason is shown once; otherwise it is shown per change
+	sharedReason := len(reasons) == 1
+	if sharedReason {
+		m.reason = reasons[0]
+	}
+
+	for i, e := range changes {
+		if i == maxChanges {
+			m.lines = append(m.lines, fmt.Sprintf("…and %d more", len(changes)-maxChanges))
+			break
+		}
+		line := summarize(e)
+		if !sharedReason && e.Reason != "" {
+			line += " (" + e.Reason + ")"
+		}
+		m.lines = append(m.lines, line)
+	}
+	return m
+}
+
+// summarize describes a single change, e.g.
+// "UPDATE backend user-svc: addr 10.0.0.1:9000 → 10.0.0.2:9000".
+func summarize(e events.Event) string {
+	var cur map[string]interface{}
+	json.Unmarshal(e.Data, &cur)
+
+	s := e.Operation + " " + e.Type + " " + resourceName(e.Type, cur)
+	if e.Operation != "UPDATE" || len(e.Old) == 0 {
+		return s
+	}
+
+	var old map[string]interface{}
+	json.Unmarshal(e.Old, &old)
+
+	diffs := diffFields(old, cur)
+	if len(diffs) == 0 {
+		return s
+	}
+	if len(diffs) > maxFieldChanges {
+		diffs = append(diffs[:maxFieldChanges], fmt.Sprintf("%d more fields", len(diffs)-maxFieldChanges))
+	}
+	return s + ": " + strings.Join(diffs, ", ")
+}
+
+// resourceName identifies a backend by name and a route by method and
+// pattern.
+func resourceName(configType string, v map[string]interface{}) string {
+	if configType == events.TypeRoute {
+		return fmt.Sprintf("%v %v", v["http_method"], v["http_pattern"])
+	}
+	return fmt.Sprint(v["name"])
+}
+
+// diffFields lists the top-level fields that differ between old and cur.
+func diffFields(old, cur map[string]interface{}) []string {
+	keys := map[string]bool{}
+	for k := range old {
+		keys[k] = true
+	}
+	for k := range cur {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		if !ignoredFields[k] {
+			sorted = append(sorted, k)
+		}
+	}
+	sort.Strings(sorted)
+
+	var diffs []string
+	for _, k := range sorted {
+		if reflect.DeepEqual(old[k], cur[k]) {
+			continue
+		}
+		diffs = append(diffs, fmt.Sprintf("%s %s → %s", k, formatValue(old[k]), formatValue(cur[k])))
+	}
+	return diffs
+}
+
+func formatValue(v interface{}) string {
+	if v == nil {
+		return "(none)"
+	}
+	var s string
+	if str, ok := v.(string); ok {
+		s = str
+	} else {
+		data, _ := json.Marshal(v)
+		s = string(data)
+	}
+	if r := []rune(s); len(r) > maxValueLen {
+		s = string(r[:maxValueLen]) + "…"
+	}
+	return s
+}
+
+// payload renders the message for a webhook of kind.
+func (m *message) payload(kind string) interface{} {
+	if kind == KindTeams {
+		return m.teams()
+	}
+	return m.slack()
+}
+
+// slackEscaper escapes the characters Slack treats as markup.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slack renders the message as a Slack incoming webhook payload.
+func (m *message) slack() interface{} {
+	var b strings.Builder
+	b.WriteString("*" + slackEscaper.Replace(m.title) + "*")
+	if m.reason != "" {
+		b.WriteString("\nReason: " + slackEscaper.Replace(m.reason))
+	}
+	for _, line := range m.lines {
+		b.WriteString("\n• " + slackEscaper.Replace(line))
+	}
+	return map[string]string{"text": b.String()}
+}
+
+// teams renders the message as a Microsoft Teams incoming webhook
+// (MessageCard) payload.
+func (m *message) teams() interface{} {
+	var b strings.Builder
+	if m.reason != "" {
+		b.WriteString("Reason: " + m.reason + "\n\n")
+	}
+	for _, line := range m.lines {
+		b.WriteString("- " + line + "\n")
+	}
+	return map[string]string{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  m.title,
+		"title":    m.title,
+		"text":     b.String(),
+	}
+}
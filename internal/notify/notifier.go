@@ -0,0 +1,219 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/outbox"
+)
+
+// Webhook kinds.
+const (
+	KindSlack = "slack"
+	KindTeams = "teams"
+)
+
+// AllEnvironments matches every environment in a Target.
+const AllEnvironments = "*"
+
+// Target is a chat webhook that receives change notifications for one
+// environment, or for all of them.
+type Target struct {
+	Environment string
+	Kind        string
+	URL         string
+}
+
+// ParseTargets parses a comma-separated list of webhooks in the form
+// [environment:]kind:url, e.g.
+// "prod:slack:https://hooks.slack.com/...,teams:https://...". Targets
+// without an environment receive notifications from every environment.
+func ParseTargets(s string) ([]Target, error) {
+	var targets []Target
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		t := Target{Environment: AllEnvironments}
+		first, rest, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("notification target %q: want [environment:]kind:url", item)
+		}
+		if first != KindSlack && first != KindTeams {
+			t.Environment = first
+			if first, rest, ok = strings.Cut(rest, ":"); !ok {
+				return nil, fmt.Errorf("notification target %q: want [environment:]kind:url", item)
+			}
+		}
+		if first != KindSlack && first != KindTeams {
+			return nil, fmt.Errorf("notification target %q: kind must be %q or %q", item, KindSlack, KindTeams)
+		}
+		t.Kind = first
+		if !strings.HasPrefix(rest, "https://") && !strings.HasPrefix(rest, "http://") {
+			return nil, fmt.Errorf("notification target %q: url must be http(s)", item)
+		}
+		t.URL = rest
+
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// Notifier posts a summary of backend and route changes to Slack or Teams
+// webhooks. Changes arriving within a short window, such as those of one
+// declarative apply, are sent as a single message.
+type Notifier struct {
+	environment string
+	targets     []Target
+	window      time.Duration
+	client      *http.Client
+	logger      *zap.Logger
+}
+
+// NewNotifier creates a Notifier for the named environment. Only targets
+// for that environment (or for all environments) are used.
+func NewNotifier(environment string, targets []Target, logger *zap.Logger) *Notifier {
+	n := &Notifier{
+		environment: environment,
+		window:      2 * time.Second,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		logger:      logger,
+	}
+	for _, t := range targets {
+		if t.Environment == AllEnvironments || t.Environment == environment {
+			n.targets = append(n.targets, t)
+		}
+	}
+	return n
+}
+
+// Enabled reports whether any target applies to this environment.
+func (n *Notifier) Enabled() bool {
+	return len(n.targets) > 0
+}
+
+// Run sends notifications for changes published to broker until ctx is
+// cancelled.
+func (n *Notifier) Run(ctx context.Context, broker *events.Broker) {
+	filter := events.Filter{Types: map[string]bool{events.TypeBackend: true, events.TypeRoute: true}}
+	sub := broker.Subscribe(filter, 256)
+	defer func() { broker.Unsubscribe(sub) }()
+
+	var pending []events.Event
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				// The broker dropped us for falling behind; subscribe again
+				n.logger.Warn("change notifications fell behind, some changes were not notified")
+				sub = broker.Subscribe(filter, 256)
+				continue
+			}
+			if pending == nil {
+				flush = time.After(n.window)
+			}
+			pending = append(pending, e)
+		case <-flush:
+			n.send(ctx, pending)
+			pending, flush = nil, nil
+		}
+	}
+}
+
+// send posts one message about changes to every target.
+func (n *Notifier) send(ctx context.Context, changes []events.Event) {
+	msg := newMessage(n.environment, changes)
+	for _, t := range n.targets {
+		if err := n.post(ctx, t.URL, msg.payload(t.Kind)); err != nil {
+			n.logger.Warn("failed to send change notification", zap.String("kind", t.Kind), zap.Error(err))
+		}
+	}
+}
+
+// Sinks returns an outbox sink per target, to send notifications from
+// the outbox instead of Run, so that none is lost and a failing target is
+// retried on its own.
+func (n *Notifier) Sinks() []outbox.Sink {
+	sinks := make([]outbox.Sink, len(n.targets))
+	for i, t := range n.targets {
+		sinks[i] = &targetSink{notifier: n, target: t}
+	}
+	return sinks
+}
+
+// targetSink sends the notifications of one target from the outbox.
+type targetSink struct {
+	notifier *Notifier
+	target   Target
+}
+
+// Name implements outbox.Sink. It is derived from the URL, which holds a
+// secret token.
+func (s *targetSink) Name() string {
+	sum := sha256.Sum256([]byte(s.target.URL))
+	return "notify-" + s.target.Kind + "-" + hex.EncodeToString(sum[:8])
+}
+
+// Deliver implements outbox.Sink, sending one message about the backend
+// and route changes of the batch.
+func (s *targetSink) Deliver(ctx context.Context, batch []events.Event) error {
+	var changes []events.Event
+	for _, e := range batch {
+		if e.Type == events.TypeBackend || e.Type == events.TypeRoute {
+			changes = append(changes, e)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	msg := newMessage(s.notifier.environment, changes)
+	return s.notifier.post(ctx, s.target.URL, msg.payload(s.target.Kind))
+}
+
+func (n *Notifier) post(ctx context.Context, webhook string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// The URL holds a token, and outbox errors are recorded
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			return uerr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Package openapi describes the HTTP surface of the gateway as an OpenAPI
+// 3 document, for API consumers.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// Version is the OpenAPI version of generated documents.
+const Version = "3.0.3"
+
+// Document is an OpenAPI document. Only the parts the gateway can describe
+// are modeled.
+type Document struct {
+	OpenAPI string              `json:"openapi"`
+	Info    Info                `json:"info"`
+	Servers []Server            `json:"servers,omitempty"`
+	Tags    []Tag               `json:"tags,omitempty"`
+	Paths   map[string]PathItem `json:"paths"`
+}
+
+// Info describes the API.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Server is a base URL the API is served at.
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Tag groups operations; there is one per backend gRPC service.
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem holds the operations of a path, keyed by lower-case HTTP method.
+type PathItem map[string]*Operation
+
+// Operation is one route.
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+	// ExternalDocs links to the route's external documentation.
+	ExternalDocs *ExternalDocs `json:"externalDocs,omitempty"`
+	// Backend tells which gRPC method serves the operation.
+	Backend *Backend `json:"x-gateway-backend,omitempty"`
+}
+
+// ExternalDocs is a link to documentation outside the document.
+type ExternalDocs struct {
+	URL string `json:"url"`
+}
+
+// Parameter is a path parameter.
+type Parameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Schema   Schema `json:"schema"`
+}
+
+// RequestBody is the body of an operation, keyed by the media types the
+// route accepts.
+type RequestBody struct {
+	Content map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a body, with an example for JSON bodies if
+// the route documents one.
+type MediaType struct {
+	Schema  Schema          `json:"schema"`
+	Example json.RawMessage `json:"example,omitempty"`
+}
+
+// Schema is a JSON schema. Messages are described as free-form objects, as
+// the gateway does not know their fields.
+type Schema struct {
+	Type string `json:"type"`
+}
+
+// Backend is the x-gateway-backend extension of an operation.
+type Backend struct {
+	Name      string `json:"name"`
+	Service   string `json:"service"`
+	Method    string `json:"method"`
+	TimeoutMS int    `json:"timeout_ms"`
+}
+
+// methods are the HTTP methods OpenAPI can describe.
+var methods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// withBody are the methods whose operations take a request body.
+var withBody = map[string]bool{"put": true, "post": true, "patch": true}
+
+// pathParam matches a path variable such as {name}.
+var pathParam = regexp.MustCompile(`\{([^}=]+)\}`)
+
+// Build describes the routes the gateway serves: enabled routes that are
+// not retired and whose backend is enabled, like compile.Build. If several routes share a method
+// and pattern the one with the lowest ID is described. Routes with a
+// method OpenAPI cannot express are left out. If info has no version, the
+// time of the latest change to a described route is used.
+func Build(backends []config.Backend, routes []config.Route, info Info) *Document {
+	known := make(map[string]bool, len(backends))
+	for _, b := range backends {
+		known[b.Name] = b.Enabled
+	}
+
+	served := make([]config.Route, 0, len(routes))
+	for _, r := range routes {
+		if r.Enabled && r.Lifecycle != config.LifecycleRetired && known[r.BackendName] && methods[strings.ToLower(r.HTTPMethod)] {
+			served = append(served, r)
+		}
+	}
+	sort.Slice(served, func(i, j int) bool { return served[i].ID < served[j].ID })
+
+	doc := &Document{OpenAPI: Version, Info: info, Paths: map[string]PathItem{}}
+	services := map[string]bool{}
+	for _, r := range served {
+		method := strings.ToLower(r.HTTPMethod)
+		item, ok := doc.Paths[r.HTTPPattern]
+		if !ok {
+			item = PathItem{}
+			doc.Paths[r.HTTPPattern] = item
+		}
+		if _, dup := item[method]; dup {
+			continue
+		}
+		item[method] = operation(&r, method)
+		services[r.BackendService] = true
+	}
+
+	if doc.Info.Version == "" {
+		var latest time.Time
+		for _, r := range served {
+			if r.UpdatedAt.After(latest) {
+				latest = r.UpdatedAt
+			}
+		}
+		doc.Info.Version = latest.UTC().Format(time.RFC3339)
+	}
+
+	for s := range services {
+		doc.Tags = append(doc.Tags, Tag{Name: s})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	return doc
+}
+
+// operation describes one route.
+func operation(r *config.Route, method string) *Operation {
+	op := &Operation{
+		OperationID: fmt.Sprintf("%s_%d", r.BackendMethod, r.ID),
+		Summary:     r.Description,
+		Tags:        []string{r.BackendService},
+		Deprecated:  r.Lifecycle == config.LifecycleDeprecated,
+		Responses: map[string]Response{
+			"200": {
+				Description: "Response of " + r.BackendService + "/" + r.BackendMethod,
+				Content:     map[string]MediaType{"application/json": {Schema: Schema{Type: "object"}}},
+			},
+			"default": {Description: "Error"},
+		},
+		Backend: &Backend{
+			Name:      r.BackendName,
+			Service:   r.BackendService,
+			Method:    r.BackendMethod,
+			TimeoutMS: r.TimeoutMS,
+		},
+	}
+	for _, m := range pathParam.FindAllStringSubmatch(r.HTTPPattern, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     m[1],
+			In:       "path",
+			Required: true,
+			Schema:   Schema{Type: "string"},
+		})
+	}
+	if withBody[method] {
+		types := r.ContentTypes
+		if len(types) == 0 {
+			types = []string{config.DefaultContentType}
+		}
+		op.RequestBody = &RequestBody{Content: map[string]MediaType{}}
+		for _, t := range types {
+			media := MediaType{Schema: Schema{Type: "object"}}
+			if r.Docs != nil && isJSON(t) {
+				media.Example = r.Docs.RequestExample
+			}
+			op.RequestBody.Content[t] = media
+		}
+	}
+	if d := r.Docs; d != nil {
+		if d.Summary != "" {
+			op.Summary = d.Summary
+			op.Description = r.Description
+		}
+		if d.ResponseExample != nil {
+			op.Responses["200"].Content["application/json"] = MediaType{Schema: Schema{Type: "object"}, Example: d.ResponseExample}
+		}
+		if d.ExternalURL != "" {
+			op.ExternalDocs = &ExternalDocs{URL: d.ExternalURL}
+		}
+	}
+	return op
+}
+
+// isJSON reports whether the media type t is JSON, e.g.
+// application/json or application/problem+json.
+func isJSON(t string) bool {
+	return t == "application/json" || strings.HasSuffix(t, "+json")
+}
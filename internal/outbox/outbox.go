@@ -0,0 +1,200 @@
+// Package outbox delivers change events recorded in the store's outbox to
+// sinks such as webhooks. Events are recorded in the transaction of the
+// change they describe, so unlike those of the in-process broker they are
+// not lost if the service stops right after a commit.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+)
+
+const (
+	// batchSize is the most entries delivered to a sink at once.
+	batchSize = 100
+	// pollInterval is how often sinks look for entries recorded by other
+	// replicas, and replicas without a lease try to take it over.
+	pollInterval = 5 * time.Second
+	// leaseTTL is how long a replica may deliver to a sink without
+	// renewing its lease. It must exceed the time a delivery takes.
+	leaseTTL = time.Minute
+	// maxBackoff caps the delay between attempts at a failing sink.
+	maxBackoff = 5 * time.Minute
+	// maxErrorLength caps the recorded delivery error.
+	maxErrorLength = 512
+)
+
+// configEvents selects the events recorded in the outbox.
+var configEvents = events.Filter{Types: map[string]bool{events.TypeBackend: true, events.TypeRoute: true, events.TypeDescriptor: true, events.TypeSchema: true}}
+
+// Sink receives the events of the outbox in order.
+type Sink interface {
+	// Name identifies the sink's delivery state in the store. It must
+	// stay the same across restarts, or the sink starts over after the
+	// latest event.
+	Name() string
+	// Deliver sends a batch of events. On error, the whole batch is sent
+	// again later, so a sink that delivers events one by one should let
+	// its receiver deduplicate them by revision.
+	Deliver(ctx context.Context, batch []events.Event) error
+}
+
+// Dispatcher delivers the outbox to sinks. Each sink is served by the
+// replica holding its lease in the store, so that it receives each event
+// once even when several replicas run; an event is only sent again if its
+// delivery cannot be acknowledged, such as when the service stops between
+// the two. A failing sink is retried with exponential backoff, without
+// holding up the others.
+type Dispatcher struct {
+	store     config.OutboxStore
+	sinks     []Sink
+	retention time.Duration
+	owner     string
+	logger    *zap.Logger
+}
+
+// NewDispatcher creates a new Dispatcher delivering the outbox of store to
+// sinks. Entries are pruned once older than retention, delivered or not.
+func NewDispatcher(store config.OutboxStore, sinks []Sink, retention time.Duration, logger *zap.Logger) *Dispatcher {
+	return &Dispatcher{
+		store:     store,
+		sinks:     sinks,
+		retention: retention,
+		owner:     newOwner(),
+		logger:    logger,
+	}
+}
+
+// newOwner returns a lease owner name unique to this process.
+func newOwner() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// Run delivers the outbox to every sink until ctx is cancelled. Sinks are
+// woken by events published to broker, and poll for the others.
+func (d *Dispatcher) Run(ctx context.Context, broker *events.Broker) {
+	for _, sink := range d.sinks {
+		go d.run(ctx, broker, sink)
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if n, err := d.store.PruneOutbox(time.Now().Add(-d.retention)); err != nil {
+			d.logger.Warn("failed to prune outbox", zap.Error(err))
+		} else if n > 0 {
+			d.logger.Info("pruned outbox", zap.Int64("entries", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run delivers the outbox to sink until ctx is cancelled.
+func (d *Dispatcher) run(ctx context.Context, broker *events.Broker, sink Sink) {
+	sub := broker.Subscribe(configEvents, 64)
+	defer func() { broker.Unsubscribe(sub) }()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		more, err := d.deliver(ctx, sink)
+		if err != nil && ctx.Err() == nil {
+			d.logger.Warn("failed to deliver outbox", zap.String("sink", sink.Name()), zap.Error(err))
+		}
+		if more && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case _, open := <-sub.C:
+			if !open {
+				// The broker dropped us for falling behind
+				sub = broker.Subscribe(configEvents, 64)
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver sends sink the next batch of entries, if this replica holds its
+// lease and it is not waiting to retry. It reports whether more entries
+// may be waiting.
+func (d *Dispatcher) deliver(ctx context.Context, sink Sink) (bool, error) {
+	state, err := d.store.ClaimOutboxSink(sink.Name(), d.owner, leaseTTL)
+	if err != nil || state == nil {
+		return false, err
+	}
+	if state.NextAttemptAt != nil && time.Now().Before(*state.NextAttemptAt) {
+		return false, nil
+	}
+
+	entries, err := d.store.GetOutbox(state.Delivered, batchSize)
+	if err != nil || len(entries) == 0 {
+		return false, err
+	}
+	batch := make([]events.Event, 0, len(entries))
+	for _, entry := range entries {
+		e, err := events.DecodeOutbox(entry.Payload)
+		if err != nil {
+			// Skipped rather than blocking the sink forever
+			d.logger.Error("failed to decode outbox entry", zap.Uint64("revision", entry.Revision), zap.Error(err))
+			continue
+		}
+		batch = append(batch, e)
+	}
+	last := entries[len(entries)-1].Revision
+
+	if len(batch) > 0 {
+		if err := sink.Deliver(ctx, batch); err != nil {
+			if ctx.Err() != nil {
+				return false, err
+			}
+			message := err.Error()
+			if len(message) > maxErrorLength {
+				message = message[:maxErrorLength]
+			}
+			if ferr := d.store.FailOutboxSink(sink.Name(), d.owner, message, time.Now().Add(backoff(state.Attempts))); ferr != nil {
+				d.logger.Warn("failed to record outbox failure", zap.String("sink", sink.Name()), zap.Error(ferr))
+			}
+			return false, fmt.Errorf("revisions %d to %d: %w", entries[0].Revision, last, err)
+		}
+	}
+
+	acked, err := d.store.AckOutboxSink(sink.Name(), d.owner, last)
+	if err != nil {
+		return false, err
+	}
+	if !acked {
+		return false, fmt.Errorf("lease lost before acknowledging revisions %d to %d; they may be delivered again", entries[0].Revision, last)
+	}
+	return len(entries) == batchSize, nil
+}
+
+// backoff returns the delay before the next attempt after failures
+// attempts already failed.
+func backoff(failures int) time.Duration {
+	delay := time.Second
+	for i := 0; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
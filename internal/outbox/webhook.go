@@ -0,0 +1,85 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+)
+
+// WebhookSink posts each event as JSON to a URL, with its revision in the
+// X-Event-ID header for the receiver to drop duplicates: after a failure,
+// events already accepted are posted again along with the rest of their
+// batch.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink creates a new WebhookSink posting to url.
+func NewWebhookSink(url string) (*WebhookSink, error) {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return nil, fmt.Errorf("event webhook %q: url must be http(s)", url)
+	}
+	return &WebhookSink{url: url, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// Name implements Sink. It is derived from the URL, which may hold a
+// secret token.
+func (s *WebhookSink) Name() string {
+	sum := sha256.Sum256([]byte(s.url))
+	return "webhook-" + hex.EncodeToString(sum[:8])
+}
+
+// Deliver implements Sink, posting the events one by one and stopping at
+// the first one not accepted.
+func (s *WebhookSink) Deliver(ctx context.Context, batch []events.Event) error {
+	for _, e := range batch {
+		if err := s.post(ctx, e); err != nil {
+			return fmt.Errorf("revision %d: %w", e.Revision, err)
+		}
+	}
+	return nil
+}
+
+func (s *WebhookSink) post(ctx context.Context, e events.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatUint(e.Revision, 10))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The URL may hold a token, and the error is recorded
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			return uerr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
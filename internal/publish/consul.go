@@ -0,0 +1,176 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConsulConfig configures a ConsulClient.
+type ConsulConfig struct {
+	// Addr is the base URL of the Consul agent, e.g.
+	// "http://127.0.0.1:8500".
+	Addr string
+	// Prefix is prepended to every key written.
+	Prefix string
+	// Token is the ACL token, if ACLs are enabled.
+	Token string
+	// Datacenter is the datacenter written to; empty for the agent's.
+	Datacenter string
+}
+
+// ConsulClient is a minimal client for the Consul KV API, covering what
+// publishing needs. Keys are laid out as by EtcdClient. Writes
+// check-and-set the revision key, so that a replica compiling a stale
+// config never overwrites a newer revision.
+type ConsulClient struct {
+	cfg    ConsulConfig
+	base   *url.URL
+	client *http.Client
+}
+
+// NewConsulClient creates a new ConsulClient.
+func NewConsulClient(cfg ConsulConfig) (*ConsulClient, error) {
+	base, err := url.Parse(strings.TrimRight(cfg.Addr, "/"))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid Consul address %q", cfg.Addr)
+	}
+	if strings.HasPrefix(cfg.Prefix, "/") {
+		return nil, fmt.Errorf("Consul key prefix must not start with /")
+	}
+
+	return &ConsulClient{
+		cfg:    cfg,
+		base:   base,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Name implements Target.
+func (c *ConsulClient) Name() string {
+	return "consul"
+}
+
+// errStaleRevision is returned when a newer revision was published than
+// the one being written.
+var errStaleRevision = errors.New("a newer revision is already published")
+
+// Publish implements Target, writing the config and its revision in one
+// transaction that fails if the revision key changed since it was read.
+// A revision older than the one published is not written.
+func (c *ConsulClient) Publish(ctx context.Context, cluster string, revision uint64, payload []byte) error {
+	dir := c.cfg.Prefix
+	if cluster != "" {
+		dir += "clusters/" + cluster + "/"
+	}
+
+	published, index, err := c.revision(ctx, dir+"revision")
+	if err != nil {
+		return err
+	}
+	if published > revision {
+		return fmt.Errorf("revision %d: %w (%d)", revision, errStaleRevision, published)
+	}
+
+	type kv struct {
+		Verb  string `json:"Verb"`
+		Key   string `json:"Key"`
+		Value string `json:"Value"`
+		Index uint64 `json:"Index,omitempty"`
+	}
+	type op struct {
+		KV kv `json:"KV"`
+	}
+	// Index 0 with cas only sets the key if it does not exist yet
+	ops := []op{
+		{KV: kv{Verb: "cas", Key: dir + "revision", Value: encode(strconv.FormatUint(revision, 10)), Index: index}},
+		{KV: kv{Verb: "set", Key: dir + "config", Value: base64.StdEncoding.EncodeToString(payload)}},
+	}
+	body, err := json.Marshal(ops)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.do(ctx, http.MethodPut, "/v1/txn", body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	switch res.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusConflict:
+		return fmt.Errorf("revision %d: %s changed concurrently, retrying later", revision, dir+"revision")
+	default:
+		return fmt.Errorf("consul: %s: %s", res.Status, strings.TrimSpace(string(data)))
+	}
+}
+
+// revision returns the revision published under key and the index to
+// check-and-set it with, both 0 if there is none.
+func (c *ConsulClient) revision(ctx context.Context, key string) (uint64, uint64, error) {
+	res, err := c.do(ctx, http.MethodGet, "/v1/kv/"+escapeKey(key), nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return 0, 0, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+		return 0, 0, fmt.Errorf("consul: get %s: %s: %s", key, res.Status, strings.TrimSpace(string(data)))
+	}
+
+	var entries []struct {
+		ModifyIndex uint64 `json:"ModifyIndex"`
+		Value       []byte `json:"Value"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&entries); err != nil {
+		return 0, 0, err
+	}
+	if len(entries) == 0 {
+		return 0, 0, nil
+	}
+	// A value that is not a revision is overwritten
+	revision, _ := strconv.ParseUint(string(entries[0].Value), 10, 64)
+	return revision, entries[0].ModifyIndex, nil
+}
+
+// do sends a request to the agent with the ACL token and datacenter.
+func (c *ConsulClient) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	u := c.base.String() + path
+	if c.cfg.Datacenter != "" {
+		u += "?dc=" + url.QueryEscape(c.cfg.Datacenter)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.cfg.Token != "" {
+		req.Header.Set("X-Consul-Token", c.cfg.Token)
+	}
+	return c.client.Do(req)
+}
+
+// escapeKey escapes each segment of a key for use in a URL path.
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
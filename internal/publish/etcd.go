@@ -0,0 +1,214 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EtcdConfig configures an EtcdClient.
+type EtcdConfig struct {
+	// Endpoints are the base URLs of the etcd members, e.g.
+	// "https://etcd-0:2379". They are tried in order.
+	Endpoints []string
+	// Prefix is prepended to every key written.
+	Prefix string
+	// Username and Password are set if etcd has authentication enabled.
+	Username string
+	Password string
+}
+
+// EtcdClient is a minimal client for the etcd v3 API over its JSON
+// gateway, covering what publishing needs: writing keys in a transaction.
+// The config of gateways outside any cluster is written to <prefix>config
+// and its revision to <prefix>revision; that of a cluster to
+// <prefix>clusters/<cluster>/config and .../revision.
+type EtcdClient struct {
+	cfg       EtcdConfig
+	endpoints []*url.URL
+	client    *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewEtcdClient creates a new EtcdClient.
+func NewEtcdClient(cfg EtcdConfig) (*EtcdClient, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, fmt.Errorf("etcd endpoints are required")
+	}
+	endpoints := make([]*url.URL, len(cfg.Endpoints))
+	for i, e := range cfg.Endpoints {
+		u, err := url.Parse(strings.TrimRight(strings.TrimSpace(e), "/"))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid etcd endpoint %q", e)
+		}
+		endpoints[i] = u
+	}
+
+	return &EtcdClient{
+		cfg:       cfg,
+		endpoints: endpoints,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Name implements Target.
+func (c *EtcdClient) Name() string {
+	return "etcd"
+}
+
+// Publish implements Target, writing the config and its revision in one
+// transaction so that watchers never see one without the other.
+func (c *EtcdClient) Publish(ctx context.Context, cluster string, revision uint64, payload []byte) error {
+	dir := c.cfg.Prefix
+	if cluster != "" {
+		dir += "clusters/" + cluster + "/"
+	}
+
+	type put struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	type op struct {
+		RequestPut put `json:"request_put"`
+	}
+	txn := struct {
+		Success []op `json:"success"`
+	}{Success: []op{
+		{RequestPut: put{Key: encode(dir + "config"), Value: base64.StdEncoding.EncodeToString(payload)}},
+		{RequestPut: put{Key: encode(dir + "revision"), Value: encode(strconv.FormatUint(revision, 10))}},
+	}}
+
+	var resp struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	if err := c.call(ctx, "/v3/kv/txn", txn, &resp); err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf("etcd transaction failed")
+	}
+	return nil
+}
+
+// errUnauthenticated is returned by do when etcd rejects the auth token.
+var errUnauthenticated = errors.New("etcd: unauthenticated")
+
+// call posts req to path on the first endpoint that answers, decoding the
+// response into resp. An expired auth token is renewed once.
+func (c *EtcdClient) call(ctx context.Context, path string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, endpoint := range c.endpoints {
+		lastErr = c.do(ctx, endpoint, path, body, resp)
+		if errors.Is(lastErr, errUnauthenticated) && c.cfg.Username != "" {
+			c.setToken("")
+			lastErr = c.do(ctx, endpoint, path, body, resp)
+		}
+		if lastErr == nil || ctx.Err() != nil {
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+// do posts body to path on endpoint, authenticating first if needed.
+func (c *EtcdClient) do(ctx context.Context, endpoint *url.URL, path string, body []byte, resp interface{}) error {
+	token := ""
+	if c.cfg.Username != "" {
+		var err error
+		if token, err = c.authenticate(ctx, endpoint); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String()+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode == http.StatusUnauthorized {
+		return errUnauthenticated
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("etcd %s: %s: %s", endpoint.Host, res.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, resp)
+}
+
+// authenticate returns the auth token, requesting one from endpoint if
+// there is none yet.
+func (c *EtcdClient) authenticate(ctx context.Context, endpoint *url.URL) (string, error) {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+	if token != "" {
+		return token, nil
+	}
+
+	body, err := json.Marshal(map[string]string{"name": c.cfg.Username, "password": c.cfg.Password})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String()+"/v3/auth/authenticate", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("etcd %s: authenticate: %s", endpoint.Host, res.Status)
+	}
+	var auth struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&auth); err != nil {
+		return "", err
+	}
+	c.setToken(auth.Token)
+	return auth.Token, nil
+}
+
+func (c *EtcdClient) setToken(token string) {
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+}
+
+// encode base64-encodes s, as the JSON gateway expects keys and values.
+func encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
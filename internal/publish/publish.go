@@ -0,0 +1,187 @@
+// Package publish mirrors the compiled gateway config into key-value
+// stores that gateways already watch, such as etcd, as a way to distribute
+// config besides the pull and subscribe APIs.
+package publish
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+)
+
+// configEvents selects the changes that may change the compiled config.
+var configEvents = events.Filter{Types: map[string]bool{events.TypeBackend: true, events.TypeRoute: true, events.TypeDescriptor: true, events.TypeSchema: true}}
+
+// Target is a key-value store the compiled config is written to.
+type Target interface {
+	// Name identifies the target in logs, e.g. "etcd".
+	Name() string
+	// Publish writes the encoded config of cluster, "" for gateways
+	// outside any cluster, along with its revision.
+	Publish(ctx context.Context, cluster string, revision uint64, payload []byte) error
+}
+
+// Publish states of a cluster's config.
+const (
+	StatePending   = "pending"   // not yet published
+	StatePublished = "published" // the last attempt succeeded
+	StateFailing   = "failing"   // the last attempt failed
+)
+
+// Status is the state of a publisher.
+type Status struct {
+	Target   string          `json:"target"`
+	Clusters []ClusterStatus `json:"clusters"`
+}
+
+// ClusterStatus is the state of the config of a cluster at a target.
+type ClusterStatus struct {
+	// Cluster is "" for gateways outside any cluster.
+	Cluster string `json:"cluster"`
+	State   string `json:"state"`
+	// Revision, SHA256 and PublishedAt describe the config last written.
+	Revision    uint64     `json:"revision,omitempty"`
+	SHA256      string     `json:"sha256,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	// LastError is the error of the last failed attempt, kept after later
+	// attempts succeed.
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// PayloadFunc returns the encoded config served to gateways of cluster
+// that do not identify themselves.
+type PayloadFunc func(ctx context.Context, cluster string) ([]byte, error)
+
+// Publisher writes the config of each cluster to a target whenever it
+// changes. It is told of changes made through this service by its event
+// broker, and recompiles every interval besides to pick up changes made
+// elsewhere, such as by other replicas or through a config rollout ending.
+// Gateways reading the target cannot be told apart as rollout canaries, so
+// while a rollout is in progress they get its baseline.
+type Publisher struct {
+	target   Target
+	payload  PayloadFunc
+	clusters []string
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu     sync.Mutex
+	status map[string]*ClusterStatus
+}
+
+// NewPublisher creates a new Publisher writing to target the config of
+// gateways outside any cluster and that of each of clusters.
+func NewPublisher(target Target, payload PayloadFunc, clusters []string, interval time.Duration, logger *zap.Logger) *Publisher {
+	p := &Publisher{
+		target:   target,
+		payload:  payload,
+		clusters: append([]string{""}, clusters...),
+		interval: interval,
+		logger:   logger,
+		status:   map[string]*ClusterStatus{},
+	}
+	for _, cluster := range p.clusters {
+		p.status[cluster] = &ClusterStatus{Cluster: cluster, State: StatePending}
+	}
+	return p
+}
+
+// Status returns the state of the publisher.
+func (p *Publisher) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := Status{Target: p.target.Name(), Clusters: make([]ClusterStatus, len(p.clusters))}
+	for i, cluster := range p.clusters {
+		status.Clusters[i] = *p.status[cluster]
+	}
+	return status
+}
+
+// Run publishes the config on start, then whenever it changes, until ctx
+// is cancelled.
+func (p *Publisher) Run(ctx context.Context, broker *events.Broker) {
+	sub := broker.Subscribe(configEvents, 64)
+	defer func() { broker.Unsubscribe(sub) }()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, cluster := range p.clusters {
+			if err := p.publish(ctx, cluster); err != nil && ctx.Err() == nil {
+				// Retried on the next change or interval
+				p.logger.Warn("failed to publish config", zap.String("target", p.target.Name()), zap.String("cluster", cluster), zap.Error(err))
+				p.failed(cluster, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case _, open := <-sub.C:
+			if !open {
+				// The broker dropped us for falling behind
+				sub = broker.Subscribe(configEvents, 64)
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// publish writes the config of cluster unless it is the one last written.
+func (p *Publisher) publish(ctx context.Context, cluster string) error {
+	payload, err := p.payload(ctx, cluster)
+	if err != nil {
+		return fmt.Errorf("compile config: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	encoded := hex.EncodeToString(sum[:])
+	p.mu.Lock()
+	current := p.status[cluster].State == StatePublished && p.status[cluster].SHA256 == encoded
+	p.mu.Unlock()
+	if current {
+		return nil
+	}
+
+	var header struct {
+		Revision uint64 `json:"revision"`
+	}
+	if err := json.Unmarshal(payload, &header); err != nil {
+		return fmt.Errorf("decode config: %w", err)
+	}
+	if err := p.target.Publish(ctx, cluster, header.Revision, payload); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	p.mu.Lock()
+	status := p.status[cluster]
+	status.State = StatePublished
+	status.Revision = header.Revision
+	status.SHA256 = encoded
+	status.PublishedAt = &now
+	p.mu.Unlock()
+	p.logger.Info("config published", zap.String("target", p.target.Name()), zap.String("cluster", cluster), zap.Uint64("revision", header.Revision))
+	return nil
+}
+
+// failed records that publishing the config of cluster failed.
+func (p *Publisher) failed(cluster string, err error) {
+	now := time.Now().UTC()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := p.status[cluster]
+	status.State = StateFailing
+	status.LastError = err.Error()
+	status.LastErrorAt = &now
+}
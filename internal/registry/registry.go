@@ -0,0 +1,43 @@
+// Package registry keeps track of the running gateway instances that
+// register with the service and send heartbeats.
+package registry
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// MissedHeartbeats is how many heartbeat intervals may pass without a
+// sign of life before a gateway instance is considered offline.
+const MissedHeartbeats = 3
+
+// Online reports whether a gateway instance sending heartbeats every
+// interval is still considered running at now.
+func Online(g *config.GatewayInstance, interval time.Duration, now time.Time) bool {
+	return now.Sub(g.LastSeenAt) <= MissedHeartbeats*interval
+}
+
+// Prune deletes gateway instances not seen for longer than retention once
+// an hour until ctx is cancelled.
+func Prune(ctx context.Context, store config.GatewayStore, retention time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if n, err := store.PruneGateways(time.Now().Add(-retention)); err != nil {
+			logger.Warn("failed to prune gateway instances", zap.Error(err))
+		} else if n > 0 {
+			logger.Debug("pruned gateway instances", zap.Int64("count", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package schema checks the request and response schemas attached to
+// routes before they are stored, and the JSON Schemas of plugins.
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// MaxSize bounds an inline JSON Schema document.
+const MaxSize = 64 << 10
+
+// Check verifies that ref names exactly one schema and that it is valid: a
+// well-formed JSON Schema document, or a message defined in files, the
+// descriptors of the route's backend, which is nil if it has none. It
+// returns why the schema is invalid, or "" if it is valid; an error means
+// the check could not be made.
+func Check(ctx context.Context, ref *config.SchemaRef, files *protoregistry.Files) (string, error) {
+	hasSchema := len(bytes.TrimSpace(ref.JSONSchema)) > 0
+	switch {
+	case hasSchema && ref.Message != "":
+		return "json_schema and message are mutually exclusive", nil
+	case hasSchema:
+		return checkJSONSchema(ctx, ref.JSONSchema)
+	case ref.Message != "":
+		return checkMessage(ref.Message, files), nil
+	}
+	return "json_schema or message is required", nil
+}
+
+// CheckDocument verifies that doc is a well-formed JSON Schema document.
+// It returns why it is not, or "" if it is; an error means the check could
+// not be made.
+func CheckDocument(ctx context.Context, doc json.RawMessage) (string, error) {
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return "json_schema is required", nil
+	}
+	return checkJSONSchema(ctx, doc)
+}
+
+func checkJSONSchema(ctx context.Context, doc json.RawMessage) (string, error) {
+	if len(doc) > MaxSize {
+		return fmt.Sprintf("json_schema too large (at most %d bytes)", MaxSize), nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(doc, &value); err != nil {
+		return "json_schema is not valid JSON: " + err.Error(), nil
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		return "json_schema must be an object", nil
+	}
+	return verify(ctx, value)
+}
+
+func checkMessage(name string, files *protoregistry.Files) string {
+	if files == nil {
+		return fmt.Sprintf("message %s cannot be resolved: the backend has no descriptor set", name)
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return fmt.Sprintf("message %s is not defined in the backend's descriptor set", name)
+	}
+	if _, ok := desc.(protoreflect.MessageDescriptor); !ok {
+		return fmt.Sprintf("%s is not a message", name)
+	}
+	return ""
+}
+
+// verifier is the Rego query checking JSON Schema documents with OPA's
+// json.verify_schema, prepared on first use. Remote $refs are not fetched.
+var verifier struct {
+	once  sync.Once
+	query rego.PreparedEvalQuery
+	err   error
+}
+
+func verify(ctx context.Context, doc interface{}) (string, error) {
+	verifier.once.Do(func() {
+		caps := ast.CapabilitiesForThisVersion()
+		caps.AllowNet = []string{}
+		verifier.query, verifier.err = rego.New(
+			rego.Query("result := json.verify_schema(input)"),
+			rego.Capabilities(caps),
+		).PrepareForEval(context.Background())
+	})
+	if verifier.err != nil {
+		return "", fmt.Errorf("prepare schema verification: %w", verifier.err)
+	}
+
+	results, err := verifier.query.Eval(ctx, rego.EvalInput(doc))
+	if err != nil {
+		return "", fmt.Errorf("verify json_schema: %w", err)
+	}
+	if len(results) == 0 {
+		return "", errors.New("verify json_schema: no result")
+	}
+	result, ok := results[0].Bindings["result"].([]interface{})
+	if !ok || len(result) != 2 {
+		return "", errors.New("verify json_schema: unexpected result")
+	}
+	if valid, _ := result[0].(bool); !valid {
+		msg, _ := result[1].(string)
+		return "invalid json_schema: " + strings.TrimPrefix(msg, "jsonschema: "), nil
+	}
+	return "", nil
+}
+
+// matcher is the Rego query matching documents against JSON Schemas with
+// OPA's json.match_schema, prepared on first use.
+var matcher struct {
+	once  sync.Once
+	query rego.PreparedEvalQuery
+	err   error
+}
+
+// Match validates value against the JSON Schema doc, which CheckDocument
+// accepted. It returns why value does not match, or "" if it does; an
+// error means the check could not be made.
+func Match(ctx context.Context, doc, value json.RawMessage) (string, error) {
+	matcher.once.Do(func() {
+		caps := ast.CapabilitiesForThisVersion()
+		caps.AllowNet = []string{}
+		matcher.query, matcher.err = rego.New(
+			rego.Query("result := json.match_schema(input.value, input.schema)"),
+			rego.Capabilities(caps),
+		).PrepareForEval(context.Background())
+	})
+	if matcher.err != nil {
+		return "", fmt.Errorf("prepare schema matching: %w", matcher.err)
+	}
+
+	if !json.Valid(value) {
+		return "not valid JSON", nil
+	}
+
+	// Both are passed as JSON text: json.match_schema would take a string
+	// value for JSON text rather than a string.
+	results, err := matcher.query.Eval(ctx, rego.EvalInput(map[string]interface{}{
+		"schema": string(doc),
+		"value":  string(value),
+	}))
+	if err != nil {
+		return "", fmt.Errorf("match json_schema: %w", err)
+	}
+	if len(results) == 0 {
+		return "", errors.New("match json_schema: no result")
+	}
+	result, ok := results[0].Bindings["result"].([]interface{})
+	if !ok || len(result) != 2 {
+		return "", errors.New("match json_schema: unexpected result")
+	}
+	if valid, _ := result[0].(bool); valid {
+		return "", nil
+	}
+	var reasons []string
+	details, _ := result[1].([]interface{})
+	for _, d := range details {
+		detail, _ := d.(map[string]interface{})
+		desc, _ := detail["desc"].(string)
+		if field, _ := detail["field"].(string); field != "" && !strings.EqualFold(field, "(root)") {
+			desc = field + ": " + desc
+		}
+		if desc != "" {
+			reasons = append(reasons, desc)
+		}
+	}
+	if len(reasons) == 0 {
+		return "does not match the schema", nil
+	}
+	return strings.Join(reasons, "; "), nil
+}
@@ -0,0 +1,73 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Cipher encrypts and decrypts small secret values for storage. Values are
+// bound to additional data, such as where they are stored: they only
+// decrypt with the same additional data.
+type Cipher interface {
+	Encrypt(plaintext string, additionalData []byte) (string, error)
+	Decrypt(ciphertext string, additionalData []byte) (string, error)
+}
+
+// aesGCM is a Cipher using AES-256-GCM with a random nonce per value. The
+// stored form is base64(nonce || ciphertext).
+type aesGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM creates a Cipher from a base64-encoded 32-byte key.
+func NewAESGCM(encodedKey string) (Cipher, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGCM{aead: aead}, nil
+}
+
+// Encrypt implements Cipher.
+func (c *aesGCM) Encrypt(plaintext string, additionalData []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), additionalData)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt implements Cipher.
+func (c *aesGCM) Decrypt(ciphertext string, additionalData []byte) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < c.aead.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	nonce, sealed := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
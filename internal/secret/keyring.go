@@ -0,0 +1,140 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// versionPrefix marks values written by a Keyring: "v2:<key-id>:<payload>",
+// bound to their additional data. Values marked with unboundPrefix were
+// written before values were bound, and those without a prefix by a
+// single-key Cipher: both decrypt whatever the additional data, the latter
+// with the "default" key if present, otherwise the primary key.
+const (
+	versionPrefix = "v2:"
+	unboundPrefix = "v1:"
+)
+
+// legacyKeyID is the key ID given to ADMIN_ENCRYPTION_KEY.
+const legacyKeyID = "default"
+
+// Keyring is a Cipher holding several named keys. New values are always
+// encrypted with the primary key; any key in the ring can decrypt, which
+// allows keys to be rotated without downtime.
+type Keyring struct {
+	primary string
+	keys    map[string]Cipher
+}
+
+// NewKeyring parses a comma-separated list of "id:base64key" entries. The
+// first entry is the primary key.
+func NewKeyring(spec string) (*Keyring, error) {
+	k := &Keyring{keys: map[string]Cipher{}}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid key entry %q (want id:base64key)", entry)
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+		c, err := NewAESGCM(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		k.keys[id] = c
+		if k.primary == "" {
+			k.primary = id
+		}
+	}
+
+	if k.primary == "" {
+		return nil, errors.New("keyring is empty")
+	}
+	return k, nil
+}
+
+// LoadKeyring builds a Keyring from the environment. ADMIN_ENCRYPTION_KEYS
+// (or a file named by ADMIN_ENCRYPTION_KEYS_FILE, e.g. a KMS- or
+// Vault-rendered secret) takes precedence over the single-key
+// ADMIN_ENCRYPTION_KEY. It returns nil if no key is configured.
+func LoadKeyring() (*Keyring, error) {
+	if path := os.Getenv("ADMIN_ENCRYPTION_KEYS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewKeyring(strings.ReplaceAll(strings.TrimSpace(string(data)), "\n", ","))
+	}
+	if spec := os.Getenv("ADMIN_ENCRYPTION_KEYS"); spec != "" {
+		return NewKeyring(spec)
+	}
+	if key := os.Getenv("ADMIN_ENCRYPTION_KEY"); key != "" {
+		return NewKeyring(legacyKeyID + ":" + key)
+	}
+	return nil, nil
+}
+
+// PrimaryKeyID returns the ID of the key used for new values.
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+// Encrypt implements Cipher.
+func (k *Keyring) Encrypt(plaintext string, additionalData []byte) (string, error) {
+	sealed, err := k.keys[k.primary].Encrypt(plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return versionPrefix + k.primary + ":" + sealed, nil
+}
+
+// Decrypt implements Cipher.
+func (k *Keyring) Decrypt(ciphertext string, additionalData []byte) (string, error) {
+	id, payload, bound, err := k.split(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	c, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("unknown encryption key %q", id)
+	}
+	if !bound {
+		additionalData = nil
+	}
+	return c.Decrypt(payload, additionalData)
+}
+
+// NeedsRotation reports whether a stored value was encrypted with a key
+// other than the primary key, or predates key IDs or additional data.
+func (k *Keyring) NeedsRotation(ciphertext string) bool {
+	id, _, bound, err := k.split(ciphertext)
+	return err == nil && (!bound || id != k.primary)
+}
+
+// split returns the key ID and payload of a stored value, and whether it
+// is bound to additional data.
+func (k *Keyring) split(ciphertext string) (id, payload string, bound bool, err error) {
+	rest, bound := strings.CutPrefix(ciphertext, versionPrefix)
+	if !bound {
+		var ok bool
+		if rest, ok = strings.CutPrefix(ciphertext, unboundPrefix); !ok {
+			if _, ok := k.keys[legacyKeyID]; ok {
+				return legacyKeyID, ciphertext, false, nil
+			}
+			return k.primary, ciphertext, false, nil
+		}
+	}
+	id, payload, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", "", false, errors.New("malformed ciphertext")
+	}
+	return id, payload, bound, nil
+}
@@ -0,0 +1,43 @@
+// Package seed loads a sample configuration into an empty or existing
+// environment.
+package seed
+
+import (
+	_ "embed"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/apply"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+//go:embed sample.yaml
+var sample []byte
+
+// Reason is recorded in history for seeded resources.
+const Reason = "seed sample data"
+
+// Document returns the sample configuration.
+func Document() (*apply.Document, error) {
+	doc, err := apply.ParseDocument(sample)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Plan computes the changes needed to seed store. Seeding only adds
+// resources: backends and routes that already exist, including disabled
+// ones, are left untouched, and nothing is deleted.
+func Plan(store config.Store) (*apply.Plan, error) {
+	doc, err := Document()
+	if err != nil {
+		return nil, err
+	}
+	plan, err := apply.ComputePlan(store, doc)
+	if err != nil {
+		return nil, err
+	}
+	return plan.CreatesOnly(), nil
+}
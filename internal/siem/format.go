@@ -0,0 +1,197 @@
+package siem
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats.
+const (
+	FormatCEF  = "cef"
+	FormatLEEF = "leef"
+)
+
+// Product identification in the CEF and LEEF headers.
+const (
+	vendor  = "AssistantGateway"
+	product = "GatewayAdmin"
+	version = "1.0"
+)
+
+// Audit record fields, named independently of the format. The field
+// mapping assigns each the CEF or LEEF key it is sent under.
+const (
+	FieldCategory     = "category"
+	FieldOutcome      = "outcome"
+	FieldOperator     = "operator"
+	FieldRole         = "role"
+	FieldSourceIP     = "source_ip"
+	FieldMethod       = "method"
+	FieldPath         = "path"
+	FieldStatus       = "status"
+	FieldResourceType = "resource_type"
+	FieldResourceID   = "resource_id"
+	FieldBackend      = "backend"
+	FieldReason       = "reason"
+	FieldRevision     = "revision"
+	FieldEnvironment  = "environment"
+)
+
+// fieldOrder is the order fields appear in a message.
+var fieldOrder = []string{
+	FieldCategory, FieldOutcome, FieldOperator, FieldRole, FieldSourceIP, FieldMethod, FieldPath, FieldStatus,
+	FieldResourceType, FieldResourceID, FieldBackend, FieldReason, FieldRevision, FieldEnvironment,
+}
+
+// defaultMappings are the keys each field is sent under by default: CEF
+// dictionary keys where one fits, and custom strings and numbers (labelled
+// with the field name) otherwise.
+var defaultMappings = map[string]map[string]string{
+	FormatCEF: {
+		FieldCategory:     "cat",
+		FieldOutcome:      "outcome",
+		FieldOperator:     "suser",
+		FieldRole:         "spriv",
+		FieldSourceIP:     "src",
+		FieldMethod:       "requestMethod",
+		FieldPath:         "request",
+		FieldStatus:       "cn1",
+		FieldResourceType: "cs1",
+		FieldResourceID:   "cs2",
+		FieldBackend:      "cs3",
+		FieldReason:       "reason",
+		FieldRevision:     "cn2",
+		FieldEnvironment:  "cs4",
+	},
+	FormatLEEF: {
+		FieldCategory:     "cat",
+		FieldOutcome:      "outcome",
+		FieldOperator:     "usrName",
+		FieldRole:         "role",
+		FieldSourceIP:     "src",
+		FieldMethod:       "method",
+		FieldPath:         "url",
+		FieldStatus:       "status",
+		FieldResourceType: "resourceType",
+		FieldResourceID:   "resourceId",
+		FieldBackend:      "backend",
+		FieldReason:       "reason",
+		FieldRevision:     "revision",
+		FieldEnvironment:  "environment",
+	},
+}
+
+var (
+	// extensionKey matches the keys fields may be mapped to.
+	extensionKey = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,62}$`)
+	// cefCustomKey matches CEF custom fields, which take a label.
+	cefCustomKey = regexp.MustCompile(`^c(s[1-6]|n[1-3])$`)
+)
+
+// parseMapping returns the default field mapping of format with the
+// overrides of a comma-separated list of field:key pairs applied. A key
+// of "-" leaves the field out.
+func parseMapping(format, overrides string) (map[string]string, error) {
+	defaults, ok := defaultMappings[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown SIEM format %q (must be %q or %q)", format, FormatCEF, FormatLEEF)
+	}
+	mapping := make(map[string]string, len(defaults))
+	for field, key := range defaults {
+		mapping[field] = key
+	}
+
+	for _, item := range strings.Split(overrides, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		field, key, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("SIEM field mapping %q: want field:key", item)
+		}
+		if _, known := defaults[field]; !known {
+			return nil, fmt.Errorf("SIEM field mapping %q: unknown field %q", item, field)
+		}
+		if key == "-" {
+			delete(mapping, field)
+			continue
+		}
+		if !extensionKey.MatchString(key) {
+			return nil, fmt.Errorf("SIEM field mapping %q: invalid key %q", item, key)
+		}
+		mapping[field] = key
+	}
+	return mapping, nil
+}
+
+// Record is one audit event.
+type Record struct {
+	Time time.Time
+	// ID classifies the event, e.g. "route.update" or "auth.forbidden".
+	ID   string
+	Name string
+	// Severity ranges from 0 (lowest) to 10.
+	Severity int
+	// Fields holds the values of the audit fields set for the event.
+	Fields map[string]string
+}
+
+// formatCEF encodes rec as a CEF message.
+func formatCEF(rec *Record, mapping map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|%s|%s|%s|%s|%s|%d|", cefHeader(vendor), cefHeader(product), cefHeader(version),
+		cefHeader(rec.ID), cefHeader(rec.Name), rec.Severity)
+	b.WriteString("rt=" + strconv.FormatInt(rec.Time.UnixMilli(), 10))
+	for _, field := range fieldOrder {
+		value, key := rec.Fields[field], mapping[field]
+		if value == "" || key == "" {
+			continue
+		}
+		b.WriteString(" " + key + "=" + cefValue(value))
+		if cefCustomKey.MatchString(key) {
+			b.WriteString(" " + key + "Label=" + field)
+		}
+	}
+	return b.String()
+}
+
+// formatLEEF encodes rec as a LEEF 2.0 message, with ^ separating the
+// attributes.
+func formatLEEF(rec *Record, mapping map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "LEEF:2.0|%s|%s|%s|%s|^|", leefValue(vendor), leefValue(product), leefValue(version), leefValue(rec.ID))
+	b.WriteString("devTime=" + rec.Time.UTC().Format("Jan 02 2006 15:04:05.000 MST"))
+	b.WriteString("^devTimeFormat=MMM dd yyyy HH:mm:ss.SSS z")
+	b.WriteString("^sev=" + strconv.Itoa(rec.Severity))
+	for _, field := range fieldOrder {
+		value, key := rec.Fields[field], mapping[field]
+		if value == "" || key == "" {
+			continue
+		}
+		b.WriteString("^" + key + "=" + leefValue(value))
+	}
+	return b.String()
+}
+
+var (
+	cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefValueEscaper  = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+	// LEEF has no escapes, so the delimiters are replaced
+	leefEscaper = strings.NewReplacer("^", " ", "|", " ", "\t", " ", "\r", " ", "\n", " ")
+)
+
+func cefHeader(s string) string {
+	return cefHeaderEscaper.Replace(s)
+}
+
+func cefValue(s string) string {
+	return cefValueEscaper.Replace(s)
+}
+
+func leefValue(s string) string {
+	return leefEscaper.Replace(s)
+}
@@ -0,0 +1,393 @@
+// Package siem streams audit events of the admin plane to a SIEM as syslog
+// messages in CEF or LEEF format: every configuration change, every
+// request refused as unauthenticated or forbidden, the sign ins and
+// lockouts of local users, and anomalous configuration activity.
+package siem
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/auth"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/events"
+)
+
+const (
+	// dialTimeout bounds connecting to the SIEM.
+	dialTimeout = 5 * time.Second
+	// writeTimeout bounds sending a message.
+	writeTimeout = 5 * time.Second
+	// queueSize is the number of request events waiting to be sent before
+	// new ones are dropped.
+	queueSize = 256
+	// appName identifies the service in the syslog header.
+	appName = "gateway-admin"
+	// facility is the syslog facility of the messages (log audit).
+	facility = 13
+)
+
+// configEvents selects the events audited as configuration changes.
+var configEvents = events.Filter{Types: map[string]bool{events.TypeBackend: true, events.TypeRoute: true, events.TypeDescriptor: true, events.TypeSchema: true}}
+
+// Config configures an Exporter.
+type Config struct {
+	// Addr is the syslog receiver, as tcp://host:port, tls://host:port or
+	// udp://host:port.
+	Addr string
+	// Format is FormatCEF or FormatLEEF.
+	Format string
+	// Fields overrides the default field mapping, as comma-separated
+	// field:key pairs; a key of "-" leaves the field out.
+	Fields string
+	// Environment is recorded with every event.
+	Environment string
+}
+
+// Exporter sends audit events to a SIEM over syslog. Configuration
+// changes come from the outbox, when the Exporter is one of its sinks, or
+// else from the broker; refused requests are reported by the HTTP
+// middleware. The connection is opened on first use and again after a
+// failure.
+type Exporter struct {
+	network     string
+	addr        string
+	tls         bool
+	format      string
+	mapping     map[string]string
+	environment string
+	hostname    string
+	requests    chan Record
+	logger      *zap.Logger
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewExporter creates an Exporter from cfg.
+func NewExporter(cfg Config, logger *zap.Logger) (*Exporter, error) {
+	u, err := url.Parse(cfg.Addr)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid SIEM address %q (want tcp://, tls:// or udp://host:port)", cfg.Addr)
+	}
+	e := &Exporter{
+		addr:        u.Host,
+		format:      cfg.Format,
+		environment: cfg.Environment,
+		requests:    make(chan Record, queueSize),
+		logger:      logger,
+	}
+	switch u.Scheme {
+	case "tcp", "udp":
+		e.network = u.Scheme
+	case "tls":
+		e.network, e.tls = "tcp", true
+	default:
+		return nil, fmt.Errorf("invalid SIEM address %q (want tcp://, tls:// or udp://host:port)", cfg.Addr)
+	}
+	if _, _, err := net.SplitHostPort(e.addr); err != nil {
+		return nil, fmt.Errorf("invalid SIEM address %q: %w", cfg.Addr, err)
+	}
+	if e.mapping, err = parseMapping(cfg.Format, cfg.Fields); err != nil {
+		return nil, err
+	}
+	if e.hostname, err = os.Hostname(); err != nil || e.hostname == "" {
+		e.hostname = "-"
+	}
+	return e, nil
+}
+
+// Name implements outbox.Sink.
+func (e *Exporter) Name() string {
+	sum := sha256.Sum256([]byte(e.network + "://" + e.addr))
+	return "siem-" + hex.EncodeToString(sum[:8])
+}
+
+// Deliver implements outbox.Sink, sending the configuration changes of
+// the batch.
+func (e *Exporter) Deliver(ctx context.Context, batch []events.Event) error {
+	for _, ev := range batch {
+		if !configEvents.Match(ev) {
+			continue
+		}
+		rec := e.changeRecord(ev)
+		if err := e.send(&rec); err != nil {
+			return fmt.Errorf("revision %d: %w", ev.Revision, err)
+		}
+	}
+	return nil
+}
+
+// Run sends refused requests and, if broker is not nil, the configuration
+// changes published to it until ctx is cancelled. Pass a nil broker when
+// the Exporter is a sink of the outbox.
+func (e *Exporter) Run(ctx context.Context, broker *events.Broker) {
+	var changes <-chan events.Event
+	var sub *events.Subscription
+	if broker != nil {
+		sub = broker.Subscribe(configEvents, 256)
+		defer func() { broker.Unsubscribe(sub) }()
+		changes = sub.C
+	}
+	defer e.close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case rec := <-e.requests:
+			if err := e.send(&rec); err != nil {
+				e.logger.Warn("failed to send audit event to SIEM", zap.String("event", rec.ID), zap.Error(err))
+			}
+		case ev, ok := <-changes:
+			if !ok {
+				// The broker dropped us for falling behind; subscribe again
+				e.logger.Warn("SIEM export fell behind, some configuration changes were not sent")
+				sub = broker.Subscribe(configEvents, 256)
+				changes = sub.C
+				continue
+			}
+			rec := e.changeRecord(ev)
+			if err := e.send(&rec); err != nil {
+				e.logger.Warn("failed to send audit event to SIEM", zap.String("event", rec.ID), zap.Error(err))
+			}
+		}
+	}
+}
+
+// AuditRequest queues an audit event for a request refused with status,
+// 401 or 403. It never blocks: if the SIEM cannot keep up, the event is
+// dropped.
+func (e *Exporter) AuditRequest(r *http.Request, status int) {
+	rec := Record{
+		Time:     time.Now(),
+		ID:       "auth.forbidden",
+		Name:     "Request forbidden",
+		Severity: 6,
+		Fields: map[string]string{
+			FieldCategory:    "auth",
+			FieldOutcome:     "failure",
+			FieldMethod:      r.Method,
+			FieldPath:        r.URL.Path,
+			FieldStatus:      strconv.Itoa(status),
+			FieldEnvironment: e.environment,
+		},
+	}
+	if status == http.StatusUnauthorized {
+		rec.ID, rec.Name, rec.Severity = "auth.unauthenticated", "Request unauthenticated", 7
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		rec.Fields[FieldSourceIP] = host
+	}
+	if p := auth.FromContext(r.Context()); p != nil {
+		rec.Fields[FieldOperator] = p.Name
+		rec.Fields[FieldRole] = p.Role
+	}
+
+	select {
+	case e.requests <- rec:
+	default:
+		e.logger.Warn("SIEM export queue full, dropped audit event", zap.String("event", rec.ID))
+	}
+}
+
+// AuditAnomaly queues an audit event for unusual configuration activity
+// found by anomaly detection. Like AuditRequest, it never blocks.
+func (e *Exporter) AuditAnomaly(ev events.Event) {
+	rec := Record{
+		Time:     ev.Time,
+		ID:       "anomaly." + ev.Operation,
+		Name:     "Anomalous configuration activity",
+		Severity: 8,
+		Fields: map[string]string{
+			FieldCategory:    "anomaly",
+			FieldOperator:    ev.Operator,
+			FieldReason:      ev.Reason,
+			FieldEnvironment: e.environment,
+		},
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+
+	select {
+	case e.requests <- rec:
+	default:
+		e.logger.Warn("SIEM export queue full, dropped audit event", zap.String("event", rec.ID))
+	}
+}
+
+// loginEvents names the sign in events and gives their severity.
+var loginEvents = map[string]struct {
+	name     string
+	severity int
+}{
+	auth.LoginSucceeded:   {"Sign in succeeded", 3},
+	auth.LoginFailed:      {"Sign in failed", 6},
+	auth.LoginLockedOut:   {"Sign in refused during lockout", 7},
+	auth.AccountLocked:    {"Account locked out", 8},
+	auth.ClientLocked:     {"Client locked out", 8},
+	auth.AccountUnlocked:  {"Account unlocked", 5},
+	auth.TOTPEnabled:      {"Two-factor authentication enabled", 3},
+	auth.TOTPDisabled:     {"Two-factor authentication disabled", 5},
+	auth.RecoveryCodeUsed: {"Recovery code used", 5},
+}
+
+// AuditLogin queues an audit event for a sign in or two-factor
+// authentication event of a local user.
+// Like AuditRequest, it never blocks.
+func (e *Exporter) AuditLogin(r *http.Request, ev auth.LoginEvent) {
+	desc := loginEvents[ev.Type]
+	rec := Record{
+		Time:     time.Now(),
+		ID:       "auth." + ev.Type,
+		Name:     desc.name,
+		Severity: desc.severity,
+		Fields: map[string]string{
+			FieldCategory:     "auth",
+			FieldOutcome:      "failure",
+			FieldOperator:     ev.Operator,
+			FieldResourceType: "user",
+			FieldResourceID:   ev.User,
+			FieldMethod:       r.Method,
+			FieldPath:         r.URL.Path,
+			FieldEnvironment:  e.environment,
+		},
+	}
+	switch ev.Type {
+	case auth.LoginSucceeded, auth.AccountUnlocked, auth.TOTPEnabled, auth.TOTPDisabled, auth.RecoveryCodeUsed:
+		rec.Fields[FieldOutcome] = "success"
+	}
+	if ev.Operator == "" {
+		rec.Fields[FieldOperator] = ev.User
+	}
+	if !ev.LockedUntil.IsZero() {
+		rec.Fields[FieldReason] = "locked until " + ev.LockedUntil.UTC().Format(time.RFC3339)
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		rec.Fields[FieldSourceIP] = host
+	}
+
+	select {
+	case e.requests <- rec:
+	default:
+		e.logger.Warn("SIEM export queue full, dropped audit event", zap.String("event", rec.ID))
+	}
+}
+
+// changeRecord describes a configuration change.
+func (e *Exporter) changeRecord(ev events.Event) Record {
+	operation := strings.ToLower(ev.Operation)
+	rec := Record{
+		Time:     ev.Time,
+		ID:       ev.Type + "." + operation,
+		Name:     strings.ToUpper(ev.Type[:1]) + ev.Type[1:] + " " + pastTense(operation),
+		Severity: 3,
+		Fields: map[string]string{
+			FieldCategory:     "config",
+			FieldOutcome:      "success",
+			FieldOperator:     ev.Operator,
+			FieldResourceType: ev.Type,
+			FieldBackend:      ev.Backend,
+			FieldReason:       ev.Reason,
+			FieldEnvironment:  e.environment,
+		},
+	}
+	if ev.Operation == "DELETE" {
+		rec.Severity = 5
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	if ev.ID != nil {
+		rec.Fields[FieldResourceID] = strconv.FormatUint(uint64(*ev.ID), 10)
+	}
+	if ev.Revision != 0 {
+		rec.Fields[FieldRevision] = strconv.FormatUint(ev.Revision, 10)
+	}
+	return rec
+}
+
+// pastTense turns an operation into the verb of an event name.
+func pastTense(operation string) string {
+	switch operation {
+	case "create", "update", "delete":
+		return operation + "d"
+	default:
+		return operation
+	}
+}
+
+// send writes rec as one syslog message (RFC 5424), reconnecting once if
+// the connection has failed since the last one.
+func (e *Exporter) send(rec *Record) error {
+	var msg string
+	if e.format == FormatLEEF {
+		msg = formatLEEF(rec, e.mapping)
+	} else {
+		msg = formatCEF(rec, e.mapping)
+	}
+	line := fmt.Sprintf("<%d>1 %s %s %s - %s - %s\n", facility*8+syslogSeverity(rec.Severity),
+		rec.Time.UTC().Format(time.RFC3339Nano), e.hostname, appName, rec.ID, msg)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if e.conn == nil {
+			if e.conn, err = e.dial(); err != nil {
+				return err
+			}
+		}
+		e.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err = e.conn.Write([]byte(line)); err == nil {
+			return nil
+		}
+		e.conn.Close()
+		e.conn = nil
+	}
+	return err
+}
+
+func (e *Exporter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if e.tls {
+		return tls.DialWithDialer(dialer, e.network, e.addr, &tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	return dialer.Dial(e.network, e.addr)
+}
+
+func (e *Exporter) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conn != nil {
+		e.conn.Close()
+		e.conn = nil
+	}
+}
+
+// syslogSeverity maps a CEF severity to a syslog one: warning, notice or
+// informational.
+func syslogSeverity(severity int) int {
+	switch {
+	case severity >= 7:
+		return 4
+	case severity >= 5:
+		return 5
+	default:
+		return 6
+	}
+}
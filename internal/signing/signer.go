@@ -0,0 +1,78 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// Algorithm is the signature scheme used for config payloads.
+const Algorithm = "Ed25519"
+
+// Signer produces detached Ed25519 signatures over config payloads.
+type Signer struct {
+	keyID string
+	key   ed25519.PrivateKey
+}
+
+// NewSigner creates a Signer from a base64-encoded Ed25519 key, either the
+// 32-byte seed or the 64-byte private key.
+func NewSigner(encodedKey string) (*Signer, error) {
+	raw, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode signing key: %w", err)
+	}
+
+	var key ed25519.PrivateKey
+	switch len(raw) {
+	case ed25519.SeedSize:
+		key = ed25519.NewKeyFromSeed(raw)
+	case ed25519.PrivateKeySize:
+		key = ed25519.PrivateKey(raw)
+	default:
+		return nil, fmt.Errorf("signing key must be %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+	}
+
+	pub := key.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(pub)
+
+	return &Signer{
+		keyID: hex.EncodeToString(sum[:8]),
+		key:   key,
+	}, nil
+}
+
+// KeyID identifies the public key, so gateways can pick the right one
+// during key rollover.
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey returns the base64-encoded public key.
+func (s *Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// Sign returns the base64-encoded signature over payload.
+func (s *Signer) Sign(payload []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, payload))
+}
+
+// Verify checks a base64-encoded signature against a base64-encoded public
+// key. Gateways can use it to validate payloads.
+func Verify(publicKey string, payload []byte, signature string) (bool, error) {
+	pub, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return false, err
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return false, fmt.Errorf("public key must be %d bytes", ed25519.PublicKeySize)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, err
+	}
+	return ed25519.Verify(pub, payload, sig), nil
+}
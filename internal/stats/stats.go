@@ -0,0 +1,200 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+const (
+	// MaxPushEntries bounds the size of one push.
+	MaxPushEntries = 10000
+	// maxClockSkew is how far in the future a reported minute may start.
+	maxClockSkew = 5 * time.Minute
+)
+
+// Push is the body gateways send to report traffic: one entry per route
+// and minute. Entries for the same route and minute, from one or several
+// gateways, are summed.
+type Push struct {
+	// Gateway identifies the reporting instance, for logging.
+	Gateway string              `json:"gateway,omitempty"`
+	Routes  []config.RouteStats `json:"routes"`
+}
+
+// Validate checks every entry of the push.
+func (p *Push) Validate() error {
+	if len(p.Routes) > MaxPushEntries {
+		return fmt.Errorf("at most %d entries per push", MaxPushEntries)
+	}
+
+	latest := time.Now().Add(maxClockSkew)
+	for i, st := range p.Routes {
+		switch {
+		case st.RouteID == 0:
+			return fmt.Errorf("routes[%d]: route_id is required", i)
+		case st.BucketStart.IsZero():
+			return fmt.Errorf("routes[%d]: bucket_start is required", i)
+		case st.BucketStart.After(latest):
+			return fmt.Errorf("routes[%d]: bucket_start is in the future", i)
+		case st.Requests < 0 || st.Errors < 0:
+			return fmt.Errorf("routes[%d]: counts cannot be negative", i)
+		case st.Errors > st.Requests:
+			return fmt.Errorf("routes[%d]: errors cannot exceed requests", i)
+		}
+
+		if len(st.LatencyBuckets) == 0 {
+			continue
+		}
+		if len(st.LatencyBuckets) != len(config.LatencyBucketBounds)+1 {
+			return fmt.Errorf("routes[%d]: latency_buckets must have %d entries", i, len(config.LatencyBucketBounds)+1)
+		}
+		var sum int64
+		for _, n := range st.LatencyBuckets {
+			if n < 0 {
+				return fmt.Errorf("routes[%d]: counts cannot be negative", i)
+			}
+			sum += n
+		}
+		if sum > st.Requests {
+			return fmt.Errorf("routes[%d]: latency_buckets cannot count more than requests", i)
+		}
+	}
+	return nil
+}
+
+// Summary aggregates traffic over a period. Latency percentiles are
+// estimated as the upper bound of the histogram bucket they fall in, and
+// are omitted when there is no latency data or the percentile falls beyond
+// the last bound.
+type Summary struct {
+	Requests       int64    `json:"requests"`
+	Errors         int64    `json:"errors"`
+	ErrorRate      float64  `json:"error_rate"`
+	LatencyBuckets []int64  `json:"latency_buckets"`
+	P50            *float64 `json:"p50_ms,omitempty"`
+	P90            *float64 `json:"p90_ms,omitempty"`
+	P99            *float64 `json:"p99_ms,omitempty"`
+}
+
+// Bucket is one step of a time series.
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Summary
+}
+
+// Report is the traffic of a route over a window.
+type Report struct {
+	RouteID         uint      `json:"route_id"`
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	Step            string    `json:"step"`
+	LatencyBoundsMS []int     `json:"latency_bounds_ms"`
+	Summary
+	Series []Bucket `json:"series"`
+}
+
+// NewReport summarises the per-minute aggregates in [from, to) overall and
+// as a time series of buckets of length step.
+func NewReport(routeID uint, rows []config.RouteStats, from, to time.Time, step time.Duration) *Report {
+	report := &Report{
+		RouteID:         routeID,
+		From:            from,
+		To:              to,
+		Step:            step.String(),
+		LatencyBoundsMS: config.LatencyBucketBounds,
+		Summary:         newSummary(),
+		Series:          []Bucket{},
+	}
+
+	n := int((to.Sub(from) + step - 1) / step)
+	for i := 0; i < n; i++ {
+		report.Series = append(report.Series, Bucket{Start: from.Add(time.Duration(i) * step), Summary: newSummary()})
+	}
+
+	for _, row := range rows {
+		if row.BucketStart.Before(from) || !row.BucketStart.Before(to) {
+			continue
+		}
+		i := int(row.BucketStart.Sub(from) / step)
+		report.Series[i].add(row)
+		report.add(row)
+	}
+
+	report.finish()
+	for i := range report.Series {
+		report.Series[i].finish()
+	}
+	return report
+}
+
+func newSummary() Summary {
+	return Summary{LatencyBuckets: make([]int64, len(config.LatencyBucketBounds)+1)}
+}
+
+func (s *Summary) add(row config.RouteStats) {
+	s.Requests += row.Requests
+	s.Errors += row.Errors
+	for i, n := range row.LatencyBuckets {
+		if i < len(s.LatencyBuckets) {
+			s.LatencyBuckets[i] += n
+		}
+	}
+}
+
+// finish derives the error rate and latency percentiles from the totals.
+func (s *Summary) finish() {
+	if s.Requests > 0 {
+		s.ErrorRate = float64(s.Errors) / float64(s.Requests)
+	}
+	s.P50 = s.percentile(0.50)
+	s.P90 = s.percentile(0.90)
+	s.P99 = s.percentile(0.99)
+}
+
+func (s *Summary) percentile(p float64) *float64 {
+	var total int64
+	for _, n := range s.LatencyBuckets {
+		total += n
+	}
+	if total == 0 {
+		return nil
+	}
+
+	rank := int64(math.Ceil(p * float64(total)))
+	var seen int64
+	for i, bound := range config.LatencyBucketBounds {
+		seen += s.LatencyBuckets[i]
+		if seen >= rank {
+			ms := float64(bound)
+			return &ms
+		}
+	}
+	return nil
+}
+
+// Prune deletes aggregates older than retention once an hour until ctx is
+// cancelled.
+func Prune(ctx context.Context, store config.StatsStore, retention time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if n, err := store.PruneRouteStats(time.Now().Add(-retention)); err != nil {
+			logger.Warn("failed to prune route stats", zap.Error(err))
+		} else if n > 0 {
+			logger.Debug("pruned route stats", zap.Int64("count", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
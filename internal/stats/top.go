@@ -0,0 +1,96 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+)
+
+// Ranking orders for TopRoutes.
+const (
+	ByRequests  = "requests"
+	ByErrors    = "errors"
+	ByErrorRate = "error_rate"
+	ByP99       = "p99"
+)
+
+// RouteTotal is the traffic of one configured route over a window.
+type RouteTotal struct {
+	RouteID     uint   `json:"route_id"`
+	HTTPMethod  string `json:"http_method"`
+	HTTPPattern string `json:"http_pattern"`
+	BackendName string `json:"backend_name"`
+	APIVersion  string `json:"api_version,omitempty"`
+	Enabled     bool   `json:"enabled"`
+	Summary
+}
+
+// TopRoutes ranks the given routes by their traffic totals in descending
+// order of by and returns at most limit of them. Routes without traffic
+// are included with zero counts so that unused configuration shows up;
+// totals for routes not in routes (e.g. deleted ones) are ignored. Routes
+// without latency data rank last by p99.
+func TopRoutes(routes []config.Route, totals []config.RouteStats, by string, limit int) ([]RouteTotal, error) {
+	var less func(a, b *RouteTotal) bool
+	switch by {
+	case ByRequests:
+		less = func(a, b *RouteTotal) bool { return a.Requests > b.Requests }
+	case ByErrors:
+		less = func(a, b *RouteTotal) bool { return a.Errors > b.Errors }
+	case ByErrorRate:
+		less = func(a, b *RouteTotal) bool { return a.ErrorRate > b.ErrorRate }
+	case ByP99:
+		less = func(a, b *RouteTotal) bool {
+			if a.P99 == nil || b.P99 == nil {
+				return a.P99 != nil && b.P99 == nil
+			}
+			return *a.P99 > *b.P99
+		}
+	default:
+		return nil, fmt.Errorf("unknown ranking %q: want %s, %s, %s or %s", by, ByRequests, ByErrors, ByErrorRate, ByP99)
+	}
+
+	byID := make(map[uint]*config.RouteStats, len(totals))
+	for i := range totals {
+		byID[totals[i].RouteID] = &totals[i]
+	}
+
+	ranked := make([]RouteTotal, 0, len(routes))
+	for _, r := range routes {
+		t := RouteTotal{
+			RouteID:     r.ID,
+			HTTPMethod:  r.HTTPMethod,
+			HTTPPattern: r.HTTPPattern,
+			BackendName: r.BackendName,
+			APIVersion:  r.APIVersion,
+			Enabled:     r.Enabled,
+			Summary:     newSummary(),
+		}
+		if total, ok := byID[r.ID]; ok {
+			t.add(*total)
+		}
+		t.finish()
+		ranked = append(ranked, t)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := &ranked[i], &ranked[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		// Ties go to the busier route, then the older one
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.RouteID < b.RouteID
+	})
+
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked, nil
+}
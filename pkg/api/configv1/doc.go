@@ -0,0 +1,6 @@
+// Package configv1 holds the gRPC API gateways use to subscribe to their
+// config, generated from config.proto, and the protobuf encoding of the
+// compiled config served over HTTP, generated from gateway_config.proto.
+package configv1
+
+//go:generate protoc -I .. --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative configv1/config.proto configv1/gateway_config.proto
@@ -0,0 +1,1814 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: configv1/gateway_config.proto
+
+package configv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// GatewayConfig is the compiled gateway config, the payload of
+// GET /api/v1/gateway/config, as served to gateways that accept
+// application/x-protobuf. Fields mirror those of the JSON encoding.
+type GatewayConfig struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Revision of the config, as the ID of the latest change it includes.
+	Revision uint64 `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	// Gateway cluster the config was compiled for, if any.
+	Cluster       string     `protobuf:"bytes,2,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	Backends      []*Backend `protobuf:"bytes,3,rep,name=backends,proto3" json:"backends,omitempty"`
+	Routes        []*Route   `protobuf:"bytes,4,rep,name=routes,proto3" json:"routes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GatewayConfig) Reset() {
+	*x = GatewayConfig{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GatewayConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GatewayConfig) ProtoMessage() {}
+
+func (x *GatewayConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GatewayConfig.ProtoReflect.Descriptor instead.
+func (*GatewayConfig) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GatewayConfig) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *GatewayConfig) GetCluster() string {
+	if x != nil {
+		return x.Cluster
+	}
+	return ""
+}
+
+func (x *GatewayConfig) GetBackends() []*Backend {
+	if x != nil {
+		return x.Backends
+	}
+	return nil
+}
+
+func (x *GatewayConfig) GetRoutes() []*Route {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+// ConfigDelta is the change between two compiled configs, the payload of
+// GET /api/v1/changes/wait. Applying it to the older config, removals
+// first, yields the newer one.
+type ConfigDelta struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Revision of the older config.
+	FromRevision uint64 `protobuf:"varint,1,opt,name=from_revision,json=fromRevision,proto3" json:"from_revision,omitempty"`
+	// Revision of the newer config.
+	Revision uint64 `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	// Backends added or changed, in full.
+	Backends []*Backend `protobuf:"bytes,3,rep,name=backends,proto3" json:"backends,omitempty"`
+	// Names of the backends removed.
+	RemovedBackends []string `protobuf:"bytes,4,rep,name=removed_backends,json=removedBackends,proto3" json:"removed_backends,omitempty"`
+	// Routes added or changed, in full.
+	Routes []*Route `protobuf:"bytes,5,rep,name=routes,proto3" json:"routes,omitempty"`
+	// IDs of the routes removed.
+	RemovedRoutes []uint64 `protobuf:"varint,6,rep,packed,name=removed_routes,json=removedRoutes,proto3" json:"removed_routes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigDelta) Reset() {
+	*x = ConfigDelta{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigDelta) ProtoMessage() {}
+
+func (x *ConfigDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigDelta.ProtoReflect.Descriptor instead.
+func (*ConfigDelta) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ConfigDelta) GetFromRevision() uint64 {
+	if x != nil {
+		return x.FromRevision
+	}
+	return 0
+}
+
+func (x *ConfigDelta) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *ConfigDelta) GetBackends() []*Backend {
+	if x != nil {
+		return x.Backends
+	}
+	return nil
+}
+
+func (x *ConfigDelta) GetRemovedBackends() []string {
+	if x != nil {
+		return x.RemovedBackends
+	}
+	return nil
+}
+
+func (x *ConfigDelta) GetRoutes() []*Route {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+func (x *ConfigDelta) GetRemovedRoutes() []uint64 {
+	if x != nil {
+		return x.RemovedRoutes
+	}
+	return nil
+}
+
+type Backend struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Addr  string                 `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+	// Latest descriptor set of the backend, if it has one.
+	Descriptors *Descriptors `protobuf:"bytes,3,opt,name=descriptors,proto3" json:"descriptors,omitempty"`
+	// Defaults of the backend's routes.
+	CircuitBreaker *CircuitBreaker `protobuf:"bytes,4,opt,name=circuit_breaker,json=circuitBreaker,proto3" json:"circuit_breaker,omitempty"`
+	// Set if gateways must send the backend no new sessions.
+	Draining bool `protobuf:"varint,5,opt,name=draining,proto3" json:"draining,omitempty"`
+	// Plugin configs by plugin name, as JSON.
+	Plugins       map[string][]byte `protobuf:"bytes,6,rep,name=plugins,proto3" json:"plugins,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Backend) Reset() {
+	*x = Backend{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Backend) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Backend) ProtoMessage() {}
+
+func (x *Backend) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Backend.ProtoReflect.Descriptor instead.
+func (*Backend) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Backend) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Backend) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *Backend) GetDescriptors() *Descriptors {
+	if x != nil {
+		return x.Descriptors
+	}
+	return nil
+}
+
+func (x *Backend) GetCircuitBreaker() *CircuitBreaker {
+	if x != nil {
+		return x.CircuitBreaker
+	}
+	return nil
+}
+
+func (x *Backend) GetDraining() bool {
+	if x != nil {
+		return x.Draining
+	}
+	return false
+}
+
+func (x *Backend) GetPlugins() map[string][]byte {
+	if x != nil {
+		return x.Plugins
+	}
+	return nil
+}
+
+type Descriptors struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       int32                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Sha256        string                 `protobuf:"bytes,2,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Descriptors) Reset() {
+	*x = Descriptors{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Descriptors) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Descriptors) ProtoMessage() {}
+
+func (x *Descriptors) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Descriptors.ProtoReflect.Descriptor instead.
+func (*Descriptors) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Descriptors) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Descriptors) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+type CircuitBreaker struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	MaxRequests        int32                  `protobuf:"varint,1,opt,name=max_requests,json=maxRequests,proto3" json:"max_requests,omitempty"`
+	Consecutive_5Xx    int32                  `protobuf:"varint,2,opt,name=consecutive_5xx,json=consecutive5xx,proto3" json:"consecutive_5xx,omitempty"`
+	EjectionSeconds    int32                  `protobuf:"varint,3,opt,name=ejection_seconds,json=ejectionSeconds,proto3" json:"ejection_seconds,omitempty"`
+	MaxEjectionPercent int32                  `protobuf:"varint,4,opt,name=max_ejection_percent,json=maxEjectionPercent,proto3" json:"max_ejection_percent,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *CircuitBreaker) Reset() {
+	*x = CircuitBreaker{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CircuitBreaker) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CircuitBreaker) ProtoMessage() {}
+
+func (x *CircuitBreaker) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CircuitBreaker.ProtoReflect.Descriptor instead.
+func (*CircuitBreaker) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CircuitBreaker) GetMaxRequests() int32 {
+	if x != nil {
+		return x.MaxRequests
+	}
+	return 0
+}
+
+func (x *CircuitBreaker) GetConsecutive_5Xx() int32 {
+	if x != nil {
+		return x.Consecutive_5Xx
+	}
+	return 0
+}
+
+func (x *CircuitBreaker) GetEjectionSeconds() int32 {
+	if x != nil {
+		return x.EjectionSeconds
+	}
+	return 0
+}
+
+func (x *CircuitBreaker) GetMaxEjectionPercent() int32 {
+	if x != nil {
+		return x.MaxEjectionPercent
+	}
+	return 0
+}
+
+type Route struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Id                 uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	HttpMethod         string                 `protobuf:"bytes,2,opt,name=http_method,json=httpMethod,proto3" json:"http_method,omitempty"`
+	HttpPattern        string                 `protobuf:"bytes,3,opt,name=http_pattern,json=httpPattern,proto3" json:"http_pattern,omitempty"`
+	BackendName        string                 `protobuf:"bytes,4,opt,name=backend_name,json=backendName,proto3" json:"backend_name,omitempty"`
+	BackendService     string                 `protobuf:"bytes,5,opt,name=backend_service,json=backendService,proto3" json:"backend_service,omitempty"`
+	BackendMethod      string                 `protobuf:"bytes,6,opt,name=backend_method,json=backendMethod,proto3" json:"backend_method,omitempty"`
+	TimeoutMs          int32                  `protobuf:"varint,7,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	ApiVersion         string                 `protobuf:"bytes,8,opt,name=api_version,json=apiVersion,proto3" json:"api_version,omitempty"`
+	Deprecated         bool                   `protobuf:"varint,9,opt,name=deprecated,proto3" json:"deprecated,omitempty"`
+	DeprecationHeaders map[string]string      `protobuf:"bytes,10,rep,name=deprecation_headers,json=deprecationHeaders,proto3" json:"deprecation_headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	MaxRequestBytes    int64                  `protobuf:"varint,11,opt,name=max_request_bytes,json=maxRequestBytes,proto3" json:"max_request_bytes,omitempty"`
+	ContentTypes       []string               `protobuf:"bytes,12,rep,name=content_types,json=contentTypes,proto3" json:"content_types,omitempty"`
+	Transcoding        *Transcoding           `protobuf:"bytes,13,opt,name=transcoding,proto3" json:"transcoding,omitempty"`
+	Schema             *Schema                `protobuf:"bytes,14,opt,name=schema,proto3" json:"schema,omitempty"`
+	Transform          *Transform             `protobuf:"bytes,15,opt,name=transform,proto3" json:"transform,omitempty"`
+	Cache              *Cache                 `protobuf:"bytes,16,opt,name=cache,proto3" json:"cache,omitempty"`
+	CircuitBreaker     *CircuitBreaker        `protobuf:"bytes,17,opt,name=circuit_breaker,json=circuitBreaker,proto3" json:"circuit_breaker,omitempty"`
+	Rollout            *Rollout               `protobuf:"bytes,18,opt,name=rollout,proto3" json:"rollout,omitempty"`
+	Experiment         *Experiment            `protobuf:"bytes,19,opt,name=experiment,proto3" json:"experiment,omitempty"`
+	Mirror             *Mirror                `protobuf:"bytes,20,opt,name=mirror,proto3" json:"mirror,omitempty"`
+	Fallback           *Fallback              `protobuf:"bytes,21,opt,name=fallback,proto3" json:"fallback,omitempty"`
+	Grpc               *GRPC                  `protobuf:"bytes,22,opt,name=grpc,proto3" json:"grpc,omitempty"`
+	Middlewares        []*Middleware          `protobuf:"bytes,23,rep,name=middlewares,proto3" json:"middlewares,omitempty"`
+	// Plugin configs by plugin name, as JSON.
+	Plugins       map[string][]byte `protobuf:"bytes,24,rep,name=plugins,proto3" json:"plugins,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	QueryParams   []*QueryParam     `protobuf:"bytes,25,rep,name=query_params,json=queryParams,proto3" json:"query_params,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Route) Reset() {
+	*x = Route{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Route) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Route) ProtoMessage() {}
+
+func (x *Route) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Route.ProtoReflect.Descriptor instead.
+func (*Route) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Route) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Route) GetHttpMethod() string {
+	if x != nil {
+		return x.HttpMethod
+	}
+	return ""
+}
+
+func (x *Route) GetHttpPattern() string {
+	if x != nil {
+		return x.HttpPattern
+	}
+	return ""
+}
+
+func (x *Route) GetBackendName() string {
+	if x != nil {
+		return x.BackendName
+	}
+	return ""
+}
+
+func (x *Route) GetBackendService() string {
+	if x != nil {
+		return x.BackendService
+	}
+	return ""
+}
+
+func (x *Route) GetBackendMethod() string {
+	if x != nil {
+		return x.BackendMethod
+	}
+	return ""
+}
+
+func (x *Route) GetTimeoutMs() int32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+func (x *Route) GetApiVersion() string {
+	if x != nil {
+		return x.ApiVersion
+	}
+	return ""
+}
+
+func (x *Route) GetDeprecated() bool {
+	if x != nil {
+		return x.Deprecated
+	}
+	return false
+}
+
+func (x *Route) GetDeprecationHeaders() map[string]string {
+	if x != nil {
+		return x.DeprecationHeaders
+	}
+	return nil
+}
+
+func (x *Route) GetMaxRequestBytes() int64 {
+	if x != nil {
+		return x.MaxRequestBytes
+	}
+	return 0
+}
+
+func (x *Route) GetContentTypes() []string {
+	if x != nil {
+		return x.ContentTypes
+	}
+	return nil
+}
+
+func (x *Route) GetTranscoding() *Transcoding {
+	if x != nil {
+		return x.Transcoding
+	}
+	return nil
+}
+
+func (x *Route) GetSchema() *Schema {
+	if x != nil {
+		return x.Schema
+	}
+	return nil
+}
+
+func (x *Route) GetTransform() *Transform {
+	if x != nil {
+		return x.Transform
+	}
+	return nil
+}
+
+func (x *Route) GetCache() *Cache {
+	if x != nil {
+		return x.Cache
+	}
+	return nil
+}
+
+func (x *Route) GetCircuitBreaker() *CircuitBreaker {
+	if x != nil {
+		return x.CircuitBreaker
+	}
+	return nil
+}
+
+func (x *Route) GetRollout() *Rollout {
+	if x != nil {
+		return x.Rollout
+	}
+	return nil
+}
+
+func (x *Route) GetExperiment() *Experiment {
+	if x != nil {
+		return x.Experiment
+	}
+	return nil
+}
+
+func (x *Route) GetMirror() *Mirror {
+	if x != nil {
+		return x.Mirror
+	}
+	return nil
+}
+
+func (x *Route) GetFallback() *Fallback {
+	if x != nil {
+		return x.Fallback
+	}
+	return nil
+}
+
+func (x *Route) GetGrpc() *GRPC {
+	if x != nil {
+		return x.Grpc
+	}
+	return nil
+}
+
+func (x *Route) GetMiddlewares() []*Middleware {
+	if x != nil {
+		return x.Middlewares
+	}
+	return nil
+}
+
+func (x *Route) GetPlugins() map[string][]byte {
+	if x != nil {
+		return x.Plugins
+	}
+	return nil
+}
+
+func (x *Route) GetQueryParams() []*QueryParam {
+	if x != nil {
+		return x.QueryParams
+	}
+	return nil
+}
+
+type Transcoding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestType   string                 `protobuf:"bytes,1,opt,name=request_type,json=requestType,proto3" json:"request_type,omitempty"`
+	ResponseType  string                 `protobuf:"bytes,2,opt,name=response_type,json=responseType,proto3" json:"response_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transcoding) Reset() {
+	*x = Transcoding{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transcoding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transcoding) ProtoMessage() {}
+
+func (x *Transcoding) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transcoding.ProtoReflect.Descriptor instead.
+func (*Transcoding) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Transcoding) GetRequestType() string {
+	if x != nil {
+		return x.RequestType
+	}
+	return ""
+}
+
+func (x *Transcoding) GetResponseType() string {
+	if x != nil {
+		return x.ResponseType
+	}
+	return ""
+}
+
+type Schema struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       int32                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Request       *SchemaRef             `protobuf:"bytes,2,opt,name=request,proto3" json:"request,omitempty"`
+	Response      *SchemaRef             `protobuf:"bytes,3,opt,name=response,proto3" json:"response,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Schema) Reset() {
+	*x = Schema{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Schema) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Schema) ProtoMessage() {}
+
+func (x *Schema) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Schema.ProtoReflect.Descriptor instead.
+func (*Schema) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Schema) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Schema) GetRequest() *SchemaRef {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *Schema) GetResponse() *SchemaRef {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+type SchemaRef struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// JSON Schema document, as JSON.
+	JsonSchema    []byte `protobuf:"bytes,1,opt,name=json_schema,json=jsonSchema,proto3" json:"json_schema,omitempty"`
+	Message       string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SchemaRef) Reset() {
+	*x = SchemaRef{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SchemaRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SchemaRef) ProtoMessage() {}
+
+func (x *SchemaRef) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SchemaRef.ProtoReflect.Descriptor instead.
+func (*SchemaRef) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SchemaRef) GetJsonSchema() []byte {
+	if x != nil {
+		return x.JsonSchema
+	}
+	return nil
+}
+
+func (x *SchemaRef) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type Transform struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Request       *BodyTemplate          `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	Response      *BodyTemplate          `protobuf:"bytes,2,opt,name=response,proto3" json:"response,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transform) Reset() {
+	*x = Transform{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transform) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transform) ProtoMessage() {}
+
+func (x *Transform) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transform.ProtoReflect.Descriptor instead.
+func (*Transform) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Transform) GetRequest() *BodyTemplate {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *Transform) GetResponse() *BodyTemplate {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+type BodyTemplate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Language      string                 `protobuf:"bytes,1,opt,name=language,proto3" json:"language,omitempty"`
+	Template      string                 `protobuf:"bytes,2,opt,name=template,proto3" json:"template,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BodyTemplate) Reset() {
+	*x = BodyTemplate{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BodyTemplate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BodyTemplate) ProtoMessage() {}
+
+func (x *BodyTemplate) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BodyTemplate.ProtoReflect.Descriptor instead.
+func (*BodyTemplate) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *BodyTemplate) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *BodyTemplate) GetTemplate() string {
+	if x != nil {
+		return x.Template
+	}
+	return ""
+}
+
+type Cache struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TtlSeconds    int32                  `protobuf:"varint,1,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
+	VaryHeaders   []string               `protobuf:"bytes,2,rep,name=vary_headers,json=varyHeaders,proto3" json:"vary_headers,omitempty"`
+	AllowPost     bool                   `protobuf:"varint,3,opt,name=allow_post,json=allowPost,proto3" json:"allow_post,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Cache) Reset() {
+	*x = Cache{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cache) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cache) ProtoMessage() {}
+
+func (x *Cache) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cache.ProtoReflect.Descriptor instead.
+func (*Cache) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Cache) GetTtlSeconds() int32 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *Cache) GetVaryHeaders() []string {
+	if x != nil {
+		return x.VaryHeaders
+	}
+	return nil
+}
+
+func (x *Cache) GetAllowPost() bool {
+	if x != nil {
+		return x.AllowPost
+	}
+	return false
+}
+
+type Rollout struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Percent       int32                  `protobuf:"varint,1,opt,name=percent,proto3" json:"percent,omitempty"`
+	HashKey       string                 `protobuf:"bytes,2,opt,name=hash_key,json=hashKey,proto3" json:"hash_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rollout) Reset() {
+	*x = Rollout{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rollout) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rollout) ProtoMessage() {}
+
+func (x *Rollout) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rollout.ProtoReflect.Descriptor instead.
+func (*Rollout) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Rollout) GetPercent() int32 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *Rollout) GetHashKey() string {
+	if x != nil {
+		return x.HashKey
+	}
+	return ""
+}
+
+type Experiment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	HashKey       string                 `protobuf:"bytes,1,opt,name=hash_key,json=hashKey,proto3" json:"hash_key,omitempty"`
+	Variants      []*Variant             `protobuf:"bytes,2,rep,name=variants,proto3" json:"variants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Experiment) Reset() {
+	*x = Experiment{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Experiment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Experiment) ProtoMessage() {}
+
+func (x *Experiment) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Experiment.ProtoReflect.Descriptor instead.
+func (*Experiment) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Experiment) GetHashKey() string {
+	if x != nil {
+		return x.HashKey
+	}
+	return ""
+}
+
+func (x *Experiment) GetVariants() []*Variant {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+type Variant struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Name           string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	BackendName    string                 `protobuf:"bytes,2,opt,name=backend_name,json=backendName,proto3" json:"backend_name,omitempty"`
+	BackendService string                 `protobuf:"bytes,3,opt,name=backend_service,json=backendService,proto3" json:"backend_service,omitempty"`
+	BackendMethod  string                 `protobuf:"bytes,4,opt,name=backend_method,json=backendMethod,proto3" json:"backend_method,omitempty"`
+	Percent        int32                  `protobuf:"varint,5,opt,name=percent,proto3" json:"percent,omitempty"`
+	Match          *VariantMatch          `protobuf:"bytes,6,opt,name=match,proto3" json:"match,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Variant) Reset() {
+	*x = Variant{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Variant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variant) ProtoMessage() {}
+
+func (x *Variant) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variant.ProtoReflect.Descriptor instead.
+func (*Variant) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Variant) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Variant) GetBackendName() string {
+	if x != nil {
+		return x.BackendName
+	}
+	return ""
+}
+
+func (x *Variant) GetBackendService() string {
+	if x != nil {
+		return x.BackendService
+	}
+	return ""
+}
+
+func (x *Variant) GetBackendMethod() string {
+	if x != nil {
+		return x.BackendMethod
+	}
+	return ""
+}
+
+func (x *Variant) GetPercent() int32 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *Variant) GetMatch() *VariantMatch {
+	if x != nil {
+		return x.Match
+	}
+	return nil
+}
+
+type VariantMatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Header        string                 `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VariantMatch) Reset() {
+	*x = VariantMatch{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VariantMatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VariantMatch) ProtoMessage() {}
+
+func (x *VariantMatch) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VariantMatch.ProtoReflect.Descriptor instead.
+func (*VariantMatch) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *VariantMatch) GetHeader() string {
+	if x != nil {
+		return x.Header
+	}
+	return ""
+}
+
+func (x *VariantMatch) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type Mirror struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	BackendName    string                 `protobuf:"bytes,1,opt,name=backend_name,json=backendName,proto3" json:"backend_name,omitempty"`
+	BackendService string                 `protobuf:"bytes,2,opt,name=backend_service,json=backendService,proto3" json:"backend_service,omitempty"`
+	BackendMethod  string                 `protobuf:"bytes,3,opt,name=backend_method,json=backendMethod,proto3" json:"backend_method,omitempty"`
+	Percent        int32                  `protobuf:"varint,4,opt,name=percent,proto3" json:"percent,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Mirror) Reset() {
+	*x = Mirror{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Mirror) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Mirror) ProtoMessage() {}
+
+func (x *Mirror) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Mirror.ProtoReflect.Descriptor instead.
+func (*Mirror) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Mirror) GetBackendName() string {
+	if x != nil {
+		return x.BackendName
+	}
+	return ""
+}
+
+func (x *Mirror) GetBackendService() string {
+	if x != nil {
+		return x.BackendService
+	}
+	return ""
+}
+
+func (x *Mirror) GetBackendMethod() string {
+	if x != nil {
+		return x.BackendMethod
+	}
+	return ""
+}
+
+func (x *Mirror) GetPercent() int32 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+type Fallback struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	BackendName    string                 `protobuf:"bytes,1,opt,name=backend_name,json=backendName,proto3" json:"backend_name,omitempty"`
+	BackendService string                 `protobuf:"bytes,2,opt,name=backend_service,json=backendService,proto3" json:"backend_service,omitempty"`
+	BackendMethod  string                 `protobuf:"bytes,3,opt,name=backend_method,json=backendMethod,proto3" json:"backend_method,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Fallback) Reset() {
+	*x = Fallback{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Fallback) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Fallback) ProtoMessage() {}
+
+func (x *Fallback) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Fallback.ProtoReflect.Descriptor instead.
+func (*Fallback) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Fallback) GetBackendName() string {
+	if x != nil {
+		return x.BackendName
+	}
+	return ""
+}
+
+func (x *Fallback) GetBackendService() string {
+	if x != nil {
+		return x.BackendService
+	}
+	return ""
+}
+
+func (x *Fallback) GetBackendMethod() string {
+	if x != nil {
+		return x.BackendMethod
+	}
+	return ""
+}
+
+type GRPC struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	PropagateDeadline bool                   `protobuf:"varint,1,opt,name=propagate_deadline,json=propagateDeadline,proto3" json:"propagate_deadline,omitempty"`
+	Headers           []*MetadataHeader      `protobuf:"bytes,2,rep,name=headers,proto3" json:"headers,omitempty"`
+	Allow             []string               `protobuf:"bytes,3,rep,name=allow,proto3" json:"allow,omitempty"`
+	Deny              []string               `protobuf:"bytes,4,rep,name=deny,proto3" json:"deny,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *GRPC) Reset() {
+	*x = GRPC{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GRPC) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GRPC) ProtoMessage() {}
+
+func (x *GRPC) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GRPC.ProtoReflect.Descriptor instead.
+func (*GRPC) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GRPC) GetPropagateDeadline() bool {
+	if x != nil {
+		return x.PropagateDeadline
+	}
+	return false
+}
+
+func (x *GRPC) GetHeaders() []*MetadataHeader {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *GRPC) GetAllow() []string {
+	if x != nil {
+		return x.Allow
+	}
+	return nil
+}
+
+func (x *GRPC) GetDeny() []string {
+	if x != nil {
+		return x.Deny
+	}
+	return nil
+}
+
+type MetadataHeader struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Header        string                 `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MetadataHeader) Reset() {
+	*x = MetadataHeader{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetadataHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetadataHeader) ProtoMessage() {}
+
+func (x *MetadataHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetadataHeader.ProtoReflect.Descriptor instead.
+func (*MetadataHeader) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *MetadataHeader) GetHeader() string {
+	if x != nil {
+		return x.Header
+	}
+	return ""
+}
+
+func (x *MetadataHeader) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type Middleware struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Parameters of the middleware, as a JSON object.
+	Params        []byte `protobuf:"bytes,2,opt,name=params,proto3" json:"params,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Middleware) Reset() {
+	*x = Middleware{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Middleware) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Middleware) ProtoMessage() {}
+
+func (x *Middleware) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Middleware.ProtoReflect.Descriptor instead.
+func (*Middleware) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Middleware) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Middleware) GetParams() []byte {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+type QueryParam struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Field         string                 `protobuf:"bytes,2,opt,name=field,proto3" json:"field,omitempty"`
+	Default       string                 `protobuf:"bytes,3,opt,name=default,proto3" json:"default,omitempty"`
+	Required      bool                   `protobuf:"varint,4,opt,name=required,proto3" json:"required,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryParam) Reset() {
+	*x = QueryParam{}
+	mi := &file_configv1_gateway_config_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryParam) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryParam) ProtoMessage() {}
+
+func (x *QueryParam) ProtoReflect() protoreflect.Message {
+	mi := &file_configv1_gateway_config_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryParam.ProtoReflect.Descriptor instead.
+func (*QueryParam) Descriptor() ([]byte, []int) {
+	return file_configv1_gateway_config_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *QueryParam) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *QueryParam) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *QueryParam) GetDefault() string {
+	if x != nil {
+		return x.Default
+	}
+	return ""
+}
+
+func (x *QueryParam) GetRequired() bool {
+	if x != nil {
+		return x.Required
+	}
+	return false
+}
+
+var File_configv1_gateway_config_proto protoreflect.FileDescriptor
+
+const file_configv1_gateway_config_proto_rawDesc = "" +
+	"\n" +
+	"\x1dconfigv1/gateway_config.proto\x12\x16gatewayadmin.config.v1\"\xb9\x01\n" +
+	"\rGatewayConfig\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x04R\brevision\x12\x18\n" +
+	"\acluster\x18\x02 \x01(\tR\acluster\x12;\n" +
+	"\bbackends\x18\x03 \x03(\v2\x1f.gatewayadmin.config.v1.BackendR\bbackends\x125\n" +
+	"\x06routes\x18\x04 \x03(\v2\x1d.gatewayadmin.config.v1.RouteR\x06routes\"\x94\x02\n" +
+	"\vConfigDelta\x12#\n" +
+	"\rfrom_revision\x18\x01 \x01(\x04R\ffromRevision\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x04R\brevision\x12;\n" +
+	"\bbackends\x18\x03 \x03(\v2\x1f.gatewayadmin.config.v1.BackendR\bbackends\x12)\n" +
+	"\x10removed_backends\x18\x04 \x03(\tR\x0fremovedBackends\x125\n" +
+	"\x06routes\x18\x05 \x03(\v2\x1d.gatewayadmin.config.v1.RouteR\x06routes\x12%\n" +
+	"\x0eremoved_routes\x18\x06 \x03(\x04R\rremovedRoutes\"\xe9\x02\n" +
+	"\aBackend\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04addr\x18\x02 \x01(\tR\x04addr\x12E\n" +
+	"\vdescriptors\x18\x03 \x01(\v2#.gatewayadmin.config.v1.DescriptorsR\vdescriptors\x12O\n" +
+	"\x0fcircuit_breaker\x18\x04 \x01(\v2&.gatewayadmin.config.v1.CircuitBreakerR\x0ecircuitBreaker\x12\x1a\n" +
+	"\bdraining\x18\x05 \x01(\bR\bdraining\x12F\n" +
+	"\aplugins\x18\x06 \x03(\v2,.gatewayadmin.config.v1.Backend.PluginsEntryR\aplugins\x1a:\n" +
+	"\fPluginsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\"?\n" +
+	"\vDescriptors\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x05R\aversion\x12\x16\n" +
+	"\x06sha256\x18\x02 \x01(\tR\x06sha256\"\xb9\x01\n" +
+	"\x0eCircuitBreaker\x12!\n" +
+	"\fmax_requests\x18\x01 \x01(\x05R\vmaxRequests\x12'\n" +
+	"\x0fconsecutive_5xx\x18\x02 \x01(\x05R\x0econsecutive5xx\x12)\n" +
+	"\x10ejection_seconds\x18\x03 \x01(\x05R\x0fejectionSeconds\x120\n" +
+	"\x14max_ejection_percent\x18\x04 \x01(\x05R\x12maxEjectionPercent\"\xaa\v\n" +
+	"\x05Route\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x1f\n" +
+	"\vhttp_method\x18\x02 \x01(\tR\n" +
+	"httpMethod\x12!\n" +
+	"\fhttp_pattern\x18\x03 \x01(\tR\vhttpPattern\x12!\n" +
+	"\fbackend_name\x18\x04 \x01(\tR\vbackendName\x12'\n" +
+	"\x0fbackend_service\x18\x05 \x01(\tR\x0ebackendService\x12%\n" +
+	"\x0ebackend_method\x18\x06 \x01(\tR\rbackendMethod\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\a \x01(\x05R\ttimeoutMs\x12\x1f\n" +
+	"\vapi_version\x18\b \x01(\tR\n" +
+	"apiVersion\x12\x1e\n" +
+	"\n" +
+	"deprecated\x18\t \x01(\bR\n" +
+	"deprecated\x12f\n" +
+	"\x13deprecation_headers\x18\n" +
+	" \x03(\v25.gatewayadmin.config.v1.Route.DeprecationHeadersEntryR\x12deprecationHeaders\x12*\n" +
+	"\x11max_request_bytes\x18\v \x01(\x03R\x0fmaxRequestBytes\x12#\n" +
+	"\rcontent_types\x18\f \x03(\tR\fcontentTypes\x12E\n" +
+	"\vtranscoding\x18\r \x01(\v2#.gatewayadmin.config.v1.TranscodingR\vtranscoding\x126\n" +
+	"\x06schema\x18\x0e \x01(\v2\x1e.gatewayadmin.config.v1.SchemaR\x06schema\x12?\n" +
+	"\ttransform\x18\x0f \x01(\v2!.gatewayadmin.config.v1.TransformR\ttransform\x123\n" +
+	"\x05cache\x18\x10 \x01(\v2\x1d.gatewayadmin.config.v1.CacheR\x05cache\x12O\n" +
+	"\x0fcircuit_breaker\x18\x11 \x01(\v2&.gatewayadmin.config.v1.CircuitBreakerR\x0ecircuitBreaker\x129\n" +
+	"\arollout\x18\x12 \x01(\v2\x1f.gatewayadmin.config.v1.RolloutR\arollout\x12B\n" +
+	"\n" +
+	"experiment\x18\x13 \x01(\v2\".gatewayadmin.config.v1.ExperimentR\n" +
+	"experiment\x126\n" +
+	"\x06mirror\x18\x14 \x01(\v2\x1e.gatewayadmin.config.v1.MirrorR\x06mirror\x12<\n" +
+	"\bfallback\x18\x15 \x01(\v2 .gatewayadmin.config.v1.FallbackR\bfallback\x120\n" +
+	"\x04grpc\x18\x16 \x01(\v2\x1c.gatewayadmin.config.v1.GRPCR\x04grpc\x12D\n" +
+	"\vmiddlewares\x18\x17 \x03(\v2\".gatewayadmin.config.v1.MiddlewareR\vmiddlewares\x12D\n" +
+	"\aplugins\x18\x18 \x03(\v2*.gatewayadmin.config.v1.Route.PluginsEntryR\aplugins\x12E\n" +
+	"\fquery_params\x18\x19 \x03(\v2\".gatewayadmin.config.v1.QueryParamR\vqueryParams\x1aE\n" +
+	"\x17DeprecationHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a:\n" +
+	"\fPluginsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\"U\n" +
+	"\vTranscoding\x12!\n" +
+	"\frequest_type\x18\x01 \x01(\tR\vrequestType\x12#\n" +
+	"\rresponse_type\x18\x02 \x01(\tR\fresponseType\"\x9e\x01\n" +
+	"\x06Schema\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x05R\aversion\x12;\n" +
+	"\arequest\x18\x02 \x01(\v2!.gatewayadmin.config.v1.SchemaRefR\arequest\x12=\n" +
+	"\bresponse\x18\x03 \x01(\v2!.gatewayadmin.config.v1.SchemaRefR\bresponse\"F\n" +
+	"\tSchemaRef\x12\x1f\n" +
+	"\vjson_schema\x18\x01 \x01(\fR\n" +
+	"jsonSchema\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x8d\x01\n" +
+	"\tTransform\x12>\n" +
+	"\arequest\x18\x01 \x01(\v2$.gatewayadmin.config.v1.BodyTemplateR\arequest\x12@\n" +
+	"\bresponse\x18\x02 \x01(\v2$.gatewayadmin.config.v1.BodyTemplateR\bresponse\"F\n" +
+	"\fBodyTemplate\x12\x1a\n" +
+	"\blanguage\x18\x01 \x01(\tR\blanguage\x12\x1a\n" +
+	"\btemplate\x18\x02 \x01(\tR\btemplate\"j\n" +
+	"\x05Cache\x12\x1f\n" +
+	"\vttl_seconds\x18\x01 \x01(\x05R\n" +
+	"ttlSeconds\x12!\n" +
+	"\fvary_headers\x18\x02 \x03(\tR\vvaryHeaders\x12\x1d\n" +
+	"\n" +
+	"allow_post\x18\x03 \x01(\bR\tallowPost\">\n" +
+	"\aRollout\x12\x18\n" +
+	"\apercent\x18\x01 \x01(\x05R\apercent\x12\x19\n" +
+	"\bhash_key\x18\x02 \x01(\tR\ahashKey\"d\n" +
+	"\n" +
+	"Experiment\x12\x19\n" +
+	"\bhash_key\x18\x01 \x01(\tR\ahashKey\x12;\n" +
+	"\bvariants\x18\x02 \x03(\v2\x1f.gatewayadmin.config.v1.VariantR\bvariants\"\xe6\x01\n" +
+	"\aVariant\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12!\n" +
+	"\fbackend_name\x18\x02 \x01(\tR\vbackendName\x12'\n" +
+	"\x0fbackend_service\x18\x03 \x01(\tR\x0ebackendService\x12%\n" +
+	"\x0ebackend_method\x18\x04 \x01(\tR\rbackendMethod\x12\x18\n" +
+	"\apercent\x18\x05 \x01(\x05R\apercent\x12:\n" +
+	"\x05match\x18\x06 \x01(\v2$.gatewayadmin.config.v1.VariantMatchR\x05match\"<\n" +
+	"\fVariantMatch\x12\x16\n" +
+	"\x06header\x18\x01 \x01(\tR\x06header\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"\x95\x01\n" +
+	"\x06Mirror\x12!\n" +
+	"\fbackend_name\x18\x01 \x01(\tR\vbackendName\x12'\n" +
+	"\x0fbackend_service\x18\x02 \x01(\tR\x0ebackendService\x12%\n" +
+	"\x0ebackend_method\x18\x03 \x01(\tR\rbackendMethod\x12\x18\n" +
+	"\apercent\x18\x04 \x01(\x05R\apercent\"}\n" +
+	"\bFallback\x12!\n" +
+	"\fbackend_name\x18\x01 \x01(\tR\vbackendName\x12'\n" +
+	"\x0fbackend_service\x18\x02 \x01(\tR\x0ebackendService\x12%\n" +
+	"\x0ebackend_method\x18\x03 \x01(\tR\rbackendMethod\"\xa1\x01\n" +
+	"\x04GRPC\x12-\n" +
+	"\x12propagate_deadline\x18\x01 \x01(\bR\x11propagateDeadline\x12@\n" +
+	"\aheaders\x18\x02 \x03(\v2&.gatewayadmin.config.v1.MetadataHeaderR\aheaders\x12\x14\n" +
+	"\x05allow\x18\x03 \x03(\tR\x05allow\x12\x12\n" +
+	"\x04deny\x18\x04 \x03(\tR\x04deny\":\n" +
+	"\x0eMetadataHeader\x12\x16\n" +
+	"\x06header\x18\x01 \x01(\tR\x06header\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\"8\n" +
+	"\n" +
+	"Middleware\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06params\x18\x02 \x01(\fR\x06params\"l\n" +
+	"\n" +
+	"QueryParam\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05field\x18\x02 \x01(\tR\x05field\x12\x18\n" +
+	"\adefault\x18\x03 \x01(\tR\adefault\x12\x1a\n" +
+	"\brequired\x18\x04 \x01(\bR\brequiredBQZOgithub.com/sunshine-walker-93/assistant_gateway_admin/pkg/api/configv1;configv1b\x06proto3"
+
+var (
+	file_configv1_gateway_config_proto_rawDescOnce sync.Once
+	file_configv1_gateway_config_proto_rawDescData []byte
+)
+
+func file_configv1_gateway_config_proto_rawDescGZIP() []byte {
+	file_configv1_gateway_config_proto_rawDescOnce.Do(func() {
+		file_configv1_gateway_config_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_configv1_gateway_config_proto_rawDesc), len(file_configv1_gateway_config_proto_rawDesc)))
+	})
+	return file_configv1_gateway_config_proto_rawDescData
+}
+
+var file_configv1_gateway_config_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
+var file_configv1_gateway_config_proto_goTypes = []any{
+	(*GatewayConfig)(nil),  // 0: gatewayadmin.config.v1.GatewayConfig
+	(*ConfigDelta)(nil),    // 1: gatewayadmin.config.v1.ConfigDelta
+	(*Backend)(nil),        // 2: gatewayadmin.config.v1.Backend
+	(*Descriptors)(nil),    // 3: gatewayadmin.config.v1.Descriptors
+	(*CircuitBreaker)(nil), // 4: gatewayadmin.config.v1.CircuitBreaker
+	(*Route)(nil),          // 5: gatewayadmin.config.v1.Route
+	(*Transcoding)(nil),    // 6: gatewayadmin.config.v1.Transcoding
+	(*Schema)(nil),         // 7: gatewayadmin.config.v1.Schema
+	(*SchemaRef)(nil),      // 8: gatewayadmin.config.v1.SchemaRef
+	(*Transform)(nil),      // 9: gatewayadmin.config.v1.Transform
+	(*BodyTemplate)(nil),   // 10: gatewayadmin.config.v1.BodyTemplate
+	(*Cache)(nil),          // 11: gatewayadmin.config.v1.Cache
+	(*Rollout)(nil),        // 12: gatewayadmin.config.v1.Rollout
+	(*Experiment)(nil),     // 13: gatewayadmin.config.v1.Experiment
+	(*Variant)(nil),        // 14: gatewayadmin.config.v1.Variant
+	(*VariantMatch)(nil),   // 15: gatewayadmin.config.v1.VariantMatch
+	(*Mirror)(nil),         // 16: gatewayadmin.config.v1.Mirror
+	(*Fallback)(nil),       // 17: gatewayadmin.config.v1.Fallback
+	(*GRPC)(nil),           // 18: gatewayadmin.config.v1.GRPC
+	(*MetadataHeader)(nil), // 19: gatewayadmin.config.v1.MetadataHeader
+	(*Middleware)(nil),     // 20: gatewayadmin.config.v1.Middleware
+	(*QueryParam)(nil),     // 21: gatewayadmin.config.v1.QueryParam
+	nil,                    // 22: gatewayadmin.config.v1.Backend.PluginsEntry
+	nil,                    // 23: gatewayadmin.config.v1.Route.DeprecationHeadersEntry
+	nil,                    // 24: gatewayadmin.config.v1.Route.PluginsEntry
+}
+var file_configv1_gateway_config_proto_depIdxs = []int32{
+	2,  // 0: gatewayadmin.config.v1.GatewayConfig.backends:type_name -> gatewayadmin.config.v1.Backend
+	5,  // 1: gatewayadmin.config.v1.GatewayConfig.routes:type_name -> gatewayadmin.config.v1.Route
+	2,  // 2: gatewayadmin.config.v1.ConfigDelta.backends:type_name -> gatewayadmin.config.v1.Backend
+	5,  // 3: gatewayadmin.config.v1.ConfigDelta.routes:type_name -> gatewayadmin.config.v1.Route
+	3,  // 4: gatewayadmin.config.v1.Backend.descriptors:type_name -> gatewayadmin.config.v1.Descriptors
+	4,  // 5: gatewayadmin.config.v1.Backend.circuit_breaker:type_name -> gatewayadmin.config.v1.CircuitBreaker
+	22, // 6: gatewayadmin.config.v1.Backend.plugins:type_name -> gatewayadmin.config.v1.Backend.PluginsEntry
+	23, // 7: gatewayadmin.config.v1.Route.deprecation_headers:type_name -> gatewayadmin.config.v1.Route.DeprecationHeadersEntry
+	6,  // 8: gatewayadmin.config.v1.Route.transcoding:type_name -> gatewayadmin.config.v1.Transcoding
+	7,  // 9: gatewayadmin.config.v1.Route.schema:type_name -> gatewayadmin.config.v1.Schema
+	9,  // 10: gatewayadmin.config.v1.Route.transform:type_name -> gatewayadmin.config.v1.Transform
+	11, // 11: gatewayadmin.config.v1.Route.cache:type_name -> gatewayadmin.config.v1.Cache
+	4,  // 12: gatewayadmin.config.v1.Route.circuit_breaker:type_name -> gatewayadmin.config.v1.CircuitBreaker
+	12, // 13: gatewayadmin.config.v1.Route.rollout:type_name -> gatewayadmin.config.v1.Rollout
+	13, // 14: gatewayadmin.config.v1.Route.experiment:type_name -> gatewayadmin.config.v1.Experiment
+	16, // 15: gatewayadmin.config.v1.Route.mirror:type_name -> gatewayadmin.config.v1.Mirror
+	17, // 16: gatewayadmin.config.v1.Route.fallback:type_name -> gatewayadmin.config.v1.Fallback
+	18, // 17: gatewayadmin.config.v1.Route.grpc:type_name -> gatewayadmin.config.v1.GRPC
+	20, // 18: gatewayadmin.config.v1.Route.middlewares:type_name -> gatewayadmin.config.v1.Middleware
+	24, // 19: gatewayadmin.config.v1.Route.plugins:type_name -> gatewayadmin.config.v1.Route.PluginsEntry
+	21, // 20: gatewayadmin.config.v1.Route.query_params:type_name -> gatewayadmin.config.v1.QueryParam
+	8,  // 21: gatewayadmin.config.v1.Schema.request:type_name -> gatewayadmin.config.v1.SchemaRef
+	8,  // 22: gatewayadmin.config.v1.Schema.response:type_name -> gatewayadmin.config.v1.SchemaRef
+	10, // 23: gatewayadmin.config.v1.Transform.request:type_name -> gatewayadmin.config.v1.BodyTemplate
+	10, // 24: gatewayadmin.config.v1.Transform.response:type_name -> gatewayadmin.config.v1.BodyTemplate
+	14, // 25: gatewayadmin.config.v1.Experiment.variants:type_name -> gatewayadmin.config.v1.Variant
+	15, // 26: gatewayadmin.config.v1.Variant.match:type_name -> gatewayadmin.config.v1.VariantMatch
+	19, // 27: gatewayadmin.config.v1.GRPC.headers:type_name -> gatewayadmin.config.v1.MetadataHeader
+	28, // [28:28] is the sub-list for method output_type
+	28, // [28:28] is the sub-list for method input_type
+	28, // [28:28] is the sub-list for extension type_name
+	28, // [28:28] is the sub-list for extension extendee
+	0,  // [0:28] is the sub-list for field type_name
+}
+
+func init() { file_configv1_gateway_config_proto_init() }
+func file_configv1_gateway_config_proto_init() {
+	if File_configv1_gateway_config_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_configv1_gateway_config_proto_rawDesc), len(file_configv1_gateway_config_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   25,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_configv1_gateway_config_proto_goTypes,
+		DependencyIndexes: file_configv1_gateway_config_proto_depIdxs,
+		MessageInfos:      file_configv1_gateway_config_proto_msgTypes,
+	}.Build()
+	File_configv1_gateway_config_proto = out.File
+	file_configv1_gateway_config_proto_goTypes = nil
+	file_configv1_gateway_config_proto_depIdxs = nil
+}
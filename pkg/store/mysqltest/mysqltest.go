@@ -0,0 +1,126 @@
+// Package mysqltest provides disposable MySQL databases for integration
+// tests of the MySQL store and the API handlers:
+//
+//	func TestBackends(t *testing.T) {
+//	    s := mysqltest.NewStore(t)
+//	    storetest.TestStore(t, s)
+//	}
+//
+// By default each call starts a MySQL container with testcontainers, which
+// needs a Docker daemon; the test is skipped when none is reachable. Set
+// ADMIN_TEST_DB_DSN to use an existing database instead, such as the one
+// started by "make dev-up".
+package mysqltest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+	tcmysql "github.com/testcontainers/testcontainers-go/modules/mysql"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+	"github.com/sunshine-walker-93/assistant_gateway_admin/internal/secret"
+)
+
+// Image is the MySQL image used for test containers.
+const Image = "mysql:8.0"
+
+// DSNEnv names the environment variable that points tests at an existing
+// database instead of a container.
+const DSNEnv = "ADMIN_TEST_DB_DSN"
+
+// Instance is a running MySQL container.
+type Instance struct {
+	// DSN connects to the instance's assistant_gateway_db database.
+	DSN string
+
+	container *tcmysql.MySQLContainer
+}
+
+// Start runs a MySQL container and waits until it accepts connections.
+func Start(ctx context.Context) (*Instance, error) {
+	container, err := tcmysql.Run(ctx, Image,
+		tcmysql.WithDatabase("assistant_gateway_db"),
+		tcmysql.WithUsername("assistant"),
+		tcmysql.WithPassword("assistant"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("start mysql container: %w", err)
+	}
+
+	dsn, err := container.ConnectionString(ctx, "parseTime=true")
+	if err != nil {
+		testcontainers.TerminateContainer(container)
+		return nil, err
+	}
+
+	return &Instance{DSN: dsn, container: container}, nil
+}
+
+// Terminate stops and removes the container.
+func (i *Instance) Terminate() error {
+	return testcontainers.TerminateContainer(i.container)
+}
+
+// Open connects to dsn, applies all migrations and configures a random
+// encryption key, returning a Store ready for use.
+func Open(dsn string) (*config.MySQLStore, error) {
+	store, err := config.NewMySQLStore(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+
+	if err := store.Migrate(); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		store.Close()
+		return nil, err
+	}
+	keyring, err := secret.NewKeyring("test:" + base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	store.SetCipher(keyring)
+
+	return store, nil
+}
+
+// NewStore returns a migrated Store for t, backed by ADMIN_TEST_DB_DSN if
+// set and otherwise by a fresh container that is removed when t finishes.
+func NewStore(t *testing.T) *config.MySQLStore {
+	t.Helper()
+
+	dsn := os.Getenv(DSNEnv)
+	if dsn == "" {
+		testcontainers.SkipIfProviderIsNotHealthy(t)
+
+		instance, err := Start(context.Background())
+		if err != nil {
+			t.Fatalf("mysqltest: %v", err)
+		}
+		t.Cleanup(func() {
+			if err := instance.Terminate(); err != nil {
+				t.Logf("mysqltest: terminate container: %v", err)
+			}
+		})
+		dsn = instance.DSN
+	}
+
+	store, err := Open(dsn)
+	if err != nil {
+		t.Fatalf("mysqltest: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
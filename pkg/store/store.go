@@ -0,0 +1,161 @@
+// Package store is the importable face of the admin service's storage
+// layer. It re-exports the Store interface and its record types so that
+// alternative Store implementations and downstream tests can be written
+// outside this module; see storetest for the conformance suite and
+// mockstore for a generated mock.
+package store
+
+import "github.com/sunshine-walker-93/assistant_gateway_admin/internal/config"
+
+//go:generate go tool mockgen -destination=mockstore/mock_store.go -package=mockstore github.com/sunshine-walker-93/assistant_gateway_admin/pkg/store Store
+
+// Store is the configuration storage interface.
+type Store = config.Store
+
+// Record types used by Store.
+type (
+	Backend           = config.Backend
+	BackendCredential = config.BackendCredential
+	BackendTLS        = config.BackendTLS
+	CircuitBreaker    = config.CircuitBreaker
+	RouteRollout      = config.RouteRollout
+	RouteExperiment   = config.RouteExperiment
+	RouteVariant      = config.RouteVariant
+	VariantMatch      = config.VariantMatch
+	RouteMirror       = config.RouteMirror
+	RouteFallback     = config.RouteFallback
+	RouteGRPC         = config.RouteGRPC
+	MetadataHeader    = config.MetadataHeader
+	RouteMiddleware   = config.RouteMiddleware
+	QueryParam        = config.QueryParam
+	RouteDeprecation  = config.RouteDeprecation
+	RouteDocs         = config.RouteDocs
+	Route             = config.Route
+	RouteTransform    = config.RouteTransform
+	BodyTemplate      = config.BodyTemplate
+	RouteCache        = config.RouteCache
+	ConfigHistory     = config.ConfigHistory
+	FreezeWindow      = config.FreezeWindow
+	LatencySample     = config.LatencySample
+	RouteStats        = config.RouteStats
+	Snapshot          = config.Snapshot
+	DescriptorSet     = config.DescriptorSet
+	RouteSchema       = config.RouteSchema
+	SchemaRef         = config.SchemaRef
+	PluginSchema      = config.PluginSchema
+	Job               = config.Job
+	JobProgress       = config.JobProgress
+	GatewayInstance   = config.GatewayInstance
+	ConfigRollout     = config.ConfigRollout
+	RolloutHealth     = config.RolloutHealth
+	OutboxEntry       = config.OutboxEntry
+	OutboxSink        = config.OutboxSink
+	Team              = config.Team
+	TeamMember        = config.TeamMember
+	User              = config.User
+	Session           = config.Session
+	UserTOTP          = config.UserTOTP
+	Group             = config.Group
+)
+
+// LatencyStore is an optional capability for keeping backend health check
+// samples.
+type LatencyStore = config.LatencyStore
+
+// StatsStore is an optional capability for keeping per-route traffic
+// aggregates reported by gateways.
+type StatsStore = config.StatsStore
+
+// SnapshotStore is an optional capability for keeping named snapshots of
+// the whole configuration.
+type SnapshotStore = config.SnapshotStore
+
+// DescriptorStore is an optional capability for keeping versioned protobuf
+// descriptor sets of backends.
+type DescriptorStore = config.DescriptorStore
+
+// SchemaStore is an optional capability for keeping versioned request and
+// response schemas of routes.
+type SchemaStore = config.SchemaStore
+
+// PluginSchemaStore is an optional capability for keeping the JSON Schemas
+// of plugin configs.
+type PluginSchemaStore = config.PluginSchemaStore
+
+// JobStore is an optional capability for keeping background jobs.
+type JobStore = config.JobStore
+
+// GatewayStore is an optional capability for keeping the registry of
+// gateway instances and the config each has loaded.
+type GatewayStore = config.GatewayStore
+
+// RolloutStore is an optional capability for keeping config rollouts and
+// the traffic gateways report during them.
+type RolloutStore = config.RolloutStore
+
+// OutboxStore is an optional capability for keeping change events until
+// they are delivered to every sink.
+type OutboxStore = config.OutboxStore
+
+// TeamStore is an optional capability for keeping the teams that own
+// backends and routes, and their members.
+type TeamStore = config.TeamStore
+
+// UserStore is an optional capability for keeping local users that sign
+// in with a password.
+type UserStore = config.UserStore
+
+// GroupStore is an optional capability for keeping the groups of users
+// pushed by an identity provider over SCIM.
+type GroupStore = config.GroupStore
+
+// Watcher is an optional capability for picking up changes made to a
+// store's data outside the service.
+type Watcher = config.Watcher
+
+// Backend credential types.
+const (
+	CredentialNone   = config.CredentialNone
+	CredentialBearer = config.CredentialBearer
+	CredentialBasic  = config.CredentialBasic
+)
+
+// Backend states.
+const (
+	BackendEnabled  = config.BackendEnabled
+	BackendDisabled = config.BackendDisabled
+	BackendDraining = config.BackendDraining
+)
+
+// Route lifecycle states.
+const (
+	LifecycleActive     = config.LifecycleActive
+	LifecycleDeprecated = config.LifecycleDeprecated
+	LifecycleRetired    = config.LifecycleRetired
+)
+
+// Job states.
+const (
+	JobPending   = config.JobPending
+	JobRunning   = config.JobRunning
+	JobSucceeded = config.JobSucceeded
+	JobFailed    = config.JobFailed
+)
+
+// Config rollout states.
+const (
+	RolloutInProgress = config.RolloutInProgress
+	RolloutPromoted   = config.RolloutPromoted
+	RolloutAborted    = config.RolloutAborted
+)
+
+// TemplateGo is the language of Go text/template body templates.
+const TemplateGo = config.TemplateGo
+
+// LatencyBucketBounds are the upper bounds, in milliseconds, of the route
+// latency histogram.
+var LatencyBucketBounds = config.LatencyBucketBounds
+
+// ErrEncryptionDisabled is returned when an inline secret is stored without
+// an encryption key configured.
+var ErrEncryptionDisabled = config.ErrEncryptionDisabled
@@ -0,0 +1,1615 @@
+// Package storetest provides a conformance suite for store.Store
+// implementations. Run it from an implementation's tests against a fresh
+// or disposable store:
+//
+//	func TestMyStore(t *testing.T) {
+//	    storetest.TestStore(t, newMyStore(t))
+//	}
+//
+// The suite only creates records with unique names, so it can also run
+// against a store that already holds data, but it leaves its records
+// behind.
+package storetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunshine-walker-93/assistant_gateway_admin/pkg/store"
+)
+
+// TestStore runs every conformance check against s.
+func TestStore(t *testing.T, s store.Store) {
+	t.Helper()
+
+	t.Run("Backends", func(t *testing.T) { testBackends(t, s) })
+	t.Run("BackendSecrets", func(t *testing.T) { testBackendSecrets(t, s) })
+	t.Run("Routes", func(t *testing.T) { testRoutes(t, s) })
+	t.Run("History", func(t *testing.T) { testHistory(t, s) })
+	t.Run("FreezeWindows", func(t *testing.T) { testFreezeWindows(t, s) })
+	t.Run("Transactions", func(t *testing.T) { testTransactions(t, s) })
+	if ls, ok := s.(store.LatencyStore); ok {
+		t.Run("Latency", func(t *testing.T) { testLatency(t, ls) })
+	}
+	if ss, ok := s.(store.StatsStore); ok {
+		t.Run("RouteStats", func(t *testing.T) { testRouteStats(t, s, ss) })
+	}
+	if ss, ok := s.(store.SnapshotStore); ok {
+		t.Run("Snapshots", func(t *testing.T) { testSnapshots(t, ss) })
+	}
+	if ds, ok := s.(store.DescriptorStore); ok {
+		t.Run("Descriptors", func(t *testing.T) { testDescriptors(t, ds) })
+	}
+	if ss, ok := s.(store.SchemaStore); ok {
+		t.Run("RouteSchemas", func(t *testing.T) { testRouteSchemas(t, ss) })
+	}
+	if ps, ok := s.(store.PluginSchemaStore); ok {
+		t.Run("PluginSchemas", func(t *testing.T) { testPluginSchemas(t, ps) })
+	}
+	if js, ok := s.(store.JobStore); ok {
+		t.Run("Jobs", func(t *testing.T) { testJobs(t, js) })
+	}
+	if gs, ok := s.(store.GatewayStore); ok {
+		t.Run("Gateways", func(t *testing.T) { testGateways(t, gs) })
+	}
+	if rs, ok := s.(store.RolloutStore); ok {
+		t.Run("Rollouts", func(t *testing.T) { testRollouts(t, rs) })
+	}
+	if _, ok := s.(store.OutboxStore); ok {
+		t.Run("Outbox", func(t *testing.T) { testOutbox(t, s) })
+	}
+	if ts, ok := s.(store.TeamStore); ok {
+		t.Run("Teams", func(t *testing.T) { testTeams(t, ts) })
+	}
+	if us, ok := s.(store.UserStore); ok {
+		t.Run("Users", func(t *testing.T) { testUsers(t, us) })
+	}
+	if gs, ok := s.(store.GroupStore); ok {
+		t.Run("Groups", func(t *testing.T) { testGroups(t, s, gs) })
+	}
+}
+
+// missingID is a route ID no conformance run will reach.
+const missingID = 1<<31 - 1
+
+// uniqueName returns a name that does not collide with earlier runs.
+func uniqueName(prefix string) string {
+	return "st-" + prefix + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+func testBackends(t *testing.T, s store.Store) {
+	name := uniqueName("backend")
+
+	got, err := s.GetBackendByName(name)
+	if err != nil {
+		t.Fatalf("GetBackendByName(missing): %v", err)
+	}
+	if got != nil {
+		t.Fatalf("GetBackendByName(missing) = %+v, want nil", got)
+	}
+
+	b := &store.Backend{Name: name, Addr: "127.0.0.1:9000", Description: "conformance", Clusters: []string{"public", "internal"}, OwnerTeam: "payments", Enabled: true}
+	if err := s.CreateBackend(b); err != nil {
+		t.Fatalf("CreateBackend: %v", err)
+	}
+	if b.ID == 0 {
+		t.Error("CreateBackend did not assign an ID")
+	}
+
+	got, err = s.GetBackendByName(name)
+	if err != nil || got == nil {
+		t.Fatalf("GetBackendByName = %v, %v; want the created backend", got, err)
+	}
+	if got.ID != b.ID || got.Addr != b.Addr || got.Description != b.Description || !reflect.DeepEqual(got.Clusters, b.Clusters) || got.OwnerTeam != "payments" || !got.Enabled {
+		t.Errorf("GetBackendByName = %+v, want %+v", got, b)
+	}
+
+	b.Addr = "127.0.0.1:9001"
+	b.Description = ""
+	b.Clusters = nil
+	b.OwnerTeam = ""
+	b.CircuitBreaker = &store.CircuitBreaker{MaxRequests: 100, Consecutive5xx: 5}
+	if err := s.UpdateBackend(name, b); err != nil {
+		t.Fatalf("UpdateBackend: %v", err)
+	}
+	got, _ = s.GetBackendByName(name)
+	if got == nil || got.Addr != "127.0.0.1:9001" || got.Description != "" || len(got.Clusters) != 0 || got.OwnerTeam != "" {
+		t.Errorf("after UpdateBackend got %+v", got)
+	}
+	if got != nil && !reflect.DeepEqual(got.CircuitBreaker, b.CircuitBreaker) {
+		t.Errorf("after UpdateBackend circuit breaker = %+v, want %+v", got.CircuitBreaker, b.CircuitBreaker)
+	}
+
+	b.Status = store.BackendDraining
+	if err := s.UpdateBackend(name, b); err != nil {
+		t.Fatalf("UpdateBackend(draining): %v", err)
+	}
+	got, _ = s.GetBackendByName(name)
+	if got == nil || got.Status != store.BackendDraining || !got.Enabled {
+		t.Errorf("after UpdateBackend(draining) got %+v, want an enabled, draining backend", got)
+	}
+	b.Status = store.BackendEnabled
+	if err := s.UpdateBackend(name, b); err != nil {
+		t.Fatalf("UpdateBackend(enabled): %v", err)
+	}
+
+	if err := s.UpdateBackend(uniqueName("missing"), b); err == nil {
+		t.Error("UpdateBackend(missing) succeeded, want error")
+	}
+
+	enabled := true
+	if !containsBackend(t, s, &enabled, name) {
+		t.Error("GetBackends(enabled=true) does not include an enabled backend")
+	}
+
+	// Deletes are soft: the backend stays readable but disabled
+	if err := s.DeleteBackend(name); err != nil {
+		t.Fatalf("DeleteBackend: %v", err)
+	}
+	got, _ = s.GetBackendByName(name)
+	if got == nil || got.Enabled {
+		t.Errorf("after DeleteBackend got %+v, want disabled backend", got)
+	}
+	if containsBackend(t, s, &enabled, name) {
+		t.Error("GetBackends(enabled=true) includes a deleted backend")
+	}
+	if !containsBackend(t, s, nil, name) {
+		t.Error("GetBackends(nil) does not include a deleted backend")
+	}
+
+	if err := s.DeleteBackend(uniqueName("missing")); err == nil {
+		t.Error("DeleteBackend(missing) succeeded, want error")
+	}
+}
+
+func containsBackend(t *testing.T, s store.Store, enabled *bool, name string) bool {
+	t.Helper()
+	backends, err := s.GetBackends(enabled)
+	if err != nil {
+		t.Fatalf("GetBackends: %v", err)
+	}
+	for _, b := range backends {
+		if b.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func testBackendSecrets(t *testing.T, s store.Store) {
+	name := uniqueName("secret")
+	b := &store.Backend{
+		Name:    name,
+		Addr:    "127.0.0.1:9000",
+		Enabled: true,
+		Credential: &store.BackendCredential{
+			Type:   store.CredentialBearer,
+			Secret: "conformance-token",
+		},
+		TLS: &store.BackendTLS{ServerName: "backend.internal"},
+	}
+
+	err := s.CreateBackend(b)
+	if errors.Is(err, store.ErrEncryptionDisabled) {
+		t.Skip("store has no encryption key configured")
+	}
+	if err != nil {
+		t.Fatalf("CreateBackend: %v", err)
+	}
+
+	got, err := s.GetBackendByName(name)
+	if err != nil || got == nil {
+		t.Fatalf("GetBackendByName = %v, %v", got, err)
+	}
+	if got.Credential == nil || got.Credential.Secret != "conformance-token" {
+		t.Errorf("credential secret did not round-trip: %+v", got.Credential)
+	}
+	if got.TLS == nil || got.TLS.ServerName != "backend.internal" {
+		t.Errorf("TLS settings did not round-trip: %+v", got.TLS)
+	}
+
+	// Secrets must never appear in JSON output
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshal backend: %v", err)
+	}
+	var out map[string]interface{}
+	json.Unmarshal(data, &out)
+	cred, _ := out["credential"].(map[string]interface{})
+	if _, ok := cred["secret"]; ok {
+		t.Error("backend JSON exposes the credential secret")
+	}
+	if cred["has_secret"] != true {
+		t.Error("backend JSON does not report has_secret")
+	}
+}
+
+func testRoutes(t *testing.T, s store.Store) {
+	backend := uniqueName("route-backend")
+	if err := s.CreateBackend(&store.Backend{Name: backend, Addr: "127.0.0.1:9000", Enabled: true}); err != nil {
+		t.Fatalf("CreateBackend: %v", err)
+	}
+
+	got, err := s.GetRouteByID(missingID)
+	if err != nil {
+		t.Fatalf("GetRouteByID(missing): %v", err)
+	}
+	if got != nil {
+		t.Fatalf("GetRouteByID(missing) = %+v, want nil", got)
+	}
+
+	r := &store.Route{
+		HTTPMethod:      "GET",
+		HTTPPattern:     "/" + uniqueName("path"),
+		BackendName:     backend,
+		BackendService:  "conformance.Service",
+		BackendMethod:   "Get",
+		TimeoutMS:       1500,
+		MaxRequestBytes: 8 << 20,
+		ContentTypes:    []string{"application/json", "multipart/form-data"},
+		Clusters:        []string{"internal"},
+		APIVersion:      "v1",
+		Cache:           &store.RouteCache{TTLSeconds: 60, VaryHeaders: []string{"Accept-Language"}},
+		CircuitBreaker:  &store.CircuitBreaker{EjectionSeconds: 10},
+		Rollout:         &store.RouteRollout{Percent: 5, HashKey: "header:X-User-Id"},
+		Experiment: &store.RouteExperiment{Variants: []store.RouteVariant{
+			{Name: "b", BackendMethod: "GetV2", Percent: 10, Match: &store.VariantMatch{Header: "X-Variant", Value: "b"}},
+		}},
+		Mirror:   &store.RouteMirror{BackendName: "svc-shadow", Percent: 20},
+		Fallback: &store.RouteFallback{BackendMethod: "GetCached"},
+		GRPC: &store.RouteGRPC{
+			PropagateDeadline: true,
+			Headers:           []store.MetadataHeader{{Header: "X-User-Id", Key: "user-id"}},
+			Deny:              []string{"cookie"},
+		},
+		Middlewares: []store.RouteMiddleware{
+			{Name: "auth", Params: map[string]interface{}{"scheme": "jwt"}},
+			{Name: "logging"},
+		},
+		Docs: &store.RouteDocs{
+			Summary:         "Get a thing",
+			ResponseExample: json.RawMessage(`{"id": 1}`),
+			ExternalURL:     "https://example.com/docs/things",
+		},
+		Plugins:     map[string]json.RawMessage{"acme.quota": json.RawMessage(`{"limit": 10}`)},
+		QueryParams: []store.QueryParam{{Name: "size", Field: "page.size", Default: "20"}, {Name: "q", Field: "query", Required: true}},
+		OwnerTeam:   "search",
+		Enabled:     true,
+	}
+	if err := s.CreateRoute(r); err != nil {
+		t.Fatalf("CreateRoute: %v", err)
+	}
+	if r.ID == 0 {
+		t.Error("CreateRoute did not assign an ID")
+	}
+
+	got, err = s.GetRouteByID(r.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetRouteByID = %v, %v; want the created route", got, err)
+	}
+	if got.HTTPPattern != r.HTTPPattern || got.BackendName != backend || got.TimeoutMS != 1500 || got.MaxRequestBytes != 8<<20 || got.OwnerTeam != "search" || !got.Enabled {
+		t.Errorf("GetRouteByID = %+v, want %+v", got, r)
+	}
+	if !reflect.DeepEqual(got.Cache, r.Cache) || !reflect.DeepEqual(got.CircuitBreaker, r.CircuitBreaker) || !reflect.DeepEqual(got.Rollout, r.Rollout) {
+		t.Errorf("GetRouteByID cache, circuit breaker, rollout = %+v, %+v, %+v; want %+v, %+v, %+v",
+			got.Cache, got.CircuitBreaker, got.Rollout, r.Cache, r.CircuitBreaker, r.Rollout)
+	}
+	if !reflect.DeepEqual(got.Experiment, r.Experiment) || !reflect.DeepEqual(got.Mirror, r.Mirror) ||
+		!reflect.DeepEqual(got.Fallback, r.Fallback) {
+		t.Errorf("GetRouteByID experiment, mirror, fallback = %+v, %+v, %+v; want %+v, %+v, %+v",
+			got.Experiment, got.Mirror, got.Fallback, r.Experiment, r.Mirror, r.Fallback)
+	}
+	if !reflect.DeepEqual(got.ContentTypes, r.ContentTypes) {
+		t.Errorf("GetRouteByID content types = %v, want %v", got.ContentTypes, r.ContentTypes)
+	}
+	if !reflect.DeepEqual(got.Clusters, r.Clusters) {
+		t.Errorf("GetRouteByID clusters = %v, want %v", got.Clusters, r.Clusters)
+	}
+	var plugin map[string]interface{}
+	if json.Unmarshal(got.Plugins["acme.quota"], &plugin) != nil || plugin["limit"] != float64(10) {
+		t.Errorf("GetRouteByID plugins = %s, want acme.quota limit 10", got.Plugins)
+	}
+	if got.APIVersion != "v1" || got.Lifecycle != store.LifecycleActive {
+		t.Errorf("GetRouteByID api version, lifecycle = %q, %q; want v1, %s", got.APIVersion, got.Lifecycle, store.LifecycleActive)
+	}
+	var example map[string]interface{}
+	if got.Docs == nil || got.Docs.Summary != r.Docs.Summary || got.Docs.ExternalURL != r.Docs.ExternalURL || got.Docs.RequestExample != nil ||
+		json.Unmarshal(got.Docs.ResponseExample, &example) != nil || example["id"] != float64(1) {
+		t.Errorf("GetRouteByID docs = %+v, want %+v", got.Docs, r.Docs)
+	}
+	if !reflect.DeepEqual(got.QueryParams, r.QueryParams) {
+		t.Errorf("GetRouteByID query params = %+v, want %+v", got.QueryParams, r.QueryParams)
+	}
+	if !reflect.DeepEqual(got.GRPC, r.GRPC) || !reflect.DeepEqual(got.Middlewares, r.Middlewares) {
+		t.Errorf("GetRouteByID grpc, middlewares = %+v, %+v; want %+v, %+v", got.GRPC, got.Middlewares, r.GRPC, r.Middlewares)
+	}
+
+	r.TimeoutMS = 3000
+	r.Description = "updated"
+	r.Lifecycle = store.LifecycleDeprecated
+	sunset := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.Deprecation = &store.RouteDeprecation{Date: time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), Sunset: &sunset, Link: "https://example.com/v2"}
+	r.Transform = &store.RouteTransform{Request: &store.BodyTemplate{Language: store.TemplateGo, Template: `{"name": {{json .user}}}`}}
+	if err := s.UpdateRoute(r.ID, r); err != nil {
+		t.Fatalf("UpdateRoute: %v", err)
+	}
+	got, _ = s.GetRouteByID(r.ID)
+	if got == nil || got.TimeoutMS != 3000 || got.Description != "updated" || got.Lifecycle != store.LifecycleDeprecated {
+		t.Errorf("after UpdateRoute got %+v", got)
+	}
+	if got != nil && (got.Deprecation == nil || !got.Deprecation.Date.Equal(r.Deprecation.Date) || got.Deprecation.Sunset == nil ||
+		!got.Deprecation.Sunset.Equal(sunset) || got.Deprecation.Link != r.Deprecation.Link) {
+		t.Errorf("after UpdateRoute deprecation = %+v, want %+v", got.Deprecation, r.Deprecation)
+	}
+	if got != nil && (got.Transform == nil || got.Transform.Request == nil || *got.Transform.Request != *r.Transform.Request || got.Transform.Response != nil) {
+		t.Errorf("after UpdateRoute transform = %+v, want %+v", got.Transform, r.Transform)
+	}
+
+	if err := s.DeleteRoute(r.ID); err != nil {
+		t.Fatalf("DeleteRoute: %v", err)
+	}
+	got, _ = s.GetRouteByID(r.ID)
+	if got == nil || got.Enabled {
+		t.Errorf("after DeleteRoute got %+v, want disabled route", got)
+	}
+
+	enabled := true
+	routes, err := s.GetRoutes(&enabled)
+	if err != nil {
+		t.Fatalf("GetRoutes: %v", err)
+	}
+	for _, route := range routes {
+		if route.ID == r.ID {
+			t.Error("GetRoutes(enabled=true) includes a deleted route")
+		}
+	}
+}
+
+func testHistory(t *testing.T, s store.Store) {
+	// Attach the entries to a fresh route so that filtering by ID only
+	// returns what this test wrote.
+	backend := uniqueName("history-backend")
+	if err := s.CreateBackend(&store.Backend{Name: backend, Addr: "127.0.0.1:9000", Enabled: true}); err != nil {
+		t.Fatalf("CreateBackend: %v", err)
+	}
+	r := &store.Route{HTTPMethod: "GET", HTTPPattern: "/" + uniqueName("history"), BackendName: backend, TimeoutMS: 1000, Enabled: true}
+	if err := s.CreateRoute(r); err != nil {
+		t.Fatalf("CreateRoute: %v", err)
+	}
+
+	configType := "route"
+	var revision uint64
+	for i, op := range []string{"CREATE", "UPDATE", "DELETE"} {
+		h := &store.ConfigHistory{
+			ConfigType:     configType,
+			ConfigID:       &r.ID,
+			Operation:      op,
+			NewValue:       json.RawMessage(`{"step":` + strconv.Itoa(i) + `}`),
+			Operator:       "storetest",
+			Reason:         "conformance " + op,
+			FreezeOverride: op == "DELETE",
+		}
+		if err := s.CreateHistory(h); err != nil {
+			t.Fatalf("CreateHistory: %v", err)
+		}
+		if h.Revision <= revision {
+			t.Errorf("CreateHistory revision = %d, want greater than %d", h.Revision, revision)
+		}
+		revision = h.Revision
+		// Ordering is by creation time, which may have second precision
+		time.Sleep(1100 * time.Millisecond)
+	}
+
+	if current, err := s.GetRevision(); err != nil || current < revision {
+		t.Errorf("GetRevision = %d, %v; want at least %d", current, err, revision)
+	}
+	if got, err := s.GetRouteByID(r.ID); err != nil || got == nil || got.Revision != revision {
+		t.Errorf("GetRouteByID after CreateHistory = %+v, %v; want revision %d", got, err, revision)
+	}
+
+	entries, total, err := s.GetHistory(&configType, &r.ID, 2, 0)
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("GetHistory total = %d, want 3", total)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("GetHistory returned %d entries, want 2", len(entries))
+	}
+	if entries[0].Operation != "DELETE" || entries[1].Operation != "UPDATE" {
+		t.Errorf("GetHistory order = %s, %s; want newest first", entries[0].Operation, entries[1].Operation)
+	}
+	if entries[0].Revision != revision {
+		t.Errorf("GetHistory revision = %d, want %d", entries[0].Revision, revision)
+	}
+	if entries[0].Reason != "conformance DELETE" || entries[0].Operator != "storetest" || !entries[0].FreezeOverride {
+		t.Errorf("GetHistory entry = %+v, attribution not preserved", entries[0])
+	}
+
+	entries, _, err = s.GetHistory(&configType, &r.ID, 2, 2)
+	if err != nil {
+		t.Fatalf("GetHistory(offset): %v", err)
+	}
+	if len(entries) != 1 || entries[0].Operation != "CREATE" {
+		t.Errorf("GetHistory(offset=2) = %+v, want the CREATE entry", entries)
+	}
+
+	operator := "storetest"
+	ages, err := s.HistoryAges(&operator, time.Hour)
+	if err != nil {
+		t.Fatalf("HistoryAges: %v", err)
+	}
+	if len(ages) < 3 {
+		t.Fatalf("HistoryAges returned %d entries, want at least 3", len(ages))
+	}
+	for i, age := range ages {
+		if age < 0 || age > time.Hour {
+			t.Errorf("HistoryAges[%d] = %v, want within the window", i, age)
+		}
+		if i > 0 && age > ages[i-1] {
+			t.Errorf("HistoryAges not oldest first: %v after %v", age, ages[i-1])
+		}
+	}
+	if all, err := s.HistoryAges(nil, time.Hour); err != nil || len(all) < len(ages) {
+		t.Errorf("HistoryAges(all) = %d entries, %v; want at least %d", len(all), err, len(ages))
+	}
+}
+
+func testFreezeWindows(t *testing.T, s store.Store) {
+	start := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	end := start.Add(2 * time.Hour)
+	f := &store.FreezeWindow{
+		Name:      uniqueName("freeze"),
+		Reason:    "conformance",
+		StartsAt:  &start,
+		EndsAt:    &end,
+		Enabled:   true,
+		CreatedBy: "storetest",
+	}
+	if err := s.CreateFreezeWindow(f); err != nil {
+		t.Fatalf("CreateFreezeWindow: %v", err)
+	}
+	if f.ID == 0 {
+		t.Error("CreateFreezeWindow did not assign an ID")
+	}
+
+	got, err := s.GetFreezeWindowByID(f.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetFreezeWindowByID = %v, %v", got, err)
+	}
+	if got.Name != f.Name || got.StartsAt == nil || !got.StartsAt.Equal(start) || got.EndsAt == nil || !got.EndsAt.Equal(end) {
+		t.Errorf("GetFreezeWindowByID = %+v, want %+v", got, f)
+	}
+
+	f.StartsAt, f.EndsAt = nil, nil
+	f.WeeklyStart, f.WeeklyEnd, f.Timezone = "Fri 18:00", "Mon 08:00", "UTC"
+	if err := s.UpdateFreezeWindow(f.ID, f); err != nil {
+		t.Fatalf("UpdateFreezeWindow: %v", err)
+	}
+	got, _ = s.GetFreezeWindowByID(f.ID)
+	if got == nil || got.StartsAt != nil || got.WeeklyStart != "Fri 18:00" || got.CreatedBy != "storetest" {
+		t.Errorf("after UpdateFreezeWindow got %+v", got)
+	}
+
+	// Unlike backends and routes, freeze windows are deleted outright
+	if err := s.DeleteFreezeWindow(f.ID); err != nil {
+		t.Fatalf("DeleteFreezeWindow: %v", err)
+	}
+	got, err = s.GetFreezeWindowByID(f.ID)
+	if err != nil || got != nil {
+		t.Errorf("after DeleteFreezeWindow got %+v, %v; want nil", got, err)
+	}
+	if err := s.DeleteFreezeWindow(f.ID); err == nil {
+		t.Error("DeleteFreezeWindow(missing) succeeded, want error")
+	}
+}
+
+func testTransactions(t *testing.T, s store.Store) {
+	committed := uniqueName("tx-commit")
+	err := s.InTx(func(tx store.Store) error {
+		return tx.CreateBackend(&store.Backend{Name: committed, Addr: "127.0.0.1:9000", Enabled: true})
+	})
+	if err != nil {
+		t.Fatalf("InTx(commit): %v", err)
+	}
+	if got, _ := s.GetBackendByName(committed); got == nil {
+		t.Error("backend created in a committed transaction is missing")
+	}
+
+	rolledBack := uniqueName("tx-rollback")
+	errAbort := errors.New("abort")
+	err = s.InTx(func(tx store.Store) error {
+		if err := tx.CreateBackend(&store.Backend{Name: rolledBack, Addr: "127.0.0.1:9000", Enabled: true}); err != nil {
+			return err
+		}
+		if got, _ := tx.GetBackendByName(rolledBack); got == nil {
+			t.Error("backend is not visible inside its own transaction")
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("InTx(rollback) = %v, want the callback's error", err)
+	}
+	if got, _ := s.GetBackendByName(rolledBack); got != nil {
+		t.Error("backend created in a rolled-back transaction was persisted")
+	}
+}
+
+func testLatency(t *testing.T, s store.LatencyStore) {
+	backend := uniqueName("latency")
+	// Millisecond precision is all stores are required to keep
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	samples := []store.LatencySample{
+		{Backend: backend, CheckedAt: now.Add(-2 * time.Hour), Latency: 3 * time.Millisecond, Success: true},
+		{Backend: backend, CheckedAt: now.Add(-time.Minute), Latency: 5 * time.Millisecond, Success: false},
+		{Backend: backend, CheckedAt: now, Latency: 1500 * time.Microsecond, Success: true},
+	}
+	if err := s.RecordLatency(samples); err != nil {
+		t.Fatalf("RecordLatency: %v", err)
+	}
+
+	got, err := s.GetLatency(backend, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetLatency: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("GetLatency returned %d samples, want 2", len(got))
+	}
+	if !got[0].CheckedAt.Equal(samples[1].CheckedAt) || got[0].Success || got[0].Latency != samples[1].Latency {
+		t.Errorf("GetLatency[0] = %+v, want %+v", got[0], samples[1])
+	}
+	if !got[1].CheckedAt.Equal(samples[2].CheckedAt) || !got[1].Success || got[1].Latency != samples[2].Latency {
+		t.Errorf("GetLatency[1] = %+v, want %+v", got[1], samples[2])
+	}
+
+	// Prune only what this test wrote: nothing else should be this old
+	ancient := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.RecordLatency([]store.LatencySample{{Backend: backend, CheckedAt: ancient, Latency: time.Millisecond, Success: true}}); err != nil {
+		t.Fatalf("RecordLatency: %v", err)
+	}
+	if n, err := s.PruneLatency(ancient.Add(time.Hour)); err != nil || n < 1 {
+		t.Fatalf("PruneLatency = %d, %v; want at least 1 removed", n, err)
+	}
+	if got, err := s.GetLatency(backend, ancient.Add(-time.Hour)); err != nil || len(got) != 3 {
+		t.Errorf("GetLatency after prune = %d samples, %v; want 3", len(got), err)
+	}
+}
+
+func testRouteStats(t *testing.T, s store.Store, ss store.StatsStore) {
+	backend := uniqueName("stats-backend")
+	if err := s.CreateBackend(&store.Backend{Name: backend, Addr: "127.0.0.1:9000", Enabled: true}); err != nil {
+		t.Fatalf("CreateBackend: %v", err)
+	}
+	r := &store.Route{HTTPMethod: "GET", HTTPPattern: "/" + uniqueName("stats"), BackendName: backend, TimeoutMS: 1000, Enabled: true}
+	if err := s.CreateRoute(r); err != nil {
+		t.Fatalf("CreateRoute: %v", err)
+	}
+
+	buckets := func(first, last int64) []int64 {
+		b := make([]int64, len(store.LatencyBucketBounds)+1)
+		b[0], b[len(b)-1] = first, last
+		return b
+	}
+	minute := time.Now().UTC().Truncate(time.Minute).Add(-10 * time.Minute)
+
+	// Two reports for the same minute, e.g. from two gateways, are summed
+	for _, st := range []store.RouteStats{
+		{RouteID: r.ID, BucketStart: minute.Add(15 * time.Second), Requests: 10, Errors: 1, LatencyBuckets: buckets(9, 1)},
+		{RouteID: r.ID, BucketStart: minute, Requests: 5, Errors: 0, LatencyBuckets: buckets(5, 0)},
+		{RouteID: r.ID, BucketStart: minute.Add(time.Minute), Requests: 1, LatencyBuckets: buckets(1, 0)},
+	} {
+		if err := ss.RecordRouteStats([]store.RouteStats{st}); err != nil {
+			t.Fatalf("RecordRouteStats: %v", err)
+		}
+	}
+
+	got, err := ss.GetRouteStats(r.ID, minute)
+	if err != nil {
+		t.Fatalf("GetRouteStats: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("GetRouteStats returned %d rows, want 2", len(got))
+	}
+	first := got[0]
+	if !first.BucketStart.Equal(minute) || first.Requests != 15 || first.Errors != 1 {
+		t.Errorf("GetRouteStats[0] = %+v, want 15 requests and 1 error at %v", first, minute)
+	}
+	if len(first.LatencyBuckets) != len(store.LatencyBucketBounds)+1 ||
+		first.LatencyBuckets[0] != 14 || first.LatencyBuckets[len(first.LatencyBuckets)-1] != 1 {
+		t.Errorf("GetRouteStats[0].LatencyBuckets = %v, want summed histogram", first.LatencyBuckets)
+	}
+
+	if got, err := ss.GetRouteStats(r.ID, minute.Add(time.Minute)); err != nil || len(got) != 1 {
+		t.Errorf("GetRouteStats(since) = %d rows, %v; want 1", len(got), err)
+	}
+
+	sums, err := ss.SumRouteStats(minute.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("SumRouteStats: %v", err)
+	}
+	var sum *store.RouteStats
+	for i := range sums {
+		if sums[i].RouteID == r.ID {
+			sum = &sums[i]
+		}
+	}
+	if sum == nil || sum.Requests != 1 || sum.Errors != 0 || len(sum.LatencyBuckets) != len(store.LatencyBucketBounds)+1 || sum.LatencyBuckets[0] != 1 {
+		t.Errorf("SumRouteStats = %+v for route %d, want 1 request since the second minute", sum, r.ID)
+	}
+}
+
+func testSnapshots(t *testing.T, s store.SnapshotStore) {
+	document := json.RawMessage(`{"backends": [{"name": "snap", "addr": "127.0.0.1:9000"}], "routes": []}`)
+	snap := &store.Snapshot{Name: uniqueName("snapshot"), Description: "before upgrade", Backends: 1, CreatedBy: "alice", Document: document}
+	if err := s.CreateSnapshot(snap); err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	if snap.ID == 0 {
+		t.Fatal("CreateSnapshot did not assign an ID")
+	}
+
+	got, err := s.GetSnapshotByID(snap.ID)
+	if err != nil {
+		t.Fatalf("GetSnapshotByID: %v", err)
+	}
+	if got == nil || got.Name != snap.Name || got.Description != "before upgrade" || got.Backends != 1 || got.CreatedBy != "alice" {
+		t.Fatalf("GetSnapshotByID = %+v, want %+v", got, snap)
+	}
+	var want, have interface{}
+	json.Unmarshal(document, &want)
+	if err := json.Unmarshal(got.Document, &have); err != nil || !reflect.DeepEqual(want, have) {
+		t.Errorf("GetSnapshotByID document = %s, want %s", got.Document, document)
+	}
+
+	list, err := s.GetSnapshots()
+	if err != nil {
+		t.Fatalf("GetSnapshots: %v", err)
+	}
+	found := false
+	for _, item := range list {
+		if item.ID == snap.ID {
+			found = true
+			if len(item.Document) != 0 {
+				t.Error("GetSnapshots should not return documents")
+			}
+		}
+	}
+	if !found {
+		t.Errorf("GetSnapshots did not return snapshot %d", snap.ID)
+	}
+
+	if err := s.DeleteSnapshot(snap.ID); err != nil {
+		t.Fatalf("DeleteSnapshot: %v", err)
+	}
+	if got, err := s.GetSnapshotByID(snap.ID); err != nil || got != nil {
+		t.Errorf("GetSnapshotByID after delete = %+v, %v; want nil", got, err)
+	}
+	if err := s.DeleteSnapshot(snap.ID); err == nil {
+		t.Error("DeleteSnapshot of a missing snapshot should fail")
+	}
+}
+
+func testDescriptors(t *testing.T, s store.DescriptorStore) {
+	backend := uniqueName("desc")
+	if got, err := s.GetDescriptorSet(backend, 0); err != nil || got != nil {
+		t.Fatalf("GetDescriptorSet without versions = %+v, %v; want nil", got, err)
+	}
+
+	for i, data := range [][]byte{{0x0a, 0x01}, {0x0a, 0x02, 0x00}} {
+		set := &store.DescriptorSet{BackendName: backend, SHA256: strings.Repeat("a", 64), Services: []string{"user.v1.Users"}, UploadedBy: "alice", Data: data}
+		if err := s.CreateDescriptorSet(set); err != nil {
+			t.Fatalf("CreateDescriptorSet: %v", err)
+		}
+		if set.ID == 0 || set.Version != i+1 || set.Size != len(data) {
+			t.Fatalf("CreateDescriptorSet = %+v, want version %d of size %d", set, i+1, len(data))
+		}
+	}
+
+	latest, err := s.GetDescriptorSet(backend, 0)
+	if err != nil {
+		t.Fatalf("GetDescriptorSet: %v", err)
+	}
+	if latest == nil || latest.Version != 2 || !bytes.Equal(latest.Data, []byte{0x0a, 0x02, 0x00}) || !reflect.DeepEqual(latest.Services, []string{"user.v1.Users"}) || latest.UploadedBy != "alice" {
+		t.Fatalf("GetDescriptorSet latest = %+v, want version 2", latest)
+	}
+	first, err := s.GetDescriptorSet(backend, 1)
+	if err != nil || first == nil || first.Version != 1 || !bytes.Equal(first.Data, []byte{0x0a, 0x01}) {
+		t.Errorf("GetDescriptorSet version 1 = %+v, %v", first, err)
+	}
+	if got, err := s.GetDescriptorSet(backend, 3); err != nil || got != nil {
+		t.Errorf("GetDescriptorSet of a missing version = %+v, %v; want nil", got, err)
+	}
+
+	list, err := s.GetDescriptorSets(backend)
+	if err != nil {
+		t.Fatalf("GetDescriptorSets: %v", err)
+	}
+	if len(list) != 2 || list[0].Version != 2 || list[1].Version != 1 || list[0].Data != nil {
+		t.Errorf("GetDescriptorSets = %+v, want versions 2 and 1 without data", list)
+	}
+}
+
+func testRouteSchemas(t *testing.T, s store.SchemaStore) {
+	// Schemas do not reference routes by foreign key; use one no other
+	// test touches and compare versions relative to earlier runs
+	routeID := uint(missingID - 1)
+	before, err := s.GetRouteSchema(routeID, 0)
+	if err != nil {
+		t.Fatalf("GetRouteSchema: %v", err)
+	}
+	base := 0
+	if before != nil {
+		base = before.Version
+	}
+
+	request := &store.SchemaRef{JSONSchema: json.RawMessage(`{"type": "object", "required": ["name"]}`)}
+	response := &store.SchemaRef{Message: "user.v1.User"}
+	first := &store.RouteSchema{RouteID: routeID, Request: request, Response: response, CreatedBy: "alice"}
+	if err := s.CreateRouteSchema(first); err != nil {
+		t.Fatalf("CreateRouteSchema: %v", err)
+	}
+	if first.ID == 0 || first.Version != base+1 {
+		t.Fatalf("CreateRouteSchema = %+v, want version %d", first, base+1)
+	}
+	cleared := &store.RouteSchema{RouteID: routeID, CreatedBy: "bob"}
+	if err := s.CreateRouteSchema(cleared); err != nil {
+		t.Fatalf("CreateRouteSchema: %v", err)
+	}
+
+	got, err := s.GetRouteSchema(routeID, first.Version)
+	if err != nil || got == nil {
+		t.Fatalf("GetRouteSchema(%d) = %+v, %v", first.Version, got, err)
+	}
+	var want, have interface{}
+	json.Unmarshal(request.JSONSchema, &want)
+	if got.Request == nil || json.Unmarshal(got.Request.JSONSchema, &have) != nil || !reflect.DeepEqual(want, have) {
+		t.Errorf("GetRouteSchema request = %+v, want %s", got.Request, request.JSONSchema)
+	}
+	if got.Response == nil || got.Response.Message != "user.v1.User" || got.CreatedBy != "alice" {
+		t.Errorf("GetRouteSchema = %+v, want response message user.v1.User by alice", got)
+	}
+
+	latest, err := s.GetRouteSchema(routeID, 0)
+	if err != nil || latest == nil || latest.Version != base+2 || latest.Request != nil || latest.Response != nil {
+		t.Errorf("GetRouteSchema latest = %+v, %v; want version %d without schemas", latest, err, base+2)
+	}
+
+	list, err := s.GetRouteSchemas(routeID)
+	if err != nil || len(list) < 2 || list[0].Version != base+2 || list[1].Version != base+1 {
+		t.Errorf("GetRouteSchemas = %+v, %v; want versions %d and %d first", list, err, base+2, base+1)
+	}
+
+	all, err := s.GetLatestRouteSchemas()
+	if err != nil {
+		t.Fatalf("GetLatestRouteSchemas: %v", err)
+	}
+	found := 0
+	for _, schema := range all {
+		if schema.RouteID == routeID {
+			found++
+			if schema.Version != base+2 {
+				t.Errorf("GetLatestRouteSchemas returned version %d, want %d", schema.Version, base+2)
+			}
+		}
+	}
+	if found != 1 {
+		t.Errorf("GetLatestRouteSchemas returned %d versions of route %d, want 1", found, routeID)
+	}
+}
+
+func testPluginSchemas(t *testing.T, s store.PluginSchemaStore) {
+	name := uniqueName("plugin")
+	got, err := s.GetPluginSchema(name)
+	if err != nil || got != nil {
+		t.Fatalf("GetPluginSchema(missing) = %+v, %v; want nil", got, err)
+	}
+
+	schema := &store.PluginSchema{Name: name, Schema: json.RawMessage(`{"type": "object"}`), UpdatedBy: "alice"}
+	if err := s.SetPluginSchema(schema); err != nil {
+		t.Fatalf("SetPluginSchema: %v", err)
+	}
+	schema.Description = "replaced"
+	schema.Schema = json.RawMessage(`{"type": "object", "required": ["limit"]}`)
+	if err := s.SetPluginSchema(schema); err != nil {
+		t.Fatalf("SetPluginSchema(replace): %v", err)
+	}
+
+	got, err = s.GetPluginSchema(name)
+	if err != nil || got == nil {
+		t.Fatalf("GetPluginSchema = %+v, %v", got, err)
+	}
+	var want, have interface{}
+	json.Unmarshal(schema.Schema, &want)
+	if json.Unmarshal(got.Schema, &have) != nil || !reflect.DeepEqual(want, have) || got.Description != "replaced" {
+		t.Errorf("GetPluginSchema = %+v, want the replaced schema", got)
+	}
+
+	all, err := s.GetPluginSchemas()
+	if err != nil {
+		t.Fatalf("GetPluginSchemas: %v", err)
+	}
+	found := false
+	for _, p := range all {
+		found = found || p.Name == name
+	}
+	if !found {
+		t.Errorf("GetPluginSchemas does not list %s", name)
+	}
+
+	if err := s.DeletePluginSchema(name); err != nil {
+		t.Fatalf("DeletePluginSchema: %v", err)
+	}
+	if err := s.DeletePluginSchema(name); err == nil {
+		t.Error("DeletePluginSchema(deleted) succeeded")
+	}
+}
+
+func testJobs(t *testing.T, s store.JobStore) {
+	kind := uniqueName("job")
+	params := json.RawMessage(`{"document": {"backends": []}}`)
+	job := &store.Job{Kind: kind, Params: params, CreatedBy: "alice"}
+	if err := s.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if job.ID == 0 || job.Status != store.JobPending {
+		t.Fatalf("CreateJob = %+v, want an ID and status pending", job)
+	}
+
+	got, err := s.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("GetJob: %v", err)
+	}
+	if got == nil || got.Kind != kind || got.Status != store.JobPending || got.CreatedBy != "alice" || got.Attempts != 0 {
+		t.Fatalf("GetJob = %+v, want %+v", got, job)
+	}
+	var want, have interface{}
+	json.Unmarshal(params, &want)
+	if err := json.Unmarshal(got.Params, &have); err != nil || !reflect.DeepEqual(want, have) {
+		t.Errorf("GetJob params = %s, want %s", got.Params, params)
+	}
+
+	list, err := s.GetJobs(kind, "", 0)
+	if err != nil {
+		t.Fatalf("GetJobs: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != job.ID || len(list[0].Params) != 0 {
+		t.Errorf("GetJobs(%s) = %+v, want job %d without params", kind, list, job.ID)
+	}
+
+	// Claiming takes the oldest pending job, which is only known to be
+	// this one if no other is pending
+	pending, err := s.GetJobs("", store.JobPending, 2)
+	if err != nil {
+		t.Fatalf("GetJobs(pending): %v", err)
+	}
+	if len(pending) == 1 {
+		claimed, err := s.ClaimJob()
+		if err != nil {
+			t.Fatalf("ClaimJob: %v", err)
+		}
+		if claimed == nil || claimed.ID != job.ID || claimed.Status != store.JobRunning || claimed.Attempts != 1 || claimed.StartedAt == nil {
+			t.Fatalf("ClaimJob = %+v, want job %d running on attempt 1", claimed, job.ID)
+		}
+		if claimed, err := s.ClaimJob(); err != nil || claimed != nil {
+			t.Errorf("ClaimJob without pending jobs = %+v, %v; want nil", claimed, err)
+		}
+	} else {
+		t.Log("other jobs are pending; not claiming")
+	}
+
+	if err := s.UpdateJobProgress(job.ID, store.JobProgress{Done: 1, Total: 3}); err != nil {
+		t.Fatalf("UpdateJobProgress: %v", err)
+	}
+
+	job.Status = store.JobFailed
+	job.Error = "backend not found"
+	job.Progress = store.JobProgress{Done: 1, Total: 3}
+	if err := s.FinishJob(job); err != nil {
+		t.Fatalf("FinishJob: %v", err)
+	}
+	if got, err := s.GetJob(job.ID); err != nil || got.Status != store.JobFailed || got.Error != "backend not found" || got.FinishedAt == nil || len(got.Params) == 0 {
+		t.Fatalf("GetJob after failure = %+v, %v; want failed with params", got, err)
+	}
+	if ok, err := s.RequeueJob(job.ID, store.JobRunning); err != nil || ok {
+		t.Errorf("RequeueJob(running) of a failed job = %v, %v; want false", ok, err)
+	}
+	if ok, err := s.RequeueJob(job.ID, store.JobFailed); err != nil || !ok {
+		t.Fatalf("RequeueJob(failed) = %v, %v; want true", ok, err)
+	}
+	if got, err := s.GetJob(job.ID); err != nil || got.Status != store.JobPending || got.Error != "" || got.FinishedAt != nil {
+		t.Fatalf("GetJob after requeue = %+v, %v; want pending", got, err)
+	}
+
+	job.Status = store.JobSucceeded
+	job.Error = ""
+	job.Result = json.RawMessage(`{"applied": true}`)
+	if err := s.FinishJob(job); err != nil {
+		t.Fatalf("FinishJob: %v", err)
+	}
+	got, err = s.GetJob(job.ID)
+	if err != nil || got.Status != store.JobSucceeded || len(got.Params) != 0 {
+		t.Fatalf("GetJob after success = %+v, %v; want succeeded without params", got, err)
+	}
+	if err := json.Unmarshal(got.Result, &have); err != nil || !reflect.DeepEqual(have, map[string]interface{}{"applied": true}) {
+		t.Errorf("GetJob result = %s, want {\"applied\": true}", got.Result)
+	}
+
+	// Jobs are stamped by the store, so only check that nothing this
+	// recent is requeued or pruned
+	ancient := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := s.RequeueStaleJobs(ancient); err != nil {
+		t.Errorf("RequeueStaleJobs: %v", err)
+	}
+	if _, err := s.PruneJobs(ancient); err != nil {
+		t.Fatalf("PruneJobs: %v", err)
+	}
+	if got, err := s.GetJob(job.ID); err != nil || got == nil || got.Status != store.JobSucceeded {
+		t.Errorf("GetJob after prune = %+v, %v; want the succeeded job", got, err)
+	}
+	if got, err := s.GetJob(missingID); err != nil || got != nil {
+		t.Errorf("GetJob(missing) = %+v, %v; want nil", got, err)
+	}
+}
+
+func testGateways(t *testing.T, s store.GatewayStore) {
+	id := uniqueName("gw")
+	first := strings.Repeat("a", 64)
+	if err := s.ReportGatewayConfig(id, "public", first, 7); err != nil {
+		t.Fatalf("ReportGatewayConfig: %v", err)
+	}
+	if got := findGateway(t, s, id); got == nil || got.ConfigSHA256 != first || got.ConfigRevision != 7 || got.Cluster != "public" || got.RegisteredAt != nil || got.LastSeenAt.IsZero() {
+		t.Fatalf("GetGateways = %+v, want unregistered public gateway with config %s", got, first)
+	}
+
+	gateway := &store.GatewayInstance{ID: id, Version: "1.4.0", Addr: "10.0.0.7:8080", Cluster: "internal", Labels: map[string]string{"zone": "eu-1"}}
+	if err := s.RegisterGateway(gateway); err != nil {
+		t.Fatalf("RegisterGateway: %v", err)
+	}
+	if gateway.RegisteredAt == nil || gateway.LastSeenAt.IsZero() {
+		t.Errorf("RegisterGateway = %+v, want registration and last seen times", gateway)
+	}
+	if got := findGateway(t, s, id); got == nil || got.Version != "1.4.0" || got.Addr != "10.0.0.7:8080" || got.Cluster != "internal" || got.ConfigSHA256 != "" || got.RegisteredAt == nil {
+		t.Fatalf("GetGateways after register = %+v, want %+v", got, gateway)
+	}
+	if got, err := s.GetGateway(id); err != nil || got == nil || !reflect.DeepEqual(got.Labels, gateway.Labels) {
+		t.Fatalf("GetGateway = %+v, %v; want labels %v", got, err, gateway.Labels)
+	}
+	if got, err := s.GetGateway(uniqueName("gw-missing")); err != nil || got != nil {
+		t.Errorf("GetGateway(missing) = %+v, %v; want nil", got, err)
+	}
+
+	if err := s.ReportGatewayFailure(id, "internal", 9, "invalid route"); err != nil {
+		t.Fatalf("ReportGatewayFailure: %v", err)
+	}
+	if got := findGateway(t, s, id); got == nil || got.FailedRevision != 9 || got.Failure != "invalid route" || got.Version != "1.4.0" {
+		t.Fatalf("GetGateways after failure = %+v, want failed revision 9", got)
+	}
+
+	second := strings.Repeat("b", 64)
+	if ok, err := s.GatewayHeartbeat(id, second, 8); err != nil || !ok {
+		t.Fatalf("GatewayHeartbeat = %v, %v; want true", ok, err)
+	}
+	if got := findGateway(t, s, id); got == nil || got.ConfigRevision != 8 || got.FailedRevision != 9 {
+		t.Errorf("GetGateways after heartbeat = %+v, want revision 8 with the failure of 9 kept", got)
+	}
+	if ok, err := s.GatewayHeartbeat(id, second, 10); err != nil || !ok {
+		t.Fatalf("GatewayHeartbeat = %v, %v; want true", ok, err)
+	}
+	if ok, err := s.GatewayHeartbeat(id, "", 0); err != nil || !ok {
+		t.Fatalf("GatewayHeartbeat without config = %v, %v; want true", ok, err)
+	}
+	if got := findGateway(t, s, id); got == nil || got.ConfigSHA256 != second || got.ConfigRevision != 10 || got.FailedRevision != 0 || got.Failure != "" || got.Version != "1.4.0" {
+		t.Errorf("GetGateways after heartbeat = %+v, want config %s at revision 10 without failure", got, second)
+	}
+	if ok, err := s.GatewayHeartbeat(uniqueName("gw-missing"), "", 0); err != nil || ok {
+		t.Errorf("GatewayHeartbeat(missing) = %v, %v; want false", ok, err)
+	}
+
+	// Gateways are stamped by the store, so only check that nothing this
+	// recent is pruned
+	if _, err := s.PruneGateways(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("PruneGateways: %v", err)
+	}
+	if got := findGateway(t, s, id); got == nil {
+		t.Error("PruneGateways removed a gateway seen just now")
+	}
+}
+
+// findGateway returns the gateway with the given ID, or nil.
+func findGateway(t *testing.T, s store.GatewayStore, id string) *store.GatewayInstance {
+	t.Helper()
+	list, err := s.GetGateways()
+	if err != nil {
+		t.Fatalf("GetGateways: %v", err)
+	}
+	for i := range list {
+		if list[i].ID == id {
+			return &list[i]
+		}
+	}
+	return nil
+}
+
+func testRollouts(t *testing.T, s store.RolloutStore) {
+	rollout := &store.ConfigRollout{
+		Percent:     10,
+		Labels:      map[string]string{"zone": "eu-1"},
+		Description: "conformance",
+		Revision:    42,
+		CreatedBy:   "storetest",
+		Baseline:    []byte(`{"backends":[],"routes":[]}`),
+	}
+	created, err := s.CreateRollout(rollout)
+	if err != nil || !created {
+		t.Fatalf("CreateRollout = %v, %v; want true (is a rollout of an earlier run still in progress?)", created, err)
+	}
+	if rollout.ID == 0 || rollout.Status != store.RolloutInProgress || rollout.CreatedAt.IsZero() {
+		t.Errorf("CreateRollout = %+v, want ID, status and creation time", rollout)
+	}
+	defer s.FinishRollout(rollout.ID, store.RolloutAborted, "storetest")
+
+	if created, err := s.CreateRollout(&store.ConfigRollout{Percent: 50, Baseline: rollout.Baseline}); err != nil || created {
+		t.Errorf("CreateRollout while one is in progress = %v, %v; want false", created, err)
+	}
+
+	active, err := s.GetActiveRollout()
+	if err != nil || active == nil || active.ID != rollout.ID || active.Baseline != nil {
+		t.Fatalf("GetActiveRollout = %+v, %v; want rollout %d without baseline", active, err, rollout.ID)
+	}
+	got, err := s.GetRollout(rollout.ID)
+	if err != nil || got == nil || got.Percent != 10 || !reflect.DeepEqual(got.Labels, rollout.Labels) || got.Description != "conformance" || got.Revision != 42 || len(got.Baseline) == 0 {
+		t.Fatalf("GetRollout = %+v, %v; want %+v with baseline", got, err, rollout)
+	}
+	list, err := s.GetRollouts()
+	if err != nil || len(list) == 0 || list[0].ID != rollout.ID {
+		t.Errorf("GetRollouts = %+v, %v; want newest first", list, err)
+	}
+
+	gateway := uniqueName("gw")
+	for i := 0; i < 2; i++ {
+		if err := s.AddRolloutHealth(rollout.ID, &store.RolloutHealth{Gateway: gateway, Canary: true, Requests: 100, Errors: 3}); err != nil {
+			t.Fatalf("AddRolloutHealth: %v", err)
+		}
+	}
+	health, err := s.GetRolloutHealth(rollout.ID)
+	if err != nil || len(health) != 1 || health[0].Gateway != gateway || !health[0].Canary || health[0].Requests != 200 || health[0].Errors != 6 {
+		t.Errorf("GetRolloutHealth = %+v, %v; want accumulated traffic of %s", health, err, gateway)
+	}
+
+	if ok, err := s.FinishRollout(rollout.ID, store.RolloutPromoted, "storetest"); err != nil || !ok {
+		t.Fatalf("FinishRollout = %v, %v; want true", ok, err)
+	}
+	if ok, err := s.FinishRollout(rollout.ID, store.RolloutAborted, "storetest"); err != nil || ok {
+		t.Errorf("FinishRollout twice = %v, %v; want false", ok, err)
+	}
+	if got, err := s.GetRollout(rollout.ID); err != nil || got == nil || got.Status != store.RolloutPromoted || got.FinishedBy != "storetest" || got.FinishedAt == nil {
+		t.Errorf("GetRollout after finish = %+v, %v; want promoted", got, err)
+	}
+	if active, err := s.GetActiveRollout(); err != nil || active != nil {
+		t.Errorf("GetActiveRollout after finish = %+v, %v; want nil", active, err)
+	}
+	if got, err := s.GetRollout(missingID); err != nil || got != nil {
+		t.Errorf("GetRollout(missing) = %+v, %v; want nil", got, err)
+	}
+}
+
+func testOutbox(t *testing.T, s store.Store) {
+	outbox := s.(store.OutboxStore)
+	name := uniqueName("sink")
+	sink, err := outbox.ClaimOutboxSink(name, "owner-a", time.Minute)
+	if err != nil || sink == nil || sink.LeaseOwner != "owner-a" {
+		t.Fatalf("ClaimOutboxSink = %+v, %v; want a lease for owner-a", sink, err)
+	}
+	if other, err := outbox.ClaimOutboxSink(name, "owner-b", time.Minute); err != nil || other != nil {
+		t.Fatalf("ClaimOutboxSink(leased) = %+v, %v; want nil", other, err)
+	}
+
+	// Entries are appended in the transaction of their change
+	h := &store.ConfigHistory{ConfigType: "schema", Operation: "CREATE", NewValue: json.RawMessage(`{}`), Operator: "storetest"}
+	err = s.InTx(func(tx store.Store) error {
+		if err := tx.CreateHistory(h); err != nil {
+			return err
+		}
+		txOutbox, ok := tx.(store.OutboxStore)
+		if !ok {
+			return errors.New("transaction store is not an OutboxStore")
+		}
+		return txOutbox.AppendOutbox(&store.OutboxEntry{Revision: h.Revision, Payload: json.RawMessage(`{"step":1}`)})
+	})
+	if err != nil {
+		t.Fatalf("AppendOutbox: %v", err)
+	}
+	entries, err := outbox.GetOutbox(sink.Delivered, 100)
+	if err != nil {
+		t.Fatalf("GetOutbox: %v", err)
+	}
+	var found *store.OutboxEntry
+	for i := range entries {
+		if i > 0 && entries[i].Revision <= entries[i-1].Revision {
+			t.Errorf("GetOutbox not oldest first: %d after %d", entries[i].Revision, entries[i-1].Revision)
+		}
+		if entries[i].Revision == h.Revision {
+			found = &entries[i]
+		}
+	}
+	var payload struct {
+		Step int `json:"step"`
+	}
+	if found == nil || json.Unmarshal(found.Payload, &payload) != nil || payload.Step != 1 {
+		t.Fatalf("GetOutbox(%d) = %+v, want the entry of revision %d", sink.Delivered, entries, h.Revision)
+	}
+
+	if err := outbox.FailOutboxSink(name, "owner-a", "connection refused", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("FailOutboxSink: %v", err)
+	}
+	if got := findOutboxSink(t, outbox, name); got == nil || got.Attempts != 1 || got.LastError != "connection refused" || got.NextAttemptAt == nil {
+		t.Errorf("GetOutboxSinks after failure = %+v, want one attempt", got)
+	}
+	if acked, err := outbox.AckOutboxSink(name, "owner-b", h.Revision); err != nil || acked {
+		t.Errorf("AckOutboxSink(not owner) = %v, %v; want false", acked, err)
+	}
+	if acked, err := outbox.AckOutboxSink(name, "owner-a", h.Revision); err != nil || !acked {
+		t.Fatalf("AckOutboxSink = %v, %v; want true", acked, err)
+	}
+	if got := findOutboxSink(t, outbox, name); got == nil || got.Delivered != h.Revision || got.Attempts != 0 || got.LastError != "" {
+		t.Errorf("GetOutboxSinks after ack = %+v, want delivered %d and no failures", got, h.Revision)
+	}
+
+	// An expired lease can be taken over
+	if _, err := outbox.ClaimOutboxSink(name, "owner-a", -time.Second); err != nil {
+		t.Fatalf("ClaimOutboxSink(renew): %v", err)
+	}
+	if other, err := outbox.ClaimOutboxSink(name, "owner-b", time.Minute); err != nil || other == nil || other.Delivered != h.Revision {
+		t.Errorf("ClaimOutboxSink(expired) = %+v, %v; want the lease at revision %d", other, err, h.Revision)
+	}
+
+	if _, err := outbox.PruneOutbox(time.Now().Add(-time.Hour)); err != nil {
+		t.Errorf("PruneOutbox: %v", err)
+	}
+}
+
+func findOutboxSink(t *testing.T, s store.OutboxStore, name string) *store.OutboxSink {
+	t.Helper()
+	sinks, err := s.GetOutboxSinks()
+	if err != nil {
+		t.Fatalf("GetOutboxSinks: %v", err)
+	}
+	for i := range sinks {
+		if sinks[i].Name == name {
+			return &sinks[i]
+		}
+	}
+	return nil
+}
+
+func testTeams(t *testing.T, s store.TeamStore) {
+	name := uniqueName("team")
+	if got, err := s.GetTeam(name); err != nil || got != nil {
+		t.Fatalf("GetTeam(missing) = %+v, %v; want nil", got, err)
+	}
+
+	team := &store.Team{Name: name, Description: "payments platform", Contact: "#payments", CreatedBy: "alice"}
+	if err := s.CreateTeam(team); err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	if err := s.CreateTeam(&store.Team{Name: name}); err == nil {
+		t.Error("CreateTeam of an existing team should fail")
+	}
+	got, err := s.GetTeam(name)
+	if err != nil || got == nil || got.Description != "payments platform" || got.Contact != "#payments" || got.CreatedBy != "alice" || got.CreatedAt.IsZero() {
+		t.Fatalf("GetTeam = %+v, %v; want %+v", got, err, team)
+	}
+
+	if err := s.UpdateTeam(name, &store.Team{Contact: "payments@example.com"}); err != nil {
+		t.Fatalf("UpdateTeam: %v", err)
+	}
+	if err := s.UpdateTeam(name, &store.Team{Contact: "payments@example.com"}); err != nil {
+		t.Errorf("UpdateTeam without changes: %v", err)
+	}
+	got, _ = s.GetTeam(name)
+	if got == nil || got.Description != "" || got.Contact != "payments@example.com" || got.CreatedBy != "alice" {
+		t.Errorf("after UpdateTeam got %+v", got)
+	}
+	if err := s.UpdateTeam(uniqueName("team"), &store.Team{}); err == nil {
+		t.Error("UpdateTeam of a missing team should fail")
+	}
+
+	list, err := s.GetTeams()
+	if err != nil {
+		t.Fatalf("GetTeams: %v", err)
+	}
+	found := false
+	for i, item := range list {
+		if i > 0 && list[i-1].Name >= item.Name {
+			t.Errorf("GetTeams not ordered by name: %q before %q", list[i-1].Name, item.Name)
+		}
+		found = found || item.Name == name
+	}
+	if !found {
+		t.Errorf("GetTeams did not return team %q", name)
+	}
+
+	for _, member := range []string{"bob", "alice"} {
+		if err := s.AddTeamMember(&store.TeamMember{Team: name, Member: member, AddedBy: "admin"}); err != nil {
+			t.Fatalf("AddTeamMember(%s): %v", member, err)
+		}
+	}
+	if err := s.AddTeamMember(&store.TeamMember{Team: name, Member: "alice", AddedBy: "admin"}); err != nil {
+		t.Errorf("AddTeamMember of an existing member: %v", err)
+	}
+	members, err := s.GetTeamMembers(name)
+	if err != nil {
+		t.Fatalf("GetTeamMembers: %v", err)
+	}
+	if len(members) != 2 || members[0].Member != "alice" || members[1].Member != "bob" || members[0].AddedBy != "admin" {
+		t.Errorf("GetTeamMembers = %+v, want alice and bob", members)
+	}
+	if teams, err := s.GetMemberTeams("alice"); err != nil || !containsString(teams, name) {
+		t.Errorf("GetMemberTeams(alice) = %v, %v; want it to include %q", teams, err, name)
+	}
+	if err := s.RemoveTeamMember(name, "bob"); err != nil {
+		t.Fatalf("RemoveTeamMember: %v", err)
+	}
+	if err := s.RemoveTeamMember(name, "bob"); err == nil {
+		t.Error("RemoveTeamMember of a missing member should fail")
+	}
+	if teams, _ := s.GetMemberTeams("bob"); containsString(teams, name) {
+		t.Errorf("GetMemberTeams(bob) after removal = %v", teams)
+	}
+
+	if err := s.DeleteTeam(name); err != nil {
+		t.Fatalf("DeleteTeam: %v", err)
+	}
+	if members, err := s.GetTeamMembers(name); err != nil || len(members) != 0 {
+		t.Errorf("GetTeamMembers after DeleteTeam = %+v, %v; want none", members, err)
+	}
+	if got, err := s.GetTeam(name); err != nil || got != nil {
+		t.Errorf("GetTeam after delete = %+v, %v; want nil", got, err)
+	}
+	if err := s.DeleteTeam(name); err == nil {
+		t.Error("DeleteTeam of a missing team should fail")
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func testUsers(t *testing.T, s store.UserStore) {
+	name := uniqueName("user")
+	if got, err := s.GetUser(name); err != nil || got != nil {
+		t.Fatalf("GetUser(missing) = %+v, %v; want nil", got, err)
+	}
+
+	changed := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	user := &store.User{Name: name, DisplayName: "Alice", ExternalID: "ext-1", Role: "editor", PasswordHash: "hash-1", MustChangePassword: true, PasswordChangedAt: changed, CreatedBy: "admin"}
+	if err := s.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := s.CreateUser(&store.User{Name: name, Role: "viewer", PasswordHash: "hash"}); err == nil {
+		t.Error("CreateUser of an existing user should fail")
+	}
+	got, err := s.GetUser(name)
+	if err != nil || got == nil || got.Role != "editor" || got.ExternalID != "ext-1" || got.PasswordHash != "hash-1" || !got.MustChangePassword || got.Disabled ||
+		!got.PasswordChangedAt.Equal(changed) || got.LastLoginAt != nil || got.CreatedBy != "admin" {
+		t.Fatalf("GetUser = %+v, %v; want %+v", got, err, user)
+	}
+
+	update := &store.User{DisplayName: "Alice A.", Role: "admin", Disabled: true}
+	if err := s.UpdateUser(name, update); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if err := s.UpdateUser(name, update); err != nil {
+		t.Errorf("UpdateUser without changes: %v", err)
+	}
+	got, _ = s.GetUser(name)
+	if got == nil || got.DisplayName != "Alice A." || got.Role != "admin" || !got.Disabled || got.MustChangePassword || got.PasswordHash != "hash-1" {
+		t.Errorf("after UpdateUser got %+v", got)
+	}
+	if err := s.UpdateUser(uniqueName("user"), &store.User{Role: "viewer"}); err == nil {
+		t.Error("UpdateUser of a missing user should fail")
+	}
+
+	failedAt := time.Now().Truncate(time.Millisecond)
+	for i := 1; i <= 3; i++ {
+		if n, err := s.RecordFailedLogin(name, failedAt, failedAt.Add(-time.Hour)); err != nil || n != i {
+			t.Fatalf("RecordFailedLogin #%d = %d, %v", i, n, err)
+		}
+	}
+	if n, err := s.RecordFailedLogin(name, failedAt.Add(2*time.Hour), failedAt.Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("RecordFailedLogin after the window = %d, %v; want 1", n, err)
+	}
+	if n, err := s.RecordFailedLogin(uniqueName("user"), failedAt, failedAt); err != nil || n != 0 {
+		t.Errorf("RecordFailedLogin of a missing user = %d, %v; want 0", n, err)
+	}
+	until := failedAt.Add(time.Minute)
+	if err := s.LockUser(name, until); err != nil {
+		t.Fatalf("LockUser: %v", err)
+	}
+	got, _ = s.GetUser(name)
+	if got == nil || got.FailedLogins != 1 || got.LastFailedLoginAt == nil || !got.LastFailedLoginAt.Equal(failedAt.Add(2*time.Hour)) ||
+		got.LockedUntil == nil || !got.LockedUntil.Equal(until) {
+		t.Errorf("after LockUser got %+v", got)
+	}
+	if err := s.UnlockUser(name); err != nil {
+		t.Fatalf("UnlockUser: %v", err)
+	}
+	if err := s.UnlockUser(name); err != nil {
+		t.Errorf("UnlockUser of an unlocked user: %v", err)
+	}
+	got, _ = s.GetUser(name)
+	if got == nil || got.FailedLogins != 0 || got.LockedUntil != nil {
+		t.Errorf("after UnlockUser got %+v", got)
+	}
+	if err := s.UnlockUser(uniqueName("user")); err == nil {
+		t.Error("UnlockUser of a missing user should fail")
+	}
+	// Signing in below clears these
+	if _, err := s.RecordFailedLogin(name, failedAt, failedAt.Add(-time.Hour)); err != nil {
+		t.Fatalf("RecordFailedLogin: %v", err)
+	}
+	if err := s.LockUser(name, until); err != nil {
+		t.Fatalf("LockUser: %v", err)
+	}
+
+	changed = time.Now().Truncate(time.Millisecond)
+	if err := s.SetUserPassword(name, "hash-2", true, changed); err != nil {
+		t.Fatalf("SetUserPassword: %v", err)
+	}
+	login := time.Now().Truncate(time.Millisecond)
+	if err := s.RecordUserLogin(name, login); err != nil {
+		t.Fatalf("RecordUserLogin: %v", err)
+	}
+	got, _ = s.GetUser(name)
+	if got == nil || got.PasswordHash != "hash-2" || !got.MustChangePassword || !got.PasswordChangedAt.Equal(changed) ||
+		got.LastLoginAt == nil || !got.LastLoginAt.Equal(login) || got.FailedLogins != 0 || got.LockedUntil != nil {
+		t.Errorf("after SetUserPassword and RecordUserLogin got %+v", got)
+	}
+	if err := s.SetUserPassword(uniqueName("user"), "hash", false, changed); err == nil {
+		t.Error("SetUserPassword of a missing user should fail")
+	}
+
+	if totp, err := s.GetUserTOTP(name); err != nil || totp != nil {
+		t.Errorf("GetUserTOTP before enrolling = %+v, %v; want nil", totp, err)
+	}
+	if err := s.EnableUserTOTP(name, 1, nil); err == nil {
+		t.Error("EnableUserTOTP without an enrollment should fail")
+	}
+	if err := s.SetUserTOTP(name, "JBSWY3DPEHPK3PXP"); err != nil {
+		t.Fatalf("SetUserTOTP: %v", err)
+	}
+	if err := s.SetUserTOTP(uniqueName("user"), "JBSWY3DPEHPK3PXP"); err == nil {
+		t.Error("SetUserTOTP of a missing user should fail")
+	}
+	totp, err := s.GetUserTOTP(name)
+	if err != nil || totp == nil || totp.Secret != "JBSWY3DPEHPK3PXP" || totp.Enabled {
+		t.Fatalf("GetUserTOTP after enrolling = %+v, %v", totp, err)
+	}
+	if err := s.EnableUserTOTP(name, 100, []string{"hash-a", "hash-b"}); err != nil {
+		t.Fatalf("EnableUserTOTP: %v", err)
+	}
+	if err := s.EnableUserTOTP(name, 100, nil); err == nil {
+		t.Error("EnableUserTOTP of an enabled authenticator should fail")
+	}
+	totp, _ = s.GetUserTOTP(name)
+	if got, _ := s.GetUser(name); totp == nil || !totp.Enabled || totp.LastStep != 100 || totp.RecoveryCodesLeft != 2 || got == nil || !got.TOTPEnabled {
+		t.Errorf("after EnableUserTOTP got %+v, user %+v", totp, got)
+	}
+	if err := s.UseTOTPStep(name, 100); err == nil {
+		t.Error("UseTOTPStep of a used step should fail")
+	}
+	if err := s.UseTOTPStep(name, 101); err != nil {
+		t.Errorf("UseTOTPStep: %v", err)
+	}
+	if err := s.UseRecoveryCode(name, "hash-a", time.Now()); err != nil {
+		t.Errorf("UseRecoveryCode: %v", err)
+	}
+	if err := s.UseRecoveryCode(name, "hash-a", time.Now()); err == nil {
+		t.Error("UseRecoveryCode of a used code should fail")
+	}
+	if totp, _ := s.GetUserTOTP(name); totp == nil || totp.LastStep != 101 || totp.RecoveryCodesLeft != 1 {
+		t.Errorf("after using codes got %+v", totp)
+	}
+	if err := s.SetRecoveryCodes(name, []string{"hash-c", "hash-d", "hash-e"}); err != nil {
+		t.Fatalf("SetRecoveryCodes: %v", err)
+	}
+	if err := s.UseRecoveryCode(name, "hash-b", time.Now()); err == nil {
+		t.Error("UseRecoveryCode of a replaced code should fail")
+	}
+	if totp, _ := s.GetUserTOTP(name); totp == nil || totp.RecoveryCodesLeft != 3 {
+		t.Errorf("after SetRecoveryCodes got %+v", totp)
+	}
+	if err := s.DisableUserTOTP(name); err != nil {
+		t.Fatalf("DisableUserTOTP: %v", err)
+	}
+	if totp, err := s.GetUserTOTP(name); err != nil || totp != nil {
+		t.Errorf("GetUserTOTP after DisableUserTOTP = %+v, %v; want nil", totp, err)
+	}
+	if err := s.UseRecoveryCode(name, "hash-c", time.Now()); err == nil {
+		t.Error("UseRecoveryCode after DisableUserTOTP should fail")
+	}
+	if err := s.DisableUserTOTP(uniqueName("user")); err == nil {
+		t.Error("DisableUserTOTP of a missing user should fail")
+	}
+
+	now := time.Now().Truncate(time.Millisecond)
+	sessions := make([]string, 2)
+	for i := range sessions {
+		sessions[i] = uniqueName("sess")
+		sess := &store.Session{ID: sessions[i], User: name, RefreshHash: "refresh-" + sessions[i], UserAgent: "curl/8", IP: "10.0.0.1",
+			CreatedAt: now, LastUsedAt: now.Add(time.Duration(i) * time.Second), ExpiresAt: now.Add(time.Hour)}
+		if err := s.CreateSession(sess); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+	}
+	if got, err := s.GetSession(uniqueName("sess")); err != nil || got != nil {
+		t.Errorf("GetSession(missing) = %+v, %v; want nil", got, err)
+	}
+	sess, err := s.GetSession(sessions[0])
+	if err != nil || sess == nil || sess.User != name || sess.RefreshHash != "refresh-"+sessions[0] || sess.UserAgent != "curl/8" ||
+		sess.IP != "10.0.0.1" || !sess.ExpiresAt.Equal(now.Add(time.Hour)) || sess.RevokedAt != nil || !sess.ActiveAt(now) {
+		t.Fatalf("GetSession = %+v, %v", sess, err)
+	}
+	active, err := s.GetUserSessions(name, now)
+	if err != nil || len(active) != 2 || active[0].ID != sessions[1] || active[1].ID != sessions[0] {
+		t.Errorf("GetUserSessions = %+v, %v; want %v most recently used first", active, err, sessions)
+	}
+	if active, _ := s.GetUserSessions(name, now.Add(2*time.Hour)); len(active) != 0 {
+		t.Errorf("GetUserSessions after expiry = %+v; want none", active)
+	}
+
+	if err := s.RotateSession(sessions[0], "wrong", "refresh-2", now.Add(time.Minute)); err == nil {
+		t.Error("RotateSession with a stale hash should fail")
+	}
+	if err := s.RotateSession(sessions[0], "refresh-"+sessions[0], "refresh-2", now.Add(time.Minute)); err != nil {
+		t.Fatalf("RotateSession: %v", err)
+	}
+	sess, _ = s.GetSession(sessions[0])
+	if sess == nil || sess.RefreshHash != "refresh-2" || !sess.LastUsedAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("after RotateSession got %+v", sess)
+	}
+
+	if err := s.RevokeSession(sessions[0], now); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if err := s.RevokeSession(sessions[0], now); err == nil {
+		t.Error("RevokeSession of a revoked session should fail")
+	}
+	if err := s.RotateSession(sessions[0], "refresh-2", "refresh-3", now); err == nil {
+		t.Error("RotateSession of a revoked session should fail")
+	}
+	sess, _ = s.GetSession(sessions[0])
+	if sess == nil || sess.RevokedAt == nil || sess.ActiveAt(now) {
+		t.Errorf("after RevokeSession got %+v", sess)
+	}
+	if err := s.RevokeUserSessions(name, now); err != nil {
+		t.Fatalf("RevokeUserSessions: %v", err)
+	}
+	if active, _ := s.GetUserSessions(name, now); len(active) != 0 {
+		t.Errorf("GetUserSessions after RevokeUserSessions = %+v; want none", active)
+	}
+
+	list, err := s.GetUsers()
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	found := false
+	for _, item := range list {
+		found = found || item.Name == name
+	}
+	if !found {
+		t.Errorf("GetUsers did not return user %q", name)
+	}
+
+	if err := s.DeleteUser(name); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if got, err := s.GetUser(name); err != nil || got != nil {
+		t.Errorf("GetUser after delete = %+v, %v; want nil", got, err)
+	}
+	if got, err := s.GetSession(sessions[1]); err != nil || got != nil {
+		t.Errorf("GetSession after DeleteUser = %+v, %v; want nil", got, err)
+	}
+	if err := s.DeleteUser(name); err == nil {
+		t.Error("DeleteUser of a missing user should fail")
+	}
+}
+
+func testGroups(t *testing.T, s store.Store, gs store.GroupStore) {
+	alice, bob := uniqueName("alice"), uniqueName("bob")
+	id := uniqueName("group")
+	if got, err := gs.GetGroup(id); err != nil || got != nil {
+		t.Fatalf("GetGroup(missing) = %+v, %v; want nil", got, err)
+	}
+
+	group := &store.Group{ID: id, DisplayName: "Group " + id, ExternalID: "ext-1", Members: []string{bob, alice}}
+	if err := gs.CreateGroup(group); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if err := gs.CreateGroup(&store.Group{ID: uniqueName("group"), DisplayName: group.DisplayName}); err == nil {
+		t.Error("CreateGroup with an existing display name should fail")
+	}
+	got, err := gs.GetGroup(id)
+	if err != nil || got == nil || got.DisplayName != group.DisplayName || got.ExternalID != "ext-1" ||
+		!reflect.DeepEqual(got.Members, []string{alice, bob}) {
+		t.Fatalf("GetGroup = %+v, %v; want %+v", got, err, group)
+	}
+	if groups, err := gs.GetUserGroups(alice); err != nil || !reflect.DeepEqual(groups, []string{group.DisplayName}) {
+		t.Errorf("GetUserGroups = %v, %v", groups, err)
+	}
+
+	update := &store.Group{DisplayName: "Renamed " + id, Members: []string{alice}}
+	if err := gs.UpdateGroup(id, update); err != nil {
+		t.Fatalf("UpdateGroup: %v", err)
+	}
+	if err := gs.UpdateGroup(id, update); err != nil {
+		t.Errorf("UpdateGroup without changes: %v", err)
+	}
+	got, _ = gs.GetGroup(id)
+	if got == nil || got.DisplayName != update.DisplayName || got.ExternalID != "" || !reflect.DeepEqual(got.Members, []string{alice}) {
+		t.Errorf("after UpdateGroup got %+v", got)
+	}
+	if err := gs.UpdateGroup(uniqueName("group"), update); err == nil {
+		t.Error("UpdateGroup of a missing group should fail")
+	}
+
+	if err := gs.AddGroupMembers(id, []string{bob, alice}); err != nil {
+		t.Fatalf("AddGroupMembers: %v", err)
+	}
+	if err := gs.RemoveGroupMembers(id, []string{alice, uniqueName("nobody")}); err != nil {
+		t.Fatalf("RemoveGroupMembers: %v", err)
+	}
+	got, _ = gs.GetGroup(id)
+	if got == nil || !reflect.DeepEqual(got.Members, []string{bob}) {
+		t.Errorf("after AddGroupMembers and RemoveGroupMembers got %+v", got)
+	}
+	if err := gs.AddGroupMembers(uniqueName("group"), []string{bob}); err == nil {
+		t.Error("AddGroupMembers of a missing group should fail")
+	}
+
+	list, err := gs.GetGroups()
+	if err != nil {
+		t.Fatalf("GetGroups: %v", err)
+	}
+	found := false
+	for _, item := range list {
+		found = found || (item.ID == id && reflect.DeepEqual(item.Members, []string{bob}))
+	}
+	if !found {
+		t.Errorf("GetGroups did not return group %q", id)
+	}
+
+	if us, ok := s.(store.UserStore); ok {
+		if err := us.CreateUser(&store.User{Name: bob, Role: "viewer"}); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		if err := us.DeleteUser(bob); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		if groups, err := gs.GetUserGroups(bob); err != nil || len(groups) != 0 {
+			t.Errorf("GetUserGroups after DeleteUser = %v, %v; want none", groups, err)
+		}
+	}
+
+	if err := gs.DeleteGroup(id); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	if got, err := gs.GetGroup(id); err != nil || got != nil {
+		t.Errorf("GetGroup after delete = %+v, %v; want nil", got, err)
+	}
+	if err := gs.DeleteGroup(id); err == nil {
+		t.Error("DeleteGroup of a missing group should fail")
+	}
+}